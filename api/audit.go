@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Tecsisa/foulkon/database"
+	"github.com/satori/go.uuid"
+)
+
+// TYPE DEFINITIONS
+
+// AuditEvent domain. It records a change made by a user over an IAM resource
+type AuditEvent struct {
+	ID        string `json:"id,omitempty"`
+	Actor     string `json:"actor,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+	Action    string `json:"action,omitempty"`
+	Urn       string `json:"urn,omitempty"`
+	// Resource state before and after the change, in JSON format. Empty when resource didn't exist
+	Before   json.RawMessage `json:"before,omitempty"`
+	After    json.RawMessage `json:"after,omitempty"`
+	CreateAt time.Time       `json:"createAt,omitempty"`
+}
+
+func (e AuditEvent) GetUrn() string {
+	return e.Urn
+}
+
+// AUDIT API IMPLEMENTATION
+
+// ListAuditEvents returns the audit events filtered by time range, actor and urn prefix whose
+// target resource is allowed to the authenticated user
+func (api WorkerAPI) ListAuditEvents(requestInfo RequestInfo, filter *Filter) ([]AuditEvent, int, error) {
+	// Validate fields
+	var total int
+	orderByValidColumns := api.AuditRepo.OrderByValidColumns(AUDIT_ACTION_LIST_EVENTS)
+	err := validateFilter(filter, orderByValidColumns)
+	if err != nil {
+		return nil, total, err
+	}
+
+	// Call repo to retrieve the audit events
+	events, total, err := api.AuditRepo.GetAuditEventsFiltered(filter)
+
+	// Error handling
+	if err != nil {
+		//Transform to DB error
+		dbError := err.(*database.Error)
+		return nil, total, &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: dbError.Message,
+		}
+	}
+
+	// Check restrictions
+	urnPrefix := "urn:*"
+	if len(filter.UrnPrefix) > 0 {
+		urnPrefix = filter.UrnPrefix + "*"
+	}
+	resources := []Resource{}
+	for _, e := range events {
+		resources = append(resources, e)
+	}
+	resourcesFiltered, err := api.getAuthorizedResources(requestInfo, urnPrefix, AUDIT_ACTION_LIST_EVENTS, resources)
+	if err != nil {
+		return nil, total, err
+	}
+
+	eventsFiltered := []AuditEvent{}
+	for _, res := range resourcesFiltered {
+		eventsFiltered = append(eventsFiltered, res.(AuditEvent))
+	}
+
+	return eventsFiltered, total, nil
+}
+
+// PRIVATE HELPER METHODS
+
+// Apply a change and store its audit event, with the state of the resource before and after the change, in the same
+// transaction, so a change is never applied without its audit event. Change is called with the repositories of the
+// transaction and returns the state of the resource after it. Errors are returned as database errors
+func (api WorkerAPI) auditedChange(requestInfo RequestInfo, action string, urn string, before interface{},
+	change func(repos Repositories) (interface{}, error)) error {
+	applyChange := func(repos Repositories) error {
+		after, err := change(repos)
+		if err != nil {
+			return err
+		}
+		event, err := newAuditEvent(requestInfo, action, urn, before, after)
+		if err != nil {
+			return &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: fmt.Sprintf("Unable to create audit event %v over %v: %v", action, urn, err),
+			}
+		}
+		return repos.AuditRepo.AddAuditEvent(*event)
+	}
+
+	// Without transaction repository, as in batch operations, repositories are already bound to a transaction
+	if api.TransactionRepo == nil {
+		return applyChange(Repositories{
+			UserRepo:     api.UserRepo,
+			GroupRepo:    api.GroupRepo,
+			PolicyRepo:   api.PolicyRepo,
+			ProxyRepo:    api.ProxyRepo,
+			AuthOidcRepo: api.AuthOidcRepo,
+			AuditRepo:    api.AuditRepo,
+		})
+	}
+	return api.TransactionRepo.RunInTransaction(applyChange)
+}
+
+// Create an audit event of the change made by the authenticated user, with the state of the resource before
+// and after it in JSON format
+func newAuditEvent(requestInfo RequestInfo, action string, urn string, before interface{}, after interface{}) (*AuditEvent, error) {
+	event := &AuditEvent{
+		ID:        uuid.NewV4().String(),
+		Actor:     requestInfo.Identifier,
+		RequestID: requestInfo.RequestID,
+		Action:    action,
+		Urn:       urn,
+		CreateAt:  time.Now().UTC(),
+	}
+	var err error
+	if before != nil {
+		if event.Before, err = json.Marshal(before); err != nil {
+			return nil, err
+		}
+	}
+	if after != nil {
+		if event.After, err = json.Marshal(after); err != nil {
+			return nil, err
+		}
+	}
+	return event, nil
+}
@@ -0,0 +1,262 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Tecsisa/foulkon/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerAPI_ListAuditEvents(t *testing.T) {
+	now := time.Now().UTC()
+	events := []AuditEvent{
+		{
+			ID:       "EVENT1",
+			Actor:    "admin",
+			Action:   POLICY_ACTION_CREATE_POLICY,
+			Urn:      CreateUrn("org1", RESOURCE_POLICY, "/path/", "policy1"),
+			After:    json.RawMessage(`{"name":"policy1"}`),
+			CreateAt: now,
+		},
+		{
+			ID:       "EVENT2",
+			Actor:    "admin",
+			Action:   USER_ACTION_CREATE_USER,
+			Urn:      CreateUrn("", RESOURCE_USER, "/path/", "user1"),
+			After:    json.RawMessage(`{"externalId":"user1"}`),
+			CreateAt: now,
+		},
+	}
+	testcases := map[string]struct {
+		// API method args
+		requestInfo RequestInfo
+		filter      *Filter
+		// Expected result
+		expectedEvents []AuditEvent
+		totalResult    int
+		wantError      error
+		// Manager Results
+		getAuditEventsFilteredResult []AuditEvent
+		getPoliciesForUserResult     []Policy
+		// Manager Errors
+		getAuditEventsFilteredErr error
+	}{
+		"OkCaseAdmin": {
+			requestInfo: RequestInfo{
+				Identifier: "admin",
+				Admin:      true,
+			},
+			filter: &Filter{
+				Actor: "admin",
+				From:  now.Add(-time.Hour),
+				To:    now,
+			},
+			expectedEvents:               events,
+			totalResult:                  2,
+			getAuditEventsFilteredResult: events,
+		},
+		"OkCaseUserAllowedOverPolicies": {
+			requestInfo: RequestInfo{
+				Identifier: "user1",
+			},
+			filter:                       &Filter{},
+			expectedEvents:               events[:1],
+			totalResult:                  2,
+			getAuditEventsFilteredResult: events,
+			getPoliciesForUserResult: []Policy{
+				{
+					ID: "POLICY-USER-ID",
+					Statements: &[]Statement{
+						{
+							Effect:    "allow",
+							Actions:   []string{AUDIT_ACTION_LIST_EVENTS},
+							Resources: []string{GetUrnPrefix("org1", RESOURCE_POLICY, "/")},
+						},
+					},
+				},
+			},
+		},
+		"ErrorCaseUserNotAllowed": {
+			requestInfo: RequestInfo{
+				Identifier: "user1",
+			},
+			filter: &Filter{
+				UrnPrefix: "urn:iws:iam:org1:",
+			},
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "User with externalId user1 is not allowed to access to resource urn:iws:iam:org1:*",
+			},
+			getAuditEventsFilteredResult: events,
+			getPoliciesForUserResult:     []Policy{},
+		},
+		"ErrorCaseInvalidActor": {
+			requestInfo: RequestInfo{
+				Identifier: "admin",
+				Admin:      true,
+			},
+			filter: &Filter{
+				Actor: "*%!",
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: actor *%!",
+			},
+		},
+		"ErrorCaseInvalidUrnPrefix": {
+			requestInfo: RequestInfo{
+				Identifier: "admin",
+				Admin:      true,
+			},
+			filter: &Filter{
+				UrnPrefix: "invalid::urn",
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: urnPrefix invalid::urn",
+			},
+		},
+		"ErrorCaseInvalidTimeRange": {
+			requestInfo: RequestInfo{
+				Identifier: "admin",
+				Admin:      true,
+			},
+			filter: &Filter{
+				From: time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC),
+				To:   time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: from 2017-01-02T00:00:00Z is after to 2017-01-01T00:00:00Z",
+			},
+		},
+		"ErrorCaseDBError": {
+			requestInfo: RequestInfo{
+				Identifier: "admin",
+				Admin:      true,
+			},
+			filter: &Filter{},
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			getAuditEventsFilteredErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetAuditEventsFilteredMethod][0] = test.getAuditEventsFilteredResult
+		testRepo.ArgsOut[GetAuditEventsFilteredMethod][1] = len(test.getAuditEventsFilteredResult)
+		testRepo.ArgsOut[GetAuditEventsFilteredMethod][2] = test.getAuditEventsFilteredErr
+		testRepo.ArgsOut[GetUserByExternalIDMethod][0] = &User{
+			ID:         "USER-ID",
+			ExternalID: test.requestInfo.Identifier,
+		}
+		testRepo.ArgsOut[GetPoliciesForUserMethod][0] = test.getPoliciesForUserResult
+
+		events, total, err := testAPI.ListAuditEvents(test.requestInfo, test.filter)
+		checkMethodResponse(t, n, test.wantError, err, test.expectedEvents, events)
+		if test.wantError == nil {
+			assert.Equal(t, test.totalResult, total, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestWorkerAPI_auditedChange(t *testing.T) {
+	requestInfo := RequestInfo{
+		Identifier: "admin",
+		Admin:      true,
+		RequestID:  "REQUEST-ID",
+	}
+	createdUser := &User{
+		ID:         "USER-ID",
+		ExternalID: "user1",
+		Path:       "/path/",
+		Urn:        CreateUrn("", RESOURCE_USER, "/path/", "user1"),
+	}
+	testcases := map[string]struct {
+		// Expected result
+		expectedResponse *User
+		wantError        error
+		// Manager Errors
+		addUserMethodErr          error
+		addAuditEventMethodErr    error
+		runInTransactionMethodErr error
+	}{
+		"OkCase": {
+			expectedResponse: createdUser,
+		},
+		"ErrorCaseAddUserDBError": {
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			addUserMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseAddAuditEventDBError": {
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			addAuditEventMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseCommitFailed": {
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			runInTransactionMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetUserByExternalIDMethod][1] = &database.Error{
+			Code: database.USER_NOT_FOUND,
+		}
+		testRepo.ArgsOut[AddUserMethod][0] = createdUser
+		testRepo.ArgsOut[AddUserMethod][1] = test.addUserMethodErr
+		testRepo.ArgsOut[AddAuditEventMethod][0] = test.addAuditEventMethodErr
+		testRepo.ArgsOut[RunInTransactionMethod][0] = test.runInTransactionMethodErr
+
+		// Operation fails if its audit event can't be stored in its transaction
+		user, err := testAPI.AddUser(requestInfo, "user1", "/path/")
+		checkMethodResponse(t, n, test.wantError, err, test.expectedResponse, user)
+
+		// Change and audit event are stored in the same transaction
+		assert.NotNil(t, testRepo.ArgsIn[RunInTransactionMethod][0], "Error in test case %v", n)
+		if test.addUserMethodErr != nil {
+			assert.Nil(t, testRepo.ArgsIn[AddAuditEventMethod][0], "Error in test case %v", n)
+			continue
+		}
+		event, ok := testRepo.ArgsIn[AddAuditEventMethod][0].(AuditEvent)
+		assert.True(t, ok, "Error in test case %v", n)
+		assert.NotEmpty(t, event.ID, "Error in test case %v", n)
+		assert.Equal(t, "admin", event.Actor, "Error in test case %v", n)
+		assert.Equal(t, "REQUEST-ID", event.RequestID, "Error in test case %v", n)
+		assert.Equal(t, USER_ACTION_CREATE_USER, event.Action, "Error in test case %v", n)
+		assert.Equal(t, createdUser.Urn, event.Urn, "Error in test case %v", n)
+		assert.Nil(t, event.Before, "Error in test case %v", n)
+		after, _ := json.Marshal(createdUser)
+		assert.Equal(t, json.RawMessage(after), event.After, "Error in test case %v", n)
+	}
+}
@@ -100,7 +100,12 @@ func (api WorkerAPI) AddOidcProvider(requestInfo RequestInfo, name string, path
 		// OIDC provider doesn't exist in DB
 		case database.AUTH_OIDC_PROVIDER_NOT_FOUND:
 			// Create OIDC provider
-			createdOidcProvider, err := api.AuthOidcRepo.AddOidcProvider(oidcProvider)
+			var createdOidcProvider *OidcProvider
+			err = api.auditedChange(requestInfo, AUTH_OIDC_ACTION_CREATE_PROVIDER, oidcProvider.Urn, nil, func(repos Repositories) (interface{}, error) {
+				var err error
+				createdOidcProvider, err = repos.AuthOidcRepo.AddOidcProvider(oidcProvider)
+				return createdOidcProvider, err
+			})
 
 			// Check if there is an unexpected error in DB
 			if err != nil {
@@ -268,6 +273,12 @@ func (api WorkerAPI) UpdateOidcProvider(requestInfo RequestInfo, oidcProviderNam
 		}
 	}
 
+	// Check that resource wasn't changed since client retrieved it
+	err = CheckPrecondition(requestInfo, oldOidcProvider.Urn, oldOidcProvider.UpdateAt)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if OIDC Provider with "newName" exists
 	targetOidcProvider, err := api.GetOidcProviderByName(requestInfo, newName)
 
@@ -321,15 +332,28 @@ func (api WorkerAPI) UpdateOidcProvider(requestInfo RequestInfo, oidcProviderNam
 	}
 
 	// Update OIDC Provider
-	updatedOidcProvider, err := api.AuthOidcRepo.UpdateOidcProvider(oidcProvider)
+	var updatedOidcProvider *OidcProvider
+	err = api.auditedChange(requestInfo, AUTH_OIDC_ACTION_UPDATE_PROVIDER, oidcProvider.Urn, oldOidcProvider, func(repos Repositories) (interface{}, error) {
+		var err error
+		updatedOidcProvider, err = repos.AuthOidcRepo.UpdateOidcProvider(oidcProvider, preconditionUpdateAt(requestInfo, oldOidcProvider.UpdateAt))
+		return updatedOidcProvider, err
+	})
 
 	// Check unexpected DB error
 	if err != nil {
 		//Transform to DB error
 		dbError := err.(*database.Error)
-		return nil, &Error{
-			Code:    UNKNOWN_API_ERROR,
-			Message: dbError.Message,
+		switch dbError.Code {
+		case database.PRECONDITION_FAILED:
+			return nil, &Error{
+				Code:    PRECONDITION_FAILED_ERROR,
+				Message: fmt.Sprintf("Resource %v has changed", oldOidcProvider.Urn),
+			}
+		default: // Unexpected error
+			return nil, &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: dbError.Message,
+			}
 		}
 	}
 
@@ -358,15 +382,31 @@ func (api WorkerAPI) RemoveOidcProvider(requestInfo RequestInfo, name string) er
 		}
 	}
 
-	err = api.AuthOidcRepo.RemoveOidcProvider(oidcProvider.ID)
+	// Check that resource wasn't changed since client retrieved it
+	err = CheckPrecondition(requestInfo, oidcProvider.Urn, oidcProvider.UpdateAt)
+	if err != nil {
+		return err
+	}
+
+	err = api.auditedChange(requestInfo, AUTH_OIDC_ACTION_DELETE_PROVIDER, oidcProvider.Urn, oidcProvider, func(repos Repositories) (interface{}, error) {
+		return nil, repos.AuthOidcRepo.RemoveOidcProvider(oidcProvider.ID, preconditionUpdateAt(requestInfo, oidcProvider.UpdateAt))
+	})
 
 	// Error handling
 	if err != nil {
 		//Transform to DB error
 		dbError := err.(*database.Error)
-		return &Error{
-			Code:    UNKNOWN_API_ERROR,
-			Message: dbError.Message,
+		switch dbError.Code {
+		case database.PRECONDITION_FAILED:
+			return &Error{
+				Code:    PRECONDITION_FAILED_ERROR,
+				Message: fmt.Sprintf("Resource %v has changed", oidcProvider.Urn),
+			}
+		default: // Unexpected error
+			return &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: dbError.Message,
+			}
 		}
 	}
 
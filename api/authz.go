@@ -2,8 +2,11 @@ package api
 
 import (
 	"fmt"
+	"net"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Tecsisa/foulkon/database"
 )
@@ -14,6 +17,20 @@ type RequestInfo struct {
 	Identifier string
 	Admin      bool
 	RequestID  string
+	Context    RequestContext
+	// Entity tags sent by the client in If-Match header. If it isn't empty, update and remove operations fail when
+	// the current entity tag of the resource doesn't match any of them
+	IfMatch []string
+}
+
+// RequestContext contains the request attributes used to evaluate statement conditions
+type RequestContext struct {
+	// IP address of the client
+	SourceIP string
+	// Time when the request was received. If it is empty, current time is used
+	RequestTime time.Time
+	// Custom attributes sent by the client, available as request:<attribute> condition keys
+	Attributes map[string]string
 }
 
 type EffectRestriction struct {
@@ -26,6 +43,62 @@ type Restrictions struct {
 	AllowedFullUrns    []string `json:"allowedFullUrns,omitempty"`
 	DeniedUrnPrefixes  []string `json:"deniedUrnPrefixes,omitempty"`
 	DeniedFullUrns     []string `json:"deniedFullUrns,omitempty"`
+	// Statements with notResources allow or deny every resource except the ones contained in each list
+	AllowedExceptUrns [][]string `json:"allowedExceptUrns,omitempty"`
+	DeniedExceptUrns  [][]string `json:"deniedExceptUrns,omitempty"`
+}
+
+// ResourceExplanation describes the authorization decision taken for a resource
+type ResourceExplanation struct {
+	Urn      string `json:"urn,omitempty"`
+	Decision string `json:"decision,omitempty"`
+	// Restriction (prefix or full urn) that decided the result. Empty for implicit denies
+	WinningRestriction string           `json:"winningRestriction,omitempty"`
+	Statements         []StatementMatch `json:"statements,omitempty"`
+}
+
+// StatementMatch identifies a policy statement that applies to a resource. StatementIndex is the position of the
+// statement in the policy document, starting at 0
+type StatementMatch struct {
+	PolicyName     string `json:"policyName,omitempty"`
+	PolicyUrn      string `json:"policyUrn,omitempty"`
+	StatementIndex int    `json:"statementIndex"`
+	Effect         string `json:"effect,omitempty"`
+	Resource       string `json:"resource,omitempty"`
+}
+
+// AuthorizedPrincipals contains the users and groups with an effective allow for an action over a resource
+type AuthorizedPrincipals struct {
+	Users  []AuthorizedUser  `json:"users,omitempty"`
+	Groups []AuthorizedGroup `json:"groups,omitempty"`
+}
+
+// AuthorizedUser is a user with the groups and policies that grant it the access
+type AuthorizedUser struct {
+	ExternalID string           `json:"externalId,omitempty"`
+	Urn        string           `json:"urn,omitempty"`
+	Groups     []GroupIdentity  `json:"groups,omitempty"`
+	Policies   []PolicyIdentity `json:"policies,omitempty"`
+}
+
+// AuthorizedGroup is a group with the policies that grant it the access
+type AuthorizedGroup struct {
+	Org      string           `json:"org,omitempty"`
+	Name     string           `json:"name,omitempty"`
+	Urn      string           `json:"urn,omitempty"`
+	Policies []PolicyIdentity `json:"policies,omitempty"`
+}
+
+// AuthorizeItem is an action requested over a list of resources in a batch authorization
+type AuthorizeItem struct {
+	Action    string   `json:"action,omitempty"`
+	Resources []string `json:"resources,omitempty"`
+}
+
+// AuthorizeItemResult contains the allowed resources for an item of a batch authorization
+type AuthorizeItemResult struct {
+	Action           string   `json:"action,omitempty"`
+	ResourcesAllowed []string `json:"resourcesAllowed"`
 }
 
 type ExternalResource struct {
@@ -125,8 +198,127 @@ func (api WorkerAPI) GetAuthorizedOidcProviders(requestInfo RequestInfo, resourc
 
 // GetAuthorizedExternalResources returns the resources where the specified user has the action granted
 func (api WorkerAPI) GetAuthorizedExternalResources(requestInfo RequestInfo, action string, resources []string) ([]string, error) {
+	start := time.Now()
 	// Validate parameters
-	if err := AreValidActions([]string{action}); err != nil {
+	externalResources, err := getExternalResources(action, resources)
+	if err != nil {
+		return nil, err
+	}
+
+	allowedUrns, err := api.getAuthorizedResources(requestInfo, "urn:*", action, externalResources)
+	if err != nil {
+		// User without any restriction for this action is a denial too
+		if apiError, ok := err.(*Error); ok && apiError.Code == UNAUTHORIZED_RESOURCES_ERROR {
+			api.logAuthzDecision(requestInfo, action, resources, []string{}, start)
+		}
+		return nil, err
+	}
+
+	response := []string{}
+	for _, res := range allowedUrns {
+		response = append(response, res.GetUrn())
+	}
+	api.logAuthzDecision(requestInfo, action, resources, response, start)
+
+	if len(response) < 1 {
+		return nil, &Error{
+			Code:    UNAUTHORIZED_RESOURCES_ERROR,
+			Message: fmt.Sprintf("User with externalId %v is not allowed to access to any resource", requestInfo.Identifier),
+		}
+	}
+
+	return response, nil
+}
+
+// GetAuthorizedExternalResourcesBatch returns the allowed resources for each action and resources item. User policies
+// are retrieved once for the whole batch, and items without allowed resources return an empty list
+func (api WorkerAPI) GetAuthorizedExternalResourcesBatch(requestInfo RequestInfo, items []AuthorizeItem) ([]AuthorizeItemResult, error) {
+	start := time.Now()
+	// Validate parameters
+	if len(items) < 1 || len(items) > MAX_AUTHZ_ITEM_NUMBER {
+		return nil, &Error{
+			Code:    INVALID_PARAMETER_ERROR,
+			Message: fmt.Sprintf("Invalid parameter Items. Items can't be empty or bigger than %v elements", MAX_AUTHZ_ITEM_NUMBER),
+		}
+	}
+	itemResources := make([][]Resource, len(items))
+	for i, item := range items {
+		externalResources, err := getExternalResources(item.Action, item.Resources)
+		if err != nil {
+			return nil, err
+		}
+		itemResources[i] = externalResources
+	}
+
+	var policies []Policy
+	if !requestInfo.Admin {
+		var err error
+		policies, err = api.getPoliciesByExternalID(requestInfo.Identifier)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results := []AuthorizeItemResult{}
+	for i, item := range items {
+		allowedResources := itemResources[i]
+		// If user is an admin all resources are allowed without restriction
+		if !requestInfo.Admin {
+			statements := getStatementsByRequestedAction(policies, item.Action, requestInfo.Context)
+			allowedResources = filterResources(allowedResources, getRestrictions(statements, "urn:*", false))
+		}
+		result := AuthorizeItemResult{
+			Action:           item.Action,
+			ResourcesAllowed: []string{},
+		}
+		for _, res := range allowedResources {
+			result.ResourcesAllowed = append(result.ResourcesAllowed, res.GetUrn())
+		}
+		results = append(results, result)
+	}
+	for i, item := range items {
+		api.logAuthzDecision(requestInfo, item.Action, item.Resources, results[i].ResourcesAllowed, start)
+	}
+
+	return results, nil
+}
+
+// ExplainAuthorizedExternalResources returns the authorization decision for each resource, with the statements that apply to it
+func (api WorkerAPI) ExplainAuthorizedExternalResources(requestInfo RequestInfo, action string, resources []string) ([]ResourceExplanation, error) {
+	// Validate parameters
+	if _, err := getExternalResources(action, resources); err != nil {
+		return nil, err
+	}
+
+	// If user is an admin all resources are allowed without restriction
+	if requestInfo.Admin {
+		explanations := []ResourceExplanation{}
+		for _, res := range resources {
+			explanations = append(explanations, ResourceExplanation{
+				Urn:      res,
+				Decision: DECISION_ALLOWED,
+			})
+		}
+		return explanations, nil
+	}
+
+	policies, err := api.getPoliciesByExternalID(requestInfo.Identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return explainResources(resources, action, requestInfo.Context, policies), nil
+}
+
+// SimulateAuthorizedExternalResources returns the authorization decision for each resource that the user would have with
+// the candidate statements. If replace is true, candidate statements replace the user policies, otherwise they are added
+func (api WorkerAPI) SimulateAuthorizedExternalResources(requestInfo RequestInfo, externalID string, statements []Statement, replace bool,
+	action string, resources []string) ([]ResourceExplanation, error) {
+	// Validate parameters
+	if _, err := getExternalResources(action, resources); err != nil {
+		return nil, err
+	}
+	if err := AreValidStatements(&statements); err != nil {
 		// Transform to API error
 		apiError := err.(*Error)
 		return nil, &Error{
@@ -134,29 +326,51 @@ func (api WorkerAPI) GetAuthorizedExternalResources(requestInfo RequestInfo, act
 			Message: apiError.Message,
 		}
 	}
-	if len(resources) < 1 || len(resources) > MAX_RESOURCE_NUMBER {
+
+	// Call repo to retrieve the user
+	user, err := api.GetUserByExternalID(requestInfo, externalID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check restrictions
+	usersFiltered, err := api.GetAuthorizedUsers(requestInfo, user.Urn, USER_ACTION_SIMULATE_AUTHZ, []User{*user})
+	if err != nil {
+		return nil, err
+	}
+	if len(usersFiltered) < 1 {
 		return nil, &Error{
-			Code:    INVALID_PARAMETER_ERROR,
-			Message: fmt.Sprintf("Invalid parameter Resources. Resources can't be empty or bigger than %v elements", MAX_RESOURCE_NUMBER),
+			Code: UNAUTHORIZED_RESOURCES_ERROR,
+			Message: fmt.Sprintf("User with externalId %v is not allowed to access to resource %v",
+				requestInfo.Identifier, user.Urn),
 		}
 	}
-	externalResources := []Resource{}
-	for _, res := range resources {
-		if !isFullUrn(res) {
-			return nil, &Error{
-				Code:    INVALID_PARAMETER_ERROR,
-				Message: fmt.Sprintf("Invalid parameter resource %v. Urn prefixes are not allowed here", res),
-			}
+
+	policies := []Policy{}
+	if !replace {
+		policies, err = api.getPoliciesByExternalID(externalID)
+		if err != nil {
+			return nil, err
 		}
-		if err := AreValidResources([]string{res}, RESOURCE_EXTERNAL); err != nil {
-			// Transform to API error
-			apiError := err.(*Error)
-			return nil, &Error{
-				Code:    INVALID_PARAMETER_ERROR,
-				Message: apiError.Message,
-			}
+	}
+	// Candidate statements are evaluated as a policy without name
+	policies = append(policies, replacePolicyVariables([]Policy{{Statements: &statements}}, user)...)
+
+	return explainResources(resources, action, requestInfo.Context, policies), nil
+}
+
+// GetAuthorizedPrincipals returns the users and groups with an effective allow for the action over the resource. Statements
+// with conditions are evaluated as potential accesses: allows are taken into account and denies are ignored. Only
+// users allowed to list all users, groups and policies can retrieve them
+func (api WorkerAPI) GetAuthorizedPrincipals(requestInfo RequestInfo, action string, resourceUrn string) (*AuthorizedPrincipals, error) {
+	// Validate parameters
+	if err := AreValidActions([]string{action}); err != nil {
+		// Transform to API error
+		apiError := err.(*Error)
+		return nil, &Error{
+			Code:    INVALID_PARAMETER_ERROR,
+			Message: apiError.Message,
 		}
-		externalResources = append(externalResources, ExternalResource{Urn: res})
 	}
 	if strings.Contains(action, "*") {
 		return nil, &Error{
@@ -164,25 +378,104 @@ func (api WorkerAPI) GetAuthorizedExternalResources(requestInfo RequestInfo, act
 			Message: fmt.Sprintf("Invalid parameter action %v. Action parameter can't be a prefix", action),
 		}
 	}
+	// Policy variables are only resolved in statements, so they aren't allowed in the resource
+	if err := AreValidResources([]string{resourceUrn}, RESOURCE_URN); err != nil {
+		// Transform to API error
+		apiError := err.(*Error)
+		return nil, &Error{
+			Code:    INVALID_PARAMETER_ERROR,
+			Message: apiError.Message,
+		}
+	}
 
-	allowedUrns, err := api.getAuthorizedResources(requestInfo, "urn:*", action, externalResources)
+	// Users, groups and policies are read at once, so the relations between them are consistent
+	snapshot, err := api.getAuthzSnapshot()
 	if err != nil {
 		return nil, err
 	}
 
-	if len(allowedUrns) < 1 {
-		return nil, &Error{
-			Code:    UNAUTHORIZED_RESOURCES_ERROR,
-			Message: fmt.Sprintf("User with externalId %v is not allowed to access to any resource", requestInfo.Identifier),
+	// Principals of hidden users or groups would be evaluated without all their policies, so full read
+	// access is required
+	if !requestInfo.Admin {
+		groups, err := api.GetAuthorizedGroups(requestInfo, "*", GROUP_ACTION_LIST_GROUPS, snapshot.Groups)
+		if err != nil {
+			return nil, err
+		}
+		users, err := api.GetAuthorizedUsers(requestInfo, GetUrnPrefix("", RESOURCE_USER, "/"), USER_ACTION_LIST_USERS, snapshot.Users)
+		if err != nil {
+			return nil, err
+		}
+		policies, err := api.GetAuthorizedPolicies(requestInfo, "*", POLICY_ACTION_LIST_POLICIES, snapshot.Policies)
+		if err != nil {
+			return nil, err
+		}
+		if len(groups) < len(snapshot.Groups) || len(users) < len(snapshot.Users) || len(policies) < len(snapshot.Policies) {
+			return nil, &Error{
+				Code: UNAUTHORIZED_RESOURCES_ERROR,
+				Message: fmt.Sprintf("User with externalId %v is not allowed to list all users, groups and policies",
+					requestInfo.Identifier),
+			}
 		}
 	}
 
-	response := []string{}
-	for _, res := range allowedUrns {
-		response = append(response, res.GetUrn())
+	principals := &AuthorizedPrincipals{
+		Users:  []AuthorizedUser{},
+		Groups: []AuthorizedGroup{},
+	}
+	index := newAuthzSnapshotIndex(snapshot)
+
+	// Groups inherit the policies of their ancestors
+	groupsByID := map[string]Group{}
+	for _, group := range snapshot.Groups {
+		groupsByID[group.ID] = group
+		policies := index.getPolicies(index.withAncestors([]string{group.ID}), "")
+		if granting := getGrantingPolicies(policies, action, resourceUrn); len(granting) > 0 {
+			principals.Groups = append(principals.Groups, AuthorizedGroup{
+				Org:      group.Org,
+				Name:     group.Name,
+				Urn:      group.Urn,
+				Policies: getPolicyIdentities(granting),
+			})
+		}
 	}
 
-	return response, nil
+	// Users have the policies of all their groups, including the inherited ones, and the policies
+	// attached directly to them
+	for _, user := range snapshot.Users {
+		groupIDs := index.withAncestors(index.userGroups[user.ID])
+		policies := index.getPolicies(groupIDs, user.ID)
+		granting := getGrantingPolicies(replacePolicyVariables(policies, &user), action, resourceUrn)
+		if len(granting) < 1 {
+			continue
+		}
+
+		// Retrieve groups with any of the granting policies
+		grantingIDs := map[string]bool{}
+		for _, policy := range granting {
+			grantingIDs[policy.ID] = true
+		}
+		grantingGroups := []GroupIdentity{}
+		for _, groupID := range groupIDs {
+			for _, policyID := range index.groupPolicies[groupID] {
+				if grantingIDs[policyID] {
+					grantingGroups = append(grantingGroups, GroupIdentity{
+						Org:  groupsByID[groupID].Org,
+						Name: groupsByID[groupID].Name,
+					})
+					break
+				}
+			}
+		}
+
+		principals.Users = append(principals.Users, AuthorizedUser{
+			ExternalID: user.ExternalID,
+			Urn:        user.Urn,
+			Groups:     grantingGroups,
+			Policies:   getPolicyIdentities(granting),
+		})
+	}
+
+	return principals, nil
 }
 
 // PRIVATE HELPER METHODS
@@ -195,15 +488,23 @@ func (api WorkerAPI) getAuthorizedResources(requestInfo RequestInfo, resourceUrn
 	}
 
 	// Check authorization for this user
-	restrictions, err := api.getRestrictions(requestInfo.Identifier, action, resourceUrn)
+	policies, err := api.getPoliciesByExternalID(requestInfo.Identifier)
 	if err != nil {
 		return nil, err
 	}
 
+	return authorizeResources(requestInfo, policies, resourceUrn, action, resources)
+}
+
+// Filter resources where the policies of the authenticated user grant the action. Throw error if the
+// policies don't grant the action over any resource contained in resourceUrn
+func authorizeResources(requestInfo RequestInfo, policies []Policy, resourceUrn string, action string, resources []Resource) ([]Resource, error) {
+	restrictions := getPolicyRestrictions(policies, requestInfo.Context, action, resourceUrn)
+
 	Log.Debugf("Restrictions: %v", *restrictions)
 
 	// Check if there are some restrictions for this urn resource
-	if len(restrictions.AllowedFullUrns) < 1 && len(restrictions.AllowedUrnPrefixes) < 1 {
+	if len(restrictions.AllowedFullUrns) < 1 && len(restrictions.AllowedUrnPrefixes) < 1 && len(restrictions.AllowedExceptUrns) < 1 {
 		return nil, &Error{
 			Code:    UNAUTHORIZED_RESOURCES_ERROR,
 			Message: fmt.Sprintf("User with externalId %v is not allowed to access to resource %v", requestInfo.Identifier, resourceUrn),
@@ -217,7 +518,34 @@ func (api WorkerAPI) getAuthorizedResources(requestInfo RequestInfo, resourceUrn
 }
 
 // Get restrictions for this action and full resource or prefix resource, attached to this authenticated user
-func (api WorkerAPI) getRestrictions(externalID string, action string, resource string) (*Restrictions, error) {
+func (api WorkerAPI) getRestrictions(requestInfo RequestInfo, action string, resource string) (*Restrictions, error) {
+	policies, err := api.getPoliciesByExternalID(requestInfo.Identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return getPolicyRestrictions(policies, requestInfo.Context, action, resource), nil
+}
+
+// Get restrictions for this action and full resource or prefix resource from the statements of the policies
+// that apply in the request context
+func getPolicyRestrictions(policies []Policy, context RequestContext, action string, resource string) *Restrictions {
+	// Retrieve valid statements
+	statements := getStatementsByRequestedAction(policies, action, context)
+
+	// Retrieve restrictions
+	return getRestrictions(statements, resource, isFullUrn(resource))
+}
+
+// Retrieve policies of the user with this external identifier. Policies are taken from the authorization cache if
+// they are there, otherwise they are stored in it
+func (api WorkerAPI) getPoliciesByExternalID(externalID string) ([]Policy, error) {
+	if policies, ok := api.AuthzCache.get(externalID); ok {
+		return policies, nil
+	}
+	// Changes made while policies are retrieved invalidate them
+	generation := api.AuthzCache.currentGeneration()
+
 	// Get user if exists
 	user, err := api.UserRepo.GetUserByExternalID(externalID)
 
@@ -239,28 +567,110 @@ func (api WorkerAPI) getRestrictions(externalID string, action string, resource
 		}
 	}
 
-	groups, err := api.getGroupsByUser(user.ID)
+	policies, groupIDs, err := api.getPoliciesByUser(user)
 	if err != nil {
 		return nil, err
 	}
+	api.AuthzCache.set(externalID, generation, groupIDs, policies)
 
-	policies, err := api.getPoliciesByGroups(groups)
+	return policies, nil
+}
+
+// Retrieve policies attached to the groups of the user and directly to the user, with their policy
+// variables replaced by the user attributes, and the IDs of the groups of the user, including the inherited ones
+func (api WorkerAPI) getPoliciesByUser(user *User) ([]Policy, []string, error) {
+	policies, groupIDs, err := api.PolicyRepo.GetPoliciesForUser(user.ID)
 	if err != nil {
-		return nil, err
+		//Transform to DB error
+		dbError := err.(*database.Error)
+		return nil, nil, &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: dbError.Message,
+		}
 	}
 
-	// Retrieve valid statements
-	statements := getStatementsByRequestedAction(policies, action)
+	return replacePolicyVariables(policies, user), groupIDs, nil
+}
 
-	// Retrieve restrictions
-	var authResources *Restrictions
-	authResources = getRestrictions(statements, resource, isFullUrn(resource))
+// Retrieve all the ancestors of a slice of groups following the group hierarchy. Each relation returned
+// contains the ancestor group and the date when it was reached. Groups of the slice aren't included
+func (api WorkerAPI) getAncestorGroups(groups []Group) ([]GroupSubgroupRelation, error) {
+	visited := map[string]bool{}
+	for _, group := range groups {
+		visited[group.ID] = true
+	}
+
+	ancestors := []GroupSubgroupRelation{}
+	pending := append([]Group{}, groups...)
+	for len(pending) > 0 {
+		group := pending[0]
+		pending = pending[1:]
 
-	return authResources, nil
+		parents, _, err := api.GroupRepo.GetParentGroups(group.ID, &Filter{})
+		if err != nil {
+			//Transform to DB error
+			dbError := err.(*database.Error)
+			return nil, &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: dbError.Message,
+			}
+		}
+
+		for _, p := range parents {
+			parent := p.GetGroup()
+			if visited[parent.ID] {
+				continue
+			}
+			visited[parent.ID] = true
+			ancestors = append(ancestors, p)
+			pending = append(pending, *parent)
+		}
+	}
+
+	return ancestors, nil
 }
 
-func (api WorkerAPI) getGroupsByUser(userID string) ([]Group, error) {
-	userGroups, _, err := api.UserRepo.GetGroupsByUserID(userID, &Filter{})
+// Retrieve all the descendants of a slice of groups following the group hierarchy. Each relation returned
+// contains the descendant subgroup and the date when it was reached. Groups of the slice aren't included
+func (api WorkerAPI) getDescendantGroups(groups []Group) ([]GroupSubgroupRelation, error) {
+	visited := map[string]bool{}
+	for _, group := range groups {
+		visited[group.ID] = true
+	}
+
+	descendants := []GroupSubgroupRelation{}
+	pending := append([]Group{}, groups...)
+	for len(pending) > 0 {
+		group := pending[0]
+		pending = pending[1:]
+
+		children, _, err := api.GroupRepo.GetSubgroups(group.ID, &Filter{})
+		if err != nil {
+			//Transform to DB error
+			dbError := err.(*database.Error)
+			return nil, &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: dbError.Message,
+			}
+		}
+
+		for _, c := range children {
+			child := c.GetSubgroup()
+			if visited[child.ID] {
+				continue
+			}
+			visited[child.ID] = true
+			descendants = append(descendants, c)
+			pending = append(pending, *child)
+		}
+	}
+
+	return descendants, nil
+}
+
+// Retrieve policies attached directly to a user
+func (api WorkerAPI) getAttachedPoliciesByUser(userID string) ([]Policy, error) {
+	attachedPolicies, _, err := api.UserRepo.GetAttachedPoliciesByUserID(userID, &Filter{})
 	if err != nil {
 		//Transform to DB error
 		dbError := err.(*database.Error)
@@ -270,13 +680,12 @@ func (api WorkerAPI) getGroupsByUser(userID string) ([]Group, error) {
 		}
 	}
 
-	// Transform to Groups
-	groups := []Group{}
-	for _, g := range userGroups {
-		groups = append(groups, *g.GetGroup())
+	policies := []Policy{}
+	for _, policy := range attachedPolicies {
+		policies = append(policies, *policy.GetPolicy())
 	}
 
-	return groups, nil
+	return policies, nil
 }
 
 // Retrieve policies attached to a slice of groups
@@ -309,8 +718,245 @@ func (api WorkerAPI) getPoliciesByGroups(groups []Group) ([]Policy, error) {
 	return policies, nil
 }
 
-// Filter a slice of statements for a specified action
-func getStatementsByRequestedAction(policies []Policy, requestedAction string) []Statement {
+// Replace policy variables in statement resources with the attributes of the user. Policies are copied, so
+// the original statements aren't modified
+func replacePolicyVariables(policies []Policy, user *User) []Policy {
+	if policies == nil {
+		return nil
+	}
+
+	replacer := strings.NewReplacer(
+		POLICY_VARIABLE_USER_EXTERNAL_ID, user.ExternalID,
+		POLICY_VARIABLE_USER_PATH, user.Path,
+		POLICY_VARIABLE_USER_URN, user.Urn,
+	)
+
+	replaceFunc := func(resources []string) []string {
+		if resources == nil {
+			return nil
+		}
+		replaced := []string{}
+		for _, resource := range resources {
+			replaced = append(replaced, replacer.Replace(resource))
+		}
+		return replaced
+	}
+
+	replacedPolicies := []Policy{}
+	for _, policy := range policies {
+		if policy.Statements != nil {
+			statements := []Statement{}
+			for _, statement := range *policy.Statements {
+				statement.Resources = replaceFunc(statement.Resources)
+				statement.NotResources = replaceFunc(statement.NotResources)
+				statements = append(statements, statement)
+			}
+			policy.Statements = &statements
+		}
+		replacedPolicies = append(replacedPolicies, policy)
+	}
+
+	return replacedPolicies
+}
+
+// Retrieve the policies with an allow statement for the resource, only if the policies give an effective allow
+// for the action over the resource
+func getGrantingPolicies(policies []Policy, action string, resource string) []Policy {
+	// Allow statements could apply whatever the conditions are, while deny statements only apply always if
+	// they are unconditional
+	statements := []Statement{}
+	grantingPolicies := []Policy{}
+	// The same policy could be attached to several groups
+	visited := map[string]bool{}
+	for _, policy := range policies {
+		if policy.Statements == nil || visited[policy.ID] {
+			continue
+		}
+		visited[policy.ID] = true
+		granting := false
+		for _, statement := range *policy.Statements {
+			if !isStatementActionContained(action, statement) {
+				continue
+			}
+			if statement.Effect == "allow" {
+				statements = append(statements, statement)
+				if _, ok := getStatementMatchedResource(resource, statement); ok {
+					granting = true
+				}
+			} else if len(statement.Conditions) < 1 {
+				statements = append(statements, statement)
+			}
+		}
+		if granting {
+			grantingPolicies = append(grantingPolicies, policy)
+		}
+	}
+
+	restrictions := getRestrictions(statements, resource, isFullUrn(resource))
+	if !isAllowedResource(ExternalResource{Urn: resource}, *restrictions) {
+		return nil
+	}
+
+	return grantingPolicies
+}
+
+func getPolicyIdentities(policies []Policy) []PolicyIdentity {
+	identities := []PolicyIdentity{}
+	for _, policy := range policies {
+		identities = append(identities, PolicyIdentity{
+			Org:  policy.Org,
+			Name: policy.Name,
+		})
+	}
+	return identities
+}
+
+// Validate the action and resources requested for external resources authorization, and transform them to resources
+func getExternalResources(action string, resources []string) ([]Resource, error) {
+	if err := AreValidActions([]string{action}); err != nil {
+		// Transform to API error
+		apiError := err.(*Error)
+		return nil, &Error{
+			Code:    INVALID_PARAMETER_ERROR,
+			Message: apiError.Message,
+		}
+	}
+	if len(resources) < 1 || len(resources) > MAX_RESOURCE_NUMBER {
+		return nil, &Error{
+			Code:    INVALID_PARAMETER_ERROR,
+			Message: fmt.Sprintf("Invalid parameter Resources. Resources can't be empty or bigger than %v elements", MAX_RESOURCE_NUMBER),
+		}
+	}
+	externalResources := []Resource{}
+	for _, res := range resources {
+		if !isFullUrn(res) {
+			return nil, &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: fmt.Sprintf("Invalid parameter resource %v. Urn prefixes are not allowed here", res),
+			}
+		}
+		if err := AreValidResources([]string{res}, RESOURCE_EXTERNAL); err != nil {
+			// Transform to API error
+			apiError := err.(*Error)
+			return nil, &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: apiError.Message,
+			}
+		}
+		externalResources = append(externalResources, ExternalResource{Urn: res})
+	}
+	if strings.Contains(action, "*") {
+		return nil, &Error{
+			Code:    INVALID_PARAMETER_ERROR,
+			Message: fmt.Sprintf("Invalid parameter action %v. Action parameter can't be a prefix", action),
+		}
+	}
+
+	return externalResources, nil
+}
+
+// Explain the decision for each resource according to the policies
+func explainResources(resources []string, action string, context RequestContext, policies []Policy) []ResourceExplanation {
+	// Retrieve restrictions for all resources
+	statements := getStatementsByRequestedAction(policies, action, context)
+	restrictions := getRestrictions(statements, "urn:*", false)
+
+	explanations := []ResourceExplanation{}
+	for _, res := range resources {
+		explanations = append(explanations, explainResource(res, action, context, policies, restrictions))
+	}
+
+	return explanations
+}
+
+// Explain the decision for a resource using the restrictions computed for the user, and
+// collect the statements from the policies that apply to the resource for this action
+func explainResource(urn string, action string, context RequestContext, policies []Policy, restrictions *Restrictions) ResourceExplanation {
+	explanation := ResourceExplanation{
+		Urn:        urn,
+		Decision:   DECISION_IMPLICIT_DENY,
+		Statements: []StatementMatch{},
+	}
+
+	// Deny restrictions have preference over allow restrictions
+	// Resources matched by restrictions of statements with notResources are reported as winning restriction
+	if restriction, ok := getMatchedRestriction(urn, restrictions.DeniedUrnPrefixes, restrictions.DeniedFullUrns); ok {
+		explanation.Decision = DECISION_DENIED
+		explanation.WinningRestriction = restriction
+	} else if isMatchedByExceptRestrictions(urn, restrictions.DeniedExceptUrns) {
+		explanation.Decision = DECISION_DENIED
+		explanation.WinningRestriction = urn
+	} else if restriction, ok := getMatchedRestriction(urn, restrictions.AllowedUrnPrefixes, restrictions.AllowedFullUrns); ok {
+		explanation.Decision = DECISION_ALLOWED
+		explanation.WinningRestriction = restriction
+	} else if isMatchedByExceptRestrictions(urn, restrictions.AllowedExceptUrns) {
+		explanation.Decision = DECISION_ALLOWED
+		explanation.WinningRestriction = urn
+	}
+
+	for _, policy := range policies {
+		if policy.Statements == nil {
+			continue
+		}
+		for i, statement := range *policy.Statements {
+			if !isStatementActionContained(action, statement) || !isStatementInContext(statement, context) {
+				continue
+			}
+			if restriction, ok := getStatementMatchedResource(urn, statement); ok {
+				explanation.Statements = append(explanation.Statements, StatementMatch{
+					PolicyName:     policy.Name,
+					PolicyUrn:      policy.Urn,
+					StatementIndex: i,
+					Effect:         statement.Effect,
+					Resource:       restriction,
+				})
+			}
+		}
+	}
+
+	return explanation
+}
+
+// Returns the first prefix that contains the resource or the first full urn equal to it
+func getMatchedRestriction(resource string, prefixes []string, fullUrns []string) (string, bool) {
+	for _, prefix := range prefixes {
+		if !isFullUrn(prefix) && isContainedOrEqual(resource, prefix) {
+			return prefix, true
+		}
+	}
+	for _, urn := range fullUrns {
+		if resource == urn {
+			return urn, true
+		}
+	}
+	return "", false
+}
+
+// Returns true if the resource isn't contained in any of the exceptions of some restriction
+func isMatchedByExceptRestrictions(resource string, exceptRestrictions [][]string) bool {
+	for _, exceptions := range exceptRestrictions {
+		if _, ok := getMatchedRestriction(resource, exceptions, exceptions); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the statement resource that contains the resource. If the statement has notResources, the resource
+// itself is returned when none of them contains it
+func getStatementMatchedResource(resource string, statement Statement) (string, bool) {
+	if len(statement.NotResources) > 0 {
+		if _, ok := getMatchedRestriction(resource, statement.NotResources, statement.NotResources); ok {
+			return "", false
+		}
+		return resource, true
+	}
+	return getMatchedRestriction(resource, statement.Resources, statement.Resources)
+}
+
+// Filter a slice of statements for a specified action, discarding statements that don't apply in the
+// request context
+func getStatementsByRequestedAction(policies []Policy, requestedAction string, context RequestContext) []Statement {
 	// Check received policies
 	if policies == nil || len(policies) < 1 {
 		return nil
@@ -319,7 +965,7 @@ func getStatementsByRequestedAction(policies []Policy, requestedAction string) [
 	statements := []Statement{}
 	for _, policy := range policies {
 		for _, statement := range *policy.Statements {
-			if isActionContained(requestedAction, statement.Actions) {
+			if isStatementActionContained(requestedAction, statement) && isStatementInContext(statement, context) {
 				statements = append(statements, statement)
 			}
 		}
@@ -328,6 +974,220 @@ func getStatementsByRequestedAction(policies []Policy, requestedAction string) [
 	return statements
 }
 
+// Flatten the statements of the policies into restrictions per action, sorted by action. Only actions
+// that match the action prefix, or prefixed actions that contain it, are returned. Statements with
+// notActions apply to every other action, so they are returned under the action "*". Statements with
+// conditions aren't evaluated, they are returned as they are
+func getEffectivePermissions(policies []Policy, actionPrefix string) []EffectivePermission {
+	actions := []string{}
+	visited := map[string]bool{}
+	for _, policy := range policies {
+		if policy.Statements == nil {
+			continue
+		}
+		for _, statement := range *policy.Statements {
+			statementActions := statement.Actions
+			if len(statement.NotActions) > 0 {
+				statementActions = []string{"*"}
+			}
+			for _, action := range statementActions {
+				if visited[action] {
+					continue
+				}
+				visited[action] = true
+				if strings.HasPrefix(action, actionPrefix) ||
+					(strings.HasSuffix(action, "*") && strings.HasPrefix(actionPrefix, strings.TrimSuffix(action, "*"))) {
+					actions = append(actions, action)
+				}
+			}
+		}
+	}
+	sort.Strings(actions)
+
+	permissions := []EffectivePermission{}
+	for _, action := range actions {
+		statements := []Statement{}
+		conditionalStatements := []Statement{}
+		for _, policy := range policies {
+			if policy.Statements == nil {
+				continue
+			}
+			for _, statement := range *policy.Statements {
+				if !isStatementActionContained(action, statement) {
+					continue
+				}
+				if len(statement.Conditions) > 0 {
+					conditionalStatements = append(conditionalStatements, statement)
+				} else {
+					statements = append(statements, statement)
+				}
+			}
+		}
+		if len(statements) < 1 && len(conditionalStatements) < 1 {
+			continue
+		}
+		permission := EffectivePermission{
+			Action: action,
+		}
+		if len(statements) > 0 {
+			permission.Restrictions = getRestrictions(statements, "urn:*", false)
+		}
+		if len(conditionalStatements) > 0 {
+			permission.ConditionalStatements = conditionalStatements
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions
+}
+
+// Returns true if the statement applies in the request context. Deny statements apply when a condition key
+// isn't present in the request context, so callers can't skip them by omitting a request attribute
+func isStatementInContext(statement Statement, context RequestContext) bool {
+	return areConditionsSatisfied(statement.Conditions, context, statement.Effect == "deny")
+}
+
+// Returns true if all conditions are satisfied by the request context. For each condition key,
+// it is enough that one of the values matches. Conditions whose key isn't present in the
+// request context are satisfied only if missingSatisfied is true.
+func areConditionsSatisfied(conditions Conditions, context RequestContext, missingSatisfied bool) bool {
+	for operator, keys := range conditions {
+		for key, values := range keys {
+			contextValue, ok := context.getValue(key)
+			if !ok {
+				if missingSatisfied {
+					continue
+				}
+				return false
+			}
+			if !isConditionSatisfied(operator, contextValue, values) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Returns true if the value from request context matches the condition values according to the operator
+func isConditionSatisfied(operator string, contextValue string, values []string) bool {
+	switch operator {
+	case CONDITION_STRING_EQUALS:
+		return isStringContained(contextValue, values)
+	case CONDITION_STRING_NOT_EQUALS:
+		return !isStringContained(contextValue, values)
+	case CONDITION_IP_ADDRESS, CONDITION_NOT_IP_ADDRESS:
+		ip := net.ParseIP(contextValue)
+		if ip == nil {
+			return false
+		}
+		contained := isIPContained(ip, values)
+		if operator == CONDITION_NOT_IP_ADDRESS {
+			return !contained
+		}
+		return contained
+	case CONDITION_DATE_LESS_THAN, CONDITION_DATE_GREATER_THAN:
+		date, err := time.Parse(time.RFC3339, contextValue)
+		if err != nil {
+			return false
+		}
+		for _, value := range values {
+			comparison, ok := compareDate(date, value)
+			if !ok {
+				continue
+			}
+			if (operator == CONDITION_DATE_LESS_THAN && comparison < 0) ||
+				(operator == CONDITION_DATE_GREATER_THAN && comparison > 0) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// Returns the value for a condition key from the request context and if it exists
+func (c RequestContext) getValue(key string) (string, bool) {
+	switch key {
+	case CONDITION_KEY_SOURCE_IP:
+		return c.SourceIP, len(c.SourceIP) > 0
+	case CONDITION_KEY_CURRENT_TIME:
+		requestTime := c.RequestTime
+		if requestTime.IsZero() {
+			requestTime = time.Now()
+		}
+		return requestTime.UTC().Format(time.RFC3339), true
+	default:
+		if strings.HasPrefix(key, CONDITION_KEY_REQUEST_PREFIX) {
+			value, ok := c.Attributes[strings.TrimPrefix(key, CONDITION_KEY_REQUEST_PREFIX)]
+			return value, ok
+		}
+		return "", false
+	}
+}
+
+func isStringContained(value string, values []string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if ip is equal to any of the IP addresses or belongs to any of the CIDR blocks
+func isIPContained(ip net.IP, values []string) bool {
+	for _, value := range values {
+		if _, network, err := net.ParseCIDR(value); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if valueIP := net.ParseIP(value); valueIP != nil && valueIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare date with a condition value, that could be a RFC3339 date or a time of day in format
+// HH:MM (UTC). It returns -1, 0 or 1 if date is before, equal or after the value, and false
+// if the value can't be parsed.
+func compareDate(date time.Time, value string) (int, bool) {
+	if conditionDate, err := time.Parse(time.RFC3339, value); err == nil {
+		switch {
+		case date.Before(conditionDate):
+			return -1, true
+		case date.After(conditionDate):
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+	if clock, err := time.Parse(CONDITION_TIME_OF_DAY_FORMAT, value); err == nil {
+		date = date.UTC()
+		minutes := date.Hour()*60 + date.Minute()
+		conditionMinutes := clock.Hour()*60 + clock.Minute()
+		switch {
+		case minutes < conditionMinutes:
+			return -1, true
+		case minutes > conditionMinutes:
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+// Returns true if the statement applies to the action, that is when the action is contained in the statement
+// actions or, if the statement has notActions, when it isn't contained in them
+func isStatementActionContained(actionRequested string, statement Statement) bool {
+	if len(statement.NotActions) > 0 {
+		return !isActionContained(actionRequested, statement.NotActions)
+	}
+	return isActionContained(actionRequested, statement.Actions)
+}
+
 // Returns true if an action is contained inside a slice of statements
 func isActionContained(actionRequested string, statementActions []string) bool {
 	match := false
@@ -488,6 +1348,33 @@ func (r *Restrictions) insertRestriction(allow bool, fullUrn bool, resource stri
 	}
 }
 
+// Insert restriction for a statement with notResources. If the resource is excluded nothing is inserted, and if
+// there aren't exclusions inside the resource, the resource itself is inserted. Otherwise, the exclusions inside
+// the resource are inserted as an except restriction
+func (r *Restrictions) insertExceptRestriction(allow bool, notResources []string, resource string, resourceIsFullUrn bool) {
+	if _, ok := getMatchedRestriction(resource, notResources, notResources); ok {
+		return
+	}
+
+	exceptions := []string{}
+	if !resourceIsFullUrn {
+		for _, notResource := range notResources {
+			if isContainedOrEqual(notResource, resource) {
+				exceptions = append(exceptions, notResource)
+			}
+		}
+	}
+
+	switch {
+	case len(exceptions) < 1:
+		r.insertRestriction(allow, resourceIsFullUrn, resource)
+	case allow:
+		r.AllowedExceptUrns = append(r.AllowedExceptUrns, exceptions)
+	default:
+		r.DeniedExceptUrns = append(r.DeniedExceptUrns, exceptions)
+	}
+}
+
 // Retrieve restrictions for a specified resource according to the statements
 func getRestrictions(statements []Statement, resource string, resourceIsFullUrn bool) *Restrictions {
 	restrictions := &Restrictions{
@@ -498,6 +1385,10 @@ func getRestrictions(statements []Statement, resource string, resourceIsFullUrn
 	}
 	if statements != nil || len(statements) > 0 {
 		for _, statement := range statements {
+			if len(statement.NotResources) > 0 {
+				restrictions.insertExceptRestriction(statement.Effect == "allow", statement.NotResources, resource, resourceIsFullUrn)
+				continue
+			}
 			for _, statementResource := range statement.Resources {
 				// Append resource to allowed or denied resources, if the resource URN is not a prefix (full URN), and is contained inside the passed resource.
 				// Else, it means that resource is a prefix, so we have to check if the passed resource contains it or vice versa.
@@ -554,6 +1445,9 @@ func isAllowedResource(resource Resource, restrictions Restrictions) bool {
 			}
 		}
 	}
+	if len(restrictions.DeniedExceptUrns) > 0 && !denied {
+		denied = isMatchedByExceptRestrictions(resource.GetUrn(), restrictions.DeniedExceptUrns)
+	}
 
 	// Check allow restrictions
 	if len(restrictions.AllowedUrnPrefixes) > 0 && !denied {
@@ -572,6 +1466,9 @@ func isAllowedResource(resource Resource, restrictions Restrictions) bool {
 			}
 		}
 	}
+	if len(restrictions.AllowedExceptUrns) > 0 && !denied && !allowed {
+		allowed = isMatchedByExceptRestrictions(resource.GetUrn(), restrictions.AllowedExceptUrns)
+	}
 
 	return allowed && !denied
 }
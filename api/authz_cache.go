@@ -0,0 +1,189 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// TYPE DEFINITIONS
+
+// AuthzCache stores the policies of each user, with their policy variables already replaced, so authorization
+// requests don't need to retrieve them from database every time. Entries expire after the configured TTL and they are
+// invalidated when the user, any of its policies or any of its groups, including the inherited ones, changes. Every
+// invalidation increases the cache generation, so policies read from database before an invalidation aren't stored
+// after it. A nil cache is a valid disabled cache
+type AuthzCache struct {
+	ttl time.Duration
+
+	lock       sync.Mutex
+	entries    map[string]*authzCacheEntry
+	generation uint64
+
+	hits          uint64
+	misses        uint64
+	invalidations uint64
+}
+
+// AuthzCacheStats contains the usage statistics of the authorization cache
+type AuthzCacheStats struct {
+	Enabled       bool   `json:"enabled"`
+	TTL           string `json:"ttl,omitempty"`
+	Entries       int    `json:"entries"`
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Invalidations uint64 `json:"invalidations"`
+}
+
+type authzCacheEntry struct {
+	policies  []Policy
+	policyIDs map[string]bool
+	// Groups of the user, including the inherited ones
+	groupIDs map[string]bool
+	expireAt time.Time
+}
+
+// NewAuthzCache creates an authorization cache whose entries live for the TTL specified. If TTL isn't positive
+// the cache is disabled and nil is returned
+func NewAuthzCache(ttl time.Duration) *AuthzCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &AuthzCache{
+		ttl:     ttl,
+		entries: map[string]*authzCacheEntry{},
+	}
+}
+
+// Stats returns the current statistics of the cache
+func (c *AuthzCache) Stats() AuthzCacheStats {
+	if c == nil {
+		return AuthzCacheStats{}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return AuthzCacheStats{
+		Enabled:       true,
+		TTL:           c.ttl.String(),
+		Entries:       len(c.entries),
+		Hits:          c.hits,
+		Misses:        c.misses,
+		Invalidations: c.invalidations,
+	}
+}
+
+// Flush removes all entries from the cache
+func (c *AuthzCache) Flush() {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.invalidations += uint64(len(c.entries))
+	c.entries = map[string]*authzCacheEntry{}
+	c.generation++
+}
+
+// PRIVATE HELPER METHODS
+
+// Retrieve the policies cached for the user with this external identifier, if they haven't expired
+func (c *AuthzCache) get(externalID string) ([]Policy, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[externalID]
+	if ok && time.Now().After(entry.expireAt) {
+		delete(c.entries, externalID)
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	// Limit capacity so appends made by callers don't modify the cached policies
+	return entry.policies[:len(entry.policies):len(entry.policies)], true
+}
+
+// Retrieve the current generation of the cache. It must be read before retrieving from database the policies to store
+func (c *AuthzCache) currentGeneration() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.generation
+}
+
+// Store the policies of the user with this external identifier, with the groups they were retrieved from. Policies
+// are discarded if the cache has been invalidated since the generation they were retrieved in
+func (c *AuthzCache) set(externalID string, generation uint64, groupIDs []string, policies []Policy) {
+	if c == nil {
+		return
+	}
+	entry := &authzCacheEntry{
+		policies:  policies,
+		policyIDs: map[string]bool{},
+		groupIDs:  map[string]bool{},
+		expireAt:  time.Now().Add(c.ttl),
+	}
+	for _, policy := range policies {
+		entry.policyIDs[policy.ID] = true
+	}
+	for _, groupID := range groupIDs {
+		entry.groupIDs[groupID] = true
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.generation != generation {
+		return
+	}
+	c.entries[externalID] = entry
+}
+
+// Remove the entry of the user with this external identifier
+func (c *AuthzCache) invalidateUser(externalID string) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generation++
+	if _, ok := c.entries[externalID]; ok {
+		delete(c.entries, externalID)
+		c.invalidations++
+	}
+}
+
+// Remove the entries that contain this policy
+func (c *AuthzCache) invalidatePolicy(policyID string) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generation++
+	for externalID, entry := range c.entries {
+		if entry.policyIDs[policyID] {
+			delete(c.entries, externalID)
+			c.invalidations++
+		}
+	}
+}
+
+// Remove the entries of the users that belong to this group, directly or through any of its subgroups
+func (c *AuthzCache) invalidateGroup(groupID string) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generation++
+	for externalID, entry := range c.entries {
+		if entry.groupIDs[groupID] {
+			delete(c.entries, externalID)
+			c.invalidations++
+		}
+	}
+}
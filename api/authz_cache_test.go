@@ -0,0 +1,512 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAuthzCache(t *testing.T) {
+	testcases := map[string]struct {
+		ttl           time.Duration
+		expectedStats AuthzCacheStats
+	}{
+		"OkCaseEnabled": {
+			ttl: time.Minute,
+			expectedStats: AuthzCacheStats{
+				Enabled: true,
+				TTL:     "1m0s",
+			},
+		},
+		"OkCaseDisabled": {
+			ttl:           0,
+			expectedStats: AuthzCacheStats{},
+		},
+		"OkCaseNegativeTTL": {
+			ttl:           -time.Second,
+			expectedStats: AuthzCacheStats{},
+		},
+	}
+
+	for n, test := range testcases {
+		cache := NewAuthzCache(test.ttl)
+		assert.Equal(t, test.expectedStats, cache.Stats(), "Error in test case %v", n)
+	}
+}
+
+func TestAuthzCache_Get(t *testing.T) {
+	policies := []Policy{
+		{
+			ID:   "POLICY1",
+			Name: "policy1",
+		},
+	}
+	testcases := map[string]struct {
+		ttl        time.Duration
+		externalID string
+		wait       time.Duration
+		// Expected result
+		expectedPolicies []Policy
+		expectedFound    bool
+		expectedStats    AuthzCacheStats
+	}{
+		"OkCaseHit": {
+			ttl:              time.Minute,
+			externalID:       "user1",
+			expectedPolicies: policies,
+			expectedFound:    true,
+			expectedStats: AuthzCacheStats{
+				Enabled: true,
+				TTL:     "1m0s",
+				Entries: 1,
+				Hits:    1,
+			},
+		},
+		"OkCaseMiss": {
+			ttl:        time.Minute,
+			externalID: "user2",
+			expectedStats: AuthzCacheStats{
+				Enabled: true,
+				TTL:     "1m0s",
+				Entries: 1,
+				Misses:  1,
+			},
+		},
+		"OkCaseExpired": {
+			ttl:        time.Millisecond,
+			externalID: "user1",
+			wait:       5 * time.Millisecond,
+			expectedStats: AuthzCacheStats{
+				Enabled: true,
+				TTL:     "1ms",
+				Misses:  1,
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		cache := NewAuthzCache(test.ttl)
+		cache.set("user1", cache.currentGeneration(), []string{"GROUP1"}, policies)
+		time.Sleep(test.wait)
+
+		receivedPolicies, found := cache.get(test.externalID)
+		assert.Equal(t, test.expectedFound, found, "Error in test case %v", n)
+		assert.Equal(t, test.expectedPolicies, receivedPolicies, "Error in test case %v", n)
+		assert.Equal(t, test.expectedStats, cache.Stats(), "Error in test case %v", n)
+	}
+}
+
+func TestAuthzCache_Invalidate(t *testing.T) {
+	policies := []Policy{
+		{
+			ID:   "POLICY1",
+			Name: "policy1",
+		},
+	}
+	testcases := map[string]struct {
+		invalidate func(cache *AuthzCache)
+		// Expected result
+		expectedEntries       int
+		expectedInvalidations uint64
+	}{
+		"OkCaseInvalidateUser": {
+			invalidate: func(cache *AuthzCache) {
+				cache.invalidateUser("user1")
+			},
+			expectedEntries:       1,
+			expectedInvalidations: 1,
+		},
+		"OkCaseInvalidateUnknownUser": {
+			invalidate: func(cache *AuthzCache) {
+				cache.invalidateUser("user3")
+			},
+			expectedEntries: 2,
+		},
+		"OkCaseInvalidatePolicy": {
+			invalidate: func(cache *AuthzCache) {
+				cache.invalidatePolicy("POLICY1")
+			},
+			expectedEntries:       1,
+			expectedInvalidations: 1,
+		},
+		"OkCaseInvalidateGroup": {
+			invalidate: func(cache *AuthzCache) {
+				cache.invalidateGroup("GROUP1")
+			},
+			expectedEntries:       1,
+			expectedInvalidations: 1,
+		},
+		"OkCaseInvalidateInheritedGroup": {
+			invalidate: func(cache *AuthzCache) {
+				cache.invalidateGroup("PARENT")
+			},
+			expectedEntries:       0,
+			expectedInvalidations: 2,
+		},
+		"OkCaseInvalidateUnknownGroup": {
+			invalidate: func(cache *AuthzCache) {
+				cache.invalidateGroup("GROUP3")
+			},
+			expectedEntries: 2,
+		},
+		"OkCaseFlush": {
+			invalidate: func(cache *AuthzCache) {
+				cache.Flush()
+			},
+			expectedEntries:       0,
+			expectedInvalidations: 2,
+		},
+	}
+
+	for n, test := range testcases {
+		cache := NewAuthzCache(time.Minute)
+		// Both users inherit from group PARENT, which GROUP1 and GROUP2 are subgroups of
+		cache.set("user1", cache.currentGeneration(), []string{"GROUP1", "PARENT"}, policies)
+		cache.set("user2", cache.currentGeneration(), []string{"GROUP2", "PARENT"}, []Policy{})
+
+		test.invalidate(cache)
+		stats := cache.Stats()
+		assert.Equal(t, test.expectedEntries, stats.Entries, "Error in test case %v", n)
+		assert.Equal(t, test.expectedInvalidations, stats.Invalidations, "Error in test case %v", n)
+	}
+}
+
+func TestAuthzCache_SetAfterInvalidation(t *testing.T) {
+	policies := []Policy{
+		{
+			ID:   "POLICY1",
+			Name: "policy1",
+		},
+	}
+	testcases := map[string]struct {
+		// Invalidation made between reading the generation and storing the policies
+		invalidate func(cache *AuthzCache)
+		// Expected result
+		expectedEntries int
+	}{
+		"OkCaseWithoutInvalidation": {
+			invalidate:      func(cache *AuthzCache) {},
+			expectedEntries: 1,
+		},
+		"OkCaseInvalidateUser": {
+			invalidate: func(cache *AuthzCache) {
+				cache.invalidateUser("user1")
+			},
+		},
+		"OkCaseInvalidateOtherUser": {
+			invalidate: func(cache *AuthzCache) {
+				cache.invalidateUser("user2")
+			},
+		},
+		"OkCaseInvalidatePolicy": {
+			invalidate: func(cache *AuthzCache) {
+				cache.invalidatePolicy("POLICY1")
+			},
+		},
+		"OkCaseInvalidateGroup": {
+			invalidate: func(cache *AuthzCache) {
+				cache.invalidateGroup("GROUP1")
+			},
+		},
+		"OkCaseFlush": {
+			invalidate: func(cache *AuthzCache) {
+				cache.Flush()
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		cache := NewAuthzCache(time.Minute)
+		generation := cache.currentGeneration()
+		test.invalidate(cache)
+		cache.set("user1", generation, []string{"GROUP1"}, policies)
+		assert.Equal(t, test.expectedEntries, cache.Stats().Entries, "Error in test case %v", n)
+	}
+}
+
+func TestAuthzCache_Disabled(t *testing.T) {
+	var cache *AuthzCache
+	cache.set("user1", cache.currentGeneration(), []string{"GROUP1"}, []Policy{{ID: "POLICY1"}})
+	policies, found := cache.get("user1")
+	assert.False(t, found, "Error in disabled cache")
+	assert.Nil(t, policies, "Error in disabled cache")
+
+	// Invalidations are ignored
+	cache.invalidateUser("user1")
+	cache.invalidatePolicy("POLICY1")
+	cache.invalidateGroup("GROUP1")
+	cache.Flush()
+	assert.Equal(t, AuthzCacheStats{}, cache.Stats(), "Error in disabled cache")
+}
+
+func TestGetAuthorizedExternalResourcesWithAuthzCache(t *testing.T) {
+	user := &User{
+		ID:         "USER-ID",
+		ExternalID: "user1",
+		Urn:        CreateUrn("", RESOURCE_USER, "/path/", "user1"),
+	}
+	group := &Group{
+		ID:   "GROUP-USER-ID",
+		Name: "groupUser",
+		Org:  "example",
+		Urn:  CreateUrn("example", RESOURCE_GROUP, "/path/", "groupUser"),
+	}
+	policy := &Policy{
+		ID:   "POLICY-USER-ID",
+		Name: "policyUser",
+		Org:  "example",
+		Urn:  CreateUrn("example", RESOURCE_POLICY, "/path/", "policyUser"),
+		Statements: &[]Statement{
+			{
+				Effect: "allow",
+				Actions: []string{
+					POLICY_ACTION_GET_POLICY,
+				},
+				Resources: []string{
+					GetUrnPrefix("example", RESOURCE_POLICY, "/path/"),
+				},
+			},
+		},
+	}
+	requestInfo := RequestInfo{
+		Identifier: "user1",
+		Admin:      false,
+	}
+	adminRequestInfo := RequestInfo{
+		Identifier: "admin",
+		Admin:      true,
+	}
+	resourceUrns := []string{
+		CreateUrn("example", RESOURCE_POLICY, "/path/", "policy1"),
+	}
+	otherGroup := &Group{
+		ID:   "GROUP-OTHER-ID",
+		Name: "groupOther",
+		Org:  "example",
+		Urn:  CreateUrn("example", RESOURCE_GROUP, "/path/", "groupOther"),
+	}
+	testcases := map[string]struct {
+		// Group returned by name in the change, groupUser if nil
+		changedGroup *Group
+		// Change done after the first authorization
+		change func(api *WorkerAPI) error
+		// Expected result of the second authorization
+		expectedResources []string
+		wantError         error
+		expectedStats     AuthzCacheStats
+	}{
+		"OkCaseCached": {
+			change: func(api *WorkerAPI) error {
+				return nil
+			},
+			expectedResources: resourceUrns,
+			expectedStats: AuthzCacheStats{
+				Enabled: true,
+				TTL:     "1m0s",
+				Entries: 1,
+				Hits:    1,
+				Misses:  1,
+			},
+		},
+		"OkCaseInvalidatedByRemoveUser": {
+			change: func(api *WorkerAPI) error {
+				return api.RemoveUser(adminRequestInfo, "user1")
+			},
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "User with externalId user1 is not allowed to access to resource urn:*",
+			},
+			expectedStats: AuthzCacheStats{
+				Enabled:       true,
+				TTL:           "1m0s",
+				Entries:       1,
+				Misses:        2,
+				Invalidations: 1,
+			},
+		},
+		"OkCaseInvalidatedByRemoveGroup": {
+			change: func(api *WorkerAPI) error {
+				return api.RemoveGroup(adminRequestInfo, "example", "groupUser")
+			},
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "User with externalId user1 is not allowed to access to resource urn:*",
+			},
+			expectedStats: AuthzCacheStats{
+				Enabled:       true,
+				TTL:           "1m0s",
+				Entries:       1,
+				Misses:        2,
+				Invalidations: 1,
+			},
+		},
+		"OkCaseInvalidatedByDetachGroupPolicy": {
+			change: func(api *WorkerAPI) error {
+				return api.DetachPolicyToGroup(adminRequestInfo, "example", "groupUser", "policyUser")
+			},
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "User with externalId user1 is not allowed to access to resource urn:*",
+			},
+			expectedStats: AuthzCacheStats{
+				Enabled:       true,
+				TTL:           "1m0s",
+				Entries:       1,
+				Misses:        2,
+				Invalidations: 1,
+			},
+		},
+		"OkCaseInvalidatedByRemoveSubgroup": {
+			change: func(api *WorkerAPI) error {
+				return api.RemoveSubgroup(adminRequestInfo, "example", "groupParent", "groupUser")
+			},
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "User with externalId user1 is not allowed to access to resource urn:*",
+			},
+			expectedStats: AuthzCacheStats{
+				Enabled:       true,
+				TTL:           "1m0s",
+				Entries:       1,
+				Misses:        2,
+				Invalidations: 1,
+			},
+		},
+		"OkCaseNotInvalidatedByOtherGroup": {
+			changedGroup: otherGroup,
+			change: func(api *WorkerAPI) error {
+				return api.DetachPolicyToGroup(adminRequestInfo, "example", "groupOther", "policyUser")
+			},
+			expectedResources: resourceUrns,
+			expectedStats: AuthzCacheStats{
+				Enabled: true,
+				TTL:     "1m0s",
+				Entries: 1,
+				Hits:    1,
+				Misses:  1,
+			},
+		},
+		"OkCaseInvalidatedByRemovePolicy": {
+			change: func(api *WorkerAPI) error {
+				return api.RemovePolicy(adminRequestInfo, "example", "policyUser")
+			},
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "User with externalId user1 is not allowed to access to resource urn:*",
+			},
+			expectedStats: AuthzCacheStats{
+				Enabled:       true,
+				TTL:           "1m0s",
+				Entries:       1,
+				Misses:        2,
+				Invalidations: 1,
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+		testAPI.AuthzCache = NewAuthzCache(time.Minute)
+
+		testRepo.ArgsOut[GetUserByExternalIDMethod][0] = user
+		testRepo.ArgsOut[GetGroupByNameMethod][0] = group
+		testRepo.ArgsOut[GetPolicyByNameMethod][0] = policy
+		testRepo.ArgsOut[GetGroupsByUserIDMethod][0] = []TestUserGroupRelation{
+			{
+				Group: group,
+			},
+		}
+		testRepo.ArgsOut[GetAttachedPoliciesMethod][0] = []TestPolicyGroupRelation{
+			{
+				Policy: policy,
+			},
+		}
+
+		// First authorization stores user policies in cache
+		resources, err := testAPI.GetAuthorizedExternalResources(requestInfo, POLICY_ACTION_GET_POLICY, resourceUrns)
+		checkMethodResponse(t, n, nil, err, resourceUrns, resources)
+
+		// Remove the policy from database and make the change
+		testRepo.ArgsOut[GetAttachedPoliciesMethod][0] = nil
+		testRepo.ArgsOut[IsAttachedToGroupMethod][0] = true
+		testRepo.ArgsOut[IsSubgroupOfGroupMethod][0] = true
+		if test.changedGroup != nil {
+			testRepo.ArgsOut[GetGroupByNameMethod][0] = test.changedGroup
+		}
+		err = test.change(testAPI)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		resources, err = testAPI.GetAuthorizedExternalResources(requestInfo, POLICY_ACTION_GET_POLICY, resourceUrns)
+		checkMethodResponse(t, n, test.wantError, err, test.expectedResources, resources)
+		assert.Equal(t, test.expectedStats, testAPI.AuthzCache.Stats(), "Error in test case %v", n)
+	}
+}
+
+func TestGetAuthorizedExternalResourcesWithAuthzCacheInvalidatedDuringFill(t *testing.T) {
+	user := &User{
+		ID:         "USER-ID",
+		ExternalID: "user1",
+		Urn:        CreateUrn("", RESOURCE_USER, "/path/", "user1"),
+	}
+	policy := Policy{
+		ID:   "POLICY-USER-ID",
+		Name: "policyUser",
+		Org:  "example",
+		Urn:  CreateUrn("example", RESOURCE_POLICY, "/path/", "policyUser"),
+		Statements: &[]Statement{
+			{
+				Effect: "allow",
+				Actions: []string{
+					POLICY_ACTION_GET_POLICY,
+				},
+				Resources: []string{
+					GetUrnPrefix("example", RESOURCE_POLICY, "/path/"),
+				},
+			},
+		},
+	}
+	requestInfo := RequestInfo{
+		Identifier: "user1",
+		Admin:      false,
+	}
+	resourceUrns := []string{
+		CreateUrn("example", RESOURCE_POLICY, "/path/", "policy1"),
+	}
+
+	testRepo := makeTestRepo()
+	testAPI := makeTestAPI(testRepo)
+	testAPI.AuthzCache = NewAuthzCache(time.Minute)
+
+	testRepo.ArgsOut[GetUserByExternalIDMethod][0] = user
+	// The policy is removed while its statements are being read, so they are stale when the read finishes
+	fills := 0
+	testRepo.SpecialFuncs[GetPoliciesForUserMethod] = func(userID string) ([]Policy, []string, error) {
+		fills++
+		if fills == 1 {
+			testAPI.AuthzCache.invalidatePolicy(policy.ID)
+		}
+		return []Policy{policy}, []string{}, nil
+	}
+
+	// Policies read before the invalidation aren't stored
+	resources, err := testAPI.GetAuthorizedExternalResources(requestInfo, POLICY_ACTION_GET_POLICY, resourceUrns)
+	checkMethodResponse(t, "FillInvalidated", nil, err, resourceUrns, resources)
+	assert.Equal(t, AuthzCacheStats{
+		Enabled: true,
+		TTL:     "1m0s",
+		Misses:  1,
+	}, testAPI.AuthzCache.Stats(), "Error in fill invalidated")
+
+	// Next authorization reads policies again and stores them
+	resources, err = testAPI.GetAuthorizedExternalResources(requestInfo, POLICY_ACTION_GET_POLICY, resourceUrns)
+	checkMethodResponse(t, "FillAfterInvalidation", nil, err, resourceUrns, resources)
+	assert.Equal(t, 2, fills, "Error in fill after invalidation")
+	assert.Equal(t, AuthzCacheStats{
+		Enabled: true,
+		TTL:     "1m0s",
+		Entries: 1,
+		Misses:  2,
+	}, testAPI.AuthzCache.Stats(), "Error in fill after invalidation")
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/satori/go.uuid"
+)
+
+const (
+	// Sources of authorization decisions
+	AUTHZ_DECISION_SOURCE_WORKER = "worker"
+	AUTHZ_DECISION_SOURCE_PROXY  = "proxy"
+
+	// Maximum number of decisions written to the sink at once
+	AUTHZ_DECISION_BATCH_SIZE = 100
+)
+
+// TYPE DEFINITIONS
+
+// AuthzDecision records the resources allowed to a user when an action over some resources was requested
+type AuthzDecision struct {
+	ID     string `json:"id,omitempty"`
+	Source string `json:"source,omitempty"`
+	// Request identifier. Decisions made by proxy also have the identifier of the worker request
+	RequestID       string   `json:"requestId,omitempty"`
+	WorkerRequestID string   `json:"workerRequestId,omitempty"`
+	User            string   `json:"user,omitempty"`
+	Action          string   `json:"action,omitempty"`
+	Requested       []string `json:"requested"`
+	Allowed         []string `json:"allowed"`
+	// Time spent to make the decision, in nanoseconds
+	Latency  time.Duration `json:"latency"`
+	CreateAt time.Time     `json:"createAt,omitempty"`
+}
+
+// AuthzDecisionSink stores authorization decisions
+type AuthzDecisionSink interface {
+	AddAuthzDecisions(decisions []AuthzDecision) error
+}
+
+// AuthzDecisionLogger writes authorization decisions to a sink asynchronously. Only a sample of the decisions is
+// logged, according to the configured rate, and they are buffered and written in batches. When the buffer is full
+// new decisions are dropped, so authorization requests never wait for the sink. A nil logger is a valid disabled logger
+type AuthzDecisionLogger struct {
+	// Decisions dropped because buffer was full. First field to keep 64-bit alignment for atomic operations
+	dropped uint64
+
+	sink       AuthzDecisionSink
+	sampleRate float64
+
+	lock      sync.RWMutex
+	closed    bool
+	decisions chan AuthzDecision
+	done      chan struct{}
+}
+
+// FileAuthzDecisionSink writes authorization decisions to a file as JSON lines
+type FileAuthzDecisionSink struct {
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// NewAuthzDecisionLogger creates a logger that writes the decisions to the sink every flush interval or when a batch
+// is completed. Decisions are sampled with a rate between 0 and 1. If sink is nil or rate isn't positive the logger
+// is disabled and nil is returned
+func NewAuthzDecisionLogger(sink AuthzDecisionSink, sampleRate float64, bufferSize int, flushInterval time.Duration) *AuthzDecisionLogger {
+	if sink == nil || sampleRate <= 0 {
+		return nil
+	}
+	l := &AuthzDecisionLogger{
+		sink:       sink,
+		sampleRate: sampleRate,
+		decisions:  make(chan AuthzDecision, bufferSize),
+		done:       make(chan struct{}),
+	}
+	go l.run(flushInterval)
+	return l
+}
+
+// Log queues a decision to be written if it is sampled
+func (l *AuthzDecisionLogger) Log(decision AuthzDecision) {
+	if l == nil || (l.sampleRate < 1 && rand.Float64() >= l.sampleRate) {
+		return
+	}
+	if decision.ID == "" {
+		decision.ID = uuid.NewV4().String()
+	}
+	if decision.CreateAt.IsZero() {
+		decision.CreateAt = time.Now().UTC()
+	}
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	if l.closed {
+		return
+	}
+	select {
+	case l.decisions <- decision:
+	default:
+		atomic.AddUint64(&l.dropped, 1)
+	}
+}
+
+// Close writes the pending decisions and stops the logger. The sink is closed if it implements io.Closer
+func (l *AuthzDecisionLogger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.lock.Lock()
+	if l.closed {
+		l.lock.Unlock()
+		return nil
+	}
+	l.closed = true
+	close(l.decisions)
+	l.lock.Unlock()
+
+	<-l.done
+	if closer, ok := l.sink.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// NewFileAuthzDecisionSink creates a sink that appends decisions to the file in this path
+func NewFileAuthzDecisionSink(path string) (*FileAuthzDecisionSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuthzDecisionSink{
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}, nil
+}
+
+// AddAuthzDecisions writes a JSON line for each decision
+func (s *FileAuthzDecisionSink) AddAuthzDecisions(decisions []AuthzDecision) error {
+	for _, decision := range decisions {
+		if err := s.encoder.Encode(decision); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the file
+func (s *FileAuthzDecisionSink) Close() error {
+	return s.file.Close()
+}
+
+// PRIVATE HELPER METHODS
+
+// Read decisions from buffer and write them to sink in batches until logger is closed
+func (l *AuthzDecisionLogger) run(flushInterval time.Duration) {
+	defer close(l.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := []AuthzDecision{}
+	for {
+		select {
+		case decision, ok := <-l.decisions:
+			if !ok {
+				l.write(batch)
+				return
+			}
+			batch = append(batch, decision)
+			if len(batch) >= AUTHZ_DECISION_BATCH_SIZE {
+				l.write(batch)
+				batch = []AuthzDecision{}
+			}
+		case <-ticker.C:
+			l.write(batch)
+			batch = []AuthzDecision{}
+		}
+	}
+}
+
+// Write a batch of decisions to sink, logging the errors and the decisions dropped since last write
+func (l *AuthzDecisionLogger) write(batch []AuthzDecision) {
+	if dropped := atomic.SwapUint64(&l.dropped, 0); dropped > 0 {
+		Log.Warnf("Authorization decision buffer full, %v decisions dropped", dropped)
+	}
+
+	if len(batch) < 1 {
+		return
+	}
+	if err := l.sink.AddAuthzDecisions(batch); err != nil {
+		Log.Errorf("Unable to store %v authorization decisions: %v", len(batch), err)
+	}
+}
+
+// Log the decision made by worker for this user, action and requested resources
+func (api WorkerAPI) logAuthzDecision(requestInfo RequestInfo, action string, requested []string, allowed []string, start time.Time) {
+	if api.AuthzLogger == nil {
+		return
+	}
+	api.AuthzLogger.Log(AuthzDecision{
+		Source:    AUTHZ_DECISION_SOURCE_WORKER,
+		RequestID: requestInfo.RequestID,
+		User:      requestInfo.Identifier,
+		Action:    action,
+		Requested: requested,
+		Allowed:   allowed,
+		Latency:   time.Since(start),
+	})
+}
@@ -0,0 +1,335 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// Sink that stores decisions in memory
+type testAuthzDecisionSink struct {
+	lock      sync.Mutex
+	decisions []AuthzDecision
+	writes    int
+	err       error
+	// Writes wait until this channel is closed if it isn't nil
+	blocked chan struct{}
+}
+
+func (s *testAuthzDecisionSink) AddAuthzDecisions(decisions []AuthzDecision) error {
+	if s.blocked != nil {
+		<-s.blocked
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.writes++
+	if s.err != nil {
+		return s.err
+	}
+	s.decisions = append(s.decisions, decisions...)
+	return nil
+}
+
+func TestNewAuthzDecisionLogger(t *testing.T) {
+	testcases := map[string]struct {
+		sink       AuthzDecisionSink
+		sampleRate float64
+		// Expected result
+		enabled bool
+	}{
+		"OkCase": {
+			sink:       &testAuthzDecisionSink{},
+			sampleRate: 0.5,
+			enabled:    true,
+		},
+		"OkCaseDisabledWithoutSink": {
+			sampleRate: 1,
+		},
+		"OkCaseDisabledWithZeroRate": {
+			sink: &testAuthzDecisionSink{},
+		},
+	}
+
+	for n, test := range testcases {
+		logger := NewAuthzDecisionLogger(test.sink, test.sampleRate, 10, time.Hour)
+		assert.Equal(t, test.enabled, logger != nil, "Error in test case %v", n)
+		assert.Nil(t, logger.Close(), "Error in test case %v", n)
+	}
+}
+
+func TestAuthzDecisionLogger_Log(t *testing.T) {
+	testcases := map[string]struct {
+		sampleRate float64
+		bufferSize int
+		decisions  int
+		blocked    bool
+		sinkErr    error
+		// Expected result
+		expectedDecisions int
+		expectedWrites    int
+	}{
+		"OkCase": {
+			sampleRate:        1,
+			bufferSize:        10,
+			decisions:         5,
+			expectedDecisions: 5,
+			expectedWrites:    1,
+		},
+		"OkCaseSeveralBatches": {
+			sampleRate:        1,
+			bufferSize:        AUTHZ_DECISION_BATCH_SIZE * 3,
+			decisions:         AUTHZ_DECISION_BATCH_SIZE*2 + 1,
+			expectedDecisions: AUTHZ_DECISION_BATCH_SIZE*2 + 1,
+			expectedWrites:    3,
+		},
+		"OkCaseBufferFull": {
+			sampleRate: 1,
+			bufferSize: 2,
+			decisions:  AUTHZ_DECISION_BATCH_SIZE + 10,
+			blocked:    true,
+		},
+		"OkCaseSinkError": {
+			sampleRate:     1,
+			bufferSize:     10,
+			decisions:      5,
+			sinkErr:        errors.New("Error"),
+			expectedWrites: 1,
+		},
+	}
+
+	// Logger used to report dropped decisions and sink errors
+	Log = &log.Logger{
+		Out:       bytes.NewBuffer([]byte{}),
+		Formatter: &log.TextFormatter{},
+		Hooks:     make(log.LevelHooks),
+		Level:     log.DebugLevel,
+	}
+
+	for n, test := range testcases {
+		sink := &testAuthzDecisionSink{
+			err: test.sinkErr,
+		}
+		if test.blocked {
+			sink.blocked = make(chan struct{})
+		}
+		logger := NewAuthzDecisionLogger(sink, test.sampleRate, test.bufferSize, time.Hour)
+		for i := 0; i < test.decisions; i++ {
+			logger.Log(AuthzDecision{
+				User:      "user1",
+				Action:    "example:get",
+				Requested: []string{"urn:ews:example:instance1:resource/1"},
+				Allowed:   []string{},
+			})
+		}
+		if test.blocked {
+			close(sink.blocked)
+		}
+		assert.Nil(t, logger.Close(), "Error in test case %v", n)
+
+		if test.blocked {
+			// Some decisions were dropped
+			assert.True(t, len(sink.decisions) < test.decisions, "Error in test case %v", n)
+			continue
+		}
+		assert.Equal(t, test.expectedDecisions, len(sink.decisions), "Error in test case %v", n)
+		assert.Equal(t, test.expectedWrites, sink.writes, "Error in test case %v", n)
+		for _, d := range sink.decisions {
+			assert.NotEmpty(t, d.ID, "Error in test case %v", n)
+			assert.False(t, d.CreateAt.IsZero(), "Error in test case %v", n)
+		}
+
+		// Decisions logged after close are discarded
+		logger.Log(AuthzDecision{User: "user1"})
+		assert.Equal(t, test.expectedDecisions, len(sink.decisions), "Error in test case %v", n)
+	}
+}
+
+func TestAuthzDecisionLogger_LogSampling(t *testing.T) {
+	sink := &testAuthzDecisionSink{}
+	logger := NewAuthzDecisionLogger(sink, 0.5, 1000, time.Hour)
+	for i := 0; i < 1000; i++ {
+		logger.Log(AuthzDecision{User: "user1"})
+	}
+	assert.Nil(t, logger.Close())
+
+	// Around half of the decisions are logged
+	assert.True(t, len(sink.decisions) > 300 && len(sink.decisions) < 700, "Unexpected decisions logged %v", len(sink.decisions))
+}
+
+func TestAuthzDecisionLogger_LogFlushInterval(t *testing.T) {
+	sink := &testAuthzDecisionSink{}
+	logger := NewAuthzDecisionLogger(sink, 1, 10, 10*time.Millisecond)
+	defer logger.Close()
+	logger.Log(AuthzDecision{User: "user1"})
+
+	// Decision is written without closing logger
+	written := false
+	for i := 0; i < 100 && !written; i++ {
+		time.Sleep(10 * time.Millisecond)
+		sink.lock.Lock()
+		written = len(sink.decisions) == 1
+		sink.lock.Unlock()
+	}
+	assert.True(t, written, "Decision not written after flush interval")
+}
+
+func TestFileAuthzDecisionSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "foulkon")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "authz.log")
+
+	now := time.Now().UTC()
+	decisions := []AuthzDecision{
+		{
+			ID:        "DecisionID1",
+			Source:    AUTHZ_DECISION_SOURCE_WORKER,
+			RequestID: "RequestID1",
+			User:      "user1",
+			Action:    "example:get",
+			Requested: []string{"urn:ews:example:instance1:resource/1", "urn:ews:example:instance1:resource/2"},
+			Allowed:   []string{"urn:ews:example:instance1:resource/1"},
+			Latency:   time.Millisecond,
+			CreateAt:  now,
+		},
+		{
+			ID:        "DecisionID2",
+			Source:    AUTHZ_DECISION_SOURCE_WORKER,
+			RequestID: "RequestID2",
+			User:      "user2",
+			Action:    "example:get",
+			Requested: []string{"urn:ews:example:instance1:resource/2"},
+			Allowed:   []string{},
+			Latency:   time.Millisecond,
+			CreateAt:  now,
+		},
+	}
+
+	// Decisions are appended to existing file
+	for _, d := range decisions {
+		sink, err := NewFileAuthzDecisionSink(path)
+		assert.Nil(t, err)
+		assert.Nil(t, sink.AddAuthzDecisions([]AuthzDecision{d}))
+		assert.Nil(t, sink.Close())
+	}
+
+	content, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Equal(t, len(decisions), len(lines))
+	for i, line := range lines {
+		decision := AuthzDecision{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &decision))
+		assert.Equal(t, decisions[i], decision)
+	}
+
+	// Invalid path
+	_, err = NewFileAuthzDecisionSink(filepath.Join(dir, "unknown", "authz.log"))
+	assert.NotNil(t, err)
+}
+
+func TestWorkerAPI_GetAuthorizedExternalResourcesDecisionLog(t *testing.T) {
+	testcases := map[string]struct {
+		// API method args
+		requestInfo RequestInfo
+		action      string
+		resources   []string
+		// Expected result
+		expectedDecisions []AuthzDecision
+		// Manager Results
+		getPoliciesForUserResult []Policy
+	}{
+		"OkCaseAllowed": {
+			requestInfo: RequestInfo{
+				Identifier: "user1",
+				RequestID:  "RequestID",
+			},
+			action:    "example:get",
+			resources: []string{"urn:ews:example:instance1:resource/1", "urn:ews:example:instance1:resource/2"},
+			expectedDecisions: []AuthzDecision{
+				{
+					Source:    AUTHZ_DECISION_SOURCE_WORKER,
+					RequestID: "RequestID",
+					User:      "user1",
+					Action:    "example:get",
+					Requested: []string{"urn:ews:example:instance1:resource/1", "urn:ews:example:instance1:resource/2"},
+					Allowed:   []string{"urn:ews:example:instance1:resource/1"},
+				},
+			},
+			getPoliciesForUserResult: []Policy{
+				{
+					ID: "POLICY-USER-ID",
+					Statements: &[]Statement{
+						{
+							Effect:    "allow",
+							Actions:   []string{"example:get"},
+							Resources: []string{"urn:ews:example:instance1:resource/1"},
+						},
+					},
+				},
+			},
+		},
+		"OkCaseDenied": {
+			requestInfo: RequestInfo{
+				Identifier: "user1",
+				RequestID:  "RequestID",
+			},
+			action:    "example:get",
+			resources: []string{"urn:ews:example:instance1:resource/2"},
+			expectedDecisions: []AuthzDecision{
+				{
+					Source:    AUTHZ_DECISION_SOURCE_WORKER,
+					RequestID: "RequestID",
+					User:      "user1",
+					Action:    "example:get",
+					Requested: []string{"urn:ews:example:instance1:resource/2"},
+					Allowed:   []string{},
+				},
+			},
+			getPoliciesForUserResult: []Policy{},
+		},
+		"OkCaseInvalidRequestNotLogged": {
+			requestInfo: RequestInfo{
+				Identifier: "user1",
+				RequestID:  "RequestID",
+			},
+			action:    "invalid*action",
+			resources: []string{"urn:ews:example:instance1:resource/2"},
+		},
+	}
+
+	for n, test := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+		sink := &testAuthzDecisionSink{}
+		testAPI.AuthzLogger = NewAuthzDecisionLogger(sink, 1, 10, time.Hour)
+
+		testRepo.ArgsOut[GetUserByExternalIDMethod][0] = &User{
+			ID:         "USER-ID",
+			ExternalID: test.requestInfo.Identifier,
+		}
+		testRepo.ArgsOut[GetPoliciesForUserMethod][0] = test.getPoliciesForUserResult
+
+		testAPI.GetAuthorizedExternalResources(test.requestInfo, test.action, test.resources)
+		assert.Nil(t, testAPI.AuthzLogger.Close(), "Error in test case %v", n)
+
+		// Ignore generated fields
+		for i := range sink.decisions {
+			assert.NotEmpty(t, sink.decisions[i].ID, "Error in test case %v", n)
+			sink.decisions[i].ID = ""
+			sink.decisions[i].Latency = 0
+			sink.decisions[i].CreateAt = time.Time{}
+		}
+		assert.Equal(t, test.expectedDecisions, sink.decisions, "Error in test case %v", n)
+	}
+}
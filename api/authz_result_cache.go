@@ -0,0 +1,159 @@
+package api
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// TYPE DEFINITIONS
+
+// AuthzResultCache stores the results of authorization checks, so repeated checks of the same identity, action and
+// urn aren't evaluated every time. Allowed and denied results expire after their own TTL, and the least recently used
+// entry is evicted when the cache is full. A nil cache is a valid disabled cache
+type AuthzResultCache struct {
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	lock sync.Mutex
+	// Entries ordered from most to least recently used
+	order   *list.List
+	entries map[string]*list.Element
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// AuthzResultCacheStats contains the usage statistics of the authorization result cache
+type AuthzResultCacheStats struct {
+	Enabled     bool   `json:"enabled"`
+	Size        int    `json:"size,omitempty"`
+	TTL         string `json:"ttl,omitempty"`
+	NegativeTTL string `json:"negativeTtl,omitempty"`
+	Entries     int    `json:"entries"`
+	Hits        uint64 `json:"hits"`
+	Misses      uint64 `json:"misses"`
+	Evictions   uint64 `json:"evictions"`
+}
+
+type authzResultCacheEntry struct {
+	key string
+	// User the result was decided for, empty if it isn't known
+	user string
+	// Error of a denied result, nil if result was allowed
+	err      *Error
+	expireAt time.Time
+}
+
+// NewAuthzResultCache creates an authorization result cache with room for size entries. Allowed results live for ttl
+// and denied ones for negativeTTL, and they aren't stored if their TTL isn't positive. If size isn't positive or
+// neither TTL is, the cache is disabled and nil is returned
+func NewAuthzResultCache(size int, ttl time.Duration, negativeTTL time.Duration) *AuthzResultCache {
+	if size <= 0 || (ttl <= 0 && negativeTTL <= 0) {
+		return nil
+	}
+	return &AuthzResultCache{
+		size:        size,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		order:       list.New(),
+		entries:     map[string]*list.Element{},
+	}
+}
+
+// Get returns the result cached with key and the user it was decided for, if it hasn't expired. Error is nil if
+// result was allowed
+func (c *AuthzResultCache) Get(key string) (string, *Error, bool) {
+	if c == nil {
+		return "", nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	element, ok := c.entries[key]
+	if ok && time.Now().After(element.Value.(*authzResultCacheEntry).expireAt) {
+		c.remove(element)
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		return "", nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(element)
+	entry := element.Value.(*authzResultCacheEntry)
+	return entry.user, entry.err, true
+}
+
+// Set stores a result with key and the user it was decided for, with a nil error if it was allowed. Least recently
+// used entry is evicted if the cache is full
+func (c *AuthzResultCache) Set(key string, user string, err *Error) {
+	if c == nil {
+		return
+	}
+	ttl := c.ttl
+	if err != nil {
+		ttl = c.negativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	entry := &authzResultCacheEntry{
+		key:      key,
+		user:     user,
+		err:      err,
+		expireAt: time.Now().Add(ttl),
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	if c.order.Len() >= c.size {
+		c.remove(c.order.Back())
+		c.evictions++
+	}
+	c.entries[key] = c.order.PushFront(entry)
+}
+
+// Stats returns the current statistics of the cache
+func (c *AuthzResultCache) Stats() AuthzResultCacheStats {
+	if c == nil {
+		return AuthzResultCacheStats{}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return AuthzResultCacheStats{
+		Enabled:     true,
+		Size:        c.size,
+		TTL:         c.ttl.String(),
+		NegativeTTL: c.negativeTTL.String(),
+		Entries:     c.order.Len(),
+		Hits:        c.hits,
+		Misses:      c.misses,
+		Evictions:   c.evictions,
+	}
+}
+
+// Flush removes all entries from the cache
+func (c *AuthzResultCache) Flush() {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.order.Init()
+	c.entries = map[string]*list.Element{}
+}
+
+// PRIVATE HELPER METHODS
+
+// Remove an entry. Lock must be held by caller
+func (c *AuthzResultCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*authzResultCacheEntry).key)
+}
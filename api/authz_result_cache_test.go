@@ -0,0 +1,195 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAuthzResultCache(t *testing.T) {
+	testcases := map[string]struct {
+		size        int
+		ttl         time.Duration
+		negativeTTL time.Duration
+		// Expected result
+		expectedStats AuthzResultCacheStats
+	}{
+		"OkCaseEnabled": {
+			size:        10,
+			ttl:         time.Minute,
+			negativeTTL: time.Second,
+			expectedStats: AuthzResultCacheStats{
+				Enabled:     true,
+				Size:        10,
+				TTL:         "1m0s",
+				NegativeTTL: "1s",
+			},
+		},
+		"OkCaseOnlyNegative": {
+			size:        10,
+			negativeTTL: time.Second,
+			expectedStats: AuthzResultCacheStats{
+				Enabled:     true,
+				Size:        10,
+				TTL:         "0s",
+				NegativeTTL: "1s",
+			},
+		},
+		"OkCaseDisabledTTL": {
+			size:          10,
+			expectedStats: AuthzResultCacheStats{},
+		},
+		"OkCaseDisabledSize": {
+			ttl:           time.Minute,
+			negativeTTL:   time.Minute,
+			expectedStats: AuthzResultCacheStats{},
+		},
+	}
+
+	for n, test := range testcases {
+		cache := NewAuthzResultCache(test.size, test.ttl, test.negativeTTL)
+		assert.Equal(t, test.expectedStats, cache.Stats(), "Error in test case %v", n)
+	}
+}
+
+func TestAuthzResultCache_Get(t *testing.T) {
+	denied := &Error{
+		Code:    UNAUTHORIZED_RESOURCES_ERROR,
+		Message: "denied",
+	}
+	testcases := map[string]struct {
+		ttl         time.Duration
+		negativeTTL time.Duration
+		// Result stored
+		err  *Error
+		key  string
+		wait time.Duration
+		// Expected result
+		expectedUser  string
+		expectedErr   *Error
+		expectedFound bool
+		expectedStats AuthzResultCacheStats
+	}{
+		"OkCaseAllowedHit": {
+			ttl:           time.Minute,
+			key:           "key1",
+			expectedUser:  "user1",
+			expectedFound: true,
+			expectedStats: AuthzResultCacheStats{
+				Enabled:     true,
+				Size:        2,
+				TTL:         "1m0s",
+				NegativeTTL: "0s",
+				Entries:     1,
+				Hits:        1,
+			},
+		},
+		"OkCaseDeniedHit": {
+			negativeTTL:   time.Minute,
+			err:           denied,
+			key:           "key1",
+			expectedUser:  "user1",
+			expectedErr:   denied,
+			expectedFound: true,
+			expectedStats: AuthzResultCacheStats{
+				Enabled:     true,
+				Size:        2,
+				TTL:         "0s",
+				NegativeTTL: "1m0s",
+				Entries:     1,
+				Hits:        1,
+			},
+		},
+		"OkCaseMiss": {
+			ttl: time.Minute,
+			key: "key2",
+			expectedStats: AuthzResultCacheStats{
+				Enabled:     true,
+				Size:        2,
+				TTL:         "1m0s",
+				NegativeTTL: "0s",
+				Entries:     1,
+				Misses:      1,
+			},
+		},
+		"OkCaseDeniedNotStored": {
+			ttl: time.Minute,
+			err: denied,
+			key: "key1",
+			expectedStats: AuthzResultCacheStats{
+				Enabled:     true,
+				Size:        2,
+				TTL:         "1m0s",
+				NegativeTTL: "0s",
+				Misses:      1,
+			},
+		},
+		"OkCaseExpired": {
+			ttl:         time.Minute,
+			negativeTTL: time.Millisecond,
+			err:         denied,
+			key:         "key1",
+			wait:        5 * time.Millisecond,
+			expectedStats: AuthzResultCacheStats{
+				Enabled:     true,
+				Size:        2,
+				TTL:         "1m0s",
+				NegativeTTL: "1ms",
+				Misses:      1,
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		cache := NewAuthzResultCache(2, test.ttl, test.negativeTTL)
+		cache.Set("key1", "user1", test.err)
+		time.Sleep(test.wait)
+
+		user, err, found := cache.Get(test.key)
+		assert.Equal(t, test.expectedFound, found, "Error in test case %v", n)
+		assert.Equal(t, test.expectedUser, user, "Error in test case %v", n)
+		assert.Equal(t, test.expectedErr, err, "Error in test case %v", n)
+		assert.Equal(t, test.expectedStats, cache.Stats(), "Error in test case %v", n)
+	}
+}
+
+func TestAuthzResultCache_Eviction(t *testing.T) {
+	cache := NewAuthzResultCache(2, time.Minute, time.Minute)
+	cache.Set("key1", "user1", nil)
+	cache.Set("key2", "user1", nil)
+	// Key1 becomes the most recently used entry, so key2 is evicted
+	_, _, found := cache.Get("key1")
+	assert.True(t, found, "Error in eviction")
+	cache.Set("key3", "user1", nil)
+
+	_, _, found = cache.Get("key2")
+	assert.False(t, found, "Error in eviction")
+	_, _, found = cache.Get("key1")
+	assert.True(t, found, "Error in eviction")
+	_, _, found = cache.Get("key3")
+	assert.True(t, found, "Error in eviction")
+
+	// Updated entries aren't evicted
+	cache.Set("key3", "user1", &Error{Code: UNAUTHORIZED_RESOURCES_ERROR})
+	stats := cache.Stats()
+	assert.Equal(t, 2, stats.Entries, "Error in eviction")
+	assert.Equal(t, uint64(1), stats.Evictions, "Error in eviction")
+
+	cache.Flush()
+	assert.Equal(t, 0, cache.Stats().Entries, "Error in flush")
+	_, _, found = cache.Get("key1")
+	assert.False(t, found, "Error in flush")
+}
+
+func TestAuthzResultCache_Disabled(t *testing.T) {
+	var cache *AuthzResultCache
+	cache.Set("key1", "user1", nil)
+	user, err, found := cache.Get("key1")
+	assert.False(t, found, "Error in disabled cache")
+	assert.Empty(t, user, "Error in disabled cache")
+	assert.Nil(t, err, "Error in disabled cache")
+
+	cache.Flush()
+	assert.Equal(t, AuthzResultCacheStats{}, cache.Stats(), "Error in disabled cache")
+}
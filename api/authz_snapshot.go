@@ -0,0 +1,236 @@
+package api
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Tecsisa/foulkon/database"
+)
+
+// TYPE DEFINITIONS
+
+// AuthzSnapshot contains all users, groups and policies with the relations between them, so requests can be
+// authorized without reading them from database each time
+type AuthzSnapshot struct {
+	Users  []User  `json:"users"`
+	Groups []Group `json:"groups"`
+	// Policies with their statements
+	Policies []Policy `json:"policies"`
+	// Relations between groups (ID) and their member users (RelatedID)
+	Members []AuthzSnapshotRelation `json:"members"`
+	// Relations between groups (ID) and their subgroups (RelatedID)
+	Subgroups []AuthzSnapshotRelation `json:"subgroups"`
+	// Relations between groups (ID) and their attached policies (RelatedID)
+	GroupPolicies []AuthzSnapshotRelation `json:"groupPolicies"`
+	// Relations between users (ID) and their attached policies (RelatedID)
+	UserPolicies []AuthzSnapshotRelation `json:"userPolicies"`
+	CreateAt     time.Time               `json:"createAt"`
+}
+
+// AuthzSnapshotRelation relates two entities by their IDs
+type AuthzSnapshotRelation struct {
+	ID        string `json:"id"`
+	RelatedID string `json:"relatedId"`
+}
+
+// LocalAuthorizer authorizes external resources like WorkerAPI, with the users and policies of the last snapshot
+// loaded instead of reading them from database. Policies of each user are resolved when the snapshot is loaded
+type LocalAuthorizer struct {
+	lock sync.RWMutex
+	// Policies of each user by external ID, with their policy variables already replaced. Nil until a snapshot
+	// is loaded
+	policies map[string][]Policy
+	createAt time.Time
+}
+
+// Relations of a snapshot indexed by the IDs of their entities
+type authzSnapshotIndex struct {
+	policies map[string]Policy
+	// Groups of each user
+	userGroups map[string][]string
+	// Parent groups of each group
+	parentGroups  map[string][]string
+	groupPolicies map[string][]string
+	userPolicies  map[string][]string
+}
+
+// AUTHZ SNAPSHOT API IMPLEMENTATION
+
+func (api WorkerAPI) GetAuthzSnapshot(requestInfo RequestInfo) (*AuthzSnapshot, error) {
+	// Only admin is authorized
+	if !requestInfo.Admin {
+		return nil, &Error{
+			Code:    UNAUTHORIZED_RESOURCES_ERROR,
+			Message: fmt.Sprintf("User with externalId %v is not allowed to retrieve authorization snapshot", requestInfo.Identifier),
+		}
+	}
+
+	return api.getAuthzSnapshot()
+}
+
+// LOCAL AUTHORIZER
+
+// Load replaces the data used to authorize with the snapshot
+func (a *LocalAuthorizer) Load(snapshot *AuthzSnapshot) {
+	index := newAuthzSnapshotIndex(snapshot)
+	usersPolicies := map[string][]Policy{}
+	for _, user := range snapshot.Users {
+		// Groups of the user, including the ones inherited through the group hierarchy
+		policies := index.getPolicies(index.withAncestors(index.userGroups[user.ID]), user.ID)
+		user := user
+		usersPolicies[user.ExternalID] = replacePolicyVariables(policies, &user)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.policies = usersPolicies
+	a.createAt = snapshot.CreateAt
+}
+
+// SnapshotTime returns the creation time of the snapshot loaded, or zero time if there isn't any
+func (a *LocalAuthorizer) SnapshotTime() time.Time {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return a.createAt
+}
+
+// GetAuthorizedExternalResources returns the resources where the specified user has the action granted, with the
+// same result as WorkerAPI. Throw error if the input parameters are invalid, user doesn't exist, user isn't
+// allowed to access to any resource or there isn't any snapshot loaded
+func (a *LocalAuthorizer) GetAuthorizedExternalResources(requestInfo RequestInfo, action string, resources []string) ([]string, error) {
+	// Validate parameters
+	externalResources, err := getExternalResources(action, resources)
+	if err != nil {
+		return nil, err
+	}
+
+	a.lock.RLock()
+	policies, ok := a.policies[requestInfo.Identifier]
+	loaded := a.policies != nil
+	a.lock.RUnlock()
+	if !loaded {
+		return nil, &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: "Authorization snapshot isn't loaded yet",
+		}
+	}
+
+	allowedUrns := externalResources
+	// If user is an admin all resources are allowed without restriction
+	if !requestInfo.Admin {
+		if !ok {
+			return nil, &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: fmt.Sprintf("Authenticated user with externalId %v not found. Unable to retrieve permissions.", requestInfo.Identifier),
+			}
+		}
+		allowedUrns, err = authorizeResources(requestInfo, policies, "urn:*", action, externalResources)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	response := []string{}
+	for _, res := range allowedUrns {
+		response = append(response, res.GetUrn())
+	}
+	if len(response) < 1 {
+		return nil, &Error{
+			Code:    UNAUTHORIZED_RESOURCES_ERROR,
+			Message: fmt.Sprintf("User with externalId %v is not allowed to access to any resource", requestInfo.Identifier),
+		}
+	}
+
+	return response, nil
+}
+
+// PRIVATE HELPER METHODS
+
+// Retrieve a snapshot from the database
+func (api WorkerAPI) getAuthzSnapshot() (*AuthzSnapshot, error) {
+	if api.AuthzSnapshotRepo == nil {
+		return nil, &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: "Authorization snapshots aren't supported by the database",
+		}
+	}
+
+	snapshot, err := api.AuthzSnapshotRepo.GetAuthzSnapshot()
+	if err != nil {
+		//Transform to DB error
+		dbError := err.(*database.Error)
+		return nil, &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: dbError.Message,
+		}
+	}
+
+	return snapshot, nil
+}
+
+// Index the relations of snapshot
+func newAuthzSnapshotIndex(snapshot *AuthzSnapshot) *authzSnapshotIndex {
+	index := &authzSnapshotIndex{
+		policies:      map[string]Policy{},
+		userGroups:    map[string][]string{},
+		parentGroups:  map[string][]string{},
+		groupPolicies: map[string][]string{},
+		userPolicies:  map[string][]string{},
+	}
+	for _, policy := range snapshot.Policies {
+		index.policies[policy.ID] = policy
+	}
+	for _, member := range snapshot.Members {
+		index.userGroups[member.RelatedID] = append(index.userGroups[member.RelatedID], member.ID)
+	}
+	for _, subgroup := range snapshot.Subgroups {
+		index.parentGroups[subgroup.RelatedID] = append(index.parentGroups[subgroup.RelatedID], subgroup.ID)
+	}
+	for _, relation := range snapshot.GroupPolicies {
+		index.groupPolicies[relation.ID] = append(index.groupPolicies[relation.ID], relation.RelatedID)
+	}
+	for _, relation := range snapshot.UserPolicies {
+		index.userPolicies[relation.ID] = append(index.userPolicies[relation.ID], relation.RelatedID)
+	}
+	return index
+}
+
+// Return the groups with all their ancestors through the group hierarchy, each one once
+func (i *authzSnapshotIndex) withAncestors(groupIDs []string) []string {
+	visited := map[string]bool{}
+	groups := []string{}
+	pending := append([]string{}, groupIDs...)
+	for len(pending) > 0 {
+		groupID := pending[0]
+		pending = pending[1:]
+		if visited[groupID] {
+			continue
+		}
+		visited[groupID] = true
+		groups = append(groups, groupID)
+		pending = append(pending, i.parentGroups[groupID]...)
+	}
+	return groups
+}
+
+// Return the policies attached to the groups and to the user, each one once, although they are reached in several ways
+func (i *authzSnapshotIndex) getPolicies(groupIDs []string, userID string) []Policy {
+	policyIDs := []string{}
+	for _, groupID := range groupIDs {
+		policyIDs = append(policyIDs, i.groupPolicies[groupID]...)
+	}
+	policyIDs = append(policyIDs, i.userPolicies[userID]...)
+
+	policies := []Policy{}
+	added := map[string]bool{}
+	for _, policyID := range policyIDs {
+		policy, ok := i.policies[policyID]
+		if !ok || added[policyID] {
+			continue
+		}
+		added[policyID] = true
+		policies = append(policies, policy)
+	}
+	return policies
+}
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Tecsisa/foulkon/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerAPI_GetAuthzSnapshot(t *testing.T) {
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// API method args
+		requestInfo RequestInfo
+		// Expected result
+		expectedResponse *AuthzSnapshot
+		wantError        error
+		// Manager Results
+		getAuthzSnapshotResult *AuthzSnapshot
+		// Manager Errors
+		getAuthzSnapshotError error
+	}{
+		"OkCase": {
+			requestInfo: RequestInfo{
+				Identifier: "admin",
+				Admin:      true,
+			},
+			getAuthzSnapshotResult: &AuthzSnapshot{
+				Users: []User{
+					{
+						ID:         "UserID1",
+						ExternalID: "user1",
+					},
+				},
+				CreateAt: now,
+			},
+			expectedResponse: &AuthzSnapshot{
+				Users: []User{
+					{
+						ID:         "UserID1",
+						ExternalID: "user1",
+					},
+				},
+				CreateAt: now,
+			},
+		},
+		"ErrorCaseNotAdmin": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      false,
+			},
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "User with externalId 123456 is not allowed to retrieve authorization snapshot",
+			},
+		},
+		"ErrorCaseDBError": {
+			requestInfo: RequestInfo{
+				Identifier: "admin",
+				Admin:      true,
+			},
+			getAuthzSnapshotError: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetAuthzSnapshotMethod][0] = test.getAuthzSnapshotResult
+		testRepo.ArgsOut[GetAuthzSnapshotMethod][1] = test.getAuthzSnapshotError
+
+		snapshot, err := testAPI.GetAuthzSnapshot(test.requestInfo)
+		checkMethodResponse(t, n, test.wantError, err, test.expectedResponse, snapshot)
+	}
+
+	// Without snapshot repository
+	testAPI := makeTestAPI(makeTestRepo())
+	testAPI.AuthzSnapshotRepo = nil
+	_, err := testAPI.GetAuthzSnapshot(RequestInfo{Identifier: "admin", Admin: true})
+	assert.Equal(t, &Error{
+		Code:    UNKNOWN_API_ERROR,
+		Message: "Authorization snapshots aren't supported by the database",
+	}, err, "Error in test case without snapshot repository")
+}
+
+func TestLocalAuthorizer_GetAuthorizedExternalResources(t *testing.T) {
+	now := time.Now().UTC()
+	snapshot := &AuthzSnapshot{
+		Users: []User{
+			{
+				ID:         "UserID1",
+				ExternalID: "user1",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "user1"),
+			},
+			{
+				ID:         "UserID2",
+				ExternalID: "user2",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "user2"),
+			},
+		},
+		Groups: []Group{
+			{
+				ID:   "GroupID1",
+				Name: "group1",
+				Org:  "org1",
+			},
+			{
+				ID:   "GroupID2",
+				Name: "group2",
+				Org:  "org1",
+			},
+		},
+		Policies: []Policy{
+			{
+				ID:   "PolicyID1",
+				Name: "policy1",
+				Org:  "org1",
+				Statements: &[]Statement{
+					{
+						Effect:    "allow",
+						Actions:   []string{"product:DoAction"},
+						Resources: []string{"urn:ews:product:instance:resource/*"},
+					},
+				},
+			},
+			{
+				ID:   "PolicyID2",
+				Name: "policy2",
+				Org:  "org1",
+				Statements: &[]Statement{
+					{
+						Effect:    "deny",
+						Actions:   []string{"product:DoAction"},
+						Resources: []string{"urn:ews:product:instance:resource/denied"},
+					},
+					{
+						Effect:    "allow",
+						Actions:   []string{"product:DoOwnAction"},
+						Resources: []string{"urn:ews:product:instance:resource/${user.externalId}"},
+					},
+				},
+			},
+		},
+		// User1 is member of group2, which is subgroup of group1
+		Members: []AuthzSnapshotRelation{
+			{ID: "GroupID2", RelatedID: "UserID1"},
+		},
+		Subgroups: []AuthzSnapshotRelation{
+			{ID: "GroupID1", RelatedID: "GroupID2"},
+		},
+		GroupPolicies: []AuthzSnapshotRelation{
+			{ID: "GroupID1", RelatedID: "PolicyID1"},
+		},
+		UserPolicies: []AuthzSnapshotRelation{
+			{ID: "UserID1", RelatedID: "PolicyID2"},
+		},
+		CreateAt: now,
+	}
+
+	testcases := map[string]struct {
+		// Snapshot to load
+		snapshot *AuthzSnapshot
+		// Method args
+		requestInfo  RequestInfo
+		action       string
+		resourceUrns []string
+		// Expected result
+		expectedResources []string
+		wantError         error
+	}{
+		"OkCaseInheritedGroupPolicy": {
+			snapshot: snapshot,
+			requestInfo: RequestInfo{
+				Identifier: "user1",
+			},
+			action: "product:DoAction",
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/res1",
+				"urn:ews:product:instance:resource/denied",
+				"urn:ews:product:instance:other/res1",
+			},
+			expectedResources: []string{
+				"urn:ews:product:instance:resource/res1",
+			},
+		},
+		"OkCasePolicyVariables": {
+			snapshot: snapshot,
+			requestInfo: RequestInfo{
+				Identifier: "user1",
+			},
+			action: "product:DoOwnAction",
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/user1",
+				"urn:ews:product:instance:resource/user2",
+			},
+			expectedResources: []string{
+				"urn:ews:product:instance:resource/user1",
+			},
+		},
+		"OkCaseAdmin": {
+			snapshot: snapshot,
+			requestInfo: RequestInfo{
+				Identifier: "admin",
+				Admin:      true,
+			},
+			action: "product:DoAction",
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/denied",
+			},
+			expectedResources: []string{
+				"urn:ews:product:instance:resource/denied",
+			},
+		},
+		"ErrorCaseInvalidAction": {
+			snapshot: snapshot,
+			requestInfo: RequestInfo{
+				Identifier: "user1",
+			},
+			action: "valid::Action",
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter action, value: valid::Action",
+			},
+		},
+		"ErrorCaseNotLoaded": {
+			requestInfo: RequestInfo{
+				Identifier: "user1",
+			},
+			action: "product:DoAction",
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/res1",
+			},
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Authorization snapshot isn't loaded yet",
+			},
+		},
+		"ErrorCaseUserNotFound": {
+			snapshot: snapshot,
+			requestInfo: RequestInfo{
+				Identifier: "user3",
+			},
+			action: "product:DoAction",
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/res1",
+			},
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Authenticated user with externalId user3 not found. Unable to retrieve permissions.",
+			},
+		},
+		"ErrorCaseNotAllowed": {
+			snapshot: snapshot,
+			requestInfo: RequestInfo{
+				Identifier: "user2",
+			},
+			action: "product:DoAction",
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/res1",
+			},
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "User with externalId user2 is not allowed to access to resource urn:*",
+			},
+		},
+		"ErrorCaseDenied": {
+			snapshot: snapshot,
+			requestInfo: RequestInfo{
+				Identifier: "user1",
+			},
+			action: "product:DoAction",
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/denied",
+			},
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "User with externalId user1 is not allowed to access to any resource",
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		authorizer := &LocalAuthorizer{}
+		if test.snapshot != nil {
+			authorizer.Load(test.snapshot)
+			assert.Equal(t, test.snapshot.CreateAt, authorizer.SnapshotTime(), "Error in test case %v", n)
+		}
+
+		resources, err := authorizer.GetAuthorizedExternalResources(test.requestInfo, test.action, test.resourceUrns)
+		checkMethodResponse(t, n, test.wantError, err, test.expectedResources, resources)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"fmt"
+	"time"
 
 	"github.com/Tecsisa/foulkon/database"
 	"github.com/stretchr/testify/assert"
@@ -675,6 +676,832 @@ func TestGetAuthorizedExternalResources(t *testing.T) {
 				},
 			},
 		},
+		"OktestCaseWithNotActionsAndNotResources": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      false,
+			},
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/path1/resource",
+				"urn:ews:product:instance:resource/path2/resource",
+				"urn:ews:product:instance:resource/private/resource",
+				"urn:ews:product:instance:resource/path1/resourceDeny",
+			},
+			action: "product:DoAction",
+			expectedResources: []string{
+				"urn:ews:product:instance:resource/path1/resource",
+				"urn:ews:product:instance:resource/path2/resource",
+			},
+			getUserByExternalIDResult: &User{
+				ID:  "123456",
+				Urn: CreateUrn("", RESOURCE_USER, "/path/", "user1"),
+			},
+			getGroupsByUserIDResult: []TestUserGroupRelation{
+				{
+					Group: &Group{
+						ID:  "GROUP-USER-ID",
+						Urn: CreateUrn("example", RESOURCE_GROUP, "/path/", "groupUser"),
+					},
+				},
+			},
+			getAttachedPoliciesResult: []TestPolicyGroupRelation{
+				{
+					Policy: &Policy{
+						ID:  "POLICY-USER-ID",
+						Urn: CreateUrn("example", RESOURCE_POLICY, "/path/", "policyUser"),
+						Statements: &[]Statement{
+							{
+								Effect: "allow",
+								NotActions: []string{
+									"product:DeleteAction",
+								},
+								NotResources: []string{
+									"urn:ews:product:instance:resource/private/*",
+								},
+							},
+							{
+								Effect: "deny",
+								NotActions: []string{
+									"product:Do*",
+								},
+								Resources: []string{
+									"urn:ews:product:instance:resource/path2/*",
+								},
+							},
+							{
+								Effect: "deny",
+								Actions: []string{
+									"product:DoAction",
+								},
+								Resources: []string{
+									"urn:ews:product:instance:resource/path1/resourceDeny",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"OktestCaseWithPolicyVariables": {
+			requestInfo: RequestInfo{
+				Identifier: "user1",
+				Admin:      false,
+			},
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/user1/resource",
+				"urn:ews:product:instance:resource/user2/resource",
+				"urn:ews:product:instance:path/team/user1",
+				"urn:ews:product:instance:path/other/user1",
+			},
+			action: "product:DoAction",
+			expectedResources: []string{
+				"urn:ews:product:instance:resource/user1/resource",
+				"urn:ews:product:instance:path/team/user1",
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "123456",
+				ExternalID: "user1",
+				Path:       "/team/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/team/", "user1"),
+			},
+			getGroupsByUserIDResult: []TestUserGroupRelation{
+				{
+					Group: &Group{
+						ID:  "GROUP-USER-ID",
+						Urn: CreateUrn("example", RESOURCE_GROUP, "/path/", "groupUser"),
+					},
+				},
+			},
+			getAttachedPoliciesResult: []TestPolicyGroupRelation{
+				{
+					Policy: &Policy{
+						ID:  "POLICY-USER-ID",
+						Urn: CreateUrn("example", RESOURCE_POLICY, "/path/", "policyUser"),
+						Statements: &[]Statement{
+							{
+								Effect: "allow",
+								Actions: []string{
+									"product:DoAction",
+								},
+								Resources: []string{
+									"urn:ews:product:instance:resource/${user.externalId}/*",
+									"urn:ews:product:instance:path${user.path}${user.externalId}",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for n, test := range testcases {
+
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetUserByExternalIDMethod][0] = test.getUserByExternalIDResult
+		testRepo.ArgsOut[GetUserByExternalIDMethod][1] = test.getUserByExternalIDError
+
+		testRepo.ArgsOut[GetGroupsByUserIDMethod][0] = test.getGroupsByUserIDResult
+		testRepo.ArgsOut[GetGroupsByUserIDMethod][1] = test.getGroupsByUserIDError
+
+		testRepo.ArgsOut[GetAttachedPoliciesMethod][0] = test.getAttachedPoliciesResult
+		testRepo.ArgsOut[GetAttachedPoliciesMethod][1] = test.getAttachedPoliciesError
+
+		resources, err := testAPI.GetAuthorizedExternalResources(test.requestInfo, test.action, test.resourceUrns)
+		checkMethodResponse(t, n, test.wantError, err, test.expectedResources, resources)
+		if !test.requestInfo.Admin {
+			// Check received authenticated user in method GetUserByExternalID
+			assert.Equal(t, test.requestInfo.Identifier, testRepo.ArgsIn[GetUserByExternalIDMethod][0], "Error in test case %v", n)
+		}
+	}
+}
+
+func TestGetAuthorizedExternalResourcesBatch(t *testing.T) {
+	testcases := map[string]struct {
+		// Authenticated user
+		requestInfo RequestInfo
+		// Items requested
+		items []AuthorizeItem
+		// Expected result
+		expectedResults []AuthorizeItemResult
+		// Error to compare when we expect an error
+		wantError error
+		// Number of times that user policies are expected to be retrieved
+		expectedPolicyCalls int
+		// GetUserByExternalID Method Out Arguments
+		getUserByExternalIDResult *User
+		getUserByExternalIDError  error
+		// GetPoliciesForUser Method Out Arguments
+		getPoliciesForUserResult []Policy
+	}{
+		"OktestCase": {
+			requestInfo: RequestInfo{
+				Identifier: "user1",
+			},
+			items: []AuthorizeItem{
+				{
+					Action: "product:Read",
+					Resources: []string{
+						"urn:ews:product:instance:resource/path1/resource1",
+						"urn:ews:product:instance:resource/path2/resource2",
+					},
+				},
+				{
+					Action: "product:Write",
+					Resources: []string{
+						"urn:ews:product:instance:resource/path1/resource1",
+						"urn:ews:product:instance:resource/path1/resourceDeny",
+					},
+				},
+				{
+					Action: "product:Delete",
+					Resources: []string{
+						"urn:ews:product:instance:resource/path1/resource1",
+					},
+				},
+			},
+			expectedResults: []AuthorizeItemResult{
+				{
+					Action: "product:Read",
+					ResourcesAllowed: []string{
+						"urn:ews:product:instance:resource/path1/resource1",
+						"urn:ews:product:instance:resource/path2/resource2",
+					},
+				},
+				{
+					Action: "product:Write",
+					ResourcesAllowed: []string{
+						"urn:ews:product:instance:resource/path1/resource1",
+					},
+				},
+				{
+					Action:           "product:Delete",
+					ResourcesAllowed: []string{},
+				},
+			},
+			expectedPolicyCalls: 1,
+			getUserByExternalIDResult: &User{
+				ID:         "123456",
+				ExternalID: "user1",
+			},
+			getPoliciesForUserResult: []Policy{
+				{
+					ID: "POLICY-USER-ID",
+					Statements: &[]Statement{
+						{
+							Effect: "allow",
+							Actions: []string{
+								"product:Read",
+								"product:Write",
+							},
+							Resources: []string{
+								"urn:ews:product:instance:resource/*",
+							},
+						},
+						{
+							Effect: "deny",
+							Actions: []string{
+								"product:Write",
+							},
+							Resources: []string{
+								"urn:ews:product:instance:resource/path1/resourceDeny",
+							},
+						},
+					},
+				},
+			},
+		},
+		"OktestCaseAdmin": {
+			requestInfo: RequestInfo{
+				Identifier: "admin",
+				Admin:      true,
+			},
+			items: []AuthorizeItem{
+				{
+					Action: "product:Read",
+					Resources: []string{
+						"urn:ews:product:instance:resource/path1/resource1",
+					},
+				},
+			},
+			expectedResults: []AuthorizeItemResult{
+				{
+					Action: "product:Read",
+					ResourcesAllowed: []string{
+						"urn:ews:product:instance:resource/path1/resource1",
+					},
+				},
+			},
+		},
+		"ErrortestCaseEmptyItems": {
+			requestInfo: RequestInfo{
+				Identifier: "user1",
+			},
+			items: []AuthorizeItem{},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: fmt.Sprintf("Invalid parameter Items. Items can't be empty or bigger than %v elements", MAX_AUTHZ_ITEM_NUMBER),
+			},
+		},
+		"ErrortestCaseMaxItemsExceed": {
+			requestInfo: RequestInfo{
+				Identifier: "user1",
+			},
+			items: make([]AuthorizeItem, MAX_AUTHZ_ITEM_NUMBER+1),
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: fmt.Sprintf("Invalid parameter Items. Items can't be empty or bigger than %v elements", MAX_AUTHZ_ITEM_NUMBER),
+			},
+		},
+		"ErrortestCaseInvalidItem": {
+			requestInfo: RequestInfo{
+				Identifier: "user1",
+			},
+			items: []AuthorizeItem{
+				{
+					Action: "product:Read",
+					Resources: []string{
+						"urn:ews:product:instance:resource/path1/resource1",
+					},
+				},
+				{
+					Action: "product:Read",
+					Resources: []string{
+						"urn:*",
+					},
+				},
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter resource urn:*. Urn prefixes are not allowed here",
+			},
+		},
+		"ErrortestCaseUserNotFound": {
+			requestInfo: RequestInfo{
+				Identifier: "user1",
+			},
+			items: []AuthorizeItem{
+				{
+					Action: "product:Read",
+					Resources: []string{
+						"urn:ews:product:instance:resource/path1/resource1",
+					},
+				},
+			},
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Authenticated user with externalId user1 not found. Unable to retrieve permissions.",
+			},
+			getUserByExternalIDError: &database.Error{
+				Code: database.USER_NOT_FOUND,
+			},
+		},
+	}
+
+	for n, test := range testcases {
+
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetUserByExternalIDMethod][0] = test.getUserByExternalIDResult
+		testRepo.ArgsOut[GetUserByExternalIDMethod][1] = test.getUserByExternalIDError
+
+		policyCalls := 0
+		testRepo.SpecialFuncs[GetPoliciesForUserMethod] = func(userID string) ([]Policy, []string, error) {
+			policyCalls++
+			return test.getPoliciesForUserResult, nil, nil
+		}
+
+		results, err := testAPI.GetAuthorizedExternalResourcesBatch(test.requestInfo, test.items)
+		checkMethodResponse(t, n, test.wantError, err, test.expectedResults, results)
+		assert.Equal(t, test.expectedPolicyCalls, policyCalls, "Error in test case %v", n)
+	}
+}
+
+func TestExplainAuthorizedExternalResources(t *testing.T) {
+	testcases := map[string]struct {
+		// Authenticated user
+		requestInfo RequestInfo
+		// Resource urns that user wants to access
+		resourceUrns []string
+		// Action to do
+		action string
+		// Expected explanations
+		expectedExplanations []ResourceExplanation
+		// Error to compare when we expect an error
+		wantError error
+		// GetUserByExternalID Method Out Arguments
+		getUserByExternalIDResult *User
+		getUserByExternalIDError  error
+		// GetGroupsByUserID Method Out Arguments
+		getGroupsByUserIDResult []TestUserGroupRelation
+		getGroupsByUserIDError  error
+		// GetAttachedPolicies Method Out Arguments
+		getAttachedPoliciesResult []TestPolicyGroupRelation
+		getAttachedPoliciesError  error
+	}{
+		"OktestCaseAdmin": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			action: "product:DoAction",
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/path1/resource",
+			},
+			expectedExplanations: []ResourceExplanation{
+				{
+					Urn:      "urn:ews:product:instance:resource/path1/resource",
+					Decision: DECISION_ALLOWED,
+				},
+			},
+		},
+		"OktestCaseExplanations": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      false,
+			},
+			action: "product:DoAction",
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/path1/resourceAllow",
+				"urn:ews:product:instance:resource/path1/resourceDeny",
+				"urn:ews:product:instance:resource/path2/resource",
+			},
+			expectedExplanations: []ResourceExplanation{
+				{
+					Urn:                "urn:ews:product:instance:resource/path1/resourceAllow",
+					Decision:           DECISION_ALLOWED,
+					WinningRestriction: "urn:ews:product:instance:resource/path1/*",
+					Statements: []StatementMatch{
+						{
+							PolicyName:     "policyUser",
+							PolicyUrn:      CreateUrn("example", RESOURCE_POLICY, "/path/", "policyUser"),
+							StatementIndex: 0,
+							Effect:         "allow",
+							Resource:       "urn:ews:product:instance:resource/path1/*",
+						},
+					},
+				},
+				{
+					Urn:                "urn:ews:product:instance:resource/path1/resourceDeny",
+					Decision:           DECISION_DENIED,
+					WinningRestriction: "urn:ews:product:instance:resource/path1/resourceDeny",
+					Statements: []StatementMatch{
+						{
+							PolicyName:     "policyUser",
+							PolicyUrn:      CreateUrn("example", RESOURCE_POLICY, "/path/", "policyUser"),
+							StatementIndex: 0,
+							Effect:         "allow",
+							Resource:       "urn:ews:product:instance:resource/path1/*",
+						},
+						{
+							PolicyName:     "policyUser",
+							PolicyUrn:      CreateUrn("example", RESOURCE_POLICY, "/path/", "policyUser"),
+							StatementIndex: 2,
+							Effect:         "deny",
+							Resource:       "urn:ews:product:instance:resource/path1/resourceDeny",
+						},
+					},
+				},
+				{
+					Urn:        "urn:ews:product:instance:resource/path2/resource",
+					Decision:   DECISION_IMPLICIT_DENY,
+					Statements: []StatementMatch{},
+				},
+			},
+			getUserByExternalIDResult: &User{
+				ID:  "123456",
+				Urn: CreateUrn("", RESOURCE_USER, "/path/", "user1"),
+			},
+			getGroupsByUserIDResult: []TestUserGroupRelation{
+				{
+					Group: &Group{
+						ID:  "GROUP-USER-ID",
+						Urn: CreateUrn("example", RESOURCE_GROUP, "/path/", "groupUser"),
+					},
+				},
+			},
+			getAttachedPoliciesResult: []TestPolicyGroupRelation{
+				{
+					Policy: &Policy{
+						ID:   "POLICY-USER-ID",
+						Name: "policyUser",
+						Urn:  CreateUrn("example", RESOURCE_POLICY, "/path/", "policyUser"),
+						Statements: &[]Statement{
+							{
+								Effect: "allow",
+								Actions: []string{
+									"product:DoAction",
+								},
+								Resources: []string{
+									"urn:ews:product:instance:resource/path1/*",
+								},
+							},
+							{
+								Effect: "allow",
+								Actions: []string{
+									"product:OtherAction",
+								},
+								Resources: []string{
+									"urn:ews:product:instance:resource/path2/*",
+								},
+							},
+							{
+								Effect: "deny",
+								Actions: []string{
+									"product:*",
+								},
+								Resources: []string{
+									"urn:ews:product:instance:resource/path1/resourceDeny",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"ErrortestCaseInvalidAction": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+			},
+			action: "valid::Action",
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter action, value: valid::Action",
+			},
+		},
+		"ErrortestCaseUserNotFound": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+			},
+			action: "product:DoAction",
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/path1/resource",
+			},
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Authenticated user with externalId 123456 not found. Unable to retrieve permissions.",
+			},
+			getUserByExternalIDError: &database.Error{
+				Code: database.USER_NOT_FOUND,
+			},
+		},
+		"ErrortestCaseGetAttachedPoliciesError": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+			},
+			action: "product:DoAction",
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/path1/resource",
+			},
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			getUserByExternalIDResult: &User{
+				ID:  "123456",
+				Urn: CreateUrn("", RESOURCE_USER, "/path/", "user1"),
+			},
+			getGroupsByUserIDResult: []TestUserGroupRelation{
+				{
+					Group: &Group{
+						ID:  "GROUP-USER-ID",
+						Urn: CreateUrn("example", RESOURCE_GROUP, "/path/", "groupUser"),
+					},
+				},
+			},
+			getAttachedPoliciesError: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	for n, test := range testcases {
+
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetUserByExternalIDMethod][0] = test.getUserByExternalIDResult
+		testRepo.ArgsOut[GetUserByExternalIDMethod][1] = test.getUserByExternalIDError
+
+		testRepo.ArgsOut[GetGroupsByUserIDMethod][0] = test.getGroupsByUserIDResult
+		testRepo.ArgsOut[GetGroupsByUserIDMethod][2] = test.getGroupsByUserIDError
+
+		testRepo.ArgsOut[GetAttachedPoliciesMethod][0] = test.getAttachedPoliciesResult
+		testRepo.ArgsOut[GetAttachedPoliciesMethod][2] = test.getAttachedPoliciesError
+
+		explanations, err := testAPI.ExplainAuthorizedExternalResources(test.requestInfo, test.action, test.resourceUrns)
+		checkMethodResponse(t, n, test.wantError, err, test.expectedExplanations, explanations)
+	}
+}
+
+func TestSimulateAuthorizedExternalResources(t *testing.T) {
+	testcases := map[string]struct {
+		// Authenticated user
+		requestInfo RequestInfo
+		// User to simulate
+		externalID string
+		// Candidate statements
+		statements []Statement
+		replace    bool
+		// Resource urns that user wants to access
+		resourceUrns []string
+		// Action to do
+		action string
+		// Expected explanations
+		expectedExplanations []ResourceExplanation
+		// Error to compare when we expect an error
+		wantError error
+		// GetUserByExternalID Method Out Arguments
+		getUserByExternalIDResult *User
+		getUserByExternalIDError  error
+		// GetGroupsByUserID Method Out Arguments
+		getGroupsByUserIDResult []TestUserGroupRelation
+		// GetAttachedPolicies Method Out Arguments
+		getAttachedPoliciesResult []TestPolicyGroupRelation
+	}{
+		"OktestCaseAddStatements": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			externalID: "user1",
+			statements: []Statement{
+				{
+					Effect: "deny",
+					Actions: []string{
+						"product:DoAction",
+					},
+					Resources: []string{
+						"urn:ews:product:instance:resource/path1/resourceDeny",
+					},
+				},
+			},
+			action: "product:DoAction",
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/path1/resourceAllow",
+				"urn:ews:product:instance:resource/path1/resourceDeny",
+			},
+			expectedExplanations: []ResourceExplanation{
+				{
+					Urn:                "urn:ews:product:instance:resource/path1/resourceAllow",
+					Decision:           DECISION_ALLOWED,
+					WinningRestriction: "urn:ews:product:instance:resource/path1/*",
+					Statements: []StatementMatch{
+						{
+							PolicyName:     "policyUser",
+							StatementIndex: 0,
+							Effect:         "allow",
+							Resource:       "urn:ews:product:instance:resource/path1/*",
+						},
+					},
+				},
+				{
+					Urn:                "urn:ews:product:instance:resource/path1/resourceDeny",
+					Decision:           DECISION_DENIED,
+					WinningRestriction: "urn:ews:product:instance:resource/path1/resourceDeny",
+					Statements: []StatementMatch{
+						{
+							PolicyName:     "policyUser",
+							StatementIndex: 0,
+							Effect:         "allow",
+							Resource:       "urn:ews:product:instance:resource/path1/*",
+						},
+						{
+							StatementIndex: 0,
+							Effect:         "deny",
+							Resource:       "urn:ews:product:instance:resource/path1/resourceDeny",
+						},
+					},
+				},
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "USER-ID",
+				ExternalID: "user1",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "user1"),
+			},
+			getGroupsByUserIDResult: []TestUserGroupRelation{
+				{
+					Group: &Group{
+						ID: "GROUP-USER-ID",
+					},
+				},
+			},
+			getAttachedPoliciesResult: []TestPolicyGroupRelation{
+				{
+					Policy: &Policy{
+						ID:   "POLICY-USER-ID",
+						Name: "policyUser",
+						Statements: &[]Statement{
+							{
+								Effect: "allow",
+								Actions: []string{
+									"product:DoAction",
+								},
+								Resources: []string{
+									"urn:ews:product:instance:resource/path1/*",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"OktestCaseReplaceStatements": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			externalID: "user1",
+			statements: []Statement{
+				{
+					Effect: "allow",
+					Actions: []string{
+						"product:*",
+					},
+					Resources: []string{
+						"urn:ews:product:instance:resource/path2/*",
+					},
+				},
+			},
+			replace: true,
+			action:  "product:DoAction",
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/path1/resource",
+				"urn:ews:product:instance:resource/path2/resource",
+			},
+			expectedExplanations: []ResourceExplanation{
+				{
+					Urn:        "urn:ews:product:instance:resource/path1/resource",
+					Decision:   DECISION_IMPLICIT_DENY,
+					Statements: []StatementMatch{},
+				},
+				{
+					Urn:                "urn:ews:product:instance:resource/path2/resource",
+					Decision:           DECISION_ALLOWED,
+					WinningRestriction: "urn:ews:product:instance:resource/path2/*",
+					Statements: []StatementMatch{
+						{
+							StatementIndex: 0,
+							Effect:         "allow",
+							Resource:       "urn:ews:product:instance:resource/path2/*",
+						},
+					},
+				},
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "USER-ID",
+				ExternalID: "user1",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "user1"),
+			},
+			getGroupsByUserIDResult: []TestUserGroupRelation{
+				{
+					Group: &Group{
+						ID: "GROUP-USER-ID",
+					},
+				},
+			},
+			getAttachedPoliciesResult: []TestPolicyGroupRelation{
+				{
+					Policy: &Policy{
+						ID:   "POLICY-USER-ID",
+						Name: "policyUser",
+						Statements: &[]Statement{
+							{
+								Effect: "allow",
+								Actions: []string{
+									"product:DoAction",
+								},
+								Resources: []string{
+									"urn:ews:product:instance:resource/path1/*",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"ErrortestCaseInvalidStatements": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			externalID: "user1",
+			statements: []Statement{
+				{
+					Effect: "maybe",
+					Actions: []string{
+						"product:DoAction",
+					},
+					Resources: []string{
+						"urn:ews:product:instance:resource/path1/*",
+					},
+				},
+			},
+			action: "product:DoAction",
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/path1/resource",
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid effect: maybe - Only 'allow' and 'deny' accepted",
+			},
+		},
+		"ErrortestCaseInvalidResources": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			externalID: "user1",
+			action:     "product:DoAction",
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: fmt.Sprintf("Invalid parameter Resources. Resources can't be empty or bigger than %v elements", MAX_RESOURCE_NUMBER),
+			},
+		},
+		"ErrortestCaseUserNotFound": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			externalID: "user1",
+			action:     "product:DoAction",
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/path1/resource",
+			},
+			wantError: &Error{
+				Code:    USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message: "User not found",
+			},
+			getUserByExternalIDError: &database.Error{
+				Code:    database.USER_NOT_FOUND,
+				Message: "User not found",
+			},
+		},
+		"ErrortestCaseUnauthorized": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      false,
+			},
+			externalID: "user1",
+			action:     "product:DoAction",
+			resourceUrns: []string{
+				"urn:ews:product:instance:resource/path1/resource",
+			},
+			wantError: &Error{
+				Code: UNAUTHORIZED_RESOURCES_ERROR,
+				Message: fmt.Sprintf("User with externalId %v is not allowed to access to resource %v",
+					"123456", CreateUrn("", RESOURCE_USER, "/path/", "user1")),
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "USER-ID",
+				ExternalID: "user1",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "user1"),
+			},
+		},
 	}
 
 	for n, test := range testcases {
@@ -686,18 +1513,381 @@ func TestGetAuthorizedExternalResources(t *testing.T) {
 		testRepo.ArgsOut[GetUserByExternalIDMethod][1] = test.getUserByExternalIDError
 
 		testRepo.ArgsOut[GetGroupsByUserIDMethod][0] = test.getGroupsByUserIDResult
-		testRepo.ArgsOut[GetGroupsByUserIDMethod][1] = test.getGroupsByUserIDError
 
 		testRepo.ArgsOut[GetAttachedPoliciesMethod][0] = test.getAttachedPoliciesResult
-		testRepo.ArgsOut[GetAttachedPoliciesMethod][1] = test.getAttachedPoliciesError
 
-		resources, err := testAPI.GetAuthorizedExternalResources(test.requestInfo, test.action, test.resourceUrns)
-		checkMethodResponse(t, n, test.wantError, err, test.expectedResources, resources)
-		if !test.requestInfo.Admin {
-			// Check received authenticated user in method GetUserByExternalID
-			assert.Equal(t, test.requestInfo.Identifier, testRepo.ArgsIn[GetUserByExternalIDMethod][0], "Error in test case %v", n)
+		explanations, err := testAPI.SimulateAuthorizedExternalResources(test.requestInfo, test.externalID, test.statements,
+			test.replace, test.action, test.resourceUrns)
+		checkMethodResponse(t, n, test.wantError, err, test.expectedExplanations, explanations)
+	}
+}
+
+func TestGetAuthorizedPrincipals(t *testing.T) {
+	resourceUrn := GetUrnPrefix("acme", RESOURCE_POLICY, "/prod/")
+	policyAllow := &Policy{
+		ID:   "POLICY-ALLOW",
+		Org:  "acme",
+		Name: "policyAllow",
+		Urn:  CreateUrn("acme", RESOURCE_POLICY, "/", "policyAllow"),
+		Statements: &[]Statement{
+			{
+				Effect:    "allow",
+				Actions:   []string{POLICY_ACTION_DELETE_POLICY},
+				Resources: []string{resourceUrn},
+			},
+		},
+	}
+	policyDeny := &Policy{
+		ID:   "POLICY-DENY",
+		Org:  "acme",
+		Name: "policyDeny",
+		Urn:  CreateUrn("acme", RESOURCE_POLICY, "/", "policyDeny"),
+		Statements: &[]Statement{
+			{
+				Effect:    "deny",
+				Actions:   []string{POLICY_ACTION_DELETE_POLICY},
+				Resources: []string{GetUrnPrefix("acme", RESOURCE_POLICY, "/")},
+			},
+		},
+	}
+	policyConditional := &Policy{
+		ID:   "POLICY-CONDITIONAL",
+		Org:  "acme",
+		Name: "policyConditional",
+		Urn:  CreateUrn("acme", RESOURCE_POLICY, "/", "policyConditional"),
+		Statements: &[]Statement{
+			{
+				Effect:    "allow",
+				Actions:   []string{"iam:*"},
+				Resources: []string{GetUrnPrefix("acme", RESOURCE_POLICY, "/")},
+				Conditions: Conditions{
+					CONDITION_IP_ADDRESS: {
+						CONDITION_KEY_SOURCE_IP: []string{"10.0.0.0/8"},
+					},
+				},
+			},
+			{
+				Effect:    "deny",
+				Actions:   []string{"iam:*"},
+				Resources: []string{resourceUrn},
+				Conditions: Conditions{
+					CONDITION_IP_ADDRESS: {
+						CONDITION_KEY_SOURCE_IP: []string{"10.0.0.1"},
+					},
+				},
+			},
+		},
+	}
+	user1 := &User{ID: "USER1", ExternalID: "user1", Urn: CreateUrn("", RESOURCE_USER, "/", "user1")}
+	user2 := &User{ID: "USER2", ExternalID: "user2", Urn: CreateUrn("", RESOURCE_USER, "/", "user2")}
+	user3 := &User{ID: "USER3", ExternalID: "user3", Urn: CreateUrn("", RESOURCE_USER, "/", "user3")}
+	groups := []Group{
+		{ID: "GROUP1", Org: "acme", Name: "group1", Urn: CreateUrn("acme", RESOURCE_GROUP, "/", "group1")},
+		{ID: "GROUP2", Org: "acme", Name: "group2", Urn: CreateUrn("acme", RESOURCE_GROUP, "/", "group2")},
+		{ID: "GROUP3", Org: "acme", Name: "group3", Urn: CreateUrn("acme", RESOURCE_GROUP, "/", "group3")},
+	}
+	snapshot := &AuthzSnapshot{
+		Users:    []User{*user1, *user2, *user3},
+		Groups:   groups,
+		Policies: []Policy{*policyAllow, *policyDeny, *policyConditional},
+		Members: []AuthzSnapshotRelation{
+			{ID: "GROUP1", RelatedID: "USER1"},
+			{ID: "GROUP1", RelatedID: "USER2"},
+			{ID: "GROUP2", RelatedID: "USER2"},
+			{ID: "GROUP3", RelatedID: "USER3"},
+		},
+		GroupPolicies: []AuthzSnapshotRelation{
+			{ID: "GROUP1", RelatedID: "POLICY-ALLOW"},
+			{ID: "GROUP2", RelatedID: "POLICY-DENY"},
+			{ID: "GROUP3", RelatedID: "POLICY-CONDITIONAL"},
+		},
+	}
+	expectedPrincipals := &AuthorizedPrincipals{
+		Users: []AuthorizedUser{
+			{
+				ExternalID: "user1",
+				Urn:        user1.Urn,
+				Groups:     []GroupIdentity{{Org: "acme", Name: "group1"}},
+				Policies:   []PolicyIdentity{{Org: "acme", Name: "policyAllow"}},
+			},
+			{
+				ExternalID: "user3",
+				Urn:        user3.Urn,
+				Groups:     []GroupIdentity{{Org: "acme", Name: "group3"}},
+				Policies:   []PolicyIdentity{{Org: "acme", Name: "policyConditional"}},
+			},
+		},
+		Groups: []AuthorizedGroup{
+			{
+				Org:      "acme",
+				Name:     "group1",
+				Urn:      groups[0].Urn,
+				Policies: []PolicyIdentity{{Org: "acme", Name: "policyAllow"}},
+			},
+			{
+				Org:      "acme",
+				Name:     "group3",
+				Urn:      groups[2].Urn,
+				Policies: []PolicyIdentity{{Org: "acme", Name: "policyConditional"}},
+			},
+		},
+	}
+	// Policies of an auditor allowed to list users, groups and policies
+	auditorPolicies := func(groupsResource string) []Policy {
+		return []Policy{
+			{
+				ID:   "POLICY-AUDITOR",
+				Name: "policyAuditor",
+				Statements: &[]Statement{
+					{
+						Effect:    "allow",
+						Actions:   []string{USER_ACTION_LIST_USERS, POLICY_ACTION_LIST_POLICIES},
+						Resources: []string{GetUrnPrefix("", RESOURCE_USER, "/"), GetUrnPrefix("acme", RESOURCE_POLICY, "/")},
+					},
+					{
+						Effect:    "allow",
+						Actions:   []string{GROUP_ACTION_LIST_GROUPS},
+						Resources: []string{groupsResource},
+					},
+				},
+			},
 		}
 	}
+
+	testcases := map[string]struct {
+		// Authenticated user
+		requestInfo RequestInfo
+		// Method args
+		action      string
+		resourceUrn string
+		// Expected result
+		expectedPrincipals *AuthorizedPrincipals
+		wantError          error
+		// Manager Results
+		getAuthzSnapshotResult    *AuthzSnapshot
+		getUserByExternalIDResult *User
+		getPoliciesForUserResult  []Policy
+		// Manager Errors
+		getAuthzSnapshotError error
+	}{
+		"OktestCase": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			action:                 POLICY_ACTION_DELETE_POLICY,
+			resourceUrn:            resourceUrn,
+			expectedPrincipals:     expectedPrincipals,
+			getAuthzSnapshotResult: snapshot,
+		},
+		"OktestCaseInheritedGroup": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			action:      POLICY_ACTION_DELETE_POLICY,
+			resourceUrn: resourceUrn,
+			expectedPrincipals: &AuthorizedPrincipals{
+				Users: []AuthorizedUser{
+					{
+						ExternalID: "user3",
+						Urn:        user3.Urn,
+						Groups:     []GroupIdentity{{Org: "acme", Name: "group1"}},
+						Policies:   []PolicyIdentity{{Org: "acme", Name: "policyAllow"}},
+					},
+				},
+				Groups: []AuthorizedGroup{
+					{
+						Org:      "acme",
+						Name:     "group1",
+						Urn:      groups[0].Urn,
+						Policies: []PolicyIdentity{{Org: "acme", Name: "policyAllow"}},
+					},
+					{
+						Org:      "acme",
+						Name:     "group3",
+						Urn:      groups[2].Urn,
+						Policies: []PolicyIdentity{{Org: "acme", Name: "policyAllow"}},
+					},
+				},
+			},
+			// User3 is member of group3, which is subgroup of group1
+			getAuthzSnapshotResult: &AuthzSnapshot{
+				Users:         []User{*user3},
+				Groups:        []Group{groups[0], groups[2]},
+				Policies:      []Policy{*policyAllow},
+				Members:       []AuthzSnapshotRelation{{ID: "GROUP3", RelatedID: "USER3"}},
+				Subgroups:     []AuthzSnapshotRelation{{ID: "GROUP1", RelatedID: "GROUP3"}},
+				GroupPolicies: []AuthzSnapshotRelation{{ID: "GROUP1", RelatedID: "POLICY-ALLOW"}},
+			},
+		},
+		"OktestCaseUserPolicy": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			action:      POLICY_ACTION_DELETE_POLICY,
+			resourceUrn: resourceUrn,
+			expectedPrincipals: &AuthorizedPrincipals{
+				Users: []AuthorizedUser{
+					{
+						ExternalID: "user1",
+						Urn:        user1.Urn,
+						Groups:     []GroupIdentity{},
+						Policies:   []PolicyIdentity{{Org: "acme", Name: "policyAllow"}},
+					},
+				},
+				Groups: []AuthorizedGroup{},
+			},
+			getAuthzSnapshotResult: &AuthzSnapshot{
+				Users:        []User{*user1},
+				Policies:     []Policy{*policyAllow},
+				UserPolicies: []AuthzSnapshotRelation{{ID: "USER1", RelatedID: "POLICY-ALLOW"}},
+			},
+		},
+		"OktestCaseFullReadAccess": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      false,
+			},
+			action:                 POLICY_ACTION_DELETE_POLICY,
+			resourceUrn:            resourceUrn,
+			expectedPrincipals:     expectedPrincipals,
+			getAuthzSnapshotResult: snapshot,
+			getUserByExternalIDResult: &User{
+				ID:         "123456",
+				ExternalID: "123456",
+			},
+			getPoliciesForUserResult: auditorPolicies(GetUrnPrefix("acme", RESOURCE_GROUP, "/")),
+		},
+		"OktestCaseNoGroups": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			action:      POLICY_ACTION_DELETE_POLICY,
+			resourceUrn: resourceUrn,
+			expectedPrincipals: &AuthorizedPrincipals{
+				Users:  []AuthorizedUser{},
+				Groups: []AuthorizedGroup{},
+			},
+			getAuthzSnapshotResult: &AuthzSnapshot{},
+		},
+		"ErrortestCaseInvalidAction": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			action:      "valid::Action",
+			resourceUrn: resourceUrn,
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter action, value: valid::Action",
+			},
+		},
+		"ErrortestCaseActionPrefix": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			action:      "iam:*",
+			resourceUrn: resourceUrn,
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter action iam:*. Action parameter can't be a prefix",
+			},
+		},
+		"ErrortestCaseInvalidResource": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			action:      POLICY_ACTION_DELETE_POLICY,
+			resourceUrn: "urn:invalid/resource:resource",
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter urn, value: urn:invalid/resource:resource",
+			},
+		},
+		"ErrortestCasePolicyVariableResource": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			action:      POLICY_ACTION_DELETE_POLICY,
+			resourceUrn: "urn:iws:iam:org1:user/path/${user.externalId}",
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter urn, value: urn:iws:iam:org1:user/path/${user.externalId}",
+			},
+		},
+		"ErrortestCaseGetAuthzSnapshotError": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			action:      POLICY_ACTION_DELETE_POLICY,
+			resourceUrn: resourceUrn,
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			getAuthzSnapshotError: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrortestCaseUnauthorized": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      false,
+			},
+			action:      POLICY_ACTION_DELETE_POLICY,
+			resourceUrn: resourceUrn,
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "User with externalId 123456 is not allowed to access to resource *",
+			},
+			getAuthzSnapshotResult: snapshot,
+			getUserByExternalIDResult: &User{
+				ID:         "123456",
+				ExternalID: "123456",
+			},
+		},
+		"ErrortestCaseHiddenGroups": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      false,
+			},
+			action:      POLICY_ACTION_DELETE_POLICY,
+			resourceUrn: resourceUrn,
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "User with externalId 123456 is not allowed to list all users, groups and policies",
+			},
+			getAuthzSnapshotResult: snapshot,
+			getUserByExternalIDResult: &User{
+				ID:         "123456",
+				ExternalID: "123456",
+			},
+			// Group2 with the deny policy is hidden
+			getPoliciesForUserResult: auditorPolicies(groups[0].Urn),
+		},
+	}
+
+	for n, test := range testcases {
+
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetAuthzSnapshotMethod][0] = test.getAuthzSnapshotResult
+		testRepo.ArgsOut[GetAuthzSnapshotMethod][1] = test.getAuthzSnapshotError
+		testRepo.ArgsOut[GetUserByExternalIDMethod][0] = test.getUserByExternalIDResult
+		testRepo.ArgsOut[GetPoliciesForUserMethod][0] = test.getPoliciesForUserResult
+
+		principals, err := testAPI.GetAuthorizedPrincipals(test.requestInfo, test.action, test.resourceUrn)
+		checkMethodResponse(t, n, test.wantError, err, test.expectedPrincipals, principals)
+	}
 }
 
 // Test for aux methods of Foulkon
@@ -1178,7 +2368,7 @@ func TestGetRestrictions(t *testing.T) {
 		testRepo.ArgsOut[GetAttachedPoliciesMethod][0] = test.getAttachedPoliciesResult
 		testRepo.ArgsOut[GetAttachedPoliciesMethod][2] = test.getAttachedPoliciesError
 
-		restrictions, err := testAPI.getRestrictions(test.authUserID, test.action, test.resourceUrn)
+		restrictions, err := testAPI.getRestrictions(RequestInfo{Identifier: test.authUserID}, test.action, test.resourceUrn)
 		checkMethodResponse(t, n, test.wantError, err, test.expectedRestrictions, restrictions)
 		if test.wantError == nil {
 			assert.Equal(t, test.authUserID, testRepo.ArgsIn[GetUserByExternalIDMethod][0], "Error in test case %v", n)
@@ -1190,47 +2380,126 @@ func TestGetRestrictions(t *testing.T) {
 	}
 }
 
-func TestGetGroupsByUser(t *testing.T) {
+func TestReplacePolicyVariables(t *testing.T) {
+	user := &User{
+		ExternalID: "user1",
+		Path:       "/path/",
+		Urn:        CreateUrn("", RESOURCE_USER, "/path/", "user1"),
+	}
+	statements := []Statement{
+		{
+			Effect: "allow",
+			Actions: []string{
+				USER_ACTION_GET_USER,
+			},
+			Resources: []string{
+				POLICY_VARIABLE_USER_URN,
+				"urn:iws:iam::user${user.path}*",
+				"urn:ews:product:instance:resource/${user.externalId}/*",
+				"urn:ews:product:instance:resource/fixed",
+			},
+		},
+	}
+	policies := []Policy{
+		{
+			ID:         "POLICY-ID",
+			Statements: &statements,
+		},
+		{
+			ID: "POLICY-WITHOUT-STATEMENTS",
+		},
+	}
+
+	expectedStatements := []Statement{
+		{
+			Effect: "allow",
+			Actions: []string{
+				USER_ACTION_GET_USER,
+			},
+			Resources: []string{
+				CreateUrn("", RESOURCE_USER, "/path/", "user1"),
+				"urn:iws:iam::user/path/*",
+				"urn:ews:product:instance:resource/user1/*",
+				"urn:ews:product:instance:resource/fixed",
+			},
+		},
+	}
+	expectedPolicies := []Policy{
+		{
+			ID:         "POLICY-ID",
+			Statements: &expectedStatements,
+		},
+		{
+			ID: "POLICY-WITHOUT-STATEMENTS",
+		},
+	}
+
+	assert.Equal(t, expectedPolicies, replacePolicyVariables(policies, user), "Error replacing policy variables")
+	// Original policies must remain unchanged
+	assert.Equal(t, "urn:ews:product:instance:resource/${user.externalId}/*", (*policies[0].Statements)[0].Resources[2],
+		"Error, original policies modified")
+	assert.Nil(t, replacePolicyVariables(nil, user), "Error replacing policy variables of nil policies")
+}
+
+func TestGetPoliciesByUser(t *testing.T) {
 	testcases := map[string]struct {
-		// User ID to retrieve its groups
-		userID string
-		// Expected Groups
-		expectedGroups []Group
+		// User to retrieve its policies
+		user *User
+		// Expected Policies
+		expectedPolicies []Policy
 		// Error to compare when we expect an error
 		wantError error
-		// GetGroupsByUserID Method Out Arguments
-		getGroupsByUserIDResult []TestUserGroupRelation
-		getGroupsByUserIDError  error
+		// GetPoliciesForUser Method Out Arguments
+		getPoliciesForUserResult   []Policy
+		getPoliciesForUserGroupIDs []string
+		getPoliciesForUserError    error
 	}{
 		"OktestCase": {
-			userID: "UserID",
-			expectedGroups: []Group{
+			user: &User{
+				ID:         "UserID",
+				ExternalID: "user1",
+			},
+			expectedPolicies: []Policy{
 				{
-					ID: "GROUP-USER-ID1",
+					ID: "POLICY-ID1",
+					Statements: &[]Statement{
+						{
+							Effect:    "allow",
+							Actions:   []string{USER_ACTION_GET_USER},
+							Resources: []string{"urn:ews:product:instance:resource/user1/*"},
+						},
+					},
 				},
 				{
-					ID: "GROUP-USER-ID2",
+					ID: "POLICY-ID2",
 				},
 			},
-			getGroupsByUserIDResult: []TestUserGroupRelation{
+			getPoliciesForUserResult: []Policy{
 				{
-					Group: &Group{
-						ID: "GROUP-USER-ID1",
+					ID: "POLICY-ID1",
+					Statements: &[]Statement{
+						{
+							Effect:    "allow",
+							Actions:   []string{USER_ACTION_GET_USER},
+							Resources: []string{"urn:ews:product:instance:resource/${user.externalId}/*"},
+						},
 					},
 				},
 				{
-					Group: &Group{
-						ID: "GROUP-USER-ID2",
-					},
+					ID: "POLICY-ID2",
 				},
 			},
+			getPoliciesForUserGroupIDs: []string{"GROUP-ID1", "GROUP-ID2"},
 		},
 		"ErrortestCase": {
-			userID: "UserID",
+			user: &User{
+				ID:         "UserID",
+				ExternalID: "user1",
+			},
 			wantError: &Error{
 				Code: UNKNOWN_API_ERROR,
 			},
-			getGroupsByUserIDError: &database.Error{
+			getPoliciesForUserError: &database.Error{
 				Code: database.INTERNAL_ERROR,
 			},
 		},
@@ -1241,12 +2510,16 @@ func TestGetGroupsByUser(t *testing.T) {
 		testRepo := makeTestRepo()
 		testAPI := makeTestAPI(testRepo)
 
-		testRepo.ArgsOut[GetGroupsByUserIDMethod][0] = test.getGroupsByUserIDResult
-		testRepo.ArgsOut[GetGroupsByUserIDMethod][2] = test.getGroupsByUserIDError
+		testRepo.ArgsOut[GetPoliciesForUserMethod][0] = test.getPoliciesForUserResult
+		testRepo.ArgsOut[GetPoliciesForUserMethod][1] = test.getPoliciesForUserGroupIDs
+		testRepo.ArgsOut[GetPoliciesForUserMethod][2] = test.getPoliciesForUserError
 
-		groups, err := testAPI.getGroupsByUser(test.userID)
-		checkMethodResponse(t, n, test.wantError, err, test.expectedGroups, groups)
-		assert.Equal(t, test.userID, testRepo.ArgsIn[GetGroupsByUserIDMethod][0], "Error in test case %v", n)
+		policies, groupIDs, err := testAPI.getPoliciesByUser(test.user)
+		checkMethodResponse(t, n, test.wantError, err, test.expectedPolicies, policies)
+		if test.wantError == nil {
+			assert.Equal(t, test.getPoliciesForUserGroupIDs, groupIDs, "Error in test case %v", n)
+		}
+		assert.Equal(t, test.user.ID, testRepo.ArgsIn[GetPoliciesForUserMethod][0], "Error in test case %v", n)
 	}
 }
 
@@ -1341,6 +2614,7 @@ func TestGetStatementsByRequestedAction(t *testing.T) {
 		// Policies to retrieve its statements according to an action
 		policies []Policy
 		action   string
+		context  RequestContext
 		// Expected data
 		expectedStatements []Statement
 	}{
@@ -1431,10 +2705,96 @@ func TestGetStatementsByRequestedAction(t *testing.T) {
 				},
 			},
 		},
+		"OktestCaseFilteredStatementsByConditions": {
+			policies: []Policy{
+				{
+					ID: "PolicyID1",
+					Statements: &[]Statement{
+						{
+							Effect: "allow",
+							Actions: []string{
+								"action",
+							},
+							Resources: []string{
+								GetUrnPrefix("example", RESOURCE_GROUP, "/path1/"),
+							},
+							Conditions: Conditions{
+								CONDITION_IP_ADDRESS: {
+									CONDITION_KEY_SOURCE_IP: {"10.0.0.0/8"},
+								},
+							},
+						},
+						{
+							Effect: "allow",
+							Actions: []string{
+								"action",
+							},
+							Resources: []string{
+								GetUrnPrefix("example", RESOURCE_GROUP, "/path2/"),
+							},
+							Conditions: Conditions{
+								CONDITION_IP_ADDRESS: {
+									CONDITION_KEY_SOURCE_IP: {"192.168.1.1"},
+								},
+							},
+						},
+						{
+							Effect: "deny",
+							Actions: []string{
+								"action",
+							},
+							Resources: []string{
+								GetUrnPrefix("example", RESOURCE_GROUP, "/path3/"),
+							},
+							Conditions: Conditions{
+								CONDITION_STRING_EQUALS: {
+									"request:env": {"prod"},
+								},
+							},
+						},
+					},
+				},
+			},
+			action: "action",
+			context: RequestContext{
+				SourceIP: "10.1.2.3",
+			},
+			expectedStatements: []Statement{
+				{
+					Effect: "allow",
+					Actions: []string{
+						"action",
+					},
+					Resources: []string{
+						GetUrnPrefix("example", RESOURCE_GROUP, "/path1/"),
+					},
+					Conditions: Conditions{
+						CONDITION_IP_ADDRESS: {
+							CONDITION_KEY_SOURCE_IP: {"10.0.0.0/8"},
+						},
+					},
+				},
+				// Deny statement applies although request:env isn't in context
+				{
+					Effect: "deny",
+					Actions: []string{
+						"action",
+					},
+					Resources: []string{
+						GetUrnPrefix("example", RESOURCE_GROUP, "/path3/"),
+					},
+					Conditions: Conditions{
+						CONDITION_STRING_EQUALS: {
+							"request:env": {"prod"},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for n, test := range testcases {
-		statements := getStatementsByRequestedAction(test.policies, test.action)
+		statements := getStatementsByRequestedAction(test.policies, test.action, test.context)
 		checkMethodResponse(t, n, nil, nil, test.expectedStatements, statements)
 	}
 }
@@ -1498,6 +2858,168 @@ func TestIsActionContained(t *testing.T) {
 	}
 }
 
+func TestAreConditionsSatisfied(t *testing.T) {
+	requestTime, _ := time.Parse(time.RFC3339, "2016-06-01T10:30:00Z")
+	context := RequestContext{
+		SourceIP:    "10.1.2.3",
+		RequestTime: requestTime,
+		Attributes: map[string]string{
+			"env": "prod",
+		},
+	}
+	testcases := map[string]struct {
+		conditions Conditions
+		expected   bool
+	}{
+		"OkCaseEmptyConditions": {
+			expected: true,
+		},
+		"OkCaseStringEquals": {
+			conditions: Conditions{
+				CONDITION_STRING_EQUALS: {"request:env": {"dev", "prod"}},
+			},
+			expected: true,
+		},
+		"OkCaseStringEqualsNoMatch": {
+			conditions: Conditions{
+				CONDITION_STRING_EQUALS: {"request:env": {"dev"}},
+			},
+			expected: false,
+		},
+		"OkCaseStringNotEquals": {
+			conditions: Conditions{
+				CONDITION_STRING_NOT_EQUALS: {"request:env": {"dev"}},
+			},
+			expected: true,
+		},
+		"OkCaseKeyNotInContext": {
+			conditions: Conditions{
+				CONDITION_STRING_NOT_EQUALS: {"request:other": {"dev"}},
+			},
+			expected: false,
+		},
+		"OkCaseIpAddressCIDR": {
+			conditions: Conditions{
+				CONDITION_IP_ADDRESS: {CONDITION_KEY_SOURCE_IP: {"10.0.0.0/8"}},
+			},
+			expected: true,
+		},
+		"OkCaseIpAddressSingleIP": {
+			conditions: Conditions{
+				CONDITION_IP_ADDRESS: {CONDITION_KEY_SOURCE_IP: {"10.1.2.4"}},
+			},
+			expected: false,
+		},
+		"OkCaseNotIpAddress": {
+			conditions: Conditions{
+				CONDITION_NOT_IP_ADDRESS: {CONDITION_KEY_SOURCE_IP: {"192.168.0.0/16"}},
+			},
+			expected: true,
+		},
+		"OkCaseDateLessThan": {
+			conditions: Conditions{
+				CONDITION_DATE_LESS_THAN: {CONDITION_KEY_CURRENT_TIME: {"2016-07-01T00:00:00Z"}},
+			},
+			expected: true,
+		},
+		"OkCaseDateGreaterThanNoMatch": {
+			conditions: Conditions{
+				CONDITION_DATE_GREATER_THAN: {CONDITION_KEY_CURRENT_TIME: {"2016-07-01T00:00:00Z"}},
+			},
+			expected: false,
+		},
+		"OkCaseBusinessHours": {
+			conditions: Conditions{
+				CONDITION_DATE_GREATER_THAN: {CONDITION_KEY_CURRENT_TIME: {"08:00"}},
+				CONDITION_DATE_LESS_THAN:    {CONDITION_KEY_CURRENT_TIME: {"18:00"}},
+			},
+			expected: true,
+		},
+		"OkCaseOutOfBusinessHours": {
+			conditions: Conditions{
+				CONDITION_DATE_GREATER_THAN: {CONDITION_KEY_CURRENT_TIME: {"11:00"}},
+				CONDITION_DATE_LESS_THAN:    {CONDITION_KEY_CURRENT_TIME: {"18:00"}},
+			},
+			expected: false,
+		},
+		"OkCaseSeveralOperators": {
+			conditions: Conditions{
+				CONDITION_IP_ADDRESS:    {CONDITION_KEY_SOURCE_IP: {"10.0.0.0/8"}},
+				CONDITION_STRING_EQUALS: {"request:env": {"dev"}},
+			},
+			expected: false,
+		},
+	}
+
+	for n, test := range testcases {
+		assert.Equal(t, test.expected, areConditionsSatisfied(test.conditions, context, false), "Error in test case %v", n)
+	}
+}
+
+func TestIsStatementInContext(t *testing.T) {
+	context := RequestContext{
+		SourceIP: "10.1.2.3",
+		Attributes: map[string]string{
+			"env": "prod",
+		},
+	}
+	testcases := map[string]struct {
+		statement Statement
+		expected  bool
+	}{
+		"OkCaseAllowMissingKey": {
+			statement: Statement{
+				Effect: "allow",
+				Conditions: Conditions{
+					CONDITION_STRING_EQUALS: {"request:team": {"admins"}},
+				},
+			},
+			expected: false,
+		},
+		"OkCaseDenyMissingKey": {
+			statement: Statement{
+				Effect: "deny",
+				Conditions: Conditions{
+					CONDITION_STRING_EQUALS: {"request:team": {"guests"}},
+				},
+			},
+			expected: true,
+		},
+		"OkCaseDenyKeyNotMatched": {
+			statement: Statement{
+				Effect: "deny",
+				Conditions: Conditions{
+					CONDITION_STRING_EQUALS: {"request:env": {"dev"}},
+				},
+			},
+			expected: false,
+		},
+		"OkCaseDenyMissingKeyAndKeyNotMatched": {
+			statement: Statement{
+				Effect: "deny",
+				Conditions: Conditions{
+					CONDITION_STRING_EQUALS: {"request:team": {"guests"}},
+					CONDITION_IP_ADDRESS:    {CONDITION_KEY_SOURCE_IP: {"192.168.0.0/16"}},
+				},
+			},
+			expected: false,
+		},
+		"OkCaseDenyKeyMatched": {
+			statement: Statement{
+				Effect: "deny",
+				Conditions: Conditions{
+					CONDITION_STRING_EQUALS: {"request:env": {"prod"}},
+				},
+			},
+			expected: true,
+		},
+	}
+
+	for n, test := range testcases {
+		assert.Equal(t, test.expected, isStatementInContext(test.statement, context), "Error in test case %v", n)
+	}
+}
+
 func TestIsResourceContained(t *testing.T) {
 	testcases := map[string]struct {
 		resource         string
@@ -2091,6 +3613,52 @@ func TestGetRestrictionsWhenResourceRequestedIsPrefix(t *testing.T) {
 				DeniedFullUrns: []string{},
 			},
 		},
+		"OktestCaseStatementNotResources": {
+			statements: []Statement{
+				{
+					Effect: "allow",
+					Actions: []string{
+						USER_ACTION_GET_USER,
+					},
+					NotResources: []string{
+						GetUrnPrefix("", RESOURCE_USER, "/path/admin/"),
+						GetUrnPrefix("", RESOURCE_USER, "/other/"),
+					},
+				},
+				{
+					Effect: "deny",
+					Actions: []string{
+						USER_ACTION_GET_USER,
+					},
+					NotResources: []string{
+						GetUrnPrefix("", RESOURCE_USER, "/"),
+					},
+				},
+				{
+					Effect: "deny",
+					Actions: []string{
+						USER_ACTION_GET_USER,
+					},
+					NotResources: []string{
+						CreateUrn("", RESOURCE_USER, "/other/", "user"),
+					},
+				},
+			},
+			resource: GetUrnPrefix("", RESOURCE_USER, "/path"),
+			expectedRestrictions: &Restrictions{
+				AllowedUrnPrefixes: []string{},
+				AllowedFullUrns:    []string{},
+				DeniedUrnPrefixes: []string{
+					GetUrnPrefix("", RESOURCE_USER, "/path"),
+				},
+				DeniedFullUrns: []string{},
+				AllowedExceptUrns: [][]string{
+					{
+						GetUrnPrefix("", RESOURCE_USER, "/path/admin/"),
+					},
+				},
+			},
+		},
 		"OktestCaseStatementResourceIsFull": {
 			statements: []Statement{
 				{
@@ -2132,6 +3700,56 @@ func TestGetRestrictionsWhenResourceRequestedIsPrefix(t *testing.T) {
 	}
 }
 
+func TestIsStatementActionContained(t *testing.T) {
+	testcases := map[string]struct {
+		actionRequested  string
+		statement        Statement
+		expectedResponse bool
+	}{
+		"OktestCaseActionContained": {
+			actionRequested: "product:action",
+			statement: Statement{
+				Actions: []string{
+					"product:*",
+				},
+			},
+			expectedResponse: true,
+		},
+		"OktestCaseActionNotContained": {
+			actionRequested: "product:action",
+			statement: Statement{
+				Actions: []string{
+					"product:otherAction",
+				},
+			},
+			expectedResponse: false,
+		},
+		"OktestCaseActionNotContainedInNotActions": {
+			actionRequested: "product:action",
+			statement: Statement{
+				NotActions: []string{
+					"product:otherAction",
+				},
+			},
+			expectedResponse: true,
+		},
+		"OktestCaseActionContainedInNotActions": {
+			actionRequested: "product:action",
+			statement: Statement{
+				NotActions: []string{
+					"product:act*",
+				},
+			},
+			expectedResponse: false,
+		},
+	}
+
+	for n, test := range testcases {
+		isContained := isStatementActionContained(test.actionRequested, test.statement)
+		checkMethodResponse(t, n, nil, nil, test.expectedResponse, isContained)
+	}
+}
+
 func TestGetRestrictionsWhenResourceRequestedIsFullUrn(t *testing.T) {
 	testcases := map[string]struct {
 		statements           []Statement
@@ -2383,6 +4001,59 @@ func TestIsAllowedResource(t *testing.T) {
 			},
 			expectedData: false,
 		},
+		"OktestCaseAllowedByExceptUrns": {
+			resource: User{
+				Urn: CreateUrn("", RESOURCE_USER, "/path/", "user"),
+			},
+			restrictions: Restrictions{
+				AllowedUrnPrefixes: []string{},
+				AllowedFullUrns:    []string{},
+				DeniedUrnPrefixes:  []string{},
+				DeniedFullUrns:     []string{},
+				AllowedExceptUrns: [][]string{
+					{
+						GetUrnPrefix("", RESOURCE_USER, "/admin/"),
+					},
+				},
+			},
+			expectedData: true,
+		},
+		"OktestCaseNotAllowedByExceptUrns": {
+			resource: User{
+				Urn: CreateUrn("", RESOURCE_USER, "/admin/", "user"),
+			},
+			restrictions: Restrictions{
+				AllowedUrnPrefixes: []string{},
+				AllowedFullUrns:    []string{},
+				DeniedUrnPrefixes:  []string{},
+				DeniedFullUrns:     []string{},
+				AllowedExceptUrns: [][]string{
+					{
+						GetUrnPrefix("", RESOURCE_USER, "/admin/"),
+					},
+				},
+			},
+			expectedData: false,
+		},
+		"OktestCaseDeniedByExceptUrns": {
+			resource: User{
+				Urn: CreateUrn("", RESOURCE_USER, "/path/", "user"),
+			},
+			restrictions: Restrictions{
+				AllowedUrnPrefixes: []string{
+					GetUrnPrefix("", RESOURCE_USER, "/path"),
+				},
+				AllowedFullUrns:   []string{},
+				DeniedUrnPrefixes: []string{},
+				DeniedFullUrns:    []string{},
+				DeniedExceptUrns: [][]string{
+					{
+						CreateUrn("", RESOURCE_USER, "/path/", "other"),
+					},
+				},
+			},
+			expectedData: false,
+		},
 		"OktestCaseAllowedWithPrefixAndFull": {
 			resource: User{
 				Urn: CreateUrn("", RESOURCE_USER, "/path/", "user"),
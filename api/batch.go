@@ -0,0 +1,175 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/Tecsisa/foulkon/database"
+)
+
+const (
+	// Maximum number of operations in a batch
+	BATCH_MAX_OPERATIONS = 500
+)
+
+// TYPE DEFINITIONS
+
+// BatchOperation is a change to apply in a batch. Action is the IAM action of the API method to call, and the
+// rest of the fields are the parameters of that method. Fields not used by the action are ignored
+type BatchOperation struct {
+	Action     string      `json:"action,omitempty"`
+	ExternalID string      `json:"externalId,omitempty"`
+	Org        string      `json:"org,omitempty"`
+	Name       string      `json:"name,omitempty"`
+	Path       string      `json:"path,omitempty"`
+	NewName    string      `json:"newName,omitempty"`
+	NewPath    string      `json:"newPath,omitempty"`
+	Group      string      `json:"group,omitempty"`
+	Policy     string      `json:"policy,omitempty"`
+	Statements []Statement `json:"statements,omitempty"`
+}
+
+// BatchOperationResult is the result of a batch operation. Resource is the user, group or policy created or
+// updated by the operation, and it's empty for the rest of actions
+type BatchOperationResult struct {
+	Action   string      `json:"action,omitempty"`
+	Resource interface{} `json:"resource,omitempty"`
+}
+
+// BATCH API IMPLEMENTATION
+
+func (api WorkerAPI) ExecuteBatch(requestInfo RequestInfo, operations []BatchOperation) ([]BatchOperationResult, error) {
+	// Validate fields
+	if len(operations) < 1 || len(operations) > BATCH_MAX_OPERATIONS {
+		return nil, &Error{
+			Code: INVALID_PARAMETER_ERROR,
+			Message: fmt.Sprintf("Invalid parameter: operations %v, number of operations must be between 1 and %v",
+				len(operations), BATCH_MAX_OPERATIONS),
+		}
+	}
+	for i, operation := range operations {
+		if !isBatchAction(operation.Action) {
+			return nil, &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: fmt.Sprintf("Invalid parameter: action %v in operation %v", operation.Action, i),
+			}
+		}
+	}
+	if api.TransactionRepo == nil {
+		return nil, &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: "Batch operations aren't supported by the database",
+		}
+	}
+
+	// Entity tags of the request don't refer to the resources of the operations
+	requestInfo.IfMatch = nil
+
+	// Call repo to run the operations in a transaction
+	results := []BatchOperationResult{}
+	err := api.TransactionRepo.RunInTransaction(func(repos Repositories) error {
+		// Operations use the transaction repos, so their audit events are stored in the transaction too. Cache is
+		// disabled, so it doesn't store uncommitted policies
+		txAPI := api
+		txAPI.UserRepo = repos.UserRepo
+		txAPI.GroupRepo = repos.GroupRepo
+		txAPI.PolicyRepo = repos.PolicyRepo
+		txAPI.ProxyRepo = repos.ProxyRepo
+		txAPI.AuthOidcRepo = repos.AuthOidcRepo
+		txAPI.AuditRepo = repos.AuditRepo
+		txAPI.AuthzCache = nil
+		txAPI.TransactionRepo = nil
+
+		for i, operation := range operations {
+			resource, err := txAPI.executeBatchOperation(requestInfo, operation)
+			if err != nil {
+				apiError := err.(*Error)
+				return &Error{
+					Code:    apiError.Code,
+					Message: fmt.Sprintf("Operation %v with action %v failed: %v", i, operation.Action, apiError.Message),
+				}
+			}
+			results = append(results, BatchOperationResult{
+				Action:   operation.Action,
+				Resource: resource,
+			})
+		}
+		return nil
+	})
+
+	// Error handling
+	if err != nil {
+		if apiError, ok := err.(*Error); ok {
+			return nil, apiError
+		}
+		//Transform to DB error
+		dbError := err.(*database.Error)
+		return nil, &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: dbError.Message,
+		}
+	}
+
+	// Operations may change the policies of any user
+	api.AuthzCache.Flush()
+
+	LogOperation(requestInfo.RequestID, requestInfo.Identifier, fmt.Sprintf("Batch of %v operations executed", len(operations)))
+
+	return results, nil
+}
+
+// PRIVATE HELPER METHODS
+
+// Return true if action can be used in a batch operation
+func isBatchAction(action string) bool {
+	switch action {
+	case USER_ACTION_CREATE_USER, USER_ACTION_UPDATE_USER, USER_ACTION_DELETE_USER,
+		USER_ACTION_ATTACH_USER_POLICY, USER_ACTION_DETACH_USER_POLICY,
+		GROUP_ACTION_CREATE_GROUP, GROUP_ACTION_UPDATE_GROUP, GROUP_ACTION_DELETE_GROUP,
+		GROUP_ACTION_ADD_MEMBER, GROUP_ACTION_REMOVE_MEMBER,
+		GROUP_ACTION_ATTACH_GROUP_POLICY, GROUP_ACTION_DETACH_GROUP_POLICY,
+		POLICY_ACTION_CREATE_POLICY, POLICY_ACTION_UPDATE_POLICY, POLICY_ACTION_DELETE_POLICY:
+		return true
+	default:
+		return false
+	}
+}
+
+// Call the API method of the operation action, returning the resource created or updated
+func (api WorkerAPI) executeBatchOperation(requestInfo RequestInfo, operation BatchOperation) (interface{}, error) {
+	switch operation.Action {
+	// Users
+	case USER_ACTION_CREATE_USER:
+		return api.AddUser(requestInfo, operation.ExternalID, operation.Path)
+	case USER_ACTION_UPDATE_USER:
+		return api.UpdateUser(requestInfo, operation.ExternalID, operation.NewPath)
+	case USER_ACTION_DELETE_USER:
+		return nil, api.RemoveUser(requestInfo, operation.ExternalID)
+	case USER_ACTION_ATTACH_USER_POLICY:
+		return nil, api.AttachPolicyToUser(requestInfo, operation.ExternalID, operation.Org, operation.Policy)
+	case USER_ACTION_DETACH_USER_POLICY:
+		return nil, api.DetachPolicyToUser(requestInfo, operation.ExternalID, operation.Org, operation.Policy)
+	// Groups
+	case GROUP_ACTION_CREATE_GROUP:
+		return api.AddGroup(requestInfo, operation.Org, operation.Name, operation.Path)
+	case GROUP_ACTION_UPDATE_GROUP:
+		return api.UpdateGroup(requestInfo, operation.Org, operation.Name, operation.NewName, operation.NewPath)
+	case GROUP_ACTION_DELETE_GROUP:
+		return nil, api.RemoveGroup(requestInfo, operation.Org, operation.Name)
+	case GROUP_ACTION_ADD_MEMBER:
+		return nil, api.AddMember(requestInfo, operation.ExternalID, operation.Group, operation.Org)
+	case GROUP_ACTION_REMOVE_MEMBER:
+		return nil, api.RemoveMember(requestInfo, operation.ExternalID, operation.Group, operation.Org)
+	case GROUP_ACTION_ATTACH_GROUP_POLICY:
+		return nil, api.AttachPolicyToGroup(requestInfo, operation.Org, operation.Group, operation.Policy)
+	case GROUP_ACTION_DETACH_GROUP_POLICY:
+		return nil, api.DetachPolicyToGroup(requestInfo, operation.Org, operation.Group, operation.Policy)
+	// Policies
+	case POLICY_ACTION_CREATE_POLICY:
+		return api.AddPolicy(requestInfo, operation.Name, operation.Path, operation.Org, operation.Statements)
+	case POLICY_ACTION_UPDATE_POLICY:
+		return api.UpdatePolicy(requestInfo, operation.Org, operation.Name, operation.NewName, operation.NewPath,
+			operation.Statements)
+	default: // Policy delete
+		return nil, api.RemovePolicy(requestInfo, operation.Org, operation.Name)
+	}
+}
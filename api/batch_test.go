@@ -0,0 +1,276 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/Tecsisa/foulkon/database"
+)
+
+func TestWorkerAPI_ExecuteBatch(t *testing.T) {
+	testcases := map[string]struct {
+		// API Method args
+		requestInfo RequestInfo
+		operations  []BatchOperation
+		// Expected result
+		expectedResponse []BatchOperationResult
+		wantError        error
+		// Manager Results
+		getUserByExternalIDResult *User
+		getGroupByNameResult      *Group
+		getPolicyByNameResult     *Policy
+		addUserResult             *User
+		// Manager Errors
+		getUserByExternalIDMethodErr error
+		attachPolicyMethodErr        error
+		addAuditEventMethodErr       error
+		runInTransactionMethodErr    error
+	}{
+		"OkCase": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			operations: []BatchOperation{
+				{
+					Action:     GROUP_ACTION_ADD_MEMBER,
+					ExternalID: "12345",
+					Org:        "org1",
+					Group:      "group1",
+				},
+				{
+					Action: GROUP_ACTION_ATTACH_GROUP_POLICY,
+					Org:    "org1",
+					Group:  "group1",
+					Policy: "policy1",
+				},
+			},
+			expectedResponse: []BatchOperationResult{
+				{
+					Action: GROUP_ACTION_ADD_MEMBER,
+				},
+				{
+					Action: GROUP_ACTION_ATTACH_GROUP_POLICY,
+				},
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "12345",
+				Path:       "/test/",
+			},
+			getGroupByNameResult: &Group{
+				ID:   "GROUP-ID",
+				Name: "group1",
+				Org:  "org1",
+				Path: "/test/",
+			},
+			getPolicyByNameResult: &Policy{
+				ID:   "POLICY-ID",
+				Name: "policy1",
+				Org:  "org1",
+				Path: "/test/",
+			},
+		},
+		"OkCaseCreateUser": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			operations: []BatchOperation{
+				{
+					Action:     USER_ACTION_CREATE_USER,
+					ExternalID: "12345",
+					Path:       "/test/",
+				},
+			},
+			expectedResponse: []BatchOperationResult{
+				{
+					Action: USER_ACTION_CREATE_USER,
+					Resource: &User{
+						ID:         "543210",
+						ExternalID: "12345",
+						Path:       "/test/",
+					},
+				},
+			},
+			addUserResult: &User{
+				ID:         "543210",
+				ExternalID: "12345",
+				Path:       "/test/",
+			},
+			getUserByExternalIDMethodErr: &database.Error{
+				Code: database.USER_NOT_FOUND,
+			},
+		},
+		"ErrorCaseNoOperations": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			operations: []BatchOperation{},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: operations 0, number of operations must be between 1 and 500",
+			},
+		},
+		"ErrorCaseTooManyOperations": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			operations: make([]BatchOperation, BATCH_MAX_OPERATIONS+1),
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: operations 501, number of operations must be between 1 and 500",
+			},
+		},
+		"ErrorCaseInvalidAction": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			operations: []BatchOperation{
+				{
+					Action:     USER_ACTION_DELETE_USER,
+					ExternalID: "12345",
+				},
+				{
+					Action: POLICY_ACTION_LIST_POLICIES,
+				},
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: action iam:ListPolicies in operation 1",
+			},
+		},
+		"ErrorCaseOperationFailed": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			operations: []BatchOperation{
+				{
+					Action:     GROUP_ACTION_ADD_MEMBER,
+					ExternalID: "12345",
+					Org:        "org1",
+					Group:      "group1",
+				},
+				{
+					Action: GROUP_ACTION_ATTACH_GROUP_POLICY,
+					Org:    "org1",
+					Group:  "group1",
+					Policy: "policy1",
+				},
+			},
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Operation 1 with action iam:AttachGroupPolicy failed: Error",
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "12345",
+				Path:       "/test/",
+			},
+			getGroupByNameResult: &Group{
+				ID:   "GROUP-ID",
+				Name: "group1",
+				Org:  "org1",
+				Path: "/test/",
+			},
+			getPolicyByNameResult: &Policy{
+				ID:   "POLICY-ID",
+				Name: "policy1",
+				Org:  "org1",
+				Path: "/test/",
+			},
+			attachPolicyMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseAuditEventFailed": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			operations: []BatchOperation{
+				{
+					Action:     GROUP_ACTION_ADD_MEMBER,
+					ExternalID: "12345",
+					Org:        "org1",
+					Group:      "group1",
+				},
+			},
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Operation 0 with action iam:AddMember failed: Error",
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "12345",
+				Path:       "/test/",
+			},
+			getGroupByNameResult: &Group{
+				ID:   "GROUP-ID",
+				Name: "group1",
+				Org:  "org1",
+				Path: "/test/",
+			},
+			addAuditEventMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseCommitFailed": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			operations: []BatchOperation{
+				{
+					Action:     GROUP_ACTION_ADD_MEMBER,
+					ExternalID: "12345",
+					Org:        "org1",
+					Group:      "group1",
+				},
+			},
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "12345",
+				Path:       "/test/",
+			},
+			getGroupByNameResult: &Group{
+				ID:   "GROUP-ID",
+				Name: "group1",
+				Org:  "org1",
+				Path: "/test/",
+			},
+			runInTransactionMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetUserByExternalIDMethod][0] = test.getUserByExternalIDResult
+		testRepo.ArgsOut[GetUserByExternalIDMethod][1] = test.getUserByExternalIDMethodErr
+		testRepo.ArgsOut[AddUserMethod][0] = test.addUserResult
+		testRepo.ArgsOut[GetGroupByNameMethod][0] = test.getGroupByNameResult
+		testRepo.ArgsOut[GetPolicyByNameMethod][0] = test.getPolicyByNameResult
+		testRepo.ArgsOut[IsMemberOfGroupMethod][0] = false
+		testRepo.ArgsOut[IsAttachedToGroupMethod][0] = false
+		testRepo.ArgsOut[AttachPolicyMethod][0] = test.attachPolicyMethodErr
+		testRepo.ArgsOut[AddAuditEventMethod][0] = test.addAuditEventMethodErr
+		testRepo.ArgsOut[RunInTransactionMethod][0] = test.runInTransactionMethodErr
+
+		response, err := testAPI.ExecuteBatch(test.requestInfo, test.operations)
+		checkMethodResponse(t, n, test.wantError, err, test.expectedResponse, response)
+	}
+}
@@ -7,6 +7,7 @@ const (
 	UNKNOWN_API_ERROR            = "UnknownApiError"
 	INVALID_PARAMETER_ERROR      = "InvalidParameterError"
 	UNAUTHORIZED_RESOURCES_ERROR = "UnauthorizedResourcesError"
+	PRECONDITION_FAILED_ERROR    = "PreconditionFailedError"
 
 	// Authentication API error code
 	AUTHENTICATION_API_ERROR = "AuthenticationApiError"
@@ -23,13 +24,23 @@ const (
 	USER_IS_ALREADY_A_MEMBER_OF_GROUP = "UserIsAlreadyAMemberOfGroup"
 	USER_IS_NOT_A_MEMBER_OF_GROUP     = "UserIsNotAMemberOfGroup"
 
+	// GroupSubgroups error codes
+	GROUP_IS_ALREADY_A_MEMBER_OF_GROUP = "GroupIsAlreadyAMemberOfGroup"
+	GROUP_IS_NOT_A_MEMBER_OF_GROUP     = "GroupIsNotAMemberOfGroup"
+	GROUP_HIERARCHY_CYCLE              = "GroupHierarchyCycle"
+
 	// GroupPolicies error codes
 	POLICY_IS_ALREADY_ATTACHED_TO_GROUP = "PolicyIsAlreadyAttachedToGroup"
 	POLICY_IS_NOT_ATTACHED_TO_GROUP     = "PolicyIsNotAttachedToGroup"
 
+	// UserPolicies error codes
+	POLICY_IS_ALREADY_ATTACHED_TO_USER = "PolicyIsAlreadyAttachedToUser"
+	POLICY_IS_NOT_ATTACHED_TO_USER     = "PolicyIsNotAttachedToUser"
+
 	// Policy API error codes
 	POLICY_ALREADY_EXIST             = "PolicyAlreadyExist"
 	POLICY_BY_ORG_AND_NAME_NOT_FOUND = "PolicyWithOrgAndNameNotFound"
+	POLICY_VERSION_NOT_FOUND         = "PolicyVersionNotFound"
 
 	// Proxy resources API error codes
 	PROXY_RESOURCE_ALREADY_EXIST             = "ProxyResourceAlreadyExist"
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// Entity tag that matches any existing resource
+const ETAG_ANY = "*"
+
+// ETag returns the entity tag of a resource, derived from its update date. It changes every time the resource is updated
+func ETag(updateAt time.Time) string {
+	return fmt.Sprintf(`"%x"`, updateAt.UnixNano())
+}
+
+// CheckPrecondition returns an error if client requested some entity tags and the current one of the resource doesn't
+// match any of them
+func CheckPrecondition(requestInfo RequestInfo, urn string, updateAt time.Time) error {
+	if len(requestInfo.IfMatch) < 1 {
+		return nil
+	}
+
+	etag := ETag(updateAt)
+	for _, tag := range requestInfo.IfMatch {
+		if tag == ETAG_ANY || tag == etag {
+			return nil
+		}
+	}
+
+	return &Error{
+		Code:    PRECONDITION_FAILED_ERROR,
+		Message: fmt.Sprintf("Resource %v has changed, current entity tag is %v", urn, etag),
+	}
+}
+
+// Return the update date a resource must still have in database when it's changed, so the entity tags requested by
+// client are checked atomically with the change. It's zero if client didn't request any entity tag or accepts any
+func preconditionUpdateAt(requestInfo RequestInfo, updateAt time.Time) time.Time {
+	if len(requestInfo.IfMatch) < 1 {
+		return time.Time{}
+	}
+	for _, tag := range requestInfo.IfMatch {
+		if tag == ETAG_ANY {
+			return time.Time{}
+		}
+	}
+	return updateAt
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETag(t *testing.T) {
+	now := time.Now().UTC()
+	assert.Equal(t, ETag(now), ETag(time.Unix(0, now.UnixNano()).UTC()))
+	assert.NotEqual(t, ETag(now), ETag(now.Add(time.Nanosecond)))
+	assert.Equal(t, `"3e8"`, ETag(time.Unix(0, 1000)))
+}
+
+func TestCheckPrecondition(t *testing.T) {
+	updateAt := time.Unix(0, 1000).UTC()
+	testcases := map[string]struct {
+		ifMatch   []string
+		wantError error
+	}{
+		"OkCaseNoIfMatch": {},
+		"OkCaseMatch": {
+			ifMatch: []string{`"3e8"`},
+		},
+		"OkCaseAny": {
+			ifMatch: []string{ETAG_ANY},
+		},
+		"OkCaseSeveralTags": {
+			ifMatch: []string{`"3e7"`, `"3e8"`},
+		},
+		"ErrorCaseNoMatch": {
+			ifMatch: []string{`"3e7"`},
+			wantError: &Error{
+				Code:    PRECONDITION_FAILED_ERROR,
+				Message: `Resource urn:iws:iam::user/path/user1 has changed, current entity tag is "3e8"`,
+			},
+		},
+		"ErrorCaseWeakTag": {
+			ifMatch: []string{`W/"3e8"`},
+			wantError: &Error{
+				Code:    PRECONDITION_FAILED_ERROR,
+				Message: `Resource urn:iws:iam::user/path/user1 has changed, current entity tag is "3e8"`,
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		requestInfo := RequestInfo{
+			Identifier: "123456",
+			IfMatch:    test.ifMatch,
+		}
+		err := CheckPrecondition(requestInfo, CreateUrn("", RESOURCE_USER, "/path/", "user1"), updateAt)
+		checkMethodResponse(t, n, test.wantError, err, nil, nil)
+	}
+}
@@ -37,8 +37,15 @@ type GroupIdentity struct {
 }
 
 type GroupMembers struct {
-	User     string    `json:"user,omitempty"`
-	CreateAt time.Time `json:"joined,omitempty"`
+	User      string    `json:"user,omitempty"`
+	CreateAt  time.Time `json:"joined,omitempty"`
+	Inherited bool      `json:"inherited"`
+}
+
+type GroupSubgroups struct {
+	Subgroup  string    `json:"subgroup,omitempty"`
+	CreateAt  time.Time `json:"joined,omitempty"`
+	Inherited bool      `json:"inherited"`
 }
 
 type GroupPolicies struct {
@@ -95,7 +102,12 @@ func (api WorkerAPI) AddGroup(requestInfo RequestInfo, org string, name string,
 		// Group doesn't exist in DB, so we can create it
 		case database.GROUP_NOT_FOUND:
 			// Create group
-			createdGroup, err := api.GroupRepo.AddGroup(group)
+			var createdGroup *Group
+			err = api.auditedChange(requestInfo, GROUP_ACTION_CREATE_GROUP, group.Urn, nil, func(repos Repositories) (interface{}, error) {
+				var err error
+				createdGroup, err = repos.GroupRepo.AddGroup(group)
+				return createdGroup, err
+			})
 
 			// Check if there is an unexpected error in DB
 			if err != nil {
@@ -258,6 +270,12 @@ func (api WorkerAPI) UpdateGroup(requestInfo RequestInfo, org string, name strin
 		}
 	}
 
+	// Check that resource wasn't changed since client retrieved it
+	err = CheckPrecondition(requestInfo, oldGroup.Urn, oldGroup.UpdateAt)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if a group with "newName" already exists
 	newGroup, err := api.GetGroupByName(requestInfo, org, newName)
 
@@ -303,15 +321,28 @@ func (api WorkerAPI) UpdateGroup(requestInfo RequestInfo, org string, name strin
 		UpdateAt: time.Now().UTC(),
 	}
 
-	updatedGroup, err := api.GroupRepo.UpdateGroup(group)
+	var updatedGroup *Group
+	err = api.auditedChange(requestInfo, GROUP_ACTION_UPDATE_GROUP, group.Urn, oldGroup, func(repos Repositories) (interface{}, error) {
+		var err error
+		updatedGroup, err = repos.GroupRepo.UpdateGroup(group, preconditionUpdateAt(requestInfo, oldGroup.UpdateAt))
+		return updatedGroup, err
+	})
 
 	// Check unexpected DB error
 	if err != nil {
 		//Transform to DB error
 		dbError := err.(*database.Error)
-		return nil, &Error{
-			Code:    UNKNOWN_API_ERROR,
-			Message: dbError.Message,
+		switch dbError.Code {
+		case database.PRECONDITION_FAILED:
+			return nil, &Error{
+				Code:    PRECONDITION_FAILED_ERROR,
+				Message: fmt.Sprintf("Resource %v has changed", oldGroup.Urn),
+			}
+		default: // Unexpected error
+			return nil, &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: dbError.Message,
+			}
 		}
 	}
 
@@ -340,18 +371,35 @@ func (api WorkerAPI) RemoveGroup(requestInfo RequestInfo, org string, name strin
 		}
 	}
 
-	err = api.GroupRepo.RemoveGroup(group.ID)
+	// Check that resource wasn't changed since client retrieved it
+	err = CheckPrecondition(requestInfo, group.Urn, group.UpdateAt)
+	if err != nil {
+		return err
+	}
+
+	err = api.auditedChange(requestInfo, GROUP_ACTION_DELETE_GROUP, group.Urn, group, func(repos Repositories) (interface{}, error) {
+		return nil, repos.GroupRepo.RemoveGroup(group.ID, preconditionUpdateAt(requestInfo, group.UpdateAt))
+	})
 
 	// Error handling
 	if err != nil {
 		//Transform to DB error
 		dbError := err.(*database.Error)
-		return &Error{
-			Code:    UNKNOWN_API_ERROR,
-			Message: dbError.Message,
+		switch dbError.Code {
+		case database.PRECONDITION_FAILED:
+			return &Error{
+				Code:    PRECONDITION_FAILED_ERROR,
+				Message: fmt.Sprintf("Resource %v has changed", group.Urn),
+			}
+		default: // Unexpected error
+			return &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: dbError.Message,
+			}
 		}
 	}
 
+	api.AuthzCache.invalidateGroup(group.ID)
 	LogOperation(requestInfo.RequestID, requestInfo.Identifier, fmt.Sprintf("Group deleted %v", group))
 	return nil
 }
@@ -402,7 +450,9 @@ func (api WorkerAPI) AddMember(requestInfo RequestInfo, externalId string, name
 	}
 
 	// Add Member
-	err = api.GroupRepo.AddMember(userDB.ID, groupDB.ID)
+	err = api.auditedChange(requestInfo, GROUP_ACTION_ADD_MEMBER, groupDB.Urn, nil, func(repos Repositories) (interface{}, error) {
+		return userDB, repos.GroupRepo.AddMember(userDB.ID, groupDB.ID)
+	})
 
 	// Check if there is an unexpected error in DB
 	if err != nil {
@@ -413,6 +463,7 @@ func (api WorkerAPI) AddMember(requestInfo RequestInfo, externalId string, name
 			Message: dbError.Message,
 		}
 	}
+	api.AuthzCache.invalidateUser(userDB.ExternalID)
 	LogOperation(requestInfo.RequestID, requestInfo.Identifier, fmt.Sprintf("Member %+v added to group %+v", userDB, groupDB))
 	return nil
 }
@@ -463,7 +514,9 @@ func (api WorkerAPI) RemoveMember(requestInfo RequestInfo, externalId string, na
 	}
 
 	// Remove Member
-	err = api.GroupRepo.RemoveMember(userDB.ID, groupDB.ID)
+	err = api.auditedChange(requestInfo, GROUP_ACTION_REMOVE_MEMBER, groupDB.Urn, userDB, func(repos Repositories) (interface{}, error) {
+		return nil, repos.GroupRepo.RemoveMember(userDB.ID, groupDB.ID)
+	})
 
 	// Check if there is an unexpected error in DB
 	if err != nil {
@@ -475,6 +528,7 @@ func (api WorkerAPI) RemoveMember(requestInfo RequestInfo, externalId string, na
 		}
 	}
 
+	api.AuthzCache.invalidateUser(userDB.ExternalID)
 	LogOperation(requestInfo.RequestID, requestInfo.Identifier, fmt.Sprintf("Member %+v removed from group %+v", userDB, groupDB))
 	return nil
 }
@@ -508,7 +562,7 @@ func (api WorkerAPI) ListMembers(requestInfo RequestInfo, filter *Filter) ([]Gro
 	}
 
 	// Get Members
-	users, total, err := api.GroupRepo.GetGroupMembers(group.ID, filter)
+	users, _, err := api.GroupRepo.GetGroupMembers(group.ID, &Filter{OrderBy: filter.OrderBy})
 
 	// Error handling
 	if err != nil {
@@ -521,17 +575,47 @@ func (api WorkerAPI) ListMembers(requestInfo RequestInfo, filter *Filter) ([]Gro
 	}
 
 	members := []GroupMembers{}
-	if users != nil {
-		members = make([]GroupMembers, len(users), cap(users))
-		for i, m := range users {
-			members[i] = GroupMembers{
-				User:     m.GetUser().ExternalID,
-				CreateAt: m.GetDate(),
+	memberIDs := map[string]bool{}
+	for _, m := range users {
+		members = append(members, GroupMembers{
+			User:     m.GetUser().ExternalID,
+			CreateAt: m.GetDate(),
+		})
+		memberIDs[m.GetUser().ID] = true
+	}
+
+	// Add members inherited from subgroups
+	subgroups, err := api.getDescendantGroups([]Group{*group})
+	if err != nil {
+		return nil, total, err
+	}
+	for _, subgroup := range subgroups {
+		subgroupUsers, _, err := api.GroupRepo.GetGroupMembers(subgroup.GetSubgroup().ID, &Filter{OrderBy: filter.OrderBy})
+		if err != nil {
+			//Transform to DB error
+			dbError := err.(*database.Error)
+			return nil, total, &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: dbError.Message,
+			}
+		}
+		for _, m := range subgroupUsers {
+			if memberIDs[m.GetUser().ID] {
+				continue
 			}
+			members = append(members, GroupMembers{
+				User:      m.GetUser().ExternalID,
+				CreateAt:  m.GetDate(),
+				Inherited: true,
+			})
+			memberIDs[m.GetUser().ID] = true
 		}
 	}
 
-	return members, total, nil
+	// Paginate
+	total = len(members)
+	start, end := getPageBounds(filter, total)
+	return members[start:end], total, nil
 }
 
 func (api WorkerAPI) AttachPolicyToGroup(requestInfo RequestInfo, org string, name string, policyName string) error {
@@ -580,7 +664,9 @@ func (api WorkerAPI) AttachPolicyToGroup(requestInfo RequestInfo, org string, na
 	}
 
 	// Attach Policy to Group
-	err = api.GroupRepo.AttachPolicy(group.ID, policy.ID)
+	err = api.auditedChange(requestInfo, GROUP_ACTION_ATTACH_GROUP_POLICY, group.Urn, nil, func(repos Repositories) (interface{}, error) {
+		return policy, repos.GroupRepo.AttachPolicy(group.ID, policy.ID)
+	})
 
 	if err != nil {
 		dbError := err.(*database.Error)
@@ -590,6 +676,7 @@ func (api WorkerAPI) AttachPolicyToGroup(requestInfo RequestInfo, org string, na
 		}
 	}
 
+	api.AuthzCache.invalidateGroup(group.ID)
 	LogOperation(requestInfo.RequestID, requestInfo.Identifier, fmt.Sprintf("Policy %+v attached to group %+v", policy, group))
 	return nil
 }
@@ -641,7 +728,9 @@ func (api WorkerAPI) DetachPolicyToGroup(requestInfo RequestInfo, org string, na
 	}
 
 	// Detach Policy to Group
-	err = api.GroupRepo.DetachPolicy(group.ID, policy.ID)
+	err = api.auditedChange(requestInfo, GROUP_ACTION_DETACH_GROUP_POLICY, group.Urn, policy, func(repos Repositories) (interface{}, error) {
+		return nil, repos.GroupRepo.DetachPolicy(group.ID, policy.ID)
+	})
 
 	if err != nil {
 		dbError := err.(*database.Error)
@@ -651,6 +740,7 @@ func (api WorkerAPI) DetachPolicyToGroup(requestInfo RequestInfo, org string, na
 		}
 	}
 
+	api.AuthzCache.invalidateGroup(group.ID)
 	LogOperation(requestInfo.RequestID, requestInfo.Identifier, fmt.Sprintf("Policy %+v detached from group %+v", policy, group))
 	return nil
 }
@@ -710,6 +800,238 @@ func (api WorkerAPI) ListAttachedGroupPolicies(requestInfo RequestInfo, filter *
 	return policies, total, nil
 }
 
+func (api WorkerAPI) AddSubgroup(requestInfo RequestInfo, org string, name string, subgroupName string) error {
+	// Call repo to retrieve the group
+	groupDB, err := api.GetGroupByName(requestInfo, org, name)
+	if err != nil {
+		return err
+	}
+
+	// Check restrictions
+	groupsFiltered, err := api.GetAuthorizedGroups(requestInfo, groupDB.Urn, GROUP_ACTION_ADD_SUBGROUP, []Group{*groupDB})
+	if err != nil {
+		return err
+	}
+	if len(groupsFiltered) < 1 {
+		return &Error{
+			Code: UNAUTHORIZED_RESOURCES_ERROR,
+			Message: fmt.Sprintf("User with externalId %v is not allowed to access to resource %v",
+				requestInfo.Identifier, groupDB.Urn),
+		}
+	}
+
+	// Call repo to retrieve the subgroup
+	subgroupDB, err := api.GetGroupByName(requestInfo, org, subgroupName)
+	if err != nil {
+		return err
+	}
+
+	// Call repo to check if subgroup is already a member of group
+	isMember, err := api.GroupRepo.IsSubgroupOfGroup(subgroupDB.ID, groupDB.ID)
+	if err != nil {
+		//Transform to DB error
+		dbError := err.(*database.Error)
+		return &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: dbError.Message,
+		}
+	}
+
+	if isMember {
+		return &Error{
+			Code: GROUP_IS_ALREADY_A_MEMBER_OF_GROUP,
+			Message: fmt.Sprintf("Group with org %v and name %v is already a member of group with org %v and name %v",
+				subgroupDB.Org, subgroupDB.Name, groupDB.Org, groupDB.Name),
+		}
+	}
+
+	// Subgroup can't be the group itself or any of its ancestors
+	ancestors, err := api.getAncestorGroups([]Group{*groupDB})
+	if err != nil {
+		return err
+	}
+	isCycle := subgroupDB.ID == groupDB.ID
+	for _, a := range ancestors {
+		if a.GetGroup().ID == subgroupDB.ID {
+			isCycle = true
+			break
+		}
+	}
+	if isCycle {
+		return &Error{
+			Code: GROUP_HIERARCHY_CYCLE,
+			Message: fmt.Sprintf("Group with org %v and name %v can't be a member of group with org %v and name %v, it would create a cycle in the group hierarchy",
+				subgroupDB.Org, subgroupDB.Name, groupDB.Org, groupDB.Name),
+		}
+	}
+
+	// Add subgroup. Repository checks the cycle again with the hierarchy locked, so concurrent changes can't
+	// create it
+	err = api.auditedChange(requestInfo, GROUP_ACTION_ADD_SUBGROUP, groupDB.Urn, nil, func(repos Repositories) (interface{}, error) {
+		return subgroupDB, repos.GroupRepo.AddSubgroup(groupDB.ID, subgroupDB.ID)
+	})
+
+	// Check if there is an unexpected error in DB
+	if err != nil {
+		//Transform to DB error
+		dbError := err.(*database.Error)
+		switch dbError.Code {
+		case database.GROUP_HIERARCHY_CYCLE:
+			return &Error{
+				Code: GROUP_HIERARCHY_CYCLE,
+				Message: fmt.Sprintf("Group with org %v and name %v can't be a member of group with org %v and name %v, it would create a cycle in the group hierarchy",
+					subgroupDB.Org, subgroupDB.Name, groupDB.Org, groupDB.Name),
+			}
+		default:
+			return &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: dbError.Message,
+			}
+		}
+	}
+	// Members of subgroup and of its descendants inherit the policies of group
+	api.AuthzCache.invalidateGroup(subgroupDB.ID)
+	LogOperation(requestInfo.RequestID, requestInfo.Identifier, fmt.Sprintf("Subgroup %+v added to group %+v", subgroupDB, groupDB))
+	return nil
+}
+
+func (api WorkerAPI) RemoveSubgroup(requestInfo RequestInfo, org string, name string, subgroupName string) error {
+	// Call repo to retrieve the group
+	groupDB, err := api.GetGroupByName(requestInfo, org, name)
+	if err != nil {
+		return err
+	}
+
+	// Check restrictions
+	groupsFiltered, err := api.GetAuthorizedGroups(requestInfo, groupDB.Urn, GROUP_ACTION_REMOVE_SUBGROUP, []Group{*groupDB})
+	if err != nil {
+		return err
+	}
+	if len(groupsFiltered) < 1 {
+		return &Error{
+			Code: UNAUTHORIZED_RESOURCES_ERROR,
+			Message: fmt.Sprintf("User with externalId %v is not allowed to access to resource %v",
+				requestInfo.Identifier, groupDB.Urn),
+		}
+	}
+
+	// Call repo to retrieve the subgroup
+	subgroupDB, err := api.GetGroupByName(requestInfo, org, subgroupName)
+	if err != nil {
+		return err
+	}
+
+	// Call repo to check if subgroup is a member of group
+	isMember, err := api.GroupRepo.IsSubgroupOfGroup(subgroupDB.ID, groupDB.ID)
+	if err != nil {
+		//Transform to DB error
+		dbError := err.(*database.Error)
+		return &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: dbError.Message,
+		}
+	}
+
+	if !isMember {
+		return &Error{
+			Code: GROUP_IS_NOT_A_MEMBER_OF_GROUP,
+			Message: fmt.Sprintf("Group with org %v and name %v is not a member of group with org %v and name %v",
+				subgroupDB.Org, subgroupDB.Name, groupDB.Org, groupDB.Name),
+		}
+	}
+
+	// Remove subgroup
+	err = api.auditedChange(requestInfo, GROUP_ACTION_REMOVE_SUBGROUP, groupDB.Urn, subgroupDB, func(repos Repositories) (interface{}, error) {
+		return nil, repos.GroupRepo.RemoveSubgroup(groupDB.ID, subgroupDB.ID)
+	})
+
+	// Check if there is an unexpected error in DB
+	if err != nil {
+		//Transform to DB error
+		dbError := err.(*database.Error)
+		return &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: dbError.Message,
+		}
+	}
+	api.AuthzCache.invalidateGroup(subgroupDB.ID)
+	LogOperation(requestInfo.RequestID, requestInfo.Identifier, fmt.Sprintf("Subgroup %+v removed from group %+v", subgroupDB, groupDB))
+	return nil
+}
+
+func (api WorkerAPI) ListSubgroups(requestInfo RequestInfo, filter *Filter) ([]GroupSubgroups, int, error) {
+	// Validate fields
+	var total int
+	orderByValidColumns := api.GroupRepo.OrderByValidColumns(GROUP_ACTION_LIST_SUBGROUPS)
+	err := validateFilter(filter, orderByValidColumns)
+	if err != nil {
+		return nil, total, err
+	}
+
+	// Call repo to retrieve the group
+	group, err := api.GetGroupByName(requestInfo, filter.Org, filter.GroupName)
+	if err != nil {
+		return nil, total, err
+	}
+
+	// Check restrictions
+	groupsFiltered, err := api.GetAuthorizedGroups(requestInfo, group.Urn, GROUP_ACTION_LIST_SUBGROUPS, []Group{*group})
+	if err != nil {
+		return nil, total, err
+	}
+	if len(groupsFiltered) < 1 {
+		return nil, total, &Error{
+			Code: UNAUTHORIZED_RESOURCES_ERROR,
+			Message: fmt.Sprintf("User with externalId %v is not allowed to access to resource %v",
+				requestInfo.Identifier, group.Urn),
+		}
+	}
+
+	// Get direct subgroups
+	relations, _, err := api.GroupRepo.GetSubgroups(group.ID, &Filter{OrderBy: filter.OrderBy})
+
+	// Error handling
+	if err != nil {
+		//Transform to DB error
+		dbError := err.(*database.Error)
+		return nil, total, &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: dbError.Message,
+		}
+	}
+
+	subgroups := []GroupSubgroups{}
+	directSubgroups := []Group{}
+	for _, r := range relations {
+		subgroups = append(subgroups, GroupSubgroups{
+			Subgroup: r.GetSubgroup().Name,
+			CreateAt: r.GetDate(),
+		})
+		directSubgroups = append(directSubgroups, *r.GetSubgroup())
+	}
+
+	// Add subgroups inherited through the group hierarchy
+	descendants, err := api.getDescendantGroups(directSubgroups)
+	if err != nil {
+		return nil, total, err
+	}
+	for _, d := range descendants {
+		if d.GetSubgroup().ID == group.ID {
+			continue
+		}
+		subgroups = append(subgroups, GroupSubgroups{
+			Subgroup:  d.GetSubgroup().Name,
+			CreateAt:  d.GetDate(),
+			Inherited: true,
+		})
+	}
+
+	// Paginate
+	total = len(subgroups)
+	start, end := getPageBounds(filter, total)
+	return subgroups[start:end], total, nil
+}
+
 // PRIVATE HELPER METHODS
 
 func createGroup(org string, name string, path string) Group {
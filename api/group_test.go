@@ -2,6 +2,7 @@ package api
 
 import (
 	"testing"
+	"time"
 
 	"github.com/Tecsisa/foulkon/database"
 	"github.com/stretchr/testify/assert"
@@ -4917,3 +4918,496 @@ func TestAuthAPI_ListAttachedGroupPolicies(t *testing.T) {
 		assert.Equal(t, testcase.totalResult, total, "Error in test case %v", x)
 	}
 }
+
+func TestAuthAPI_AddSubgroup(t *testing.T) {
+	groups := map[string]*Group{
+		"group1": {
+			ID:   "GROUP1-ID",
+			Name: "group1",
+			Org:  "123",
+			Path: "/path/",
+			Urn:  CreateUrn("123", RESOURCE_GROUP, "/path/", "group1"),
+		},
+		"group2": {
+			ID:   "GROUP2-ID",
+			Name: "group2",
+			Org:  "123",
+			Path: "/path/",
+			Urn:  CreateUrn("123", RESOURCE_GROUP, "/path/", "group2"),
+		},
+		"group3": {
+			ID:   "GROUP3-ID",
+			Name: "group3",
+			Org:  "123",
+			Path: "/path/",
+			Urn:  CreateUrn("123", RESOURCE_GROUP, "/path/", "group3"),
+		},
+	}
+	testcases := map[string]struct {
+		requestInfo  RequestInfo
+		org          string
+		groupName    string
+		subgroupName string
+		// Expected result
+		wantError error
+		// Manager Results
+		parentGroups            map[string][]*Group
+		isSubgroupOfGroupResult bool
+		// Manager Errors
+		getGroupByNameMethodErr    error
+		isSubgroupOfGroupMethodErr error
+		getParentGroupsMethodErr   error
+		addSubgroupMethodErr       error
+	}{
+		"OkCase": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:          "123",
+			groupName:    "group1",
+			subgroupName: "group2",
+			parentGroups: map[string][]*Group{
+				"GROUP1-ID": {groups["group3"]},
+			},
+		},
+		"ErrorCaseGroupNotFound": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:          "123",
+			groupName:    "group1",
+			subgroupName: "group2",
+			wantError: &Error{
+				Code:    GROUP_BY_ORG_AND_NAME_NOT_FOUND,
+				Message: "Group not found",
+			},
+			getGroupByNameMethodErr: &database.Error{
+				Code:    database.GROUP_NOT_FOUND,
+				Message: "Group not found",
+			},
+		},
+		"ErrorCaseIsAlreadyMember": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:          "123",
+			groupName:    "group1",
+			subgroupName: "group2",
+			wantError: &Error{
+				Code:    GROUP_IS_ALREADY_A_MEMBER_OF_GROUP,
+				Message: "Group with org 123 and name group2 is already a member of group with org 123 and name group1",
+			},
+			isSubgroupOfGroupResult: true,
+		},
+		"ErrorCaseCycleSameGroup": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:          "123",
+			groupName:    "group1",
+			subgroupName: "group1",
+			wantError: &Error{
+				Code: GROUP_HIERARCHY_CYCLE,
+				Message: "Group with org 123 and name group1 can't be a member of group with org 123 and name group1, " +
+					"it would create a cycle in the group hierarchy",
+			},
+		},
+		"ErrorCaseCycleAncestor": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:          "123",
+			groupName:    "group1",
+			subgroupName: "group3",
+			wantError: &Error{
+				Code: GROUP_HIERARCHY_CYCLE,
+				Message: "Group with org 123 and name group3 can't be a member of group with org 123 and name group1, " +
+					"it would create a cycle in the group hierarchy",
+			},
+			parentGroups: map[string][]*Group{
+				"GROUP1-ID": {groups["group2"]},
+				"GROUP2-ID": {groups["group3"]},
+			},
+		},
+		"ErrorCaseIsSubgroupOfGroupDBErr": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:          "123",
+			groupName:    "group1",
+			subgroupName: "group2",
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			isSubgroupOfGroupMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseGetParentGroupsDBErr": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:          "123",
+			groupName:    "group1",
+			subgroupName: "group2",
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			getParentGroupsMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseAddSubgroupDBErr": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:          "123",
+			groupName:    "group1",
+			subgroupName: "group2",
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			addSubgroupMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseAddSubgroupCycle": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:          "123",
+			groupName:    "group1",
+			subgroupName: "group2",
+			wantError: &Error{
+				Code: GROUP_HIERARCHY_CYCLE,
+				Message: "Group with org 123 and name group2 can't be a member of group with org 123 and name group1, " +
+					"it would create a cycle in the group hierarchy",
+			},
+			// Cycle created by a concurrent change, found by repository
+			addSubgroupMethodErr: &database.Error{
+				Code:    database.GROUP_HIERARCHY_CYCLE,
+				Message: "Group GROUP2-ID is an ancestor of group GROUP1-ID",
+			},
+		},
+	}
+
+	for x, testcase := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		getGroupByNameMethodErr := testcase.getGroupByNameMethodErr
+		testRepo.SpecialFuncs[GetGroupByNameMethod] = func(org string, name string) (*Group, error) {
+			if getGroupByNameMethodErr != nil {
+				return nil, getGroupByNameMethodErr
+			}
+			return groups[name], nil
+		}
+		if testcase.parentGroups != nil {
+			parentGroups := testcase.parentGroups
+			testRepo.SpecialFuncs[GetParentGroupsMethod] = func(subgroupID string, filter *Filter) ([]GroupSubgroupRelation, int, error) {
+				relations := []GroupSubgroupRelation{}
+				for _, parent := range parentGroups[subgroupID] {
+					relations = append(relations, TestGroupSubgroupRelation{Group: parent})
+				}
+				return relations, len(relations), nil
+			}
+		}
+		testRepo.ArgsOut[GetParentGroupsMethod][2] = testcase.getParentGroupsMethodErr
+		testRepo.ArgsOut[IsSubgroupOfGroupMethod][0] = testcase.isSubgroupOfGroupResult
+		testRepo.ArgsOut[IsSubgroupOfGroupMethod][1] = testcase.isSubgroupOfGroupMethodErr
+		testRepo.ArgsOut[AddSubgroupMethod][0] = testcase.addSubgroupMethodErr
+
+		err := testAPI.AddSubgroup(testcase.requestInfo, testcase.org, testcase.groupName, testcase.subgroupName)
+		checkMethodResponse(t, x, testcase.wantError, err, nil, nil)
+		if testcase.wantError == nil {
+			assert.Equal(t, groups[testcase.groupName].ID, testRepo.ArgsIn[AddSubgroupMethod][0], "Error in test case %v", x)
+			assert.Equal(t, groups[testcase.subgroupName].ID, testRepo.ArgsIn[AddSubgroupMethod][1], "Error in test case %v", x)
+		}
+	}
+}
+
+func TestAuthAPI_RemoveSubgroup(t *testing.T) {
+	groups := map[string]*Group{
+		"group1": {
+			ID:   "GROUP1-ID",
+			Name: "group1",
+			Org:  "123",
+			Path: "/path/",
+			Urn:  CreateUrn("123", RESOURCE_GROUP, "/path/", "group1"),
+		},
+		"group2": {
+			ID:   "GROUP2-ID",
+			Name: "group2",
+			Org:  "123",
+			Path: "/path/",
+			Urn:  CreateUrn("123", RESOURCE_GROUP, "/path/", "group2"),
+		},
+	}
+	testcases := map[string]struct {
+		requestInfo  RequestInfo
+		org          string
+		groupName    string
+		subgroupName string
+		// Expected result
+		wantError error
+		// Manager Results
+		getUserByExternalIDResult *User
+		isSubgroupOfGroupResult   bool
+		// Manager Errors
+		isSubgroupOfGroupMethodErr error
+		removeSubgroupMethodErr    error
+	}{
+		"OkCase": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:                     "123",
+			groupName:               "group1",
+			subgroupName:            "group2",
+			isSubgroupOfGroupResult: true,
+		},
+		"ErrorCaseUnauthorized": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      false,
+			},
+			org:          "123",
+			groupName:    "group1",
+			subgroupName: "group2",
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "User with externalId 123456 is not allowed to access to resource urn:iws:iam:123:group/path/group1",
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "123456",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "123456"),
+			},
+		},
+		"ErrorCaseIsNotMember": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:          "123",
+			groupName:    "group1",
+			subgroupName: "group2",
+			wantError: &Error{
+				Code:    GROUP_IS_NOT_A_MEMBER_OF_GROUP,
+				Message: "Group with org 123 and name group2 is not a member of group with org 123 and name group1",
+			},
+		},
+		"ErrorCaseIsSubgroupOfGroupDBErr": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:          "123",
+			groupName:    "group1",
+			subgroupName: "group2",
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			isSubgroupOfGroupMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseRemoveSubgroupDBErr": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:                     "123",
+			groupName:               "group1",
+			subgroupName:            "group2",
+			isSubgroupOfGroupResult: true,
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			removeSubgroupMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	for x, testcase := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.SpecialFuncs[GetGroupByNameMethod] = func(org string, name string) (*Group, error) {
+			return groups[name], nil
+		}
+		testRepo.ArgsOut[GetUserByExternalIDMethod][0] = testcase.getUserByExternalIDResult
+		testRepo.ArgsOut[IsSubgroupOfGroupMethod][0] = testcase.isSubgroupOfGroupResult
+		testRepo.ArgsOut[IsSubgroupOfGroupMethod][1] = testcase.isSubgroupOfGroupMethodErr
+		testRepo.ArgsOut[RemoveSubgroupMethod][0] = testcase.removeSubgroupMethodErr
+
+		err := testAPI.RemoveSubgroup(testcase.requestInfo, testcase.org, testcase.groupName, testcase.subgroupName)
+		checkMethodResponse(t, x, testcase.wantError, err, nil, nil)
+	}
+}
+
+func TestAuthAPI_ListSubgroups(t *testing.T) {
+	now := time.Now().UTC()
+	group1 := &Group{
+		ID:   "GROUP1-ID",
+		Name: "group1",
+		Org:  "123",
+		Path: "/path/",
+		Urn:  CreateUrn("123", RESOURCE_GROUP, "/path/", "group1"),
+	}
+	group2 := &Group{
+		ID:   "GROUP2-ID",
+		Name: "group2",
+		Org:  "123",
+		Path: "/path/",
+		Urn:  CreateUrn("123", RESOURCE_GROUP, "/path/", "group2"),
+	}
+	group3 := &Group{
+		ID:   "GROUP3-ID",
+		Name: "group3",
+		Org:  "123",
+		Path: "/path/",
+		Urn:  CreateUrn("123", RESOURCE_GROUP, "/path/", "group3"),
+	}
+	testcases := map[string]struct {
+		requestInfo RequestInfo
+		filter      *Filter
+		// Expected result
+		expectedResponse []GroupSubgroups
+		totalResult      int
+		wantError        error
+		// Manager Results
+		subgroups map[string][]*Group
+		// Manager Errors
+		getSubgroupsMethodErr error
+	}{
+		"OkCase": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				Org:       "123",
+				GroupName: "group1",
+			},
+			expectedResponse: []GroupSubgroups{
+				{
+					Subgroup: "group2",
+					CreateAt: now,
+				},
+				{
+					Subgroup:  "group3",
+					CreateAt:  now,
+					Inherited: true,
+				},
+			},
+			totalResult: 2,
+			subgroups: map[string][]*Group{
+				"GROUP1-ID": {group2},
+				"GROUP2-ID": {group3},
+			},
+		},
+		"OkCasePagination": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				Org:       "123",
+				GroupName: "group1",
+				Offset:    1,
+				Limit:     1,
+			},
+			expectedResponse: []GroupSubgroups{
+				{
+					Subgroup:  "group3",
+					CreateAt:  now,
+					Inherited: true,
+				},
+			},
+			totalResult: 2,
+			subgroups: map[string][]*Group{
+				"GROUP1-ID": {group2},
+				"GROUP2-ID": {group3},
+			},
+		},
+		"ErrorCaseInvalidFilter": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				Org:       "123",
+				GroupName: "group1",
+				OrderBy:   "name",
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: OrderBy name",
+			},
+		},
+		"ErrorCaseGetSubgroupsDBErr": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				Org:       "123",
+				GroupName: "group1",
+			},
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			getSubgroupsMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	for x, testcase := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetGroupByNameMethod][0] = group1
+		testRepo.ArgsOut[OrderByValidColumnsMethod][0] = []string{"create_at"}
+		if testcase.subgroups != nil {
+			subgroups := testcase.subgroups
+			testRepo.SpecialFuncs[GetSubgroupsMethod] = func(groupID string, filter *Filter) ([]GroupSubgroupRelation, int, error) {
+				relations := []GroupSubgroupRelation{}
+				for _, subgroup := range subgroups[groupID] {
+					relations = append(relations, TestGroupSubgroupRelation{Subgroup: subgroup, CreateAt: now})
+				}
+				return relations, len(relations), nil
+			}
+		}
+		testRepo.ArgsOut[GetSubgroupsMethod][2] = testcase.getSubgroupsMethodErr
+
+		subgroups, total, err := testAPI.ListSubgroups(testcase.requestInfo, testcase.filter)
+		checkMethodResponse(t, x, testcase.wantError, err, testcase.expectedResponse, subgroups)
+		assert.Equal(t, testcase.totalResult, total, "Error in test case %v", x)
+	}
+}
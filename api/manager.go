@@ -26,6 +26,20 @@ type PolicyGroupRelation interface {
 	GetDate() time.Time
 }
 
+// PolicyUserRelation interface for Policy-User relationships
+type PolicyUserRelation interface {
+	GetUser() *User
+	GetPolicy() *Policy
+	GetDate() time.Time
+}
+
+// GroupSubgroupRelation interface for Group-Subgroup relationships
+type GroupSubgroupRelation interface {
+	GetGroup() *Group
+	GetSubgroup() *Group
+	GetDate() time.Time
+}
+
 // WorkerAPI that implements API interfaces using repositories
 type WorkerAPI struct {
 	UserRepo     UserRepo
@@ -33,6 +47,29 @@ type WorkerAPI struct {
 	PolicyRepo   PolicyRepo
 	ProxyRepo    ProxyRepo
 	AuthOidcRepo AuthOidcRepo
+	AuditRepo    AuditRepo
+
+	// Repository to run batch operations in a single transaction. Batch operations are disabled if nil
+	TransactionRepo TransactionRepo
+
+	// Repository to read authorization snapshots. Snapshots are disabled if nil
+	AuthzSnapshotRepo AuthzSnapshotRepo
+
+	// Cache of user policies used in authorization. It is disabled if nil
+	AuthzCache *AuthzCache
+
+	// Logger of authorization decisions. It is disabled if nil
+	AuthzLogger *AuthzDecisionLogger
+}
+
+// Repositories bound to a database transaction
+type Repositories struct {
+	UserRepo     UserRepo
+	GroupRepo    GroupRepo
+	PolicyRepo   PolicyRepo
+	ProxyRepo    ProxyRepo
+	AuthOidcRepo AuthOidcRepo
+	AuditRepo    AuditRepo
 }
 
 // ProxyAPI that implements API interfaces using repositories
@@ -49,6 +86,15 @@ type Filter struct {
 	GroupName         string
 	ProxyResourceName string
 	AuthProviderName  string
+	ActionPrefix      string
+	// Audit events
+	Actor     string
+	UrnPrefix string
+	From      time.Time
+	To        time.Time
+	// Policy versions
+	PolicyVersion       int
+	TargetPolicyVersion int
 	// Pagination
 	Offset int
 	Limit  int
@@ -83,6 +129,23 @@ type UserAPI interface {
 	// Retrieve groups that belongs to the user. Throw error if externalId parameter is invalid, user
 	// doesn't exist or unexpected error happen.
 	ListGroupsByUser(requestInfo RequestInfo, filter *Filter) ([]UserGroups, int, error)
+
+	// Retrieve the restrictions per action that the user has through its groups and its attached policies,
+	// filtered by action prefix. Throw error if externalId parameter is invalid, user doesn't exist or
+	// unexpected error happen.
+	ListEffectivePermissionsByUser(requestInfo RequestInfo, filter *Filter) ([]EffectivePermission, int, error)
+
+	// Attach policy to user. Throw error if the input parameters are invalid, policy doesn't exist,
+	// user doesn't exist, policy is already attached to the user or unexpected error happen.
+	AttachPolicyToUser(requestInfo RequestInfo, externalId string, org string, policyName string) error
+
+	// Detach policy from user. Throw error if the input parameters are invalid, policy doesn't exist,
+	// user doesn't exist, policy isn't attached to the user or unexpected error happen.
+	DetachPolicyToUser(requestInfo RequestInfo, externalId string, org string, policyName string) error
+
+	// Retrieve policies attached directly to the user. Throw error if the input parameters are invalid,
+	// user doesn't exist or unexpected error happen.
+	ListAttachedUserPolicies(requestInfo RequestInfo, filter *Filter) ([]UserPolicies, int, error)
 }
 
 // GroupAPI interface
@@ -131,6 +194,19 @@ type GroupAPI interface {
 	// Retrieve policies that are attached to the group. Throw error if the input parameters are invalid,
 	// group doesn't exist or unexpected error happen.
 	ListAttachedGroupPolicies(requestInfo RequestInfo, filter *Filter) ([]GroupPolicies, int, error)
+
+	// Add group as a member of another group. Members of the subgroup inherit the policies of the group.
+	// Throw error if the input parameters are invalid, any group doesn't exist, subgroup is already a member
+	// of the group, the relation would create a cycle in the group hierarchy or unexpected error happen.
+	AddSubgroup(requestInfo RequestInfo, org string, groupName string, subgroupName string) error
+
+	// Remove group as a member of another group. Throw error if the input parameters are invalid, any group
+	// doesn't exist, subgroup isn't a member of the group or unexpected error happen.
+	RemoveSubgroup(requestInfo RequestInfo, org string, groupName string, subgroupName string) error
+
+	// List direct and inherited subgroups of the group. Throw error if the input parameters are invalid,
+	// group doesn't exist or unexpected error happen.
+	ListSubgroups(requestInfo RequestInfo, filter *Filter) ([]GroupSubgroups, int, error)
 }
 
 // PolicyAPI interface
@@ -153,13 +229,29 @@ type PolicyAPI interface {
 	UpdatePolicy(requestInfo RequestInfo, org string, name string, newName string, newPath string,
 		newStatements []Statement) (*Policy, error)
 
-	// Remove policy stored in database with its groups relationships.
+	// Remove policy stored in database with its groups relationships and all its versions.
 	// Throw error if the input parameters are invalid, the policy doesn't exist or unexpected error happen.
 	RemovePolicy(requestInfo RequestInfo, org string, name string) error
 
 	// Retrieve groups that are attached to the policy. Throw error if the input parameters are invalid,
 	// policy doesn't exist or unexpected error happen.
 	ListAttachedGroups(requestInfo RequestInfo, filter *Filter) ([]PolicyGroups, int, error)
+
+	// Retrieve versions of the policy, most recent first. Throw error if the input parameters are invalid,
+	// policy doesn't exist or unexpected error happen.
+	ListPolicyVersions(requestInfo RequestInfo, filter *Filter) ([]PolicyVersion, int, error)
+
+	// Retrieve a version of the policy. Throw error if the input parameters are invalid,
+	// policy or version don't exist or unexpected error happen.
+	GetPolicyVersion(requestInfo RequestInfo, org string, name string, version int) (*PolicyVersion, error)
+
+	// Retrieve the statements added and removed from a version of the policy to the target version. Throw error if
+	// the input parameters are invalid, policy or versions don't exist or unexpected error happen.
+	DiffPolicyVersions(requestInfo RequestInfo, org string, name string, version int, targetVersion int) (*PolicyVersionDiff, error)
+
+	// Update policy with the statements of a previous version, storing them as a new version. Throw error if the
+	// input parameters are invalid, policy or version don't exist or unexpected error happen.
+	RollbackPolicy(requestInfo RequestInfo, org string, name string, version int) (*Policy, error)
 }
 
 // AuthzAPI interface
@@ -183,6 +275,31 @@ type AuthzAPI interface {
 	// Retrieve list of authorized external resources filtered according to the input parameters. Throw error
 	// if requestInfo doesn't exist, requestInfo doesn't have access to any resources or unexpected error happen.
 	GetAuthorizedExternalResources(requestInfo RequestInfo, action string, resources []string) ([]string, error)
+
+	// Retrieve the authorized external resources for each action and resources item, loading user policies once
+	// for the whole batch. Throw error if the input parameters are invalid, requestInfo doesn't exist or
+	// unexpected error happen.
+	GetAuthorizedExternalResourcesBatch(requestInfo RequestInfo, items []AuthorizeItem) ([]AuthorizeItemResult, error)
+
+	// Retrieve the authorization decision for each external resource, with the policies and statements
+	// that caused it. Throw error if the input parameters are invalid, requestInfo doesn't exist or
+	// unexpected error happen.
+	ExplainAuthorizedExternalResources(requestInfo RequestInfo, action string, resources []string) ([]ResourceExplanation, error)
+
+	// Retrieve the authorization decision for each external resource that the user would have with the candidate
+	// statements, added to or replacing its policies. Nothing is stored. Throw error if the input parameters
+	// are invalid, user doesn't exist, requestInfo doesn't have access to the user or unexpected error happen.
+	SimulateAuthorizedExternalResources(requestInfo RequestInfo, externalID string, statements []Statement, replace bool,
+		action string, resources []string) ([]ResourceExplanation, error)
+
+	// Retrieve users and groups with an effective allow for the action over the resource, with the policies
+	// that grant it. Throw error if the input parameters are invalid, requestInfo doesn't have access to list
+	// all users, groups and policies or unexpected error happen.
+	GetAuthorizedPrincipals(requestInfo RequestInfo, action string, resourceUrn string) (*AuthorizedPrincipals, error)
+
+	// Retrieve all users, groups and policies with their relations, to authorize requests out of worker.
+	// Throw error if requestInfo isn't admin or unexpected error happen.
+	GetAuthzSnapshot(requestInfo RequestInfo) (*AuthzSnapshot, error)
 }
 
 // InternalProxyAPI interface to manage proxy resources
@@ -239,6 +356,22 @@ type AuthOidcAPI interface {
 	RemoveOidcProvider(requestInfo RequestInfo, name string) error
 }
 
+// AuditAPI interface
+type AuditAPI interface {
+	// Retrieve audit events filtered by time range, actor and urn prefix. These input parameters are optional.
+	// Throw error if the input parameters are invalid, requestInfo doesn't have access to any event or
+	// unexpected error happen.
+	ListAuditEvents(requestInfo RequestInfo, filter *Filter) ([]AuditEvent, int, error)
+}
+
+// BatchAPI interface
+type BatchAPI interface {
+	// Apply the operations in order in a single transaction. Each operation is authorized as if it were
+	// requested alone. If any operation fails, the changes of all of them are discarded and the error of the
+	// failed operation is thrown. Throw error if operations are invalid or unexpected error happen.
+	ExecuteBatch(requestInfo RequestInfo, operations []BatchOperation) ([]BatchOperationResult, error)
+}
+
 // REPOSITORY INTERFACES
 
 // UserRepo contains all database operations
@@ -253,18 +386,30 @@ type UserRepo interface {
 	// if there are problems with database.
 	GetUsersFiltered(filter *Filter) ([]User, int, error)
 
-	// Update user stored in database with new fields. Throw error if the database restrictions
-	// are not satisfied or unexpected error happen.
-	UpdateUser(user User) (*User, error)
+	// Update user stored in database with new fields. If lastUpdateAt isn't zero, user is only updated if it
+	// wasn't changed since then. Throw error if the database restrictions are not satisfied or unexpected error happen.
+	UpdateUser(user User, lastUpdateAt time.Time) (*User, error)
 
-	// Remove user stored in database with its group relationships.
-	// Throw error if there are problems during transactions.
-	RemoveUser(id string) error
+	// Remove user stored in database with its group and policy relationships. If lastUpdateAt isn't zero, user
+	// is only removed if it wasn't changed since then. Throw error if there are problems during transactions.
+	RemoveUser(id string, lastUpdateAt time.Time) error
 
 	// Retrieve groups that belong to the user. Throw error
 	// if there are problems with database.
 	GetGroupsByUserID(id string, filter *Filter) ([]UserGroupRelation, int, error)
 
+	// Attach policy to user. Throw error if there are problems with database.
+	AttachPolicyToUser(userID string, policyID string) error
+
+	// Detach policy from user. Throw error if there are problems with database.
+	DetachPolicyFromUser(userID string, policyID string) error
+
+	// Check if policy is attached to user. Throw error if there are problems with database.
+	IsAttachedToUser(userID string, policyID string) (bool, error)
+
+	// Retrieve policies attached directly to the user. Throw error if there are problems with database.
+	GetAttachedPoliciesByUserID(userID string, filter *Filter) ([]PolicyUserRelation, int, error)
+
 	// OrderByValidColumns returns valid columns that you can use in OrderBy
 	OrderByValidColumns(action string) []string
 }
@@ -281,13 +426,13 @@ type GroupRepo interface {
 	// if there are problems with database.
 	GetGroupsFiltered(filter *Filter) ([]Group, int, error)
 
-	// Update group stored in database with new fields.
-	// Throw error if there are problems with database.
-	UpdateGroup(group Group) (*Group, error)
+	// Update group stored in database with new fields. If lastUpdateAt isn't zero, group is only updated if it
+	// wasn't changed since then. Throw error if there are problems with database.
+	UpdateGroup(group Group, lastUpdateAt time.Time) (*Group, error)
 
-	// Remove group stored in database with its user and policy relationships.
-	// Throw error if there are problems during transactions.
-	RemoveGroup(groupID string) error
+	// Remove group stored in database with its user and policy relationships. If lastUpdateAt isn't zero, group
+	// is only removed if it wasn't changed since then. Throw error if there are problems during transactions.
+	RemoveGroup(groupID string, lastUpdateAt time.Time) error
 
 	// Add new member to group. It doesn't check restrictions about existence of group or user. It throws
 	// errors if there are problems with database.
@@ -319,14 +464,33 @@ type GroupRepo interface {
 	// Retrieve policies that are attached to the group. Throw error if there are problems with database.
 	GetAttachedPolicies(groupID string, filter *Filter) ([]PolicyGroupRelation, int, error)
 
+	// Add group as a member of another group. It doesn't check restrictions about existence of groups. It throws
+	// GROUP_HIERARCHY_CYCLE error if subgroup is the group itself or any of its ancestors, checked in the same
+	// transaction, or errors if there are problems with database.
+	AddSubgroup(groupID string, subgroupID string) error
+
+	// Remove group as a member of another group. It doesn't check restrictions about existence of groups.
+	// It throws errors if there are problems with database.
+	RemoveSubgroup(groupID string, subgroupID string) error
+
+	// Check if group is a direct member of another group. It returns true if at least one relation exists.
+	// It throws errors if there are problems with database.
+	IsSubgroupOfGroup(subgroupID string, groupID string) (bool, error)
+
+	// Retrieve groups that are direct members of the group. Throw error if there are problems with database.
+	GetSubgroups(groupID string, filter *Filter) ([]GroupSubgroupRelation, int, error)
+
+	// Retrieve groups which the group is a direct member of. Throw error if there are problems with database.
+	GetParentGroups(subgroupID string, filter *Filter) ([]GroupSubgroupRelation, int, error)
+
 	// OrderByValidColumns returns valid columns that you can use in OrderBy
 	OrderByValidColumns(action string) []string
 }
 
 // PolicyRepo contains all database operations
 type PolicyRepo interface {
-	// Store policy in database if there aren't errors.
-	AddPolicy(policy Policy) (*Policy, error)
+	// Store policy in database with its first version, created by author, if there aren't errors.
+	AddPolicy(policy Policy, author string) (*Policy, error)
 
 	// Retrieve policy from database if it exists. Otherwise it throws an error.
 	GetPolicyByName(org string, name string) (*Policy, error)
@@ -335,17 +499,32 @@ type PolicyRepo interface {
 	// if there are problems with database.
 	GetPoliciesFiltered(filter *Filter) ([]Policy, int, error)
 
-	// Update policy stored in database with new fields. Also it overrides statements if it has.
-	// Throw error if there are problems with database.
-	UpdatePolicy(policy Policy) (*Policy, error)
+	// Update policy stored in database with new fields. Also it overrides statements if it has, and stores them
+	// as a new version created by author. If policy doesn't have any version, its previous statements are stored
+	// as the first one, without author. If lastUpdateAt isn't zero, policy is only updated if it wasn't changed
+	// since then. Throw error if there are problems with database.
+	UpdatePolicy(policy Policy, author string, lastUpdateAt time.Time) (*Policy, error)
 
-	// Remove policy stored in database with its groups relationships.
+	// Remove policy stored in database with its groups and users relationships and all its versions. If
+	// lastUpdateAt isn't zero, policy is only removed if it wasn't changed since then.
 	// Throw error if there are problems during transactions.
-	RemovePolicy(id string) error
+	RemovePolicy(id string, lastUpdateAt time.Time) error
 
 	// Retrieve groups that are attached to the policy. Throw error if there are problems with database.
 	GetAttachedGroups(policyID string, filter *Filter) ([]PolicyGroupRelation, int, error)
 
+	// Retrieve versions of the policy from database, most recent first by default. Throw error
+	// if there are problems with database.
+	GetPolicyVersions(policyID string, filter *Filter) ([]PolicyVersion, int, error)
+
+	// Retrieve a version of the policy from database if it exists. Otherwise it throws an error.
+	GetPolicyVersion(policyID string, version int) (*PolicyVersion, error)
+
+	// Retrieve with their statements all policies that apply to the user: the ones attached to its groups, including
+	// the groups inherited through the group hierarchy, and the ones attached directly to it. IDs of these groups are
+	// returned too, read in the same snapshot of database. Throw error if there are problems with database.
+	GetPoliciesForUser(userID string) ([]Policy, []string, error)
+
 	// OrderByValidColumns returns valid columns that you can use in OrderBy
 	OrderByValidColumns(action string) []string
 }
@@ -361,13 +540,14 @@ type ProxyRepo interface {
 	// Store proxy resource in database if there aren't errors.
 	AddProxyResource(proxyResource ProxyResource) (*ProxyResource, error)
 
-	// Update proxy resource stored in database with new fields. Also it overrides statements if it has.
+	// Update proxy resource stored in database with new fields. Also it overrides statements if it has. If
+	// lastUpdateAt isn't zero, proxy resource is only updated if it wasn't changed since then.
 	// Throw error if there are problems with database.
-	UpdateProxyResource(proxyResource ProxyResource) (*ProxyResource, error)
+	UpdateProxyResource(proxyResource ProxyResource, lastUpdateAt time.Time) (*ProxyResource, error)
 
-	// Remove proxy resource stored in database.
-	// Throw error if there are problems during transaction.
-	RemoveProxyResource(proxyResourceID string) error
+	// Remove proxy resource stored in database. If lastUpdateAt isn't zero, proxy resource is only removed if it
+	// wasn't changed since then. Throw error if there are problems during transaction.
+	RemoveProxyResource(proxyResourceID string, lastUpdateAt time.Time) error
 
 	// OrderByValidColumns returns valid columns that you can use in OrderBy
 	OrderByValidColumns(action string) []string
@@ -385,14 +565,41 @@ type AuthOidcRepo interface {
 	// if there are problems with database.
 	GetOidcProvidersFiltered(filter *Filter) ([]OidcProvider, int, error)
 
-	// Update the OIDC provider stored in database with new fields.
-	// Throw error if there are problems with database.
-	UpdateOidcProvider(oidcProvider OidcProvider) (*OidcProvider, error)
+	// Update the OIDC provider stored in database with new fields. If lastUpdateAt isn't zero, OIDC provider is
+	// only updated if it wasn't changed since then. Throw error if there are problems with database.
+	UpdateOidcProvider(oidcProvider OidcProvider, lastUpdateAt time.Time) (*OidcProvider, error)
 
-	// Remove the OIDC provider stored in database with its OIDC Clients.
-	// Throw error if there are problems during transactions.
-	RemoveOidcProvider(id string) error
+	// Remove the OIDC provider stored in database with its OIDC Clients. If lastUpdateAt isn't zero, OIDC
+	// provider is only removed if it wasn't changed since then. Throw error if there are problems during transactions.
+	RemoveOidcProvider(id string, lastUpdateAt time.Time) error
+
+	// OrderByValidColumns returns valid columns that you can use in OrderBy
+	OrderByValidColumns(action string) []string
+}
+
+// AuditRepo contains all database operations
+type AuditRepo interface {
+	// Store audit event in database if there aren't errors.
+	AddAuditEvent(event AuditEvent) error
+
+	// Retrieve audit events from database filtered by time range, actor and urn prefix optional parameters.
+	// Throw error if there are problems with database.
+	GetAuditEventsFiltered(filter *Filter) ([]AuditEvent, int, error)
 
 	// OrderByValidColumns returns valid columns that you can use in OrderBy
 	OrderByValidColumns(action string) []string
 }
+
+// AuthzSnapshotRepo reads all the data used in authorization
+type AuthzSnapshotRepo interface {
+	// Retrieve all users, groups and policies with their statements, and the relations between them, in a
+	// consistent read. Throw error if there are problems with database.
+	GetAuthzSnapshot() (*AuthzSnapshot, error)
+}
+
+// TransactionRepo runs database operations in a single transaction
+type TransactionRepo interface {
+	// Call function with repositories bound to a new transaction. Transaction is committed if function
+	// doesn't return error, otherwise it is rolled back and the error is returned.
+	RunInTransaction(fn func(repos Repositories) error) error
+}
@@ -37,19 +37,30 @@ type PolicyIdentity struct {
 	Name string `json:"name,omitempty"`
 }
 
+// Statement applies to its actions, or to every action except its notActions, and to its resources, or to every resource
+// except its notResources
 type Statement struct {
-	Effect    string   `json:"effect,omitempty"`
-	Actions   []string `json:"actions,omitempty"`
-	Resources []string `json:"resources,omitempty"`
+	Effect       string     `json:"effect,omitempty"`
+	Actions      []string   `json:"actions,omitempty"`
+	Resources    []string   `json:"resources,omitempty"`
+	NotActions   []string   `json:"notActions,omitempty"`
+	NotResources []string   `json:"notResources,omitempty"`
+	Conditions   Conditions `json:"conditions,omitempty"`
 }
 
+// Conditions of a statement, grouped by operator and then by condition key. A statement only applies
+// when every condition is satisfied by the request context.
+// E.g: {"IpAddress": {"foulkon:SourceIp": ["10.0.0.0/8"]}}
+type Conditions map[string]map[string][]string
+
 type PolicyGroups struct {
 	Group    string    `json:"group,omitempty"`
 	CreateAt time.Time `json:"attached,omitempty"`
 }
 
 func (s Statement) String() string {
-	return fmt.Sprintf("[effect: %v, actions: %v, resources: %v]", s.Effect, s.Actions, s.Resources)
+	return fmt.Sprintf("[effect: %v, actions: %v, resources: %v, notActions: %v, notResources: %v, conditions: %v]",
+		s.Effect, s.Actions, s.Resources, s.NotActions, s.NotResources, s.Conditions)
 }
 
 // POLICY API IMPLEMENTATION
@@ -111,7 +122,12 @@ func (api WorkerAPI) AddPolicy(requestInfo RequestInfo, name string, path string
 		// Policy doesn't exist in DB
 		case database.POLICY_NOT_FOUND:
 			// Create policy
-			createdPolicy, err := api.PolicyRepo.AddPolicy(policy)
+			var createdPolicy *Policy
+			err = api.auditedChange(requestInfo, POLICY_ACTION_CREATE_POLICY, policy.Urn, nil, func(repos Repositories) (interface{}, error) {
+				var err error
+				createdPolicy, err = repos.PolicyRepo.AddPolicy(policy, requestInfo.Identifier)
+				return createdPolicy, err
+			})
 
 			// Check if there is an unexpected error in DB
 			if err != nil {
@@ -282,6 +298,12 @@ func (api WorkerAPI) UpdatePolicy(requestInfo RequestInfo, org string, policyNam
 		}
 	}
 
+	// Check that resource wasn't changed since client retrieved it
+	err = CheckPrecondition(requestInfo, oldPolicy.Urn, oldPolicy.UpdateAt)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if policy with "newName" exists
 	targetPolicy, err := api.GetPolicyByName(requestInfo, org, newName)
 
@@ -328,18 +350,32 @@ func (api WorkerAPI) UpdatePolicy(requestInfo RequestInfo, org string, policyNam
 	}
 
 	// Update policy
-	updatedPolicy, err := api.PolicyRepo.UpdatePolicy(policy)
+	var updatedPolicy *Policy
+	err = api.auditedChange(requestInfo, POLICY_ACTION_UPDATE_POLICY, policy.Urn, oldPolicy, func(repos Repositories) (interface{}, error) {
+		var err error
+		updatedPolicy, err = repos.PolicyRepo.UpdatePolicy(policy, requestInfo.Identifier, preconditionUpdateAt(requestInfo, oldPolicy.UpdateAt))
+		return updatedPolicy, err
+	})
 
 	// Check unexpected DB error
 	if err != nil {
 		//Transform to DB error
 		dbError := err.(*database.Error)
-		return nil, &Error{
-			Code:    UNKNOWN_API_ERROR,
-			Message: dbError.Message,
+		switch dbError.Code {
+		case database.PRECONDITION_FAILED:
+			return nil, &Error{
+				Code:    PRECONDITION_FAILED_ERROR,
+				Message: fmt.Sprintf("Resource %v has changed", oldPolicy.Urn),
+			}
+		default: // Unexpected error
+			return nil, &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: dbError.Message,
+			}
 		}
 	}
 
+	api.AuthzCache.invalidatePolicy(oldPolicy.ID)
 	LogOperation(requestInfo.RequestID, requestInfo.Identifier, fmt.Sprintf("Policy updated from %+v to %+v", oldPolicy, updatedPolicy))
 	return updatedPolicy, nil
 }
@@ -365,16 +401,33 @@ func (api WorkerAPI) RemovePolicy(requestInfo RequestInfo, org string, name stri
 		}
 	}
 
-	err = api.PolicyRepo.RemovePolicy(policy.ID)
+	// Check that resource wasn't changed since client retrieved it
+	err = CheckPrecondition(requestInfo, policy.Urn, policy.UpdateAt)
+	if err != nil {
+		return err
+	}
+
+	err = api.auditedChange(requestInfo, POLICY_ACTION_DELETE_POLICY, policy.Urn, policy, func(repos Repositories) (interface{}, error) {
+		return nil, repos.PolicyRepo.RemovePolicy(policy.ID, preconditionUpdateAt(requestInfo, policy.UpdateAt))
+	})
 	if err != nil {
 		//Transform to DB error
 		dbError := err.(*database.Error)
-		return &Error{
-			Code:    UNKNOWN_API_ERROR,
-			Message: dbError.Message,
+		switch dbError.Code {
+		case database.PRECONDITION_FAILED:
+			return &Error{
+				Code:    PRECONDITION_FAILED_ERROR,
+				Message: fmt.Sprintf("Resource %v has changed", policy.Urn),
+			}
+		default: // Unexpected error
+			return &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: dbError.Message,
+			}
 		}
 	}
 
+	api.AuthzCache.invalidatePolicy(policy.ID)
 	LogOperation(requestInfo.RequestID, requestInfo.Identifier, fmt.Sprintf("Policy deleted %+v", policy))
 	return nil
 }
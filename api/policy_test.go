@@ -2,6 +2,7 @@ package api
 
 import (
 	"testing"
+	"time"
 
 	"github.com/Tecsisa/foulkon/database"
 	"github.com/stretchr/testify/assert"
@@ -2379,7 +2380,8 @@ func TestAuthAPI_RemovePolicy(t *testing.T) {
 		getUserByExternalIDErr      error
 		deletePolicyErr             error
 
-		wantError error
+		wantError            error
+		expectedLastUpdateAt time.Time
 	}{
 		"OkCase": {
 			requestInfo: RequestInfo{
@@ -2407,6 +2409,70 @@ func TestAuthAPI_RemovePolicy(t *testing.T) {
 				},
 			},
 		},
+		"OkCaseIfMatch": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+				IfMatch:    []string{`"1"`, ETag(time.Unix(0, 100).UTC())},
+			},
+			org:  "example",
+			name: "test",
+			getPolicyByNameMethodResult: &Policy{
+				ID:       "test1",
+				Name:     "test",
+				Org:      "example",
+				Path:     "/path/",
+				Urn:      CreateUrn("example", RESOURCE_POLICY, "/path/", "test"),
+				UpdateAt: time.Unix(0, 100).UTC(),
+			},
+			expectedLastUpdateAt: time.Unix(0, 100).UTC(),
+		},
+		"ErrorCasePolicyChanged": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+				IfMatch:    []string{ETag(time.Unix(0, 100).UTC())},
+			},
+			org:  "example",
+			name: "test",
+			getPolicyByNameMethodResult: &Policy{
+				ID:       "test1",
+				Name:     "test",
+				Org:      "example",
+				Path:     "/path/",
+				Urn:      CreateUrn("example", RESOURCE_POLICY, "/path/", "test"),
+				UpdateAt: time.Unix(0, 100).UTC(),
+			},
+			deletePolicyErr: &database.Error{
+				Code: database.PRECONDITION_FAILED,
+			},
+			wantError: &Error{
+				Code:    PRECONDITION_FAILED_ERROR,
+				Message: "Resource urn:iws:iam:example:policy/path/test has changed",
+			},
+			expectedLastUpdateAt: time.Unix(0, 100).UTC(),
+		},
+		"ErrorCasePreconditionFailed": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+				IfMatch:    []string{ETag(time.Unix(0, 99).UTC())},
+			},
+			org:  "example",
+			name: "test",
+			getPolicyByNameMethodResult: &Policy{
+				ID:       "test1",
+				Name:     "test",
+				Org:      "example",
+				Path:     "/path/",
+				Urn:      CreateUrn("example", RESOURCE_POLICY, "/path/", "test"),
+				UpdateAt: time.Unix(0, 100).UTC(),
+			},
+			wantError: &Error{
+				Code:    PRECONDITION_FAILED_ERROR,
+				Message: `Resource urn:iws:iam:example:policy/path/test has changed, current entity tag is "64"`,
+			},
+		},
 		"ErrorCaseInvalidName": {
 			requestInfo: RequestInfo{
 				Identifier: "123456",
@@ -2633,6 +2699,9 @@ func TestAuthAPI_RemovePolicy(t *testing.T) {
 		testRepo.ArgsOut[GetAttachedPoliciesMethod][0] = testcase.getAttachedPoliciesResult
 		err := testAPI.RemovePolicy(testcase.requestInfo, testcase.org, testcase.name)
 		checkMethodResponse(t, x, testcase.wantError, err, nil, nil)
+		if !testcase.expectedLastUpdateAt.IsZero() {
+			assert.Equal(t, testcase.expectedLastUpdateAt, testRepo.ArgsIn[RemovePolicyMethod][1], "Error in test case %v", x)
+		}
 	}
 }
 
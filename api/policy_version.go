@@ -0,0 +1,220 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/Tecsisa/foulkon/database"
+)
+
+// TYPE DEFINITIONS
+
+// PolicyVersion is an immutable copy of the policy statements, stored each time the policy is created or updated
+type PolicyVersion struct {
+	Version    int          `json:"version,omitempty"`
+	Author     string       `json:"author,omitempty"`
+	CreateAt   time.Time    `json:"createAt,omitempty"`
+	Statements *[]Statement `json:"statements,omitempty"`
+}
+
+// PolicyVersionDiff contains the statements added and removed from a policy version to the target version
+type PolicyVersionDiff struct {
+	Version       int         `json:"version,omitempty"`
+	TargetVersion int         `json:"targetVersion,omitempty"`
+	Added         []Statement `json:"added"`
+	Removed       []Statement `json:"removed"`
+}
+
+// POLICY VERSION API IMPLEMENTATION
+
+func (api WorkerAPI) ListPolicyVersions(requestInfo RequestInfo, filter *Filter) ([]PolicyVersion, int, error) {
+	// Validate fields
+	var total int
+	orderByValidColumns := api.PolicyRepo.OrderByValidColumns(POLICY_ACTION_LIST_POLICY_VERSIONS)
+	err := validateFilter(filter, orderByValidColumns)
+	if err != nil {
+		return nil, total, err
+	}
+
+	// Call repo to retrieve the policy
+	policy, err := api.getPolicyForVersions(requestInfo, filter.Org, filter.PolicyName, POLICY_ACTION_LIST_POLICIES)
+	if err != nil {
+		return nil, total, err
+	}
+
+	// Call repo to retrieve the versions
+	versions, total, err := api.PolicyRepo.GetPolicyVersions(policy.ID, filter)
+
+	// Error handling
+	if err != nil {
+		//Transform to DB error
+		dbError := err.(*database.Error)
+		return nil, total, &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: dbError.Message,
+		}
+	}
+
+	return versions, total, nil
+}
+
+func (api WorkerAPI) GetPolicyVersion(requestInfo RequestInfo, org string, name string, version int) (*PolicyVersion, error) {
+	// Validate fields
+	if version < 1 {
+		return nil, &Error{
+			Code:    INVALID_PARAMETER_ERROR,
+			Message: fmt.Sprintf("Invalid parameter: version %v", version),
+		}
+	}
+
+	// Call repo to retrieve the policy
+	policy, err := api.GetPolicyByName(requestInfo, org, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.getPolicyVersion(policy, version)
+}
+
+func (api WorkerAPI) DiffPolicyVersions(requestInfo RequestInfo, org string, name string, version int, targetVersion int) (*PolicyVersionDiff, error) {
+	// Validate fields
+	if version < 1 {
+		return nil, &Error{
+			Code:    INVALID_PARAMETER_ERROR,
+			Message: fmt.Sprintf("Invalid parameter: version %v", version),
+		}
+	}
+	if targetVersion < 1 {
+		return nil, &Error{
+			Code:    INVALID_PARAMETER_ERROR,
+			Message: fmt.Sprintf("Invalid parameter: target version %v", targetVersion),
+		}
+	}
+
+	// Call repo to retrieve the policy
+	policy, err := api.GetPolicyByName(requestInfo, org, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Call repo to retrieve both versions
+	policyVersion, err := api.getPolicyVersion(policy, version)
+	if err != nil {
+		return nil, err
+	}
+	policyTargetVersion, err := api.getPolicyVersion(policy, targetVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	added, removed := diffStatements(*policyVersion.Statements, *policyTargetVersion.Statements)
+	return &PolicyVersionDiff{
+		Version:       version,
+		TargetVersion: targetVersion,
+		Added:         added,
+		Removed:       removed,
+	}, nil
+}
+
+func (api WorkerAPI) RollbackPolicy(requestInfo RequestInfo, org string, name string, version int) (*Policy, error) {
+	// Validate fields
+	if version < 1 {
+		return nil, &Error{
+			Code:    INVALID_PARAMETER_ERROR,
+			Message: fmt.Sprintf("Invalid parameter: version %v", version),
+		}
+	}
+
+	// Call repo to retrieve the policy and the version
+	policy, err := api.getPolicyForVersions(requestInfo, org, name, POLICY_ACTION_UPDATE_POLICY)
+	if err != nil {
+		return nil, err
+	}
+	policyVersion, err := api.getPolicyVersion(policy, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update policy with version statements. Name and path don't change
+	return api.UpdatePolicy(requestInfo, org, name, policy.Name, policy.Path, *policyVersion.Statements)
+}
+
+// PRIVATE HELPER METHODS
+
+// Retrieve the policy if user is allowed to do the action over it
+func (api WorkerAPI) getPolicyForVersions(requestInfo RequestInfo, org string, name string, action string) (*Policy, error) {
+	policy, err := api.GetPolicyByName(requestInfo, org, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check restrictions
+	policiesFiltered, err := api.GetAuthorizedPolicies(requestInfo, policy.Urn, action, []Policy{*policy})
+	if err != nil {
+		return nil, err
+	}
+	if len(policiesFiltered) < 1 {
+		return nil, &Error{
+			Code: UNAUTHORIZED_RESOURCES_ERROR,
+			Message: fmt.Sprintf("User with externalId %v is not allowed to access to resource %v",
+				requestInfo.Identifier, policy.Urn),
+		}
+	}
+
+	return policy, nil
+}
+
+// Retrieve a version of the policy
+func (api WorkerAPI) getPolicyVersion(policy *Policy, version int) (*PolicyVersion, error) {
+	policyVersion, err := api.PolicyRepo.GetPolicyVersion(policy.ID, version)
+
+	// Error handling
+	if err != nil {
+		//Transform to DB error
+		dbError := err.(*database.Error)
+		// Version doesn't exist in DB
+		switch dbError.Code {
+		case database.POLICY_VERSION_NOT_FOUND:
+			return nil, &Error{
+				Code:    POLICY_VERSION_NOT_FOUND,
+				Message: dbError.Message,
+			}
+		default: // Unexpected error
+			return nil, &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: dbError.Message,
+			}
+		}
+	}
+
+	return policyVersion, nil
+}
+
+// Return the statements of target that aren't in source (added), and the ones of source that aren't in target (removed)
+func diffStatements(source []Statement, target []Statement) ([]Statement, []Statement) {
+	matched := make([]bool, len(target))
+	removed := []Statement{}
+	for _, s := range source {
+		found := false
+		for i, t := range target {
+			if !matched[i] && reflect.DeepEqual(s, t) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			removed = append(removed, s)
+		}
+	}
+
+	added := []Statement{}
+	for i, t := range target {
+		if !matched[i] {
+			added = append(added, t)
+		}
+	}
+
+	return added, removed
+}
@@ -0,0 +1,683 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Tecsisa/foulkon/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthAPI_ListPolicyVersions(t *testing.T) {
+	now := time.Now().UTC()
+	testPolicy := &Policy{
+		ID:   "test1",
+		Name: "test",
+		Org:  "example",
+		Path: "/path/",
+		Urn:  CreateUrn("example", RESOURCE_POLICY, "/path/", "test"),
+	}
+	testcases := map[string]struct {
+		// API Method args
+		requestInfo RequestInfo
+		filter      *Filter
+		// Expected result
+		expectedVersions []PolicyVersion
+		totalResult      int
+		wantError        error
+		// Manager Results
+		getPolicyByNameMethodResult *Policy
+		getPolicyVersionsResult     []PolicyVersion
+		getUserByExternalIDResult   *User
+		getGroupsByUserIDResult     []TestUserGroupRelation
+		getAttachedPoliciesResult   []TestPolicyGroupRelation
+		// Manager Errors
+		getPolicyByNameMethodErr error
+		getPolicyVersionsErr     error
+	}{
+		"OkCase": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				Org:        "example",
+				PolicyName: "test",
+				Limit:      20,
+			},
+			getPolicyByNameMethodResult: testPolicy,
+			getPolicyVersionsResult: []PolicyVersion{
+				{
+					Version:  2,
+					Author:   "123456",
+					CreateAt: now,
+				},
+				{
+					Version:  1,
+					Author:   "123456",
+					CreateAt: now,
+				},
+			},
+			totalResult: 2,
+			expectedVersions: []PolicyVersion{
+				{
+					Version:  2,
+					Author:   "123456",
+					CreateAt: now,
+				},
+				{
+					Version:  1,
+					Author:   "123456",
+					CreateAt: now,
+				},
+			},
+		},
+		"OkCaseReadOnlyUser": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      false,
+			},
+			filter: &Filter{
+				Org:        "example",
+				PolicyName: "test",
+				Limit:      20,
+			},
+			getPolicyByNameMethodResult: testPolicy,
+			getPolicyVersionsResult: []PolicyVersion{
+				{
+					Version:  1,
+					Author:   "123456",
+					CreateAt: now,
+				},
+			},
+			totalResult: 1,
+			expectedVersions: []PolicyVersion{
+				{
+					Version:  1,
+					Author:   "123456",
+					CreateAt: now,
+				},
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "123456",
+			},
+			getGroupsByUserIDResult: []TestUserGroupRelation{
+				{
+					Group: &Group{
+						ID:   "GROUP-USER-ID",
+						Name: "groupUser",
+					},
+				},
+			},
+			getAttachedPoliciesResult: []TestPolicyGroupRelation{
+				{
+					Policy: &Policy{
+						ID:   "POLICY-USER-ID",
+						Name: "policyUser",
+						Statements: &[]Statement{
+							{
+								Effect: "allow",
+								Actions: []string{
+									POLICY_ACTION_GET_POLICY,
+									POLICY_ACTION_LIST_POLICIES,
+								},
+								Resources: []string{
+									GetUrnPrefix("example", RESOURCE_POLICY, "/"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"ErrorCaseMaxLimitSize": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				Org:        "example",
+				PolicyName: "test",
+				Limit:      10000,
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: limit 10000, max limit allowed: 1000",
+			},
+		},
+		"ErrorCasePolicyNotExist": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				Org:        "example",
+				PolicyName: "test",
+			},
+			wantError: &Error{
+				Code: POLICY_BY_ORG_AND_NAME_NOT_FOUND,
+			},
+			getPolicyByNameMethodErr: &database.Error{
+				Code: database.POLICY_NOT_FOUND,
+			},
+		},
+		"ErrorCaseNotEnoughPermissions": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      false,
+			},
+			filter: &Filter{
+				Org:        "example",
+				PolicyName: "test",
+			},
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "User with externalId 123456 is not allowed to access to resource urn:iws:iam:example:policy/path/test",
+			},
+			getPolicyByNameMethodResult: testPolicy,
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "123456",
+			},
+			getGroupsByUserIDResult: []TestUserGroupRelation{
+				{
+					Group: &Group{
+						ID:   "GROUP-USER-ID",
+						Name: "groupUser",
+					},
+				},
+			},
+			getAttachedPoliciesResult: []TestPolicyGroupRelation{
+				{
+					Policy: &Policy{
+						ID:   "POLICY-USER-ID",
+						Name: "policyUser",
+						Statements: &[]Statement{
+							{
+								Effect: "allow",
+								Actions: []string{
+									POLICY_ACTION_GET_POLICY,
+								},
+								Resources: []string{
+									GetUrnPrefix("example", RESOURCE_POLICY, "/"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"ErrorCaseGetPolicyVersionsFail": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				Org:        "example",
+				PolicyName: "test",
+			},
+			wantError: &Error{
+				Code: UNKNOWN_API_ERROR,
+			},
+			getPolicyByNameMethodResult: testPolicy,
+			getPolicyVersionsErr: &database.Error{
+				Code: database.INTERNAL_ERROR,
+			},
+		},
+	}
+
+	for x, testcase := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetPolicyByNameMethod][0] = testcase.getPolicyByNameMethodResult
+		testRepo.ArgsOut[GetPolicyByNameMethod][1] = testcase.getPolicyByNameMethodErr
+		testRepo.ArgsOut[GetUserByExternalIDMethod][0] = testcase.getUserByExternalIDResult
+		testRepo.ArgsOut[GetGroupsByUserIDMethod][0] = testcase.getGroupsByUserIDResult
+		testRepo.ArgsOut[GetAttachedPoliciesMethod][0] = testcase.getAttachedPoliciesResult
+		testRepo.ArgsOut[GetPolicyVersionsMethod][0] = testcase.getPolicyVersionsResult
+		testRepo.ArgsOut[GetPolicyVersionsMethod][1] = testcase.totalResult
+		testRepo.ArgsOut[GetPolicyVersionsMethod][2] = testcase.getPolicyVersionsErr
+		versions, total, err := testAPI.ListPolicyVersions(testcase.requestInfo, testcase.filter)
+		checkMethodResponse(t, x, testcase.wantError, err, testcase.expectedVersions, versions)
+		assert.Equal(t, testcase.totalResult, total, "Error in test case %v", x)
+		if testcase.wantError == nil {
+			assert.Equal(t, "test1", testRepo.ArgsIn[GetPolicyVersionsMethod][0], "Error in test case %v", x)
+		}
+	}
+}
+
+func TestAuthAPI_GetPolicyVersion(t *testing.T) {
+	now := time.Now().UTC()
+	testPolicy := &Policy{
+		ID:   "test1",
+		Name: "test",
+		Org:  "example",
+		Path: "/path/",
+		Urn:  CreateUrn("example", RESOURCE_POLICY, "/path/", "test"),
+	}
+	testcases := map[string]struct {
+		// API Method args
+		requestInfo RequestInfo
+		org         string
+		name        string
+		version     int
+		// Expected result
+		expectedVersion *PolicyVersion
+		wantError       error
+		// Manager Results
+		getPolicyByNameMethodResult *Policy
+		getPolicyVersionResult      *PolicyVersion
+		getUserByExternalIDResult   *User
+		getGroupsByUserIDResult     []TestUserGroupRelation
+		getAttachedPoliciesResult   []TestPolicyGroupRelation
+		// Manager Errors
+		getPolicyByNameMethodErr error
+		getPolicyVersionErr      error
+	}{
+		"OkCase": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:                         "example",
+			name:                        "test",
+			version:                     1,
+			getPolicyByNameMethodResult: testPolicy,
+			getPolicyVersionResult: &PolicyVersion{
+				Version:  1,
+				Author:   "123456",
+				CreateAt: now,
+				Statements: &[]Statement{
+					{
+						Effect:    "allow",
+						Actions:   []string{USER_ACTION_GET_USER},
+						Resources: []string{GetUrnPrefix("", RESOURCE_USER, "/path/")},
+					},
+				},
+			},
+			expectedVersion: &PolicyVersion{
+				Version:  1,
+				Author:   "123456",
+				CreateAt: now,
+				Statements: &[]Statement{
+					{
+						Effect:    "allow",
+						Actions:   []string{USER_ACTION_GET_USER},
+						Resources: []string{GetUrnPrefix("", RESOURCE_USER, "/path/")},
+					},
+				},
+			},
+		},
+		"OkCaseReadOnlyUser": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      false,
+			},
+			org:                         "example",
+			name:                        "test",
+			version:                     1,
+			getPolicyByNameMethodResult: testPolicy,
+			getPolicyVersionResult: &PolicyVersion{
+				Version:  1,
+				Author:   "123456",
+				CreateAt: now,
+			},
+			expectedVersion: &PolicyVersion{
+				Version:  1,
+				Author:   "123456",
+				CreateAt: now,
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "123456",
+			},
+			getGroupsByUserIDResult: []TestUserGroupRelation{
+				{
+					Group: &Group{
+						ID:   "GROUP-USER-ID",
+						Name: "groupUser",
+					},
+				},
+			},
+			getAttachedPoliciesResult: []TestPolicyGroupRelation{
+				{
+					Policy: &Policy{
+						ID:   "POLICY-USER-ID",
+						Name: "policyUser",
+						Statements: &[]Statement{
+							{
+								Effect: "allow",
+								Actions: []string{
+									POLICY_ACTION_GET_POLICY,
+								},
+								Resources: []string{
+									GetUrnPrefix("example", RESOURCE_POLICY, "/"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"ErrorCaseInvalidVersion": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:     "example",
+			name:    "test",
+			version: 0,
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: version 0",
+			},
+		},
+		"ErrorCaseInvalidName": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:     "example",
+			name:    "invalid*",
+			version: 1,
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: name invalid*",
+			},
+		},
+		"ErrorCaseVersionNotFound": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:                         "example",
+			name:                        "test",
+			version:                     3,
+			getPolicyByNameMethodResult: testPolicy,
+			wantError: &Error{
+				Code: POLICY_VERSION_NOT_FOUND,
+			},
+			getPolicyVersionErr: &database.Error{
+				Code: database.POLICY_VERSION_NOT_FOUND,
+			},
+		},
+		"ErrorCaseGetPolicyVersionFail": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			org:                         "example",
+			name:                        "test",
+			version:                     1,
+			getPolicyByNameMethodResult: testPolicy,
+			wantError: &Error{
+				Code: UNKNOWN_API_ERROR,
+			},
+			getPolicyVersionErr: &database.Error{
+				Code: database.INTERNAL_ERROR,
+			},
+		},
+	}
+
+	for x, testcase := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetPolicyByNameMethod][0] = testcase.getPolicyByNameMethodResult
+		testRepo.ArgsOut[GetPolicyByNameMethod][1] = testcase.getPolicyByNameMethodErr
+		testRepo.ArgsOut[GetPolicyVersionMethod][0] = testcase.getPolicyVersionResult
+		testRepo.ArgsOut[GetPolicyVersionMethod][1] = testcase.getPolicyVersionErr
+		testRepo.ArgsOut[GetUserByExternalIDMethod][0] = testcase.getUserByExternalIDResult
+		testRepo.ArgsOut[GetGroupsByUserIDMethod][0] = testcase.getGroupsByUserIDResult
+		testRepo.ArgsOut[GetAttachedPoliciesMethod][0] = testcase.getAttachedPoliciesResult
+		policyVersion, err := testAPI.GetPolicyVersion(testcase.requestInfo, testcase.org, testcase.name, testcase.version)
+		checkMethodResponse(t, x, testcase.wantError, err, testcase.expectedVersion, policyVersion)
+	}
+}
+
+func TestAuthAPI_DiffPolicyVersions(t *testing.T) {
+	testPolicy := &Policy{
+		ID:   "test1",
+		Name: "test",
+		Org:  "example",
+		Path: "/path/",
+		Urn:  CreateUrn("example", RESOURCE_POLICY, "/path/", "test"),
+	}
+	getUser := Statement{
+		Effect:    "allow",
+		Actions:   []string{USER_ACTION_GET_USER},
+		Resources: []string{GetUrnPrefix("", RESOURCE_USER, "/path/")},
+	}
+	listUsers := Statement{
+		Effect:    "allow",
+		Actions:   []string{USER_ACTION_LIST_USERS},
+		Resources: []string{GetUrnPrefix("", RESOURCE_USER, "/path/")},
+	}
+	denyGetUser := Statement{
+		Effect:    "deny",
+		Actions:   []string{USER_ACTION_GET_USER},
+		Resources: []string{GetUrnPrefix("", RESOURCE_USER, "/path/admin/")},
+	}
+	versions := map[int]*PolicyVersion{
+		1: {
+			Version:    1,
+			Statements: &[]Statement{getUser, listUsers},
+		},
+		2: {
+			Version:    2,
+			Statements: &[]Statement{listUsers, denyGetUser},
+		},
+	}
+	testcases := map[string]struct {
+		// API Method args
+		requestInfo   RequestInfo
+		version       int
+		targetVersion int
+		// Expected result
+		expectedDiff *PolicyVersionDiff
+		wantError    error
+		// Manager Errors
+		getPolicyVersionErr error
+	}{
+		"OkCase": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			version:       1,
+			targetVersion: 2,
+			expectedDiff: &PolicyVersionDiff{
+				Version:       1,
+				TargetVersion: 2,
+				Added:         []Statement{denyGetUser},
+				Removed:       []Statement{getUser},
+			},
+		},
+		"OkCaseSameVersion": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			version:       2,
+			targetVersion: 2,
+			expectedDiff: &PolicyVersionDiff{
+				Version:       2,
+				TargetVersion: 2,
+				Added:         []Statement{},
+				Removed:       []Statement{},
+			},
+		},
+		"ErrorCaseInvalidTargetVersion": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			version:       1,
+			targetVersion: -2,
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: target version -2",
+			},
+		},
+		"ErrorCaseTargetVersionNotFound": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			version:       1,
+			targetVersion: 5,
+			wantError: &Error{
+				Code:    POLICY_VERSION_NOT_FOUND,
+				Message: "Version 5 not found",
+			},
+		},
+	}
+
+	for x, testcase := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetPolicyByNameMethod][0] = testPolicy
+		testRepo.SpecialFuncs[GetPolicyVersionMethod] = func(policyID string, version int) (*PolicyVersion, error) {
+			if policyVersion, ok := versions[version]; ok {
+				return policyVersion, nil
+			}
+			return nil, &database.Error{
+				Code:    database.POLICY_VERSION_NOT_FOUND,
+				Message: "Version 5 not found",
+			}
+		}
+		diff, err := testAPI.DiffPolicyVersions(testcase.requestInfo, "example", "test", testcase.version, testcase.targetVersion)
+		checkMethodResponse(t, x, testcase.wantError, err, testcase.expectedDiff, diff)
+	}
+}
+
+func TestAuthAPI_RollbackPolicy(t *testing.T) {
+	testPolicy := &Policy{
+		ID:   "test1",
+		Name: "test",
+		Org:  "example",
+		Path: "/path/",
+		Urn:  CreateUrn("example", RESOURCE_POLICY, "/path/", "test"),
+		Statements: &[]Statement{
+			{
+				Effect:    "allow",
+				Actions:   []string{USER_ACTION_LIST_USERS},
+				Resources: []string{GetUrnPrefix("", RESOURCE_USER, "/path/")},
+			},
+		},
+	}
+	versionStatements := []Statement{
+		{
+			Effect:    "allow",
+			Actions:   []string{USER_ACTION_GET_USER},
+			Resources: []string{GetUrnPrefix("", RESOURCE_USER, "/path/")},
+		},
+	}
+	testcases := map[string]struct {
+		// API Method args
+		requestInfo RequestInfo
+		version     int
+		// Expected result
+		expectedPolicy *Policy
+		wantError      error
+		// Manager Results
+		getPolicyVersionResult *PolicyVersion
+		updatePolicyResult     *Policy
+		// Manager Errors
+		getPolicyVersionErr error
+		updatePolicyErr     error
+	}{
+		"OkCase": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			version: 1,
+			getPolicyVersionResult: &PolicyVersion{
+				Version:    1,
+				Statements: &versionStatements,
+			},
+			updatePolicyResult: &Policy{
+				ID:         "test1",
+				Name:       "test",
+				Org:        "example",
+				Path:       "/path/",
+				Urn:        CreateUrn("example", RESOURCE_POLICY, "/path/", "test"),
+				Statements: &versionStatements,
+			},
+			expectedPolicy: &Policy{
+				ID:         "test1",
+				Name:       "test",
+				Org:        "example",
+				Path:       "/path/",
+				Urn:        CreateUrn("example", RESOURCE_POLICY, "/path/", "test"),
+				Statements: &versionStatements,
+			},
+		},
+		"ErrorCaseInvalidVersion": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			version: 0,
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: version 0",
+			},
+		},
+		"ErrorCaseVersionNotFound": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			version: 4,
+			wantError: &Error{
+				Code: POLICY_VERSION_NOT_FOUND,
+			},
+			getPolicyVersionErr: &database.Error{
+				Code: database.POLICY_VERSION_NOT_FOUND,
+			},
+		},
+		"ErrorCaseUpdatePolicyFail": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			version: 1,
+			getPolicyVersionResult: &PolicyVersion{
+				Version:    1,
+				Statements: &versionStatements,
+			},
+			wantError: &Error{
+				Code: UNKNOWN_API_ERROR,
+			},
+			updatePolicyErr: &database.Error{
+				Code: database.INTERNAL_ERROR,
+			},
+		},
+	}
+
+	for x, testcase := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetPolicyByNameMethod][0] = testPolicy
+		testRepo.ArgsOut[GetPolicyVersionMethod][0] = testcase.getPolicyVersionResult
+		testRepo.ArgsOut[GetPolicyVersionMethod][1] = testcase.getPolicyVersionErr
+		testRepo.ArgsOut[UpdatePolicyMethod][0] = testcase.updatePolicyResult
+		testRepo.ArgsOut[UpdatePolicyMethod][1] = testcase.updatePolicyErr
+		policy, err := testAPI.RollbackPolicy(testcase.requestInfo, "example", "test", testcase.version)
+		checkMethodResponse(t, x, testcase.wantError, err, testcase.expectedPolicy, policy)
+		if testcase.wantError == nil {
+			// Check that policy was updated with the version statements
+			updated := testRepo.ArgsIn[UpdatePolicyMethod][0].(Policy)
+			assert.Equal(t, versionStatements, *updated.Statements, "Error in test case %v", x)
+			assert.Equal(t, "test", updated.Name, "Error in test case %v", x)
+			assert.Equal(t, "/path/", updated.Path, "Error in test case %v", x)
+			assert.Equal(t, testcase.requestInfo.Identifier, testRepo.ArgsIn[UpdatePolicyMethod][1], "Error in test case %v", x)
+		}
+	}
+}
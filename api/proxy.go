@@ -138,7 +138,12 @@ func (api WorkerAPI) AddProxyResource(requestInfo RequestInfo, name string, org
 			}
 
 			// Create proxy resource
-			created, err := api.ProxyRepo.AddProxyResource(proxyResource)
+			var created *ProxyResource
+			err = api.auditedChange(requestInfo, PROXY_ACTION_CREATE_RESOURCE, proxyResource.Urn, nil, func(repos Repositories) (interface{}, error) {
+				var err error
+				created, err = repos.ProxyRepo.AddProxyResource(proxyResource)
+				return created, err
+			})
 
 			// Check unexpected DB error
 			if err != nil {
@@ -258,6 +263,12 @@ func (api WorkerAPI) UpdateProxyResource(requestInfo RequestInfo, org string, na
 		}
 	}
 
+	// Check that resource wasn't changed since client retrieved it
+	err = CheckPrecondition(requestInfo, oldProxyResource.Urn, oldProxyResource.UpdateAt)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if a proxy resource with "newName" already exists
 	newProxyResource, err := api.GetProxyResourceByName(requestInfo, org, newName)
 
@@ -339,15 +350,28 @@ func (api WorkerAPI) UpdateProxyResource(requestInfo RequestInfo, org string, na
 		}
 	}
 
-	updatedProxyResource, err := api.ProxyRepo.UpdateProxyResource(proxyResource)
+	var updatedProxyResource *ProxyResource
+	err = api.auditedChange(requestInfo, PROXY_ACTION_UPDATE_RESOURCE, proxyResource.Urn, oldProxyResource, func(repos Repositories) (interface{}, error) {
+		var err error
+		updatedProxyResource, err = repos.ProxyRepo.UpdateProxyResource(proxyResource, preconditionUpdateAt(requestInfo, oldProxyResource.UpdateAt))
+		return updatedProxyResource, err
+	})
 
 	// Check unexpected DB error
 	if err != nil {
 		//Transform to DB error
 		dbError := err.(*database.Error)
-		return nil, &Error{
-			Code:    UNKNOWN_API_ERROR,
-			Message: dbError.Message,
+		switch dbError.Code {
+		case database.PRECONDITION_FAILED:
+			return nil, &Error{
+				Code:    PRECONDITION_FAILED_ERROR,
+				Message: fmt.Sprintf("Resource %v has changed", oldProxyResource.Urn),
+			}
+		default: // Unexpected error
+			return nil, &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: dbError.Message,
+			}
 		}
 	}
 
@@ -375,15 +399,31 @@ func (api WorkerAPI) RemoveProxyResource(requestInfo RequestInfo, org string, na
 		}
 	}
 
-	err = api.ProxyRepo.RemoveProxyResource(proxyResource.ID)
+	// Check that resource wasn't changed since client retrieved it
+	err = CheckPrecondition(requestInfo, proxyResource.Urn, proxyResource.UpdateAt)
+	if err != nil {
+		return err
+	}
+
+	err = api.auditedChange(requestInfo, PROXY_ACTION_DELETE_RESOURCE, proxyResource.Urn, proxyResource, func(repos Repositories) (interface{}, error) {
+		return nil, repos.ProxyRepo.RemoveProxyResource(proxyResource.ID, preconditionUpdateAt(requestInfo, proxyResource.UpdateAt))
+	})
 
 	// Error handling
 	if err != nil {
 		// Transform to DB error
 		dbError := err.(*database.Error)
-		return &Error{
-			Code:    UNKNOWN_API_ERROR,
-			Message: dbError.Message,
+		switch dbError.Code {
+		case database.PRECONDITION_FAILED:
+			return &Error{
+				Code:    PRECONDITION_FAILED_ERROR,
+				Message: fmt.Sprintf("Resource %v has changed", proxyResource.Urn),
+			}
+		default: // Unexpected error
+			return &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: dbError.Message,
+			}
 		}
 	}
 
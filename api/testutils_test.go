@@ -12,42 +12,58 @@ import (
 )
 
 const (
-	GetUserByExternalIDMethod      = "GetUserByExternalID"
-	AddUserMethod                  = "AddUser"
-	UpdateUserMethod               = "UpdateUser"
-	GetUsersFilteredMethod         = "GetUsersFiltered"
-	GetGroupsByUserIDMethod        = "GetGroupsByUserID"
-	RemoveUserMethod               = "RemoveUser"
-	GetGroupByNameMethod           = "GetGroupByName"
-	IsMemberOfGroupMethod          = "IsMemberOfGroup"
-	GetGroupMembersMethod          = "GetGroupMembers"
-	IsAttachedToGroupMethod        = "IsAttachedToGroup"
-	GetAttachedPoliciesMethod      = "GetAttachedPolicies"
-	GetGroupsFilteredMethod        = "GetGroupsFiltered"
-	RemoveGroupMethod              = "RemoveGroup"
-	AddGroupMethod                 = "AddGroup"
-	AddMemberMethod                = "AddMember"
-	RemoveMemberMethod             = "RemoveMember"
-	UpdateGroupMethod              = "UpdateGroup"
-	AttachPolicyMethod             = "AttachPolicy"
-	DetachPolicyMethod             = "DetachPolicy"
-	GetPolicyByNameMethod          = "GetPolicyByName"
-	AddPolicyMethod                = "AddPolicy"
-	UpdatePolicyMethod             = "UpdatePolicy"
-	RemovePolicyMethod             = "RemovePolicy"
-	GetPoliciesFilteredMethod      = "GetPoliciesFiltered"
-	GetAttachedGroupsMethod        = "GetAttachedGroups"
-	OrderByValidColumnsMethod      = "OrderByValidColumns"
-	GetProxyResourcesMethod        = "GetProxyResources"
-	RemoveProxyResourceMethod      = "RemoveProxyResource"
-	AddProxyResourceMethod         = "AddProxyResource"
-	UpdateProxyResourceMethod      = "UpdateProxyResource"
-	GetProxyResourceByNameMethod   = "GetProxyResourceByName"
-	AddOidcProviderMethod          = "AddOidcProvider"
-	GetOidcProviderByNameMethod    = "GetOidcProviderByName"
-	GetOidcProvidersFilteredMethod = "GetOidcProvidersFiltered"
-	UpdateOidcProviderMethod       = "UpdateOidcProvider"
-	RemoveOidcProviderMethod       = "RemoveOidcProviderMethod"
+	GetUserByExternalIDMethod         = "GetUserByExternalID"
+	AddUserMethod                     = "AddUser"
+	UpdateUserMethod                  = "UpdateUser"
+	GetUsersFilteredMethod            = "GetUsersFiltered"
+	GetGroupsByUserIDMethod           = "GetGroupsByUserID"
+	RemoveUserMethod                  = "RemoveUser"
+	GetGroupByNameMethod              = "GetGroupByName"
+	IsMemberOfGroupMethod             = "IsMemberOfGroup"
+	GetGroupMembersMethod             = "GetGroupMembers"
+	IsAttachedToGroupMethod           = "IsAttachedToGroup"
+	GetAttachedPoliciesMethod         = "GetAttachedPolicies"
+	GetGroupsFilteredMethod           = "GetGroupsFiltered"
+	RemoveGroupMethod                 = "RemoveGroup"
+	AddGroupMethod                    = "AddGroup"
+	AddMemberMethod                   = "AddMember"
+	RemoveMemberMethod                = "RemoveMember"
+	UpdateGroupMethod                 = "UpdateGroup"
+	AttachPolicyMethod                = "AttachPolicy"
+	DetachPolicyMethod                = "DetachPolicy"
+	GetPolicyByNameMethod             = "GetPolicyByName"
+	AddPolicyMethod                   = "AddPolicy"
+	UpdatePolicyMethod                = "UpdatePolicy"
+	RemovePolicyMethod                = "RemovePolicy"
+	GetPoliciesFilteredMethod         = "GetPoliciesFiltered"
+	GetAttachedGroupsMethod           = "GetAttachedGroups"
+	GetPolicyVersionsMethod           = "GetPolicyVersions"
+	GetPolicyVersionMethod            = "GetPolicyVersion"
+	OrderByValidColumnsMethod         = "OrderByValidColumns"
+	GetProxyResourcesMethod           = "GetProxyResources"
+	RemoveProxyResourceMethod         = "RemoveProxyResource"
+	AddProxyResourceMethod            = "AddProxyResource"
+	UpdateProxyResourceMethod         = "UpdateProxyResource"
+	GetProxyResourceByNameMethod      = "GetProxyResourceByName"
+	AddOidcProviderMethod             = "AddOidcProvider"
+	GetOidcProviderByNameMethod       = "GetOidcProviderByName"
+	GetOidcProvidersFilteredMethod    = "GetOidcProvidersFiltered"
+	UpdateOidcProviderMethod          = "UpdateOidcProvider"
+	RemoveOidcProviderMethod          = "RemoveOidcProviderMethod"
+	AttachPolicyToUserMethod          = "AttachPolicyToUser"
+	DetachPolicyFromUserMethod        = "DetachPolicyFromUser"
+	IsAttachedToUserMethod            = "IsAttachedToUser"
+	GetAttachedPoliciesByUserIDMethod = "GetAttachedPoliciesByUserID"
+	AddSubgroupMethod                 = "AddSubgroup"
+	RemoveSubgroupMethod              = "RemoveSubgroup"
+	IsSubgroupOfGroupMethod           = "IsSubgroupOfGroup"
+	GetSubgroupsMethod                = "GetSubgroups"
+	GetParentGroupsMethod             = "GetParentGroups"
+	GetPoliciesForUserMethod          = "GetPoliciesForUser"
+	AddAuditEventMethod               = "AddAuditEvent"
+	GetAuditEventsFilteredMethod      = "GetAuditEventsFiltered"
+	RunInTransactionMethod            = "RunInTransaction"
+	GetAuthzSnapshotMethod            = "GetAuthzSnapshot"
 )
 
 // TestRepo that implements all repo manager interfaces
@@ -69,6 +85,18 @@ type TestPolicyGroupRelation struct {
 	CreateAt time.Time
 }
 
+type TestPolicyUserRelation struct {
+	User     *User
+	Policy   *Policy
+	CreateAt time.Time
+}
+
+type TestGroupSubgroupRelation struct {
+	Group    *Group
+	Subgroup *Group
+	CreateAt time.Time
+}
+
 var testFilter = Filter{
 	PathPrefix: "",
 	Org:        "",
@@ -88,40 +116,55 @@ func makeTestRepo() *TestRepo {
 	}
 	testRepo.ArgsIn[GetUserByExternalIDMethod] = make([]interface{}, 1)
 	testRepo.ArgsIn[AddUserMethod] = make([]interface{}, 1)
-	testRepo.ArgsIn[UpdateUserMethod] = make([]interface{}, 1)
+	testRepo.ArgsIn[UpdateUserMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[GetUsersFilteredMethod] = make([]interface{}, 1)
 	testRepo.ArgsIn[GetGroupsByUserIDMethod] = make([]interface{}, 2)
-	testRepo.ArgsIn[RemoveUserMethod] = make([]interface{}, 1)
+	testRepo.ArgsIn[RemoveUserMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[GetGroupByNameMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[IsMemberOfGroupMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[GetGroupMembersMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[IsAttachedToGroupMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[GetAttachedPoliciesMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[GetGroupsFilteredMethod] = make([]interface{}, 1)
-	testRepo.ArgsIn[RemoveGroupMethod] = make([]interface{}, 1)
+	testRepo.ArgsIn[RemoveGroupMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[AddGroupMethod] = make([]interface{}, 1)
 	testRepo.ArgsIn[AddMemberMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[RemoveMemberMethod] = make([]interface{}, 2)
-	testRepo.ArgsIn[UpdateGroupMethod] = make([]interface{}, 1)
+	testRepo.ArgsIn[UpdateGroupMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[AttachPolicyMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[DetachPolicyMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[GetPolicyByNameMethod] = make([]interface{}, 2)
-	testRepo.ArgsIn[AddPolicyMethod] = make([]interface{}, 1)
-	testRepo.ArgsIn[UpdatePolicyMethod] = make([]interface{}, 1)
-	testRepo.ArgsIn[RemovePolicyMethod] = make([]interface{}, 1)
+	testRepo.ArgsIn[AddPolicyMethod] = make([]interface{}, 2)
+	testRepo.ArgsIn[UpdatePolicyMethod] = make([]interface{}, 3)
+	testRepo.ArgsIn[RemovePolicyMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[GetPoliciesFilteredMethod] = make([]interface{}, 1)
 	testRepo.ArgsIn[GetAttachedGroupsMethod] = make([]interface{}, 2)
+	testRepo.ArgsIn[GetPolicyVersionsMethod] = make([]interface{}, 2)
+	testRepo.ArgsIn[GetPolicyVersionMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[OrderByValidColumnsMethod] = make([]interface{}, 1)
 	testRepo.ArgsIn[GetProxyResourcesMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[RemoveProxyResourceMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[AddProxyResourceMethod] = make([]interface{}, 1)
-	testRepo.ArgsIn[UpdateProxyResourceMethod] = make([]interface{}, 1)
+	testRepo.ArgsIn[UpdateProxyResourceMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[GetProxyResourceByNameMethod] = make([]interface{}, 2)
 	testRepo.ArgsIn[AddOidcProviderMethod] = make([]interface{}, 1)
 	testRepo.ArgsIn[GetOidcProviderByNameMethod] = make([]interface{}, 1)
 	testRepo.ArgsIn[GetOidcProvidersFilteredMethod] = make([]interface{}, 1)
-	testRepo.ArgsIn[UpdateOidcProviderMethod] = make([]interface{}, 1)
-	testRepo.ArgsIn[RemoveOidcProviderMethod] = make([]interface{}, 1)
+	testRepo.ArgsIn[UpdateOidcProviderMethod] = make([]interface{}, 2)
+	testRepo.ArgsIn[RemoveOidcProviderMethod] = make([]interface{}, 2)
+	testRepo.ArgsIn[AttachPolicyToUserMethod] = make([]interface{}, 2)
+	testRepo.ArgsIn[DetachPolicyFromUserMethod] = make([]interface{}, 2)
+	testRepo.ArgsIn[IsAttachedToUserMethod] = make([]interface{}, 2)
+	testRepo.ArgsIn[GetAttachedPoliciesByUserIDMethod] = make([]interface{}, 2)
+	testRepo.ArgsIn[AddSubgroupMethod] = make([]interface{}, 2)
+	testRepo.ArgsIn[RemoveSubgroupMethod] = make([]interface{}, 2)
+	testRepo.ArgsIn[IsSubgroupOfGroupMethod] = make([]interface{}, 2)
+	testRepo.ArgsIn[GetSubgroupsMethod] = make([]interface{}, 2)
+	testRepo.ArgsIn[GetParentGroupsMethod] = make([]interface{}, 2)
+	testRepo.ArgsIn[GetPoliciesForUserMethod] = make([]interface{}, 1)
+	testRepo.ArgsIn[AddAuditEventMethod] = make([]interface{}, 1)
+	testRepo.ArgsIn[GetAuditEventsFilteredMethod] = make([]interface{}, 1)
+	testRepo.ArgsIn[RunInTransactionMethod] = make([]interface{}, 1)
 
 	testRepo.ArgsOut[GetUserByExternalIDMethod] = make([]interface{}, 2)
 	testRepo.ArgsOut[AddUserMethod] = make([]interface{}, 2)
@@ -148,6 +191,8 @@ func makeTestRepo() *TestRepo {
 	testRepo.ArgsOut[RemovePolicyMethod] = make([]interface{}, 1)
 	testRepo.ArgsOut[GetPoliciesFilteredMethod] = make([]interface{}, 3)
 	testRepo.ArgsOut[GetAttachedGroupsMethod] = make([]interface{}, 3)
+	testRepo.ArgsOut[GetPolicyVersionsMethod] = make([]interface{}, 3)
+	testRepo.ArgsOut[GetPolicyVersionMethod] = make([]interface{}, 2)
 	testRepo.ArgsOut[OrderByValidColumnsMethod] = make([]interface{}, 1)
 	testRepo.ArgsOut[GetProxyResourcesMethod] = make([]interface{}, 3)
 	testRepo.ArgsOut[RemoveProxyResourceMethod] = make([]interface{}, 1)
@@ -159,6 +204,20 @@ func makeTestRepo() *TestRepo {
 	testRepo.ArgsOut[GetOidcProvidersFilteredMethod] = make([]interface{}, 3)
 	testRepo.ArgsOut[UpdateOidcProviderMethod] = make([]interface{}, 2)
 	testRepo.ArgsOut[RemoveOidcProviderMethod] = make([]interface{}, 1)
+	testRepo.ArgsOut[AttachPolicyToUserMethod] = make([]interface{}, 1)
+	testRepo.ArgsOut[DetachPolicyFromUserMethod] = make([]interface{}, 1)
+	testRepo.ArgsOut[IsAttachedToUserMethod] = make([]interface{}, 2)
+	testRepo.ArgsOut[GetAttachedPoliciesByUserIDMethod] = make([]interface{}, 3)
+	testRepo.ArgsOut[AddSubgroupMethod] = make([]interface{}, 1)
+	testRepo.ArgsOut[RemoveSubgroupMethod] = make([]interface{}, 1)
+	testRepo.ArgsOut[IsSubgroupOfGroupMethod] = make([]interface{}, 2)
+	testRepo.ArgsOut[GetSubgroupsMethod] = make([]interface{}, 3)
+	testRepo.ArgsOut[GetParentGroupsMethod] = make([]interface{}, 3)
+	testRepo.ArgsOut[GetPoliciesForUserMethod] = make([]interface{}, 3)
+	testRepo.ArgsOut[AddAuditEventMethod] = make([]interface{}, 1)
+	testRepo.ArgsOut[GetAuditEventsFilteredMethod] = make([]interface{}, 3)
+	testRepo.ArgsOut[RunInTransactionMethod] = make([]interface{}, 1)
+	testRepo.ArgsOut[GetAuthzSnapshotMethod] = make([]interface{}, 2)
 
 	return testRepo
 }
@@ -170,6 +229,10 @@ func makeTestAPI(testRepo *TestRepo) *WorkerAPI {
 		PolicyRepo:   testRepo,
 		ProxyRepo:    testRepo,
 		AuthOidcRepo: testRepo,
+		AuditRepo:    testRepo,
+
+		AuthzSnapshotRepo: testRepo,
+		TransactionRepo:   testRepo,
 	}
 	Log = &log.Logger{
 		Out:       bytes.NewBuffer([]byte{}),
@@ -225,6 +288,34 @@ func (t TestPolicyGroupRelation) GetDate() time.Time {
 	return t.CreateAt
 }
 
+//////////////////////
+// PolicyUserRelation
+//////////////////////
+
+func (t TestPolicyUserRelation) GetUser() *User {
+	return t.User
+}
+
+func (t TestPolicyUserRelation) GetPolicy() *Policy {
+	return t.Policy
+}
+
+func (t TestPolicyUserRelation) GetDate() time.Time {
+	return t.CreateAt
+}
+
+func (t TestGroupSubgroupRelation) GetGroup() *Group {
+	return t.Group
+}
+
+func (t TestGroupSubgroupRelation) GetSubgroup() *Group {
+	return t.Subgroup
+}
+
+func (t TestGroupSubgroupRelation) GetDate() time.Time {
+	return t.CreateAt
+}
+
 //////////////////
 // User repo
 //////////////////
@@ -258,8 +349,9 @@ func (t TestRepo) AddUser(user User) (*User, error) {
 	return created, err
 }
 
-func (t TestRepo) UpdateUser(user User) (*User, error) {
+func (t TestRepo) UpdateUser(user User, lastUpdateAt time.Time) (*User, error) {
 	t.ArgsIn[UpdateUserMethod][0] = user
+	t.ArgsIn[UpdateUserMethod][1] = lastUpdateAt
 	var updated *User
 	if t.ArgsOut[UpdateUserMethod][0] != nil {
 		updated = t.ArgsOut[UpdateUserMethod][0].(*User)
@@ -310,8 +402,64 @@ func (t TestRepo) GetGroupsByUserID(id string, filter *Filter) ([]UserGroupRelat
 	return groups, total, err
 }
 
-func (t TestRepo) RemoveUser(id string) error {
+func (t TestRepo) AttachPolicyToUser(userID string, policyID string) error {
+	t.ArgsIn[AttachPolicyToUserMethod][0] = userID
+	t.ArgsIn[AttachPolicyToUserMethod][1] = policyID
+	var err error
+	if t.ArgsOut[AttachPolicyToUserMethod][0] != nil {
+		err = t.ArgsOut[AttachPolicyToUserMethod][0].(error)
+	}
+	return err
+}
+
+func (t TestRepo) DetachPolicyFromUser(userID string, policyID string) error {
+	t.ArgsIn[DetachPolicyFromUserMethod][0] = userID
+	t.ArgsIn[DetachPolicyFromUserMethod][1] = policyID
+	var err error
+	if t.ArgsOut[DetachPolicyFromUserMethod][0] != nil {
+		err = t.ArgsOut[DetachPolicyFromUserMethod][0].(error)
+	}
+	return err
+}
+
+func (t TestRepo) IsAttachedToUser(userID string, policyID string) (bool, error) {
+	t.ArgsIn[IsAttachedToUserMethod][0] = userID
+	t.ArgsIn[IsAttachedToUserMethod][1] = policyID
+	var isAttached bool
+	if t.ArgsOut[IsAttachedToUserMethod][0] != nil {
+		isAttached = t.ArgsOut[IsAttachedToUserMethod][0].(bool)
+	}
+	var err error
+	if t.ArgsOut[IsAttachedToUserMethod][1] != nil {
+		err = t.ArgsOut[IsAttachedToUserMethod][1].(error)
+	}
+	return isAttached, err
+}
+
+func (t TestRepo) GetAttachedPoliciesByUserID(userID string, filter *Filter) ([]PolicyUserRelation, int, error) {
+	t.ArgsIn[GetAttachedPoliciesByUserIDMethod][0] = userID
+	t.ArgsIn[GetAttachedPoliciesByUserIDMethod][1] = filter
+	var policies []PolicyUserRelation
+	if t.ArgsOut[GetAttachedPoliciesByUserIDMethod][0] != nil {
+		testPolicies := t.ArgsOut[GetAttachedPoliciesByUserIDMethod][0].([]TestPolicyUserRelation)
+		for _, v := range testPolicies {
+			policies = append(policies, v)
+		}
+	}
+	var total int
+	if t.ArgsOut[GetAttachedPoliciesByUserIDMethod][1] != nil {
+		total = t.ArgsOut[GetAttachedPoliciesByUserIDMethod][1].(int)
+	}
+	var err error
+	if t.ArgsOut[GetAttachedPoliciesByUserIDMethod][2] != nil {
+		err = t.ArgsOut[GetAttachedPoliciesByUserIDMethod][2].(error)
+	}
+	return policies, total, err
+}
+
+func (t TestRepo) RemoveUser(id string, lastUpdateAt time.Time) error {
 	t.ArgsIn[RemoveUserMethod][0] = id
+	t.ArgsIn[RemoveUserMethod][1] = lastUpdateAt
 	var err error
 	if t.ArgsOut[RemoveUserMethod][0] != nil {
 		err = t.ArgsOut[RemoveUserMethod][0].(error)
@@ -356,6 +504,9 @@ func (t TestRepo) IsMemberOfGroup(userID string, groupID string) (bool, error) {
 
 func (t TestRepo) GetGroupMembers(groupID string, filter *Filter) ([]UserGroupRelation, int, error) {
 	t.ArgsIn[GetGroupMembersMethod][0] = groupID
+	if specialFunc, ok := t.SpecialFuncs[GetGroupMembersMethod].(func(groupID string, filter *Filter) ([]UserGroupRelation, int, error)); ok && specialFunc != nil {
+		return specialFunc(groupID, filter)
+	}
 	var members []UserGroupRelation
 	if t.ArgsOut[GetGroupMembersMethod][0] != nil {
 		testMembers := t.ArgsOut[GetGroupMembersMethod][0].([]TestUserGroupRelation)
@@ -391,6 +542,9 @@ func (t TestRepo) IsAttachedToGroup(groupID string, policyID string) (bool, erro
 
 func (t TestRepo) GetAttachedPolicies(groupID string, filter *Filter) ([]PolicyGroupRelation, int, error) {
 	t.ArgsIn[GetAttachedPoliciesMethod][0] = groupID
+	if specialFunc, ok := t.SpecialFuncs[GetAttachedPoliciesMethod].(func(groupID string, filter *Filter) ([]PolicyGroupRelation, int, error)); ok && specialFunc != nil {
+		return specialFunc(groupID, filter)
+	}
 	var policies []PolicyGroupRelation
 	if t.ArgsOut[GetAttachedPoliciesMethod][0] != nil {
 		testPolicies := t.ArgsOut[GetAttachedPoliciesMethod][0].([]TestPolicyGroupRelation)
@@ -410,6 +564,88 @@ func (t TestRepo) GetAttachedPolicies(groupID string, filter *Filter) ([]PolicyG
 	return policies, total, err
 }
 
+func (t TestRepo) AddSubgroup(groupID string, subgroupID string) error {
+	t.ArgsIn[AddSubgroupMethod][0] = groupID
+	t.ArgsIn[AddSubgroupMethod][1] = subgroupID
+	var err error
+	if t.ArgsOut[AddSubgroupMethod][0] != nil {
+		err = t.ArgsOut[AddSubgroupMethod][0].(error)
+	}
+	return err
+}
+
+func (t TestRepo) RemoveSubgroup(groupID string, subgroupID string) error {
+	t.ArgsIn[RemoveSubgroupMethod][0] = groupID
+	t.ArgsIn[RemoveSubgroupMethod][1] = subgroupID
+	var err error
+	if t.ArgsOut[RemoveSubgroupMethod][0] != nil {
+		err = t.ArgsOut[RemoveSubgroupMethod][0].(error)
+	}
+	return err
+}
+
+func (t TestRepo) IsSubgroupOfGroup(subgroupID string, groupID string) (bool, error) {
+	t.ArgsIn[IsSubgroupOfGroupMethod][0] = subgroupID
+	t.ArgsIn[IsSubgroupOfGroupMethod][1] = groupID
+	var isMember bool
+	if t.ArgsOut[IsSubgroupOfGroupMethod][0] != nil {
+		isMember = t.ArgsOut[IsSubgroupOfGroupMethod][0].(bool)
+	}
+	var err error
+	if t.ArgsOut[IsSubgroupOfGroupMethod][1] != nil {
+		err = t.ArgsOut[IsSubgroupOfGroupMethod][1].(error)
+	}
+	return isMember, err
+}
+
+func (t TestRepo) GetSubgroups(groupID string, filter *Filter) ([]GroupSubgroupRelation, int, error) {
+	t.ArgsIn[GetSubgroupsMethod][0] = groupID
+	t.ArgsIn[GetSubgroupsMethod][1] = filter
+	if specialFunc, ok := t.SpecialFuncs[GetSubgroupsMethod].(func(groupID string, filter *Filter) ([]GroupSubgroupRelation, int, error)); ok && specialFunc != nil {
+		return specialFunc(groupID, filter)
+	}
+	var subgroups []GroupSubgroupRelation
+	if t.ArgsOut[GetSubgroupsMethod][0] != nil {
+		testSubgroups := t.ArgsOut[GetSubgroupsMethod][0].([]TestGroupSubgroupRelation)
+		for _, v := range testSubgroups {
+			subgroups = append(subgroups, v)
+		}
+	}
+	var total int
+	if t.ArgsOut[GetSubgroupsMethod][1] != nil {
+		total = t.ArgsOut[GetSubgroupsMethod][1].(int)
+	}
+	var err error
+	if t.ArgsOut[GetSubgroupsMethod][2] != nil {
+		err = t.ArgsOut[GetSubgroupsMethod][2].(error)
+	}
+	return subgroups, total, err
+}
+
+func (t TestRepo) GetParentGroups(subgroupID string, filter *Filter) ([]GroupSubgroupRelation, int, error) {
+	t.ArgsIn[GetParentGroupsMethod][0] = subgroupID
+	t.ArgsIn[GetParentGroupsMethod][1] = filter
+	if specialFunc, ok := t.SpecialFuncs[GetParentGroupsMethod].(func(subgroupID string, filter *Filter) ([]GroupSubgroupRelation, int, error)); ok && specialFunc != nil {
+		return specialFunc(subgroupID, filter)
+	}
+	var groups []GroupSubgroupRelation
+	if t.ArgsOut[GetParentGroupsMethod][0] != nil {
+		testGroups := t.ArgsOut[GetParentGroupsMethod][0].([]TestGroupSubgroupRelation)
+		for _, v := range testGroups {
+			groups = append(groups, v)
+		}
+	}
+	var total int
+	if t.ArgsOut[GetParentGroupsMethod][1] != nil {
+		total = t.ArgsOut[GetParentGroupsMethod][1].(int)
+	}
+	var err error
+	if t.ArgsOut[GetParentGroupsMethod][2] != nil {
+		err = t.ArgsOut[GetParentGroupsMethod][2].(error)
+	}
+	return groups, total, err
+}
+
 func (t TestRepo) GetGroupsFiltered(filter *Filter) ([]Group, int, error) {
 	t.ArgsIn[GetGroupsFilteredMethod][0] = filter
 
@@ -427,8 +663,9 @@ func (t TestRepo) GetGroupsFiltered(filter *Filter) ([]Group, int, error) {
 	}
 	return groups, total, err
 }
-func (t TestRepo) RemoveGroup(id string) error {
+func (t TestRepo) RemoveGroup(id string, lastUpdateAt time.Time) error {
 	t.ArgsIn[RemoveGroupMethod][0] = id
+	t.ArgsIn[RemoveGroupMethod][1] = lastUpdateAt
 	var err error
 	if t.ArgsOut[RemoveGroupMethod][0] != nil {
 		err = t.ArgsOut[RemoveGroupMethod][0].(error)
@@ -469,8 +706,9 @@ func (t TestRepo) RemoveMember(userID string, groupID string) error {
 	return err
 }
 
-func (t TestRepo) UpdateGroup(group Group) (*Group, error) {
+func (t TestRepo) UpdateGroup(group Group, lastUpdateAt time.Time) (*Group, error) {
 	t.ArgsIn[UpdateGroupMethod][0] = group
+	t.ArgsIn[UpdateGroupMethod][1] = lastUpdateAt
 
 	var updated *Group
 	if t.ArgsOut[UpdateGroupMethod][0] != nil {
@@ -523,8 +761,9 @@ func (t TestRepo) GetPolicyByName(org string, name string) (*Policy, error) {
 	return policy, err
 }
 
-func (t TestRepo) AddPolicy(policy Policy) (*Policy, error) {
+func (t TestRepo) AddPolicy(policy Policy, author string) (*Policy, error) {
 	t.ArgsIn[AddPolicyMethod][0] = policy
+	t.ArgsIn[AddPolicyMethod][1] = author
 	var created *Policy
 	if t.ArgsOut[AddPolicyMethod][0] != nil {
 		created = t.ArgsOut[AddPolicyMethod][0].(*Policy)
@@ -536,8 +775,10 @@ func (t TestRepo) AddPolicy(policy Policy) (*Policy, error) {
 	return created, err
 }
 
-func (t TestRepo) UpdatePolicy(policy Policy) (*Policy, error) {
+func (t TestRepo) UpdatePolicy(policy Policy, author string, lastUpdateAt time.Time) (*Policy, error) {
 	t.ArgsIn[UpdatePolicyMethod][0] = policy
+	t.ArgsIn[UpdatePolicyMethod][1] = author
+	t.ArgsIn[UpdatePolicyMethod][2] = lastUpdateAt
 
 	var updated *Policy
 	if t.ArgsOut[UpdatePolicyMethod][0] != nil {
@@ -550,8 +791,9 @@ func (t TestRepo) UpdatePolicy(policy Policy) (*Policy, error) {
 	return updated, err
 }
 
-func (t TestRepo) RemovePolicy(id string) error {
+func (t TestRepo) RemovePolicy(id string, lastUpdateAt time.Time) error {
 	t.ArgsIn[RemovePolicyMethod][0] = id
+	t.ArgsIn[RemovePolicyMethod][1] = lastUpdateAt
 	var err error
 	if t.ArgsOut[RemovePolicyMethod][0] != nil {
 		err = t.ArgsOut[RemovePolicyMethod][0].(error)
@@ -598,6 +840,116 @@ func (t TestRepo) GetAttachedGroups(policyID string, filter *Filter) ([]PolicyGr
 	return groups, total, err
 }
 
+func (t TestRepo) GetPolicyVersions(policyID string, filter *Filter) ([]PolicyVersion, int, error) {
+	t.ArgsIn[GetPolicyVersionsMethod][0] = policyID
+	t.ArgsIn[GetPolicyVersionsMethod][1] = filter
+
+	var versions []PolicyVersion
+	if t.ArgsOut[GetPolicyVersionsMethod][0] != nil {
+		versions = t.ArgsOut[GetPolicyVersionsMethod][0].([]PolicyVersion)
+	}
+	var total int
+	if t.ArgsOut[GetPolicyVersionsMethod][1] != nil {
+		total = t.ArgsOut[GetPolicyVersionsMethod][1].(int)
+	}
+	var err error
+	if t.ArgsOut[GetPolicyVersionsMethod][2] != nil {
+		err = t.ArgsOut[GetPolicyVersionsMethod][2].(error)
+	}
+	return versions, total, err
+}
+
+func (t TestRepo) GetPolicyVersion(policyID string, version int) (*PolicyVersion, error) {
+	t.ArgsIn[GetPolicyVersionMethod][0] = policyID
+	t.ArgsIn[GetPolicyVersionMethod][1] = version
+	if specialFunc, ok := t.SpecialFuncs[GetPolicyVersionMethod].(func(policyID string, version int) (*PolicyVersion, error)); ok && specialFunc != nil {
+		return specialFunc(policyID, version)
+	}
+
+	var policyVersion *PolicyVersion
+	if t.ArgsOut[GetPolicyVersionMethod][0] != nil {
+		policyVersion = t.ArgsOut[GetPolicyVersionMethod][0].(*PolicyVersion)
+	}
+	var err error
+	if t.ArgsOut[GetPolicyVersionMethod][1] != nil {
+		err = t.ArgsOut[GetPolicyVersionMethod][1].(error)
+	}
+	return policyVersion, err
+}
+
+func (t TestRepo) GetPoliciesForUser(userID string) ([]Policy, []string, error) {
+	t.ArgsIn[GetPoliciesForUserMethod][0] = userID
+	if specialFunc, ok := t.SpecialFuncs[GetPoliciesForUserMethod].(func(userID string) ([]Policy, []string, error)); ok && specialFunc != nil {
+		return specialFunc(userID)
+	}
+	if t.ArgsOut[GetPoliciesForUserMethod][0] != nil || t.ArgsOut[GetPoliciesForUserMethod][1] != nil ||
+		t.ArgsOut[GetPoliciesForUserMethod][2] != nil {
+		var policies []Policy
+		if t.ArgsOut[GetPoliciesForUserMethod][0] != nil {
+			policies = t.ArgsOut[GetPoliciesForUserMethod][0].([]Policy)
+		}
+		var groupIDs []string
+		if t.ArgsOut[GetPoliciesForUserMethod][1] != nil {
+			groupIDs = t.ArgsOut[GetPoliciesForUserMethod][1].([]string)
+		}
+		var err error
+		if t.ArgsOut[GetPoliciesForUserMethod][2] != nil {
+			err = t.ArgsOut[GetPoliciesForUserMethod][2].(error)
+		}
+		return policies, groupIDs, err
+	}
+
+	// By default, policies are composed from the user groups, group hierarchy and attached policies methods,
+	// as database does in a single query
+	userGroups, _, err := t.GetGroupsByUserID(userID, &Filter{})
+	if err != nil {
+		return nil, nil, err
+	}
+	groups := []Group{}
+	visited := map[string]bool{}
+	for _, g := range userGroups {
+		groups = append(groups, *g.GetGroup())
+		visited[g.GetGroup().ID] = true
+	}
+	for i := 0; i < len(groups); i++ {
+		parents, _, err := t.GetParentGroups(groups[i].ID, &Filter{})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, p := range parents {
+			if !visited[p.GetGroup().ID] {
+				visited[p.GetGroup().ID] = true
+				groups = append(groups, *p.GetGroup())
+			}
+		}
+	}
+
+	policies := []Policy{}
+	for _, group := range groups {
+		groupPolicies, _, err := t.GetAttachedPolicies(group.ID, &Filter{})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, p := range groupPolicies {
+			policies = append(policies, *p.GetPolicy())
+		}
+	}
+	userPolicies, _, err := t.GetAttachedPoliciesByUserID(userID, &Filter{})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, p := range userPolicies {
+		policies = append(policies, *p.GetPolicy())
+	}
+
+	groupIDs := []string{}
+	for _, group := range groups {
+		groupIDs = append(groupIDs, group.ID)
+	}
+
+	return policies, groupIDs, nil
+}
+
 func (t TestRepo) OrderByValidColumns(action string) []string {
 	t.ArgsIn[OrderByValidColumnsMethod][0] = action
 	var validColumns []string
@@ -624,8 +976,9 @@ func (t TestRepo) AddProxyResource(pr ProxyResource) (*ProxyResource, error) {
 	return created, err
 }
 
-func (t TestRepo) UpdateProxyResource(proxyResource ProxyResource) (*ProxyResource, error) {
+func (t TestRepo) UpdateProxyResource(proxyResource ProxyResource, lastUpdateAt time.Time) (*ProxyResource, error) {
 	t.ArgsIn[UpdateProxyResourceMethod][0] = proxyResource
+	t.ArgsIn[UpdateProxyResourceMethod][1] = lastUpdateAt
 
 	var updated *ProxyResource
 	if t.ArgsOut[UpdateProxyResourceMethod][0] != nil {
@@ -673,8 +1026,9 @@ func (t TestRepo) GetProxyResources(filter *Filter) ([]ProxyResource, int, error
 	return resources, total, err
 }
 
-func (t TestRepo) RemoveProxyResource(id string) error {
+func (t TestRepo) RemoveProxyResource(id string, lastUpdateAt time.Time) error {
 	t.ArgsIn[RemoveProxyResourceMethod][0] = id
+	t.ArgsIn[RemoveProxyResourceMethod][1] = lastUpdateAt
 	var err error
 	if t.ArgsOut[RemoveProxyResourceMethod][0] != nil {
 		err = t.ArgsOut[RemoveProxyResourceMethod][0].(error)
@@ -733,8 +1087,9 @@ func (t TestRepo) GetOidcProvidersFiltered(filter *Filter) ([]OidcProvider, int,
 	return resources, total, err
 }
 
-func (t TestRepo) UpdateOidcProvider(oidcProvider OidcProvider) (*OidcProvider, error) {
+func (t TestRepo) UpdateOidcProvider(oidcProvider OidcProvider, lastUpdateAt time.Time) (*OidcProvider, error) {
 	t.ArgsIn[UpdateOidcProviderMethod][0] = oidcProvider
+	t.ArgsIn[UpdateOidcProviderMethod][1] = lastUpdateAt
 
 	var updated *OidcProvider
 	if t.ArgsOut[UpdateOidcProviderMethod][0] != nil {
@@ -747,8 +1102,9 @@ func (t TestRepo) UpdateOidcProvider(oidcProvider OidcProvider) (*OidcProvider,
 	return updated, err
 }
 
-func (t TestRepo) RemoveOidcProvider(id string) error {
+func (t TestRepo) RemoveOidcProvider(id string, lastUpdateAt time.Time) error {
 	t.ArgsIn[RemoveOidcProviderMethod][0] = id
+	t.ArgsIn[RemoveOidcProviderMethod][1] = lastUpdateAt
 	var err error
 	if t.ArgsOut[RemoveOidcProviderMethod][0] != nil {
 		err = t.ArgsOut[RemoveOidcProviderMethod][0].(error)
@@ -756,6 +1112,64 @@ func (t TestRepo) RemoveOidcProvider(id string) error {
 	return err
 }
 
+func (t TestRepo) AddAuditEvent(event AuditEvent) error {
+	t.ArgsIn[AddAuditEventMethod][0] = event
+	var err error
+	if t.ArgsOut[AddAuditEventMethod][0] != nil {
+		err = t.ArgsOut[AddAuditEventMethod][0].(error)
+	}
+	return err
+}
+
+func (t TestRepo) GetAuditEventsFiltered(filter *Filter) ([]AuditEvent, int, error) {
+	t.ArgsIn[GetAuditEventsFilteredMethod][0] = filter
+
+	var events []AuditEvent
+	if t.ArgsOut[GetAuditEventsFilteredMethod][0] != nil {
+		events = t.ArgsOut[GetAuditEventsFilteredMethod][0].([]AuditEvent)
+	}
+	var total int
+	if t.ArgsOut[GetAuditEventsFilteredMethod][1] != nil {
+		total = t.ArgsOut[GetAuditEventsFilteredMethod][1].(int)
+	}
+	var err error
+	if t.ArgsOut[GetAuditEventsFilteredMethod][2] != nil {
+		err = t.ArgsOut[GetAuditEventsFilteredMethod][2].(error)
+	}
+	return events, total, err
+}
+
+func (t TestRepo) RunInTransaction(fn func(repos Repositories) error) error {
+	t.ArgsIn[RunInTransactionMethod][0] = fn
+	if err := fn(Repositories{
+		UserRepo:     t,
+		GroupRepo:    t,
+		PolicyRepo:   t,
+		ProxyRepo:    t,
+		AuthOidcRepo: t,
+		AuditRepo:    t,
+	}); err != nil {
+		return err
+	}
+	var err error
+	if t.ArgsOut[RunInTransactionMethod][0] != nil {
+		err = t.ArgsOut[RunInTransactionMethod][0].(error)
+	}
+	return err
+}
+
+func (t TestRepo) GetAuthzSnapshot() (*AuthzSnapshot, error) {
+	var snapshot *AuthzSnapshot
+	if t.ArgsOut[GetAuthzSnapshotMethod][0] != nil {
+		snapshot = t.ArgsOut[GetAuthzSnapshotMethod][0].(*AuthzSnapshot)
+	}
+	var err error
+	if t.ArgsOut[GetAuthzSnapshotMethod][1] != nil {
+		err = t.ArgsOut[GetAuthzSnapshotMethod][1].(error)
+	}
+	return snapshot, err
+}
+
 // Private helper methods
 
 func getRandomString(runeValue []rune, n int) string {
@@ -783,3 +1197,4 @@ func checkMethodResponse(t *testing.T, testcase string, expectedError error, rec
 		assert.Equal(t, receivedResponse, expectedResponse, "Error in test case %v", testcase)
 	}
 }
+
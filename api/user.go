@@ -21,9 +21,24 @@ type User struct {
 }
 
 type UserGroups struct {
+	Org       string    `json:"org,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	CreateAt  time.Time `json:"joined,omitempty"`
+	Inherited bool      `json:"inherited"`
+}
+
+type UserPolicies struct {
 	Org      string    `json:"org,omitempty"`
-	Name     string    `json:"name,omitempty"`
-	CreateAt time.Time `json:"joined,omitempty"`
+	Policy   string    `json:"policy,omitempty"`
+	CreateAt time.Time `json:"attached,omitempty"`
+}
+
+// Restrictions that a user has for an action. Statements with conditions depend on the context of each request,
+// so they aren't part of the restrictions and are returned with their conditions
+type EffectivePermission struct {
+	Action                string        `json:"action,omitempty"`
+	Restrictions          *Restrictions `json:"restrictions,omitempty"`
+	ConditionalStatements []Statement   `json:"conditionalStatements,omitempty"`
 }
 
 func (u User) String() string {
@@ -77,7 +92,12 @@ func (api WorkerAPI) AddUser(requestInfo RequestInfo, externalId string, path st
 		switch dbError.Code {
 		case database.USER_NOT_FOUND:
 			// Create user
-			createdUser, err := api.UserRepo.AddUser(user)
+			var createdUser *User
+			err = api.auditedChange(requestInfo, USER_ACTION_CREATE_USER, user.Urn, nil, func(repos Repositories) (interface{}, error) {
+				var err error
+				createdUser, err = repos.UserRepo.AddUser(user)
+				return createdUser, err
+			})
 
 			// Check unexpected DB error
 			if err != nil {
@@ -213,6 +233,12 @@ func (api WorkerAPI) UpdateUser(requestInfo RequestInfo, externalId string, newP
 		}
 	}
 
+	// Check that resource wasn't changed since client retrieved it
+	err = CheckPrecondition(requestInfo, oldUser.Urn, oldUser.UpdateAt)
+	if err != nil {
+		return nil, err
+	}
+
 	auxUser := User{
 		Urn: CreateUrn("", RESOURCE_USER, newPath, externalId),
 	}
@@ -239,18 +265,32 @@ func (api WorkerAPI) UpdateUser(requestInfo RequestInfo, externalId string, newP
 		Urn:        auxUser.Urn,
 	}
 
-	updatedUser, err := api.UserRepo.UpdateUser(user)
+	var updatedUser *User
+	err = api.auditedChange(requestInfo, USER_ACTION_UPDATE_USER, user.Urn, oldUser, func(repos Repositories) (interface{}, error) {
+		var err error
+		updatedUser, err = repos.UserRepo.UpdateUser(user, preconditionUpdateAt(requestInfo, oldUser.UpdateAt))
+		return updatedUser, err
+	})
 
 	// Check unexpected DB error
 	if err != nil {
 		//Transform to DB error
 		dbError := err.(*database.Error)
-		return nil, &Error{
-			Code:    UNKNOWN_API_ERROR,
-			Message: dbError.Message,
+		switch dbError.Code {
+		case database.PRECONDITION_FAILED:
+			return nil, &Error{
+				Code:    PRECONDITION_FAILED_ERROR,
+				Message: fmt.Sprintf("Resource %v has changed", oldUser.Urn),
+			}
+		default: // Unexpected error
+			return nil, &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: dbError.Message,
+			}
 		}
 	}
 
+	api.AuthzCache.invalidateUser(oldUser.ExternalID)
 	LogOperation(requestInfo.RequestID, requestInfo.Identifier, fmt.Sprintf("User updated from %+v to %+v", oldUser, updatedUser))
 	return updatedUser, nil
 
@@ -276,17 +316,34 @@ func (api WorkerAPI) RemoveUser(requestInfo RequestInfo, externalId string) erro
 		}
 	}
 
-	err = api.UserRepo.RemoveUser(user.ID)
+	// Check that resource wasn't changed since client retrieved it
+	err = CheckPrecondition(requestInfo, user.Urn, user.UpdateAt)
+	if err != nil {
+		return err
+	}
+
+	err = api.auditedChange(requestInfo, USER_ACTION_DELETE_USER, user.Urn, user, func(repos Repositories) (interface{}, error) {
+		return nil, repos.UserRepo.RemoveUser(user.ID, preconditionUpdateAt(requestInfo, user.UpdateAt))
+	})
 
 	// Error handling
 	if err != nil {
 		//Transform to DB error
 		dbError := err.(*database.Error)
-		return &Error{
-			Code:    UNKNOWN_API_ERROR,
-			Message: dbError.Message,
+		switch dbError.Code {
+		case database.PRECONDITION_FAILED:
+			return &Error{
+				Code:    PRECONDITION_FAILED_ERROR,
+				Message: fmt.Sprintf("Resource %v has changed", user.Urn),
+			}
+		default: // Unexpected error
+			return &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: dbError.Message,
+			}
 		}
 	}
+	api.AuthzCache.invalidateUser(user.ExternalID)
 	LogOperation(requestInfo.RequestID, requestInfo.Identifier, fmt.Sprintf("User deleted %+v", user))
 	return nil
 }
@@ -320,7 +377,7 @@ func (api WorkerAPI) ListGroupsByUser(requestInfo RequestInfo, filter *Filter) (
 	}
 
 	// Call group repo to retrieve groups associated to user
-	groups, total, err := api.UserRepo.GetGroupsByUserID(user.ID, filter)
+	groups, _, err := api.UserRepo.GetGroupsByUserID(user.ID, &Filter{OrderBy: filter.OrderBy})
 
 	// Error handling
 	if err != nil {
@@ -333,15 +390,253 @@ func (api WorkerAPI) ListGroupsByUser(requestInfo RequestInfo, filter *Filter) (
 	}
 	// Transform to identifiers
 	groupIDs := []UserGroups{}
+	directGroups := []Group{}
 	for _, g := range groups {
 		groupIDs = append(groupIDs, UserGroups{
 			Org:      g.GetGroup().Org,
 			Name:     g.GetGroup().Name,
 			CreateAt: g.GetDate(),
 		})
+		directGroups = append(directGroups, *g.GetGroup())
+	}
+
+	// Add groups inherited through the group hierarchy
+	ancestors, err := api.getAncestorGroups(directGroups)
+	if err != nil {
+		return nil, total, err
+	}
+	for _, a := range ancestors {
+		groupIDs = append(groupIDs, UserGroups{
+			Org:       a.GetGroup().Org,
+			Name:      a.GetGroup().Name,
+			CreateAt:  a.GetDate(),
+			Inherited: true,
+		})
+	}
+
+	// Paginate
+	total = len(groupIDs)
+	start, end := getPageBounds(filter, total)
+	return groupIDs[start:end], total, nil
+}
+
+func (api WorkerAPI) ListEffectivePermissionsByUser(requestInfo RequestInfo, filter *Filter) ([]EffectivePermission, int, error) {
+	// Check parameters
+	var total int
+	err := validateFilter(filter, nil)
+	if err != nil {
+		return nil, total, err
+	}
+
+	// Call repo to retrieve the user
+	user, err := api.GetUserByExternalID(requestInfo, filter.ExternalID)
+	if err != nil {
+		return nil, total, err
+	}
+
+	// Check restrictions
+	usersFiltered, err := api.GetAuthorizedUsers(requestInfo, user.Urn, USER_ACTION_LIST_EFFECTIVE_PERMS, []User{*user})
+	if err != nil {
+		return nil, total, err
+	}
+	if len(usersFiltered) < 1 {
+		return nil, total, &Error{
+			Code: UNAUTHORIZED_RESOURCES_ERROR,
+			Message: fmt.Sprintf("User with externalId %v is not allowed to access to resource %v",
+				requestInfo.Identifier, user.Urn),
+		}
+	}
+
+	// Retrieve policies attached to user groups and to the user
+	policies, _, err := api.getPoliciesByUser(user)
+	if err != nil {
+		return nil, total, err
+	}
+
+	permissions := getEffectivePermissions(policies, filter.ActionPrefix)
+	total = len(permissions)
+
+	// Paginate
+	start, end := getPageBounds(filter, total)
+	return permissions[start:end], total, nil
+}
+
+func (api WorkerAPI) AttachPolicyToUser(requestInfo RequestInfo, externalId string, org string, policyName string) error {
+	// Call repo to retrieve the user
+	user, err := api.GetUserByExternalID(requestInfo, externalId)
+	if err != nil {
+		return err
+	}
+
+	// Check restrictions
+	usersFiltered, err := api.GetAuthorizedUsers(requestInfo, user.Urn, USER_ACTION_ATTACH_USER_POLICY, []User{*user})
+	if err != nil {
+		return err
+	}
+	if len(usersFiltered) < 1 {
+		return &Error{
+			Code: UNAUTHORIZED_RESOURCES_ERROR,
+			Message: fmt.Sprintf("User with externalId %v is not allowed to access to resource %v",
+				requestInfo.Identifier, user.Urn),
+		}
+	}
+
+	// Check if policy exists
+	policy, err := api.GetPolicyByName(requestInfo, org, policyName)
+	if err != nil {
+		return err
+	}
+
+	// Check existing relationship
+	isAttached, err := api.UserRepo.IsAttachedToUser(user.ID, policy.ID)
+	if err != nil {
+		dbError := err.(*database.Error)
+		return &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: dbError.Message,
+		}
+	}
+
+	if isAttached {
+		return &Error{
+			Code: POLICY_IS_ALREADY_ATTACHED_TO_USER,
+			Message: fmt.Sprintf("Policy with org %v and name %v is already attached to user with externalId %v",
+				policy.Org, policy.Name, user.ExternalID),
+		}
+	}
+
+	// Attach Policy to User
+	err = api.auditedChange(requestInfo, USER_ACTION_ATTACH_USER_POLICY, user.Urn, nil, func(repos Repositories) (interface{}, error) {
+		return policy, repos.UserRepo.AttachPolicyToUser(user.ID, policy.ID)
+	})
+
+	if err != nil {
+		dbError := err.(*database.Error)
+		return &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: dbError.Message,
+		}
+	}
+
+	api.AuthzCache.invalidateUser(user.ExternalID)
+	LogOperation(requestInfo.RequestID, requestInfo.Identifier, fmt.Sprintf("Policy %+v attached to user %+v", policy, user))
+	return nil
+}
+
+func (api WorkerAPI) DetachPolicyToUser(requestInfo RequestInfo, externalId string, org string, policyName string) error {
+	// Call repo to retrieve the user
+	user, err := api.GetUserByExternalID(requestInfo, externalId)
+	if err != nil {
+		return err
+	}
+
+	// Check restrictions
+	usersFiltered, err := api.GetAuthorizedUsers(requestInfo, user.Urn, USER_ACTION_DETACH_USER_POLICY, []User{*user})
+	if err != nil {
+		return err
+	}
+	if len(usersFiltered) < 1 {
+		return &Error{
+			Code: UNAUTHORIZED_RESOURCES_ERROR,
+			Message: fmt.Sprintf("User with externalId %v is not allowed to access to resource %v",
+				requestInfo.Identifier, user.Urn),
+		}
+	}
+
+	// Check if policy exists
+	policy, err := api.GetPolicyByName(requestInfo, org, policyName)
+	if err != nil {
+		return err
+	}
+
+	// Check existing relationship
+	isAttached, err := api.UserRepo.IsAttachedToUser(user.ID, policy.ID)
+	if err != nil {
+		dbError := err.(*database.Error)
+		return &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: dbError.Message,
+		}
+	}
+
+	if !isAttached {
+		return &Error{
+			Code: POLICY_IS_NOT_ATTACHED_TO_USER,
+			Message: fmt.Sprintf("Policy with org %v and name %v is not attached to user with externalId %v",
+				policy.Org, policy.Name, user.ExternalID),
+		}
+	}
+
+	// Detach Policy from User
+	err = api.auditedChange(requestInfo, USER_ACTION_DETACH_USER_POLICY, user.Urn, policy, func(repos Repositories) (interface{}, error) {
+		return nil, repos.UserRepo.DetachPolicyFromUser(user.ID, policy.ID)
+	})
+
+	if err != nil {
+		dbError := err.(*database.Error)
+		return &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: dbError.Message,
+		}
+	}
+
+	api.AuthzCache.invalidateUser(user.ExternalID)
+	LogOperation(requestInfo.RequestID, requestInfo.Identifier, fmt.Sprintf("Policy %+v detached from user %+v", policy, user))
+	return nil
+}
+
+func (api WorkerAPI) ListAttachedUserPolicies(requestInfo RequestInfo, filter *Filter) ([]UserPolicies, int, error) {
+	// Check parameters
+	var total int
+	orderByValidColumns := api.UserRepo.OrderByValidColumns(USER_ACTION_LIST_ATTACHED_USER_POLICIES)
+	err := validateFilter(filter, orderByValidColumns)
+	if err != nil {
+		return nil, total, err
+	}
+
+	// Call repo to retrieve the user
+	user, err := api.GetUserByExternalID(requestInfo, filter.ExternalID)
+	if err != nil {
+		return nil, total, err
+	}
+
+	// Check restrictions
+	usersFiltered, err := api.GetAuthorizedUsers(requestInfo, user.Urn, USER_ACTION_LIST_ATTACHED_USER_POLICIES, []User{*user})
+	if err != nil {
+		return nil, total, err
+	}
+	if len(usersFiltered) < 1 {
+		return nil, total, &Error{
+			Code: UNAUTHORIZED_RESOURCES_ERROR,
+			Message: fmt.Sprintf("User with externalId %v is not allowed to access to resource %v",
+				requestInfo.Identifier, user.Urn),
+		}
+	}
+
+	// Call repo to retrieve the UserPolicyRelations
+	attachedPolicies, total, err := api.UserRepo.GetAttachedPoliciesByUserID(user.ID, filter)
+
+	// Error handling
+	if err != nil {
+		//Transform to DB error
+		dbError := err.(*database.Error)
+		return nil, total, &Error{
+			Code:    UNKNOWN_API_ERROR,
+			Message: dbError.Message,
+		}
+	}
+
+	// Transform to identifiers
+	policies := []UserPolicies{}
+	for _, p := range attachedPolicies {
+		policies = append(policies, UserPolicies{
+			Org:      p.GetPolicy().Org,
+			Policy:   p.GetPolicy().Name,
+			CreateAt: p.GetDate(),
+		})
 	}
 
-	return groupIDs, total, nil
+	return policies, total, nil
 }
 
 // PRIVATE HELPER METHODS
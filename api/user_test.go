@@ -2,6 +2,7 @@ package api
 
 import (
 	"testing"
+	"time"
 
 	"github.com/Tecsisa/foulkon/database"
 	"github.com/stretchr/testify/assert"
@@ -1706,6 +1707,7 @@ func TestAuthAPI_ListGroupsByUser(t *testing.T) {
 		getUserByExternalIDMethodResult *User
 		getGroupsByUserIDMethodResult   []TestUserGroupRelation
 		getAttachedPoliciesMethodResult []TestPolicyGroupRelation
+		getParentGroupsMethodResult     []TestGroupSubgroupRelation
 		// Manager Errors
 		getGroupsByUserIDMethodErr   error
 		getUserByExternalIDMethodErr error
@@ -1757,6 +1759,54 @@ func TestAuthAPI_ListGroupsByUser(t *testing.T) {
 				},
 			},
 		},
+		"OKCaseInheritedGroups": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				ExternalID: "1234",
+			},
+			expectedResponse: []UserGroups{
+				{
+					Org:  "org1",
+					Name: "groupUser1",
+				},
+				{
+					Org:       "org1",
+					Name:      "parentGroup",
+					Inherited: true,
+				},
+			},
+			totalResult: 2,
+			getUserByExternalIDMethodResult: &User{
+				ID:         "543210",
+				ExternalID: "1234",
+				Path:       "/example/",
+			},
+			getGroupsByUserIDMethodResult: []TestUserGroupRelation{
+				{
+					Group: &Group{
+						ID:   "GROUP1",
+						Org:  "org1",
+						Name: "groupUser1",
+						Path: "/path/",
+						Urn:  CreateUrn("org1", RESOURCE_GROUP, "/path/", "groupUser1"),
+					},
+				},
+			},
+			getParentGroupsMethodResult: []TestGroupSubgroupRelation{
+				{
+					Group: &Group{
+						ID:   "PARENT",
+						Org:  "org1",
+						Name: "parentGroup",
+						Path: "/path/",
+						Urn:  CreateUrn("org1", RESOURCE_GROUP, "/path/", "parentGroup"),
+					},
+				},
+			},
+		},
 		"OKCase": {
 			requestInfo: RequestInfo{
 				Identifier: "123456",
@@ -2047,6 +2097,7 @@ func TestAuthAPI_ListGroupsByUser(t *testing.T) {
 		testRepo.ArgsOut[GetGroupsByUserIDMethod][0] = testcase.getGroupsByUserIDMethodResult
 		testRepo.ArgsOut[GetGroupsByUserIDMethod][1] = testcase.totalResult
 		testRepo.ArgsOut[GetGroupsByUserIDMethod][2] = testcase.getGroupsByUserIDMethodErr
+		testRepo.ArgsOut[GetParentGroupsMethod][0] = testcase.getParentGroupsMethodResult
 		testRepo.ArgsOut[GetAttachedPoliciesMethod][0] = testcase.getAttachedPoliciesMethodResult
 		groups, total, err := testAPI.ListGroupsByUser(testcase.requestInfo, testcase.filter)
 		checkMethodResponse(t, x, testcase.wantError, err, testcase.expectedResponse, groups)
@@ -2054,3 +2105,856 @@ func TestAuthAPI_ListGroupsByUser(t *testing.T) {
 	}
 
 }
+
+func TestAuthAPI_ListEffectivePermissionsByUser(t *testing.T) {
+	policies := []TestPolicyGroupRelation{
+		{
+			Policy: &Policy{
+				ID:   "POLICY-ID",
+				Name: "policy",
+				Statements: &[]Statement{
+					{
+						Effect:    "allow",
+						Actions:   []string{USER_ACTION_GET_USER},
+						Resources: []string{GetUrnPrefix("", RESOURCE_USER, "/path/")},
+					},
+					{
+						Effect:    "deny",
+						Actions:   []string{USER_ACTION_GET_USER},
+						Resources: []string{GetUrnPrefix("", RESOURCE_USER, "/path/secret/")},
+					},
+					{
+						Effect:    "allow",
+						Actions:   []string{"iam:*"},
+						Resources: []string{GetUrnPrefix("", RESOURCE_USER, "/other/")},
+					},
+					{
+						Effect:    "allow",
+						Actions:   []string{"product:Read"},
+						Resources: []string{"urn:ews:product:instance:*"},
+					},
+					{
+						Effect:    "deny",
+						Actions:   []string{USER_ACTION_GET_USER},
+						Resources: []string{GetUrnPrefix("", RESOURCE_USER, "/path/")},
+						Conditions: Conditions{
+							CONDITION_IP_ADDRESS: {
+								CONDITION_KEY_SOURCE_IP: {"10.0.0.0/8"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	iamPermission := EffectivePermission{
+		Action: "iam:*",
+		Restrictions: &Restrictions{
+			AllowedUrnPrefixes: []string{GetUrnPrefix("", RESOURCE_USER, "/other/")},
+			AllowedFullUrns:    []string{},
+			DeniedUrnPrefixes:  []string{},
+			DeniedFullUrns:     []string{},
+		},
+	}
+	getUserPermission := EffectivePermission{
+		Action: USER_ACTION_GET_USER,
+		Restrictions: &Restrictions{
+			AllowedUrnPrefixes: []string{
+				GetUrnPrefix("", RESOURCE_USER, "/path/"),
+				GetUrnPrefix("", RESOURCE_USER, "/other/"),
+			},
+			AllowedFullUrns:   []string{},
+			DeniedUrnPrefixes: []string{GetUrnPrefix("", RESOURCE_USER, "/path/secret/")},
+			DeniedFullUrns:    []string{},
+		},
+		// Conditional statements aren't evaluated with the context of the request
+		ConditionalStatements: []Statement{
+			{
+				Effect:    "deny",
+				Actions:   []string{USER_ACTION_GET_USER},
+				Resources: []string{GetUrnPrefix("", RESOURCE_USER, "/path/")},
+				Conditions: Conditions{
+					CONDITION_IP_ADDRESS: {
+						CONDITION_KEY_SOURCE_IP: {"10.0.0.0/8"},
+					},
+				},
+			},
+		},
+	}
+	testcases := map[string]struct {
+		// API Method args
+		requestInfo RequestInfo
+		filter      *Filter
+		// Expected result
+		expectedResponse []EffectivePermission
+		totalResult      int
+		wantError        error
+		// Manager Results
+		getUserByExternalIDMethodResult *User
+		getGroupsByUserIDMethodResult   []TestUserGroupRelation
+		getAttachedPoliciesMethodResult []TestPolicyGroupRelation
+		// Manager Errors
+		getUserByExternalIDMethodErr error
+	}{
+		"OKCaseActionPrefix": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+				Context: RequestContext{
+					SourceIP: "10.1.2.3",
+				},
+			},
+			filter: &Filter{
+				ExternalID:   "1234",
+				ActionPrefix: "iam:Get",
+			},
+			expectedResponse: []EffectivePermission{
+				iamPermission,
+				getUserPermission,
+			},
+			totalResult: 2,
+			getUserByExternalIDMethodResult: &User{
+				ID:         "1234",
+				ExternalID: "1234",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getGroupsByUserIDMethodResult: []TestUserGroupRelation{
+				{
+					Group: &Group{
+						ID: "GROUP-ID",
+					},
+				},
+			},
+			getAttachedPoliciesMethodResult: policies,
+		},
+		"OKCasePagination": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				ExternalID: "1234",
+				Offset:     1,
+				Limit:      1,
+			},
+			expectedResponse: []EffectivePermission{
+				getUserPermission,
+			},
+			totalResult: 3,
+			getUserByExternalIDMethodResult: &User{
+				ID:         "1234",
+				ExternalID: "1234",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getGroupsByUserIDMethodResult: []TestUserGroupRelation{
+				{
+					Group: &Group{
+						ID: "GROUP-ID",
+					},
+				},
+			},
+			getAttachedPoliciesMethodResult: policies,
+		},
+		"OKCaseOffsetExceeded": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				ExternalID: "1234",
+				Offset:     5,
+			},
+			expectedResponse: []EffectivePermission{},
+			totalResult:      3,
+			getUserByExternalIDMethodResult: &User{
+				ID:         "1234",
+				ExternalID: "1234",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getGroupsByUserIDMethodResult: []TestUserGroupRelation{
+				{
+					Group: &Group{
+						ID: "GROUP-ID",
+					},
+				},
+			},
+			getAttachedPoliciesMethodResult: policies,
+		},
+		"ErrorCaseInvalidActionPrefix": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				ExternalID:   "1234",
+				ActionPrefix: "iam*?",
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: actionPrefix iam*?",
+			},
+		},
+		"ErrorCaseUserNotFound": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				ExternalID: "1234",
+			},
+			wantError: &Error{
+				Code:    USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message: "User not found",
+			},
+			getUserByExternalIDMethodErr: &database.Error{
+				Code:    database.USER_NOT_FOUND,
+				Message: "User not found",
+			},
+		},
+		"ErrorCaseUnauthorized": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      false,
+			},
+			filter: &Filter{
+				ExternalID: "1234",
+			},
+			wantError: &Error{
+				Code: UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "User with externalId 123456 is not allowed to access to resource " +
+					CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getUserByExternalIDMethodResult: &User{
+				ID:         "1234",
+				ExternalID: "1234",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+		},
+	}
+
+	for x, testcase := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetUserByExternalIDMethod][0] = testcase.getUserByExternalIDMethodResult
+		testRepo.ArgsOut[GetUserByExternalIDMethod][1] = testcase.getUserByExternalIDMethodErr
+		testRepo.ArgsOut[GetGroupsByUserIDMethod][0] = testcase.getGroupsByUserIDMethodResult
+		testRepo.ArgsOut[GetAttachedPoliciesMethod][0] = testcase.getAttachedPoliciesMethodResult
+		permissions, total, err := testAPI.ListEffectivePermissionsByUser(testcase.requestInfo, testcase.filter)
+		checkMethodResponse(t, x, testcase.wantError, err, testcase.expectedResponse, permissions)
+		assert.Equal(t, testcase.totalResult, total, "Error in test case %v", x)
+	}
+}
+
+func TestAuthAPI_AttachPolicyToUser(t *testing.T) {
+	testcases := map[string]struct {
+		requestInfo RequestInfo
+		externalID  string
+		org         string
+		policyName  string
+		// Expected result
+		wantError error
+		// Manager Results
+		getUserByExternalIDResult *User
+		getPolicyByNameResult     *Policy
+		getPoliciesByUserIDResult []TestPolicyUserRelation
+		isAttachedToUserResult    bool
+		// Manager Errors
+		getUserByExternalIDMethodErr error
+		getPolicyByNameMethodErr     error
+		isAttachedToUserMethodErr    error
+		attachPolicyToUserMethodErr  error
+	}{
+		"OkCaseAdmin": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			externalID: "1234",
+			org:        "123",
+			policyName: "policy1",
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "1234",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getPolicyByNameResult: &Policy{
+				ID:   "test1",
+				Name: "policy1",
+				Org:  "123",
+				Path: "/path/",
+				Urn:  CreateUrn("123", RESOURCE_POLICY, "/path/", "policy1"),
+			},
+		},
+		"OkCase": {
+			requestInfo: RequestInfo{
+				Identifier: "1234",
+				Admin:      false,
+			},
+			externalID: "1234",
+			org:        "123",
+			policyName: "policy1",
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "1234",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getPolicyByNameResult: &Policy{
+				ID:   "test1",
+				Name: "policy1",
+				Org:  "123",
+				Path: "/path/",
+				Urn:  CreateUrn("123", RESOURCE_POLICY, "/path/", "policy1"),
+			},
+			getPoliciesByUserIDResult: []TestPolicyUserRelation{
+				{
+					Policy: &Policy{
+						ID:   "POLICY-USER-ID",
+						Name: "policyUser",
+						Org:  "123",
+						Path: "/path/",
+						Urn:  CreateUrn("123", RESOURCE_POLICY, "/path/", "policyUser"),
+						Statements: &[]Statement{
+							{
+								Effect: "allow",
+								Actions: []string{
+									USER_ACTION_GET_USER,
+									USER_ACTION_ATTACH_USER_POLICY,
+								},
+								Resources: []string{
+									GetUrnPrefix("", RESOURCE_USER, "/path/"),
+								},
+							},
+							{
+								Effect: "allow",
+								Actions: []string{
+									POLICY_ACTION_GET_POLICY,
+								},
+								Resources: []string{
+									GetUrnPrefix("123", RESOURCE_POLICY, "/path/"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"ErrorCaseUserNotFound": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			externalID: "1234",
+			org:        "123",
+			policyName: "policy1",
+			wantError: &Error{
+				Code:    USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message: "Code: UserNotFound",
+			},
+			getUserByExternalIDMethodErr: &database.Error{
+				Code:    database.USER_NOT_FOUND,
+				Message: "Code: UserNotFound",
+			},
+		},
+		"ErrorCaseUnauthorized": {
+			requestInfo: RequestInfo{
+				Identifier: "1234",
+				Admin:      false,
+			},
+			externalID: "1234",
+			org:        "123",
+			policyName: "policy1",
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "User with externalId 1234 is not allowed to access to resource urn:iws:iam::user/path/1234",
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "1234",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getPoliciesByUserIDResult: []TestPolicyUserRelation{
+				{
+					Policy: &Policy{
+						ID:   "POLICY-USER-ID",
+						Name: "policyUser",
+						Org:  "123",
+						Path: "/path/",
+						Urn:  CreateUrn("123", RESOURCE_POLICY, "/path/", "policyUser"),
+						Statements: &[]Statement{
+							{
+								Effect: "allow",
+								Actions: []string{
+									USER_ACTION_GET_USER,
+								},
+								Resources: []string{
+									GetUrnPrefix("", RESOURCE_USER, "/path/"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"ErrorCasePolicyNotFound": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			externalID: "1234",
+			org:        "123",
+			policyName: "policy1",
+			wantError: &Error{
+				Code:    POLICY_BY_ORG_AND_NAME_NOT_FOUND,
+				Message: "Code: PolicyNotFound",
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "1234",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getPolicyByNameMethodErr: &database.Error{
+				Code:    database.POLICY_NOT_FOUND,
+				Message: "Code: PolicyNotFound",
+			},
+		},
+		"ErrorCaseIsAttachedDBErr": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			externalID: "1234",
+			org:        "123",
+			policyName: "policy1",
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "1234",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getPolicyByNameResult: &Policy{
+				ID:   "test1",
+				Name: "policy1",
+				Org:  "123",
+				Path: "/path/",
+				Urn:  CreateUrn("123", RESOURCE_POLICY, "/path/", "policy1"),
+			},
+			isAttachedToUserMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCasePolicyIsAlreadyAttached": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			externalID: "1234",
+			org:        "123",
+			policyName: "policy1",
+			wantError: &Error{
+				Code:    POLICY_IS_ALREADY_ATTACHED_TO_USER,
+				Message: "Policy with org 123 and name policy1 is already attached to user with externalId 1234",
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "1234",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getPolicyByNameResult: &Policy{
+				ID:   "test1",
+				Name: "policy1",
+				Org:  "123",
+				Path: "/path/",
+				Urn:  CreateUrn("123", RESOURCE_POLICY, "/path/", "policy1"),
+			},
+			isAttachedToUserResult: true,
+		},
+		"ErrorCaseAttachPolicyDBErr": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			externalID: "1234",
+			org:        "123",
+			policyName: "policy1",
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "1234",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getPolicyByNameResult: &Policy{
+				ID:   "test1",
+				Name: "policy1",
+				Org:  "123",
+				Path: "/path/",
+				Urn:  CreateUrn("123", RESOURCE_POLICY, "/path/", "policy1"),
+			},
+			attachPolicyToUserMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	for x, testcase := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetUserByExternalIDMethod][0] = testcase.getUserByExternalIDResult
+		testRepo.ArgsOut[GetUserByExternalIDMethod][1] = testcase.getUserByExternalIDMethodErr
+		testRepo.ArgsOut[GetPolicyByNameMethod][0] = testcase.getPolicyByNameResult
+		testRepo.ArgsOut[GetPolicyByNameMethod][1] = testcase.getPolicyByNameMethodErr
+		testRepo.ArgsOut[GetAttachedPoliciesByUserIDMethod][0] = testcase.getPoliciesByUserIDResult
+		testRepo.ArgsOut[IsAttachedToUserMethod][0] = testcase.isAttachedToUserResult
+		testRepo.ArgsOut[IsAttachedToUserMethod][1] = testcase.isAttachedToUserMethodErr
+		testRepo.ArgsOut[AttachPolicyToUserMethod][0] = testcase.attachPolicyToUserMethodErr
+
+		err := testAPI.AttachPolicyToUser(testcase.requestInfo, testcase.externalID, testcase.org, testcase.policyName)
+		checkMethodResponse(t, x, testcase.wantError, err, nil, nil)
+	}
+}
+
+func TestAuthAPI_DetachPolicyToUser(t *testing.T) {
+	testcases := map[string]struct {
+		requestInfo RequestInfo
+		externalID  string
+		org         string
+		policyName  string
+		// Expected result
+		wantError error
+		// Manager Results
+		getUserByExternalIDResult *User
+		getPolicyByNameResult     *Policy
+		isAttachedToUserResult    bool
+		// Manager Errors
+		getUserByExternalIDMethodErr error
+		isAttachedToUserMethodErr    error
+		detachPolicyMethodErr        error
+	}{
+		"OkCaseAdmin": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			externalID: "1234",
+			org:        "123",
+			policyName: "policy1",
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "1234",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getPolicyByNameResult: &Policy{
+				ID:   "test1",
+				Name: "policy1",
+				Org:  "123",
+				Path: "/path/",
+				Urn:  CreateUrn("123", RESOURCE_POLICY, "/path/", "policy1"),
+			},
+			isAttachedToUserResult: true,
+		},
+		"ErrorCaseUserNotFound": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			externalID: "1234",
+			org:        "123",
+			policyName: "policy1",
+			wantError: &Error{
+				Code:    USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message: "Code: UserNotFound",
+			},
+			getUserByExternalIDMethodErr: &database.Error{
+				Code:    database.USER_NOT_FOUND,
+				Message: "Code: UserNotFound",
+			},
+		},
+		"ErrorCasePolicyIsNotAttached": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			externalID: "1234",
+			org:        "123",
+			policyName: "policy1",
+			wantError: &Error{
+				Code:    POLICY_IS_NOT_ATTACHED_TO_USER,
+				Message: "Policy with org 123 and name policy1 is not attached to user with externalId 1234",
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "1234",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getPolicyByNameResult: &Policy{
+				ID:   "test1",
+				Name: "policy1",
+				Org:  "123",
+				Path: "/path/",
+				Urn:  CreateUrn("123", RESOURCE_POLICY, "/path/", "policy1"),
+			},
+			isAttachedToUserResult: false,
+		},
+		"ErrorCaseIsAttachedDBErr": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			externalID: "1234",
+			org:        "123",
+			policyName: "policy1",
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "1234",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getPolicyByNameResult: &Policy{
+				ID:   "test1",
+				Name: "policy1",
+				Org:  "123",
+				Path: "/path/",
+				Urn:  CreateUrn("123", RESOURCE_POLICY, "/path/", "policy1"),
+			},
+			isAttachedToUserMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseDetachPolicyDBErr": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			externalID: "1234",
+			org:        "123",
+			policyName: "policy1",
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			getUserByExternalIDResult: &User{
+				ID:         "543210",
+				ExternalID: "1234",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getPolicyByNameResult: &Policy{
+				ID:   "test1",
+				Name: "policy1",
+				Org:  "123",
+				Path: "/path/",
+				Urn:  CreateUrn("123", RESOURCE_POLICY, "/path/", "policy1"),
+			},
+			isAttachedToUserResult: true,
+			detachPolicyMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	for x, testcase := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetUserByExternalIDMethod][0] = testcase.getUserByExternalIDResult
+		testRepo.ArgsOut[GetUserByExternalIDMethod][1] = testcase.getUserByExternalIDMethodErr
+		testRepo.ArgsOut[GetPolicyByNameMethod][0] = testcase.getPolicyByNameResult
+		testRepo.ArgsOut[IsAttachedToUserMethod][0] = testcase.isAttachedToUserResult
+		testRepo.ArgsOut[IsAttachedToUserMethod][1] = testcase.isAttachedToUserMethodErr
+		testRepo.ArgsOut[DetachPolicyFromUserMethod][0] = testcase.detachPolicyMethodErr
+
+		err := testAPI.DetachPolicyToUser(testcase.requestInfo, testcase.externalID, testcase.org, testcase.policyName)
+		checkMethodResponse(t, x, testcase.wantError, err, nil, nil)
+	}
+}
+
+func TestAuthAPI_ListAttachedUserPolicies(t *testing.T) {
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// API Method args
+		requestInfo RequestInfo
+		filter      *Filter
+		// Expected result
+		expectedResponse []UserPolicies
+		totalResult      int
+		wantError        error
+		// Manager Results
+		getUserByExternalIDMethodResult *User
+		getPoliciesByUserIDMethodResult []TestPolicyUserRelation
+		// Manager Errors
+		getUserByExternalIDMethodErr error
+		getPoliciesByUserIDMethodErr error
+	}{
+		"OKCaseAdmin": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				ExternalID: "1234",
+				Limit:      20,
+			},
+			expectedResponse: []UserPolicies{
+				{
+					Org:      "123",
+					Policy:   "policy1",
+					CreateAt: now,
+				},
+			},
+			totalResult: 1,
+			getUserByExternalIDMethodResult: &User{
+				ID:         "543210",
+				ExternalID: "1234",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getPoliciesByUserIDMethodResult: []TestPolicyUserRelation{
+				{
+					Policy: &Policy{
+						ID:   "test1",
+						Name: "policy1",
+						Org:  "123",
+						Path: "/path/",
+						Urn:  CreateUrn("123", RESOURCE_POLICY, "/path/", "policy1"),
+					},
+					CreateAt: now,
+				},
+			},
+		},
+		"ErrorCaseInvalidFilter": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				ExternalID: "*%~#@|",
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: externalID *%~#@|",
+			},
+		},
+		"ErrorCaseUserNotFound": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				ExternalID: "1234",
+				Limit:      20,
+			},
+			wantError: &Error{
+				Code:    USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message: "Code: UserNotFound",
+			},
+			getUserByExternalIDMethodErr: &database.Error{
+				Code:    database.USER_NOT_FOUND,
+				Message: "Code: UserNotFound",
+			},
+		},
+		"ErrorCaseUnauthorized": {
+			requestInfo: RequestInfo{
+				Identifier: "1234",
+				Admin:      false,
+			},
+			filter: &Filter{
+				ExternalID: "1234",
+				Limit:      20,
+			},
+			wantError: &Error{
+				Code:    UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "User with externalId 1234 is not allowed to access to resource urn:iws:iam::user/path/1234",
+			},
+			getUserByExternalIDMethodResult: &User{
+				ID:         "543210",
+				ExternalID: "1234",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getPoliciesByUserIDMethodResult: []TestPolicyUserRelation{
+				{
+					Policy: &Policy{
+						ID:   "POLICY-USER-ID",
+						Name: "policyUser",
+						Org:  "123",
+						Path: "/path/",
+						Urn:  CreateUrn("123", RESOURCE_POLICY, "/path/", "policyUser"),
+						Statements: &[]Statement{
+							{
+								Effect: "allow",
+								Actions: []string{
+									USER_ACTION_GET_USER,
+								},
+								Resources: []string{
+									GetUrnPrefix("", RESOURCE_USER, "/path/"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"ErrorCaseGetPoliciesDBErr": {
+			requestInfo: RequestInfo{
+				Identifier: "123456",
+				Admin:      true,
+			},
+			filter: &Filter{
+				ExternalID: "1234",
+				Limit:      20,
+			},
+			wantError: &Error{
+				Code:    UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			getUserByExternalIDMethodResult: &User{
+				ID:         "543210",
+				ExternalID: "1234",
+				Path:       "/path/",
+				Urn:        CreateUrn("", RESOURCE_USER, "/path/", "1234"),
+			},
+			getPoliciesByUserIDMethodErr: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	for x, testcase := range testcases {
+		testRepo := makeTestRepo()
+		testAPI := makeTestAPI(testRepo)
+
+		testRepo.ArgsOut[GetUserByExternalIDMethod][0] = testcase.getUserByExternalIDMethodResult
+		testRepo.ArgsOut[GetUserByExternalIDMethod][1] = testcase.getUserByExternalIDMethodErr
+		testRepo.ArgsOut[GetAttachedPoliciesByUserIDMethod][0] = testcase.getPoliciesByUserIDMethodResult
+		testRepo.ArgsOut[GetAttachedPoliciesByUserIDMethod][1] = testcase.totalResult
+		testRepo.ArgsOut[GetAttachedPoliciesByUserIDMethod][2] = testcase.getPoliciesByUserIDMethodErr
+		policies, total, err := testAPI.ListAttachedUserPolicies(testcase.requestInfo, testcase.filter)
+		checkMethodResponse(t, x, testcase.wantError, err, testcase.expectedResponse, policies)
+		assert.Equal(t, testcase.totalResult, total, "Error in test case %v", x)
+	}
+}
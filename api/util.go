@@ -2,8 +2,10 @@ package api
 
 import (
 	"fmt"
+	"net"
 	"regexp"
 	"strings"
+	"time"
 )
 
 const (
@@ -17,6 +19,7 @@ const (
 	// Resource validation
 	RESOURCE_EXTERNAL = "external"
 	RESOURCE_IAM      = "iam"
+	RESOURCE_URN      = "urn"
 
 	// Constraints
 	MAX_EXTERNAL_ID_LENGTH = 128
@@ -24,18 +27,24 @@ const (
 	MAX_ACTION_LENGTH      = 128
 	MAX_PATH_LENGTH        = 512
 	MAX_RESOURCE_NUMBER    = 50
+	MAX_AUTHZ_ITEM_NUMBER  = 50
 	MAX_LIMIT_SIZE         = 1000
 	DEFAULT_LIMIT_SIZE     = 20
 
 	// Actions
 
 	// User actions
-	USER_ACTION_CREATE_USER          = "iam:CreateUser"
-	USER_ACTION_DELETE_USER          = "iam:DeleteUser"
-	USER_ACTION_GET_USER             = "iam:GetUser"
-	USER_ACTION_LIST_USERS           = "iam:ListUsers"
-	USER_ACTION_UPDATE_USER          = "iam:UpdateUser"
-	USER_ACTION_LIST_GROUPS_FOR_USER = "iam:ListGroupsForUser"
+	USER_ACTION_CREATE_USER                 = "iam:CreateUser"
+	USER_ACTION_DELETE_USER                 = "iam:DeleteUser"
+	USER_ACTION_GET_USER                    = "iam:GetUser"
+	USER_ACTION_LIST_USERS                  = "iam:ListUsers"
+	USER_ACTION_UPDATE_USER                 = "iam:UpdateUser"
+	USER_ACTION_LIST_GROUPS_FOR_USER        = "iam:ListGroupsForUser"
+	USER_ACTION_SIMULATE_AUTHZ              = "iam:SimulateAuthorization"
+	USER_ACTION_LIST_EFFECTIVE_PERMS        = "iam:ListEffectivePermissions"
+	USER_ACTION_ATTACH_USER_POLICY          = "iam:AttachUserPolicy"
+	USER_ACTION_DETACH_USER_POLICY          = "iam:DetachUserPolicy"
+	USER_ACTION_LIST_ATTACHED_USER_POLICIES = "iam:ListAttachedUserPolicies"
 
 	// Group actions
 	GROUP_ACTION_CREATE_GROUP                 = "iam:CreateGroup"
@@ -49,6 +58,9 @@ const (
 	GROUP_ACTION_ATTACH_GROUP_POLICY          = "iam:AttachGroupPolicy"
 	GROUP_ACTION_DETACH_GROUP_POLICY          = "iam:DetachGroupPolicy"
 	GROUP_ACTION_LIST_ATTACHED_GROUP_POLICIES = "iam:ListAttachedGroupPolicies"
+	GROUP_ACTION_ADD_SUBGROUP                 = "iam:AddSubgroup"
+	GROUP_ACTION_REMOVE_SUBGROUP              = "iam:RemoveSubgroup"
+	GROUP_ACTION_LIST_SUBGROUPS               = "iam:ListSubgroups"
 
 	// Policy actions
 	POLICY_ACTION_CREATE_POLICY        = "iam:CreatePolicy"
@@ -57,6 +69,7 @@ const (
 	POLICY_ACTION_GET_POLICY           = "iam:GetPolicy"
 	POLICY_ACTION_LIST_ATTACHED_GROUPS = "iam:ListAttachedGroups"
 	POLICY_ACTION_LIST_POLICIES        = "iam:ListPolicies"
+	POLICY_ACTION_LIST_POLICY_VERSIONS = "iam:ListPolicyVersions"
 
 	// Proxy resource actions
 	PROXY_ACTION_CREATE_RESOURCE    = "iam:CreateProxyResource"
@@ -71,6 +84,35 @@ const (
 	AUTH_OIDC_ACTION_UPDATE_PROVIDER = "auth:UpdateOidcProvider"
 	AUTH_OIDC_ACTION_LIST_PROVIDERS  = "auth:ListOidcProviders"
 	AUTH_OIDC_ACTION_GET_PROVIDER    = "auth:GetOidcProvider"
+
+	// Audit actions
+	AUDIT_ACTION_LIST_EVENTS = "iam:ListAuditEvents"
+
+	// Authorization decisions
+	DECISION_ALLOWED       = "allowed"
+	DECISION_DENIED        = "denied"
+	DECISION_IMPLICIT_DENY = "implicitDeny"
+
+	// Condition operators
+	CONDITION_STRING_EQUALS     = "StringEquals"
+	CONDITION_STRING_NOT_EQUALS = "StringNotEquals"
+	CONDITION_IP_ADDRESS        = "IpAddress"
+	CONDITION_NOT_IP_ADDRESS    = "NotIpAddress"
+	CONDITION_DATE_LESS_THAN    = "DateLessThan"
+	CONDITION_DATE_GREATER_THAN = "DateGreaterThan"
+
+	// Condition keys
+	CONDITION_KEY_SOURCE_IP      = "foulkon:SourceIp"
+	CONDITION_KEY_CURRENT_TIME   = "foulkon:CurrentTime"
+	CONDITION_KEY_REQUEST_PREFIX = "request:"
+
+	// Time of day format allowed in date conditions
+	CONDITION_TIME_OF_DAY_FORMAT = "15:04"
+
+	// Policy variables
+	POLICY_VARIABLE_USER_EXTERNAL_ID = "${user.externalId}"
+	POLICY_VARIABLE_USER_PATH        = "${user.path}"
+	POLICY_VARIABLE_USER_URN         = "${user.urn}"
 )
 
 var (
@@ -89,6 +131,14 @@ var (
 	rPathResource, _       = regexp.Compile(`^/$|^(/([\w*_-]+|:[\w_-]+))+$`)
 	rHost, _               = regexp.Compile(`^https?:/{2}[\w+\/\-_.]+(:\d{1,5})?$`)
 	rUrnProxy, _           = regexp.Compile(`^\*$|^[\w+\-@.]+\*?$|^[\w+\-@.]+\*?$|^([\w+\-@.]|\{\w+\})+(/?(([\w+\-@.]|\{\w+\})+/)*([\w+\-@.]|\{\w+\})+)?$`)
+	rConditionKey, _       = regexp.Compile(`^request:[\w\-_.]+$`)
+
+	// Sample values used to validate resources with policy variables
+	policyVariablesValidator = strings.NewReplacer(
+		POLICY_VARIABLE_USER_EXTERNAL_ID, "externalId",
+		POLICY_VARIABLE_USER_PATH, "/",
+		POLICY_VARIABLE_USER_URN, CreateUrn("", RESOURCE_USER, "/", "externalId"),
+	)
 )
 
 func CreateUrn(org string, resource string, path string, name string) string {
@@ -150,6 +200,11 @@ func IsValidProxyResource(resource *ResourceEntity) error {
 		return errFunc("host", resource.Host)
 	}
 
+	return IsValidResourceRoute(resource)
+}
+
+// IsValidResourceRoute checks the resource fields used to authorize requests, so its host isn't needed
+func IsValidResourceRoute(resource *ResourceEntity) error {
 	if !rPathResource.MatchString(resource.Path) {
 		return errFunc("path_resource", resource.Path)
 	}
@@ -187,7 +242,12 @@ func AreValidActions(actions []string) error {
 func AreValidResources(resources []string, resourceType string) error {
 	for _, resource := range resources {
 		err := errFunc("urn", resource)
-		blocks := strings.Split(resource, ":")
+		// Policy variables are only allowed in statement resources
+		urn := resource
+		if resourceType == RESOURCE_IAM {
+			urn = policyVariablesValidator.Replace(resource)
+		}
+		blocks := strings.Split(urn, ":")
 		for n, block := range blocks {
 			switch n {
 			case 0:
@@ -260,28 +320,91 @@ func AreValidStatements(statements *[]Statement) error {
 		}
 
 		// check actions
-		if len(statement.Actions) < 1 {
+		if len(statement.Actions) < 1 && len(statement.NotActions) < 1 {
 			return &Error{
 				Code:    INVALID_PARAMETER_ERROR,
 				Message: "Empty actions",
 			}
 		}
+		if len(statement.Actions) > 0 && len(statement.NotActions) > 0 {
+			return &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Actions and notActions can't be used in the same statement",
+			}
+		}
 		err = AreValidActions(statement.Actions)
 		if err != nil {
 			return err
 		}
+		err = AreValidActions(statement.NotActions)
+		if err != nil {
+			return err
+		}
 
 		// check resources
-		if len(statement.Resources) < 1 {
+		if len(statement.Resources) < 1 && len(statement.NotResources) < 1 {
 			return &Error{
 				Code:    INVALID_PARAMETER_ERROR,
 				Message: "Empty resources",
 			}
 		}
+		if len(statement.Resources) > 0 && len(statement.NotResources) > 0 {
+			return &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Resources and notResources can't be used in the same statement",
+			}
+		}
 		err = AreValidResources(statement.Resources, RESOURCE_IAM)
 		if err != nil {
 			return err
 		}
+		err = AreValidResources(statement.NotResources, RESOURCE_IAM)
+		if err != nil {
+			return err
+		}
+
+		// check conditions
+		err = AreValidConditions(statement.Conditions)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func AreValidConditions(conditions Conditions) error {
+	for operator, keys := range conditions {
+		switch operator {
+		case CONDITION_STRING_EQUALS, CONDITION_STRING_NOT_EQUALS, CONDITION_IP_ADDRESS, CONDITION_NOT_IP_ADDRESS,
+			CONDITION_DATE_LESS_THAN, CONDITION_DATE_GREATER_THAN:
+		default:
+			return &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: fmt.Sprintf("Invalid condition operator: %v", operator),
+			}
+		}
+		if len(keys) < 1 {
+			return &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: fmt.Sprintf("Empty keys in condition operator %v", operator),
+			}
+		}
+		for key, values := range keys {
+			if key != CONDITION_KEY_SOURCE_IP && key != CONDITION_KEY_CURRENT_TIME && !rConditionKey.MatchString(key) {
+				return errFunc("condition key", key)
+			}
+			if len(values) < 1 {
+				return &Error{
+					Code:    INVALID_PARAMETER_ERROR,
+					Message: fmt.Sprintf("Empty values in condition key %v", key),
+				}
+			}
+			for _, value := range values {
+				if !isValidConditionValue(operator, value) {
+					return errFunc("condition value", value)
+				}
+			}
+		}
 	}
 	return nil
 }
@@ -298,6 +421,18 @@ func AreValidOidcClientNames(oidcClients []string) error {
 	return nil
 }
 
+// Return the bounds of the page requested in filter for a list of total elements
+func getPageBounds(filter *Filter, total int) (int, int) {
+	if filter.Offset >= total {
+		return total, total
+	}
+	end := filter.Offset + filter.Limit
+	if end > total {
+		end = total
+	}
+	return filter.Offset, end
+}
+
 func validateFilter(filter *Filter, validColumns []string) error {
 	if len(filter.Org) > 0 && !IsValidOrg(filter.Org) {
 		return &Error{
@@ -336,6 +471,34 @@ func validateFilter(filter *Filter, validColumns []string) error {
 		}
 	}
 
+	if len(filter.ActionPrefix) > 0 && AreValidActions([]string{filter.ActionPrefix + "*"}) != nil {
+		return &Error{
+			Code:    INVALID_PARAMETER_ERROR,
+			Message: fmt.Sprintf("Invalid parameter: actionPrefix %v", filter.ActionPrefix),
+		}
+	}
+
+	if len(filter.Actor) > 0 && !IsValidUserExternalID(filter.Actor) {
+		return &Error{
+			Code:    INVALID_PARAMETER_ERROR,
+			Message: fmt.Sprintf("Invalid parameter: actor %v", filter.Actor),
+		}
+	}
+
+	if len(filter.UrnPrefix) > 0 && AreValidResources([]string{filter.UrnPrefix + "*"}, RESOURCE_EXTERNAL) != nil {
+		return &Error{
+			Code:    INVALID_PARAMETER_ERROR,
+			Message: fmt.Sprintf("Invalid parameter: urnPrefix %v", filter.UrnPrefix),
+		}
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return &Error{
+			Code:    INVALID_PARAMETER_ERROR,
+			Message: fmt.Sprintf("Invalid parameter: from %v is after to %v", filter.From.Format(time.RFC3339), filter.To.Format(time.RFC3339)),
+		}
+	}
+
 	if filter.Limit == 0 {
 		filter.Limit = DEFAULT_LIMIT_SIZE
 	} else if filter.Limit > MAX_LIMIT_SIZE {
@@ -374,6 +537,22 @@ func validateFilter(filter *Filter, validColumns []string) error {
 
 // Private Methods
 
+// isValidConditionValue checks that value has the right format for the condition operator
+func isValidConditionValue(operator string, value string) bool {
+	switch operator {
+	case CONDITION_IP_ADDRESS, CONDITION_NOT_IP_ADDRESS:
+		if _, _, err := net.ParseCIDR(value); err == nil {
+			return true
+		}
+		return net.ParseIP(value) != nil
+	case CONDITION_DATE_LESS_THAN, CONDITION_DATE_GREATER_THAN:
+		_, ok := compareDate(time.Time{}, value)
+		return ok
+	default:
+		return len(value) > 0 && len(value) < MAX_NAME_LENGTH
+	}
+}
+
 func errFunc(parameter string, value string) error {
 	return &Error{
 		Code:    REGEX_NO_MATCH,
@@ -439,6 +439,112 @@ func TestAreValidStatements(t *testing.T) {
 				Message: "Invalid parameter urn, value: urn:iws:iam::user/path/****",
 			},
 		},
+		"OKCaseNotActionsAndNotResources": {
+			Statements: &[]Statement{
+				{
+					Effect: "allow",
+					NotActions: []string{
+						USER_ACTION_DELETE_USER,
+					},
+					NotResources: []string{
+						GetUrnPrefix("", RESOURCE_USER, "/admin/"),
+					},
+				},
+			},
+		},
+		"ErrorCaseActionsAndNotActions": {
+			Statements: &[]Statement{
+				{
+					Effect: "allow",
+					Actions: []string{
+						USER_ACTION_GET_USER,
+					},
+					NotActions: []string{
+						USER_ACTION_DELETE_USER,
+					},
+					Resources: []string{
+						GetUrnPrefix("", RESOURCE_USER, "/path/"),
+					},
+				},
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Actions and notActions can't be used in the same statement",
+			},
+		},
+		"ErrorCaseResourcesAndNotResources": {
+			Statements: &[]Statement{
+				{
+					Effect: "allow",
+					Actions: []string{
+						USER_ACTION_GET_USER,
+					},
+					Resources: []string{
+						GetUrnPrefix("", RESOURCE_USER, "/path/"),
+					},
+					NotResources: []string{
+						GetUrnPrefix("", RESOURCE_USER, "/admin/"),
+					},
+				},
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Resources and notResources can't be used in the same statement",
+			},
+		},
+		"ErrorCaseEmptyActions": {
+			Statements: &[]Statement{
+				{
+					Effect: "allow",
+					Resources: []string{
+						GetUrnPrefix("", RESOURCE_USER, "/path/"),
+					},
+				},
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Empty actions",
+			},
+		},
+		"ErrorCaseInvalidNotResource": {
+			Statements: &[]Statement{
+				{
+					Effect: "allow",
+					Actions: []string{
+						USER_ACTION_GET_USER,
+					},
+					NotResources: []string{
+						GetUrnPrefix("", RESOURCE_USER, "/path/***"),
+					},
+				},
+			},
+			wantError: &Error{
+				Code:    REGEX_NO_MATCH,
+				Message: "Invalid parameter urn, value: urn:iws:iam::user/path/****",
+			},
+		},
+		"ErrorCaseInvalidCondition": {
+			Statements: &[]Statement{
+				{
+					Effect: "allow",
+					Actions: []string{
+						USER_ACTION_GET_USER,
+					},
+					Resources: []string{
+						GetUrnPrefix("", RESOURCE_USER, "/path/"),
+					},
+					Conditions: Conditions{
+						CONDITION_IP_ADDRESS: {
+							CONDITION_KEY_SOURCE_IP: {"10.0.0.300"},
+						},
+					},
+				},
+			},
+			wantError: &Error{
+				Code:    REGEX_NO_MATCH,
+				Message: "Invalid parameter condition value, value: 10.0.0.300",
+			},
+		},
 	}
 
 	for x, testcase := range testcases {
@@ -447,6 +553,75 @@ func TestAreValidStatements(t *testing.T) {
 	}
 }
 
+func TestAreValidConditions(t *testing.T) {
+	testcases := map[string]struct {
+		// Method args
+		conditions Conditions
+		// Expected results
+		wantError error
+	}{
+		"OKCaseEmptyConditions": {},
+		"OKCase": {
+			conditions: Conditions{
+				CONDITION_STRING_EQUALS:     {"request:env": {"dev", "prod"}},
+				CONDITION_IP_ADDRESS:        {CONDITION_KEY_SOURCE_IP: {"10.0.0.0/8", "192.168.1.1"}},
+				CONDITION_DATE_LESS_THAN:    {CONDITION_KEY_CURRENT_TIME: {"2016-07-01T00:00:00Z"}},
+				CONDITION_DATE_GREATER_THAN: {CONDITION_KEY_CURRENT_TIME: {"08:00"}},
+			},
+		},
+		"ErrorCaseInvalidOperator": {
+			conditions: Conditions{
+				"StringLike": {"request:env": {"dev"}},
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid condition operator: StringLike",
+			},
+		},
+		"ErrorCaseEmptyKeys": {
+			conditions: Conditions{
+				CONDITION_STRING_EQUALS: {},
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Empty keys in condition operator StringEquals",
+			},
+		},
+		"ErrorCaseInvalidKey": {
+			conditions: Conditions{
+				CONDITION_STRING_EQUALS: {"foulkon:Other": {"dev"}},
+			},
+			wantError: &Error{
+				Code:    REGEX_NO_MATCH,
+				Message: "Invalid parameter condition key, value: foulkon:Other",
+			},
+		},
+		"ErrorCaseEmptyValues": {
+			conditions: Conditions{
+				CONDITION_STRING_EQUALS: {"request:env": {}},
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Empty values in condition key request:env",
+			},
+		},
+		"ErrorCaseInvalidDate": {
+			conditions: Conditions{
+				CONDITION_DATE_LESS_THAN: {CONDITION_KEY_CURRENT_TIME: {"2016-07-01"}},
+			},
+			wantError: &Error{
+				Code:    REGEX_NO_MATCH,
+				Message: "Invalid parameter condition value, value: 2016-07-01",
+			},
+		},
+	}
+
+	for x, testcase := range testcases {
+		err := AreValidConditions(testcase.conditions)
+		checkMethodResponse(t, x, testcase.wantError, err, nil, nil)
+	}
+}
+
 func TestAreValidResources(t *testing.T) {
 	testcases := map[string]struct {
 		// Method args
@@ -591,6 +766,51 @@ func TestAreValidResources(t *testing.T) {
 				Message: "Invalid parameter urn, value: urn:iws:iam:org1:fail**!^_#",
 			},
 		},
+		"OKCasePolicyVariables": {
+			Resources: []string{
+				"urn:ews:product:instance:resource/${user.externalId}/*",
+				"urn:iws:iam::user${user.path}*",
+				"${user.urn}",
+			},
+			resourceType: RESOURCE_IAM,
+		},
+		"ErrorCasePolicyVariablesExternal": {
+			Resources: []string{
+				"urn:ews:product:instance:resource/${user.externalId}",
+			},
+			resourceType: RESOURCE_EXTERNAL,
+			wantError: &Error{
+				Code:    REGEX_NO_MATCH,
+				Message: "Invalid parameter urn, value: urn:ews:product:instance:resource/${user.externalId}",
+			},
+		},
+		"OKCaseUrn": {
+			Resources: []string{
+				"urn:ews:product:instance:resource/path/*",
+				"urn:iws:iam:org1:user/path/user1",
+			},
+			resourceType: RESOURCE_URN,
+		},
+		"ErrorCasePolicyVariablesUrn": {
+			Resources: []string{
+				"urn:iws:iam::user${user.path}*",
+			},
+			resourceType: RESOURCE_URN,
+			wantError: &Error{
+				Code:    REGEX_NO_MATCH,
+				Message: "Invalid parameter urn, value: urn:iws:iam::user${user.path}*",
+			},
+		},
+		"ErrorCaseUnknownPolicyVariable": {
+			Resources: []string{
+				"urn:ews:product:instance:resource/${user.name}",
+			},
+			resourceType: RESOURCE_IAM,
+			wantError: &Error{
+				Code:    REGEX_NO_MATCH,
+				Message: "Invalid parameter urn, value: urn:ews:product:instance:resource/${user.name}",
+			},
+		},
 		"ErrorCaseBadResource": {
 			Resources: []string{
 				"urn:iws:iam:org1:fail:fail:fail",
@@ -658,6 +878,17 @@ func TestValidateFilter(t *testing.T) {
 				Message: "Invalid parameter: pathPrefix fail",
 			},
 		},
+		"ErrorCaseInvalidActionPrefix": {
+			filter: &Filter{
+				ExternalID:   "123",
+				Limit:        10,
+				ActionPrefix: "iam:Get?",
+			},
+			wantError: &Error{
+				Code:    INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: actionPrefix iam:Get?",
+			},
+		},
 		"ErrorCaseInvalidLimit": {
 			filter: &Filter{
 				ExternalID: "123",
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/Tecsisa/foulkon/api"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+)
+
+// AUDIT METHODS
+
+// ListAuditEvents returns a page of audit events matching filter, and their total number
+func (c *Client) ListAuditEvents(filter *api.Filter) ([]api.AuditEvent, int, error) {
+	response := &internalhttp.ListAuditEventsResponse{}
+	if err := c.do(http.MethodGet, internalhttp.AUDIT_ROOT_URL, filterQuery(filter), nil, response); err != nil {
+		return nil, 0, err
+	}
+	return response.Events, response.Total, nil
+}
+
+// ListAuditEventsPages calls fn with each page of audit events matching filter, until every event is retrieved or
+// fn returns false
+func (c *Client) ListAuditEventsPages(filter *api.Filter, fn func(events []api.AuditEvent) bool) error {
+	return pages(filter, func(f *api.Filter) (int, int, bool, error) {
+		events, total, err := c.ListAuditEvents(f)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return len(events), total, fn(events), nil
+	})
+}
+
+// BATCH METHODS
+
+// ExecuteBatch runs operations in a single transaction, so either every operation is applied or none of them
+func (c *Client) ExecuteBatch(operations []api.BatchOperation) ([]api.BatchOperationResult, error) {
+	request := internalhttp.BatchRequest{
+		Operations: operations,
+	}
+	response := &internalhttp.BatchResponse{}
+	if err := c.do(http.MethodPost, internalhttp.BATCH_URL, nil, request, response); err != nil {
+		return nil, err
+	}
+	return response.Results, nil
+}
+
+// ADMIN METHODS
+
+// GetCurrentConfig returns worker configuration
+func (c *Client) GetCurrentConfig() (*internalhttp.Config, error) {
+	config := &internalhttp.Config{}
+	if err := c.do(http.MethodGet, internalhttp.ABOUT, nil, nil, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// GetAuthzCacheStats returns the statistics of worker authorization cache
+func (c *Client) GetAuthzCacheStats() (*api.AuthzCacheStats, error) {
+	stats := &api.AuthzCacheStats{}
+	if err := c.do(http.MethodGet, internalhttp.AUTHZ_CACHE_URL, nil, nil, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// FlushAuthzCache removes every entry of worker authorization cache
+func (c *Client) FlushAuthzCache() error {
+	return c.do(http.MethodDelete, internalhttp.AUTHZ_CACHE_URL, nil, nil, nil)
+}
+
+// GetAuthzSnapshot returns every user, group and policy with their relations, used to authorize requests locally
+func (c *Client) GetAuthzSnapshot() (*api.AuthzSnapshot, error) {
+	snapshot := &api.AuthzSnapshot{}
+	if err := c.do(http.MethodGet, internalhttp.AUTHZ_SNAPSHOT_URL, nil, nil, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
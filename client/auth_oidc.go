@@ -0,0 +1,73 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/Tecsisa/foulkon/api"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+)
+
+// OIDC PROVIDER METHODS
+
+func (c *Client) AddOidcProvider(name string, path string, issuerURL string, oidcClients []string) (*api.OidcProvider, error) {
+	request := internalhttp.CreateOidcProviderRequest{
+		Name:        name,
+		Path:        path,
+		IssuerURL:   issuerURL,
+		OidcClients: oidcClients,
+	}
+	oidcProvider := &api.OidcProvider{}
+	if err := c.do(http.MethodPost, internalhttp.OIDC_AUTH_ROOT_URL, nil, request, oidcProvider); err != nil {
+		return nil, err
+	}
+	return oidcProvider, nil
+}
+
+func (c *Client) GetOidcProviderByName(name string) (*api.OidcProvider, error) {
+	oidcProvider := &api.OidcProvider{}
+	if err := c.do(http.MethodGet, urlPath(internalhttp.OIDC_AUTH_ID_URL, name), nil, nil, oidcProvider); err != nil {
+		return nil, err
+	}
+	return oidcProvider, nil
+}
+
+// ListOidcProviders returns the names of a page of OIDC providers, and the total number of OIDC providers matching
+// filter
+func (c *Client) ListOidcProviders(filter *api.Filter) ([]string, int, error) {
+	response := &internalhttp.ListOidcProvidersResponse{}
+	if err := c.do(http.MethodGet, internalhttp.OIDC_AUTH_ROOT_URL, filterQuery(filter), nil, response); err != nil {
+		return nil, 0, err
+	}
+	return response.Providers, response.Total, nil
+}
+
+// ListOidcProvidersPages calls fn with each page of OIDC providers matching filter, until every OIDC provider is
+// retrieved or fn returns false
+func (c *Client) ListOidcProvidersPages(filter *api.Filter, fn func(names []string) bool) error {
+	return pages(filter, func(f *api.Filter) (int, int, bool, error) {
+		names, total, err := c.ListOidcProviders(f)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return len(names), total, fn(names), nil
+	})
+}
+
+func (c *Client) UpdateOidcProvider(oidcProviderName string, newName string, newPath string, newIssuerUrl string,
+	newClients []string) (*api.OidcProvider, error) {
+	request := internalhttp.UpdateOidcProviderRequest{
+		Name:        newName,
+		Path:        newPath,
+		IssuerURL:   newIssuerUrl,
+		OidcClients: newClients,
+	}
+	oidcProvider := &api.OidcProvider{}
+	if err := c.do(http.MethodPut, urlPath(internalhttp.OIDC_AUTH_ID_URL, oidcProviderName), nil, request, oidcProvider); err != nil {
+		return nil, err
+	}
+	return oidcProvider, nil
+}
+
+func (c *Client) RemoveOidcProvider(name string) error {
+	return c.do(http.MethodDelete, urlPath(internalhttp.OIDC_AUTH_ID_URL, name), nil, nil, nil)
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/Tecsisa/foulkon/api"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+)
+
+// AUTHORIZATION METHODS
+
+// GetAuthorizedExternalResources returns the resources that authenticated user is allowed to access with action
+func (c *Client) GetAuthorizedExternalResources(action string, resources []string) ([]string, error) {
+	request := internalhttp.AuthorizeResourcesRequest{
+		Action:    action,
+		Resources: resources,
+		Context:   c.authzContext,
+	}
+	response := &internalhttp.AuthorizeResourcesResponse{}
+	if err := c.do(http.MethodPost, internalhttp.RESOURCE_URL, nil, request, response); err != nil {
+		return nil, err
+	}
+	return response.ResourcesAllowed, nil
+}
+
+// GetAuthorizedExternalResourcesBatch returns the resources that authenticated user is allowed to access for
+// each item
+func (c *Client) GetAuthorizedExternalResourcesBatch(items []api.AuthorizeItem) ([]api.AuthorizeItemResult, error) {
+	request := internalhttp.AuthorizeResourcesBatchRequest{
+		Items:   items,
+		Context: c.authzContext,
+	}
+	response := &internalhttp.AuthorizeResourcesBatchResponse{}
+	if err := c.do(http.MethodPost, internalhttp.RESOURCE_BATCH_URL, nil, request, response); err != nil {
+		return nil, err
+	}
+	return response.Items, nil
+}
+
+// ExplainAuthorizedExternalResources returns the decision of each resource for authenticated user and action,
+// with the statements that matched it
+func (c *Client) ExplainAuthorizedExternalResources(action string, resources []string) ([]api.ResourceExplanation, error) {
+	request := internalhttp.AuthorizeResourcesRequest{
+		Action:    action,
+		Resources: resources,
+		Context:   c.authzContext,
+		Explain:   true,
+	}
+	response := &internalhttp.AuthorizeResourcesResponse{}
+	if err := c.do(http.MethodPost, internalhttp.RESOURCE_URL, nil, request, response); err != nil {
+		return nil, err
+	}
+	return response.Explanations, nil
+}
+
+// SimulateAuthorizedExternalResources returns the decision of each resource for a user and action, adding
+// statements to the ones of the user, or using only statements if replace is true
+func (c *Client) SimulateAuthorizedExternalResources(externalID string, statements []api.Statement, replace bool,
+	action string, resources []string) ([]api.ResourceExplanation, error) {
+	request := internalhttp.SimulateAuthorizationRequest{
+		ExternalID: externalID,
+		Statements: statements,
+		Replace:    replace,
+		Action:     action,
+		Resources:  resources,
+		Context:    c.authzContext,
+	}
+	response := &internalhttp.SimulateAuthorizationResponse{}
+	if err := c.do(http.MethodPost, internalhttp.RESOURCE_SIMULATE_URL, nil, request, response); err != nil {
+		return nil, err
+	}
+	return response.Explanations, nil
+}
+
+// GetAuthorizedPrincipals returns the users and groups allowed to access resourceUrn with action
+func (c *Client) GetAuthorizedPrincipals(action string, resourceUrn string) (*api.AuthorizedPrincipals, error) {
+	query := url.Values{}
+	query.Set("Action", action)
+	query.Set("Urn", resourceUrn)
+	response := &internalhttp.GetAuthorizedPrincipalsResponse{}
+	if err := c.do(http.MethodGet, internalhttp.RESOURCE_PRINCIPALS_URL, query, nil, response); err != nil {
+		return nil, err
+	}
+	return &api.AuthorizedPrincipals{
+		Users:  response.Users,
+		Groups: response.Groups,
+	}, nil
+}
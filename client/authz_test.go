@@ -0,0 +1,92 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Tecsisa/foulkon/api"
+)
+
+func TestAuthorizationMethods(t *testing.T) {
+	testcases := map[string]methodTestCase{
+		"OkCaseGetAuthorizedExternalResources": {
+			call: func(c *Client) (interface{}, error) {
+				return c.WithAuthzContext(map[string]string{"ip": "127.0.0.1"}).
+					GetAuthorizedExternalResources("example:get", []string{"urn:ews:example:instance1:resource/res1"})
+			},
+			responses: []workerResponse{
+				{StatusCode: http.StatusOK, Body: `{"resourcesAllowed":["urn:ews:example:instance1:resource/res1"]}`},
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method:      http.MethodPost,
+					URI:         "/api/v1/resource",
+					ContentType: "application/json",
+					Body: `{"action":"example:get","resources":["urn:ews:example:instance1:resource/res1"],` +
+						`"context":{"ip":"127.0.0.1"}}`,
+				},
+			},
+			expectedResult: []string{"urn:ews:example:instance1:resource/res1"},
+		},
+		"OkCaseExplainAuthorizedExternalResources": {
+			call: func(c *Client) (interface{}, error) {
+				return c.ExplainAuthorizedExternalResources("example:get", []string{"urn:ews:example:instance1:resource/res1"})
+			},
+			responses: []workerResponse{
+				{StatusCode: http.StatusOK, Body: `{"explanations":[{"urn":"urn:ews:example:instance1:resource/res1","decision":"allow"}]}`},
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method:      http.MethodPost,
+					URI:         "/api/v1/resource",
+					ContentType: "application/json",
+					Body:        `{"action":"example:get","resources":["urn:ews:example:instance1:resource/res1"],"explain":true}`,
+				},
+			},
+			expectedResult: []api.ResourceExplanation{
+				{Urn: "urn:ews:example:instance1:resource/res1", Decision: "allow"},
+			},
+		},
+		"OkCaseGetAuthorizedPrincipals": {
+			call: func(c *Client) (interface{}, error) {
+				return c.GetAuthorizedPrincipals("example:get", "urn:ews:example:instance1:resource/res1")
+			},
+			responses: []workerResponse{
+				{StatusCode: http.StatusOK, Body: `{"users":[{"externalId":"user1"}]}`},
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method: http.MethodGet,
+					URI:    "/api/v1/resource/principals?Action=example%3Aget&Urn=urn%3Aews%3Aexample%3Ainstance1%3Aresource%2Fres1",
+				},
+			},
+			expectedResult: &api.AuthorizedPrincipals{
+				Users: []api.AuthorizedUser{{ExternalID: "user1"}},
+			},
+		},
+		"ErrorCaseUnauthorized": {
+			call: func(c *Client) (interface{}, error) {
+				return c.GetAuthorizedExternalResources("example:get", []string{"urn:ews:example:instance1:resource/res1"})
+			},
+			responses: []workerResponse{
+				{StatusCode: http.StatusUnauthorized, Body: "Authentication failed\n"},
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method:      http.MethodPost,
+					URI:         "/api/v1/resource",
+					ContentType: "application/json",
+					Body:        `{"action":"example:get","resources":["urn:ews:example:instance1:resource/res1"]}`,
+				},
+			},
+			expectedError: &AuthenticationError{&WorkerError{
+				StatusCode: http.StatusUnauthorized,
+				RequestID:  "worker-request-id",
+				Code:       api.AUTHENTICATION_API_ERROR,
+				Message:    "Authentication failed",
+			}},
+		},
+	}
+
+	testMethods(t, testcases)
+}
@@ -0,0 +1,261 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+	"github.com/Tecsisa/foulkon/middleware"
+)
+
+const (
+	// Default values of client config
+	DEFAULT_TIMEOUT    = 30 * time.Second
+	DEFAULT_RETRY_WAIT = 100 * time.Millisecond
+)
+
+// TYPE DEFINITIONS
+
+// Config of a worker client
+type Config struct {
+	// Worker URL, e.g. http://localhost:8000
+	URL string
+	// Admin credentials for basic authentication. They are used instead of Token if set
+	AdminUser     string
+	AdminPassword string
+	// Bearer token of authenticated user
+	Token string
+	// HTTP client to send requests. A client with DEFAULT_TIMEOUT is used if nil
+	HTTPClient *http.Client
+	// Number of times a request is retried when worker is unreachable or unavailable (502, 503 and 504
+	// status codes). Only idempotent requests are retried: GET, PUT, DELETE and authorization ones
+	Retries int
+	// Wait before first retry, doubled in each next one. DEFAULT_RETRY_WAIT is used if 0
+	RetryWait time.Duration
+}
+
+// Client calls worker API. Its methods mirror the ones of API interfaces, and they return the errors of worker as
+// *WorkerError or one of its typed errors, e.g. *NotFoundError
+type Client struct {
+	config     Config
+	httpClient *http.Client
+	// Headers sent in every request
+	requestID string
+	ifMatch   string
+	// Attributes to evaluate conditions in authorization requests
+	authzContext map[string]string
+}
+
+// JSONPatchOperation is an operation of a JSON Patch document (RFC 6902). Patch methods send a JSON Patch if patch
+// is a []JSONPatchOperation, or a JSON Merge Patch (RFC 7396) otherwise
+type JSONPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// NewClient returns a client configured with config
+func NewClient(config Config) *Client {
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DEFAULT_TIMEOUT}
+	}
+	if config.RetryWait <= 0 {
+		config.RetryWait = DEFAULT_RETRY_WAIT
+	}
+	return &Client{
+		config:     config,
+		httpClient: httpClient,
+	}
+}
+
+// WithRequestID returns a copy of the client that sends requestID in X-Request-Id header, e.g. to propagate
+// the request ID of an incoming request. Worker logs and audit events use it for the requests of the copy
+func (c *Client) WithRequestID(requestID string) *Client {
+	copy := *c
+	copy.requestID = requestID
+	return &copy
+}
+
+// WithIfMatch returns a copy of the client that sends etag in If-Match header, so updates and removals fail with
+// *PreconditionFailedError if the resource changed. ETag of a resource is api.ETag(resource.UpdateAt)
+func (c *Client) WithIfMatch(etag string) *Client {
+	copy := *c
+	copy.ifMatch = etag
+	return &copy
+}
+
+// WithAuthzContext returns a copy of the client that sends attributes to evaluate policy conditions in
+// authorization requests
+func (c *Client) WithAuthzContext(attributes map[string]string) *Client {
+	copy := *c
+	copy.authzContext = attributes
+	return &copy
+}
+
+// PRIVATE HELPER METHODS
+
+// Send a request to worker with the JSON encoded request body, if any, and decode the JSON response into
+// response, if any
+func (c *Client) do(method string, path string, query url.Values, request interface{}, response interface{}) error {
+	var body []byte
+	contentType := "application/json"
+	if request != nil {
+		var err error
+		if body, err = json.Marshal(request); err != nil {
+			return err
+		}
+		if method == http.MethodPatch {
+			contentType = internalhttp.MERGE_PATCH_CONTENT_TYPE
+			if _, ok := request.([]JSONPatchOperation); ok {
+				contentType = internalhttp.JSON_PATCH_CONTENT_TYPE
+			}
+		}
+	}
+
+	u := strings.TrimSuffix(c.config.URL, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	retryable := method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete ||
+		strings.HasPrefix(path, internalhttp.RESOURCE_URL)
+	wait := c.config.RetryWait
+	for retry := 0; ; retry++ {
+		req, err := http.NewRequest(method, u, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		if request != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if c.config.AdminUser != "" {
+			req.SetBasicAuth(c.config.AdminUser, c.config.AdminPassword)
+		} else if c.config.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.config.Token)
+		}
+		if c.requestID != "" {
+			req.Header.Set(middleware.REQUEST_ID_HEADER, c.requestID)
+		}
+		if c.ifMatch != "" {
+			req.Header.Set(internalhttp.IF_MATCH_HEADER, c.ifMatch)
+		}
+
+		res, err := c.httpClient.Do(req)
+		if retryable && retry < c.config.Retries && (err != nil || isUnavailable(res.StatusCode)) {
+			if err == nil {
+				io.Copy(ioutil.Discard, res.Body)
+				res.Body.Close()
+			}
+			time.Sleep(wait)
+			wait *= 2
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return decodeResponse(res, response)
+	}
+}
+
+// Decode a worker response into response, or return its error
+func decodeResponse(res *http.Response, response interface{}) error {
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return newError(res.StatusCode, res.Header.Get(middleware.REQUEST_ID_HEADER), data)
+	}
+	if response != nil && len(data) > 0 {
+		return json.Unmarshal(data, response)
+	}
+	return nil
+}
+
+func isUnavailable(statusCode int) bool {
+	return statusCode == http.StatusBadGateway || statusCode == http.StatusServiceUnavailable ||
+		statusCode == http.StatusGatewayTimeout
+}
+
+// Fill the params of a worker URL, e.g. /api/v1/users/:userid, with the escaped values in order
+func urlPath(template string, params ...string) string {
+	segments := strings.Split(template, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") && len(params) > 0 {
+			segments[i] = url.PathEscape(params[0])
+			params = params[1:]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Return the query params of the pagination and filter fields of a list request
+func filterQuery(filter *api.Filter) url.Values {
+	query := url.Values{}
+	if filter == nil {
+		return query
+	}
+	if filter.Offset > 0 {
+		query.Set("Offset", strconv.Itoa(filter.Offset))
+	}
+	if filter.Limit > 0 {
+		query.Set("Limit", strconv.Itoa(filter.Limit))
+	}
+	values := map[string]string{
+		"OrderBy":      filter.OrderBy,
+		"PathPrefix":   filter.PathPrefix,
+		"ActionPrefix": filter.ActionPrefix,
+		"Actor":        filter.Actor,
+		"UrnPrefix":    filter.UrnPrefix,
+	}
+	if !filter.From.IsZero() {
+		values["From"] = filter.From.Format(time.RFC3339)
+	}
+	if !filter.To.IsZero() {
+		values["To"] = filter.To.Format(time.RFC3339)
+	}
+	for key, value := range values {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	return query
+}
+
+// Call list with the offsets of consecutive pages, starting at filter offset, until every item is retrieved or
+// list returns false. List returns the number of items of the page and the total number of items
+func pages(filter *api.Filter, list func(filter *api.Filter) (items int, total int, more bool, err error)) error {
+	f := &api.Filter{}
+	if filter != nil {
+		*f = *filter
+	}
+	for {
+		items, total, more, err := list(f)
+		if err != nil {
+			return err
+		}
+		f.Offset += items
+		if !more || items < 1 || f.Offset >= total {
+			return nil
+		}
+	}
+}
+
+// Return a filter copy, or an empty filter if nil
+func copyFilter(filter *api.Filter) api.Filter {
+	if filter == nil {
+		return api.Filter{}
+	}
+	return *filter
+}
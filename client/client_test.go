@@ -0,0 +1,382 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+// workerRequest is a request received by the test worker
+type workerRequest struct {
+	Method      string
+	URI         string
+	ContentType string
+	Auth        string
+	RequestID   string
+	IfMatch     string
+	Body        string
+}
+
+// workerResponse is a response of the test worker
+type workerResponse struct {
+	StatusCode int
+	Body       string
+}
+
+// Start a test worker that replies responses in order, repeating the last one, and records its requests
+func newTestWorker(responses []workerResponse) (*httptest.Server, *[]workerRequest) {
+	var requests []workerRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, workerRequest{
+			Method:      r.Method,
+			URI:         r.RequestURI,
+			ContentType: r.Header.Get("Content-Type"),
+			Auth:        r.Header.Get("Authorization"),
+			RequestID:   r.Header.Get(middleware.REQUEST_ID_HEADER),
+			IfMatch:     r.Header.Get("If-Match"),
+			Body:        string(body),
+		})
+		response := responses[len(responses)-1]
+		if len(requests) <= len(responses) {
+			response = responses[len(requests)-1]
+		}
+		w.Header().Set(middleware.REQUEST_ID_HEADER, "worker-request-id")
+		w.WriteHeader(response.StatusCode)
+		w.Write([]byte(response.Body))
+	}))
+	return server, &requests
+}
+
+func TestClient_Do(t *testing.T) {
+	testcases := map[string]struct {
+		// Client
+		config    Config
+		requestID string
+		ifMatch   string
+		// Method args
+		method  string
+		path    string
+		request interface{}
+		// Worker responses
+		responses []workerResponse
+		// Expected result
+		expectedRequests []workerRequest
+		expectedResponse map[string]string
+		expectedError    error
+	}{
+		"OkCaseAdminHeaders": {
+			config: Config{
+				AdminUser:     "admin",
+				AdminPassword: "admin",
+				Token:         "token",
+			},
+			requestID: "request-id",
+			ifMatch:   `"etag"`,
+			method:    http.MethodPut,
+			path:      "/api/v1/users/user1",
+			request:   map[string]string{"path": "/path/"},
+			responses: []workerResponse{
+				{StatusCode: http.StatusOK, Body: `{"path":"/path/"}`},
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method:      http.MethodPut,
+					URI:         "/api/v1/users/user1",
+					ContentType: "application/json",
+					Auth:        "Basic YWRtaW46YWRtaW4=",
+					RequestID:   "request-id",
+					IfMatch:     `"etag"`,
+					Body:        `{"path":"/path/"}`,
+				},
+			},
+			expectedResponse: map[string]string{"path": "/path/"},
+		},
+		"OkCaseTokenMergePatch": {
+			config: Config{
+				Token: "token",
+			},
+			method:  http.MethodPatch,
+			path:    "/api/v1/users/user1",
+			request: map[string]string{"path": "/path/"},
+			responses: []workerResponse{
+				{StatusCode: http.StatusOK, Body: `{"path":"/path/"}`},
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method:      http.MethodPatch,
+					URI:         "/api/v1/users/user1",
+					ContentType: "application/merge-patch+json",
+					Auth:        "Bearer token",
+					Body:        `{"path":"/path/"}`,
+				},
+			},
+			expectedResponse: map[string]string{"path": "/path/"},
+		},
+		"OkCaseJSONPatch": {
+			method:  http.MethodPatch,
+			path:    "/api/v1/users/user1",
+			request: []JSONPatchOperation{{Op: "replace", Path: "/path", Value: "/path/"}},
+			responses: []workerResponse{
+				{StatusCode: http.StatusOK, Body: `{"path":"/path/"}`},
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method:      http.MethodPatch,
+					URI:         "/api/v1/users/user1",
+					ContentType: "application/json-patch+json",
+					Body:        `[{"op":"replace","path":"/path","value":"/path/"}]`,
+				},
+			},
+			expectedResponse: map[string]string{"path": "/path/"},
+		},
+		"OkCaseRetryGet": {
+			config: Config{
+				Retries:   2,
+				RetryWait: time.Millisecond,
+			},
+			method: http.MethodGet,
+			path:   "/api/v1/users/user1",
+			responses: []workerResponse{
+				{StatusCode: http.StatusServiceUnavailable},
+				{StatusCode: http.StatusBadGateway},
+				{StatusCode: http.StatusOK, Body: `{"path":"/path/"}`},
+			},
+			expectedRequests: []workerRequest{
+				{Method: http.MethodGet, URI: "/api/v1/users/user1"},
+				{Method: http.MethodGet, URI: "/api/v1/users/user1"},
+				{Method: http.MethodGet, URI: "/api/v1/users/user1"},
+			},
+			expectedResponse: map[string]string{"path": "/path/"},
+		},
+		"OkCaseRetryAuthorization": {
+			config: Config{
+				Retries:   1,
+				RetryWait: time.Millisecond,
+			},
+			method:  http.MethodPost,
+			path:    "/api/v1/resource",
+			request: map[string]string{"action": "example:get"},
+			responses: []workerResponse{
+				{StatusCode: http.StatusGatewayTimeout},
+				{StatusCode: http.StatusOK, Body: `{"action":"example:get"}`},
+			},
+			expectedRequests: []workerRequest{
+				{Method: http.MethodPost, URI: "/api/v1/resource", ContentType: "application/json", Body: `{"action":"example:get"}`},
+				{Method: http.MethodPost, URI: "/api/v1/resource", ContentType: "application/json", Body: `{"action":"example:get"}`},
+			},
+			expectedResponse: map[string]string{"action": "example:get"},
+		},
+		"ErrorCaseRetriesExhausted": {
+			config: Config{
+				Retries:   1,
+				RetryWait: time.Millisecond,
+			},
+			method: http.MethodGet,
+			path:   "/api/v1/users/user1",
+			responses: []workerResponse{
+				{StatusCode: http.StatusServiceUnavailable},
+			},
+			expectedRequests: []workerRequest{
+				{Method: http.MethodGet, URI: "/api/v1/users/user1"},
+				{Method: http.MethodGet, URI: "/api/v1/users/user1"},
+			},
+			expectedError: &WorkerError{
+				StatusCode: http.StatusServiceUnavailable,
+				RequestID:  "worker-request-id",
+				Code:       api.UNKNOWN_API_ERROR,
+				Message:    "Service Unavailable",
+			},
+		},
+		"ErrorCasePostNotRetried": {
+			config: Config{
+				Retries:   2,
+				RetryWait: time.Millisecond,
+			},
+			method:  http.MethodPost,
+			path:    "/api/v1/users",
+			request: map[string]string{"externalId": "user1"},
+			responses: []workerResponse{
+				{StatusCode: http.StatusServiceUnavailable},
+			},
+			expectedRequests: []workerRequest{
+				{Method: http.MethodPost, URI: "/api/v1/users", ContentType: "application/json", Body: `{"externalId":"user1"}`},
+			},
+			expectedError: &WorkerError{
+				StatusCode: http.StatusServiceUnavailable,
+				RequestID:  "worker-request-id",
+				Code:       api.UNKNOWN_API_ERROR,
+				Message:    "Service Unavailable",
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		server, requests := newTestWorker(test.responses)
+		test.config.URL = server.URL
+		c := NewClient(test.config)
+		if test.requestID != "" {
+			c = c.WithRequestID(test.requestID)
+		}
+		if test.ifMatch != "" {
+			c = c.WithIfMatch(test.ifMatch)
+		}
+
+		response := map[string]string{}
+		err := c.do(test.method, test.path, nil, test.request, &response)
+		server.Close()
+
+		assert.Equal(t, test.expectedRequests, *requests, "Error in test case %v", n)
+		if test.expectedError != nil {
+			assert.Equal(t, test.expectedError, err, "Error in test case %v", n)
+			continue
+		}
+		assert.Nil(t, err, "Error in test case %v", n)
+		assert.Equal(t, test.expectedResponse, response, "Error in test case %v", n)
+	}
+}
+
+func TestUrlPath(t *testing.T) {
+	testcases := map[string]struct {
+		// Method args
+		template string
+		params   []string
+		// Expected result
+		expectedPath string
+	}{
+		"OkCaseParams": {
+			template:     "/api/v1/organizations/:orgname/groups/:groupname",
+			params:       []string{"org1", "group 1"},
+			expectedPath: "/api/v1/organizations/org1/groups/group%201",
+		},
+		"OkCaseNoParams": {
+			template:     "/api/v1/users",
+			expectedPath: "/api/v1/users",
+		},
+		"OkCaseMissingParams": {
+			template:     "/api/v1/users/:userid",
+			expectedPath: "/api/v1/users/:userid",
+		},
+	}
+
+	for n, test := range testcases {
+		assert.Equal(t, test.expectedPath, urlPath(test.template, test.params...), "Error in test case %v", n)
+	}
+}
+
+func TestFilterQuery(t *testing.T) {
+	testcases := map[string]struct {
+		// Method args
+		filter *api.Filter
+		// Expected result
+		expectedQuery string
+	}{
+		"OkCaseNilFilter": {
+			expectedQuery: "",
+		},
+		"OkCasePagination": {
+			filter: &api.Filter{
+				Offset:     10,
+				Limit:      5,
+				OrderBy:    "path-desc",
+				PathPrefix: "/example/",
+				Org:        "org1",
+			},
+			expectedQuery: "Limit=5&Offset=10&OrderBy=path-desc&PathPrefix=%2Fexample%2F",
+		},
+		"OkCaseAudit": {
+			filter: &api.Filter{
+				ActionPrefix: "iam:",
+				Actor:        "admin",
+				UrnPrefix:    "urn:iws:iam:",
+				From:         time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC),
+				To:           time.Date(2016, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+			expectedQuery: "ActionPrefix=iam%3A&Actor=admin&From=2016-01-01T00%3A00%3A00Z&To=2016-02-01T00%3A00%3A00Z&UrnPrefix=urn%3Aiws%3Aiam%3A",
+		},
+	}
+
+	for n, test := range testcases {
+		assert.Equal(t, test.expectedQuery, filterQuery(test.filter).Encode(), "Error in test case %v", n)
+	}
+}
+
+func TestPages(t *testing.T) {
+	testcases := map[string]struct {
+		// Method args
+		filter *api.Filter
+		total  int
+		stopAt int
+		// Expected result
+		expectedOffsets []int
+	}{
+		"OkCaseEveryPage": {
+			filter:          &api.Filter{Limit: 2},
+			total:           5,
+			expectedOffsets: []int{0, 2, 4},
+		},
+		"OkCaseFromOffset": {
+			filter:          &api.Filter{Offset: 3, Limit: 2},
+			total:           5,
+			expectedOffsets: []int{3},
+		},
+		"OkCaseStopped": {
+			filter:          &api.Filter{Limit: 1},
+			total:           5,
+			stopAt:          1,
+			expectedOffsets: []int{0, 1},
+		},
+		"OkCaseEmpty": {
+			total:           0,
+			expectedOffsets: []int{0},
+		},
+	}
+
+	for n, test := range testcases {
+		offsets := []int{}
+		err := pages(test.filter, func(f *api.Filter) (int, int, bool, error) {
+			offsets = append(offsets, f.Offset)
+			items := test.total - f.Offset
+			if f.Limit > 0 && items > f.Limit {
+				items = f.Limit
+			}
+			return items, test.total, test.stopAt == 0 || f.Offset < test.stopAt, nil
+		})
+		assert.Nil(t, err, "Error in test case %v", n)
+		assert.Equal(t, test.expectedOffsets, offsets, "Error in test case %v", n)
+	}
+}
+
+// methodTestCase is a test case of a client method, called with a client of the test worker
+type methodTestCase struct {
+	// Method call
+	call func(c *Client) (interface{}, error)
+	// Worker responses
+	responses []workerResponse
+	// Expected result
+	expectedRequests []workerRequest
+	expectedResult   interface{}
+	expectedError    error
+}
+
+func testMethods(t *testing.T, testcases map[string]methodTestCase) {
+	for n, test := range testcases {
+		server, requests := newTestWorker(test.responses)
+		result, err := test.call(NewClient(Config{URL: server.URL}))
+		server.Close()
+
+		assert.Equal(t, test.expectedRequests, *requests, "Error in test case %v", n)
+		if test.expectedError != nil {
+			assert.Equal(t, test.expectedError, err, "Error in test case %v", n)
+			continue
+		}
+		assert.Nil(t, err, "Error in test case %v", n)
+		assert.Equal(t, test.expectedResult, result, "Error in test case %v", n)
+	}
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Tecsisa/foulkon/api"
+)
+
+// WorkerError is an error returned by worker, with the api.Error code and message of its response
+type WorkerError struct {
+	StatusCode int
+	RequestID  string
+	Code       string
+	Message    string
+}
+
+func (e *WorkerError) Error() string {
+	return fmt.Sprintf("Code: %v, Message: %v, Status code: %v, Request ID: %v", e.Code, e.Message, e.StatusCode, e.RequestID)
+}
+
+// NotFoundError is returned when a resource or a relation between resources doesn't exist
+type NotFoundError struct {
+	*WorkerError
+}
+
+// ConflictError is returned when a resource or a relation between resources already exists, or a change
+// conflicts with other resources
+type ConflictError struct {
+	*WorkerError
+}
+
+// InvalidParameterError is returned when a request parameter isn't valid
+type InvalidParameterError struct {
+	*WorkerError
+}
+
+// AuthenticationError is returned when worker can't authenticate the client
+type AuthenticationError struct {
+	*WorkerError
+}
+
+// UnauthorizedError is returned when authenticated user isn't allowed to do the request
+type UnauthorizedError struct {
+	*WorkerError
+}
+
+// PreconditionFailedError is returned when a resource changed since its If-Match entity tag was retrieved
+type PreconditionFailedError struct {
+	*WorkerError
+}
+
+// PRIVATE HELPER METHODS
+
+// Return the typed error of a worker error response
+func newError(statusCode int, requestID string, data []byte) error {
+	err := &WorkerError{
+		StatusCode: statusCode,
+		RequestID:  requestID,
+	}
+	apiError := &api.Error{}
+	if json.Unmarshal(data, apiError) == nil && apiError.Code != "" {
+		err.Code = apiError.Code
+		err.Message = apiError.Message
+	} else {
+		// Authentication and unexpected errors don't have API errors
+		err.Code = api.UNKNOWN_API_ERROR
+		if statusCode == http.StatusUnauthorized {
+			err.Code = api.AUTHENTICATION_API_ERROR
+		}
+		err.Message = strings.TrimSpace(string(data))
+		if err.Message == "" {
+			err.Message = http.StatusText(statusCode)
+		}
+	}
+
+	switch err.Code {
+	case api.USER_BY_EXTERNAL_ID_NOT_FOUND, api.GROUP_BY_ORG_AND_NAME_NOT_FOUND,
+		api.USER_IS_NOT_A_MEMBER_OF_GROUP, api.POLICY_IS_NOT_ATTACHED_TO_GROUP,
+		api.GROUP_IS_NOT_A_MEMBER_OF_GROUP,
+		api.POLICY_IS_NOT_ATTACHED_TO_USER,
+		api.POLICY_BY_ORG_AND_NAME_NOT_FOUND, api.PROXY_RESOURCE_BY_ORG_AND_NAME_NOT_FOUND,
+		api.AUTH_OIDC_PROVIDER_BY_NAME_NOT_FOUND, api.POLICY_VERSION_NOT_FOUND:
+		return &NotFoundError{err}
+	case api.USER_ALREADY_EXIST, api.GROUP_ALREADY_EXIST,
+		api.USER_IS_ALREADY_A_MEMBER_OF_GROUP,
+		api.GROUP_IS_ALREADY_A_MEMBER_OF_GROUP, api.GROUP_HIERARCHY_CYCLE,
+		api.PROXY_RESOURCE_ALREADY_EXIST,
+		api.POLICY_IS_ALREADY_ATTACHED_TO_GROUP, api.POLICY_ALREADY_EXIST,
+		api.POLICY_IS_ALREADY_ATTACHED_TO_USER,
+		api.PROXY_RESOURCES_ROUTES_CONFLICT,
+		api.AUTH_OIDC_PROVIDER_ALREADY_EXIST:
+		return &ConflictError{err}
+	case api.INVALID_PARAMETER_ERROR, api.REGEX_NO_MATCH:
+		return &InvalidParameterError{err}
+	case api.AUTHENTICATION_API_ERROR:
+		return &AuthenticationError{err}
+	case api.UNAUTHORIZED_RESOURCES_ERROR:
+		return &UnauthorizedError{err}
+	case api.PRECONDITION_FAILED_ERROR:
+		return &PreconditionFailedError{err}
+	}
+	return err
+}
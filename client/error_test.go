@@ -0,0 +1,104 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewError(t *testing.T) {
+	testcases := map[string]struct {
+		// Method args
+		statusCode int
+		data       string
+		// Expected result
+		expectedError error
+	}{
+		"OkCaseNotFound": {
+			statusCode: http.StatusNotFound,
+			data:       `{"code":"UserWithExternalIDNotFound","message":"User not found"}`,
+			expectedError: &NotFoundError{&WorkerError{
+				StatusCode: http.StatusNotFound,
+				RequestID:  "request-id",
+				Code:       api.USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message:    "User not found",
+			}},
+		},
+		"OkCaseConflict": {
+			statusCode: http.StatusConflict,
+			data:       `{"code":"GroupHierarchyCycle","message":"Cycle"}`,
+			expectedError: &ConflictError{&WorkerError{
+				StatusCode: http.StatusConflict,
+				RequestID:  "request-id",
+				Code:       api.GROUP_HIERARCHY_CYCLE,
+				Message:    "Cycle",
+			}},
+		},
+		"OkCaseInvalidParameter": {
+			statusCode: http.StatusBadRequest,
+			data:       `{"code":"InvalidParameterError","message":"Invalid parameter"}`,
+			expectedError: &InvalidParameterError{&WorkerError{
+				StatusCode: http.StatusBadRequest,
+				RequestID:  "request-id",
+				Code:       api.INVALID_PARAMETER_ERROR,
+				Message:    "Invalid parameter",
+			}},
+		},
+		"OkCaseUnauthorized": {
+			statusCode: http.StatusForbidden,
+			data:       `{"code":"UnauthorizedResourcesError","message":"Unauthorized"}`,
+			expectedError: &UnauthorizedError{&WorkerError{
+				StatusCode: http.StatusForbidden,
+				RequestID:  "request-id",
+				Code:       api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message:    "Unauthorized",
+			}},
+		},
+		"OkCasePreconditionFailed": {
+			statusCode: http.StatusPreconditionFailed,
+			data:       `{"code":"PreconditionFailedError","message":"Changed"}`,
+			expectedError: &PreconditionFailedError{&WorkerError{
+				StatusCode: http.StatusPreconditionFailed,
+				RequestID:  "request-id",
+				Code:       api.PRECONDITION_FAILED_ERROR,
+				Message:    "Changed",
+			}},
+		},
+		"OkCaseAuthentication": {
+			statusCode: http.StatusUnauthorized,
+			data:       "Authentication failed\n",
+			expectedError: &AuthenticationError{&WorkerError{
+				StatusCode: http.StatusUnauthorized,
+				RequestID:  "request-id",
+				Code:       api.AUTHENTICATION_API_ERROR,
+				Message:    "Authentication failed",
+			}},
+		},
+		"OkCaseUnknown": {
+			statusCode: http.StatusInternalServerError,
+			data:       `{"code":"UnknownApiError","message":"Internal error"}`,
+			expectedError: &WorkerError{
+				StatusCode: http.StatusInternalServerError,
+				RequestID:  "request-id",
+				Code:       api.UNKNOWN_API_ERROR,
+				Message:    "Internal error",
+			},
+		},
+		"OkCaseEmptyBody": {
+			statusCode: http.StatusBadGateway,
+			expectedError: &WorkerError{
+				StatusCode: http.StatusBadGateway,
+				RequestID:  "request-id",
+				Code:       api.UNKNOWN_API_ERROR,
+				Message:    "Bad Gateway",
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		err := newError(test.statusCode, "request-id", []byte(test.data))
+		assert.Equal(t, test.expectedError, err, "Error in test case %v", n)
+	}
+}
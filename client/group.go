@@ -0,0 +1,174 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/Tecsisa/foulkon/api"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+)
+
+// GROUP METHODS
+
+func (c *Client) AddGroup(org string, name string, path string) (*api.Group, error) {
+	request := internalhttp.CreateGroupRequest{
+		Name: name,
+		Path: path,
+	}
+	group := &api.Group{}
+	if err := c.do(http.MethodPost, urlPath(internalhttp.GROUP_ORG_ROOT_URL, org), nil, request, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (c *Client) GetGroupByName(org string, name string) (*api.Group, error) {
+	group := &api.Group{}
+	if err := c.do(http.MethodGet, urlPath(internalhttp.GROUP_ID_URL, org, name), nil, nil, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// ListGroups returns a page of groups of organization filter.Org, or of every organization if it's empty, and the
+// total number of groups matching filter
+func (c *Client) ListGroups(filter *api.Filter) ([]api.GroupIdentity, int, error) {
+	query := filterQuery(filter)
+	if f := copyFilter(filter); f.Org != "" {
+		query.Set("Org", f.Org)
+	}
+	response := &internalhttp.ListAllGroupsResponse{}
+	if err := c.do(http.MethodGet, internalhttp.API_VERSION_1+"/groups", query, nil, response); err != nil {
+		return nil, 0, err
+	}
+	return response.Groups, response.Total, nil
+}
+
+// ListGroupsPages calls fn with each page of groups matching filter, until every group is retrieved or fn returns
+// false
+func (c *Client) ListGroupsPages(filter *api.Filter, fn func(groups []api.GroupIdentity) bool) error {
+	return pages(filter, func(f *api.Filter) (int, int, bool, error) {
+		groups, total, err := c.ListGroups(f)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return len(groups), total, fn(groups), nil
+	})
+}
+
+func (c *Client) UpdateGroup(org string, groupName string, newName string, newPath string) (*api.Group, error) {
+	request := internalhttp.UpdateGroupRequest{
+		Name: newName,
+		Path: newPath,
+	}
+	group := &api.Group{}
+	if err := c.do(http.MethodPut, urlPath(internalhttp.GROUP_ID_URL, org, groupName), nil, request, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// PatchGroup updates group fields with a JSON Merge Patch or a []JSONPatchOperation
+func (c *Client) PatchGroup(org string, groupName string, patch interface{}) (*api.Group, error) {
+	group := &api.Group{}
+	if err := c.do(http.MethodPatch, urlPath(internalhttp.GROUP_ID_URL, org, groupName), nil, patch, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (c *Client) RemoveGroup(org string, name string) error {
+	return c.do(http.MethodDelete, urlPath(internalhttp.GROUP_ID_URL, org, name), nil, nil, nil)
+}
+
+func (c *Client) AddMember(externalId string, groupName string, org string) error {
+	return c.do(http.MethodPost, urlPath(internalhttp.GROUP_ID_USERS_ID_URL, org, groupName, externalId), nil, nil, nil)
+}
+
+func (c *Client) RemoveMember(externalId string, groupName string, org string) error {
+	return c.do(http.MethodDelete, urlPath(internalhttp.GROUP_ID_USERS_ID_URL, org, groupName, externalId), nil, nil, nil)
+}
+
+// ListMembers returns a page of members of group filter.GroupName of organization filter.Org, including members
+// of its subgroups, and their total number
+func (c *Client) ListMembers(filter *api.Filter) ([]api.GroupMembers, int, error) {
+	f := copyFilter(filter)
+	response := &internalhttp.ListMembersResponse{}
+	if err := c.do(http.MethodGet, urlPath(internalhttp.GROUP_ID_USERS_URL, f.Org, f.GroupName), filterQuery(filter), nil, response); err != nil {
+		return nil, 0, err
+	}
+	return response.Members, response.Total, nil
+}
+
+// ListMembersPages calls fn with each page of members of group filter.GroupName of organization filter.Org,
+// until every member is retrieved or fn returns false
+func (c *Client) ListMembersPages(filter *api.Filter, fn func(members []api.GroupMembers) bool) error {
+	return pages(filter, func(f *api.Filter) (int, int, bool, error) {
+		members, total, err := c.ListMembers(f)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return len(members), total, fn(members), nil
+	})
+}
+
+func (c *Client) AttachPolicyToGroup(org string, groupName string, policyName string) error {
+	return c.do(http.MethodPost, urlPath(internalhttp.GROUP_ID_POLICIES_ID_URL, org, groupName, policyName), nil, nil, nil)
+}
+
+func (c *Client) DetachPolicyToGroup(org string, groupName string, policyName string) error {
+	return c.do(http.MethodDelete, urlPath(internalhttp.GROUP_ID_POLICIES_ID_URL, org, groupName, policyName), nil, nil, nil)
+}
+
+// ListAttachedGroupPolicies returns a page of policies attached to group filter.GroupName of organization
+// filter.Org, and their total number
+func (c *Client) ListAttachedGroupPolicies(filter *api.Filter) ([]api.GroupPolicies, int, error) {
+	f := copyFilter(filter)
+	response := &internalhttp.ListAttachedGroupPoliciesResponse{}
+	if err := c.do(http.MethodGet, urlPath(internalhttp.GROUP_ID_POLICIES_URL, f.Org, f.GroupName), filterQuery(filter), nil, response); err != nil {
+		return nil, 0, err
+	}
+	return response.AttachedPolicies, response.Total, nil
+}
+
+// ListAttachedGroupPoliciesPages calls fn with each page of policies attached to group filter.GroupName of
+// organization filter.Org, until every policy is retrieved or fn returns false
+func (c *Client) ListAttachedGroupPoliciesPages(filter *api.Filter, fn func(policies []api.GroupPolicies) bool) error {
+	return pages(filter, func(f *api.Filter) (int, int, bool, error) {
+		policies, total, err := c.ListAttachedGroupPolicies(f)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return len(policies), total, fn(policies), nil
+	})
+}
+
+func (c *Client) AddSubgroup(org string, groupName string, subgroupName string) error {
+	return c.do(http.MethodPost, urlPath(internalhttp.GROUP_ID_GROUPS_ID_URL, org, groupName, subgroupName), nil, nil, nil)
+}
+
+func (c *Client) RemoveSubgroup(org string, groupName string, subgroupName string) error {
+	return c.do(http.MethodDelete, urlPath(internalhttp.GROUP_ID_GROUPS_ID_URL, org, groupName, subgroupName), nil, nil, nil)
+}
+
+// ListSubgroups returns a page of subgroups of group filter.GroupName of organization filter.Org, and their
+// total number
+func (c *Client) ListSubgroups(filter *api.Filter) ([]api.GroupSubgroups, int, error) {
+	f := copyFilter(filter)
+	response := &internalhttp.ListSubgroupsResponse{}
+	if err := c.do(http.MethodGet, urlPath(internalhttp.GROUP_ID_GROUPS_URL, f.Org, f.GroupName), filterQuery(filter), nil, response); err != nil {
+		return nil, 0, err
+	}
+	return response.Subgroups, response.Total, nil
+}
+
+// ListSubgroupsPages calls fn with each page of subgroups of group filter.GroupName of organization filter.Org,
+// until every subgroup is retrieved or fn returns false
+func (c *Client) ListSubgroupsPages(filter *api.Filter, fn func(subgroups []api.GroupSubgroups) bool) error {
+	return pages(filter, func(f *api.Filter) (int, int, bool, error) {
+		subgroups, total, err := c.ListSubgroups(f)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return len(subgroups), total, fn(subgroups), nil
+	})
+}
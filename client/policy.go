@@ -0,0 +1,158 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Tecsisa/foulkon/api"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+)
+
+// POLICY METHODS
+
+func (c *Client) AddPolicy(name string, path string, org string, statements []api.Statement) (*api.Policy, error) {
+	request := internalhttp.CreatePolicyRequest{
+		Name:       name,
+		Path:       path,
+		Statements: statements,
+	}
+	policy := &api.Policy{}
+	if err := c.do(http.MethodPost, urlPath(internalhttp.POLICY_ROOT_URL, org), nil, request, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (c *Client) GetPolicyByName(org string, name string) (*api.Policy, error) {
+	policy := &api.Policy{}
+	if err := c.do(http.MethodGet, urlPath(internalhttp.POLICY_ID_URL, org, name), nil, nil, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// ListPolicies returns a page of policies of organization filter.Org, or of every organization if it's empty, and
+// the total number of policies matching filter
+func (c *Client) ListPolicies(filter *api.Filter) ([]api.PolicyIdentity, int, error) {
+	query := filterQuery(filter)
+	if f := copyFilter(filter); f.Org != "" {
+		query.Set("Org", f.Org)
+	}
+	response := &internalhttp.ListAllPoliciesResponse{}
+	if err := c.do(http.MethodGet, internalhttp.API_VERSION_1+"/policies", query, nil, response); err != nil {
+		return nil, 0, err
+	}
+	return response.Policies, response.Total, nil
+}
+
+// ListPoliciesPages calls fn with each page of policies matching filter, until every policy is retrieved or fn
+// returns false
+func (c *Client) ListPoliciesPages(filter *api.Filter, fn func(policies []api.PolicyIdentity) bool) error {
+	return pages(filter, func(f *api.Filter) (int, int, bool, error) {
+		policies, total, err := c.ListPolicies(f)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return len(policies), total, fn(policies), nil
+	})
+}
+
+func (c *Client) UpdatePolicy(org string, name string, newName string, newPath string,
+	newStatements []api.Statement) (*api.Policy, error) {
+	request := internalhttp.UpdatePolicyRequest{
+		Name:       newName,
+		Path:       newPath,
+		Statements: newStatements,
+	}
+	policy := &api.Policy{}
+	if err := c.do(http.MethodPut, urlPath(internalhttp.POLICY_ID_URL, org, name), nil, request, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// PatchPolicy updates policy fields with a JSON Merge Patch or a []JSONPatchOperation
+func (c *Client) PatchPolicy(org string, name string, patch interface{}) (*api.Policy, error) {
+	policy := &api.Policy{}
+	if err := c.do(http.MethodPatch, urlPath(internalhttp.POLICY_ID_URL, org, name), nil, patch, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (c *Client) RemovePolicy(org string, name string) error {
+	return c.do(http.MethodDelete, urlPath(internalhttp.POLICY_ID_URL, org, name), nil, nil, nil)
+}
+
+// ListAttachedGroups returns a page of groups attached to policy filter.PolicyName of organization filter.Org,
+// and their total number
+func (c *Client) ListAttachedGroups(filter *api.Filter) ([]api.PolicyGroups, int, error) {
+	f := copyFilter(filter)
+	response := &internalhttp.ListAttachedGroupsResponse{}
+	if err := c.do(http.MethodGet, urlPath(internalhttp.POLICY_ID_GROUPS_URL, f.Org, f.PolicyName), filterQuery(filter), nil, response); err != nil {
+		return nil, 0, err
+	}
+	return response.Groups, response.Total, nil
+}
+
+// ListAttachedGroupsPages calls fn with each page of groups attached to policy filter.PolicyName of organization
+// filter.Org, until every group is retrieved or fn returns false
+func (c *Client) ListAttachedGroupsPages(filter *api.Filter, fn func(groups []api.PolicyGroups) bool) error {
+	return pages(filter, func(f *api.Filter) (int, int, bool, error) {
+		groups, total, err := c.ListAttachedGroups(f)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return len(groups), total, fn(groups), nil
+	})
+}
+
+// ListPolicyVersions returns a page of versions of policy filter.PolicyName of organization filter.Org, and
+// their total number
+func (c *Client) ListPolicyVersions(filter *api.Filter) ([]api.PolicyVersion, int, error) {
+	f := copyFilter(filter)
+	response := &internalhttp.ListPolicyVersionsResponse{}
+	if err := c.do(http.MethodGet, urlPath(internalhttp.POLICY_ID_VERSIONS_URL, f.Org, f.PolicyName), filterQuery(filter), nil, response); err != nil {
+		return nil, 0, err
+	}
+	return response.Versions, response.Total, nil
+}
+
+// ListPolicyVersionsPages calls fn with each page of versions of policy filter.PolicyName of organization
+// filter.Org, until every version is retrieved or fn returns false
+func (c *Client) ListPolicyVersionsPages(filter *api.Filter, fn func(versions []api.PolicyVersion) bool) error {
+	return pages(filter, func(f *api.Filter) (int, int, bool, error) {
+		versions, total, err := c.ListPolicyVersions(f)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return len(versions), total, fn(versions), nil
+	})
+}
+
+func (c *Client) GetPolicyVersion(org string, name string, version int) (*api.PolicyVersion, error) {
+	policyVersion := &api.PolicyVersion{}
+	path := urlPath(internalhttp.POLICY_ID_VERSIONS_ID_URL, org, name, strconv.Itoa(version))
+	if err := c.do(http.MethodGet, path, nil, nil, policyVersion); err != nil {
+		return nil, err
+	}
+	return policyVersion, nil
+}
+
+func (c *Client) DiffPolicyVersions(org string, name string, version int, targetVersion int) (*api.PolicyVersionDiff, error) {
+	diff := &api.PolicyVersionDiff{}
+	path := urlPath(internalhttp.POLICY_ID_VERSIONS_DIFF_URL, org, name, strconv.Itoa(version), strconv.Itoa(targetVersion))
+	if err := c.do(http.MethodGet, path, nil, nil, diff); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+func (c *Client) RollbackPolicy(org string, name string, version int) (*api.Policy, error) {
+	policy := &api.Policy{}
+	path := urlPath(internalhttp.POLICY_ID_VERSIONS_ROLLBACK_URL, org, name, strconv.Itoa(version))
+	if err := c.do(http.MethodPost, path, nil, nil, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
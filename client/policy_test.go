@@ -0,0 +1,114 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Tecsisa/foulkon/api"
+)
+
+func TestPolicyMethods(t *testing.T) {
+	statements := []api.Statement{
+		{
+			Effect:    "allow",
+			Actions:   []string{"example:get"},
+			Resources: []string{"urn:ews:example:instance1:resource/*"},
+		},
+	}
+	testcases := map[string]methodTestCase{
+		"OkCaseAddPolicy": {
+			call: func(c *Client) (interface{}, error) {
+				return c.AddPolicy("policy1", "/path/", "org1", statements)
+			},
+			responses: []workerResponse{
+				{StatusCode: http.StatusCreated, Body: `{"name":"policy1","path":"/path/","org":"org1"}`},
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method:      http.MethodPost,
+					URI:         "/api/v1/organizations/org1/policies",
+					ContentType: "application/json",
+					Body: `{"name":"policy1","path":"/path/","statements":[{"effect":"allow","actions":["example:get"],` +
+						`"resources":["urn:ews:example:instance1:resource/*"]}]}`,
+				},
+			},
+			expectedResult: &api.Policy{Name: "policy1", Path: "/path/", Org: "org1"},
+		},
+		"OkCaseListPolicies": {
+			call: func(c *Client) (interface{}, error) {
+				policies, total, err := c.ListPolicies(&api.Filter{Org: "org1", OrderBy: "name-asc"})
+				return map[string]interface{}{"policies": policies, "total": total}, err
+			},
+			responses: []workerResponse{
+				{StatusCode: http.StatusOK, Body: `{"policies":[{"org":"org1","name":"policy1"}],"limit":20,"offset":0,"total":1}`},
+			},
+			expectedRequests: []workerRequest{
+				{Method: http.MethodGet, URI: "/api/v1/policies?OrderBy=name-asc&Org=org1"},
+			},
+			expectedResult: map[string]interface{}{
+				"policies": []api.PolicyIdentity{{Org: "org1", Name: "policy1"}},
+				"total":    1,
+			},
+		},
+		"OkCasePatchPolicy": {
+			call: func(c *Client) (interface{}, error) {
+				return c.PatchPolicy("org1", "policy1", map[string]string{"path": "/new/"})
+			},
+			responses: []workerResponse{
+				{StatusCode: http.StatusOK, Body: `{"name":"policy1","path":"/new/","org":"org1"}`},
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method:      http.MethodPatch,
+					URI:         "/api/v1/organizations/org1/policies/policy1",
+					ContentType: "application/merge-patch+json",
+					Body:        `{"path":"/new/"}`,
+				},
+			},
+			expectedResult: &api.Policy{Name: "policy1", Path: "/new/", Org: "org1"},
+		},
+		"OkCaseDiffPolicyVersions": {
+			call: func(c *Client) (interface{}, error) {
+				return c.DiffPolicyVersions("org1", "policy1", 1, 2)
+			},
+			responses: []workerResponse{
+				{StatusCode: http.StatusOK, Body: `{}`},
+			},
+			expectedRequests: []workerRequest{
+				{Method: http.MethodGet, URI: "/api/v1/organizations/org1/policies/policy1/versions/1/diff/2"},
+			},
+			expectedResult: &api.PolicyVersionDiff{},
+		},
+		"OkCaseRollbackPolicy": {
+			call: func(c *Client) (interface{}, error) {
+				return c.RollbackPolicy("org1", "policy1", 1)
+			},
+			responses: []workerResponse{
+				{StatusCode: http.StatusOK, Body: `{"name":"policy1","org":"org1"}`},
+			},
+			expectedRequests: []workerRequest{
+				{Method: http.MethodPost, URI: "/api/v1/organizations/org1/policies/policy1/versions/1/rollback"},
+			},
+			expectedResult: &api.Policy{Name: "policy1", Org: "org1"},
+		},
+		"ErrorCasePreconditionFailed": {
+			call: func(c *Client) (interface{}, error) {
+				return nil, c.RemovePolicy("org1", "policy1")
+			},
+			responses: []workerResponse{
+				{StatusCode: http.StatusPreconditionFailed, Body: `{"code":"PreconditionFailedError","message":"Changed"}`},
+			},
+			expectedRequests: []workerRequest{
+				{Method: http.MethodDelete, URI: "/api/v1/organizations/org1/policies/policy1"},
+			},
+			expectedError: &PreconditionFailedError{&WorkerError{
+				StatusCode: http.StatusPreconditionFailed,
+				RequestID:  "worker-request-id",
+				Code:       api.PRECONDITION_FAILED_ERROR,
+				Message:    "Changed",
+			}},
+		},
+	}
+
+	testMethods(t, testcases)
+}
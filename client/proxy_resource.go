@@ -0,0 +1,81 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/Tecsisa/foulkon/api"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+)
+
+// PROXY RESOURCE METHODS
+
+func (c *Client) AddProxyResource(name string, org string, path string, resource api.ResourceEntity) (*api.ProxyResource, error) {
+	request := internalhttp.CreateProxyResourceRequest{
+		Name:     name,
+		Path:     path,
+		Resource: resource,
+	}
+	proxyResource := &api.ProxyResource{}
+	if err := c.do(http.MethodPost, urlPath(internalhttp.PROXY_RESOURCE_ROOT_URL, org), nil, request, proxyResource); err != nil {
+		return nil, err
+	}
+	return proxyResource, nil
+}
+
+func (c *Client) GetProxyResourceByName(org string, name string) (*api.ProxyResource, error) {
+	proxyResource := &api.ProxyResource{}
+	if err := c.do(http.MethodGet, urlPath(internalhttp.PROXY_RESOURCE_ID_URL, org, name), nil, nil, proxyResource); err != nil {
+		return nil, err
+	}
+	return proxyResource, nil
+}
+
+// ListProxyResources returns the names of a page of proxy resources of organization filter.Org, and the total
+// number of proxy resources matching filter
+func (c *Client) ListProxyResources(filter *api.Filter) ([]string, int, error) {
+	f := copyFilter(filter)
+	response := &internalhttp.ListProxyResourcesResponse{}
+	if err := c.do(http.MethodGet, urlPath(internalhttp.PROXY_RESOURCE_ROOT_URL, f.Org), filterQuery(filter), nil, response); err != nil {
+		return nil, 0, err
+	}
+	return response.Resources, response.Total, nil
+}
+
+// ListProxyResourcesPages calls fn with each page of proxy resources of organization filter.Org, until every
+// proxy resource is retrieved or fn returns false
+func (c *Client) ListProxyResourcesPages(filter *api.Filter, fn func(names []string) bool) error {
+	return pages(filter, func(f *api.Filter) (int, int, bool, error) {
+		names, total, err := c.ListProxyResources(f)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return len(names), total, fn(names), nil
+	})
+}
+
+func (c *Client) UpdateProxyResource(org string, name string, newName string, newPath string,
+	newResource api.ResourceEntity) (*api.ProxyResource, error) {
+	request := internalhttp.UpdateProxyResourceRequest{
+		Name:     newName,
+		Path:     newPath,
+		Resource: newResource,
+	}
+	proxyResource := &api.ProxyResource{}
+	if err := c.do(http.MethodPut, urlPath(internalhttp.PROXY_RESOURCE_ID_URL, org, name), nil, request, proxyResource); err != nil {
+		return nil, err
+	}
+	return proxyResource, nil
+}
+
+// PatchProxyResource updates proxy resource fields with a JSON Merge Patch or a []JSONPatchOperation
+func (c *Client) PatchProxyResource(org string, name string, patch interface{}) (*api.ProxyResource, error) {
+	proxyResource := &api.ProxyResource{}
+	if err := c.do(http.MethodPatch, urlPath(internalhttp.PROXY_RESOURCE_ID_URL, org, name), nil, patch, proxyResource); err != nil {
+		return nil, err
+	}
+	return proxyResource, nil
+}
+
+func (c *Client) RemoveProxyResource(org string, name string) error {
+	return c.do(http.MethodDelete, urlPath(internalhttp.PROXY_RESOURCE_ID_URL, org, name), nil, nil, nil)
+}
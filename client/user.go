@@ -0,0 +1,149 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/Tecsisa/foulkon/api"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+)
+
+// USER METHODS
+
+func (c *Client) AddUser(externalId string, path string) (*api.User, error) {
+	request := internalhttp.CreateUserRequest{
+		ExternalID: externalId,
+		Path:       path,
+	}
+	user := &api.User{}
+	if err := c.do(http.MethodPost, internalhttp.USER_ROOT_URL, nil, request, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (c *Client) GetUserByExternalID(externalId string) (*api.User, error) {
+	user := &api.User{}
+	if err := c.do(http.MethodGet, urlPath(internalhttp.USER_ID_URL, externalId), nil, nil, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ListUsers returns the external IDs of a page of users, and the total number of users matching filter
+func (c *Client) ListUsers(filter *api.Filter) ([]string, int, error) {
+	response := &internalhttp.GetUserExternalIDsResponse{}
+	if err := c.do(http.MethodGet, internalhttp.USER_ROOT_URL, filterQuery(filter), nil, response); err != nil {
+		return nil, 0, err
+	}
+	return response.ExternalIDs, response.Total, nil
+}
+
+// ListUsersPages calls fn with each page of users matching filter, until every user is retrieved or fn returns false
+func (c *Client) ListUsersPages(filter *api.Filter, fn func(externalIds []string) bool) error {
+	return pages(filter, func(f *api.Filter) (int, int, bool, error) {
+		externalIds, total, err := c.ListUsers(f)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return len(externalIds), total, fn(externalIds), nil
+	})
+}
+
+func (c *Client) UpdateUser(externalId string, newPath string) (*api.User, error) {
+	request := internalhttp.UpdateUserRequest{
+		Path: newPath,
+	}
+	user := &api.User{}
+	if err := c.do(http.MethodPut, urlPath(internalhttp.USER_ID_URL, externalId), nil, request, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// PatchUser updates user fields with a JSON Merge Patch or a []JSONPatchOperation
+func (c *Client) PatchUser(externalId string, patch interface{}) (*api.User, error) {
+	user := &api.User{}
+	if err := c.do(http.MethodPatch, urlPath(internalhttp.USER_ID_URL, externalId), nil, patch, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (c *Client) RemoveUser(externalId string) error {
+	return c.do(http.MethodDelete, urlPath(internalhttp.USER_ID_URL, externalId), nil, nil, nil)
+}
+
+// ListGroupsByUser returns a page of groups of user filter.ExternalID, and their total number
+func (c *Client) ListGroupsByUser(filter *api.Filter) ([]api.UserGroups, int, error) {
+	f := copyFilter(filter)
+	response := &internalhttp.GetGroupsByUserIdResponse{}
+	if err := c.do(http.MethodGet, urlPath(internalhttp.USER_ID_GROUPS_URL, f.ExternalID), filterQuery(filter), nil, response); err != nil {
+		return nil, 0, err
+	}
+	return response.Groups, response.Total, nil
+}
+
+// ListGroupsByUserPages calls fn with each page of groups of user filter.ExternalID, until every group is
+// retrieved or fn returns false
+func (c *Client) ListGroupsByUserPages(filter *api.Filter, fn func(groups []api.UserGroups) bool) error {
+	return pages(filter, func(f *api.Filter) (int, int, bool, error) {
+		groups, total, err := c.ListGroupsByUser(f)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return len(groups), total, fn(groups), nil
+	})
+}
+
+// ListEffectivePermissionsByUser returns a page of effective permissions of user filter.ExternalID, and their
+// total number
+func (c *Client) ListEffectivePermissionsByUser(filter *api.Filter) ([]api.EffectivePermission, int, error) {
+	f := copyFilter(filter)
+	response := &internalhttp.GetEffectivePermissionsResponse{}
+	if err := c.do(http.MethodGet, urlPath(internalhttp.USER_ID_PERMS_URL, f.ExternalID), filterQuery(filter), nil, response); err != nil {
+		return nil, 0, err
+	}
+	return response.Permissions, response.Total, nil
+}
+
+// ListEffectivePermissionsByUserPages calls fn with each page of effective permissions of user filter.ExternalID,
+// until every permission is retrieved or fn returns false
+func (c *Client) ListEffectivePermissionsByUserPages(filter *api.Filter, fn func(permissions []api.EffectivePermission) bool) error {
+	return pages(filter, func(f *api.Filter) (int, int, bool, error) {
+		permissions, total, err := c.ListEffectivePermissionsByUser(f)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return len(permissions), total, fn(permissions), nil
+	})
+}
+
+func (c *Client) AttachPolicyToUser(externalId string, org string, policyName string) error {
+	return c.do(http.MethodPost, urlPath(internalhttp.USER_ID_POLICIES_ID_URL, externalId, org, policyName), nil, nil, nil)
+}
+
+func (c *Client) DetachPolicyToUser(externalId string, org string, policyName string) error {
+	return c.do(http.MethodDelete, urlPath(internalhttp.USER_ID_POLICIES_ID_URL, externalId, org, policyName), nil, nil, nil)
+}
+
+// ListAttachedUserPolicies returns a page of policies attached to user filter.ExternalID, and their total number
+func (c *Client) ListAttachedUserPolicies(filter *api.Filter) ([]api.UserPolicies, int, error) {
+	f := copyFilter(filter)
+	response := &internalhttp.ListAttachedUserPoliciesResponse{}
+	if err := c.do(http.MethodGet, urlPath(internalhttp.USER_ID_POLICIES_URL, f.ExternalID), filterQuery(filter), nil, response); err != nil {
+		return nil, 0, err
+	}
+	return response.AttachedPolicies, response.Total, nil
+}
+
+// ListAttachedUserPoliciesPages calls fn with each page of policies attached to user filter.ExternalID, until
+// every policy is retrieved or fn returns false
+func (c *Client) ListAttachedUserPoliciesPages(filter *api.Filter, fn func(policies []api.UserPolicies) bool) error {
+	return pages(filter, func(f *api.Filter) (int, int, bool, error) {
+		policies, total, err := c.ListAttachedUserPolicies(f)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return len(policies), total, fn(policies), nil
+	})
+}
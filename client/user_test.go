@@ -0,0 +1,95 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Tecsisa/foulkon/api"
+)
+
+func TestUserMethods(t *testing.T) {
+	testcases := map[string]methodTestCase{
+		"OkCaseAddUser": {
+			call: func(c *Client) (interface{}, error) {
+				return c.AddUser("user1", "/path/")
+			},
+			responses: []workerResponse{
+				{StatusCode: http.StatusCreated, Body: `{"externalId":"user1","path":"/path/"}`},
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method:      http.MethodPost,
+					URI:         "/api/v1/users",
+					ContentType: "application/json",
+					Body:        `{"externalId":"user1","path":"/path/"}`,
+				},
+			},
+			expectedResult: &api.User{ExternalID: "user1", Path: "/path/"},
+		},
+		"OkCaseListUsersPages": {
+			call: func(c *Client) (interface{}, error) {
+				users := []string{}
+				err := c.ListUsersPages(&api.Filter{Limit: 1}, func(externalIds []string) bool {
+					users = append(users, externalIds...)
+					return true
+				})
+				return users, err
+			},
+			responses: []workerResponse{
+				{StatusCode: http.StatusOK, Body: `{"users":["user1"],"limit":1,"offset":0,"total":2}`},
+				{StatusCode: http.StatusOK, Body: `{"users":["user2"],"limit":1,"offset":1,"total":2}`},
+			},
+			expectedRequests: []workerRequest{
+				{Method: http.MethodGet, URI: "/api/v1/users?Limit=1"},
+				{Method: http.MethodGet, URI: "/api/v1/users?Limit=1&Offset=1"},
+			},
+			expectedResult: []string{"user1", "user2"},
+		},
+		"OkCaseListGroupsByUser": {
+			call: func(c *Client) (interface{}, error) {
+				groups, total, err := c.ListGroupsByUser(&api.Filter{ExternalID: "user 1", Limit: 10})
+				return map[string]interface{}{"groups": groups, "total": total}, err
+			},
+			responses: []workerResponse{
+				{StatusCode: http.StatusOK, Body: `{"groups":[{"org":"org1","name":"group1"}],"limit":10,"offset":0,"total":1}`},
+			},
+			expectedRequests: []workerRequest{
+				{Method: http.MethodGet, URI: "/api/v1/users/user%201/groups?Limit=10"},
+			},
+			expectedResult: map[string]interface{}{
+				"groups": []api.UserGroups{{Org: "org1", Name: "group1"}},
+				"total":  1,
+			},
+		},
+		"OkCaseRemoveUser": {
+			call: func(c *Client) (interface{}, error) {
+				return nil, c.RemoveUser("user1")
+			},
+			responses: []workerResponse{
+				{StatusCode: http.StatusNoContent},
+			},
+			expectedRequests: []workerRequest{
+				{Method: http.MethodDelete, URI: "/api/v1/users/user1"},
+			},
+		},
+		"ErrorCaseUserNotFound": {
+			call: func(c *Client) (interface{}, error) {
+				return c.GetUserByExternalID("user1")
+			},
+			responses: []workerResponse{
+				{StatusCode: http.StatusNotFound, Body: `{"code":"UserWithExternalIDNotFound","message":"User not found"}`},
+			},
+			expectedRequests: []workerRequest{
+				{Method: http.MethodGet, URI: "/api/v1/users/user1"},
+			},
+			expectedError: &NotFoundError{&WorkerError{
+				StatusCode: http.StatusNotFound,
+				RequestID:  "worker-request-id",
+				Code:       api.USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message:    "User not found",
+			}},
+		},
+	}
+
+	testMethods(t, testcases)
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/client"
+)
+
+const (
+	// Output formats
+	OUTPUT_TABLE = "table"
+	OUTPUT_JSON  = "json"
+)
+
+// adminAction is an action of an admin command. Function setup defines the action flags and returns the function
+// that calls the worker once flags are parsed. Its result is printed using the selected output format
+type adminAction struct {
+	description string
+	required    []string
+	setup       func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error)
+}
+
+// adminCommand returns the function that runs an admin command. First argument selects one of the actions
+func adminCommand(name string, actions map[string]adminAction) func(args []string) error {
+	return func(args []string) error {
+		if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+			return fmt.Errorf("Action is required, usage: foulkonctl %v <action> [flags]\n\n%v", name, actionsUsage(actions))
+		}
+		action, ok := actions[args[0]]
+		if !ok {
+			return fmt.Errorf("Unknown action %v, usage: foulkonctl %v <action> [flags]\n\n%v", args[0], name, actionsUsage(actions))
+		}
+
+		fs, connect := newFlagSet(name + " " + args[0])
+		output := fs.String("o", OUTPUT_TABLE, "Output format, table or json")
+		run := action.setup(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		for _, flagName := range action.required {
+			if fs.Lookup(flagName).Value.String() == "" {
+				return fmt.Errorf("Flag -%v is required", flagName)
+			}
+		}
+		if *output != OUTPUT_TABLE && *output != OUTPUT_JSON {
+			return fmt.Errorf("Invalid output format %v, use table or json", *output)
+		}
+
+		result, err := run(connect())
+		if err != nil {
+			return err
+		}
+		return printOutput(os.Stdout, *output, result)
+	}
+}
+
+// listFlags defines the pagination and filter flags of list actions. Returned function returns the filter they set
+func listFlags(fs *flag.FlagSet) func() *api.Filter {
+	offset := fs.Int("offset", 0, "Number of items to skip")
+	limit := fs.Int("limit", 0, "Maximum number of items to return, worker default is used if 0")
+	orderBy := fs.String("order-by", "", "Order by a column, e.g. name-asc or name-desc")
+	pathPrefix := fs.String("path-prefix", "", "Filter by path prefix")
+	return func() *api.Filter {
+		return &api.Filter{
+			Offset:     *offset,
+			Limit:      *limit,
+			OrderBy:    *orderBy,
+			PathPrefix: *pathPrefix,
+		}
+	}
+}
+
+// pageLimit returns the maximum number of items of a page listed with filter
+func pageLimit(filter *api.Filter) int {
+	if filter.Limit < 1 {
+		return api.DEFAULT_LIMIT_SIZE
+	}
+	return filter.Limit
+}
+
+// listValue is a flag with a list of values, set either with comma separated values or repeating the flag
+type listValue []string
+
+func (l *listValue) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// mapValue is a flag with key=value pairs, set either with comma separated pairs or repeating the flag
+type mapValue map[string]string
+
+func (m mapValue) String() string {
+	pairs := []string{}
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (m mapValue) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("invalid pair %v, use key=value", pair)
+		}
+		m[kv[0]] = kv[1]
+	}
+	return nil
+}
+
+// printOutput prints an action result. Table format prints the items of list responses as rows and the fields
+// of any other response as key-value rows
+func printOutput(w io.Writer, format string, result interface{}) error {
+	if result == nil {
+		return nil
+	}
+	if format == OUTPUT_JSON {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(result))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	switch {
+	case v.Kind() == reflect.Slice:
+		printRows(tw, "VALUE", v)
+	case v.Kind() == reflect.Struct && v.FieldByName("Total").IsValid() && v.Field(0).Kind() == reflect.Slice:
+		printRows(tw, columnName(v.Type().Field(0)), v.Field(0))
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(w, "\nShowing %v items from offset %v, total %v\n",
+			v.Field(0).Len(), v.FieldByName("Offset").Interface(), v.FieldByName("Total").Interface())
+		return err
+	case v.Kind() == reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Type().Field(i); field.PkgPath == "" {
+				fmt.Fprintf(tw, "%v\t%v\n", columnName(field), formatValue(v.Field(i)))
+			}
+		}
+	default:
+		fmt.Fprintln(tw, formatValue(v))
+	}
+	return tw.Flush()
+}
+
+// PRIVATE HELPER METHODS
+
+// Print a row for each item. Struct items have a column for each field, other items have a single column
+func printRows(w io.Writer, header string, items reflect.Value) {
+	elemType := items.Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct || elemType == reflect.TypeOf(time.Time{}) {
+		fmt.Fprintln(w, header)
+		for i := 0; i < items.Len(); i++ {
+			fmt.Fprintln(w, formatValue(items.Index(i)))
+		}
+		return
+	}
+
+	fields := []int{}
+	headers := []string{}
+	for i := 0; i < elemType.NumField(); i++ {
+		if field := elemType.Field(i); field.PkgPath == "" && field.Tag.Get("json") != "-" {
+			fields = append(fields, i)
+			headers = append(headers, columnName(field))
+		}
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for i := 0; i < items.Len(); i++ {
+		item := reflect.Indirect(items.Index(i))
+		row := []string{}
+		for _, f := range fields {
+			if item.IsValid() {
+				row = append(row, formatValue(item.Field(f)))
+			}
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+}
+
+// Return the column name of a field from its JSON name, e.g. EXTERNAL ID for externalId
+func columnName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		name = field.Name
+	}
+	runes := []rune(name)
+	column := []rune{}
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && unicode.IsLower(runes[i-1]) {
+			column = append(column, ' ')
+		}
+		column = append(column, unicode.ToUpper(r))
+	}
+	return string(column)
+}
+
+// Format a value as a table cell. Lists of strings are comma separated and complex values are JSON encoded
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Interface())
+	case reflect.Slice:
+		if v.Type().Elem() == reflect.TypeOf("") {
+			return strings.Join(v.Interface().([]string), ", ")
+		}
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprint(v.Interface())
+	}
+	return string(data)
+}
+
+// Return the list of actions with their descriptions
+func actionsUsage(actions map[string]adminAction) string {
+	names := []string{}
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := []string{"Actions:"}
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("  %-16v %v", name, actions[name].description))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+	"github.com/stretchr/testify/assert"
+)
+
+// workerRequest is a request received by the test worker
+type workerRequest struct {
+	Method      string
+	URI         string
+	ContentType string
+	Body        string
+}
+
+func TestAdminActions(t *testing.T) {
+	testcases := map[string]struct {
+		// Method args
+		actions map[string]adminAction
+		action  string
+		args    []string
+		// Content of the YAML file passed with flag -f, if any. FILE is replaced by its path in expected error
+		file string
+		// Worker responses, by request method
+		responses map[string]string
+		// Expected result
+		expectedRequests []workerRequest
+		expectedResult   interface{}
+		expectedError    string
+	}{
+		"OkCaseListUsers": {
+			actions: userActions,
+			action:  "list",
+			args:    []string{"-offset", "10", "-limit", "5", "-order-by", "path-desc", "-path-prefix", "/example/"},
+			responses: map[string]string{
+				http.MethodGet: `{"users":["user1"],"limit":5,"offset":10,"total":11}`,
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method: http.MethodGet,
+					URI:    "/api/v1/users?Limit=5&Offset=10&OrderBy=path-desc&PathPrefix=%2Fexample%2F",
+				},
+			},
+			expectedResult: &internalhttp.GetUserExternalIDsResponse{
+				ExternalIDs: []string{"user1"},
+				Limit:       5,
+				Offset:      10,
+				Total:       11,
+			},
+		},
+		"OkCaseUpdateGroup": {
+			actions: groupActions,
+			action:  "update",
+			args:    []string{"-org", "org1", "-name", "group1", "-new-path", "/new/"},
+			responses: map[string]string{
+				http.MethodPatch: `{"name":"group1","path":"/new/"}`,
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method:      http.MethodPatch,
+					URI:         "/api/v1/organizations/org1/groups/group1",
+					ContentType: internalhttp.MERGE_PATCH_CONTENT_TYPE,
+					Body:        `{"path":"/new/"}`,
+				},
+			},
+			expectedResult: &api.Group{
+				Name: "group1",
+				Path: "/new/",
+			},
+		},
+		"OkCaseAddMember": {
+			actions: memberActions,
+			action:  "add",
+			args:    []string{"-org", "org1", "-group", "group1", "-id", "user 1"},
+			expectedRequests: []workerRequest{
+				{
+					Method: http.MethodPost,
+					URI:    "/api/v1/organizations/org1/groups/group1/users/user%201",
+				},
+			},
+		},
+		"OkCaseAttachUserPolicy": {
+			actions: attachmentActions,
+			action:  "attach",
+			args:    []string{"-id", "user1", "-org", "org1", "-policy", "policy1"},
+			expectedRequests: []workerRequest{
+				{
+					Method: http.MethodPost,
+					URI:    "/api/v1/users/user1/policies/organizations/org1/policy1",
+				},
+			},
+		},
+		"OkCaseUpdateOidcProvider": {
+			actions: oidcProviderActions,
+			action:  "update",
+			args:    []string{"-name", "google", "-clients", "client2,client3"},
+			responses: map[string]string{
+				http.MethodGet: `{"name":"google","path":"/","issuerUrl":"https://accounts.google.com","clients":[{"name":"client1"}]}`,
+				http.MethodPut: `{"name":"google","path":"/","issuerUrl":"https://accounts.google.com","clients":[{"name":"client2"},{"name":"client3"}]}`,
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method: http.MethodGet,
+					URI:    "/api/v1/admin/auth/oidc/providers/google",
+				},
+				{
+					Method:      http.MethodPut,
+					URI:         "/api/v1/admin/auth/oidc/providers/google",
+					ContentType: "application/json",
+					Body:        `{"name":"google","path":"/","issuerUrl":"https://accounts.google.com","clients":["client2","client3"]}`,
+				},
+			},
+			expectedResult: &api.OidcProvider{
+				Name:        "google",
+				Path:        "/",
+				IssuerURL:   "https://accounts.google.com",
+				OidcClients: []api.OidcClient{{Name: "client2"}, {Name: "client3"}},
+			},
+		},
+		"OkCaseAuthorizeResources": {
+			actions: authorizeActions,
+			action:  "resources",
+			args:    []string{"-action", "example:get", "-resources", "urn:ews:example:instance1:resource/1", "-context", "ip=127.0.0.1"},
+			responses: map[string]string{
+				http.MethodPost: `{"resourcesAllowed":["urn:ews:example:instance1:resource/1"]}`,
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method:      http.MethodPost,
+					URI:         "/api/v1/resource",
+					ContentType: "application/json",
+					Body:        `{"action":"example:get","resources":["urn:ews:example:instance1:resource/1"],"context":{"ip":"127.0.0.1"}}`,
+				},
+			},
+			expectedResult: &internalhttp.AuthorizeResourcesResponse{
+				ResourcesAllowed: []string{"urn:ews:example:instance1:resource/1"},
+			},
+		},
+		"OkCasePatchUser": {
+			actions: userActions,
+			action:  "patch",
+			args:    []string{"-id", "user1"},
+			file:    "- op: replace\n  path: /path\n  value: /new/\n",
+			responses: map[string]string{
+				http.MethodPatch: `{"externalId":"user1","path":"/new/"}`,
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method:      http.MethodPatch,
+					URI:         "/api/v1/users/user1",
+					ContentType: internalhttp.JSON_PATCH_CONTENT_TYPE,
+					Body:        `[{"op":"replace","path":"/path","value":"/new/"}]`,
+				},
+			},
+			expectedResult: &api.User{
+				ExternalID: "user1",
+				Path:       "/new/",
+			},
+		},
+		"OkCaseMergePatchGroup": {
+			actions: groupActions,
+			action:  "patch",
+			args:    []string{"-org", "org1", "-name", "group1"},
+			file:    "path: /new/\n",
+			responses: map[string]string{
+				http.MethodPatch: `{"name":"group1","path":"/new/"}`,
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method:      http.MethodPatch,
+					URI:         "/api/v1/organizations/org1/groups/group1",
+					ContentType: internalhttp.MERGE_PATCH_CONTENT_TYPE,
+					Body:        `{"path":"/new/"}`,
+				},
+			},
+			expectedResult: &api.Group{
+				Name: "group1",
+				Path: "/new/",
+			},
+		},
+		"OkCaseRunBatch": {
+			actions: batchActions,
+			action:  "run",
+			file:    "- action: iam:CreateUser\n  externalId: user1\n  path: /example/\n",
+			responses: map[string]string{
+				http.MethodPost: `{"results":[{"action":"iam:CreateUser"}]}`,
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method:      http.MethodPost,
+					URI:         "/api/v1/batch",
+					ContentType: "application/json",
+					Body:        `{"operations":[{"action":"iam:CreateUser","externalId":"user1","path":"/example/"}]}`,
+				},
+			},
+			expectedResult: &internalhttp.BatchResponse{
+				Results: []api.BatchOperationResult{
+					{
+						Action: api.USER_ACTION_CREATE_USER,
+					},
+				},
+			},
+		},
+		"OkCaseListAuditEvents": {
+			actions: auditActions,
+			action:  "list",
+			args:    []string{"-actor", "user1", "-from", "2017-01-01T00:00:00Z"},
+			responses: map[string]string{
+				http.MethodGet: `{"limit":20,"total":0}`,
+			},
+			expectedRequests: []workerRequest{
+				{
+					Method: http.MethodGet,
+					URI:    "/api/v1/audit?Actor=user1&From=2017-01-01T00%3A00%3A00Z",
+				},
+			},
+			expectedResult: &internalhttp.ListAuditEventsResponse{
+				Limit: api.DEFAULT_LIMIT_SIZE,
+			},
+		},
+		"OkCaseFlushAuthzCache": {
+			actions: workerActions,
+			action:  "cache-flush",
+			expectedRequests: []workerRequest{
+				{
+					Method: http.MethodDelete,
+					URI:    "/api/v1/admin/authz/cache",
+				},
+			},
+		},
+		"ErrorCaseInvalidPatchFile": {
+			actions:       userActions,
+			action:        "patch",
+			args:          []string{"-id", "user1"},
+			file:          "- op: [replace\n",
+			expectedError: "Invalid file FILE: yaml: line 1: did not find expected ',' or ']'",
+		},
+		"ErrorCaseWorkerError": {
+			actions: userActions,
+			action:  "get",
+			args:    []string{"-id", "user1"},
+			expectedRequests: []workerRequest{
+				{
+					Method: http.MethodGet,
+					URI:    "/api/v1/users/user1",
+				},
+			},
+			expectedError: "Code: NotFound, Message: User not found, Status code: 404, Request ID: ",
+		},
+		"ErrorCaseAttachmentWithoutUserOrGroup": {
+			actions:       attachmentActions,
+			action:        "attach",
+			args:          []string{"-org", "org1", "-policy", "policy1"},
+			expectedError: "Either flag -id or flag -group is required",
+		},
+	}
+
+	for n, test := range testcases {
+		var requests []workerRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			requests = append(requests, workerRequest{
+				Method:      r.Method,
+				URI:         r.URL.RequestURI(),
+				ContentType: r.Header.Get("Content-Type"),
+				Body:        string(bytes.TrimSpace(body)),
+			})
+			if user, password, ok := r.BasicAuth(); !ok || user != "admin" || password != "admin" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if test.expectedError != "" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"code":"NotFound","message":"User not found"}`))
+				return
+			}
+			if response, ok := test.responses[r.Method]; ok {
+				w.Write([]byte(response))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+		args := test.args
+		expectedError := test.expectedError
+		if test.file != "" {
+			dir, err := ioutil.TempDir("", "foulkonctl")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			file := filepath.Join(dir, "file.yaml")
+			if err := ioutil.WriteFile(file, []byte(test.file), 0600); err != nil {
+				t.Fatal(err)
+			}
+			args = append(args, "-f", file)
+			expectedError = strings.Replace(expectedError, "FILE", file, 1)
+		}
+
+		fs := flag.NewFlagSet(test.action, flag.ContinueOnError)
+		run := test.actions[test.action].setup(fs)
+		assert.Nil(t, fs.Parse(args), "Error in test case %v", n)
+		result, err := run(newClient(server.URL, "admin", "admin", ""))
+		server.Close()
+
+		if test.expectedError != "" {
+			if assert.NotNil(t, err, "Error in test case %v", n) {
+				assert.Equal(t, expectedError, err.Error(), "Error in test case %v", n)
+			}
+		} else {
+			assert.Nil(t, err, "Error in test case %v", n)
+			assert.Equal(t, test.expectedResult, result, "Error in test case %v", n)
+		}
+		assert.Equal(t, test.expectedRequests, requests, "Error in test case %v", n)
+	}
+}
+
+func TestPrintOutput(t *testing.T) {
+	testcases := map[string]struct {
+		// Method args
+		format string
+		result interface{}
+		// Expected result
+		expectedOutput string
+	}{
+		"OkCaseListTable": {
+			format: OUTPUT_TABLE,
+			result: &internalhttp.ListAllGroupsResponse{
+				Groups: []api.GroupIdentity{
+					{
+						Org:  "org1",
+						Name: "group1",
+					},
+				},
+				Limit:  20,
+				Offset: 0,
+				Total:  1,
+			},
+			expectedOutput: "ORG   NAME\norg1  group1\n\nShowing 1 items from offset 0, total 1\n",
+		},
+		"OkCaseEntityTable": {
+			format: OUTPUT_TABLE,
+			result: &api.User{
+				ID:         "1234",
+				ExternalID: "user1",
+				Path:       "/",
+				CreateAt:   time.Date(2016, 7, 1, 0, 0, 0, 0, time.UTC),
+			},
+			expectedOutput: "ID           1234\nEXTERNAL ID  user1\nPATH         /\nURN          \nCREATE AT    2016-07-01T00:00:00Z\nUPDATE AT    \n",
+		},
+		"OkCaseJSON": {
+			format: OUTPUT_JSON,
+			result: &internalhttp.ListPoliciesResponse{
+				Policies: []string{"policy1"},
+				Limit:    20,
+				Total:    1,
+			},
+			expectedOutput: "{\n  \"policies\": [\n    \"policy1\"\n  ],\n  \"limit\": 20,\n  \"offset\": 0,\n  \"total\": 1\n}\n",
+		},
+		"OkCaseNoResult": {
+			format: OUTPUT_TABLE,
+		},
+	}
+
+	for n, test := range testcases {
+		output := &bytes.Buffer{}
+		err := printOutput(output, test.format, test.result)
+		assert.Nil(t, err, "Error in test case %v", n)
+		assert.Equal(t, test.expectedOutput, output.String(), "Error in test case %v", n)
+	}
+}
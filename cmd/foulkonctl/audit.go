@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/Tecsisa/foulkon/client"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+)
+
+var auditActions = map[string]adminAction{
+	"list": {
+		description: "List audit events",
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			filter := listFlags(fs)
+			actionPrefix := fs.String("action-prefix", "", "Filter by action prefix, e.g. iam:CreateUser")
+			actor := fs.String("actor", "", "Filter by external ID of the user that made the change")
+			urnPrefix := fs.String("urn-prefix", "", "Filter by URN prefix of the changed resource")
+			from := fs.String("from", "", "Filter events since a date, in RFC 3339 format")
+			to := fs.String("to", "", "Filter events until a date, in RFC 3339 format")
+			return func(c *client.Client) (interface{}, error) {
+				f := filter()
+				f.ActionPrefix = *actionPrefix
+				f.Actor = *actor
+				f.UrnPrefix = *urnPrefix
+				var err error
+				if f.From, err = parseDate("from", *from); err != nil {
+					return nil, err
+				}
+				if f.To, err = parseDate("to", *to); err != nil {
+					return nil, err
+				}
+				events, total, err := c.ListAuditEvents(f)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.ListAuditEventsResponse{
+					Events: events,
+					Limit:  pageLimit(f),
+					Offset: f.Offset,
+					Total:  total,
+				}, nil
+			}
+		},
+	},
+}
+
+// PRIVATE HELPER METHODS
+
+// Parse the RFC 3339 date of a flag, zero if it isn't set
+func parseDate(flagName string, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid date %v in flag -%v, use RFC 3339 format", value, flagName)
+	}
+	return date, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/client"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+)
+
+var authorizeActions = map[string]adminAction{
+	"resources": {
+		description: "Return the resources the authenticated user is allowed to access with an action",
+		required:    []string{"action", "resources"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			action := fs.String("action", "", "Action, e.g. example:get")
+			resources := &listValue{}
+			fs.Var(resources, "resources", "Comma separated resource URNs")
+			context := mapValue{}
+			fs.Var(context, "context", "Comma separated key=value pairs of request context")
+			explain := fs.Bool("explain", false, "Explain the decision of each resource")
+			return func(c *client.Client) (interface{}, error) {
+				c = c.WithAuthzContext(context)
+				if *explain {
+					explanations, err := c.ExplainAuthorizedExternalResources(*action, *resources)
+					if err != nil {
+						return nil, err
+					}
+					return &internalhttp.AuthorizeResourcesResponse{Explanations: explanations}, nil
+				}
+				allowed, err := c.GetAuthorizedExternalResources(*action, *resources)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.AuthorizeResourcesResponse{ResourcesAllowed: allowed}, nil
+			}
+		},
+	},
+	"batch": {
+		description: "Return the resources the authenticated user is allowed to access for several actions",
+		required:    []string{"f"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			file := fs.String("f", "", "List of items with an action and its resources, in YAML or JSON format")
+			context := mapValue{}
+			fs.Var(context, "context", "Comma separated key=value pairs of request context")
+			return func(c *client.Client) (interface{}, error) {
+				items := []api.AuthorizeItem{}
+				if err := loadFile(*file, &items); err != nil {
+					return nil, err
+				}
+				results, err := c.WithAuthzContext(context).GetAuthorizedExternalResourcesBatch(items)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.AuthorizeResourcesBatchResponse{Items: results}, nil
+			}
+		},
+	},
+	"simulate": {
+		description: "Simulate the authorization of a user, optionally adding the statements of a policy file",
+		required:    []string{"id", "action", "resources"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			id := fs.String("id", "", "User external ID")
+			action := fs.String("action", "", "Action, e.g. example:get")
+			resources := &listValue{}
+			fs.Var(resources, "resources", "Comma separated resource URNs")
+			context := mapValue{}
+			fs.Var(context, "context", "Comma separated key=value pairs of request context")
+			file := fs.String("f", "", "Statements to simulate, in YAML or JSON format")
+			replace := fs.Bool("replace", false, "Use only the simulated statements instead of adding them to user ones")
+			return func(c *client.Client) (interface{}, error) {
+				var statements []api.Statement
+				if *file != "" {
+					var err error
+					if statements, err = loadStatements(*file); err != nil {
+						return nil, err
+					}
+				}
+				explanations, err := c.WithAuthzContext(context).SimulateAuthorizedExternalResources(*id, statements,
+					*replace, *action, *resources)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.SimulateAuthorizationResponse{Explanations: explanations}, nil
+			}
+		},
+	},
+	"principals": {
+		description: "List users and groups allowed to access a resource with an action",
+		required:    []string{"action", "urn"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			action := fs.String("action", "", "Action, e.g. example:get")
+			urn := fs.String("urn", "", "Resource URN")
+			return func(c *client.Client) (interface{}, error) {
+				return c.GetAuthorizedPrincipals(*action, *urn)
+			}
+		},
+	},
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/client"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+)
+
+var batchActions = map[string]adminAction{
+	"run": {
+		description: "Run user, group and policy operations in a single transaction",
+		required:    []string{"f"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			file := fs.String("f", "", "Operations, in YAML or JSON format")
+			return func(c *client.Client) (interface{}, error) {
+				operations := []api.BatchOperation{}
+				if err := loadFile(*file, &operations); err != nil {
+					return nil, err
+				}
+				results, err := c.ExecuteBatch(operations)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.BatchResponse{Results: results}, nil
+			}
+		},
+	},
+}
+
+// PRIVATE HELPER METHODS
+
+// Load a YAML or JSON file into v. Files with .json extension are parsed as JSON
+func loadFile(file string, v interface{}) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := unmarshal(data, strings.ToLower(filepath.Ext(file)) == ".json", v); err != nil {
+		return fmt.Errorf("Invalid file %v: %v", file, err)
+	}
+	return nil
+}
+
+// Load a patch from a file. A list of operations is a JSON Patch, and an object is a JSON Merge Patch
+func loadPatch(file string) (interface{}, error) {
+	var patch interface{}
+	if err := loadFile(file, &patch); err != nil {
+		return nil, err
+	}
+	if _, ok := patch.([]interface{}); !ok {
+		return patch, nil
+	}
+	operations := []client.JSONPatchOperation{}
+	if err := loadFile(file, &operations); err != nil {
+		return nil, err
+	}
+	return operations, nil
+}
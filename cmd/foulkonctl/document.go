@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/Tecsisa/foulkon/api"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// Path of resources declared without path
+	DEFAULT_PATH = "/"
+)
+
+// TYPE DEFINITIONS
+
+// iamDocument declares the IAM configuration managed by apply command. It's also used to hold the live
+// configuration retrieved from worker. Org is the default organization of resources declared without org
+type iamDocument struct {
+	Org            string                  `json:"org,omitempty"`
+	Users          []userDocument          `json:"users,omitempty"`
+	Groups         []groupDocument         `json:"groups,omitempty"`
+	Policies       []policyDocument        `json:"policies,omitempty"`
+	ProxyResources []proxyResourceDocument `json:"proxyResources,omitempty"`
+	OidcProviders  []oidcProviderDocument  `json:"oidcProviders,omitempty"`
+}
+
+type policyReference struct {
+	Org  string `json:"org,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type userDocument struct {
+	ExternalID string            `json:"externalId,omitempty"`
+	Path       string            `json:"path,omitempty"`
+	Policies   []policyReference `json:"policies,omitempty"`
+}
+
+// groupDocument declares a group, its direct members and the policies of the group organization attached to it
+type groupDocument struct {
+	Org      string   `json:"org,omitempty"`
+	Name     string   `json:"name,omitempty"`
+	Path     string   `json:"path,omitempty"`
+	Members  []string `json:"members,omitempty"`
+	Policies []string `json:"policies,omitempty"`
+}
+
+type policyDocument struct {
+	Org        string          `json:"org,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	Path       string          `json:"path,omitempty"`
+	Statements []api.Statement `json:"statements,omitempty"`
+}
+
+type proxyResourceDocument struct {
+	Org      string             `json:"org,omitempty"`
+	Name     string             `json:"name,omitempty"`
+	Path     string             `json:"path,omitempty"`
+	Resource api.ResourceEntity `json:"resource,omitempty"`
+}
+
+type oidcProviderDocument struct {
+	Name      string   `json:"name,omitempty"`
+	Path      string   `json:"path,omitempty"`
+	IssuerURL string   `json:"issuerUrl,omitempty"`
+	Clients   []string `json:"clients,omitempty"`
+}
+
+// loadDocument reads an IAM document from a JSON file, if file has .json extension, or from a YAML file.
+// Default org and path are set in resources that don't declare them
+func loadDocument(file string) (*iamDocument, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseDocument(data, strings.ToLower(filepath.Ext(file)) == ".json")
+	if err != nil {
+		return nil, fmt.Errorf("Invalid document %v: %v", file, err)
+	}
+	return doc, nil
+}
+
+// PRIVATE HELPER METHODS
+
+func parseDocument(data []byte, isJSON bool) (*iamDocument, error) {
+	doc := &iamDocument{}
+	if err := unmarshal(data, isJSON, doc); err != nil {
+		return nil, err
+	}
+	doc.setDefaults()
+	if err := doc.validate(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Decode YAML or JSON data. YAML is converted to JSON, so its fields are the same ones used by worker API
+func unmarshal(data []byte, isJSON bool, v interface{}) error {
+	if !isJSON {
+		var value interface{}
+		if err := yaml.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		var err error
+		if data, err = json.Marshal(yamlToJSON(value)); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Convert YAML maps, which may have keys of any type, to JSON objects
+func yamlToJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		object := map[string]interface{}{}
+		for key, item := range v {
+			object[fmt.Sprintf("%v", key)] = yamlToJSON(item)
+		}
+		return object
+	case []interface{}:
+		array := make([]interface{}, len(v))
+		for i, item := range v {
+			array[i] = yamlToJSON(item)
+		}
+		return array
+	default:
+		return v
+	}
+}
+
+func (doc *iamDocument) setDefaults() {
+	for i := range doc.Users {
+		user := &doc.Users[i]
+		user.Path = defaultValue(user.Path, DEFAULT_PATH)
+		for j := range user.Policies {
+			user.Policies[j].Org = defaultValue(user.Policies[j].Org, doc.Org)
+		}
+	}
+	for i := range doc.Groups {
+		doc.Groups[i].Org = defaultValue(doc.Groups[i].Org, doc.Org)
+		doc.Groups[i].Path = defaultValue(doc.Groups[i].Path, DEFAULT_PATH)
+	}
+	for i := range doc.Policies {
+		doc.Policies[i].Org = defaultValue(doc.Policies[i].Org, doc.Org)
+		doc.Policies[i].Path = defaultValue(doc.Policies[i].Path, DEFAULT_PATH)
+	}
+	for i := range doc.ProxyResources {
+		doc.ProxyResources[i].Org = defaultValue(doc.ProxyResources[i].Org, doc.Org)
+		doc.ProxyResources[i].Path = defaultValue(doc.ProxyResources[i].Path, DEFAULT_PATH)
+	}
+	for i := range doc.OidcProviders {
+		doc.OidcProviders[i].Path = defaultValue(doc.OidcProviders[i].Path, DEFAULT_PATH)
+	}
+}
+
+// Check that resources have identifiers and they aren't declared twice. Worker validates the rest of fields
+func (doc *iamDocument) validate() error {
+	declared := map[string]bool{}
+	check := func(kind string, key string, ids ...string) error {
+		for _, id := range ids {
+			if id == "" {
+				return fmt.Errorf("%v %v without org, name or externalId", kind, key)
+			}
+		}
+		if declared[kind+" "+key] {
+			return fmt.Errorf("%v %v is declared twice", kind, key)
+		}
+		declared[kind+" "+key] = true
+		return nil
+	}
+
+	for _, user := range doc.Users {
+		if err := check("user", user.ExternalID, user.ExternalID); err != nil {
+			return err
+		}
+		for _, policy := range user.Policies {
+			if policy.Org == "" || policy.Name == "" {
+				return fmt.Errorf("user %v has a policy without org or name", user.ExternalID)
+			}
+		}
+	}
+	for _, group := range doc.Groups {
+		if err := check("group", orgKey(group.Org, group.Name), group.Org, group.Name); err != nil {
+			return err
+		}
+	}
+	for _, policy := range doc.Policies {
+		if err := check("policy", orgKey(policy.Org, policy.Name), policy.Org, policy.Name); err != nil {
+			return err
+		}
+	}
+	for _, pr := range doc.ProxyResources {
+		if err := check("proxy resource", orgKey(pr.Org, pr.Name), pr.Org, pr.Name); err != nil {
+			return err
+		}
+	}
+	for _, provider := range doc.OidcProviders {
+		if err := check("OIDC provider", provider.Name, provider.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return the organizations of resources in document
+func (doc *iamDocument) orgs() []string {
+	orgs := []string{}
+	found := map[string]bool{}
+	add := func(org string) {
+		if !found[org] {
+			found[org] = true
+			orgs = append(orgs, org)
+		}
+	}
+	for _, group := range doc.Groups {
+		add(group.Org)
+	}
+	for _, policy := range doc.Policies {
+		add(policy.Org)
+	}
+	for _, pr := range doc.ProxyResources {
+		add(pr.Org)
+	}
+	return orgs
+}
+
+func defaultValue(value string, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// Key of a resource that belongs to an organization
+func orgKey(org string, name string) string {
+	return org + "/" + name
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDocument(t *testing.T) {
+	testcases := map[string]struct {
+		// Method args
+		data   string
+		isJSON bool
+		// Expected result
+		expectedDocument *iamDocument
+		expectedError    error
+	}{
+		"OkCaseYAML": {
+			data: `
+org: tecsisa
+users:
+  - externalId: user1
+    policies:
+      - name: policy1
+groups:
+  - name: group1
+    path: /example/
+    members: [user1]
+    policies: [policy1]
+policies:
+  - name: policy1
+    statements:
+      - effect: allow
+        actions: ["iam:*"]
+        resources: ["urn:*"]
+proxyResources:
+  - org: other
+    name: resource1
+    resource:
+      host: http://localhost:8001
+      path: /resource
+      method: GET
+      urn: urn:ews:example:instance1:resource/get
+      action: example:get
+oidcProviders:
+  - name: google
+    issuerUrl: https://accounts.google.com
+    clients: [client1]
+`,
+			expectedDocument: &iamDocument{
+				Org: "tecsisa",
+				Users: []userDocument{
+					{
+						ExternalID: "user1",
+						Path:       "/",
+						Policies: []policyReference{
+							{
+								Org:  "tecsisa",
+								Name: "policy1",
+							},
+						},
+					},
+				},
+				Groups: []groupDocument{
+					{
+						Org:      "tecsisa",
+						Name:     "group1",
+						Path:     "/example/",
+						Members:  []string{"user1"},
+						Policies: []string{"policy1"},
+					},
+				},
+				Policies: []policyDocument{
+					{
+						Org:  "tecsisa",
+						Name: "policy1",
+						Path: "/",
+						Statements: []api.Statement{
+							{
+								Effect:    "allow",
+								Actions:   []string{"iam:*"},
+								Resources: []string{"urn:*"},
+							},
+						},
+					},
+				},
+				ProxyResources: []proxyResourceDocument{
+					{
+						Org:  "other",
+						Name: "resource1",
+						Path: "/",
+						Resource: api.ResourceEntity{
+							Host:   "http://localhost:8001",
+							Path:   "/resource",
+							Method: "GET",
+							Urn:    "urn:ews:example:instance1:resource/get",
+							Action: "example:get",
+						},
+					},
+				},
+				OidcProviders: []oidcProviderDocument{
+					{
+						Name:      "google",
+						Path:      "/",
+						IssuerURL: "https://accounts.google.com",
+						Clients:   []string{"client1"},
+					},
+				},
+			},
+		},
+		"OkCaseJSON": {
+			data: `{
+	"users": [{"externalId": "user1", "path": "/example/"}]
+}`,
+			isJSON: true,
+			expectedDocument: &iamDocument{
+				Users: []userDocument{
+					{
+						ExternalID: "user1",
+						Path:       "/example/",
+					},
+				},
+			},
+		},
+		"ErrorCaseWithoutOrg": {
+			data: `
+groups:
+  - name: group1
+`,
+			expectedError: errors.New("group /group1 without org, name or externalId"),
+		},
+		"ErrorCaseUserPolicyWithoutOrg": {
+			data: `
+users:
+  - externalId: user1
+    policies:
+      - name: policy1
+`,
+			expectedError: errors.New("user user1 has a policy without org or name"),
+		},
+		"ErrorCaseDeclaredTwice": {
+			data: `
+users:
+  - externalId: user1
+  - externalId: user1
+`,
+			expectedError: errors.New("user user1 is declared twice"),
+		},
+	}
+
+	for n, test := range testcases {
+		doc, err := parseDocument([]byte(test.data), test.isJSON)
+		if test.expectedError != nil {
+			assert.Equal(t, test.expectedError, err, "Error in test case %v", n)
+		} else {
+			assert.Nil(t, err, "Error in test case %v", n)
+			assert.Equal(t, test.expectedDocument, doc, "Error in test case %v", n)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/Tecsisa/foulkon/client"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+)
+
+var groupActions = map[string]adminAction{
+	"list": {
+		description: "List groups, of every organization if -org isn't set",
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			filter := listFlags(fs)
+			return func(c *client.Client) (interface{}, error) {
+				f := filter()
+				f.Org = *org
+				groups, total, err := c.ListGroups(f)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.ListAllGroupsResponse{
+					Groups: groups,
+					Limit:  pageLimit(f),
+					Offset: f.Offset,
+					Total:  total,
+				}, nil
+			}
+		},
+	},
+	"get": {
+		description: "Get a group",
+		required:    []string{"org", "name"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Group name")
+			return func(c *client.Client) (interface{}, error) {
+				return c.GetGroupByName(*org, *name)
+			}
+		},
+	},
+	"create": {
+		description: "Create a group",
+		required:    []string{"org", "name"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Group name")
+			path := fs.String("path", DEFAULT_PATH, "Group path")
+			return func(c *client.Client) (interface{}, error) {
+				return c.AddGroup(*org, *name, *path)
+			}
+		},
+	},
+	"update": {
+		description: "Update the name or path of a group",
+		required:    []string{"org", "name"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Group name")
+			newName := fs.String("new-name", "", "New group name")
+			newPath := fs.String("new-path", "", "New group path")
+			return func(c *client.Client) (interface{}, error) {
+				// Fields not set are kept, so they are sent in a merge patch
+				patch := internalhttp.UpdateGroupRequest{
+					Name: *newName,
+					Path: *newPath,
+				}
+				return c.PatchGroup(*org, *name, patch)
+			}
+		},
+	},
+	"patch": {
+		description: "Update fields of a group with a JSON Patch or a JSON Merge Patch file",
+		required:    []string{"org", "name", "f"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Group name")
+			file := fs.String("f", "", "Patch, a list of JSON Patch operations or a JSON Merge Patch object, in YAML or JSON format")
+			return func(c *client.Client) (interface{}, error) {
+				patch, err := loadPatch(*file)
+				if err != nil {
+					return nil, err
+				}
+				return c.PatchGroup(*org, *name, patch)
+			}
+		},
+	},
+	"delete": {
+		description: "Delete a group",
+		required:    []string{"org", "name"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Group name")
+			return func(c *client.Client) (interface{}, error) {
+				return nil, c.RemoveGroup(*org, *name)
+			}
+		},
+	},
+	"subgroups": {
+		description: "List subgroups of a group",
+		required:    []string{"org", "name"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Group name")
+			filter := listFlags(fs)
+			return func(c *client.Client) (interface{}, error) {
+				f := filter()
+				f.Org = *org
+				f.GroupName = *name
+				subgroups, total, err := c.ListSubgroups(f)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.ListSubgroupsResponse{
+					Subgroups: subgroups,
+					Limit:     pageLimit(f),
+					Offset:    f.Offset,
+					Total:     total,
+				}, nil
+			}
+		},
+	},
+	"add-subgroup": {
+		description: "Add a subgroup to a group",
+		required:    []string{"org", "name", "subgroup"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Group name")
+			subgroup := fs.String("subgroup", "", "Subgroup name")
+			return func(c *client.Client) (interface{}, error) {
+				return nil, c.AddSubgroup(*org, *name, *subgroup)
+			}
+		},
+	},
+	"remove-subgroup": {
+		description: "Remove a subgroup from a group",
+		required:    []string{"org", "name", "subgroup"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Group name")
+			subgroup := fs.String("subgroup", "", "Subgroup name")
+			return func(c *client.Client) (interface{}, error) {
+				return nil, c.RemoveSubgroup(*org, *name, *subgroup)
+			}
+		},
+	},
+}
+
+var memberActions = map[string]adminAction{
+	"list": {
+		description: "List members of a group, including members of its subgroups",
+		required:    []string{"org", "group"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			group := fs.String("group", "", "Group name")
+			filter := listFlags(fs)
+			return func(c *client.Client) (interface{}, error) {
+				f := filter()
+				f.Org = *org
+				f.GroupName = *group
+				members, total, err := c.ListMembers(f)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.ListMembersResponse{
+					Members: members,
+					Limit:   pageLimit(f),
+					Offset:  f.Offset,
+					Total:   total,
+				}, nil
+			}
+		},
+	},
+	"add": {
+		description: "Add a user to a group",
+		required:    []string{"org", "group", "id"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			group := fs.String("group", "", "Group name")
+			id := fs.String("id", "", "User external ID")
+			return func(c *client.Client) (interface{}, error) {
+				return nil, c.AddMember(*id, *group, *org)
+			}
+		},
+	},
+	"remove": {
+		description: "Remove a user from a group",
+		required:    []string{"org", "group", "id"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			group := fs.String("group", "", "Group name")
+			id := fs.String("id", "", "User external ID")
+			return func(c *client.Client) (interface{}, error) {
+				return nil, c.RemoveMember(*id, *group, *org)
+			}
+		},
+	},
+}
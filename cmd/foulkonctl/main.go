@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Tecsisa/foulkon/client"
+)
+
+const (
+	// Environment variables with default values of connection flags
+	WORKER_URL_ENV      = "FOULKON_WORKER_URL"
+	WORKER_USER_ENV     = "FOULKON_USER"
+	WORKER_PASSWORD_ENV = "FOULKON_PASSWORD"
+	WORKER_TOKEN_ENV    = "FOULKON_TOKEN"
+
+	DEFAULT_WORKER_URL = "http://localhost:8000"
+)
+
+// command runs a foulkonctl subcommand with its arguments
+type command struct {
+	description string
+	run         func(args []string) error
+}
+
+var commands = map[string]command{
+	"plan": {
+		description: "Print the changes needed to apply an IAM document",
+		run:         runPlan,
+	},
+	"apply": {
+		description: "Apply an IAM document after confirmation",
+		run:         runApply,
+	},
+	"users": {
+		description: "Manage users",
+		run:         adminCommand("users", userActions),
+	},
+	"groups": {
+		description: "Manage groups and subgroups",
+		run:         adminCommand("groups", groupActions),
+	},
+	"members": {
+		description: "Manage group members",
+		run:         adminCommand("members", memberActions),
+	},
+	"policies": {
+		description: "Manage policies and their versions",
+		run:         adminCommand("policies", policyActions),
+	},
+	"attachments": {
+		description: "Manage policies attached to users and groups",
+		run:         adminCommand("attachments", attachmentActions),
+	},
+	"proxy-resources": {
+		description: "Manage proxy resources",
+		run:         adminCommand("proxy-resources", proxyResourceActions),
+	},
+	"oidc-providers": {
+		description: "Manage OIDC providers",
+		run:         adminCommand("oidc-providers", oidcProviderActions),
+	},
+	"authorize": {
+		description: "Check authorizations",
+		run:         adminCommand("authorize", authorizeActions),
+	},
+	"audit": {
+		description: "List audit events",
+		run:         adminCommand("audit", auditActions),
+	},
+	"batch": {
+		description: "Run user, group and policy operations in a single transaction",
+		run:         adminCommand("batch", batchActions),
+	},
+	"worker": {
+		description: "Get worker configuration and manage its authorization cache",
+		run:         adminCommand("worker", workerActions),
+	},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %v\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+func runPlan(args []string) error {
+	fs, connect := newFlagSet("plan")
+	file := fs.String("f", "", "IAM document, in YAML or JSON format")
+	prune := fs.Bool("prune", false, "Delete resources not declared in the document")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	_, plan, err := makePlan(connect(), *file, *prune)
+	if err != nil {
+		return err
+	}
+	printPlan(os.Stdout, plan)
+	return nil
+}
+
+func runApply(args []string) error {
+	fs, connect := newFlagSet("apply")
+	file := fs.String("f", "", "IAM document, in YAML or JSON format")
+	prune := fs.Bool("prune", false, "Delete resources not declared in the document")
+	autoApprove := fs.Bool("auto-approve", false, "Apply the plan without confirmation")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	c, plan, err := makePlan(connect(), *file, *prune)
+	if err != nil {
+		return err
+	}
+	printPlan(os.Stdout, plan)
+	if len(plan) < 1 {
+		return nil
+	}
+	if !*autoApprove && !confirm(os.Stdin, os.Stdout) {
+		fmt.Println("Apply cancelled")
+		return nil
+	}
+	if err := applyPlan(c, plan); err != nil {
+		return err
+	}
+	fmt.Println("Apply complete")
+	return nil
+}
+
+// PRIVATE HELPER METHODS
+
+// Create a flag set with the worker connection flags. Returned function creates the client once flags are parsed
+func newFlagSet(name string) (*flag.FlagSet, func() *client.Client) {
+	fs := flag.NewFlagSet("foulkonctl "+name, flag.ExitOnError)
+	workerURL := fs.String("url", getEnv(WORKER_URL_ENV, DEFAULT_WORKER_URL), "Worker URL")
+	user := fs.String("user", os.Getenv(WORKER_USER_ENV), "Admin user for basic authentication")
+	password := fs.String("password", os.Getenv(WORKER_PASSWORD_ENV), "Admin password for basic authentication")
+	token := fs.String("token", os.Getenv(WORKER_TOKEN_ENV), "Bearer token, used instead of basic authentication")
+	return fs, func() *client.Client {
+		return newClient(*workerURL, *user, *password, *token)
+	}
+}
+
+// Create a worker client. Token is used instead of admin credentials if both of them are set
+func newClient(workerURL string, user string, password string, token string) *client.Client {
+	config := client.Config{
+		URL:   workerURL,
+		Token: token,
+	}
+	if token == "" {
+		config.AdminUser = user
+		config.AdminPassword = password
+	}
+	return client.NewClient(config)
+}
+
+// Load the document and compute the plan against the live configuration
+func makePlan(c *client.Client, file string, prune bool) (*client.Client, []planChange, error) {
+	if file == "" {
+		return nil, nil, fmt.Errorf("Document file is required, use -f flag")
+	}
+	desired, err := loadDocument(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	live, err := fetchState(c, desired)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to retrieve configuration from worker: %v", err)
+	}
+	return c, computePlan(desired, live, prune), nil
+}
+
+// Ask user to confirm the changes
+func confirm(r io.Reader, w io.Writer) bool {
+	fmt.Fprint(w, "Do you want to apply these changes? Only 'yes' will be accepted: ")
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	return strings.TrimSpace(answer) == "yes"
+}
+
+func getEnv(key string, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+func usage() {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(os.Stderr, "Usage: foulkonctl <command> [<action>] [flags]\n\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-16v %v\n", name, commands[name].description)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/Tecsisa/foulkon/client"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+)
+
+var oidcProviderActions = map[string]adminAction{
+	"list": {
+		description: "List OIDC providers",
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			filter := listFlags(fs)
+			return func(c *client.Client) (interface{}, error) {
+				f := filter()
+				names, total, err := c.ListOidcProviders(f)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.ListOidcProvidersResponse{
+					Providers: names,
+					Limit:     pageLimit(f),
+					Offset:    f.Offset,
+					Total:     total,
+				}, nil
+			}
+		},
+	},
+	"get": {
+		description: "Get an OIDC provider",
+		required:    []string{"name"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			name := fs.String("name", "", "OIDC provider name")
+			return func(c *client.Client) (interface{}, error) {
+				return c.GetOidcProviderByName(*name)
+			}
+		},
+	},
+	"create": {
+		description: "Create an OIDC provider",
+		required:    []string{"name", "issuer-url", "clients"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			name := fs.String("name", "", "OIDC provider name")
+			path := fs.String("path", DEFAULT_PATH, "OIDC provider path")
+			issuerURL := fs.String("issuer-url", "", "Issuer URL")
+			clients := &listValue{}
+			fs.Var(clients, "clients", "Comma separated client IDs")
+			return func(c *client.Client) (interface{}, error) {
+				return c.AddOidcProvider(*name, *path, *issuerURL, *clients)
+			}
+		},
+	},
+	"update": {
+		description: "Update the name, path, issuer URL or clients of an OIDC provider",
+		required:    []string{"name"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			name := fs.String("name", "", "OIDC provider name")
+			newName := fs.String("new-name", "", "New OIDC provider name")
+			newPath := fs.String("new-path", "", "New OIDC provider path")
+			issuerURL := fs.String("issuer-url", "", "New issuer URL")
+			clients := &listValue{}
+			fs.Var(clients, "clients", "New comma separated client IDs")
+			return func(c *client.Client) (interface{}, error) {
+				// Worker replaces every field, so unchanged ones are taken from current provider
+				current, err := c.GetOidcProviderByName(*name)
+				if err != nil {
+					return nil, err
+				}
+				oidcClients := []string(*clients)
+				if len(oidcClients) < 1 {
+					for _, oidcClient := range current.OidcClients {
+						oidcClients = append(oidcClients, oidcClient.Name)
+					}
+				}
+				return c.UpdateOidcProvider(*name, defaultValue(*newName, current.Name), defaultValue(*newPath, current.Path),
+					defaultValue(*issuerURL, current.IssuerURL), oidcClients)
+			}
+		},
+	},
+	"delete": {
+		description: "Delete an OIDC provider",
+		required:    []string{"name"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			name := fs.String("name", "", "OIDC provider name")
+			return func(c *client.Client) (interface{}, error) {
+				return nil, c.RemoveOidcProvider(*name)
+			}
+		},
+	},
+}
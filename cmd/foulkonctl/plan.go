@@ -0,0 +1,475 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/client"
+)
+
+const (
+	// Types of changes
+	CHANGE_CREATE = "create"
+	CHANGE_UPDATE = "update"
+	CHANGE_DELETE = "delete"
+)
+
+// planChange is a change needed to make the live configuration match the desired one. Changes of users, groups,
+// policies and their relations are applied with a batch operation, the rest of them with a worker request
+type planChange struct {
+	Type        string
+	Description string
+
+	Operation *api.BatchOperation
+
+	// Resource changed with a worker request. Only its identifiers are set if it's deleted
+	OidcProvider  *oidcProviderDocument
+	ProxyResource *proxyResourceDocument
+}
+
+// computePlan returns the changes needed to make the live configuration match the desired one, in the order they
+// must be applied. Batch operations go first, so they are applied together before the changes that need a worker
+// request of their own. Resources not declared in desired document are only deleted if prune is true, and groups,
+// policies and proxy resources are only deleted in the organizations of desired document
+func computePlan(desired *iamDocument, live *iamDocument, prune bool) []planChange {
+	plan := []planChange{}
+	requests := []planChange{}
+
+	// OIDC providers
+	liveOidcProviders := map[string]oidcProviderDocument{}
+	for _, provider := range live.OidcProviders {
+		liveOidcProviders[provider.Name] = provider
+	}
+	for _, provider := range desired.OidcProviders {
+		current, ok := liveOidcProviders[provider.Name]
+		if ok && current.Path == provider.Path && current.IssuerURL == provider.IssuerURL &&
+			equalSets(current.Clients, provider.Clients) {
+			continue
+		}
+		// Copy loop variable, so each change references its own provider
+		provider := provider
+		change := planChange{
+			Type:         CHANGE_CREATE,
+			Description:  fmt.Sprintf("OIDC provider %v", provider.Name),
+			OidcProvider: &provider,
+		}
+		if ok {
+			change.Type = CHANGE_UPDATE
+		}
+		requests = append(requests, change)
+	}
+
+	// Policies
+	livePolicies := map[string]policyDocument{}
+	for _, policy := range live.Policies {
+		livePolicies[orgKey(policy.Org, policy.Name)] = policy
+	}
+	for _, policy := range desired.Policies {
+		current, ok := livePolicies[orgKey(policy.Org, policy.Name)]
+		if ok && current.Path == policy.Path && equalStatements(current.Statements, policy.Statements) {
+			continue
+		}
+		operation := &api.BatchOperation{
+			Action:     api.POLICY_ACTION_CREATE_POLICY,
+			Org:        policy.Org,
+			Name:       policy.Name,
+			Path:       policy.Path,
+			Statements: policy.Statements,
+		}
+		if ok {
+			operation.Action = api.POLICY_ACTION_UPDATE_POLICY
+			operation.Path = ""
+			operation.NewName = policy.Name
+			operation.NewPath = policy.Path
+		}
+		plan = append(plan, operationChange(ok, fmt.Sprintf("policy %v", orgKey(policy.Org, policy.Name)), operation))
+	}
+
+	// Users
+	liveUsers := map[string]userDocument{}
+	for _, user := range live.Users {
+		liveUsers[user.ExternalID] = user
+	}
+	for _, user := range desired.Users {
+		current, ok := liveUsers[user.ExternalID]
+		if ok && current.Path == user.Path {
+			continue
+		}
+		operation := &api.BatchOperation{
+			Action:     api.USER_ACTION_CREATE_USER,
+			ExternalID: user.ExternalID,
+			Path:       user.Path,
+		}
+		if ok {
+			operation.Action = api.USER_ACTION_UPDATE_USER
+			operation.Path = ""
+			operation.NewPath = user.Path
+		}
+		plan = append(plan, operationChange(ok, fmt.Sprintf("user %v", user.ExternalID), operation))
+	}
+
+	// Groups
+	liveGroups := map[string]groupDocument{}
+	for _, group := range live.Groups {
+		liveGroups[orgKey(group.Org, group.Name)] = group
+	}
+	for _, group := range desired.Groups {
+		current, ok := liveGroups[orgKey(group.Org, group.Name)]
+		if ok && current.Path == group.Path {
+			continue
+		}
+		operation := &api.BatchOperation{
+			Action: api.GROUP_ACTION_CREATE_GROUP,
+			Org:    group.Org,
+			Name:   group.Name,
+			Path:   group.Path,
+		}
+		if ok {
+			operation.Action = api.GROUP_ACTION_UPDATE_GROUP
+			operation.Path = ""
+			operation.NewName = group.Name
+			operation.NewPath = group.Path
+		}
+		plan = append(plan, operationChange(ok, fmt.Sprintf("group %v", orgKey(group.Org, group.Name)), operation))
+	}
+
+	// User policies
+	for _, user := range desired.Users {
+		references := map[string]policyReference{}
+		desiredPolicies := []string{}
+		for _, policy := range user.Policies {
+			references[orgKey(policy.Org, policy.Name)] = policy
+			desiredPolicies = append(desiredPolicies, orgKey(policy.Org, policy.Name))
+		}
+		currentPolicies := []string{}
+		for _, policy := range liveUsers[user.ExternalID].Policies {
+			references[orgKey(policy.Org, policy.Name)] = policy
+			currentPolicies = append(currentPolicies, orgKey(policy.Org, policy.Name))
+		}
+		added, removed := diffSets(currentPolicies, desiredPolicies)
+		for _, policy := range append(added, removed...) {
+			plan = append(plan, relationChange(contains(added, policy),
+				fmt.Sprintf("policy %v attached to user %v", policy, user.ExternalID),
+				&api.BatchOperation{
+					Action:     api.USER_ACTION_ATTACH_USER_POLICY,
+					ExternalID: user.ExternalID,
+					Org:        references[policy].Org,
+					Policy:     references[policy].Name,
+				}, api.USER_ACTION_DETACH_USER_POLICY))
+		}
+	}
+
+	// Group members and policies
+	for _, group := range desired.Groups {
+		current := liveGroups[orgKey(group.Org, group.Name)]
+		added, removed := diffSets(current.Members, group.Members)
+		for _, member := range append(added, removed...) {
+			plan = append(plan, relationChange(contains(added, member),
+				fmt.Sprintf("member %v of group %v", member, orgKey(group.Org, group.Name)),
+				&api.BatchOperation{
+					Action:     api.GROUP_ACTION_ADD_MEMBER,
+					ExternalID: member,
+					Org:        group.Org,
+					Group:      group.Name,
+				}, api.GROUP_ACTION_REMOVE_MEMBER))
+		}
+		added, removed = diffSets(current.Policies, group.Policies)
+		for _, policy := range append(added, removed...) {
+			plan = append(plan, relationChange(contains(added, policy),
+				fmt.Sprintf("policy %v attached to group %v", orgKey(group.Org, policy), orgKey(group.Org, group.Name)),
+				&api.BatchOperation{
+					Action: api.GROUP_ACTION_ATTACH_GROUP_POLICY,
+					Org:    group.Org,
+					Group:  group.Name,
+					Policy: policy,
+				}, api.GROUP_ACTION_DETACH_GROUP_POLICY))
+		}
+	}
+
+	// Proxy resources
+	liveProxyResources := map[string]proxyResourceDocument{}
+	for _, pr := range live.ProxyResources {
+		liveProxyResources[orgKey(pr.Org, pr.Name)] = pr
+	}
+	for _, pr := range desired.ProxyResources {
+		current, ok := liveProxyResources[orgKey(pr.Org, pr.Name)]
+		if ok && current.Path == pr.Path && current.Resource == pr.Resource {
+			continue
+		}
+		// Copy loop variable, so each change references its own proxy resource
+		pr := pr
+		change := planChange{
+			Type:          CHANGE_CREATE,
+			Description:   fmt.Sprintf("proxy resource %v", orgKey(pr.Org, pr.Name)),
+			ProxyResource: &pr,
+		}
+		if ok {
+			change.Type = CHANGE_UPDATE
+		}
+		requests = append(requests, change)
+	}
+
+	if !prune {
+		return append(plan, requests...)
+	}
+
+	// Unmanaged resources. Relations of deleted resources are removed by worker
+	orgs := map[string]bool{}
+	for _, org := range desired.orgs() {
+		orgs[org] = true
+	}
+	declared := map[string]bool{}
+	for _, pr := range desired.ProxyResources {
+		declared[orgKey(pr.Org, pr.Name)] = true
+	}
+	for _, pr := range live.ProxyResources {
+		if !declared[orgKey(pr.Org, pr.Name)] {
+			requests = append(requests, planChange{
+				Type:          CHANGE_DELETE,
+				Description:   fmt.Sprintf("proxy resource %v", orgKey(pr.Org, pr.Name)),
+				ProxyResource: &proxyResourceDocument{Org: pr.Org, Name: pr.Name},
+			})
+		}
+	}
+	declared = map[string]bool{}
+	for _, group := range desired.Groups {
+		declared[orgKey(group.Org, group.Name)] = true
+	}
+	for _, group := range live.Groups {
+		if orgs[group.Org] && !declared[orgKey(group.Org, group.Name)] {
+			plan = append(plan, operationDeleteChange(fmt.Sprintf("group %v", orgKey(group.Org, group.Name)),
+				&api.BatchOperation{
+					Action: api.GROUP_ACTION_DELETE_GROUP,
+					Org:    group.Org,
+					Name:   group.Name,
+				}))
+		}
+	}
+	declared = map[string]bool{}
+	for _, user := range desired.Users {
+		declared[user.ExternalID] = true
+	}
+	for _, user := range live.Users {
+		if !declared[user.ExternalID] {
+			plan = append(plan, operationDeleteChange(fmt.Sprintf("user %v", user.ExternalID),
+				&api.BatchOperation{
+					Action:     api.USER_ACTION_DELETE_USER,
+					ExternalID: user.ExternalID,
+				}))
+		}
+	}
+	declared = map[string]bool{}
+	for _, policy := range desired.Policies {
+		declared[orgKey(policy.Org, policy.Name)] = true
+	}
+	for _, policy := range live.Policies {
+		if orgs[policy.Org] && !declared[orgKey(policy.Org, policy.Name)] {
+			plan = append(plan, operationDeleteChange(fmt.Sprintf("policy %v", orgKey(policy.Org, policy.Name)),
+				&api.BatchOperation{
+					Action: api.POLICY_ACTION_DELETE_POLICY,
+					Org:    policy.Org,
+					Name:   policy.Name,
+				}))
+		}
+	}
+	declared = map[string]bool{}
+	for _, provider := range desired.OidcProviders {
+		declared[provider.Name] = true
+	}
+	for _, provider := range live.OidcProviders {
+		if !declared[provider.Name] {
+			requests = append(requests, planChange{
+				Type:         CHANGE_DELETE,
+				Description:  fmt.Sprintf("OIDC provider %v", provider.Name),
+				OidcProvider: &oidcProviderDocument{Name: provider.Name},
+			})
+		}
+	}
+
+	return append(plan, requests...)
+}
+
+// printPlan writes a line for each change, and a summary of the number of changes of each type. If changes need
+// several worker requests, it warns that they aren't applied atomically
+func printPlan(w io.Writer, plan []planChange) {
+	symbols := map[string]string{
+		CHANGE_CREATE: "+",
+		CHANGE_UPDATE: "~",
+		CHANGE_DELETE: "-",
+	}
+	count := map[string]int{}
+	for _, change := range plan {
+		fmt.Fprintf(w, "%v %v %v\n", symbols[change.Type], change.Type, change.Description)
+		count[change.Type]++
+	}
+	fmt.Fprintf(w, "Plan: %v to create, %v to update, %v to delete\n",
+		count[CHANGE_CREATE], count[CHANGE_UPDATE], count[CHANGE_DELETE])
+	if requests := countRequests(plan); requests > 1 {
+		fmt.Fprintf(w, "Warning: changes are applied in %v requests, not atomically. If a request fails, "+
+			"changes of previous requests are kept and the rest of them aren't applied\n", requests)
+	}
+}
+
+// applyPlan applies the changes in order. Consecutive batch operations are sent together, so they are applied in
+// the same transaction, up to the maximum number of operations allowed in a batch
+func applyPlan(c *client.Client, plan []planChange) error {
+	operations := []api.BatchOperation{}
+	flush := func() error {
+		if len(operations) < 1 {
+			return nil
+		}
+		_, err := c.ExecuteBatch(operations)
+		operations = []api.BatchOperation{}
+		return err
+	}
+
+	for _, change := range plan {
+		if change.Operation != nil {
+			operations = append(operations, *change.Operation)
+			if len(operations) == api.BATCH_MAX_OPERATIONS {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		if err := applyChange(c, change); err != nil {
+			return fmt.Errorf("Unable to %v %v: %v", change.Type, change.Description, err)
+		}
+	}
+	return flush()
+}
+
+// PRIVATE HELPER METHODS
+
+// Apply a change of a resource that isn't changed with a batch operation
+func applyChange(c *client.Client, change planChange) error {
+	var err error
+	switch {
+	case change.OidcProvider != nil:
+		provider := change.OidcProvider
+		switch change.Type {
+		case CHANGE_CREATE:
+			_, err = c.AddOidcProvider(provider.Name, provider.Path, provider.IssuerURL, provider.Clients)
+		case CHANGE_UPDATE:
+			_, err = c.UpdateOidcProvider(provider.Name, provider.Name, provider.Path, provider.IssuerURL, provider.Clients)
+		case CHANGE_DELETE:
+			err = c.RemoveOidcProvider(provider.Name)
+		}
+	case change.ProxyResource != nil:
+		pr := change.ProxyResource
+		switch change.Type {
+		case CHANGE_CREATE:
+			_, err = c.AddProxyResource(pr.Name, pr.Org, pr.Path, pr.Resource)
+		case CHANGE_UPDATE:
+			_, err = c.UpdateProxyResource(pr.Org, pr.Name, pr.Name, pr.Path, pr.Resource)
+		case CHANGE_DELETE:
+			err = c.RemoveProxyResource(pr.Org, pr.Name)
+		}
+	}
+	return err
+}
+
+// Return the number of worker requests needed to apply the plan, grouping operations like applyPlan does
+func countRequests(plan []planChange) int {
+	requests := 0
+	operations := 0
+	for _, change := range plan {
+		if change.Operation != nil {
+			if operations == 0 {
+				requests++
+			}
+			operations++
+			if operations == api.BATCH_MAX_OPERATIONS {
+				operations = 0
+			}
+			continue
+		}
+		operations = 0
+		requests++
+	}
+	return requests
+}
+
+// Change of a resource applied with a batch operation
+func operationChange(exists bool, description string, operation *api.BatchOperation) planChange {
+	change := planChange{
+		Type:        CHANGE_CREATE,
+		Description: description,
+		Operation:   operation,
+	}
+	if exists {
+		change.Type = CHANGE_UPDATE
+	}
+	return change
+}
+
+func operationDeleteChange(description string, operation *api.BatchOperation) planChange {
+	return planChange{
+		Type:        CHANGE_DELETE,
+		Description: description,
+		Operation:   operation,
+	}
+}
+
+// Change of a relation. Operation adds the relation, and its action is replaced by removeAction if relation must be
+// removed
+func relationChange(add bool, description string, operation *api.BatchOperation, removeAction string) planChange {
+	change := planChange{
+		Type:        CHANGE_CREATE,
+		Description: description,
+		Operation:   operation,
+	}
+	if !add {
+		change.Type = CHANGE_DELETE
+		operation.Action = removeAction
+	}
+	return change
+}
+
+// Return the sorted values of target that aren't in source (added), and the ones of source that aren't in
+// target (removed)
+func diffSets(source []string, target []string) ([]string, []string) {
+	added := []string{}
+	for _, value := range target {
+		if !contains(source, value) && !contains(added, value) {
+			added = append(added, value)
+		}
+	}
+	removed := []string{}
+	for _, value := range source {
+		if !contains(target, value) && !contains(removed, value) {
+			removed = append(removed, value)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+func equalSets(a []string, b []string) bool {
+	added, removed := diffSets(a, b)
+	return len(added) == 0 && len(removed) == 0
+}
+
+// Compare statements by their JSON representation, so empty and nil fields are equal
+func equalStatements(a []api.Statement, b []api.Statement) bool {
+	jsonA, errA := json.Marshal(append([]api.Statement{}, a...))
+	jsonB, errB := json.Marshal(append([]api.Statement{}, b...))
+	return errA == nil && errB == nil && string(jsonA) == string(jsonB)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputePlan(t *testing.T) {
+	statements := []api.Statement{
+		{
+			Effect:    "allow",
+			Actions:   []string{"iam:*"},
+			Resources: []string{"urn:*"},
+		},
+	}
+	testcases := map[string]struct {
+		// Method args
+		desired *iamDocument
+		live    *iamDocument
+		prune   bool
+		// Expected result
+		expectedPlan []planChange
+	}{
+		"OkCaseNoChanges": {
+			desired: &iamDocument{
+				Users: []userDocument{
+					{
+						ExternalID: "user1",
+						Path:       "/",
+					},
+				},
+				Policies: []policyDocument{
+					{
+						Org:        "org1",
+						Name:       "policy1",
+						Path:       "/",
+						Statements: statements,
+					},
+				},
+			},
+			live: &iamDocument{
+				Users: []userDocument{
+					{
+						ExternalID: "user1",
+						Path:       "/",
+					},
+					{
+						ExternalID: "user2",
+					},
+				},
+				Policies: []policyDocument{
+					{
+						Org:        "org1",
+						Name:       "policy1",
+						Path:       "/",
+						Statements: statements,
+					},
+				},
+			},
+			expectedPlan: []planChange{},
+		},
+		"OkCaseCreateAndUpdate": {
+			desired: &iamDocument{
+				Users: []userDocument{
+					{
+						ExternalID: "user1",
+						Path:       "/new/",
+					},
+					{
+						ExternalID: "user2",
+						Path:       "/",
+						Policies: []policyReference{
+							{
+								Org:  "org1",
+								Name: "policy1",
+							},
+						},
+					},
+				},
+				Groups: []groupDocument{
+					{
+						Org:      "org1",
+						Name:     "group1",
+						Path:     "/",
+						Members:  []string{"user1", "user2"},
+						Policies: []string{"policy1"},
+					},
+				},
+				Policies: []policyDocument{
+					{
+						Org:        "org1",
+						Name:       "policy1",
+						Path:       "/",
+						Statements: statements,
+					},
+				},
+				ProxyResources: []proxyResourceDocument{
+					{
+						Org:  "org1",
+						Name: "resource1",
+						Path: "/",
+						Resource: api.ResourceEntity{
+							Host:   "http://localhost:8001",
+							Path:   "/resource",
+							Method: "GET",
+							Urn:    "urn:ews:example:instance1:resource/get",
+							Action: "example:get",
+						},
+					},
+				},
+			},
+			live: &iamDocument{
+				Users: []userDocument{
+					{
+						ExternalID: "user1",
+						Path:       "/",
+					},
+				},
+				Groups: []groupDocument{
+					{
+						Org:     "org1",
+						Name:    "group1",
+						Path:    "/",
+						Members: []string{"user1", "user3"},
+					},
+				},
+			},
+			expectedPlan: []planChange{
+				{
+					Type:        CHANGE_CREATE,
+					Description: "policy org1/policy1",
+					Operation: &api.BatchOperation{
+						Action:     api.POLICY_ACTION_CREATE_POLICY,
+						Org:        "org1",
+						Name:       "policy1",
+						Path:       "/",
+						Statements: statements,
+					},
+				},
+				{
+					Type:        CHANGE_UPDATE,
+					Description: "user user1",
+					Operation: &api.BatchOperation{
+						Action:     api.USER_ACTION_UPDATE_USER,
+						ExternalID: "user1",
+						NewPath:    "/new/",
+					},
+				},
+				{
+					Type:        CHANGE_CREATE,
+					Description: "user user2",
+					Operation: &api.BatchOperation{
+						Action:     api.USER_ACTION_CREATE_USER,
+						ExternalID: "user2",
+						Path:       "/",
+					},
+				},
+				{
+					Type:        CHANGE_CREATE,
+					Description: "policy org1/policy1 attached to user user2",
+					Operation: &api.BatchOperation{
+						Action:     api.USER_ACTION_ATTACH_USER_POLICY,
+						ExternalID: "user2",
+						Org:        "org1",
+						Policy:     "policy1",
+					},
+				},
+				{
+					Type:        CHANGE_CREATE,
+					Description: "member user2 of group org1/group1",
+					Operation: &api.BatchOperation{
+						Action:     api.GROUP_ACTION_ADD_MEMBER,
+						ExternalID: "user2",
+						Org:        "org1",
+						Group:      "group1",
+					},
+				},
+				{
+					Type:        CHANGE_DELETE,
+					Description: "member user3 of group org1/group1",
+					Operation: &api.BatchOperation{
+						Action:     api.GROUP_ACTION_REMOVE_MEMBER,
+						ExternalID: "user3",
+						Org:        "org1",
+						Group:      "group1",
+					},
+				},
+				{
+					Type:        CHANGE_CREATE,
+					Description: "policy org1/policy1 attached to group org1/group1",
+					Operation: &api.BatchOperation{
+						Action: api.GROUP_ACTION_ATTACH_GROUP_POLICY,
+						Org:    "org1",
+						Group:  "group1",
+						Policy: "policy1",
+					},
+				},
+				{
+					Type:        CHANGE_CREATE,
+					Description: "proxy resource org1/resource1",
+					ProxyResource: &proxyResourceDocument{
+						Org:  "org1",
+						Name: "resource1",
+						Path: "/",
+						Resource: api.ResourceEntity{
+							Host:   "http://localhost:8001",
+							Path:   "/resource",
+							Method: "GET",
+							Urn:    "urn:ews:example:instance1:resource/get",
+							Action: "example:get",
+						},
+					},
+				},
+			},
+		},
+		"OkCasePrune": {
+			desired: &iamDocument{
+				Users: []userDocument{
+					{
+						ExternalID: "user1",
+						Path:       "/",
+					},
+				},
+				Groups: []groupDocument{
+					{
+						Org:  "org1",
+						Name: "group2",
+						Path: "/",
+					},
+				},
+			},
+			live: &iamDocument{
+				Users: []userDocument{
+					{
+						ExternalID: "user1",
+						Path:       "/",
+					},
+					{
+						ExternalID: "user2",
+					},
+				},
+				Groups: []groupDocument{
+					{
+						Org:  "org1",
+						Name: "group1",
+					},
+					{
+						Org:  "org1",
+						Name: "group2",
+						Path: "/",
+					},
+					{
+						Org:  "org2",
+						Name: "group3",
+					},
+				},
+				Policies: []policyDocument{
+					{
+						Org:  "org2",
+						Name: "policy1",
+					},
+				},
+				ProxyResources: []proxyResourceDocument{
+					{
+						Org:  "org1",
+						Name: "resource1",
+					},
+				},
+				OidcProviders: []oidcProviderDocument{
+					{
+						Name: "google",
+					},
+				},
+			},
+			prune: true,
+			expectedPlan: []planChange{
+				{
+					Type:        CHANGE_DELETE,
+					Description: "group org1/group1",
+					Operation: &api.BatchOperation{
+						Action: api.GROUP_ACTION_DELETE_GROUP,
+						Org:    "org1",
+						Name:   "group1",
+					},
+				},
+				{
+					Type:        CHANGE_DELETE,
+					Description: "user user2",
+					Operation: &api.BatchOperation{
+						Action:     api.USER_ACTION_DELETE_USER,
+						ExternalID: "user2",
+					},
+				},
+				{
+					Type:          CHANGE_DELETE,
+					Description:   "proxy resource org1/resource1",
+					ProxyResource: &proxyResourceDocument{Org: "org1", Name: "resource1"},
+				},
+				{
+					Type:         CHANGE_DELETE,
+					Description:  "OIDC provider google",
+					OidcProvider: &oidcProviderDocument{Name: "google"},
+				},
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		plan := computePlan(test.desired, test.live, test.prune)
+		assert.Equal(t, test.expectedPlan, plan, "Error in test case %v", n)
+	}
+}
+
+func TestCountRequests(t *testing.T) {
+	operations := func(n int) []planChange {
+		plan := []planChange{}
+		for i := 0; i < n; i++ {
+			plan = append(plan, planChange{Operation: &api.BatchOperation{}})
+		}
+		return plan
+	}
+	request := planChange{
+		OidcProvider: &oidcProviderDocument{Name: "google"},
+	}
+	testcases := map[string]struct {
+		// Method args
+		plan []planChange
+		// Expected result
+		expectedRequests int
+	}{
+		"OkCaseEmpty": {
+			plan:             []planChange{},
+			expectedRequests: 0,
+		},
+		"OkCaseOneBatch": {
+			plan:             operations(api.BATCH_MAX_OPERATIONS),
+			expectedRequests: 1,
+		},
+		"OkCaseSplitBatch": {
+			plan:             operations(api.BATCH_MAX_OPERATIONS + 1),
+			expectedRequests: 2,
+		},
+		"OkCaseBatchAndRequests": {
+			plan:             append(operations(2), request, request),
+			expectedRequests: 3,
+		},
+		"OkCaseInterleaved": {
+			plan:             append(append(operations(1), request), operations(1)...),
+			expectedRequests: 3,
+		},
+	}
+
+	for n, test := range testcases {
+		requests := countRequests(test.plan)
+		assert.Equal(t, test.expectedRequests, requests, "Error in test case %v", n)
+	}
+}
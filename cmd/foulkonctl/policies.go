@@ -0,0 +1,330 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/client"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+)
+
+var policyActions = map[string]adminAction{
+	"list": {
+		description: "List policies, of every organization if -org isn't set",
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			filter := listFlags(fs)
+			return func(c *client.Client) (interface{}, error) {
+				f := filter()
+				f.Org = *org
+				policies, total, err := c.ListPolicies(f)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.ListAllPoliciesResponse{
+					Policies: policies,
+					Limit:    pageLimit(f),
+					Offset:   f.Offset,
+					Total:    total,
+				}, nil
+			}
+		},
+	},
+	"get": {
+		description: "Get a policy",
+		required:    []string{"org", "name"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Policy name")
+			return func(c *client.Client) (interface{}, error) {
+				return c.GetPolicyByName(*org, *name)
+			}
+		},
+	},
+	"create": {
+		description: "Create a policy",
+		required:    []string{"org", "name", "f"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Policy name")
+			path := fs.String("path", DEFAULT_PATH, "Policy path")
+			file := fs.String("f", "", "Policy statements, in YAML or JSON format")
+			return func(c *client.Client) (interface{}, error) {
+				statements, err := loadStatements(*file)
+				if err != nil {
+					return nil, err
+				}
+				return c.AddPolicy(*name, *path, *org, statements)
+			}
+		},
+	},
+	"update": {
+		description: "Update the name, path or statements of a policy",
+		required:    []string{"org", "name"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Policy name")
+			newName := fs.String("new-name", "", "New policy name")
+			newPath := fs.String("new-path", "", "New policy path")
+			file := fs.String("f", "", "New policy statements, in YAML or JSON format")
+			return func(c *client.Client) (interface{}, error) {
+				// Fields not set are kept, so they are sent in a merge patch
+				patch := internalhttp.UpdatePolicyRequest{
+					Name: *newName,
+					Path: *newPath,
+				}
+				if *file != "" {
+					statements, err := loadStatements(*file)
+					if err != nil {
+						return nil, err
+					}
+					patch.Statements = statements
+				}
+				return c.PatchPolicy(*org, *name, patch)
+			}
+		},
+	},
+	"patch": {
+		description: "Update fields of a policy with a JSON Patch or a JSON Merge Patch file",
+		required:    []string{"org", "name", "f"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Policy name")
+			file := fs.String("f", "", "Patch, a list of JSON Patch operations or a JSON Merge Patch object, in YAML or JSON format")
+			return func(c *client.Client) (interface{}, error) {
+				patch, err := loadPatch(*file)
+				if err != nil {
+					return nil, err
+				}
+				return c.PatchPolicy(*org, *name, patch)
+			}
+		},
+	},
+	"delete": {
+		description: "Delete a policy",
+		required:    []string{"org", "name"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Policy name")
+			return func(c *client.Client) (interface{}, error) {
+				return nil, c.RemovePolicy(*org, *name)
+			}
+		},
+	},
+	"groups": {
+		description: "List groups attached to a policy",
+		required:    []string{"org", "name"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Policy name")
+			filter := listFlags(fs)
+			return func(c *client.Client) (interface{}, error) {
+				f := filter()
+				f.Org = *org
+				f.PolicyName = *name
+				groups, total, err := c.ListAttachedGroups(f)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.ListAttachedGroupsResponse{
+					Groups: groups,
+					Limit:  pageLimit(f),
+					Offset: f.Offset,
+					Total:  total,
+				}, nil
+			}
+		},
+	},
+	"versions": {
+		description: "List versions of a policy",
+		required:    []string{"org", "name"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Policy name")
+			filter := listFlags(fs)
+			return func(c *client.Client) (interface{}, error) {
+				f := filter()
+				f.Org = *org
+				f.PolicyName = *name
+				versions, total, err := c.ListPolicyVersions(f)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.ListPolicyVersionsResponse{
+					Versions: versions,
+					Limit:    pageLimit(f),
+					Offset:   f.Offset,
+					Total:    total,
+				}, nil
+			}
+		},
+	},
+	"version": {
+		description: "Get a version of a policy",
+		required:    []string{"org", "name", "version"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Policy name")
+			version := fs.String("version", "", "Policy version")
+			return func(c *client.Client) (interface{}, error) {
+				v, err := parseVersion("version", *version)
+				if err != nil {
+					return nil, err
+				}
+				return c.GetPolicyVersion(*org, *name, v)
+			}
+		},
+	},
+	"diff": {
+		description: "Compare the statements of two versions of a policy",
+		required:    []string{"org", "name", "version", "target"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Policy name")
+			version := fs.String("version", "", "Policy version")
+			target := fs.String("target", "", "Policy version to compare with")
+			return func(c *client.Client) (interface{}, error) {
+				v, err := parseVersion("version", *version)
+				if err != nil {
+					return nil, err
+				}
+				t, err := parseVersion("target", *target)
+				if err != nil {
+					return nil, err
+				}
+				return c.DiffPolicyVersions(*org, *name, v, t)
+			}
+		},
+	},
+	"rollback": {
+		description: "Restore the statements of a version of a policy",
+		required:    []string{"org", "name", "version"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Policy name")
+			version := fs.String("version", "", "Policy version")
+			return func(c *client.Client) (interface{}, error) {
+				v, err := parseVersion("version", *version)
+				if err != nil {
+					return nil, err
+				}
+				return c.RollbackPolicy(*org, *name, v)
+			}
+		},
+	},
+}
+
+var attachmentActions = map[string]adminAction{
+	"list": {
+		description: "List policies attached to a user (-id) or a group (-org and -group)",
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			id, org, group := attachmentFlags(fs)
+			filter := listFlags(fs)
+			return func(c *client.Client) (interface{}, error) {
+				if err := checkAttachmentFlags(*id, *org, *group); err != nil {
+					return nil, err
+				}
+				f := filter()
+				if *id != "" {
+					f.ExternalID = *id
+					policies, total, err := c.ListAttachedUserPolicies(f)
+					if err != nil {
+						return nil, err
+					}
+					return &internalhttp.ListAttachedUserPoliciesResponse{
+						AttachedPolicies: policies,
+						Limit:            pageLimit(f),
+						Offset:           f.Offset,
+						Total:            total,
+					}, nil
+				}
+				f.Org = *org
+				f.GroupName = *group
+				policies, total, err := c.ListAttachedGroupPolicies(f)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.ListAttachedGroupPoliciesResponse{
+					AttachedPolicies: policies,
+					Limit:            pageLimit(f),
+					Offset:           f.Offset,
+					Total:            total,
+				}, nil
+			}
+		},
+	},
+	"attach": {
+		description: "Attach a policy to a user (-id) or a group (-org and -group)",
+		required:    []string{"org", "policy"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			id, org, group := attachmentFlags(fs)
+			policy := fs.String("policy", "", "Policy name")
+			return func(c *client.Client) (interface{}, error) {
+				if err := checkAttachmentFlags(*id, *org, *group); err != nil {
+					return nil, err
+				}
+				if *id != "" {
+					return nil, c.AttachPolicyToUser(*id, *org, *policy)
+				}
+				return nil, c.AttachPolicyToGroup(*org, *group, *policy)
+			}
+		},
+	},
+	"detach": {
+		description: "Detach a policy from a user (-id) or a group (-org and -group)",
+		required:    []string{"org", "policy"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			id, org, group := attachmentFlags(fs)
+			policy := fs.String("policy", "", "Policy name")
+			return func(c *client.Client) (interface{}, error) {
+				if err := checkAttachmentFlags(*id, *org, *group); err != nil {
+					return nil, err
+				}
+				if *id != "" {
+					return nil, c.DetachPolicyToUser(*id, *org, *policy)
+				}
+				return nil, c.DetachPolicyToGroup(*org, *group, *policy)
+			}
+		},
+	},
+}
+
+// PRIVATE HELPER METHODS
+
+// Load policy statements from a file with a list of statements
+func loadStatements(file string) ([]api.Statement, error) {
+	statements := []api.Statement{}
+	if err := loadFile(file, &statements); err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
+
+// Parse the policy version of a flag
+func parseVersion(flagName string, value string) (int, error) {
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("Invalid policy version %v in flag -%v", value, flagName)
+	}
+	return version, nil
+}
+
+// Define the flags of the user or group of an attachment. Organization is also the organization of the policy
+func attachmentFlags(fs *flag.FlagSet) (*string, *string, *string) {
+	id := fs.String("id", "", "User external ID")
+	org := fs.String("org", "", "Organization")
+	group := fs.String("group", "", "Group name")
+	return id, org, group
+}
+
+func checkAttachmentFlags(id string, org string, group string) error {
+	if (id == "") == (group == "") {
+		return fmt.Errorf("Either flag -id or flag -group is required")
+	}
+	if group != "" && org == "" {
+		return fmt.Errorf("Flag -org is required")
+	}
+	return nil
+}
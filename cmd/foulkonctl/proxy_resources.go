@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/client"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+)
+
+var proxyResourceActions = map[string]adminAction{
+	"list": {
+		description: "List proxy resources of an organization",
+		required:    []string{"org"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			filter := listFlags(fs)
+			return func(c *client.Client) (interface{}, error) {
+				f := filter()
+				f.Org = *org
+				names, total, err := c.ListProxyResources(f)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.ListProxyResourcesResponse{
+					Resources: names,
+					Limit:     pageLimit(f),
+					Offset:    f.Offset,
+					Total:     total,
+				}, nil
+			}
+		},
+	},
+	"get": {
+		description: "Get a proxy resource",
+		required:    []string{"org", "name"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Proxy resource name")
+			return func(c *client.Client) (interface{}, error) {
+				return c.GetProxyResourceByName(*org, *name)
+			}
+		},
+	},
+	"create": {
+		description: "Create a proxy resource",
+		required:    []string{"org", "name", "host", "resource-path", "method", "urn", "action"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Proxy resource name")
+			path := fs.String("path", DEFAULT_PATH, "Proxy resource path")
+			resource := resourceFlags(fs)
+			return func(c *client.Client) (interface{}, error) {
+				return c.AddProxyResource(*name, *org, *path, *resource)
+			}
+		},
+	},
+	"update": {
+		description: "Update the name, path or resource of a proxy resource",
+		required:    []string{"org", "name"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Proxy resource name")
+			newName := fs.String("new-name", "", "New proxy resource name")
+			newPath := fs.String("new-path", "", "New proxy resource path")
+			resource := resourceFlags(fs)
+			return func(c *client.Client) (interface{}, error) {
+				// Fields not set are kept, so they are sent in a merge patch
+				patch := internalhttp.UpdateProxyResourceRequest{
+					Name:     *newName,
+					Path:     *newPath,
+					Resource: *resource,
+				}
+				return c.PatchProxyResource(*org, *name, patch)
+			}
+		},
+	},
+	"patch": {
+		description: "Update fields of a proxy resource with a JSON Patch or a JSON Merge Patch file",
+		required:    []string{"org", "name", "f"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Proxy resource name")
+			file := fs.String("f", "", "Patch, a list of JSON Patch operations or a JSON Merge Patch object, in YAML or JSON format")
+			return func(c *client.Client) (interface{}, error) {
+				patch, err := loadPatch(*file)
+				if err != nil {
+					return nil, err
+				}
+				return c.PatchProxyResource(*org, *name, patch)
+			}
+		},
+	},
+	"delete": {
+		description: "Delete a proxy resource",
+		required:    []string{"org", "name"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			org := fs.String("org", "", "Organization")
+			name := fs.String("name", "", "Proxy resource name")
+			return func(c *client.Client) (interface{}, error) {
+				return nil, c.RemoveProxyResource(*org, *name)
+			}
+		},
+	},
+}
+
+// PRIVATE HELPER METHODS
+
+// Define the flags of the resource entity of a proxy resource
+func resourceFlags(fs *flag.FlagSet) *api.ResourceEntity {
+	resource := &api.ResourceEntity{}
+	fs.StringVar(&resource.Host, "host", "", "Host where requests are forwarded, e.g. http://localhost:8001")
+	fs.StringVar(&resource.Path, "resource-path", "", "Path of the requests, e.g. /example/:id")
+	fs.StringVar(&resource.Method, "method", "", "HTTP method of the requests")
+	fs.StringVar(&resource.Urn, "urn", "", "URN of the requested resource, e.g. urn:ews:example:instance1:resource/{id}")
+	fs.StringVar(&resource.Action, "action", "", "Action of the requests, e.g. example:get")
+	return resource
+}
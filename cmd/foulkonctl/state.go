@@ -0,0 +1,207 @@
+package main
+
+import (
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/client"
+)
+
+// fetchState retrieves the live configuration from worker. Every user, group, policy and OIDC provider is listed,
+// and proxy resources are listed for the organizations of the desired document. Only resources declared in the
+// desired document are retrieved in detail, the rest of them only have their identifiers
+func fetchState(c *client.Client, desired *iamDocument) (*iamDocument, error) {
+	live := &iamDocument{}
+	// Error retrieving the detail of a resource, which stops the listing
+	var fetchErr error
+
+	// Users
+	declaredUsers := map[string]bool{}
+	for _, user := range desired.Users {
+		declaredUsers[user.ExternalID] = true
+	}
+	err := c.ListUsersPages(listAllFilter(), func(externalIDs []string) bool {
+		for _, externalID := range externalIDs {
+			user := userDocument{ExternalID: externalID}
+			if declaredUsers[externalID] {
+				if fetchErr = fetchUser(c, &user); fetchErr != nil {
+					return false
+				}
+			}
+			live.Users = append(live.Users, user)
+		}
+		return true
+	})
+	if err = firstError(err, fetchErr); err != nil {
+		return nil, err
+	}
+
+	// Groups
+	declaredGroups := map[string]bool{}
+	for _, group := range desired.Groups {
+		declaredGroups[orgKey(group.Org, group.Name)] = true
+	}
+	err = c.ListGroupsPages(listAllFilter(), func(groups []api.GroupIdentity) bool {
+		for _, identity := range groups {
+			group := groupDocument{Org: identity.Org, Name: identity.Name}
+			if declaredGroups[orgKey(group.Org, group.Name)] {
+				if fetchErr = fetchGroup(c, &group); fetchErr != nil {
+					return false
+				}
+			}
+			live.Groups = append(live.Groups, group)
+		}
+		return true
+	})
+	if err = firstError(err, fetchErr); err != nil {
+		return nil, err
+	}
+
+	// Policies
+	declaredPolicies := map[string]bool{}
+	for _, policy := range desired.Policies {
+		declaredPolicies[orgKey(policy.Org, policy.Name)] = true
+	}
+	err = c.ListPoliciesPages(listAllFilter(), func(policies []api.PolicyIdentity) bool {
+		for _, identity := range policies {
+			policy := policyDocument{Org: identity.Org, Name: identity.Name}
+			if declaredPolicies[orgKey(policy.Org, policy.Name)] {
+				var result *api.Policy
+				if result, fetchErr = c.GetPolicyByName(policy.Org, policy.Name); fetchErr != nil {
+					return false
+				}
+				policy.Path = result.Path
+				if result.Statements != nil {
+					policy.Statements = *result.Statements
+				}
+			}
+			live.Policies = append(live.Policies, policy)
+		}
+		return true
+	})
+	if err = firstError(err, fetchErr); err != nil {
+		return nil, err
+	}
+
+	// Proxy resources
+	declaredProxyResources := map[string]bool{}
+	for _, pr := range desired.ProxyResources {
+		declaredProxyResources[orgKey(pr.Org, pr.Name)] = true
+	}
+	for _, org := range desired.orgs() {
+		filter := listAllFilter()
+		filter.Org = org
+		err = c.ListProxyResourcesPages(filter, func(names []string) bool {
+			for _, name := range names {
+				pr := proxyResourceDocument{Org: org, Name: name}
+				if declaredProxyResources[orgKey(org, name)] {
+					var result *api.ProxyResource
+					if result, fetchErr = c.GetProxyResourceByName(org, name); fetchErr != nil {
+						return false
+					}
+					pr.Path = result.Path
+					pr.Resource = result.Resource
+				}
+				live.ProxyResources = append(live.ProxyResources, pr)
+			}
+			return true
+		})
+		if err = firstError(err, fetchErr); err != nil {
+			return nil, err
+		}
+	}
+
+	// OIDC providers
+	declaredOidcProviders := map[string]bool{}
+	for _, provider := range desired.OidcProviders {
+		declaredOidcProviders[provider.Name] = true
+	}
+	err = c.ListOidcProvidersPages(listAllFilter(), func(names []string) bool {
+		for _, name := range names {
+			provider := oidcProviderDocument{Name: name}
+			if declaredOidcProviders[name] {
+				var result *api.OidcProvider
+				if result, fetchErr = c.GetOidcProviderByName(name); fetchErr != nil {
+					return false
+				}
+				provider.Path = result.Path
+				provider.IssuerURL = result.IssuerURL
+				for _, oidcClient := range result.OidcClients {
+					provider.Clients = append(provider.Clients, oidcClient.Name)
+				}
+			}
+			live.OidcProviders = append(live.OidcProviders, provider)
+		}
+		return true
+	})
+	if err = firstError(err, fetchErr); err != nil {
+		return nil, err
+	}
+
+	return live, nil
+}
+
+// PRIVATE HELPER METHODS
+
+// Retrieve user path and attached policies
+func fetchUser(c *client.Client, user *userDocument) error {
+	result, err := c.GetUserByExternalID(user.ExternalID)
+	if err != nil {
+		return err
+	}
+	user.Path = result.Path
+
+	filter := listAllFilter()
+	filter.ExternalID = user.ExternalID
+	return c.ListAttachedUserPoliciesPages(filter, func(policies []api.UserPolicies) bool {
+		for _, policy := range policies {
+			user.Policies = append(user.Policies, policyReference{Org: policy.Org, Name: policy.Policy})
+		}
+		return true
+	})
+}
+
+// Retrieve group path, direct members and attached policies
+func fetchGroup(c *client.Client, group *groupDocument) error {
+	result, err := c.GetGroupByName(group.Org, group.Name)
+	if err != nil {
+		return err
+	}
+	group.Path = result.Path
+
+	filter := listAllFilter()
+	filter.Org = group.Org
+	filter.GroupName = group.Name
+	err = c.ListMembersPages(filter, func(members []api.GroupMembers) bool {
+		for _, member := range members {
+			// Members of subgroups aren't managed by the group
+			if !member.Inherited {
+				group.Members = append(group.Members, member.User)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.ListAttachedGroupPoliciesPages(filter, func(policies []api.GroupPolicies) bool {
+		for _, policy := range policies {
+			group.Policies = append(group.Policies, policy.Policy)
+		}
+		return true
+	})
+}
+
+// Return a filter to list every item with the fewest requests
+func listAllFilter() *api.Filter {
+	return &api.Filter{
+		Limit: api.MAX_LIMIT_SIZE,
+	}
+}
+
+// Return the error of a listing, or the one that stopped it
+func firstError(listErr error, fetchErr error) error {
+	if listErr != nil {
+		return listErr
+	}
+	return fetchErr
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/Tecsisa/foulkon/client"
+	internalhttp "github.com/Tecsisa/foulkon/http"
+)
+
+var userActions = map[string]adminAction{
+	"list": {
+		description: "List users",
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			filter := listFlags(fs)
+			return func(c *client.Client) (interface{}, error) {
+				f := filter()
+				externalIDs, total, err := c.ListUsers(f)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.GetUserExternalIDsResponse{
+					ExternalIDs: externalIDs,
+					Limit:       pageLimit(f),
+					Offset:      f.Offset,
+					Total:       total,
+				}, nil
+			}
+		},
+	},
+	"get": {
+		description: "Get a user",
+		required:    []string{"id"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			id := fs.String("id", "", "User external ID")
+			return func(c *client.Client) (interface{}, error) {
+				return c.GetUserByExternalID(*id)
+			}
+		},
+	},
+	"create": {
+		description: "Create a user",
+		required:    []string{"id"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			id := fs.String("id", "", "User external ID")
+			path := fs.String("path", DEFAULT_PATH, "User path")
+			return func(c *client.Client) (interface{}, error) {
+				return c.AddUser(*id, *path)
+			}
+		},
+	},
+	"update": {
+		description: "Update the path of a user",
+		required:    []string{"id", "path"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			id := fs.String("id", "", "User external ID")
+			path := fs.String("path", "", "New user path")
+			return func(c *client.Client) (interface{}, error) {
+				return c.UpdateUser(*id, *path)
+			}
+		},
+	},
+	"patch": {
+		description: "Update fields of a user with a JSON Patch or a JSON Merge Patch file",
+		required:    []string{"id", "f"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			id := fs.String("id", "", "User external ID")
+			file := fs.String("f", "", "Patch, a list of JSON Patch operations or a JSON Merge Patch object, in YAML or JSON format")
+			return func(c *client.Client) (interface{}, error) {
+				patch, err := loadPatch(*file)
+				if err != nil {
+					return nil, err
+				}
+				return c.PatchUser(*id, patch)
+			}
+		},
+	},
+	"delete": {
+		description: "Delete a user",
+		required:    []string{"id"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			id := fs.String("id", "", "User external ID")
+			return func(c *client.Client) (interface{}, error) {
+				return nil, c.RemoveUser(*id)
+			}
+		},
+	},
+	"groups": {
+		description: "List groups of a user",
+		required:    []string{"id"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			id := fs.String("id", "", "User external ID")
+			filter := listFlags(fs)
+			return func(c *client.Client) (interface{}, error) {
+				f := filter()
+				f.ExternalID = *id
+				groups, total, err := c.ListGroupsByUser(f)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.GetGroupsByUserIdResponse{
+					Groups: groups,
+					Limit:  pageLimit(f),
+					Offset: f.Offset,
+					Total:  total,
+				}, nil
+			}
+		},
+	},
+	"permissions": {
+		description: "List effective permissions of a user",
+		required:    []string{"id"},
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			id := fs.String("id", "", "User external ID")
+			filter := listFlags(fs)
+			return func(c *client.Client) (interface{}, error) {
+				f := filter()
+				f.ExternalID = *id
+				permissions, total, err := c.ListEffectivePermissionsByUser(f)
+				if err != nil {
+					return nil, err
+				}
+				return &internalhttp.GetEffectivePermissionsResponse{
+					Permissions: permissions,
+					Limit:       pageLimit(f),
+					Offset:      f.Offset,
+					Total:       total,
+				}, nil
+			}
+		},
+	},
+}
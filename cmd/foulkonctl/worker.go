@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/Tecsisa/foulkon/client"
+)
+
+var workerActions = map[string]adminAction{
+	"about": {
+		description: "Get worker version and configuration",
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			return func(c *client.Client) (interface{}, error) {
+				return c.GetCurrentConfig()
+			}
+		},
+	},
+	"cache-stats": {
+		description: "Get the statistics of worker authorization cache",
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			return func(c *client.Client) (interface{}, error) {
+				return c.GetAuthzCacheStats()
+			}
+		},
+	},
+	"cache-flush": {
+		description: "Remove every entry of worker authorization cache",
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			return func(c *client.Client) (interface{}, error) {
+				return nil, c.FlushAuthzCache()
+			}
+		},
+	},
+	"snapshot": {
+		description: "Get the snapshot of users, groups and policies used to authorize requests locally",
+		setup: func(fs *flag.FlagSet) func(c *client.Client) (interface{}, error) {
+			return func(c *client.Client) (interface{}, error) {
+				return c.GetAuthzSnapshot()
+			}
+		},
+	},
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/Tecsisa/foulkon/api"
 	"github.com/Tecsisa/foulkon/foulkon"
 	internalhttp "github.com/Tecsisa/foulkon/http"
+	"github.com/Tecsisa/foulkon/rpc"
 	"github.com/pelletier/go-toml"
 )
 
@@ -59,6 +60,14 @@ func main() {
 		}
 	}()
 
+	if core.GrpcPort != "" {
+		go func() {
+			api.Log.Infof("gRPC server running in %v:%v", core.Host, core.GrpcPort)
+			api.Log.Error(rpc.NewGrpcServer(core).Run().Error())
+			os.Exit(foulkon.CloseWorker())
+		}()
+	}
+
 	api.Log.Infof("Server running in %v:%v", core.Host, core.Port)
 	ws := internalhttp.NewWorker(core, internalhttp.WorkerHandlerRouter(core))
 	ws.Configuration()
@@ -5,12 +5,15 @@ import "fmt"
 const (
 	// Database
 	INTERNAL_ERROR = "InternalError"
+	// Resource was changed or removed since the update date expected by a change
+	PRECONDITION_FAILED = "PreconditionFailed"
 
 	// User Codes
 	USER_NOT_FOUND = "UserNotFound"
 
 	// Group Codes
-	GROUP_NOT_FOUND = "GroupNotFound"
+	GROUP_NOT_FOUND       = "GroupNotFound"
+	GROUP_HIERARCHY_CYCLE = "GroupHierarchyCycle"
 
 	// Group User Relation Codes
 	GROUP_USER_RELATION_NOT_FOUND = "GroupUserRelationNotFound"
@@ -21,6 +24,9 @@ const (
 	// Policy Codes
 	POLICY_NOT_FOUND = "PolicyNotFound"
 
+	// Policy Version Codes
+	POLICY_VERSION_NOT_FOUND = "PolicyVersionNotFound"
+
 	// Proxy resource Codes
 	PROXY_RESOURCE_NOT_FOUND = "ProxyResourceNotFound"
 
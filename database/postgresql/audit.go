@@ -0,0 +1,112 @@
+package postgresql
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/database"
+)
+
+// AUDIT REPOSITORY IMPLEMENTATION
+
+func (pr PostgresRepo) AddAuditEvent(event api.AuditEvent) error {
+	// Create audit event model
+	eventDB := &AuditEvent{
+		ID:        event.ID,
+		Actor:     event.Actor,
+		RequestID: event.RequestID,
+		Action:    event.Action,
+		Urn:       event.Urn,
+		Before:    string(event.Before),
+		After:     string(event.After),
+		CreateAt:  event.CreateAt.UnixNano(),
+	}
+
+	// Store audit event
+	err := pr.Dbmap.Create(eventDB).Error
+
+	// Error handling
+	if err != nil {
+		return &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) GetAuditEventsFiltered(filter *api.Filter) ([]api.AuditEvent, int, error) {
+	var total int
+	events := []AuditEvent{}
+	query := pr.Dbmap
+
+	if len(filter.Actor) > 0 {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if len(filter.UrnPrefix) > 0 {
+		query = query.Where("urn like ? escape '\\'", escapeLike(filter.UrnPrefix)+"%")
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("create_at >= ?", filter.From.UnixNano())
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("create_at <= ?", filter.To.UnixNano())
+	}
+	if len(filter.OrderBy) > 0 {
+		query = query.Order(filter.OrderBy)
+	} else {
+		// Most recent events first by default
+		query = query.Order("create_at desc")
+	}
+
+	// Error handling
+	if err := query.Find(&events).Count(&total).Offset(filter.Offset).Limit(filter.Limit).Find(&events).Error; err != nil {
+		return nil, total, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	// Transform audit events to API domain
+	var apiEvents []api.AuditEvent
+	if events != nil {
+		apiEvents = make([]api.AuditEvent, len(events), cap(events))
+		for i, e := range events {
+			apiEvents[i] = *dbAuditEventToAPIAuditEvent(&e)
+		}
+	}
+
+	return apiEvents, total, nil
+}
+
+// PRIVATE HELPER METHODS
+
+// Replacer of LIKE wildcards and of the escape character, so values are matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Escape a value to use it in a LIKE pattern with backslash as escape character
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// Transform an audit event retrieved from db into an audit event for API
+func dbAuditEventToAPIAuditEvent(e *AuditEvent) *api.AuditEvent {
+	event := &api.AuditEvent{
+		ID:        e.ID,
+		Actor:     e.Actor,
+		RequestID: e.RequestID,
+		Action:    e.Action,
+		Urn:       e.Urn,
+		CreateAt:  time.Unix(0, e.CreateAt).UTC(),
+	}
+	if len(e.Before) > 0 {
+		event.Before = json.RawMessage(e.Before)
+	}
+	if len(e.After) > 0 {
+		event.After = json.RawMessage(e.After)
+	}
+	return event
+}
@@ -0,0 +1,220 @@
+package postgresql
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresRepo_AddAuditEvent(t *testing.T) {
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// Postgres Repo Args
+		event api.AuditEvent
+		// Expected result
+		expectedResponse *api.AuditEvent
+	}{
+		"OkCase": {
+			event: api.AuditEvent{
+				ID:        "EventID",
+				Actor:     "admin",
+				RequestID: "RequestID",
+				Action:    api.POLICY_ACTION_UPDATE_POLICY,
+				Urn:       api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy1"),
+				Before:    json.RawMessage(`{"name":"policy1","path":"/path/"}`),
+				After:     json.RawMessage(`{"name":"policy1","path":"/path2/"}`),
+				CreateAt:  now,
+			},
+			expectedResponse: &api.AuditEvent{
+				ID:        "EventID",
+				Actor:     "admin",
+				RequestID: "RequestID",
+				Action:    api.POLICY_ACTION_UPDATE_POLICY,
+				Urn:       api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy1"),
+				Before:    json.RawMessage(`{"name":"policy1","path":"/path/"}`),
+				After:     json.RawMessage(`{"name":"policy1","path":"/path2/"}`),
+				CreateAt:  now,
+			},
+		},
+		"OkCaseWithoutBefore": {
+			event: api.AuditEvent{
+				ID:        "EventID",
+				Actor:     "admin",
+				RequestID: "RequestID",
+				Action:    api.USER_ACTION_CREATE_USER,
+				Urn:       api.CreateUrn("", api.RESOURCE_USER, "/path/", "user1"),
+				After:     json.RawMessage(`{"externalId":"user1"}`),
+				CreateAt:  now,
+			},
+			expectedResponse: &api.AuditEvent{
+				ID:        "EventID",
+				Actor:     "admin",
+				RequestID: "RequestID",
+				Action:    api.USER_ACTION_CREATE_USER,
+				Urn:       api.CreateUrn("", api.RESOURCE_USER, "/path/", "user1"),
+				After:     json.RawMessage(`{"externalId":"user1"}`),
+				CreateAt:  now,
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		// Clean audit events database
+		cleanAuditEventsTable(t, n)
+
+		// Call to repository to store an audit event
+		err := repoDB.AddAuditEvent(test.event)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		// Check database
+		count := getAuditEventsCountFiltered(t, n, test.event.ID, test.event.Actor, test.event.Action, test.event.Urn)
+		assert.Equal(t, 1, count, "Error in test case %v", n)
+
+		events, _, err := repoDB.GetAuditEventsFiltered(&api.Filter{Limit: 20})
+		assert.Nil(t, err, "Error in test case %v", n)
+		assert.Equal(t, []api.AuditEvent{*test.expectedResponse}, events, "Error in test case %v", n)
+	}
+}
+
+func TestPostgresRepo_GetAuditEventsFiltered(t *testing.T) {
+	now := time.Now().UTC()
+	previousEvents := []AuditEvent{
+		{
+			ID:        "EventID1",
+			Actor:     "admin",
+			RequestID: "RequestID1",
+			Action:    api.POLICY_ACTION_CREATE_POLICY,
+			Urn:       api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy1"),
+			After:     `{"name":"policy1"}`,
+			CreateAt:  now.Add(-2 * time.Hour).UnixNano(),
+		},
+		{
+			ID:        "EventID2",
+			Actor:     "user1",
+			RequestID: "RequestID2",
+			Action:    api.GROUP_ACTION_CREATE_GROUP,
+			Urn:       api.CreateUrn("org1", api.RESOURCE_GROUP, "/path/", "group1"),
+			After:     `{"name":"group1"}`,
+			CreateAt:  now.Add(-time.Hour).UnixNano(),
+		},
+		{
+			ID:        "EventID3",
+			Actor:     "admin",
+			RequestID: "RequestID3",
+			Action:    api.POLICY_ACTION_DELETE_POLICY,
+			Urn:       api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy1"),
+			Before:    `{"name":"policy1"}`,
+			CreateAt:  now.UnixNano(),
+		},
+	}
+	apiEvents := make([]api.AuditEvent, len(previousEvents))
+	for i, e := range previousEvents {
+		apiEvents[i] = *dbAuditEventToAPIAuditEvent(&e)
+	}
+	testcases := map[string]struct {
+		// Postgres Repo Args
+		filter *api.Filter
+		// Expected result
+		expectedResponse []api.AuditEvent
+		expectedTotal    int
+	}{
+		"OkCaseAll": {
+			filter: &api.Filter{
+				Limit: 20,
+			},
+			expectedResponse: []api.AuditEvent{apiEvents[2], apiEvents[1], apiEvents[0]},
+			expectedTotal:    3,
+		},
+		"OkCaseActor": {
+			filter: &api.Filter{
+				Actor: "admin",
+				Limit: 20,
+			},
+			expectedResponse: []api.AuditEvent{apiEvents[2], apiEvents[0]},
+			expectedTotal:    2,
+		},
+		"OkCaseUrnPrefix": {
+			filter: &api.Filter{
+				UrnPrefix: "urn:iws:iam:org1:group/",
+				Limit:     20,
+			},
+			expectedResponse: []api.AuditEvent{apiEvents[1]},
+			expectedTotal:    1,
+		},
+		"OkCaseActorWithWildcards": {
+			filter: &api.Filter{
+				Actor: "adm%",
+				Limit: 20,
+			},
+			expectedResponse: []api.AuditEvent{},
+			expectedTotal:    0,
+		},
+		"OkCaseUrnPrefixWithWildcards": {
+			filter: &api.Filter{
+				UrnPrefix: "urn:iws:iam:org1:_roup/",
+				Limit:     20,
+			},
+			expectedResponse: []api.AuditEvent{},
+			expectedTotal:    0,
+		},
+		"OkCaseTimeRange": {
+			filter: &api.Filter{
+				From:  now.Add(-90 * time.Minute),
+				To:    now.Add(-30 * time.Minute),
+				Limit: 20,
+			},
+			expectedResponse: []api.AuditEvent{apiEvents[1]},
+			expectedTotal:    1,
+		},
+		"OkCaseOrderByAndPagination": {
+			filter: &api.Filter{
+				OrderBy: "create_at asc",
+				Offset:  1,
+				Limit:   1,
+			},
+			expectedResponse: []api.AuditEvent{apiEvents[1]},
+			expectedTotal:    3,
+		},
+	}
+
+	for n, test := range testcases {
+		// Clean audit events database
+		cleanAuditEventsTable(t, n)
+
+		// Insert previous data
+		for _, e := range previousEvents {
+			insertAuditEvent(t, n, e)
+		}
+
+		// Call to repository to get audit events
+		events, total, err := repoDB.GetAuditEventsFiltered(test.filter)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		// Check response
+		assert.Equal(t, test.expectedTotal, total, "Error in test case %v", n)
+		assert.Equal(t, test.expectedResponse, events, "Error in test case %v", n)
+	}
+}
+
+func Test_escapeLike(t *testing.T) {
+	testcases := map[string]struct {
+		value          string
+		expectedString string
+	}{
+		"OkCaseWithoutWildcards": {
+			value:          "urn:iws:iam:org1:group/path/",
+			expectedString: "urn:iws:iam:org1:group/path/",
+		},
+		"OkCaseWildcards": {
+			value:          `urn:iws:iam:org1:group/a_b%c\d`,
+			expectedString: `urn:iws:iam:org1:group/a\_b\%c\\d`,
+		},
+	}
+
+	for n, test := range testcases {
+		assert.Equal(t, test.expectedString, escapeLike(test.value), "Error in test case %v", n)
+	}
+}
@@ -24,11 +24,11 @@ func (pr PostgresRepo) AddOidcProvider(oidcProvider api.OidcProvider) (*api.Oidc
 		IssuerURL: oidcProvider.IssuerURL,
 	}
 
-	transaction := pr.Dbmap.Begin()
+	transaction := pr.begin()
 
 	// Create OIDC Provider
 	if err := transaction.Create(oidcProviderDB).Error; err != nil {
-		transaction.Rollback()
+		pr.rollback(transaction)
 		return nil, &database.Error{
 			Code:    database.INTERNAL_ERROR,
 			Message: err.Error(),
@@ -44,7 +44,7 @@ func (pr PostgresRepo) AddOidcProvider(oidcProvider api.OidcProvider) (*api.Oidc
 			Name:           oidcClientApi.Name,
 		}
 		if err := transaction.Create(oidcClientDB).Error; err != nil {
-			transaction.Rollback()
+			pr.rollback(transaction)
 			return nil, &database.Error{
 				Code:    database.INTERNAL_ERROR,
 				Message: err.Error(),
@@ -52,7 +52,7 @@ func (pr PostgresRepo) AddOidcProvider(oidcProvider api.OidcProvider) (*api.Oidc
 		}
 	}
 
-	transaction.Commit()
+	pr.commit(transaction)
 
 	// Create API OIDC Provider
 	oidcProviderApi := dbOidcProviderToAPIOidcProvider(oidcProviderDB)
@@ -149,7 +149,7 @@ func (pr PostgresRepo) GetOidcProvidersFiltered(filter *api.Filter) ([]api.OidcP
 	return apiOidcProviders, total, nil
 }
 
-func (pr PostgresRepo) UpdateOidcProvider(oidcProvider api.OidcProvider) (*api.OidcProvider, error) {
+func (pr PostgresRepo) UpdateOidcProvider(oidcProvider api.OidcProvider, lastUpdateAt time.Time) (*api.OidcProvider, error) {
 	oidcProviderDB := OidcProvider{
 		ID:        oidcProvider.ID,
 		Name:      oidcProvider.Name,
@@ -160,20 +160,25 @@ func (pr PostgresRepo) UpdateOidcProvider(oidcProvider api.OidcProvider) (*api.O
 		IssuerURL: oidcProvider.IssuerURL,
 	}
 
-	transaction := pr.Dbmap.Begin()
+	transaction := pr.begin()
 
 	// Update OIDC Provider
-	if err := transaction.Model(&OidcProvider{ID: oidcProvider.ID}).Update(oidcProviderDB).Error; err != nil {
-		transaction.Rollback()
+	query := whereUpdateAt(transaction.Model(&OidcProvider{ID: oidcProvider.ID}), lastUpdateAt).Update(oidcProviderDB)
+	if err := query.Error; err != nil {
+		pr.rollback(transaction)
 		return nil, &database.Error{
 			Code:    database.INTERNAL_ERROR,
 			Message: err.Error(),
 		}
 	}
+	if err := checkUpdateAt(query, lastUpdateAt, "OIDC provider", oidcProvider.ID); err != nil {
+		pr.rollback(transaction)
+		return nil, err
+	}
 
 	// Clean old OIDC Clients
 	if err := transaction.Where("oidc_provider_id like ?", oidcProvider.ID).Delete(OidcClient{}).Error; err != nil {
-		transaction.Rollback()
+		pr.rollback(transaction)
 		return nil, &database.Error{
 			Code:    database.INTERNAL_ERROR,
 			Message: err.Error(),
@@ -188,7 +193,7 @@ func (pr PostgresRepo) UpdateOidcProvider(oidcProvider api.OidcProvider) (*api.O
 			Name:           oc.Name,
 		}
 		if err := transaction.Create(oidcClientDB).Error; err != nil {
-			transaction.Rollback()
+			pr.rollback(transaction)
 			return nil, &database.Error{
 				Code:    database.INTERNAL_ERROR,
 				Message: err.Error(),
@@ -196,28 +201,32 @@ func (pr PostgresRepo) UpdateOidcProvider(oidcProvider api.OidcProvider) (*api.O
 		}
 	}
 
-	transaction.Commit()
+	pr.commit(transaction)
 
 	return &oidcProvider, nil
 }
 
-func (pr PostgresRepo) RemoveOidcProvider(id string) error {
-	transaction := pr.Dbmap.Begin()
+func (pr PostgresRepo) RemoveOidcProvider(id string, lastUpdateAt time.Time) error {
+	transaction := pr.begin()
 
 	// Delete OIDC Provider
-	transaction.Where("id like ?", id).Delete(&OidcProvider{})
-	if err := transaction.Error; err != nil {
-		transaction.Rollback()
+	query := whereUpdateAt(transaction.Where("id like ?", id), lastUpdateAt).Delete(&OidcProvider{})
+	if err := query.Error; err != nil {
+		pr.rollback(transaction)
 		return &database.Error{
 			Code:    database.INTERNAL_ERROR,
 			Message: err.Error(),
 		}
 	}
+	if err := checkUpdateAt(query, lastUpdateAt, "OIDC provider", id); err != nil {
+		pr.rollback(transaction)
+		return err
+	}
 
 	// Delete all OIDC Clients
 	transaction.Where("oidc_provider_id like ?", id).Delete(&OidcClient{})
 	if err := transaction.Error; err != nil {
-		transaction.Rollback()
+		pr.rollback(transaction)
 		return &database.Error{
 			Code:    database.INTERNAL_ERROR,
 			Message: err.Error(),
@@ -225,7 +234,7 @@ func (pr PostgresRepo) RemoveOidcProvider(id string) error {
 
 	}
 
-	transaction.Commit()
+	pr.commit(transaction)
 	return nil
 }
 
@@ -1,6 +1,7 @@
 package postgresql
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -452,6 +453,7 @@ func TestPostgresRepo_UpdateOidcProvider(t *testing.T) {
 		previousOidcProviders []OidcProvider
 		previousOidcClients   []OidcClient
 		oidcProvider          api.OidcProvider
+		lastUpdateAt          time.Time
 		// Expected result
 		expectedResponse *api.OidcProvider
 		expectedError    *database.Error
@@ -596,6 +598,45 @@ func TestPostgresRepo_UpdateOidcProvider(t *testing.T) {
 				Message: "pq: duplicate key value violates unique constraint \"idx_oidc_client\"",
 			},
 		},
+		"ErrorCaseOidcProviderChanged": {
+			previousOidcProviders: []OidcProvider{
+				{
+					ID:        "111",
+					Name:      "test1",
+					Path:      "/path1/",
+					CreateAt:  now.UnixNano(),
+					UpdateAt:  now.UnixNano(),
+					Urn:       api.CreateUrn("", api.RESOURCE_AUTH_OIDC_PROVIDER, "/path1/", "test1"),
+					IssuerURL: "http://test1.com",
+				},
+			},
+			previousOidcClients: []OidcClient{
+				{
+					ID:             "1",
+					Name:           "client1",
+					OidcProviderID: "111",
+				},
+			},
+			oidcProvider: api.OidcProvider{
+				ID:        "111",
+				Name:      "newName",
+				Path:      "/newPath/",
+				CreateAt:  now,
+				UpdateAt:  now.Add(time.Second),
+				Urn:       api.CreateUrn("", api.RESOURCE_AUTH_OIDC_PROVIDER, "/newPath/", "newName"),
+				IssuerURL: "http://testNew.com",
+				OidcClients: []api.OidcClient{
+					{
+						Name: "clientNew",
+					},
+				},
+			},
+			lastUpdateAt: now.Add(-time.Second),
+			expectedError: &database.Error{
+				Code:    database.PRECONDITION_FAILED,
+				Message: fmt.Sprintf("OIDC provider with id 111 has changed since %v", now.Add(-time.Second)),
+			},
+		},
 	}
 
 	for n, test := range testcases {
@@ -609,7 +650,7 @@ func TestPostgresRepo_UpdateOidcProvider(t *testing.T) {
 				insertOidcProvider(t, n, op, test.previousOidcClients)
 			}
 		}
-		receivedOidcProvider, err := repoDB.UpdateOidcProvider(test.oidcProvider)
+		receivedOidcProvider, err := repoDB.UpdateOidcProvider(test.oidcProvider, test.lastUpdateAt)
 		if test.expectedError != nil {
 			dbError, _ := err.(*database.Error)
 			assert.Equal(t, test.expectedError, dbError, "Error in test case %v", n)
@@ -695,7 +736,7 @@ func TestPostgresRepo_RemoveOidcProvider(t *testing.T) {
 		}
 
 		// Call to repository to remove OIDC Provider
-		err := repoDB.RemoveOidcProvider(test.oidcProviderToDelete)
+		err := repoDB.RemoveOidcProvider(test.oidcProviderToDelete, time.Time{})
 		assert.Nil(t, err, "Error in test case %v", n)
 
 		// Check database
@@ -0,0 +1,38 @@
+package postgresql
+
+import (
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/database"
+)
+
+// AUTHZ DECISION SINK IMPLEMENTATION
+
+func (pr PostgresRepo) AddAuthzDecisions(decisions []api.AuthzDecision) error {
+	transaction := pr.begin()
+
+	for _, decision := range decisions {
+		// Create authorization decision model
+		decisionDB := &AuthzDecision{
+			ID:              decision.ID,
+			Source:          decision.Source,
+			RequestID:       decision.RequestID,
+			WorkerRequestID: decision.WorkerRequestID,
+			Identifier:      decision.User,
+			Action:          decision.Action,
+			Requested:       stringArrayToString(decision.Requested),
+			Allowed:         stringArrayToString(decision.Allowed),
+			Latency:         int64(decision.Latency),
+			CreateAt:        decision.CreateAt.UnixNano(),
+		}
+		if err := transaction.Create(decisionDB).Error; err != nil {
+			pr.rollback(transaction)
+			return &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: err.Error(),
+			}
+		}
+	}
+
+	pr.commit(transaction)
+	return nil
+}
@@ -0,0 +1,81 @@
+package postgresql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresRepo_AddAuthzDecisions(t *testing.T) {
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// Postgres Repo Args
+		decisions []api.AuthzDecision
+		// Expected result
+		expectedResponse []AuthzDecision
+	}{
+		"OkCase": {
+			decisions: []api.AuthzDecision{
+				{
+					ID:        "DecisionID1",
+					Source:    api.AUTHZ_DECISION_SOURCE_WORKER,
+					RequestID: "RequestID1",
+					User:      "user1",
+					Action:    "example:get",
+					Requested: []string{"urn:ews:example:instance1:resource/1", "urn:ews:example:instance1:resource/2"},
+					Allowed:   []string{"urn:ews:example:instance1:resource/1"},
+					Latency:   time.Millisecond,
+					CreateAt:  now,
+				},
+				{
+					ID:              "DecisionID2",
+					Source:          api.AUTHZ_DECISION_SOURCE_PROXY,
+					RequestID:       "RequestID2",
+					WorkerRequestID: "RequestID3",
+					Action:          "example:get",
+					Requested:       []string{"urn:ews:example:instance1:resource/2"},
+					Allowed:         []string{},
+					Latency:         2 * time.Millisecond,
+					CreateAt:        now.Add(time.Second),
+				},
+			},
+			expectedResponse: []AuthzDecision{
+				{
+					ID:         "DecisionID1",
+					Source:     api.AUTHZ_DECISION_SOURCE_WORKER,
+					RequestID:  "RequestID1",
+					Identifier: "user1",
+					Action:     "example:get",
+					Requested:  "urn:ews:example:instance1:resource/1;urn:ews:example:instance1:resource/2",
+					Allowed:    "urn:ews:example:instance1:resource/1",
+					Latency:    int64(time.Millisecond),
+					CreateAt:   now.UnixNano(),
+				},
+				{
+					ID:              "DecisionID2",
+					Source:          api.AUTHZ_DECISION_SOURCE_PROXY,
+					RequestID:       "RequestID2",
+					WorkerRequestID: "RequestID3",
+					Action:          "example:get",
+					Requested:       "urn:ews:example:instance1:resource/2",
+					Latency:         int64(2 * time.Millisecond),
+					CreateAt:        now.Add(time.Second).UnixNano(),
+				},
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		// Clean authorization decisions database
+		cleanAuthzDecisionsTable(t, n)
+
+		// Call to repository to store the decisions
+		err := repoDB.AddAuthzDecisions(test.decisions)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		// Check database
+		assert.Equal(t, test.expectedResponse, getAuthzDecisions(t, n), "Error in test case %v", n)
+	}
+}
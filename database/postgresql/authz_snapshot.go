@@ -0,0 +1,105 @@
+package postgresql
+
+import (
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/database"
+	"github.com/jinzhu/gorm"
+)
+
+// AUTHZ SNAPSHOT REPOSITORY IMPLEMENTATION
+
+func (pr PostgresRepo) GetAuthzSnapshot() (*api.AuthzSnapshot, error) {
+	// All tables are read in the same snapshot of database
+	transaction := pr.Dbmap.Begin()
+	if err := transaction.Error; err != nil {
+		return nil, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+	defer transaction.Rollback()
+	if err := transaction.Exec("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY").Error; err != nil {
+		return nil, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	snapshot := &api.AuthzSnapshot{
+		Users:         []api.User{},
+		Groups:        []api.Group{},
+		Policies:      []api.Policy{},
+		Members:       []api.AuthzSnapshotRelation{},
+		Subgroups:     []api.AuthzSnapshotRelation{},
+		GroupPolicies: []api.AuthzSnapshotRelation{},
+		UserPolicies:  []api.AuthzSnapshotRelation{},
+		CreateAt:      time.Now().UTC(),
+	}
+
+	users := []User{}
+	groups := []Group{}
+	policies := []Policy{}
+	statements := []Statement{}
+	members := []GroupUserRelation{}
+	subgroups := []GroupSubgroupRelation{}
+	groupPolicies := []GroupPolicyRelation{}
+	userPolicies := []UserPolicyRelation{}
+	queries := []*gorm.DB{
+		transaction.Order("id").Find(&users),
+		transaction.Order("id").Find(&groups),
+		transaction.Order("id").Find(&policies),
+		transaction.Order("policy_id, ordinal, id").Find(&statements),
+		transaction.Find(&members),
+		transaction.Find(&subgroups),
+		transaction.Find(&groupPolicies),
+		transaction.Find(&userPolicies),
+	}
+	for _, query := range queries {
+		if err := query.Error; err != nil {
+			return nil, &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: err.Error(),
+			}
+		}
+	}
+
+	// Transform data to API domain
+	for _, u := range users {
+		snapshot.Users = append(snapshot.Users, *dbUserToAPIUser(&u))
+	}
+	for _, g := range groups {
+		snapshot.Groups = append(snapshot.Groups, *dbGroupToAPIGroup(&g))
+	}
+	policyStatements := map[string][]Statement{}
+	for _, s := range statements {
+		policyStatements[s.PolicyID] = append(policyStatements[s.PolicyID], s)
+	}
+	for _, p := range policies {
+		policy := dbPolicyToAPIPolicy(&p)
+		apiStatements, err := dbStatementsToAPIStatements(policyStatements[p.ID])
+		if err != nil {
+			return nil, &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: err.Error(),
+			}
+		}
+		policy.Statements = apiStatements
+		snapshot.Policies = append(snapshot.Policies, *policy)
+	}
+	for _, r := range members {
+		snapshot.Members = append(snapshot.Members, api.AuthzSnapshotRelation{ID: r.GroupID, RelatedID: r.UserID})
+	}
+	for _, r := range subgroups {
+		snapshot.Subgroups = append(snapshot.Subgroups, api.AuthzSnapshotRelation{ID: r.GroupID, RelatedID: r.SubgroupID})
+	}
+	for _, r := range groupPolicies {
+		snapshot.GroupPolicies = append(snapshot.GroupPolicies, api.AuthzSnapshotRelation{ID: r.GroupID, RelatedID: r.PolicyID})
+	}
+	for _, r := range userPolicies {
+		snapshot.UserPolicies = append(snapshot.UserPolicies, api.AuthzSnapshotRelation{ID: r.UserID, RelatedID: r.PolicyID})
+	}
+
+	return snapshot, nil
+}
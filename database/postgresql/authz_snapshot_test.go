@@ -0,0 +1,208 @@
+package postgresql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresRepo_GetAuthzSnapshot(t *testing.T) {
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// Previous data
+		previousUsers    []User
+		previousGroups   []Group
+		previousPolicies []Policy
+		statements       map[string][]Statement
+		// Relations to insert
+		groupUserRelations     map[string]string
+		groupSubgroupRelations map[string]string
+		groupPolicyRelations   map[string]string
+		userPolicyRelations    map[string]string
+		// Expected result
+		expectedResponse *api.AuthzSnapshot
+	}{
+		"OkCase": {
+			previousUsers: []User{
+				{
+					ID:         "UserID1",
+					ExternalID: "user1",
+					Path:       "/path/",
+					CreateAt:   now.UnixNano(),
+					UpdateAt:   now.UnixNano(),
+					Urn:        api.CreateUrn("", api.RESOURCE_USER, "/path/", "user1"),
+				},
+			},
+			previousGroups: []Group{
+				{
+					ID:       "GroupID1",
+					Name:     "group1",
+					Path:     "/path/",
+					Org:      "org1",
+					CreateAt: now.UnixNano(),
+					UpdateAt: now.UnixNano(),
+					Urn:      api.CreateUrn("org1", api.RESOURCE_GROUP, "/path/", "group1"),
+				},
+				{
+					ID:       "GroupID2",
+					Name:     "group2",
+					Path:     "/path/",
+					Org:      "org1",
+					CreateAt: now.UnixNano(),
+					UpdateAt: now.UnixNano(),
+					Urn:      api.CreateUrn("org1", api.RESOURCE_GROUP, "/path/", "group2"),
+				},
+			},
+			previousPolicies: []Policy{
+				{
+					ID:       "PolicyID1",
+					Name:     "policy1",
+					Path:     "/path/",
+					Org:      "org1",
+					CreateAt: now.UnixNano(),
+					UpdateAt: now.UnixNano(),
+					Urn:      api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy1"),
+				},
+			},
+			statements: map[string][]Statement{
+				"PolicyID1": {
+					{
+						ID:        "StatementID1",
+						Effect:    "allow",
+						Actions:   api.USER_ACTION_GET_USER,
+						Resources: api.GetUrnPrefix("", api.RESOURCE_USER, "/path/"),
+					},
+				},
+			},
+			groupUserRelations: map[string]string{
+				"GroupID2": "UserID1",
+			},
+			groupSubgroupRelations: map[string]string{
+				"GroupID1": "GroupID2",
+			},
+			groupPolicyRelations: map[string]string{
+				"GroupID1": "PolicyID1",
+			},
+			userPolicyRelations: map[string]string{
+				"UserID1": "PolicyID1",
+			},
+			expectedResponse: &api.AuthzSnapshot{
+				Users: []api.User{
+					{
+						ID:         "UserID1",
+						ExternalID: "user1",
+						Path:       "/path/",
+						CreateAt:   now,
+						UpdateAt:   now,
+						Urn:        api.CreateUrn("", api.RESOURCE_USER, "/path/", "user1"),
+					},
+				},
+				Groups: []api.Group{
+					{
+						ID:       "GroupID1",
+						Name:     "group1",
+						Path:     "/path/",
+						Org:      "org1",
+						CreateAt: now,
+						UpdateAt: now,
+						Urn:      api.CreateUrn("org1", api.RESOURCE_GROUP, "/path/", "group1"),
+					},
+					{
+						ID:       "GroupID2",
+						Name:     "group2",
+						Path:     "/path/",
+						Org:      "org1",
+						CreateAt: now,
+						UpdateAt: now,
+						Urn:      api.CreateUrn("org1", api.RESOURCE_GROUP, "/path/", "group2"),
+					},
+				},
+				Policies: []api.Policy{
+					{
+						ID:       "PolicyID1",
+						Name:     "policy1",
+						Path:     "/path/",
+						Org:      "org1",
+						CreateAt: now,
+						UpdateAt: now,
+						Urn:      api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy1"),
+						Statements: &[]api.Statement{
+							{
+								Effect:    "allow",
+								Actions:   []string{api.USER_ACTION_GET_USER},
+								Resources: []string{api.GetUrnPrefix("", api.RESOURCE_USER, "/path/")},
+							},
+						},
+					},
+				},
+				Members: []api.AuthzSnapshotRelation{
+					{ID: "GroupID2", RelatedID: "UserID1"},
+				},
+				Subgroups: []api.AuthzSnapshotRelation{
+					{ID: "GroupID1", RelatedID: "GroupID2"},
+				},
+				GroupPolicies: []api.AuthzSnapshotRelation{
+					{ID: "GroupID1", RelatedID: "PolicyID1"},
+				},
+				UserPolicies: []api.AuthzSnapshotRelation{
+					{ID: "UserID1", RelatedID: "PolicyID1"},
+				},
+			},
+		},
+		"OkCaseEmpty": {
+			expectedResponse: &api.AuthzSnapshot{
+				Users:         []api.User{},
+				Groups:        []api.Group{},
+				Policies:      []api.Policy{},
+				Members:       []api.AuthzSnapshotRelation{},
+				Subgroups:     []api.AuthzSnapshotRelation{},
+				GroupPolicies: []api.AuthzSnapshotRelation{},
+				UserPolicies:  []api.AuthzSnapshotRelation{},
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		// Clean database
+		cleanUserTable(t, n)
+		cleanGroupTable(t, n)
+		cleanPolicyTable(t, n)
+		cleanStatementTable(t, n)
+		cleanGroupUserRelationTable(t, n)
+		cleanGroupSubgroupRelationTable(t, n)
+		cleanGroupPolicyRelationTable(t, n)
+		cleanUserPolicyRelationTable(t, n)
+
+		// Insert previous data
+		for _, user := range test.previousUsers {
+			insertUser(t, n, user)
+		}
+		for _, group := range test.previousGroups {
+			insertGroup(t, n, group)
+		}
+		for _, policy := range test.previousPolicies {
+			insertPolicy(t, n, policy, test.statements[policy.ID])
+		}
+		for groupID, userID := range test.groupUserRelations {
+			insertGroupUserRelation(t, n, userID, groupID, now.UnixNano())
+		}
+		for groupID, subgroupID := range test.groupSubgroupRelations {
+			insertGroupSubgroupRelation(t, n, groupID, subgroupID, now.UnixNano())
+		}
+		for groupID, policyID := range test.groupPolicyRelations {
+			insertGroupPolicyRelation(t, n, groupID, policyID, now.UnixNano())
+		}
+		for userID, policyID := range test.userPolicyRelations {
+			insertUserPolicyRelation(t, n, userID, policyID, now.UnixNano())
+		}
+
+		snapshot, err := repoDB.GetAuthzSnapshot()
+		assert.Nil(t, err, "Error in test case %v", n)
+		// Snapshot time is set when it's read
+		assert.False(t, snapshot.CreateAt.IsZero(), "Error in test case %v", n)
+		snapshot.CreateAt = time.Time{}
+		assert.Equal(t, test.expectedResponse, snapshot, "Error in test case %v", n)
+	}
+}
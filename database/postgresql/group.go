@@ -118,7 +118,7 @@ func (pr PostgresRepo) GetGroupsFiltered(filter *api.Filter) ([]api.Group, int,
 	return apiGroups, total, nil
 }
 
-func (pr PostgresRepo) UpdateGroup(group api.Group) (*api.Group, error) {
+func (pr PostgresRepo) UpdateGroup(group api.Group, lastUpdateAt time.Time) (*api.Group, error) {
 	groupDB := Group{
 		ID:       group.ID,
 		Name:     group.Name,
@@ -130,7 +130,7 @@ func (pr PostgresRepo) UpdateGroup(group api.Group) (*api.Group, error) {
 	}
 
 	// Update group
-	query := pr.Dbmap.Model(&Group{ID: group.ID}).Updates(groupDB)
+	query := whereUpdateAt(pr.Dbmap.Model(&Group{ID: group.ID}), lastUpdateAt).Updates(groupDB)
 
 	// Check if group exist
 	if query.RecordNotFound() {
@@ -147,27 +147,34 @@ func (pr PostgresRepo) UpdateGroup(group api.Group) (*api.Group, error) {
 			Message: err.Error(),
 		}
 	}
+	if err := checkUpdateAt(query, lastUpdateAt, "Group", group.ID); err != nil {
+		return nil, err
+	}
 
 	return &group, nil
 }
 
-func (pr PostgresRepo) RemoveGroup(id string) error {
-	transaction := pr.Dbmap.Begin()
+func (pr PostgresRepo) RemoveGroup(id string, lastUpdateAt time.Time) error {
+	transaction := pr.begin()
 
 	// Delete group
-	transaction.Where("id like ?", id).Delete(&Group{})
-	if err := transaction.Error; err != nil {
-		transaction.Rollback()
+	query := whereUpdateAt(transaction.Where("id like ?", id), lastUpdateAt).Delete(&Group{})
+	if err := query.Error; err != nil {
+		pr.rollback(transaction)
 		return &database.Error{
 			Code:    database.INTERNAL_ERROR,
 			Message: err.Error(),
 		}
 	}
+	if err := checkUpdateAt(query, lastUpdateAt, "Group", id); err != nil {
+		pr.rollback(transaction)
+		return err
+	}
 
 	// Delete all group relations
 	transaction.Where("group_id like ?", id).Delete(&GroupUserRelation{})
 	if err := transaction.Error; err != nil {
-		transaction.Rollback()
+		pr.rollback(transaction)
 		return &database.Error{
 			Code:    database.INTERNAL_ERROR,
 			Message: err.Error(),
@@ -177,14 +184,24 @@ func (pr PostgresRepo) RemoveGroup(id string) error {
 	// Delete all policy relations
 	transaction.Where("group_id like ?", id).Delete(&GroupPolicyRelation{})
 	if err := transaction.Error; err != nil {
-		transaction.Rollback()
+		pr.rollback(transaction)
+		return &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	// Delete all subgroup relations (as parent and as member)
+	transaction.Where("group_id like ? OR subgroup_id like ?", id, id).Delete(&GroupSubgroupRelation{})
+	if err := transaction.Error; err != nil {
+		pr.rollback(transaction)
 		return &database.Error{
 			Code:    database.INTERNAL_ERROR,
 			Message: err.Error(),
 		}
 	}
 
-	transaction.Commit()
+	pr.commit(transaction)
 	return nil
 }
 
@@ -382,6 +399,189 @@ func (pr PostgresRepo) GetAttachedPolicies(groupID string, filter *api.Filter) (
 	return policies, total, nil
 }
 
+// Query that blocks concurrent writes of relations between groups and subgroups until the transaction ends
+const lockSubgroupRelationsQuery = `LOCK TABLE group_subgroup_relations IN SHARE ROW EXCLUSIVE MODE`
+
+// Query that counts the times a group is found between the descendants of another one, through the group hierarchy
+const subgroupCycleQuery = `WITH RECURSIVE descendants(group_id) AS (
+	SELECT subgroup_id FROM group_subgroup_relations WHERE group_id = ?
+	UNION
+	SELECT r.subgroup_id FROM group_subgroup_relations r INNER JOIN descendants d ON r.group_id = d.group_id
+)
+SELECT COUNT(*) FROM descendants WHERE group_id = ?`
+
+func (pr PostgresRepo) AddSubgroup(groupID string, subgroupID string) error {
+	if groupID == subgroupID {
+		return &database.Error{
+			Code:    database.GROUP_HIERARCHY_CYCLE,
+			Message: fmt.Sprintf("Group %v can't be a subgroup of itself", groupID),
+		}
+	}
+
+	transaction := pr.begin()
+
+	// Concurrent changes of the hierarchy wait until this transaction ends, so they can't create a cycle
+	// between them. Reads aren't blocked
+	if err := transaction.Exec(lockSubgroupRelationsQuery).Error; err != nil {
+		pr.rollback(transaction)
+		return &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	// Subgroup can't be the group itself or any of its ancestors
+	var cycles int
+	if err := transaction.Raw(subgroupCycleQuery, subgroupID, groupID).Row().Scan(&cycles); err != nil {
+		pr.rollback(transaction)
+		return &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+	if cycles > 0 {
+		pr.rollback(transaction)
+		return &database.Error{
+			Code:    database.GROUP_HIERARCHY_CYCLE,
+			Message: fmt.Sprintf("Group %v is an ancestor of group %v", subgroupID, groupID),
+		}
+	}
+
+	// Create relation
+	relation := &GroupSubgroupRelation{
+		GroupID:    groupID,
+		SubgroupID: subgroupID,
+		CreateAt:   time.Now().UTC().UnixNano(),
+	}
+
+	// Store relation
+	if err := transaction.Create(relation).Error; err != nil {
+		pr.rollback(transaction)
+		return &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	pr.commit(transaction)
+	return nil
+}
+
+func (pr PostgresRepo) RemoveSubgroup(groupID string, subgroupID string) error {
+	err := pr.Dbmap.Where("group_id like ? AND subgroup_id like ?", groupID, subgroupID).Delete(&GroupSubgroupRelation{}).Error
+
+	// Error handling
+	if err != nil {
+		return &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+	return nil
+}
+
+func (pr PostgresRepo) IsSubgroupOfGroup(subgroupID string, groupID string) (bool, error) {
+	relation := GroupSubgroupRelation{}
+	query := pr.Dbmap.Where("group_id like ? AND subgroup_id like ?", groupID, subgroupID).First(&relation)
+
+	// Check if relation exists
+	if query.RecordNotFound() {
+		return false, nil
+	}
+
+	// Error Handling
+	if err := query.Error; err != nil {
+		return false, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	return true, nil
+}
+
+func (pr PostgresRepo) GetSubgroups(groupID string, filter *api.Filter) ([]api.GroupSubgroupRelation, int, error) {
+	var total int
+	relations := []GroupSubgroupRelation{}
+	query := pr.Dbmap.Where("group_id like ?", groupID)
+
+	if len(filter.OrderBy) > 0 {
+		query = query.Order(filter.OrderBy)
+	}
+
+	// Error handling
+	if err := query.Find(&relations).Count(&total).Offset(filter.Offset).Limit(filter.Limit).Find(&relations).Error; err != nil {
+		return nil, total, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	var subgroups []api.GroupSubgroupRelation
+	// Transform relations to API domain
+	if relations != nil {
+		subgroups = make([]api.GroupSubgroupRelation, len(relations), cap(relations))
+		for i, r := range relations {
+			subgroup, err := pr.GetGroupById(r.SubgroupID)
+			// Error handling
+			if err != nil {
+				return nil, total, &database.Error{
+					Code:    database.INTERNAL_ERROR,
+					Message: err.Error(),
+				}
+			}
+
+			subgroups[i] = &GroupSubgroup{
+				Subgroup: subgroup,
+				CreateAt: time.Unix(0, r.CreateAt).UTC(),
+			}
+		}
+	}
+
+	return subgroups, total, nil
+}
+
+func (pr PostgresRepo) GetParentGroups(subgroupID string, filter *api.Filter) ([]api.GroupSubgroupRelation, int, error) {
+	var total int
+	relations := []GroupSubgroupRelation{}
+	query := pr.Dbmap.Where("subgroup_id like ?", subgroupID)
+
+	if len(filter.OrderBy) > 0 {
+		query = query.Order(filter.OrderBy)
+	}
+
+	// Error handling
+	if err := query.Find(&relations).Count(&total).Offset(filter.Offset).Limit(filter.Limit).Find(&relations).Error; err != nil {
+		return nil, total, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	var groups []api.GroupSubgroupRelation
+	// Transform relations to API domain
+	if relations != nil {
+		groups = make([]api.GroupSubgroupRelation, len(relations), cap(relations))
+		for i, r := range relations {
+			group, err := pr.GetGroupById(r.GroupID)
+			// Error handling
+			if err != nil {
+				return nil, total, &database.Error{
+					Code:    database.INTERNAL_ERROR,
+					Message: err.Error(),
+				}
+			}
+
+			groups[i] = &GroupSubgroup{
+				Group:    group,
+				CreateAt: time.Unix(0, r.CreateAt).UTC(),
+			}
+		}
+	}
+
+	return groups, total, nil
+}
+
 // PRIVATE HELPER METHODS
 
 // Transform a Group retrieved from db into a group for API
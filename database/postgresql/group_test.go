@@ -1,6 +1,7 @@
 package postgresql
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -560,6 +561,7 @@ func TestPostgresRepo_UpdateGroup(t *testing.T) {
 		previousGroups []Group
 		// Postgres Repo Args
 		groupToUpdate *api.Group
+		lastUpdateAt  time.Time
 		// Expected result
 		expectedResponse *api.Group
 		expectedError    *database.Error
@@ -629,6 +631,33 @@ func TestPostgresRepo_UpdateGroup(t *testing.T) {
 				Message: "pq: duplicate key value violates unique constraint \"groups_urn_key\"",
 			},
 		},
+		"ErrorCaseGroupChanged": {
+			previousGroups: []Group{
+				{
+					ID:       "GroupID",
+					Name:     "Name",
+					Path:     "Path",
+					Urn:      "Urn",
+					CreateAt: now.UnixNano(),
+					UpdateAt: now.UnixNano(),
+					Org:      "Org",
+				},
+			},
+			groupToUpdate: &api.Group{
+				ID:       "GroupID",
+				Name:     "NewName",
+				Path:     "NewPath",
+				Urn:      "NewUrn",
+				CreateAt: now,
+				UpdateAt: now.Add(time.Second),
+				Org:      "Org",
+			},
+			lastUpdateAt: now.Add(-time.Second),
+			expectedError: &database.Error{
+				Code:    database.PRECONDITION_FAILED,
+				Message: fmt.Sprintf("Group with id GroupID has changed since %v", now.Add(-time.Second)),
+			},
+		},
 	}
 
 	for n, test := range testcases {
@@ -643,7 +672,7 @@ func TestPostgresRepo_UpdateGroup(t *testing.T) {
 		}
 
 		// Call to repository to update group
-		updatedGroup, err := repoDB.UpdateGroup(*test.groupToUpdate)
+		updatedGroup, err := repoDB.UpdateGroup(*test.groupToUpdate, test.lastUpdateAt)
 		if test.expectedError != nil {
 			dbError, _ := err.(*database.Error)
 			assert.Equal(t, test.expectedError, dbError, "Error in test case %v", n)
@@ -671,12 +700,19 @@ func TestPostgresRepo_RemoveGroup(t *testing.T) {
 		groupID  string
 		CreateAt int64
 	}
+	type subgroupRelation struct {
+		groupID    string
+		subgroupID string
+		CreateAt   int64
+	}
 	now := time.Now().UTC()
 	testcases := map[string]struct {
 		// Previous data
 		previousGroups  []Group
 		userRelations   []userRelation
 		policyRelations []policyRelation
+		// Subgroup relations
+		subgroupRelations []subgroupRelation
 		// Postgres Repo Args
 		groupToDelete string
 	}{
@@ -725,6 +761,23 @@ func TestPostgresRepo_RemoveGroup(t *testing.T) {
 					CreateAt: now.UnixNano(),
 				},
 			},
+			subgroupRelations: []subgroupRelation{
+				{
+					groupID:    "GroupID",
+					subgroupID: "GroupID2",
+					CreateAt:   now.UnixNano(),
+				},
+				{
+					groupID:    "GroupID3",
+					subgroupID: "GroupID",
+					CreateAt:   now.UnixNano(),
+				},
+				{
+					groupID:    "GroupID3",
+					subgroupID: "GroupID2",
+					CreateAt:   now.UnixNano(),
+				},
+			},
 			groupToDelete: "GroupID",
 		},
 	}
@@ -733,6 +786,7 @@ func TestPostgresRepo_RemoveGroup(t *testing.T) {
 		cleanGroupTable(t, n)
 		cleanGroupUserRelationTable(t, n)
 		cleanGroupPolicyRelationTable(t, n)
+		cleanGroupSubgroupRelationTable(t, n)
 
 		// Insert previous data
 		if test.previousGroups != nil {
@@ -751,8 +805,13 @@ func TestPostgresRepo_RemoveGroup(t *testing.T) {
 				insertGroupPolicyRelation(t, n, rel.groupID, rel.policyID, rel.CreateAt)
 			}
 		}
+		if test.subgroupRelations != nil {
+			for _, rel := range test.subgroupRelations {
+				insertGroupSubgroupRelation(t, n, rel.groupID, rel.subgroupID, rel.CreateAt)
+			}
+		}
 		// Call to repository to remove group
-		err := repoDB.RemoveGroup(test.groupToDelete)
+		err := repoDB.RemoveGroup(test.groupToDelete, time.Time{})
 		assert.Nil(t, err, "Error in test case %v", n)
 
 		// Check database
@@ -778,6 +837,16 @@ func TestPostgresRepo_RemoveGroup(t *testing.T) {
 		// Check total group policy relations
 		totalRelations = getGroupPolicyRelationCount(t, n, "", "")
 		assert.Equal(t, 1, totalRelations, "Error in test case %v", n)
+
+		// Check group subgroup relations
+		relations = getGroupSubgroupRelationCount(t, n, test.groupToDelete, "")
+		assert.Equal(t, 0, relations, "Error in test case %v", n)
+		relations = getGroupSubgroupRelationCount(t, n, "", test.groupToDelete)
+		assert.Equal(t, 0, relations, "Error in test case %v", n)
+
+		// Check total group subgroup relations
+		totalRelations = getGroupSubgroupRelationCount(t, n, "", "")
+		assert.Equal(t, 1, totalRelations, "Error in test case %v", n)
 	}
 }
 
@@ -1376,3 +1445,422 @@ func TestPostgresRepo_GetAttachedPolicies(t *testing.T) {
 		}
 	}
 }
+
+func TestPostgresRepo_AddSubgroup(t *testing.T) {
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// Previous data, relations between groups (first) and subgroups (second)
+		relations [][2]string
+		// Postgres Repo Args
+		groupID    string
+		subgroupID string
+		// Expected result
+		expectedError *database.Error
+	}{
+		"OkCase": {
+			groupID:    "GroupID",
+			subgroupID: "SubgroupID",
+		},
+		"OkCaseSiblingHierarchy": {
+			relations: [][2]string{
+				{"ParentID", "GroupID"},
+				{"ParentID", "SubgroupID"},
+			},
+			groupID:    "GroupID",
+			subgroupID: "SubgroupID",
+		},
+		"ErrorCaseCycleSameGroup": {
+			groupID:    "GroupID",
+			subgroupID: "GroupID",
+			expectedError: &database.Error{
+				Code:    database.GROUP_HIERARCHY_CYCLE,
+				Message: "Group GroupID can't be a subgroup of itself",
+			},
+		},
+		"ErrorCaseCycleAncestor": {
+			relations: [][2]string{
+				{"SubgroupID", "MiddleID"},
+				{"MiddleID", "GroupID"},
+			},
+			groupID:    "GroupID",
+			subgroupID: "SubgroupID",
+			expectedError: &database.Error{
+				Code:    database.GROUP_HIERARCHY_CYCLE,
+				Message: "Group SubgroupID is an ancestor of group GroupID",
+			},
+		},
+		"ErrorCaseInternalError": {
+			groupID: "GroupID",
+			expectedError: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "pq: null value in column \"subgroup_id\" violates not-null constraint",
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		// Clean GroupSubgroupRelation database
+		cleanGroupSubgroupRelationTable(t, n)
+
+		// Insert previous data
+		for _, rel := range test.relations {
+			insertGroupSubgroupRelation(t, n, rel[0], rel[1], now.UnixNano())
+		}
+
+		// Call to repository to store subgroup
+		err := repoDB.AddSubgroup(test.groupID, test.subgroupID)
+		if test.expectedError != nil {
+			dbError, _ := err.(*database.Error)
+			assert.Equal(t, test.expectedError, dbError, "Error in test case %v", n)
+		} else {
+			assert.Nil(t, err, "Error in test case %v", n)
+
+			// Check database
+			relations := getGroupSubgroupRelationCount(t, n, test.groupID, test.subgroupID)
+			assert.Equal(t, 1, relations, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestPostgresRepo_RemoveSubgroup(t *testing.T) {
+	type relation struct {
+		groupID    string
+		subgroupID string
+		createAt   int64
+	}
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// Previous data
+		relations []relation
+		// Postgres Repo Args
+		groupID    string
+		subgroupID string
+	}{
+		"OkCase": {
+			relations: []relation{
+				{
+					groupID:    "GroupID",
+					subgroupID: "SubgroupID",
+					createAt:   now.UnixNano(),
+				},
+				{
+					groupID:    "GroupID",
+					subgroupID: "SubgroupID2",
+					createAt:   now.UnixNano(),
+				},
+			},
+			groupID:    "GroupID",
+			subgroupID: "SubgroupID",
+		},
+	}
+
+	for n, test := range testcases {
+		// Clean GroupSubgroupRelation database
+		cleanGroupSubgroupRelationTable(t, n)
+
+		// Insert previous data
+		for _, rel := range test.relations {
+			insertGroupSubgroupRelation(t, n, rel.groupID, rel.subgroupID, rel.createAt)
+		}
+
+		// Call to repository to remove subgroup
+		err := repoDB.RemoveSubgroup(test.groupID, test.subgroupID)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		// Check database
+		relations := getGroupSubgroupRelationCount(t, n, test.groupID, test.subgroupID)
+		assert.Equal(t, 0, relations, "Error in test case %v", n)
+
+		// Check total relations
+		totalRelations := getGroupSubgroupRelationCount(t, n, "", "")
+		assert.Equal(t, len(test.relations)-1, totalRelations, "Error in test case %v", n)
+	}
+}
+
+func TestPostgresRepo_IsSubgroupOfGroup(t *testing.T) {
+	type relation struct {
+		groupID    string
+		subgroupID string
+		createAt   int64
+	}
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// Previous data
+		relation *relation
+		// Postgres Repo Args
+		group    string
+		subgroup string
+		// Expected result
+		isSubgroup bool
+	}{
+		"OkCaseIsSubgroup": {
+			relation: &relation{
+				groupID:    "GroupID",
+				subgroupID: "SubgroupID",
+				createAt:   now.UnixNano(),
+			},
+			group:      "GroupID",
+			subgroup:   "SubgroupID",
+			isSubgroup: true,
+		},
+		"OkCaseIsNotSubgroup": {
+			group:      "GroupID",
+			subgroup:   "SubgroupID",
+			isSubgroup: false,
+		},
+		"OkCaseReverseRelation": {
+			relation: &relation{
+				groupID:    "SubgroupID",
+				subgroupID: "GroupID",
+				createAt:   now.UnixNano(),
+			},
+			group:      "GroupID",
+			subgroup:   "SubgroupID",
+			isSubgroup: false,
+		},
+	}
+
+	for n, test := range testcases {
+		cleanGroupSubgroupRelationTable(t, n)
+
+		// Insert previous data
+		if test.relation != nil {
+			insertGroupSubgroupRelation(t, n, test.relation.groupID, test.relation.subgroupID, test.relation.createAt)
+		}
+
+		isSubgroup, err := repoDB.IsSubgroupOfGroup(test.subgroup, test.group)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		// Check response
+		assert.Equal(t, test.isSubgroup, isSubgroup, "Error in test case %v", n)
+	}
+}
+
+func TestPostgresRepo_GetSubgroups(t *testing.T) {
+	type relations struct {
+		subgroups     []Group
+		groupID       string
+		createAt      []int64
+		groupNotFound bool
+	}
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// Previous data
+		relations *relations
+		// Postgres Repo Args
+		groupID string
+		filter  *api.Filter
+		// Expected result
+		expectedResponse []*GroupSubgroup
+		expectedError    *database.Error
+	}{
+		"OkCase": {
+			relations: &relations{
+				subgroups: []Group{
+					{
+						ID:       "SubgroupID1",
+						Name:     "Subgroup1",
+						Path:     "Path",
+						Urn:      "urn1",
+						CreateAt: now.UnixNano(),
+						UpdateAt: now.UnixNano(),
+						Org:      "Org",
+					},
+					{
+						ID:       "SubgroupID2",
+						Name:     "Subgroup2",
+						Path:     "Path",
+						Urn:      "urn2",
+						CreateAt: now.UnixNano(),
+						UpdateAt: now.UnixNano(),
+						Org:      "Org",
+					},
+				},
+				groupID:  "GroupID",
+				createAt: []int64{now.UnixNano(), now.UnixNano()},
+			},
+			groupID: "GroupID",
+			filter:  testFilter,
+			expectedResponse: []*GroupSubgroup{
+				{
+					Subgroup: &api.Group{
+						ID:       "SubgroupID1",
+						Name:     "Subgroup1",
+						Path:     "Path",
+						Urn:      "urn1",
+						CreateAt: now,
+						UpdateAt: now,
+						Org:      "Org",
+					},
+					CreateAt: now,
+				},
+				{
+					Subgroup: &api.Group{
+						ID:       "SubgroupID2",
+						Name:     "Subgroup2",
+						Path:     "Path",
+						Urn:      "urn2",
+						CreateAt: now,
+						UpdateAt: now,
+						Org:      "Org",
+					},
+					CreateAt: now,
+				},
+			},
+		},
+		"ErrorCase": {
+			relations: &relations{
+				subgroups: []Group{
+					{
+						ID: "SubgroupID1",
+					},
+				},
+				groupID:       "GroupID",
+				groupNotFound: true,
+				createAt:      []int64{now.UnixNano()},
+			},
+			groupID: "GroupID",
+			filter:  testFilter,
+			expectedError: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Code: GroupNotFound, Message: Group with id SubgroupID1 not found",
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		cleanGroupTable(t, n)
+		cleanGroupSubgroupRelationTable(t, n)
+
+		// Insert previous data
+		if test.relations != nil {
+			for x, subgroup := range test.relations.subgroups {
+				insertGroupSubgroupRelation(t, n, test.relations.groupID, subgroup.ID, test.relations.createAt[x])
+				if !test.relations.groupNotFound {
+					insertGroup(t, n, subgroup)
+				}
+			}
+		}
+
+		receivedSubgroups, total, err := repoDB.GetSubgroups(test.groupID, test.filter)
+		if test.expectedError != nil {
+			dbError, _ := err.(*database.Error)
+			assert.Equal(t, test.expectedError, dbError, "Error in test case %v", n)
+		} else {
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check total
+			assert.Equal(t, len(test.expectedResponse), total, "Error in test case %v", n)
+
+			// Check response
+			for i, r := range receivedSubgroups {
+				assert.Equal(t, test.expectedResponse[i].GetGroup(), r.GetGroup(), "Error in test case %v", n)
+				assert.Equal(t, test.expectedResponse[i].GetSubgroup(), r.GetSubgroup(), "Error in test case %v", n)
+				assert.Equal(t, test.expectedResponse[i].GetDate(), r.GetDate(), "Error in test case %v", n)
+			}
+		}
+	}
+}
+
+func TestPostgresRepo_GetParentGroups(t *testing.T) {
+	type relations struct {
+		groups        []Group
+		subgroupID    string
+		createAt      []int64
+		groupNotFound bool
+	}
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// Previous data
+		relations *relations
+		// Postgres Repo Args
+		subgroupID string
+		filter     *api.Filter
+		// Expected result
+		expectedResponse []*GroupSubgroup
+		expectedError    *database.Error
+	}{
+		"OkCase": {
+			relations: &relations{
+				groups: []Group{
+					{
+						ID:       "GroupID1",
+						Name:     "Group1",
+						Path:     "Path",
+						Urn:      "urn1",
+						CreateAt: now.UnixNano(),
+						UpdateAt: now.UnixNano(),
+						Org:      "Org",
+					},
+				},
+				subgroupID: "SubgroupID",
+				createAt:   []int64{now.UnixNano()},
+			},
+			subgroupID: "SubgroupID",
+			filter:     testFilter,
+			expectedResponse: []*GroupSubgroup{
+				{
+					Group: &api.Group{
+						ID:       "GroupID1",
+						Name:     "Group1",
+						Path:     "Path",
+						Urn:      "urn1",
+						CreateAt: now,
+						UpdateAt: now,
+						Org:      "Org",
+					},
+					CreateAt: now,
+				},
+			},
+		},
+		"ErrorCase": {
+			relations: &relations{
+				groups: []Group{
+					{
+						ID: "GroupID1",
+					},
+				},
+				subgroupID:    "SubgroupID",
+				groupNotFound: true,
+				createAt:      []int64{now.UnixNano()},
+			},
+			subgroupID: "SubgroupID",
+			filter:     testFilter,
+			expectedError: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Code: GroupNotFound, Message: Group with id GroupID1 not found",
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		cleanGroupTable(t, n)
+		cleanGroupSubgroupRelationTable(t, n)
+
+		// Insert previous data
+		if test.relations != nil {
+			for x, group := range test.relations.groups {
+				insertGroupSubgroupRelation(t, n, group.ID, test.relations.subgroupID, test.relations.createAt[x])
+				if !test.relations.groupNotFound {
+					insertGroup(t, n, group)
+				}
+			}
+		}
+
+		receivedGroups, total, err := repoDB.GetParentGroups(test.subgroupID, test.filter)
+		if test.expectedError != nil {
+			dbError, _ := err.(*database.Error)
+			assert.Equal(t, test.expectedError, dbError, "Error in test case %v", n)
+		} else {
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check total
+			assert.Equal(t, len(test.expectedResponse), total, "Error in test case %v", n)
+
+			// Check response
+			for i, r := range receivedGroups {
+				assert.Equal(t, test.expectedResponse[i].GetGroup(), r.GetGroup(), "Error in test case %v", n)
+				assert.Equal(t, test.expectedResponse[i].GetSubgroup(), r.GetSubgroup(), "Error in test case %v", n)
+				assert.Equal(t, test.expectedResponse[i].GetDate(), r.GetDate(), "Error in test case %v", n)
+			}
+		}
+	}
+}
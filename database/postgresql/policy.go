@@ -1,6 +1,7 @@
 package postgresql
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -12,7 +13,7 @@ import (
 
 // POLICY REPOSITORY IMPLEMENTATION
 
-func (pr PostgresRepo) AddPolicy(policy api.Policy) (*api.Policy, error) {
+func (pr PostgresRepo) AddPolicy(policy api.Policy, author string) (*api.Policy, error) {
 	// Create policy model
 	policyDB := &Policy{
 		ID:       policy.ID,
@@ -24,11 +25,11 @@ func (pr PostgresRepo) AddPolicy(policy api.Policy) (*api.Policy, error) {
 		Org:      policy.Org,
 	}
 
-	transaction := pr.Dbmap.Begin()
+	transaction := pr.begin()
 
 	// Create policy
 	if err := transaction.Create(policyDB).Error; err != nil {
-		transaction.Rollback()
+		pr.rollback(transaction)
 		return nil, &database.Error{
 			Code:    database.INTERNAL_ERROR,
 			Message: err.Error(),
@@ -36,17 +37,29 @@ func (pr PostgresRepo) AddPolicy(policy api.Policy) (*api.Policy, error) {
 	}
 
 	// Create statements
-	for _, statementApi := range *policy.Statements {
+	for i, statementApi := range *policy.Statements {
+		conditions, err := conditionsToString(statementApi.Conditions)
+		if err != nil {
+			pr.rollback(transaction)
+			return nil, &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: err.Error(),
+			}
+		}
 		// Create statement model
 		statementDB := &Statement{
-			ID:        uuid.NewV4().String(),
-			PolicyID:  policy.ID,
-			Effect:    statementApi.Effect,
-			Actions:   stringArrayToString(statementApi.Actions),
-			Resources: stringArrayToString(statementApi.Resources),
+			ID:           uuid.NewV4().String(),
+			PolicyID:     policy.ID,
+			Effect:       statementApi.Effect,
+			Actions:      stringArrayToString(statementApi.Actions),
+			Resources:    stringArrayToString(statementApi.Resources),
+			NotActions:   stringArrayToString(statementApi.NotActions),
+			NotResources: stringArrayToString(statementApi.NotResources),
+			Conditions:   conditions,
+			Ordinal:      i,
 		}
 		if err := transaction.Create(statementDB).Error; err != nil {
-			transaction.Rollback()
+			pr.rollback(transaction)
 			return nil, &database.Error{
 				Code:    database.INTERNAL_ERROR,
 				Message: err.Error(),
@@ -54,7 +67,16 @@ func (pr PostgresRepo) AddPolicy(policy api.Policy) (*api.Policy, error) {
 		}
 	}
 
-	transaction.Commit()
+	// Create first version
+	if err := createPolicyVersion(transaction, policy.ID, 1, author, policy.Statements, policy.CreateAt); err != nil {
+		pr.rollback(transaction)
+		return nil, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	pr.commit(transaction)
 
 	// Create API policy
 	policyApi := dbPolicyToAPIPolicy(policyDB)
@@ -85,7 +107,7 @@ func (pr PostgresRepo) GetPolicyByName(org string, name string) (*api.Policy, er
 
 	// Retrieve associated statements
 	statements := []Statement{}
-	query = pr.Dbmap.Where("policy_id like ?", policy.ID).Find(&statements)
+	query = pr.Dbmap.Where("policy_id like ?", policy.ID).Order("ordinal, id").Find(&statements)
 	// Error Handling
 	if err := query.Error; err != nil {
 		return nil, &database.Error{
@@ -96,7 +118,14 @@ func (pr PostgresRepo) GetPolicyByName(org string, name string) (*api.Policy, er
 
 	// Create API policy
 	policyApi := dbPolicyToAPIPolicy(policy)
-	policyApi.Statements = dbStatementsToAPIStatements(statements)
+	apiStatements, err := dbStatementsToAPIStatements(statements)
+	if err != nil {
+		return nil, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+	policyApi.Statements = apiStatements
 
 	return policyApi, nil
 }
@@ -123,7 +152,7 @@ func (pr PostgresRepo) GetPolicyById(id string) (*api.Policy, error) {
 
 	// Retrieve associated statements
 	statements := []Statement{}
-	query = pr.Dbmap.Where("policy_id like ?", policy.ID).Find(&statements)
+	query = pr.Dbmap.Where("policy_id like ?", policy.ID).Order("ordinal, id").Find(&statements)
 	// Error Handling
 	if err := query.Error; err != nil {
 		return nil, &database.Error{
@@ -134,7 +163,14 @@ func (pr PostgresRepo) GetPolicyById(id string) (*api.Policy, error) {
 
 	// Create API policy
 	policyApi := dbPolicyToAPIPolicy(policy)
-	policyApi.Statements = dbStatementsToAPIStatements(statements)
+	apiStatements, err := dbStatementsToAPIStatements(statements)
+	if err != nil {
+		return nil, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+	policyApi.Statements = apiStatements
 
 	return policyApi, nil
 }
@@ -172,7 +208,7 @@ func (pr PostgresRepo) GetPoliciesFiltered(filter *api.Filter) ([]api.Policy, in
 
 			// Retrieve associated statements
 			statements := []Statement{}
-			query = pr.Dbmap.Where("policy_id like ?", policy.ID).Find(&statements)
+			query = pr.Dbmap.Where("policy_id like ?", policy.ID).Order("ordinal, id").Find(&statements)
 			// Error Handling
 			if err := query.Error; err != nil {
 				return nil, total, &database.Error{
@@ -181,7 +217,14 @@ func (pr PostgresRepo) GetPoliciesFiltered(filter *api.Filter) ([]api.Policy, in
 				}
 			}
 
-			policy.Statements = dbStatementsToAPIStatements(statements)
+			apiStatements, err := dbStatementsToAPIStatements(statements)
+			if err != nil {
+				return nil, total, &database.Error{
+					Code:    database.INTERNAL_ERROR,
+					Message: err.Error(),
+				}
+			}
+			policy.Statements = apiStatements
 
 			// Assign policy
 			apiPolicies[i] = *policy
@@ -192,7 +235,10 @@ func (pr PostgresRepo) GetPoliciesFiltered(filter *api.Filter) ([]api.Policy, in
 	return apiPolicies, total, nil
 }
 
-func (pr PostgresRepo) UpdatePolicy(policy api.Policy) (*api.Policy, error) {
+// Query that blocks concurrent updates of a policy until the transaction ends
+const lockPolicyQuery = `SELECT id FROM policies WHERE id = ? FOR UPDATE`
+
+func (pr PostgresRepo) UpdatePolicy(policy api.Policy, author string, lastUpdateAt time.Time) (*api.Policy, error) {
 
 	policyDB := Policy{
 		ID:       policy.ID,
@@ -204,20 +250,56 @@ func (pr PostgresRepo) UpdatePolicy(policy api.Policy) (*api.Policy, error) {
 		Org:      policy.Org,
 	}
 
-	transaction := pr.Dbmap.Begin()
+	transaction := pr.begin()
+
+	// Concurrent updates of the policy wait until this transaction ends, so they don't read the same last version
+	if err := transaction.Exec(lockPolicyQuery, policy.ID).Error; err != nil {
+		pr.rollback(transaction)
+		return nil, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	// Retrieve last version. Policies created before versioning don't have any, so their current statements are
+	// stored as the first version before they are replaced
+	lastVersion := &PolicyVersion{}
+	query := transaction.Where("policy_id like ?", policy.ID).Order("version desc").First(lastVersion)
+	if err := query.Error; err != nil && !query.RecordNotFound() {
+		pr.rollback(transaction)
+		return nil, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+	if query.RecordNotFound() {
+		if err := createLegacyPolicyVersion(transaction, policy.ID); err != nil {
+			pr.rollback(transaction)
+			return nil, &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: err.Error(),
+			}
+		}
+		lastVersion.Version = 1
+	}
 
 	// Update policy
-	if err := transaction.Model(&Policy{ID: policy.ID}).Update(policyDB).Error; err != nil {
-		transaction.Rollback()
+	query = whereUpdateAt(transaction.Model(&Policy{ID: policy.ID}), lastUpdateAt).Update(policyDB)
+	if err := query.Error; err != nil {
+		pr.rollback(transaction)
 		return nil, &database.Error{
 			Code:    database.INTERNAL_ERROR,
 			Message: err.Error(),
 		}
 	}
+	if err := checkUpdateAt(query, lastUpdateAt, "Policy", policy.ID); err != nil {
+		pr.rollback(transaction)
+		return nil, err
+	}
 
 	// Clear old statements
 	if err := transaction.Where("policy_id like ?", policy.ID).Delete(Statement{}).Error; err != nil {
-		transaction.Rollback()
+		pr.rollback(transaction)
 		return nil, &database.Error{
 			Code:    database.INTERNAL_ERROR,
 			Message: err.Error(),
@@ -225,16 +307,28 @@ func (pr PostgresRepo) UpdatePolicy(policy api.Policy) (*api.Policy, error) {
 	}
 
 	// Create new statements
-	for _, s := range *policy.Statements {
+	for i, s := range *policy.Statements {
+		conditions, err := conditionsToString(s.Conditions)
+		if err != nil {
+			pr.rollback(transaction)
+			return nil, &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: err.Error(),
+			}
+		}
 		statementDB := &Statement{
-			ID:        uuid.NewV4().String(),
-			PolicyID:  policy.ID,
-			Effect:    s.Effect,
-			Actions:   stringArrayToString(s.Actions),
-			Resources: stringArrayToString(s.Resources),
+			ID:           uuid.NewV4().String(),
+			PolicyID:     policy.ID,
+			Effect:       s.Effect,
+			Actions:      stringArrayToString(s.Actions),
+			Resources:    stringArrayToString(s.Resources),
+			NotActions:   stringArrayToString(s.NotActions),
+			NotResources: stringArrayToString(s.NotResources),
+			Conditions:   conditions,
+			Ordinal:      i,
 		}
 		if err := transaction.Create(statementDB).Error; err != nil {
-			transaction.Rollback()
+			pr.rollback(transaction)
 			return nil, &database.Error{
 				Code:    database.INTERNAL_ERROR,
 				Message: err.Error(),
@@ -242,19 +336,51 @@ func (pr PostgresRepo) UpdatePolicy(policy api.Policy) (*api.Policy, error) {
 		}
 	}
 
-	transaction.Commit()
+	// Store new version
+	if err := createPolicyVersion(transaction, policy.ID, lastVersion.Version+1, author, policy.Statements, policy.UpdateAt); err != nil {
+		pr.rollback(transaction)
+		return nil, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	pr.commit(transaction)
 
 	return &policy, nil
 }
 
-func (pr PostgresRepo) RemovePolicy(id string) error {
+func (pr PostgresRepo) RemovePolicy(id string, lastUpdateAt time.Time) error {
 
-	transaction := pr.Dbmap.Begin()
+	transaction := pr.begin()
+
+	//  Delete policy
+	query := whereUpdateAt(transaction.Where("id like ?", id), lastUpdateAt).Delete(&Policy{})
+	if err := query.Error; err != nil {
+		pr.rollback(transaction)
+		return &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+	if err := checkUpdateAt(query, lastUpdateAt, "Policy", id); err != nil {
+		pr.rollback(transaction)
+		return err
+	}
 
 	// Delete policy relations (group)
 	transaction.Where("policy_id like ?", id).Delete(&GroupPolicyRelation{})
 	if err := transaction.Error; err != nil {
-		transaction.Rollback()
+		pr.rollback(transaction)
+		return &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+	// Delete policy relations (user)
+	transaction.Where("policy_id like ?", id).Delete(&UserPolicyRelation{})
+	if err := transaction.Error; err != nil {
+		pr.rollback(transaction)
 		return &database.Error{
 			Code:    database.INTERNAL_ERROR,
 			Message: err.Error(),
@@ -263,23 +389,22 @@ func (pr PostgresRepo) RemovePolicy(id string) error {
 	// Delete policy statements
 	transaction.Where("policy_id like ?", id).Delete(&Statement{})
 	if err := transaction.Error; err != nil {
-		transaction.Rollback()
+		pr.rollback(transaction)
 		return &database.Error{
 			Code:    database.INTERNAL_ERROR,
 			Message: err.Error(),
 		}
 	}
-	//  Delete policy
-	transaction.Where("id like ?", id).Delete(&Policy{})
+	// Delete policy versions, they can't be retrieved without the policy
+	transaction.Where("policy_id like ?", id).Delete(&PolicyVersion{})
 	if err := transaction.Error; err != nil {
-		transaction.Rollback()
+		pr.rollback(transaction)
 		return &database.Error{
 			Code:    database.INTERNAL_ERROR,
 			Message: err.Error(),
 		}
 	}
-
-	transaction.Commit()
+	pr.commit(transaction)
 	return nil
 }
 
@@ -326,6 +451,134 @@ func (pr PostgresRepo) GetAttachedGroups(policyID string, filter *api.Filter) ([
 	return groups, total, nil
 }
 
+// Common table expression with the groups of a user, including the groups inherited through the group hierarchy
+const userGroupsCTE = `WITH RECURSIVE user_groups(group_id) AS (
+	SELECT group_id FROM group_user_relations WHERE user_id = ?
+	UNION
+	SELECT r.group_id FROM group_subgroup_relations r INNER JOIN user_groups g ON r.subgroup_id = g.group_id
+)`
+
+// Query that retrieves the IDs of the groups of a user, including the groups inherited through the group hierarchy
+const userGroupsQuery = userGroupsCTE + `
+SELECT group_id FROM user_groups ORDER BY group_id`
+
+// Query that retrieves the statements of every policy reachable from a user: policies attached to the groups of the
+// user, including the groups inherited through the group hierarchy, and policies attached directly to the user
+const userPoliciesQuery = userGroupsCTE + `, user_policy_ids(policy_id) AS (
+	SELECT policy_id FROM group_policy_relations WHERE group_id IN (SELECT group_id FROM user_groups)
+	UNION
+	SELECT policy_id FROM user_policies WHERE user_id = ?
+)
+SELECT p.id, p.name, p.path, p.org, p.create_at, p.update_at, p.urn,
+	s.effect, s.actions, s.resources, s.not_actions, s.not_resources, s.conditions
+FROM policies p
+INNER JOIN user_policy_ids u ON u.policy_id = p.id
+INNER JOIN statements s ON s.policy_id = p.id
+ORDER BY p.id, s.ordinal, s.id`
+
+func (pr PostgresRepo) GetPoliciesForUser(userID string) ([]api.Policy, []string, error) {
+	// Groups and policies are read in the same snapshot of database
+	transaction := pr.Dbmap
+	if !pr.inTransaction {
+		transaction = pr.Dbmap.Begin()
+		if err := transaction.Error; err != nil {
+			return nil, nil, &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: err.Error(),
+			}
+		}
+		defer transaction.Rollback()
+		if err := transaction.Exec("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY").Error; err != nil {
+			return nil, nil, &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: err.Error(),
+			}
+		}
+	}
+
+	groupIDs := []string{}
+	groupRows, err := transaction.Raw(userGroupsQuery, userID).Rows()
+	// Error Handling
+	if err != nil {
+		return nil, nil, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+	defer groupRows.Close()
+	for groupRows.Next() {
+		var groupID string
+		if err := groupRows.Scan(&groupID); err != nil {
+			return nil, nil, &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: err.Error(),
+			}
+		}
+		groupIDs = append(groupIDs, groupID)
+	}
+	if err := groupRows.Err(); err != nil {
+		return nil, nil, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	rows, err := transaction.Raw(userPoliciesQuery, userID, userID).Rows()
+	// Error Handling
+	if err != nil {
+		return nil, nil, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+	defer rows.Close()
+
+	policies := []Policy{}
+	statements := map[string][]Statement{}
+	for rows.Next() {
+		policy := Policy{}
+		statement := Statement{}
+		err := rows.Scan(&policy.ID, &policy.Name, &policy.Path, &policy.Org, &policy.CreateAt, &policy.UpdateAt, &policy.Urn,
+			&statement.Effect, &statement.Actions, &statement.Resources, &statement.NotActions, &statement.NotResources,
+			&statement.Conditions)
+		// Error Handling
+		if err != nil {
+			return nil, nil, &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: err.Error(),
+			}
+		}
+
+		if _, ok := statements[policy.ID]; !ok {
+			policies = append(policies, policy)
+		}
+		statements[policy.ID] = append(statements[policy.ID], statement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	// Transform policies to API domain
+	apiPolicies := make([]api.Policy, len(policies))
+	for i, p := range policies {
+		policy := dbPolicyToAPIPolicy(&p)
+		apiStatements, err := dbStatementsToAPIStatements(statements[p.ID])
+		if err != nil {
+			return nil, nil, &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: err.Error(),
+			}
+		}
+		policy.Statements = apiStatements
+		apiPolicies[i] = *policy
+	}
+
+	return apiPolicies, groupIDs, nil
+}
+
 // PRIVATE HELPER METHODS
 
 // Transform a policy retrieved from db into a policy for API
@@ -341,18 +594,26 @@ func dbPolicyToAPIPolicy(policydb *Policy) *api.Policy {
 	}
 }
 
-// Transform a list of statements from db into API statements
-func dbStatementsToAPIStatements(statements []Statement) *[]api.Statement {
+// Transform a list of statements from db into API statements. Statements with malformed conditions return an error,
+// so they are never evaluated without their conditions
+func dbStatementsToAPIStatements(statements []Statement) (*[]api.Statement, error) {
 	statementsApi := make([]api.Statement, len(statements), cap(statements))
 	for i, s := range statements {
+		conditions, err := stringToConditions(s.Conditions)
+		if err != nil {
+			return nil, err
+		}
 		statementsApi[i] = api.Statement{
-			Actions:   strings.Split(s.Actions, ";"),
-			Effect:    s.Effect,
-			Resources: strings.Split(s.Resources, ";"),
+			Actions:      stringToStringArray(s.Actions),
+			Effect:       s.Effect,
+			Resources:    stringToStringArray(s.Resources),
+			NotActions:   stringToStringArray(s.NotActions),
+			NotResources: stringToStringArray(s.NotResources),
+			Conditions:   conditions,
 		}
 	}
 
-	return &statementsApi
+	return &statementsApi, nil
 }
 
 // Transform an array of strings into a semicolon-separated string
@@ -368,3 +629,38 @@ func stringArrayToString(array []string) string {
 
 	return stringVal
 }
+
+// Transform a semicolon-separated string into an array of strings. Empty strings are returned as nil
+func stringToStringArray(value string) []string {
+	if len(value) < 1 {
+		return nil
+	}
+
+	return strings.Split(value, ";")
+}
+
+// Transform statement conditions into a JSON string. Empty conditions are stored as an empty string
+func conditionsToString(conditions api.Conditions) (string, error) {
+	if len(conditions) < 1 {
+		return "", nil
+	}
+	b, err := json.Marshal(conditions)
+	if err != nil {
+		return "", fmt.Errorf("Invalid statement conditions: %v", err)
+	}
+
+	return string(b), nil
+}
+
+// Transform a JSON string stored in db into statement conditions
+func stringToConditions(value string) (api.Conditions, error) {
+	if len(value) < 1 {
+		return nil, nil
+	}
+	conditions := api.Conditions{}
+	if err := json.Unmarshal([]byte(value), &conditions); err != nil {
+		return nil, fmt.Errorf("Invalid statement conditions: %v", err)
+	}
+
+	return conditions, nil
+}
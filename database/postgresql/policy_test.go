@@ -1,6 +1,7 @@
 package postgresql
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -110,12 +111,13 @@ func TestPostgresRepo_AddPolicy(t *testing.T) {
 		// Clean policy database
 		cleanPolicyTable(t, n)
 		cleanStatementTable(t, n)
+		cleanPolicyVersionTable(t, n)
 
 		// Call to repository to add a policy
 		if test.previousPolicy != nil {
 			insertPolicy(t, n, *test.previousPolicy, test.statements)
 		}
-		receivedPolicy, err := repoDB.AddPolicy(test.policy)
+		receivedPolicy, err := repoDB.AddPolicy(test.policy, "author")
 		if test.expectedError != nil {
 			dbError, _ := err.(*database.Error)
 			assert.Equal(t, test.expectedError, dbError, "Error in test case %v", n)
@@ -138,6 +140,16 @@ func TestPostgresRepo_AddPolicy(t *testing.T) {
 					stringArrayToString(statement.Resources))
 				assert.Equal(t, 1, statementNumber, "Error in test case %v", n)
 			}
+
+			// Check first version
+			version, err := repoDB.GetPolicyVersion(test.policy.ID, 1)
+			assert.Nil(t, err, "Error in test case %v", n)
+			assert.Equal(t, &api.PolicyVersion{
+				Version:    1,
+				Author:     "author",
+				CreateAt:   test.policy.CreateAt,
+				Statements: test.policy.Statements,
+			}, version, "Error in test case %v", n)
 		}
 	}
 }
@@ -280,6 +292,95 @@ func TestPostgresRepo_GetPolicyById(t *testing.T) {
 				},
 			},
 		},
+		"OkCaseWithConditions": {
+			id: "1234",
+			policy: &Policy{
+				ID:       "1234",
+				Name:     "test",
+				Org:      "org1",
+				Path:     "/path/",
+				CreateAt: now.UnixNano(),
+				UpdateAt: now.UnixNano(),
+				Urn:      api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "test"),
+			},
+			statements: []Statement{
+				{
+					ID:         "0123",
+					Effect:     "allow",
+					PolicyID:   "1234",
+					Actions:    api.USER_ACTION_GET_USER,
+					Resources:  api.GetUrnPrefix("", api.RESOURCE_USER, "/path/"),
+					Conditions: `{"IpAddress":{"foulkon:SourceIp":["10.0.0.0/8"]}}`,
+				},
+			},
+			expectedResponse: &api.Policy{
+				ID:       "1234",
+				Name:     "test",
+				Org:      "org1",
+				Path:     "/path/",
+				CreateAt: now,
+				UpdateAt: now,
+				Urn:      api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "test"),
+				Statements: &[]api.Statement{
+					{
+						Effect: "allow",
+						Actions: []string{
+							api.USER_ACTION_GET_USER,
+						},
+						Resources: []string{
+							api.GetUrnPrefix("", api.RESOURCE_USER, "/path/"),
+						},
+						Conditions: api.Conditions{
+							api.CONDITION_IP_ADDRESS: {
+								api.CONDITION_KEY_SOURCE_IP: []string{"10.0.0.0/8"},
+							},
+						},
+					},
+				},
+			},
+		},
+		"OkCaseWithNotActionsAndNotResources": {
+			id: "1234",
+			policy: &Policy{
+				ID:       "1234",
+				Name:     "test",
+				Org:      "org1",
+				Path:     "/path/",
+				CreateAt: now.UnixNano(),
+				UpdateAt: now.UnixNano(),
+				Urn:      api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "test"),
+			},
+			statements: []Statement{
+				{
+					ID:           "0123",
+					Effect:       "allow",
+					PolicyID:     "1234",
+					NotActions:   api.USER_ACTION_DELETE_USER + ";" + api.USER_ACTION_UPDATE_USER,
+					NotResources: api.GetUrnPrefix("", api.RESOURCE_USER, "/admin/"),
+				},
+			},
+			expectedResponse: &api.Policy{
+				ID:       "1234",
+				Name:     "test",
+				Org:      "org1",
+				Path:     "/path/",
+				CreateAt: now,
+				UpdateAt: now,
+				Urn:      api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "test"),
+				Statements: &[]api.Statement{
+					{
+						Effect: "allow",
+						NotActions: []string{
+							api.USER_ACTION_DELETE_USER,
+							api.USER_ACTION_UPDATE_USER,
+						},
+						NotResources: []string{
+							api.GetUrnPrefix("", api.RESOURCE_USER, "/admin/"),
+						},
+					},
+				},
+			},
+		},
 		"ErrorCaseNotFound": {
 			id: "1234",
 			expectedError: &database.Error{
@@ -287,6 +388,32 @@ func TestPostgresRepo_GetPolicyById(t *testing.T) {
 				Message: "Policy with id 1234 not found",
 			},
 		},
+		"ErrorCaseInvalidConditions": {
+			id: "1234",
+			policy: &Policy{
+				ID:       "1234",
+				Name:     "test",
+				Org:      "org1",
+				Path:     "/path/",
+				CreateAt: now.UnixNano(),
+				UpdateAt: now.UnixNano(),
+				Urn:      api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "test"),
+			},
+			statements: []Statement{
+				{
+					ID:         "0123",
+					Effect:     "allow",
+					PolicyID:   "1234",
+					Actions:    api.USER_ACTION_GET_USER,
+					Resources:  api.GetUrnPrefix("", api.RESOURCE_USER, "/path/"),
+					Conditions: `{"IpAddress":`,
+				},
+			},
+			expectedError: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Invalid statement conditions: unexpected end of JSON input",
+			},
+		},
 	}
 
 	for n, test := range testcases {
@@ -446,11 +573,87 @@ func TestPostgresRepo_UpdatePolicy(t *testing.T) {
 	testcases := map[string]struct {
 		previousPolicies   []Policy
 		previousStatements []Statement
+		previousVersions   []PolicyVersion
 		policy             *api.Policy
 		// Expected result
 		expectedResponse *api.Policy
+		// Version stored by update
+		expectedVersion int
+		// First version expected when policy didn't have any before update
+		expectedLegacyVersion *api.PolicyVersion
 	}{
 		"OkCase": {
+			previousPolicies: []Policy{
+				{
+					ID:       "test1",
+					Name:     "test",
+					Org:      "123",
+					Path:     "/path/",
+					CreateAt: now.UnixNano(),
+					UpdateAt: now.UnixNano(),
+					Urn:      api.CreateUrn("123", api.RESOURCE_POLICY, "/path/", "test"),
+				},
+			},
+			previousStatements: []Statement{
+				{
+					ID:        "1",
+					PolicyID:  "111",
+					Effect:    "allow",
+					Actions:   api.USER_ACTION_GET_USER,
+					Resources: api.GetUrnPrefix("", api.RESOURCE_USER, "/path/"),
+				},
+			},
+			previousVersions: []PolicyVersion{
+				{
+					ID:         "version1",
+					PolicyID:   "test1",
+					Version:    1,
+					Author:     "author",
+					Statements: "[]",
+					CreateAt:   now.UnixNano(),
+				},
+			},
+			policy: &api.Policy{
+				ID:       "test1",
+				Name:     "newName",
+				Org:      "123",
+				Path:     "/newPath/",
+				CreateAt: now,
+				Urn:      api.CreateUrn("123", api.RESOURCE_POLICY, "/newPath/", "newName"),
+				Statements: &[]api.Statement{
+					{
+						Effect: "allow",
+						Actions: []string{
+							api.USER_ACTION_GET_USER,
+						},
+						Resources: []string{
+							api.GetUrnPrefix("123", api.RESOURCE_USER, "/newPath/"),
+						},
+					},
+				},
+			},
+			expectedResponse: &api.Policy{
+				ID:       "test1",
+				Name:     "newName",
+				Org:      "123",
+				Path:     "/newPath/",
+				CreateAt: now,
+				Urn:      api.CreateUrn("123", api.RESOURCE_POLICY, "/newPath/", "newName"),
+				Statements: &[]api.Statement{
+					{
+						Effect: "allow",
+						Actions: []string{
+							api.USER_ACTION_GET_USER,
+						},
+						Resources: []string{
+							api.GetUrnPrefix("123", api.RESOURCE_USER, "/newPath/"),
+						},
+					},
+				},
+			},
+			expectedVersion: 2,
+		},
+		"OkCaseLegacyPolicy": {
 			previousPolicies: []Policy{
 				{
 					ID:       "test1",
@@ -509,6 +712,18 @@ func TestPostgresRepo_UpdatePolicy(t *testing.T) {
 					},
 				},
 			},
+			expectedVersion: 2,
+			expectedLegacyVersion: &api.PolicyVersion{
+				Version:  1,
+				CreateAt: time.Unix(0, now.UnixNano()).UTC(),
+				Statements: &[]api.Statement{
+					{
+						Effect:    "allow",
+						Actions:   []string{api.USER_ACTION_GET_USER},
+						Resources: []string{api.GetUrnPrefix("", api.RESOURCE_USER, "/path/")},
+					},
+				},
+			},
 		},
 	}
 
@@ -516,6 +731,7 @@ func TestPostgresRepo_UpdatePolicy(t *testing.T) {
 		// Clean policy database
 		cleanPolicyTable(t, n)
 		cleanStatementTable(t, n)
+		cleanPolicyVersionTable(t, n)
 
 		// Call to repository to add a policy
 		if test.previousPolicies != nil {
@@ -523,11 +739,53 @@ func TestPostgresRepo_UpdatePolicy(t *testing.T) {
 				insertPolicy(t, n, p, test.previousStatements)
 			}
 		}
-		receivedPolicy, err := repoDB.UpdatePolicy(*test.policy)
+		for _, v := range test.previousVersions {
+			insertPolicyVersion(t, n, v)
+		}
+		receivedPolicy, err := repoDB.UpdatePolicy(*test.policy, "author", time.Time{})
 		assert.Nil(t, err, "Error in test case %v", n)
 
 		// Check response
 		assert.Equal(t, test.expectedResponse, receivedPolicy, "Error in test case %v", n)
+
+		// Check previous statements are kept as first version
+		if test.expectedLegacyVersion != nil {
+			version, err := repoDB.GetPolicyVersion(test.policy.ID, 1)
+			assert.Nil(t, err, "Error in test case %v", n)
+			assert.Equal(t, test.expectedLegacyVersion, version, "Error in test case %v", n)
+		}
+
+		// Check a new version is stored in each update
+		version, err := repoDB.GetPolicyVersion(test.policy.ID, test.expectedVersion)
+		assert.Nil(t, err, "Error in test case %v", n)
+		assert.Equal(t, "author", version.Author, "Error in test case %v", n)
+		for v := test.expectedVersion + 1; v <= test.expectedVersion+2; v++ {
+			policy := *test.policy
+			policy.UpdateAt = now.Add(time.Duration(v) * time.Second)
+			_, err = repoDB.UpdatePolicy(policy, "author2", time.Time{})
+			assert.Nil(t, err, "Error in test case %v", n)
+			version, err := repoDB.GetPolicyVersion(test.policy.ID, v)
+			assert.Nil(t, err, "Error in test case %v", n)
+			assert.Equal(t, &api.PolicyVersion{
+				Version:    v,
+				Author:     "author2",
+				CreateAt:   policy.UpdateAt,
+				Statements: test.policy.Statements,
+			}, version, "Error in test case %v", n)
+		}
+
+		// Check policy isn't updated if it changed since the expected update date
+		lastUpdateAt := now.Add(time.Duration(test.expectedVersion+2) * time.Second)
+		staleUpdateAt := lastUpdateAt.Add(-time.Second)
+		_, err = repoDB.UpdatePolicy(*test.policy, "author3", staleUpdateAt)
+		assert.Equal(t, &database.Error{
+			Code:    database.PRECONDITION_FAILED,
+			Message: fmt.Sprintf("Policy with id %v has changed since %v", test.policy.ID, staleUpdateAt),
+		}, err, "Error in test case %v", n)
+		_, err = repoDB.GetPolicyVersion(test.policy.ID, test.expectedVersion+3)
+		assert.NotNil(t, err, "Error in test case %v", n)
+		_, err = repoDB.UpdatePolicy(*test.policy, "author3", lastUpdateAt)
+		assert.Nil(t, err, "Error in test case %v", n)
 	}
 }
 
@@ -615,11 +873,20 @@ func TestPostgresRepo_RemovePolicy(t *testing.T) {
 		cleanStatementTable(t, n)
 		cleanGroupTable(t, n)
 		cleanGroupPolicyRelationTable(t, n)
+		cleanPolicyVersionTable(t, n)
 
 		// insert previous policy
 		if test.previousPolicies != nil {
 			for _, p := range test.previousPolicies {
 				insertPolicy(t, n, p.policy, p.statements)
+				insertPolicyVersion(t, n, PolicyVersion{
+					ID:         p.policy.ID,
+					PolicyID:   p.policy.ID,
+					Version:    1,
+					Author:     "author",
+					Statements: "[]",
+					CreateAt:   p.policy.CreateAt,
+				})
 			}
 		}
 		if test.relations != nil {
@@ -627,7 +894,7 @@ func TestPostgresRepo_RemovePolicy(t *testing.T) {
 				insertGroupPolicyRelation(t, n, rel.groupID, rel.policyID, rel.createAt)
 			}
 		}
-		err := repoDB.RemovePolicy(test.policyToDelete)
+		err := repoDB.RemovePolicy(test.policyToDelete, time.Time{})
 		assert.Nil(t, err, "Error in test case %v", n)
 
 		// Check database
@@ -653,6 +920,11 @@ func TestPostgresRepo_RemovePolicy(t *testing.T) {
 
 		totalGroupPolicyRelationNumber := getGroupPolicyRelationCount(t, n, "", "")
 		assert.Equal(t, 1, totalGroupPolicyRelationNumber, "Error in test case %v", n)
+
+		versionNumber := getPolicyVersionsCount(t, n, test.policyToDelete)
+		assert.Equal(t, 0, versionNumber, "Error in test case %v", n)
+		totalVersionNumber := getPolicyVersionsCount(t, n, "")
+		assert.Equal(t, 1, totalVersionNumber, "Error in test case %v", n)
 	}
 }
 
@@ -767,6 +1039,234 @@ func TestPostgresRepo_GetAttachedGroups(t *testing.T) {
 	}
 }
 
+func TestPostgresRepo_GetPoliciesForUser(t *testing.T) {
+	now := time.Now().UTC()
+	policies := []Policy{
+		{
+			ID:       "PolicyID1",
+			Name:     "policy1",
+			Org:      "org1",
+			Path:     "/path/",
+			CreateAt: now.UnixNano(),
+			UpdateAt: now.UnixNano(),
+			Urn:      api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy1"),
+		},
+		{
+			ID:       "PolicyID2",
+			Name:     "policy2",
+			Org:      "org1",
+			Path:     "/path/",
+			CreateAt: now.UnixNano(),
+			UpdateAt: now.UnixNano(),
+			Urn:      api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy2"),
+		},
+		{
+			ID:       "PolicyID3",
+			Name:     "policy3",
+			Org:      "org1",
+			Path:     "/path/",
+			CreateAt: now.UnixNano(),
+			UpdateAt: now.UnixNano(),
+			Urn:      api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy3"),
+		},
+	}
+	statements := map[string][]Statement{
+		// Statements are returned by ordinal, not by ID
+		"PolicyID1": {
+			{
+				ID:        "StatementID2",
+				Effect:    "allow",
+				Actions:   api.USER_ACTION_GET_USER,
+				Resources: api.GetUrnPrefix("", api.RESOURCE_USER, "/path/"),
+				Ordinal:   0,
+			},
+			{
+				ID:        "StatementID1",
+				Effect:    "deny",
+				Actions:   api.USER_ACTION_DELETE_USER,
+				Resources: api.GetUrnPrefix("", api.RESOURCE_USER, "/path/"),
+				Ordinal:   1,
+			},
+		},
+		"PolicyID2": {
+			{
+				ID:        "StatementID3",
+				Effect:    "allow",
+				Actions:   api.GROUP_ACTION_GET_GROUP,
+				Resources: api.GetUrnPrefix("org1", api.RESOURCE_GROUP, "/path/"),
+			},
+		},
+		"PolicyID3": {
+			{
+				ID:        "StatementID4",
+				Effect:    "allow",
+				Actions:   api.POLICY_ACTION_GET_POLICY,
+				Resources: api.GetUrnPrefix("org1", api.RESOURCE_POLICY, "/path/"),
+			},
+		},
+	}
+	expectedPolicies := map[string]api.Policy{
+		"PolicyID1": {
+			ID:       "PolicyID1",
+			Name:     "policy1",
+			Org:      "org1",
+			Path:     "/path/",
+			CreateAt: now,
+			UpdateAt: now,
+			Urn:      api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy1"),
+			Statements: &[]api.Statement{
+				{
+					Effect:    "allow",
+					Actions:   []string{api.USER_ACTION_GET_USER},
+					Resources: []string{api.GetUrnPrefix("", api.RESOURCE_USER, "/path/")},
+				},
+				{
+					Effect:    "deny",
+					Actions:   []string{api.USER_ACTION_DELETE_USER},
+					Resources: []string{api.GetUrnPrefix("", api.RESOURCE_USER, "/path/")},
+				},
+			},
+		},
+		"PolicyID2": {
+			ID:       "PolicyID2",
+			Name:     "policy2",
+			Org:      "org1",
+			Path:     "/path/",
+			CreateAt: now,
+			UpdateAt: now,
+			Urn:      api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy2"),
+			Statements: &[]api.Statement{
+				{
+					Effect:    "allow",
+					Actions:   []string{api.GROUP_ACTION_GET_GROUP},
+					Resources: []string{api.GetUrnPrefix("org1", api.RESOURCE_GROUP, "/path/")},
+				},
+			},
+		},
+		"PolicyID3": {
+			ID:       "PolicyID3",
+			Name:     "policy3",
+			Org:      "org1",
+			Path:     "/path/",
+			CreateAt: now,
+			UpdateAt: now,
+			Urn:      api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy3"),
+			Statements: &[]api.Statement{
+				{
+					Effect:    "allow",
+					Actions:   []string{api.POLICY_ACTION_GET_POLICY},
+					Resources: []string{api.GetUrnPrefix("org1", api.RESOURCE_POLICY, "/path/")},
+				},
+			},
+		},
+	}
+	testcases := map[string]struct {
+		// Relations to insert
+		groupUserRelations     map[string]string
+		groupSubgroupRelations map[string]string
+		groupPolicyRelations   map[string][]string
+		userPolicyRelations    []string
+		// Postgres Repo Args
+		userID string
+		// Expected result
+		expectedResponse []api.Policy
+		expectedGroupIDs []string
+	}{
+		"OkCaseGroupPolicies": {
+			userID: "UserID1",
+			groupUserRelations: map[string]string{
+				"GroupID1": "UserID1",
+			},
+			groupPolicyRelations: map[string][]string{
+				"GroupID1": {"PolicyID1", "PolicyID2"},
+			},
+			expectedResponse: []api.Policy{
+				expectedPolicies["PolicyID1"],
+				expectedPolicies["PolicyID2"],
+			},
+			expectedGroupIDs: []string{"GroupID1"},
+		},
+		"OkCaseInheritedGroupPolicies": {
+			userID: "UserID1",
+			groupUserRelations: map[string]string{
+				"GroupID2": "UserID1",
+			},
+			groupSubgroupRelations: map[string]string{
+				"GroupID1": "GroupID2",
+			},
+			groupPolicyRelations: map[string][]string{
+				"GroupID1": {"PolicyID1"},
+				"GroupID2": {"PolicyID2"},
+			},
+			expectedResponse: []api.Policy{
+				expectedPolicies["PolicyID1"],
+				expectedPolicies["PolicyID2"],
+			},
+			expectedGroupIDs: []string{"GroupID1", "GroupID2"},
+		},
+		"OkCaseUserPolicies": {
+			userID: "UserID1",
+			groupUserRelations: map[string]string{
+				"GroupID1": "UserID1",
+			},
+			groupPolicyRelations: map[string][]string{
+				"GroupID1": {"PolicyID1"},
+			},
+			userPolicyRelations: []string{"PolicyID1", "PolicyID3"},
+			expectedResponse: []api.Policy{
+				expectedPolicies["PolicyID1"],
+				expectedPolicies["PolicyID3"],
+			},
+			expectedGroupIDs: []string{"GroupID1"},
+		},
+		"OkCaseOtherUser": {
+			userID: "UserID2",
+			groupUserRelations: map[string]string{
+				"GroupID1": "UserID1",
+			},
+			groupPolicyRelations: map[string][]string{
+				"GroupID1": {"PolicyID1"},
+			},
+			expectedResponse: []api.Policy{},
+			expectedGroupIDs: []string{},
+		},
+	}
+
+	for n, test := range testcases {
+		// Clean database
+		cleanPolicyTable(t, n)
+		cleanStatementTable(t, n)
+		cleanGroupUserRelationTable(t, n)
+		cleanGroupSubgroupRelationTable(t, n)
+		cleanGroupPolicyRelationTable(t, n)
+		cleanUserPolicyRelationTable(t, n)
+
+		// Insert previous data
+		for _, policy := range policies {
+			insertPolicy(t, n, policy, statements[policy.ID])
+		}
+		for groupID, userID := range test.groupUserRelations {
+			insertGroupUserRelation(t, n, userID, groupID, now.UnixNano())
+		}
+		for groupID, subgroupID := range test.groupSubgroupRelations {
+			insertGroupSubgroupRelation(t, n, groupID, subgroupID, now.UnixNano())
+		}
+		for groupID, policyIDs := range test.groupPolicyRelations {
+			for _, policyID := range policyIDs {
+				insertGroupPolicyRelation(t, n, groupID, policyID, now.UnixNano())
+			}
+		}
+		for _, policyID := range test.userPolicyRelations {
+			insertUserPolicyRelation(t, n, "UserID1", policyID, now.UnixNano())
+		}
+
+		receivedPolicies, groupIDs, err := repoDB.GetPoliciesForUser(test.userID)
+		assert.Nil(t, err, "Error in test case %v", n)
+		assert.Equal(t, test.expectedResponse, receivedPolicies, "Error in test case %v", n)
+		assert.Equal(t, test.expectedGroupIDs, groupIDs, "Error in test case %v", n)
+	}
+}
+
 func Test_dbPolicyToAPIPolicy(t *testing.T) {
 	now := time.Now().UTC()
 	testcases := map[string]struct {
@@ -806,6 +1306,7 @@ func Test_dbStatementsToAPIStatements(t *testing.T) {
 	testcases := map[string]struct {
 		dbStatements  []Statement
 		apiStatements *[]api.Statement
+		expectedError string
 	}{
 		"OkCase": {
 			dbStatements: []Statement{
@@ -869,10 +1370,28 @@ func Test_dbStatementsToAPIStatements(t *testing.T) {
 				},
 			},
 		},
+		"ErrorCaseInvalidConditions": {
+			dbStatements: []Statement{
+				{
+					ID:         "0123",
+					Effect:     "allow",
+					PolicyID:   "1234",
+					Actions:    api.USER_ACTION_GET_USER,
+					Resources:  api.GetUrnPrefix("", api.RESOURCE_USER, "/path/"),
+					Conditions: `{"IpAddress":`,
+				},
+			},
+			expectedError: "Invalid statement conditions: unexpected end of JSON input",
+		},
 	}
 
 	for n, test := range testcases {
-		receivedAPIStatements := dbStatementsToAPIStatements(test.dbStatements)
+		receivedAPIStatements, err := dbStatementsToAPIStatements(test.dbStatements)
+		if test.expectedError != "" {
+			assert.EqualError(t, err, test.expectedError, "Error in test case %v", n)
+		} else {
+			assert.Nil(t, err, "Error in test case %v", n)
+		}
 		// Check response
 		assert.Equal(t, test.apiStatements, receivedAPIStatements, "Error in test case %v", n)
 	}
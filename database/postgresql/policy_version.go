@@ -0,0 +1,159 @@
+package postgresql
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/database"
+	"github.com/jinzhu/gorm"
+	"github.com/satori/go.uuid"
+)
+
+// POLICY VERSION REPOSITORY IMPLEMENTATION
+
+func (pr PostgresRepo) GetPolicyVersions(policyID string, filter *api.Filter) ([]api.PolicyVersion, int, error) {
+	var total int
+	versions := []PolicyVersion{}
+	query := pr.Dbmap
+
+	if len(filter.OrderBy) > 0 {
+		query = query.Order(filter.OrderBy)
+	} else {
+		// Most recent versions first by default
+		query = query.Order("version desc")
+	}
+
+	// Error handling
+	if err := query.Where("policy_id like ?", policyID).Find(&versions).Count(&total).Offset(filter.Offset).Limit(filter.Limit).Find(&versions).Error; err != nil {
+		return nil, total, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	// Transform policy versions to API domain
+	var apiVersions []api.PolicyVersion
+	if versions != nil {
+		apiVersions = make([]api.PolicyVersion, len(versions), cap(versions))
+		for i, v := range versions {
+			apiVersion, err := dbPolicyVersionToAPIPolicyVersion(&v)
+			if err != nil {
+				return nil, total, &database.Error{
+					Code:    database.INTERNAL_ERROR,
+					Message: err.Error(),
+				}
+			}
+			apiVersions[i] = *apiVersion
+		}
+	}
+
+	return apiVersions, total, nil
+}
+
+func (pr PostgresRepo) GetPolicyVersion(policyID string, version int) (*api.PolicyVersion, error) {
+	policyVersion := &PolicyVersion{}
+	query := pr.Dbmap.Where("policy_id like ? AND version = ?", policyID, version).First(policyVersion)
+
+	// Check if version exists
+	if query.RecordNotFound() {
+		return nil, &database.Error{
+			Code:    database.POLICY_VERSION_NOT_FOUND,
+			Message: fmt.Sprintf("Version %v of policy with id %v not found", version, policyID),
+		}
+	}
+
+	// Error Handling
+	if err := query.Error; err != nil {
+		return nil, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	apiVersion, err := dbPolicyVersionToAPIPolicyVersion(policyVersion)
+	if err != nil {
+		return nil, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	return apiVersion, nil
+}
+
+// PRIVATE HELPER METHODS
+
+// Store a version of the policy with these statements inside the transaction
+func createPolicyVersion(transaction *gorm.DB, policyID string, version int, author string, statements *[]api.Statement,
+	createAt time.Time) error {
+	statementsString, err := statementsToString(statements)
+	if err != nil {
+		return err
+	}
+	versionDB := &PolicyVersion{
+		ID:         uuid.NewV4().String(),
+		PolicyID:   policyID,
+		Version:    version,
+		Author:     author,
+		Statements: statementsString,
+		CreateAt:   createAt.UTC().UnixNano(),
+	}
+	return transaction.Create(versionDB).Error
+}
+
+// Store the current statements of a policy created before versioning as its first version, without author, so
+// they aren't lost when the policy is updated
+func createLegacyPolicyVersion(transaction *gorm.DB, policyID string) error {
+	policyDB := &Policy{}
+	if err := transaction.Where("id like ?", policyID).First(policyDB).Error; err != nil {
+		return err
+	}
+	statements := []Statement{}
+	if err := transaction.Where("policy_id like ?", policyID).Order("ordinal, id").Find(&statements).Error; err != nil {
+		return err
+	}
+	apiStatements, err := dbStatementsToAPIStatements(statements)
+	if err != nil {
+		return err
+	}
+	return createPolicyVersion(transaction, policyID, 1, "", apiStatements, time.Unix(0, policyDB.UpdateAt))
+}
+
+// Transform a policy version retrieved from db into a policy version for API
+func dbPolicyVersionToAPIPolicyVersion(v *PolicyVersion) (*api.PolicyVersion, error) {
+	statements, err := stringToStatements(v.Statements)
+	if err != nil {
+		return nil, err
+	}
+	return &api.PolicyVersion{
+		Version:    v.Version,
+		Author:     v.Author,
+		CreateAt:   time.Unix(0, v.CreateAt).UTC(),
+		Statements: statements,
+	}, nil
+}
+
+// Transform statements into a JSON string
+func statementsToString(statements *[]api.Statement) (string, error) {
+	if statements == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(statements)
+	if err != nil {
+		return "", fmt.Errorf("Invalid policy version statements: %v", err)
+	}
+
+	return string(b), nil
+}
+
+// Transform a JSON string stored in db into statements
+func stringToStatements(value string) (*[]api.Statement, error) {
+	statements := []api.Statement{}
+	if err := json.Unmarshal([]byte(value), &statements); err != nil {
+		return nil, fmt.Errorf("Invalid policy version statements: %v", err)
+	}
+
+	return &statements, nil
+}
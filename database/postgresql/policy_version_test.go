@@ -0,0 +1,172 @@
+package postgresql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresRepo_GetPolicyVersions(t *testing.T) {
+	now := time.Now().UTC()
+	previousVersions := []PolicyVersion{
+		{
+			ID:         "VersionID1",
+			PolicyID:   "PolicyID",
+			Version:    1,
+			Author:     "user1",
+			Statements: `[{"effect":"allow","actions":["iam:GetUser"],"resources":["urn:iws:iam::user/path/*"]}]`,
+			CreateAt:   now.Add(-time.Hour).UnixNano(),
+		},
+		{
+			ID:         "VersionID2",
+			PolicyID:   "PolicyID",
+			Version:    2,
+			Author:     "user2",
+			Statements: `[{"effect":"deny","actions":["iam:GetUser"],"resources":["urn:iws:iam::user/path/*"]}]`,
+			CreateAt:   now.UnixNano(),
+		},
+		{
+			ID:         "VersionID3",
+			PolicyID:   "PolicyID2",
+			Version:    1,
+			Author:     "user1",
+			Statements: `[]`,
+			CreateAt:   now.UnixNano(),
+		},
+	}
+	apiVersions := make([]api.PolicyVersion, len(previousVersions))
+	for i, v := range previousVersions {
+		apiVersion, err := dbPolicyVersionToAPIPolicyVersion(&v)
+		assert.Nil(t, err, "Error in version %v", v.ID)
+		apiVersions[i] = *apiVersion
+	}
+	testcases := map[string]struct {
+		// Postgres Repo Args
+		policyID string
+		filter   *api.Filter
+		// Expected result
+		expectedResponse []api.PolicyVersion
+		expectedTotal    int
+	}{
+		"OkCase": {
+			policyID: "PolicyID",
+			filter: &api.Filter{
+				Limit: 20,
+			},
+			expectedResponse: []api.PolicyVersion{apiVersions[1], apiVersions[0]},
+			expectedTotal:    2,
+		},
+		"OkCaseOrderByAndPagination": {
+			policyID: "PolicyID",
+			filter: &api.Filter{
+				OrderBy: "version asc",
+				Offset:  1,
+				Limit:   1,
+			},
+			expectedResponse: []api.PolicyVersion{apiVersions[1]},
+			expectedTotal:    2,
+		},
+		"OkCaseWithoutVersions": {
+			policyID: "UnknownPolicyID",
+			filter: &api.Filter{
+				Limit: 20,
+			},
+			expectedResponse: []api.PolicyVersion{},
+			expectedTotal:    0,
+		},
+	}
+
+	for n, test := range testcases {
+		// Clean policy version database
+		cleanPolicyVersionTable(t, n)
+
+		// Insert previous data
+		for _, v := range previousVersions {
+			insertPolicyVersion(t, n, v)
+		}
+
+		// Call to repository to get policy versions
+		versions, total, err := repoDB.GetPolicyVersions(test.policyID, test.filter)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		// Check response
+		assert.Equal(t, test.expectedTotal, total, "Error in test case %v", n)
+		assert.Equal(t, test.expectedResponse, versions, "Error in test case %v", n)
+	}
+}
+
+func TestPostgresRepo_GetPolicyVersion(t *testing.T) {
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// Previous data
+		previousVersion PolicyVersion
+		// Postgres Repo Args
+		policyID string
+		version  int
+		// Expected result
+		expectedResponse *api.PolicyVersion
+		expectedError    *database.Error
+	}{
+		"OkCase": {
+			previousVersion: PolicyVersion{
+				ID:         "VersionID1",
+				PolicyID:   "PolicyID",
+				Version:    1,
+				Author:     "user1",
+				Statements: `[{"effect":"allow","actions":["iam:GetUser"],"resources":["urn:iws:iam::user/path/*"]}]`,
+				CreateAt:   now.UnixNano(),
+			},
+			policyID: "PolicyID",
+			version:  1,
+			expectedResponse: &api.PolicyVersion{
+				Version:  1,
+				Author:   "user1",
+				CreateAt: now,
+				Statements: &[]api.Statement{
+					{
+						Effect:    "allow",
+						Actions:   []string{api.USER_ACTION_GET_USER},
+						Resources: []string{api.GetUrnPrefix("", api.RESOURCE_USER, "/path/")},
+					},
+				},
+			},
+		},
+		"ErrorCaseVersionNotFound": {
+			previousVersion: PolicyVersion{
+				ID:         "VersionID1",
+				PolicyID:   "PolicyID",
+				Version:    1,
+				Author:     "user1",
+				Statements: `[]`,
+				CreateAt:   now.UnixNano(),
+			},
+			policyID: "PolicyID",
+			version:  2,
+			expectedError: &database.Error{
+				Code:    database.POLICY_VERSION_NOT_FOUND,
+				Message: "Version 2 of policy with id PolicyID not found",
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		// Clean policy version database
+		cleanPolicyVersionTable(t, n)
+
+		// Insert previous data
+		insertPolicyVersion(t, n, test.previousVersion)
+
+		// Call to repository to get the policy version
+		version, err := repoDB.GetPolicyVersion(test.policyID, test.version)
+		if test.expectedError != nil {
+			dbError, _ := err.(*database.Error)
+			assert.Equal(t, test.expectedError, dbError, "Error in test case %v", n)
+		} else {
+			assert.Nil(t, err, "Error in test case %v", n)
+			assert.Equal(t, test.expectedResponse, version, "Error in test case %v", n)
+		}
+	}
+}
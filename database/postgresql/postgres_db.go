@@ -12,6 +12,9 @@ import (
 
 type PostgresRepo struct {
 	Dbmap *gorm.DB
+	// True when Dbmap is a transaction started by RunInTransaction. Repo methods join it
+	// instead of starting their own
+	inTransaction bool
 }
 
 func InitDb(datasourcename string, idleConns string, maxOpenConns string, connTTL string) (*gorm.DB, error) {
@@ -46,7 +49,8 @@ func InitDb(datasourcename string, idleConns string, maxOpenConns string, connTT
 
 	// Create tables if not exist
 	err = db.AutoMigrate(&User{}, &Group{}, &Policy{}, &Statement{}, &GroupUserRelation{}, &GroupPolicyRelation{},
-		&ProxyResource{}, &OidcProvider{}, &OidcClient{}).Error
+		&UserPolicyRelation{}, &GroupSubgroupRelation{}, &ProxyResource{}, &OidcProvider{}, &OidcClient{}, &AuditEvent{},
+		&AuthzDecision{}, &PolicyVersion{}).Error
 	if err != nil {
 		return nil, err
 	}
@@ -103,11 +107,17 @@ func (Policy) TableName() string {
 
 // Statement table
 type Statement struct {
-	ID        string `gorm:"primary_key"`
-	PolicyID  string `gorm:"not null"`
-	Effect    string `gorm:"not null"`
-	Actions   string `gorm:"not null"`
-	Resources string `gorm:"not null"`
+	ID           string `gorm:"primary_key"`
+	PolicyID     string `gorm:"not null"`
+	Effect       string `gorm:"not null"`
+	Actions      string `gorm:"not null"`
+	Resources    string `gorm:"not null"`
+	NotActions   string `gorm:"not null;default:''"`
+	NotResources string `gorm:"not null;default:''"`
+	Conditions   string `gorm:"not null;default:''"`
+	// Position of the statement in its policy. Statements stored before this column existed have 0 until their policy
+	// is updated, so they are ordered by ID
+	Ordinal int `gorm:"not null;default:0"`
 }
 
 // Statement's table name
@@ -115,6 +125,21 @@ func (Statement) TableName() string {
 	return "statements"
 }
 
+// Policy version table
+type PolicyVersion struct {
+	ID         string `gorm:"primary_key"`
+	PolicyID   string `gorm:"not null;unique_index:idx_policy_version"`
+	Version    int    `gorm:"not null;unique_index:idx_policy_version"`
+	Author     string `gorm:"not null"`
+	Statements string `gorm:"not null"`
+	CreateAt   int64  `gorm:"not null"`
+}
+
+// PolicyVersion's table name
+func (PolicyVersion) TableName() string {
+	return "policy_versions"
+}
+
 // Group-Users Relationship
 type GroupUserRelation struct {
 	UserID   string `gorm:"primary_key"`
@@ -139,27 +164,59 @@ func (GroupPolicyRelation) TableName() string {
 	return "group_policy_relations"
 }
 
+// Group Subgroup table
+type GroupSubgroupRelation struct {
+	GroupID    string `gorm:"primary_key"`
+	SubgroupID string `gorm:"primary_key"`
+	CreateAt   int64  `gorm:"not null"`
+}
+
+// GroupSubgroupRelation's table name
+func (GroupSubgroupRelation) TableName() string {
+	return "group_subgroup_relations"
+}
+
+// User Policy table
+type UserPolicyRelation struct {
+	UserID   string `gorm:"primary_key"`
+	PolicyID string `gorm:"primary_key"`
+	CreateAt int64  `gorm:"not null"`
+}
+
+// UserPolicyRelation's table name
+func (UserPolicyRelation) TableName() string {
+	return "user_policies"
+}
+
 func (pr PostgresRepo) OrderByValidColumns(action string) []string {
 	switch action {
 	case api.USER_ACTION_LIST_USERS:
 		return []string{"path", "external_id", "create_at", "update_at", "urn"}
 	case api.USER_ACTION_LIST_GROUPS_FOR_USER:
 		return []string{"create_at"}
+	case api.USER_ACTION_LIST_ATTACHED_USER_POLICIES:
+		return []string{"create_at"}
 	case api.GROUP_ACTION_LIST_GROUPS:
 		return []string{"name", "path", "org", "create_at", "update_at", "urn"}
 	case api.GROUP_ACTION_LIST_MEMBERS:
 		return []string{"create_at"}
 	case api.GROUP_ACTION_LIST_ATTACHED_GROUP_POLICIES:
 		return []string{"create_at"}
+	case api.GROUP_ACTION_LIST_SUBGROUPS:
+		return []string{"create_at"}
 	case api.POLICY_ACTION_LIST_POLICIES:
 		return []string{"name", "path", "org", "create_at", "update_at", "urn"}
 	case api.POLICY_ACTION_LIST_ATTACHED_GROUPS:
 		return []string{"create_at"}
+	case api.POLICY_ACTION_LIST_POLICY_VERSIONS:
+		return []string{"version", "author", "create_at"}
 	case api.PROXY_ACTION_LIST_RESOURCES:
 		return []string{"name", "path", "org", "host", "path_resource", "method",
 			"urn_resource", "urn", "action", "create_at", "update_at"}
 	case api.AUTH_OIDC_ACTION_LIST_PROVIDERS:
 		return []string{"name", "path", "create_at", "update_at", "urn"}
+	case api.AUDIT_ACTION_LIST_EVENTS:
+		return []string{"actor", "action", "urn", "create_at"}
 	default:
 		return nil
 	}
@@ -213,3 +270,39 @@ type OidcClient struct {
 func (OidcClient) TableName() string {
 	return "oidc_clients"
 }
+
+// Audit event table
+type AuditEvent struct {
+	ID        string `gorm:"primary_key"`
+	Actor     string `gorm:"not null;index"`
+	RequestID string `gorm:"not null"`
+	Action    string `gorm:"not null"`
+	Urn       string `gorm:"not null;index"`
+	Before    string `gorm:"not null;default:''"`
+	After     string `gorm:"not null;default:''"`
+	CreateAt  int64  `gorm:"not null;index"`
+}
+
+// AuditEvent's table name
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
+
+// Authorization decision table
+type AuthzDecision struct {
+	ID              string `gorm:"primary_key"`
+	Source          string `gorm:"not null"`
+	RequestID       string `gorm:"not null;index"`
+	WorkerRequestID string `gorm:"not null;default:''"`
+	Identifier      string `gorm:"not null;index"`
+	Action          string `gorm:"not null"`
+	Requested       string `gorm:"not null;default:''"`
+	Allowed         string `gorm:"not null;default:''"`
+	Latency         int64  `gorm:"not null"`
+	CreateAt        int64  `gorm:"not null;index"`
+}
+
+// AuthzDecision's table name
+func (AuthzDecision) TableName() string {
+	return "authz_decisions"
+}
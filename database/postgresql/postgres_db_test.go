@@ -85,6 +85,10 @@ func TestPostgresRepo_OrderByValidColumns(t *testing.T) {
 			action:          api.USER_ACTION_LIST_GROUPS_FOR_USER,
 			expectedColumns: []string{"create_at"},
 		},
+		"OkCaseAction-" + api.USER_ACTION_LIST_ATTACHED_USER_POLICIES: {
+			action:          api.USER_ACTION_LIST_ATTACHED_USER_POLICIES,
+			expectedColumns: []string{"create_at"},
+		},
 		"OkCaseAction-" + api.GROUP_ACTION_LIST_GROUPS: {
 			action:          api.GROUP_ACTION_LIST_GROUPS,
 			expectedColumns: []string{"name", "path", "org", "create_at", "update_at", "urn"},
@@ -97,6 +101,10 @@ func TestPostgresRepo_OrderByValidColumns(t *testing.T) {
 			action:          api.GROUP_ACTION_LIST_ATTACHED_GROUP_POLICIES,
 			expectedColumns: []string{"create_at"},
 		},
+		"OkCaseAction-" + api.GROUP_ACTION_LIST_SUBGROUPS: {
+			action:          api.GROUP_ACTION_LIST_SUBGROUPS,
+			expectedColumns: []string{"create_at"},
+		},
 		"OkCaseAction-" + api.POLICY_ACTION_LIST_POLICIES: {
 			action:          api.POLICY_ACTION_LIST_POLICIES,
 			expectedColumns: []string{"name", "path", "org", "create_at", "update_at", "urn"},
@@ -105,11 +113,19 @@ func TestPostgresRepo_OrderByValidColumns(t *testing.T) {
 			action:          api.POLICY_ACTION_LIST_ATTACHED_GROUPS,
 			expectedColumns: []string{"create_at"},
 		},
+		"OkCaseAction-" + api.POLICY_ACTION_UPDATE_POLICY: {
+			action:          api.POLICY_ACTION_UPDATE_POLICY,
+			expectedColumns: []string{"version", "author", "create_at"},
+		},
 		"OkCaseAction-" + api.PROXY_ACTION_LIST_RESOURCES: {
 			action: api.PROXY_ACTION_LIST_RESOURCES,
 			expectedColumns: []string{"name", "path", "org", "host", "path_resource", "method",
 				"urn_resource", "urn", "action", "create_at", "update_at"},
 		},
+		"OkCaseAction-" + api.AUDIT_ACTION_LIST_EVENTS: {
+			action:          api.AUDIT_ACTION_LIST_EVENTS,
+			expectedColumns: []string{"actor", "action", "urn", "create_at"},
+		},
 		"OkCaseOtherActions": {
 			action:          "other",
 			expectedColumns: nil,
@@ -247,6 +263,62 @@ func cleanGroupPolicyRelationTable(t *testing.T, testcase string) {
 	assert.Nil(t, err, "Error in test case %v", testcase)
 }
 
+func cleanGroupSubgroupRelationTable(t *testing.T, testcase string) {
+	err := repoDB.Dbmap.Delete(&GroupSubgroupRelation{}).Error
+	assert.Nil(t, err, "Error in test case %v", testcase)
+}
+
+func getGroupSubgroupRelationCount(t *testing.T, testcase string, groupID string, subgroupID string) int {
+	query := repoDB.Dbmap.Table(GroupSubgroupRelation{}.TableName())
+	if groupID != "" {
+		query = query.Where("group_id = ?", groupID)
+	}
+	if subgroupID != "" {
+		query = query.Where("subgroup_id = ?", subgroupID)
+	}
+	var number int
+	err := query.Count(&number).Error
+	assert.Nil(t, err, "Error in test case %v", testcase)
+
+	return number
+}
+
+func insertGroupSubgroupRelation(t *testing.T, testcase string, groupID string, subgroupID string, createAt int64) {
+	err := repoDB.Dbmap.Exec("INSERT INTO public.group_subgroup_relations (group_id, subgroup_id, create_at) VALUES (?, ?, ?)",
+		groupID, subgroupID, createAt).Error
+
+	// Error handling
+	assert.Nil(t, err, "Error in test case %v", testcase)
+}
+
+func cleanUserPolicyRelationTable(t *testing.T, testcase string) {
+	err := repoDB.Dbmap.Delete(&UserPolicyRelation{}).Error
+	assert.Nil(t, err, "Error in test case %v", testcase)
+}
+
+func getUserPolicyRelationCount(t *testing.T, testcase string, policyID string, userID string) int {
+	query := repoDB.Dbmap.Table(UserPolicyRelation{}.TableName())
+	if policyID != "" {
+		query = query.Where("policy_id = ?", policyID)
+	}
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	var number int
+	err := query.Count(&number).Error
+	assert.Nil(t, err, "Error in test case %v", testcase)
+
+	return number
+}
+
+func insertUserPolicyRelation(t *testing.T, testcase string, userID string, policyID string, createAt int64) {
+	err := repoDB.Dbmap.Exec("INSERT INTO public.user_policies (user_id, policy_id, create_at) VALUES (?, ?, ?)",
+		userID, policyID, createAt).Error
+
+	// Error handling
+	assert.Nil(t, err, "Error in test case %v", testcase)
+}
+
 // POLICY
 
 func cleanPolicyTable(t *testing.T, testcase string) {
@@ -275,8 +347,9 @@ func insertPolicy(t *testing.T, testcase string, policy Policy, statements []Sta
 }
 
 func insertStatements(t *testing.T, testcase string, statement Statement) {
-	err := repoDB.Dbmap.Exec("INSERT INTO public.statements (id, policy_id, effect, actions, resources) VALUES (?, ?, ?, ?, ?)",
-		statement.ID, statement.PolicyID, statement.Effect, statement.Actions, statement.Resources).Error
+	err := repoDB.Dbmap.Exec("INSERT INTO public.statements (id, policy_id, effect, actions, resources, not_actions, not_resources, conditions, ordinal) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		statement.ID, statement.PolicyID, statement.Effect, statement.Actions, statement.Resources, statement.NotActions,
+		statement.NotResources, statement.Conditions, statement.Ordinal).Error
 
 	// Error handling
 	assert.Nil(t, err, "Error in test case %v", testcase)
@@ -489,3 +562,79 @@ func getOidcClientsCountFiltered(t *testing.T, testcase string,
 
 	return number
 }
+
+// AUDIT
+
+func cleanAuditEventsTable(t *testing.T, testcase string) {
+	err := repoDB.Dbmap.Delete(&AuditEvent{}).Error
+	assert.Nil(t, err, "Error in test case %v", testcase)
+}
+
+func insertAuditEvent(t *testing.T, testcase string, event AuditEvent) {
+	err := repoDB.Dbmap.Exec("INSERT INTO public.audit_events (id, actor, request_id, action, urn, before, after, create_at) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		event.ID, event.Actor, event.RequestID, event.Action, event.Urn, event.Before, event.After, event.CreateAt).Error
+
+	// Error handling
+	assert.Nil(t, err, "Error in test case %v", testcase)
+}
+
+func getAuditEventsCountFiltered(t *testing.T, testcase string, id string, actor string, action string, urn string) int {
+	query := repoDB.Dbmap.Table(AuditEvent{}.TableName())
+	if id != "" {
+		query = query.Where("id = ?", id)
+	}
+	if actor != "" {
+		query = query.Where("actor = ?", actor)
+	}
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if urn != "" {
+		query = query.Where("urn = ?", urn)
+	}
+	var number int
+	err := query.Count(&number).Error
+	assert.Nil(t, err, "Error in test case %v", testcase)
+
+	return number
+}
+
+func cleanAuthzDecisionsTable(t *testing.T, testcase string) {
+	err := repoDB.Dbmap.Delete(&AuthzDecision{}).Error
+	assert.Nil(t, err, "Error in test case %v", testcase)
+}
+
+func getAuthzDecisions(t *testing.T, testcase string) []AuthzDecision {
+	decisions := []AuthzDecision{}
+	err := repoDB.Dbmap.Order("create_at").Find(&decisions).Error
+	assert.Nil(t, err, "Error in test case %v", testcase)
+
+	return decisions
+}
+
+func cleanPolicyVersionTable(t *testing.T, testcase string) {
+	err := repoDB.Dbmap.Delete(&PolicyVersion{}).Error
+	assert.Nil(t, err, "Error in test case %v", testcase)
+}
+
+func insertPolicyVersion(t *testing.T, testcase string, version PolicyVersion) {
+	err := repoDB.Dbmap.Exec("INSERT INTO public.policy_versions (id, policy_id, version, author, statements, create_at) "+
+		"VALUES (?, ?, ?, ?, ?, ?)",
+		version.ID, version.PolicyID, version.Version, version.Author, version.Statements, version.CreateAt).Error
+
+	// Error handling
+	assert.Nil(t, err, "Error in test case %v", testcase)
+}
+
+func getPolicyVersionsCount(t *testing.T, testcase string, policyID string) int {
+	query := repoDB.Dbmap.Table(PolicyVersion{}.TableName())
+	if policyID != "" {
+		query = query.Where("policy_id = ?", policyID)
+	}
+	var number int
+	err := query.Count(&number).Error
+	assert.Nil(t, err, "Error in test case %v", testcase)
+
+	return number
+}
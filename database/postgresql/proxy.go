@@ -99,7 +99,7 @@ func (pr PostgresRepo) AddProxyResource(proxyResource api.ProxyResource) (*api.P
 	return dbResourceToApiResource(proxyResourceDB), nil
 }
 
-func (pr PostgresRepo) UpdateProxyResource(proxyResource api.ProxyResource) (*api.ProxyResource, error) {
+func (pr PostgresRepo) UpdateProxyResource(proxyResource api.ProxyResource, lastUpdateAt time.Time) (*api.ProxyResource, error) {
 	proxyResourceDB := &ProxyResource{
 		ID:           proxyResource.ID,
 		Name:         proxyResource.Name,
@@ -116,7 +116,7 @@ func (pr PostgresRepo) UpdateProxyResource(proxyResource api.ProxyResource) (*ap
 	}
 
 	// Store proxyResource
-	query := pr.Dbmap.Model(&ProxyResource{ID: proxyResource.ID}).Updates(proxyResourceDB)
+	query := whereUpdateAt(pr.Dbmap.Model(&ProxyResource{ID: proxyResource.ID}), lastUpdateAt).Updates(proxyResourceDB)
 
 	// Error Handling
 	if err := query.Error; err != nil {
@@ -125,13 +125,16 @@ func (pr PostgresRepo) UpdateProxyResource(proxyResource api.ProxyResource) (*ap
 			Message: err.Error(),
 		}
 	}
+	if err := checkUpdateAt(query, lastUpdateAt, "Proxy resource", proxyResource.ID); err != nil {
+		return nil, err
+	}
 
 	return &proxyResource, nil
 }
 
-func (pr PostgresRepo) RemoveProxyResource(id string) error {
+func (pr PostgresRepo) RemoveProxyResource(id string, lastUpdateAt time.Time) error {
 	// Remove proxy resource
-	query := pr.Dbmap.Where("id like ?", id).Delete(&ProxyResource{})
+	query := whereUpdateAt(pr.Dbmap.Where("id like ?", id), lastUpdateAt).Delete(&ProxyResource{})
 
 	// Error handling
 	if err := query.Error; err != nil {
@@ -140,6 +143,9 @@ func (pr PostgresRepo) RemoveProxyResource(id string) error {
 			Message: err.Error(),
 		}
 	}
+	if err := checkUpdateAt(query, lastUpdateAt, "Proxy resource", id); err != nil {
+		return err
+	}
 
 	return nil
 }
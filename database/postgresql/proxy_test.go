@@ -1,6 +1,7 @@
 package postgresql
 
 import (
+	"fmt"
 	"testing"
 
 	"time"
@@ -399,6 +400,7 @@ func TestPostgresRepo_UpdateProxyResource(t *testing.T) {
 		previousProxyResources []ProxyResource
 		// Postgres Repo Args
 		proxyResourceToUpdate *api.ProxyResource
+		lastUpdateAt          time.Time
 		// Expected result
 		expectedResponse *api.ProxyResource
 		expectedError    *database.Error
@@ -453,6 +455,45 @@ func TestPostgresRepo_UpdateProxyResource(t *testing.T) {
 				UpdateAt: now,
 			},
 		},
+		"ErrorCaseProxyResourceChanged": {
+			previousProxyResources: []ProxyResource{
+				{
+					ID:           "ID",
+					Name:         "name",
+					Path:         "/path/",
+					Org:          "org",
+					Host:         "http://host.com",
+					PathResource: "/path",
+					Method:       "GET",
+					UrnResource:  "urn2",
+					Action:       "example:get",
+					Urn:          "urn",
+					CreateAt:     now.UnixNano(),
+					UpdateAt:     now.UnixNano(),
+				},
+			},
+			proxyResourceToUpdate: &api.ProxyResource{
+				ID:   "ID",
+				Name: "newName",
+				Path: "/newPath/",
+				Org:  "org",
+				Resource: api.ResourceEntity{
+					Host:   "http://newhost.com",
+					Path:   "/newPath",
+					Method: "POST",
+					Urn:    "newurn",
+					Action: "newexample:get",
+				},
+				Urn:      "urn",
+				CreateAt: now,
+				UpdateAt: now.Add(time.Second),
+			},
+			lastUpdateAt: now.Add(-time.Second),
+			expectedError: &database.Error{
+				Code:    database.PRECONDITION_FAILED,
+				Message: fmt.Sprintf("Proxy resource with id ID has changed since %v", now.Add(-time.Second)),
+			},
+		},
 	}
 
 	for n, test := range testcases {
@@ -467,7 +508,7 @@ func TestPostgresRepo_UpdateProxyResource(t *testing.T) {
 		}
 
 		// Call to repository to update proxy resource
-		updateProxyResource, err := repoDB.UpdateProxyResource(*test.proxyResourceToUpdate)
+		updateProxyResource, err := repoDB.UpdateProxyResource(*test.proxyResourceToUpdate, test.lastUpdateAt)
 		if test.expectedError != nil {
 			dbError, _ := err.(*database.Error)
 			assert.Equal(t, test.expectedError, dbError, "Error in test case %v", n)
@@ -538,7 +579,7 @@ func TestPostgresRepo_RemoveProxyResource(t *testing.T) {
 		}
 
 		// Call to repository to remove proxy resource
-		err := repoDB.RemoveProxyResource(test.proxyResourceToDelete)
+		err := repoDB.RemoveProxyResource(test.proxyResourceToDelete, time.Time{})
 		assert.Nil(t, err, "Error in test case %v", n)
 
 		// Check database
@@ -0,0 +1,91 @@
+package postgresql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/database"
+	"github.com/jinzhu/gorm"
+)
+
+// TRANSACTION REPOSITORY IMPLEMENTATION
+
+func (pr PostgresRepo) RunInTransaction(fn func(repos api.Repositories) error) error {
+	transaction := pr.Dbmap.Begin()
+	if err := transaction.Error; err != nil {
+		return &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	txRepo := PostgresRepo{
+		Dbmap:         transaction,
+		inTransaction: true,
+	}
+	err := fn(api.Repositories{
+		UserRepo:     txRepo,
+		GroupRepo:    txRepo,
+		PolicyRepo:   txRepo,
+		ProxyRepo:    txRepo,
+		AuthOidcRepo: txRepo,
+		AuditRepo:    txRepo,
+	})
+	if err != nil {
+		transaction.Rollback()
+		return err
+	}
+
+	if err := transaction.Commit().Error; err != nil {
+		return &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+	return nil
+}
+
+// PRIVATE HELPER METHODS
+
+// Start a transaction, or join the one this repo is bound to
+func (pr PostgresRepo) begin() *gorm.DB {
+	if pr.inTransaction {
+		return pr.Dbmap
+	}
+	return pr.Dbmap.Begin()
+}
+
+// Commit the transaction unless it's owned by RunInTransaction
+func (pr PostgresRepo) commit(transaction *gorm.DB) {
+	if !pr.inTransaction {
+		transaction.Commit()
+	}
+}
+
+// Rollback the transaction unless it's owned by RunInTransaction, which rolls it back when error is returned
+func (pr PostgresRepo) rollback(transaction *gorm.DB) {
+	if !pr.inTransaction {
+		transaction.Rollback()
+	}
+}
+
+// Restrict query to the row that still has the update date lastUpdateAt, so a change is only applied if the resource
+// wasn't changed since it was read. Query isn't restricted if lastUpdateAt is zero
+func whereUpdateAt(query *gorm.DB, lastUpdateAt time.Time) *gorm.DB {
+	if lastUpdateAt.IsZero() {
+		return query
+	}
+	return query.Where("update_at = ?", lastUpdateAt.UTC().UnixNano())
+}
+
+// Return an error if a change restricted by whereUpdateAt didn't affect any row
+func checkUpdateAt(query *gorm.DB, lastUpdateAt time.Time, resource string, id string) error {
+	if lastUpdateAt.IsZero() || query.RowsAffected > 0 {
+		return nil
+	}
+	return &database.Error{
+		Code:    database.PRECONDITION_FAILED,
+		Message: fmt.Sprintf("%v with id %v has changed since %v", resource, id, lastUpdateAt.UTC()),
+	}
+}
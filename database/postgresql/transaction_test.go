@@ -0,0 +1,79 @@
+package postgresql
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresRepo_RunInTransaction(t *testing.T) {
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// Users to add in transaction
+		users []api.User
+		// Error returned by transaction function
+		fnErr error
+		// Expected result
+		expectedUsers int
+		expectedError error
+	}{
+		"OkCase": {
+			users: []api.User{
+				{
+					ID:         "UserID1",
+					ExternalID: "ExternalID1",
+					Path:       "Path",
+					CreateAt:   now,
+					UpdateAt:   now,
+					Urn:        "urn1",
+				},
+				{
+					ID:         "UserID2",
+					ExternalID: "ExternalID2",
+					Path:       "Path",
+					CreateAt:   now,
+					UpdateAt:   now,
+					Urn:        "urn2",
+				},
+			},
+			expectedUsers: 2,
+		},
+		"ErrorCaseRollback": {
+			users: []api.User{
+				{
+					ID:         "UserID1",
+					ExternalID: "ExternalID1",
+					Path:       "Path",
+					CreateAt:   now,
+					UpdateAt:   now,
+					Urn:        "urn1",
+				},
+			},
+			fnErr:         errors.New("Error"),
+			expectedUsers: 0,
+			expectedError: errors.New("Error"),
+		},
+	}
+
+	for n, test := range testcases {
+		// Clean user database
+		cleanUserTable(t, n)
+
+		// Call to repository to add users in a transaction
+		err := repoDB.RunInTransaction(func(repos api.Repositories) error {
+			for _, user := range test.users {
+				_, err := repos.UserRepo.AddUser(user)
+				assert.Nil(t, err, "Error in test case %v", n)
+			}
+			return test.fnErr
+		})
+		assert.Equal(t, test.expectedError, err, "Error in test case %v", n)
+
+		// Check database
+		usersNumber := getUsersCountFiltered(t, n, "", "", "", 0, 0, "", "")
+		assert.Equal(t, test.expectedUsers, usersNumber, "Error in test case %v", n)
+	}
+}
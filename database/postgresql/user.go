@@ -113,7 +113,7 @@ func (pr PostgresRepo) GetUsersFiltered(filter *api.Filter) ([]api.User, int, er
 	return apiusers, total, nil
 }
 
-func (pr PostgresRepo) UpdateUser(user api.User) (*api.User, error) {
+func (pr PostgresRepo) UpdateUser(user api.User, lastUpdateAt time.Time) (*api.User, error) {
 	userDB := User{
 		ID:         user.ID,
 		ExternalID: user.ExternalID,
@@ -124,7 +124,7 @@ func (pr PostgresRepo) UpdateUser(user api.User) (*api.User, error) {
 	}
 
 	// Update user
-	query := pr.Dbmap.Model(&User{ID: user.ID}).Updates(userDB)
+	query := whereUpdateAt(pr.Dbmap.Model(&User{ID: user.ID}), lastUpdateAt).Updates(userDB)
 
 	// Error Handling
 	if err := query.Error; err != nil {
@@ -133,37 +133,56 @@ func (pr PostgresRepo) UpdateUser(user api.User) (*api.User, error) {
 			Message: err.Error(),
 		}
 	}
+	if err := checkUpdateAt(query, lastUpdateAt, "User", user.ID); err != nil {
+		return nil, err
+	}
 
 	return &user, nil
 }
 
-func (pr PostgresRepo) RemoveUser(id string) error {
-	transaction := pr.Dbmap.Begin()
+func (pr PostgresRepo) RemoveUser(id string, lastUpdateAt time.Time) error {
+	transaction := pr.begin()
 	// Delete user
-	transaction.Where("id like ?", id).Delete(&User{})
+	query := whereUpdateAt(transaction.Where("id like ?", id), lastUpdateAt).Delete(&User{})
 
 	// Error handling
-	if err := transaction.Error; err != nil {
-		transaction.Rollback()
+	if err := query.Error; err != nil {
+		pr.rollback(transaction)
 		return &database.Error{
 			Code:    database.INTERNAL_ERROR,
 			Message: err.Error(),
 		}
 	}
+	if err := checkUpdateAt(query, lastUpdateAt, "User", id); err != nil {
+		pr.rollback(transaction)
+		return err
+	}
 
 	//  delete all user relations
 	transaction.Where("user_id like ?", id).Delete(&GroupUserRelation{})
 
 	// Error handling
 	if err := transaction.Error; err != nil {
-		transaction.Rollback()
+		pr.rollback(transaction)
 		return &database.Error{
 			Code:    database.INTERNAL_ERROR,
 			Message: err.Error(),
 		}
 	}
 
-	transaction.Commit()
+	// Delete user policy relations
+	transaction.Where("user_id like ?", id).Delete(&UserPolicyRelation{})
+
+	// Error handling
+	if err := transaction.Error; err != nil {
+		pr.rollback(transaction)
+		return &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	pr.commit(transaction)
 	return nil
 }
 
@@ -209,6 +228,104 @@ func (pr PostgresRepo) GetGroupsByUserID(id string, filter *api.Filter) ([]api.U
 	return groups, total, nil
 }
 
+func (pr PostgresRepo) AttachPolicyToUser(userID string, policyID string) error {
+	// Create relation
+	relation := &UserPolicyRelation{
+		UserID:   userID,
+		PolicyID: policyID,
+		CreateAt: time.Now().UTC().UnixNano(),
+	}
+
+	// Store relation
+	err := pr.Dbmap.Create(relation).Error
+
+	// Error handling
+	if err != nil {
+		return &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) DetachPolicyFromUser(userID string, policyID string) error {
+	// Remove relation
+	err := pr.Dbmap.Where("user_id like ? AND policy_id like ?", userID, policyID).Delete(&UserPolicyRelation{}).Error
+
+	// Error handling
+	if err != nil {
+		return &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) IsAttachedToUser(userID string, policyID string) (bool, error) {
+	relation := UserPolicyRelation{}
+	query := pr.Dbmap.Where("user_id like ? AND policy_id like ?", userID, policyID).First(&relation)
+
+	// Check if relation exists
+	if query.RecordNotFound() {
+		return false, nil
+	}
+
+	// Error Handling
+	if err := query.Error; err != nil {
+		return false, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	return true, nil
+}
+
+func (pr PostgresRepo) GetAttachedPoliciesByUserID(userID string, filter *api.Filter) ([]api.PolicyUserRelation, int, error) {
+	var total int
+	relations := []UserPolicyRelation{}
+	query := pr.Dbmap.Where("user_id like ?", userID)
+
+	if len(filter.OrderBy) > 0 {
+		query = query.Order(filter.OrderBy)
+	}
+
+	// Error Handling
+	if err := query.Find(&relations).Count(&total).Offset(filter.Offset).Limit(filter.Limit).Find(&relations).Error; err != nil {
+		return nil, total, &database.Error{
+			Code:    database.INTERNAL_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	var policies []api.PolicyUserRelation
+	// Transform relations to API domain
+	if relations != nil {
+		policies = make([]api.PolicyUserRelation, len(relations), cap(relations))
+		for i, r := range relations {
+			policy, err := pr.GetPolicyById(r.PolicyID)
+			// Error handling
+			if err != nil {
+				return nil, total, &database.Error{
+					Code:    database.INTERNAL_ERROR,
+					Message: err.Error(),
+				}
+			}
+
+			policies[i] = &PolicyUser{
+				Policy:   policy,
+				CreateAt: time.Unix(0, r.CreateAt).UTC(),
+			}
+		}
+	}
+
+	return policies, total, nil
+}
+
 // PRIVATE HELPER METHODS
 
 // Transform a user retrieved from db into a user for API
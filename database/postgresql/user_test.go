@@ -1,6 +1,7 @@
 package postgresql
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -373,8 +374,10 @@ func TestPostgresRepo_UpdateUser(t *testing.T) {
 		previousUser *User
 		// Postgres Repo Args
 		userToUpdate *api.User
+		lastUpdateAt time.Time
 		// Expected result
 		expectedResponse *api.User
+		expectedError    error
 	}{
 		"OkCase": {
 			previousUser: &User{
@@ -402,6 +405,56 @@ func TestPostgresRepo_UpdateUser(t *testing.T) {
 				UpdateAt:   now,
 			},
 		},
+		"OkCaseLastUpdateAt": {
+			previousUser: &User{
+				ID:         "UserID",
+				ExternalID: "ExternalID",
+				Path:       "OldPath",
+				Urn:        "Oldurn",
+				CreateAt:   now.UnixNano(),
+				UpdateAt:   now.UnixNano(),
+			},
+			userToUpdate: &api.User{
+				ID:         "UserID",
+				ExternalID: "ExternalID",
+				Path:       "NewPath",
+				Urn:        "NewUrn",
+				CreateAt:   now,
+				UpdateAt:   now.Add(time.Second),
+			},
+			lastUpdateAt: now,
+			expectedResponse: &api.User{
+				ID:         "UserID",
+				ExternalID: "ExternalID",
+				Path:       "NewPath",
+				Urn:        "NewUrn",
+				CreateAt:   now,
+				UpdateAt:   now.Add(time.Second),
+			},
+		},
+		"ErrorCaseUserChanged": {
+			previousUser: &User{
+				ID:         "UserID",
+				ExternalID: "ExternalID",
+				Path:       "OldPath",
+				Urn:        "Oldurn",
+				CreateAt:   now.UnixNano(),
+				UpdateAt:   now.UnixNano(),
+			},
+			userToUpdate: &api.User{
+				ID:         "UserID",
+				ExternalID: "ExternalID",
+				Path:       "NewPath",
+				Urn:        "NewUrn",
+				CreateAt:   now,
+				UpdateAt:   now.Add(time.Second),
+			},
+			lastUpdateAt: now.Add(-time.Second),
+			expectedError: &database.Error{
+				Code:    database.PRECONDITION_FAILED,
+				Message: fmt.Sprintf("User with id UserID has changed since %v", now.Add(-time.Second)),
+			},
+		},
 	}
 
 	for n, test := range testcases {
@@ -413,7 +466,15 @@ func TestPostgresRepo_UpdateUser(t *testing.T) {
 			insertUser(t, n, *test.previousUser)
 		}
 		// Call to repository to update an user
-		updatedUser, err := repoDB.UpdateUser(*test.userToUpdate)
+		updatedUser, err := repoDB.UpdateUser(*test.userToUpdate, test.lastUpdateAt)
+		if test.expectedError != nil {
+			assert.Equal(t, test.expectedError, err, "Error in test case %v", n)
+			// Check user wasn't updated
+			userNumber := getUsersCountFiltered(t, n, test.previousUser.ID, test.previousUser.ExternalID, test.previousUser.Path,
+				test.previousUser.CreateAt, test.previousUser.UpdateAt, test.previousUser.Urn, "")
+			assert.Equal(t, 1, userNumber, "Error in test case %v", n)
+			continue
+		}
 		assert.Nil(t, err, "Error in test case %v", n)
 
 		// Check response
@@ -439,6 +500,9 @@ func TestPostgresRepo_RemoveUser(t *testing.T) {
 		relations     []relation
 		// Postgres Repo Args
 		userToDelete string
+		lastUpdateAt time.Time
+		// Expected result
+		expectedError error
 	}{
 		"OkCase": {
 			previousUsers: []User{
@@ -473,12 +537,31 @@ func TestPostgresRepo_RemoveUser(t *testing.T) {
 			},
 			userToDelete: "UserID",
 		},
+		"ErrorCaseUserChanged": {
+			previousUsers: []User{
+				{
+					ID:         "UserID",
+					ExternalID: "ExternalID",
+					Path:       "OldPath",
+					Urn:        "Oldurn",
+					CreateAt:   now.UnixNano(),
+					UpdateAt:   now.UnixNano(),
+				},
+			},
+			userToDelete: "UserID",
+			lastUpdateAt: now.Add(-time.Second),
+			expectedError: &database.Error{
+				Code:    database.PRECONDITION_FAILED,
+				Message: fmt.Sprintf("User with id UserID has changed since %v", now.Add(-time.Second)),
+			},
+		},
 	}
 
 	for n, test := range testcases {
 		// Clean user database
 		cleanUserTable(t, n)
 		cleanGroupUserRelationTable(t, n)
+		cleanUserPolicyRelationTable(t, n)
 
 		// Insert previous data
 		if test.previousUsers != nil {
@@ -489,10 +572,18 @@ func TestPostgresRepo_RemoveUser(t *testing.T) {
 		if test.relations != nil {
 			for _, rel := range test.relations {
 				insertGroupUserRelation(t, n, rel.userID, rel.groupID, rel.createAt)
+				insertUserPolicyRelation(t, n, rel.userID, "PolicyID", rel.createAt)
 			}
 		}
 		// Call to repository to remove user
-		err := repoDB.RemoveUser(test.userToDelete)
+		err := repoDB.RemoveUser(test.userToDelete, test.lastUpdateAt)
+		if test.expectedError != nil {
+			assert.Equal(t, test.expectedError, err, "Error in test case %v", n)
+			// Check user wasn't removed
+			userNumber := getUsersCountFiltered(t, n, test.userToDelete, "", "", 0, 0, "", "")
+			assert.Equal(t, 1, userNumber, "Error in test case %v", n)
+			continue
+		}
 		assert.Nil(t, err, "Error in test case %v", n)
 
 		// Check database
@@ -510,6 +601,14 @@ func TestPostgresRepo_RemoveUser(t *testing.T) {
 		// Check total user relations
 		totalRelations := getGroupUserRelations(t, n, "", "")
 		assert.Equal(t, 1, totalRelations, "Error in test case %v", n)
+
+		// Check user deleted policy relations
+		policyRelations := getUserPolicyRelationCount(t, n, "", test.userToDelete)
+		assert.Equal(t, 0, policyRelations, "Error in test case %v", n)
+
+		// Check total user policy relations
+		totalPolicyRelations := getUserPolicyRelationCount(t, n, "", "")
+		assert.Equal(t, 1, totalPolicyRelations, "Error in test case %v", n)
 	}
 }
 
@@ -699,3 +798,277 @@ func TestPostgresRepo_GetGroupsByUserID(t *testing.T) {
 		}
 	}
 }
+
+func TestPostgresRepo_AttachPolicyToUser(t *testing.T) {
+	testcases := map[string]struct {
+		// Postgres Repo Args
+		policyID string
+		userID   string
+		// Expected result
+		expectedError *database.Error
+	}{
+		"OkCase": {
+			policyID: "PolicyID",
+			userID:   "UserID",
+		},
+		"ErrorCaseInternalError": {
+			expectedError: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "pq: null value in column \"user_id\" violates not-null constraint",
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		// Clean UserPolicyRelation database
+		cleanUserPolicyRelationTable(t, n)
+
+		// Call to repository to attach policy
+		err := repoDB.AttachPolicyToUser(test.userID, test.policyID)
+		if test.expectedError != nil {
+			dbError, _ := err.(*database.Error)
+			assert.Equal(t, test.expectedError, dbError, "Error in test case %v", n)
+		} else {
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check database
+			relations := getUserPolicyRelationCount(t, n, test.policyID, test.userID)
+			assert.Equal(t, 1, relations, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestPostgresRepo_DetachPolicyFromUser(t *testing.T) {
+	type relation struct {
+		policyID string
+		userID   string
+		createAt int64
+	}
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// Previous data
+		relation *relation
+		// Postgres Repo Args
+		policyID string
+		userID   string
+	}{
+		"OkCase": {
+			relation: &relation{
+				policyID: "PolicyID",
+				userID:   "UserID",
+				createAt: now.UnixNano(),
+			},
+			policyID: "PolicyID",
+			userID:   "UserID",
+		},
+	}
+
+	for n, test := range testcases {
+		// Clean UserPolicyRelation database
+		cleanUserPolicyRelationTable(t, n)
+
+		// Insert previous data
+		if test.relation != nil {
+			insertUserPolicyRelation(t, n, test.relation.userID, test.relation.policyID, test.relation.createAt)
+		}
+
+		// Call to repository to detach policy
+		err := repoDB.DetachPolicyFromUser(test.userID, test.policyID)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		// Check database
+		relations := getUserPolicyRelationCount(t, n, test.policyID, test.userID)
+		assert.Equal(t, 0, relations, "Error in test case %v", n)
+	}
+}
+
+func TestPostgresRepo_IsAttachedToUser(t *testing.T) {
+	type relation struct {
+		userID   string
+		policyID string
+		createAt int64
+	}
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// Previous data
+		relation *relation
+		// Postgres Repo Args
+		userID   string
+		policyID string
+		// Expected result
+		expectedResult bool
+	}{
+		"OkCase": {
+			relation: &relation{
+				userID:   "UserID",
+				policyID: "PolicyID",
+				createAt: now.UnixNano(),
+			},
+			userID:         "UserID",
+			policyID:       "PolicyID",
+			expectedResult: true,
+		},
+		"OkCaseNotFound": {
+			relation: &relation{
+				userID:   "UserID",
+				policyID: "PolicyID",
+				createAt: now.UnixNano(),
+			},
+			userID:         "UserID",
+			policyID:       "PolicyIDXXXXXXX",
+			expectedResult: false,
+		},
+	}
+
+	for n, test := range testcases {
+		// Clean UserPolicyRelation database
+		cleanUserPolicyRelationTable(t, n)
+
+		// Insert previous data
+		if test.relation != nil {
+			insertUserPolicyRelation(t, n, test.relation.userID, test.relation.policyID, test.relation.createAt)
+		}
+
+		// Call repository to check if policy is attached to user
+		result, err := repoDB.IsAttachedToUser(test.userID, test.policyID)
+		assert.Nil(t, err, "Error in test case %v", n)
+		assert.Equal(t, test.expectedResult, result, "Error in test case %v", n)
+	}
+}
+
+func TestPostgresRepo_GetAttachedPoliciesByUserID(t *testing.T) {
+	type relations struct {
+		policies       []Policy
+		userID         string
+		createAt       []int64
+		policyNotFound bool
+	}
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// Previous data
+		relations  *relations
+		statements []Statement
+		// Postgres Repo Args
+		userID string
+		filter *api.Filter
+		// Expected result
+		expectedResponse []*PolicyUser
+		expectedError    *database.Error
+	}{
+		"OkCase": {
+			relations: &relations{
+				policies: []Policy{
+					{
+						ID:       "PolicyID1",
+						Name:     "Name1",
+						Org:      "org1",
+						Path:     "/path/",
+						CreateAt: now.UnixNano(),
+						UpdateAt: now.UnixNano(),
+						Urn:      "Urn1",
+					},
+					{
+						ID:       "PolicyID2",
+						Name:     "Name2",
+						Org:      "org1",
+						Path:     "/path/",
+						CreateAt: now.UnixNano(),
+						UpdateAt: now.UnixNano(),
+						Urn:      "Urn2",
+					},
+				},
+				userID:   "UserID",
+				createAt: []int64{now.UnixNano() - 1, now.UnixNano()},
+			},
+			statements: []Statement{},
+			userID:     "UserID",
+			filter: &api.Filter{
+				OrderBy: "create_at desc",
+			},
+			expectedResponse: []*PolicyUser{
+				{
+					Policy: &api.Policy{
+						ID:         "PolicyID2",
+						Name:       "Name2",
+						Org:        "org1",
+						Path:       "/path/",
+						CreateAt:   now,
+						UpdateAt:   now,
+						Urn:        "Urn2",
+						Statements: &[]api.Statement{},
+					},
+					CreateAt: now,
+				},
+				{
+					Policy: &api.Policy{
+						ID:         "PolicyID1",
+						Name:       "Name1",
+						Org:        "org1",
+						Path:       "/path/",
+						CreateAt:   now,
+						UpdateAt:   now,
+						Urn:        "Urn1",
+						Statements: &[]api.Statement{},
+					},
+					CreateAt: now.Add(-1),
+				},
+			},
+		},
+		"ErrorCase": {
+			relations: &relations{
+				policies: []Policy{
+					{
+						ID:       "PolicyID1",
+						Name:     "Name1",
+						Org:      "org1",
+						Path:     "/path/",
+						CreateAt: now.UnixNano(),
+						UpdateAt: now.UnixNano(),
+						Urn:      "Urn1",
+					},
+				},
+				userID:         "UserID",
+				createAt:       []int64{now.UnixNano()},
+				policyNotFound: true,
+			},
+			statements: []Statement{},
+			userID:     "UserID",
+			filter:     testFilter,
+			expectedError: &database.Error{
+				Code:    database.INTERNAL_ERROR,
+				Message: "Code: PolicyNotFound, Message: Policy with id PolicyID1 not found",
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		cleanPolicyTable(t, n)
+		cleanUserPolicyRelationTable(t, n)
+
+		// Insert previous data
+		if test.relations != nil {
+			for i, policy := range test.relations.policies {
+				insertUserPolicyRelation(t, n, test.relations.userID, policy.ID, test.relations.createAt[i])
+				if !test.relations.policyNotFound {
+					insertPolicy(t, n, policy, test.statements)
+				}
+			}
+		}
+
+		receivedPolicies, total, err := repoDB.GetAttachedPoliciesByUserID(test.userID, test.filter)
+		if test.expectedError != nil {
+			dbError, _ := err.(*database.Error)
+			assert.Equal(t, test.expectedError, dbError, "Error in test case %v", n)
+		} else {
+			assert.Nil(t, err, "Error in test case %v", n)
+
+			// Check total
+			assert.Equal(t, len(test.expectedResponse), total, "Error in test case %v", n)
+
+			// Check response
+			for i, r := range receivedPolicies {
+				assert.Equal(t, test.expectedResponse[i].GetPolicy(), r.GetPolicy(), "Error in test case %v", n)
+				assert.Equal(t, test.expectedResponse[i].GetDate(), r.GetDate(), "Error in test case %v", n)
+			}
+		}
+	}
+}
@@ -3,6 +3,8 @@ package foulkon
 import (
 	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	"errors"
 
@@ -11,6 +13,8 @@ import (
 	"time"
 
 	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/middleware"
+	"github.com/Tecsisa/foulkon/middleware/auth"
 	"github.com/pelletier/go-toml"
 	"github.com/sirupsen/logrus"
 
@@ -27,6 +31,8 @@ type Proxy struct {
 
 	// Worker location
 	WorkerHost string
+	// Secret sent to worker to forward the source IP of clients, sent only if not empty
+	ProxySecret string
 
 	// Proxy Flush Interval Duration for flushing data in reverse proxy calls
 	ProxyFlushInterval time.Duration
@@ -38,8 +44,29 @@ type Proxy struct {
 	// API
 	ProxyApi api.InternalProxyAPI
 
+	// Logger of authorization decisions, nil if it is disabled
+	AuthzLogger *api.AuthzDecisionLogger
+
 	// Refresh time
 	RefreshTime time.Duration
+
+	// Authorizer that evaluates requests in proxy, nil if they are authorized by worker
+	LocalAuthorizer *api.LocalAuthorizer
+
+	// Repository to load authorization snapshots from database, nil if they are loaded from worker
+	AuthzSnapshotRepo api.AuthzSnapshotRepo
+
+	// Admin user, used to load authorization snapshots from worker
+	AdminUser     string
+	AdminPassword string
+
+	// Middleware handler that authenticates requests authorized in proxy
+	MiddlewareHandler *middleware.MiddlewareHandler
+
+	// Cache of authorization results, nil if it is disabled
+	AuthzResultCache *api.AuthzResultCache
+	// Request headers with the credentials that identify the user of cached results
+	AuthzCacheHeaders []string
 }
 
 func NewProxy(config *toml.Tree) (*Proxy, error) {
@@ -72,6 +99,9 @@ func NewProxy(config *toml.Tree) (*Proxy, error) {
 
 	// Start DB with API
 	var prApi api.ProxyAPI
+	var dbSink api.AuthzDecisionSink
+	var authOidcRepo api.AuthOidcRepo
+	var authzSnapshotRepo api.AuthzSnapshotRepo
 
 	dbType, err := getMandatoryValue(config, "database.type")
 	if err != nil {
@@ -105,6 +135,9 @@ func NewProxy(config *toml.Tree) (*Proxy, error) {
 		prApi = api.ProxyAPI{
 			ProxyRepo: repoDB,
 		}
+		dbSink = repoDB
+		authOidcRepo = repoDB
+		authzSnapshotRepo = repoDB
 
 	default:
 		err := errors.New("Unexpected db_type value in configuration file (Maybe it is empty)")
@@ -140,20 +173,56 @@ func NewProxy(config *toml.Tree) (*Proxy, error) {
 		return nil, err
 	}
 
-	return &Proxy{
+	// Authorization decision log. Disabled by default
+	authzDecisionLogger, _, err = newAuthzDecisionLogger(config, dbSink)
+	if err != nil {
+		api.Log.Error(err)
+		return nil, err
+	}
+
+	proxy := &Proxy{
 		Host:               host,
 		Port:               port,
 		WorkerHost:         workerHost,
+		ProxySecret:        getDefaultValue(config, "server.proxysecret", ""),
 		CertFile:           getDefaultValue(config, "server.certfile", ""),
 		KeyFile:            getDefaultValue(config, "server.keyfile", ""),
 		ProxyApi:           prApi,
+		AuthzLogger:        authzDecisionLogger,
 		ProxyFlushInterval: proxyFlushInterval,
 		RefreshTime:        refresh,
-	}, nil
+	}
+
+	// Authorization mode. Requests are authorized by worker by default
+	authzMode := getDefaultValue(config, "authz.mode", "worker")
+	switch authzMode {
+	case "worker":
+	case "local":
+		if err := configureLocalAuthorization(config, proxy, authOidcRepo, authzSnapshotRepo); err != nil {
+			api.Log.Error(err)
+			return nil, err
+		}
+	default:
+		err := fmt.Errorf("Unexpected authz.mode value in configuration file: '%s'", authzMode)
+		api.Log.Error(err)
+		return nil, err
+	}
+
+	// Authorization result cache. Disabled by default
+	if err := configureAuthzResultCache(config, proxy); err != nil {
+		api.Log.Error(err)
+		return nil, err
+	}
+
+	return proxy, nil
 }
 
 func CloseProxy() int {
 	status := 0
+	if err := authzDecisionLogger.Close(); err != nil {
+		api.Log.Errorf("Couldn't close authorization decision log: %v", err)
+		status = 1
+	}
 	if err := db.Close(); err != nil {
 		api.Log.Errorf("Couldn't close DB connection: %v", err)
 		status = 1
@@ -166,3 +235,78 @@ func CloseProxy() int {
 	}
 	return status
 }
+
+// This aux method configures the proxy to authorize requests with a local authorizer, authenticating them like
+// worker does. Authorization snapshots are loaded from database or from worker
+func configureLocalAuthorization(config *toml.Tree, proxy *Proxy, authOidcRepo api.AuthOidcRepo,
+	authzSnapshotRepo api.AuthzSnapshotRepo) error {
+	authConnector, _, _, err := newAuthConnector(config, authOidcRepo)
+	if err != nil {
+		return err
+	}
+	adminUser, adminPassword, err := getAdminCredentials(config)
+	if err != nil {
+		return err
+	}
+
+	source := getDefaultValue(config, "authz.local.source", "database")
+	switch source {
+	case "database":
+		proxy.AuthzSnapshotRepo = authzSnapshotRepo
+	case "worker":
+	default:
+		return fmt.Errorf("Unexpected authz.local.source value in configuration file: '%s'", source)
+	}
+
+	proxy.LocalAuthorizer = &api.LocalAuthorizer{}
+	proxy.AdminUser = adminUser
+	proxy.AdminPassword = adminPassword
+	proxy.MiddlewareHandler = &middleware.MiddlewareHandler{
+		Middlewares: map[string]middleware.Middleware{
+			middleware.AUTHENTICATOR_MIDDLEWARE: auth.NewAuthenticatorMiddleware(authConnector, adminUser, adminPassword),
+		},
+	}
+	api.Log.Infof("Local authorization enabled with snapshots from %v", source)
+	return nil
+}
+
+// This aux method configures the cache of authorization results. Admin user is needed to manage the cache, so it's
+// mandatory if the cache is enabled
+func configureAuthzResultCache(config *toml.Tree, proxy *Proxy) error {
+	ttl, err := time.ParseDuration(getDefaultValue(config, "authz.cache.ttl", "0s"))
+	if err != nil {
+		return err
+	}
+	negativeTTL, err := time.ParseDuration(getDefaultValue(config, "authz.cache.negativettl", "0s"))
+	if err != nil {
+		return err
+	}
+	size, err := strconv.Atoi(getDefaultValue(config, "authz.cache.size", "10000"))
+	if err != nil || size < 1 {
+		return fmt.Errorf("Invalid authz.cache.size value in configuration file, it must be a positive number")
+	}
+	proxy.AuthzResultCache = api.NewAuthzResultCache(size, ttl, negativeTTL)
+	if proxy.AuthzResultCache == nil {
+		return nil
+	}
+
+	headers := []string{}
+	for _, header := range strings.Split(getDefaultValue(config, "authz.cache.headers", "Authorization"), ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, header)
+		}
+	}
+	if len(headers) < 1 {
+		return fmt.Errorf("Invalid authz.cache.headers value in configuration file, it can't be empty")
+	}
+	proxy.AuthzCacheHeaders = headers
+
+	if proxy.AdminUser == "" {
+		proxy.AdminUser, proxy.AdminPassword, err = getAdminCredentials(config)
+		if err != nil {
+			return err
+		}
+	}
+	api.Log.Infof("Authorization result cache enabled with size %v, TTL %v and negative TTL %v", size, ttl, negativeTTL)
+	return nil
+}
@@ -14,6 +14,8 @@ import (
 
 	"strconv"
 
+	"time"
+
 	"github.com/Tecsisa/foulkon/api"
 	"github.com/Tecsisa/foulkon/database/postgresql"
 	"github.com/Tecsisa/foulkon/middleware"
@@ -34,12 +36,17 @@ const (
 var rEnvVar, _ = regexp.Compile(`^\$\{(\w+)\}$`)
 var db *sql.DB
 var workerLogfile *os.File
+var authzDecisionLogger *api.AuthzDecisionLogger
 
 // Worker is the Authorization server.
 type Worker struct {
 	// Server config
 	Host string
 	Port string
+	// Port of gRPC authorization server, disabled if empty
+	GrpcPort string
+	// Secret sent by proxies to forward the source IP of their clients, disabled if empty
+	ProxySecret string
 
 	// TLS configuration
 	CertFile string
@@ -52,6 +59,11 @@ type Worker struct {
 	AuthzApi    api.AuthzAPI
 	ProxyApi    api.ProxyResourcesAPI
 	AuthOidcAPI api.AuthOidcAPI
+	AuditApi    api.AuditAPI
+	BatchApi    api.BatchAPI
+
+	// Authorization cache, nil if it is disabled
+	AuthzCache *api.AuthzCache
 
 	//  Middleware handler
 	MiddlewareHandler *middleware.MiddlewareHandler
@@ -77,6 +89,12 @@ type WorkerConfig struct {
 	AuthType      string
 	OidcProviders []api.OidcProvider
 
+	// Authorization cache Config
+	AuthzCacheTTL time.Duration
+
+	// Authorization decision log Config
+	AuthzLogSink string
+
 	Version string
 }
 
@@ -117,6 +135,7 @@ func NewWorker(config *toml.Tree) (*Worker, error) {
 
 	// Start DB with API
 	var authApi api.WorkerAPI
+	var dbSink api.AuthzDecisionSink
 
 	dbType, err := getMandatoryValue(config, "database.type")
 	if err != nil {
@@ -154,7 +173,12 @@ func NewWorker(config *toml.Tree) (*Worker, error) {
 			PolicyRepo:   repoDB,
 			ProxyRepo:    repoDB,
 			AuthOidcRepo: repoDB,
+			AuditRepo:    repoDB,
+
+			TransactionRepo:   repoDB,
+			AuthzSnapshotRepo: repoDB,
 		}
+		dbSink = repoDB
 		wc.IdleConns, _ = strconv.Atoi(dbIdleconns)
 		wc.MaxOpenConns, _ = strconv.Atoi(dbMaxopenconns)
 		wc.ConnTtl, _ = strconv.Atoi(dbConttl)
@@ -165,61 +189,36 @@ func NewWorker(config *toml.Tree) (*Worker, error) {
 		return nil, err
 	}
 
-	// Instantiate Auth Connector
-	var authConnector auth.AuthConnector
-	authType, err := getMandatoryValue(config, "authenticator.type")
+	// Authorization cache. Disabled by default
+	authzCacheTTL, err := time.ParseDuration(getDefaultValue(config, "authz.cache.ttl", "0s"))
 	if err != nil {
-		return nil, err
-	}
-	wc.AuthType = authType
-
-	switch authType {
-	case "header":
-		headerName, err := getMandatoryValue(config, "authenticator.header.name")
-		if err != nil {
-			api.Log.Warn("Header authenticator configured, but no header provided - only admin access allowed")
-		} else {
-			authConnector = header.InitHeaderConnector(headerName)
-			api.Log.Infof("Header authenticator configured with header: %v", headerName)
-		}
-	case "oidc":
-		oidcProviders, total, err := authApi.AuthOidcRepo.GetOidcProvidersFiltered(&api.Filter{})
-		if err != nil {
-			return nil, err
-		}
-
-		if total > 0 {
-			wc.OidcProviders = oidcProviders
-			api.Log.Infof("OIDC connectors retrieved %v", total)
-
-			authOidcConnector, err := oidc.InitOIDCConnector(oidcProviders)
-			if err != nil {
-				api.Log.Error(err)
-				return nil, err
-			}
-			authConnector = authOidcConnector
-			api.Log.Infof("OIDC connector configured with %v OIDC Providers: %v", total, oidcProviders)
-		} else {
-			api.Log.Warn("No OIDC connectors retrieved, only admin access allowed")
-		}
-	default:
-		err := fmt.Errorf("Unexpected auth_connector_type value in configuration file: '%s' (maybe it is empty)", authType)
 		api.Log.Error(err)
 		return nil, err
 	}
+	authApi.AuthzCache = api.NewAuthzCache(authzCacheTTL)
+	wc.AuthzCacheTTL = authzCacheTTL
+	if authApi.AuthzCache != nil {
+		api.Log.Infof("Authorization cache enabled with TTL %v", authzCacheTTL)
+	}
 
-	adminUser, err := getMandatoryValue(config, "admin.username")
+	// Authorization decision log. Disabled by default
+	authzDecisionLogger, wc.AuthzLogSink, err = newAuthzDecisionLogger(config, dbSink)
 	if err != nil {
 		api.Log.Error(err)
 		return nil, err
 	}
-	adminPassword, err := getMandatoryValue(config, "admin.password")
+	authApi.AuthzLogger = authzDecisionLogger
+
+	// Instantiate Auth Connector
+	authConnector, authType, oidcProviders, err := newAuthConnector(config, authApi.AuthOidcRepo)
 	if err != nil {
-		api.Log.Error(err)
 		return nil, err
 	}
-	if len(strings.TrimSpace(adminUser)) < 1 || len(strings.TrimSpace(adminPassword)) < 1 {
-		err := fmt.Errorf("Admin user config unexpected adminUser:%v, adminpassword:%v", adminUser, adminPassword)
+	wc.AuthType = authType
+	wc.OidcProviders = oidcProviders
+
+	adminUser, adminPassword, err := getAdminCredentials(config)
+	if err != nil {
 		api.Log.Error(err)
 		return nil, err
 	}
@@ -256,6 +255,8 @@ func NewWorker(config *toml.Tree) (*Worker, error) {
 	return &Worker{
 		Host:              host,
 		Port:              port,
+		GrpcPort:          getDefaultValue(config, "server.grpcport", ""),
+		ProxySecret:       getDefaultValue(config, "server.proxysecret", ""),
 		CertFile:          getDefaultValue(config, "server.certfile", ""),
 		KeyFile:           getDefaultValue(config, "server.keyfile", ""),
 		MiddlewareHandler: &middleware.MiddlewareHandler{Middlewares: middlewares},
@@ -265,12 +266,19 @@ func NewWorker(config *toml.Tree) (*Worker, error) {
 		AuthzApi:          authApi,
 		ProxyApi:          authApi,
 		AuthOidcAPI:       authApi,
+		AuditApi:          authApi,
+		BatchApi:          authApi,
+		AuthzCache:        authApi.AuthzCache,
 		Config:            wc,
 	}, nil
 }
 
 func CloseWorker() int {
 	status := 0
+	if err := authzDecisionLogger.Close(); err != nil {
+		api.Log.Errorf("Couldn't close authorization decision log: %v", err)
+		status = 1
+	}
 	if err := db.Close(); err != nil {
 		api.Log.Errorf("Couldn't close DB connection: %v", err)
 		status = 1
@@ -284,6 +292,105 @@ func CloseWorker() int {
 	return status
 }
 
+// This aux method returns the authorization decision logger configured and its sink type. Logger is nil if it is disabled
+func newAuthzDecisionLogger(config *toml.Tree, dbSink api.AuthzDecisionSink) (*api.AuthzDecisionLogger, string, error) {
+	sinkType := getDefaultValue(config, "authz.log.sink", "none")
+	switch sinkType {
+	case "none":
+		return nil, sinkType, nil
+	case "file", "postgres":
+	default:
+		return nil, "", fmt.Errorf("Unexpected authz.log.sink value in configuration file: '%s'", sinkType)
+	}
+
+	sampleRate, err := strconv.ParseFloat(getDefaultValue(config, "authz.log.samplerate", "1"), 64)
+	if err != nil || sampleRate < 0 || sampleRate > 1 {
+		return nil, "", fmt.Errorf("Invalid authz.log.samplerate value in configuration file, it must be a number between 0 and 1")
+	}
+	bufferSize, err := strconv.Atoi(getDefaultValue(config, "authz.log.buffersize", "1000"))
+	if err != nil || bufferSize < 1 {
+		return nil, "", fmt.Errorf("Invalid authz.log.buffersize value in configuration file, it must be a positive number")
+	}
+	flushInterval, err := time.ParseDuration(getDefaultValue(config, "authz.log.flushinterval", "1s"))
+	if err != nil || flushInterval <= 0 {
+		return nil, "", fmt.Errorf("Invalid authz.log.flushinterval value in configuration file, it must be a positive duration")
+	}
+	if sampleRate == 0 {
+		return nil, sinkType, nil
+	}
+
+	sink := dbSink
+	if sinkType == "file" {
+		fileSink, err := api.NewFileAuthzDecisionSink(getDefaultValue(config, "authz.log.file.dir", "/tmp/foulkon/authz.log"))
+		if err != nil {
+			return nil, "", err
+		}
+		sink = fileSink
+	}
+
+	api.Log.Infof("Authorization decision log enabled with %v sink, sample rate %v", sinkType, sampleRate)
+	return api.NewAuthzDecisionLogger(sink, sampleRate, bufferSize, flushInterval), sinkType, nil
+}
+
+// This aux method returns the authentication connector configured, its type and the OIDC providers it uses.
+// Connector is nil if only admin access is allowed
+func newAuthConnector(config *toml.Tree, authOidcRepo api.AuthOidcRepo) (auth.AuthConnector, string, []api.OidcProvider, error) {
+	authType, err := getMandatoryValue(config, "authenticator.type")
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	switch authType {
+	case "header":
+		headerName, err := getMandatoryValue(config, "authenticator.header.name")
+		if err != nil {
+			api.Log.Warn("Header authenticator configured, but no header provided - only admin access allowed")
+			return nil, authType, nil, nil
+		}
+		api.Log.Infof("Header authenticator configured with header: %v", headerName)
+		return header.InitHeaderConnector(headerName), authType, nil, nil
+	case "oidc":
+		oidcProviders, total, err := authOidcRepo.GetOidcProvidersFiltered(&api.Filter{})
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		if total < 1 {
+			api.Log.Warn("No OIDC connectors retrieved, only admin access allowed")
+			return nil, authType, nil, nil
+		}
+		api.Log.Infof("OIDC connectors retrieved %v", total)
+
+		authOidcConnector, err := oidc.InitOIDCConnector(oidcProviders)
+		if err != nil {
+			api.Log.Error(err)
+			return nil, "", nil, err
+		}
+		api.Log.Infof("OIDC connector configured with %v OIDC Providers: %v", total, oidcProviders)
+		return authOidcConnector, authType, oidcProviders, nil
+	default:
+		err := fmt.Errorf("Unexpected auth_connector_type value in configuration file: '%s' (maybe it is empty)", authType)
+		api.Log.Error(err)
+		return nil, "", nil, err
+	}
+}
+
+// This aux method returns admin user and password, or an error if any of them is missing or empty
+func getAdminCredentials(config *toml.Tree) (string, string, error) {
+	adminUser, err := getMandatoryValue(config, "admin.username")
+	if err != nil {
+		return "", "", err
+	}
+	adminPassword, err := getMandatoryValue(config, "admin.password")
+	if err != nil {
+		return "", "", err
+	}
+	if len(strings.TrimSpace(adminUser)) < 1 || len(strings.TrimSpace(adminPassword)) < 1 {
+		return "", "", fmt.Errorf("Admin user config unexpected adminUser:%v, adminpassword:%v", adminUser, adminPassword)
+	}
+	return adminUser, adminPassword, nil
+}
+
 // This aux method returns mandatory config value or any error occurred
 func getMandatoryValue(config *toml.Tree, key string) (string, error) {
 	if !config.Has(key) {
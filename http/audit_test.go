@@ -0,0 +1,177 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerHandler_HandleListAuditEvents(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	testcases := map[string]struct {
+		// API method args
+		filter       *api.Filter
+		from         string
+		ignoreArgsIn bool
+		// Expected result
+		expectedStatusCode int
+		expectedResponse   ListAuditEventsResponse
+		expectedError      api.Error
+		// Manager Results
+		listAuditEventsResult []api.AuditEvent
+		totalAuditEvents      int
+		// Manager Errors
+		listAuditEventsErr error
+	}{
+		"OkCase": {
+			filter: &api.Filter{
+				Actor:     "admin",
+				UrnPrefix: "urn:iws:iam:org1:policy/",
+				From:      now.Add(-time.Hour),
+				To:        now,
+				Offset:    0,
+				Limit:     0,
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: ListAuditEventsResponse{
+				Events: []api.AuditEvent{
+					{
+						ID:        "EventID",
+						Actor:     "admin",
+						RequestID: "RequestID",
+						Action:    api.POLICY_ACTION_DELETE_POLICY,
+						Urn:       api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy1"),
+						Before:    json.RawMessage(`{"name":"policy1"}`),
+						CreateAt:  now,
+					},
+				},
+				Offset: 0,
+				Limit:  0,
+				Total:  1,
+			},
+			listAuditEventsResult: []api.AuditEvent{
+				{
+					ID:        "EventID",
+					Actor:     "admin",
+					RequestID: "RequestID",
+					Action:    api.POLICY_ACTION_DELETE_POLICY,
+					Urn:       api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy1"),
+					Before:    json.RawMessage(`{"name":"policy1"}`),
+					CreateAt:  now,
+				},
+			},
+			totalAuditEvents: 1,
+		},
+		"ErrorCaseInvalidFilterParams": {
+			filter: &api.Filter{
+				Limit: -1,
+			},
+			ignoreArgsIn:       true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: Limit -1",
+			},
+		},
+		"ErrorCaseInvalidFrom": {
+			filter:             &api.Filter{},
+			from:               "yesterday",
+			ignoreArgsIn:       true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: From yesterday",
+			},
+		},
+		"ErrorCaseInvalidParameterError": {
+			filter: &api.Filter{
+				Actor: "admin",
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Error",
+			},
+			listAuditEventsErr: &api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseUnauthorizedError": {
+			filter: &api.Filter{
+				Actor: "admin",
+			},
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+			listAuditEventsErr: &api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+		},
+		"ErrorCaseUnknownApiError": {
+			filter:             testFilter,
+			expectedStatusCode: http.StatusInternalServerError,
+			listAuditEventsErr: &api.Error{
+				Code:    api.UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[ListAuditEventsMethod][0] = test.listAuditEventsResult
+		testApi.ArgsOut[ListAuditEventsMethod][1] = test.totalAuditEvents
+		testApi.ArgsOut[ListAuditEventsMethod][2] = test.listAuditEventsErr
+
+		req, err := http.NewRequest(http.MethodGet, server.URL+AUDIT_ROOT_URL, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		addQueryParams(test.filter, req)
+		if test.from != "" {
+			q := req.URL.Query()
+			q.Add("From", test.from)
+			req.URL.RawQuery = q.Encode()
+		}
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		if !test.ignoreArgsIn {
+			// Check received parameters
+			filterData, ok := testApi.ArgsIn[ListAuditEventsMethod][1].(*api.Filter)
+			if ok {
+				// Check result
+				assert.Equal(t, test.filter, filterData, "Error in test case %v", n)
+			}
+		}
+
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			listAuditEvents := ListAuditEventsResponse{}
+			err = json.NewDecoder(res.Body).Decode(&listAuditEvents)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedResponse, listAuditEvents, "Error in test case %v", n)
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
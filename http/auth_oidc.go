@@ -3,6 +3,7 @@ package http
 import (
 	"net/http"
 
+	"github.com/Tecsisa/foulkon/api"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -57,6 +58,9 @@ func (wh *WorkerHandler) HandleGetOidcProviderByName(w http.ResponseWriter, r *h
 
 	// Call Auth Provider API to get the provider
 	response, err := wh.worker.AuthOidcAPI.GetOidcProviderByName(requestInfo, filterData.AuthProviderName)
+	if err == nil {
+		w.Header().Set(ETAG_HEADER, api.ETag(response.UpdateAt))
+	}
 	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
 }
 
@@ -92,6 +96,9 @@ func (wh *WorkerHandler) HandleUpdateOidcProvider(w http.ResponseWriter, r *http
 	// Call Auth Provider API to update the OIDC Provider
 	response, err := wh.worker.AuthOidcAPI.UpdateOidcProvider(requestInfo, filterData.AuthProviderName,
 		request.Name, request.Path, request.IssuerURL, request.OidcClients)
+	if err == nil {
+		w.Header().Set(ETAG_HEADER, api.ETag(response.UpdateAt))
+	}
 	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
 }
 
@@ -3,20 +3,53 @@ package http
 import (
 	"net/http"
 
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/middleware"
 	"github.com/julienschmidt/httprouter"
 )
 
 // REQUESTS
 
 type AuthorizeResourcesRequest struct {
-	Action    string   `json:"action,omitempty"`
-	Resources []string `json:"resources,omitempty"`
+	Action    string            `json:"action,omitempty"`
+	Resources []string          `json:"resources,omitempty"`
+	Context   map[string]string `json:"context,omitempty"`
+	Explain   bool              `json:"explain,omitempty"`
+}
+
+type AuthorizeResourcesBatchRequest struct {
+	Items   []api.AuthorizeItem `json:"items,omitempty"`
+	Context map[string]string   `json:"context,omitempty"`
+}
+
+type SimulateAuthorizationRequest struct {
+	ExternalID string            `json:"externalId,omitempty"`
+	Statements []api.Statement   `json:"statements,omitempty"`
+	Replace    bool              `json:"replace,omitempty"`
+	Action     string            `json:"action,omitempty"`
+	Resources  []string          `json:"resources,omitempty"`
+	Context    map[string]string `json:"context,omitempty"`
 }
 
 // RESPONSES
 
 type AuthorizeResourcesResponse struct {
-	ResourcesAllowed []string `json:"resourcesAllowed,omitempty"`
+	ResourcesAllowed []string                  `json:"resourcesAllowed,omitempty"`
+	Explanations     []api.ResourceExplanation `json:"explanations,omitempty"`
+}
+
+type AuthorizeResourcesBatchResponse struct {
+	Items []api.AuthorizeItemResult `json:"items,omitempty"`
+}
+
+type SimulateAuthorizationResponse struct {
+	ResourcesAllowed []string                  `json:"resourcesAllowed,omitempty"`
+	Explanations     []api.ResourceExplanation `json:"explanations,omitempty"`
+}
+
+type GetAuthorizedPrincipalsResponse struct {
+	Users  []api.AuthorizedUser  `json:"users,omitempty"`
+	Groups []api.AuthorizedGroup `json:"groups,omitempty"`
 }
 
 // HANDLERS
@@ -30,6 +63,26 @@ func (wh *WorkerHandler) HandleGetAuthorizedExternalResources(w http.ResponseWri
 		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
 		return
 	}
+	// Return authenticated user, so proxies can log who the decision was made for
+	w.Header().Set(middleware.USER_ID_HEADER, requestInfo.Identifier)
+
+	// Add request attributes to evaluate conditions
+	requestInfo.Context.Attributes = request.Context
+
+	// Explain decision for each resource
+	if request.Explain {
+		explanations, err := wh.worker.AuthzApi.ExplainAuthorizedExternalResources(requestInfo, request.Action, request.Resources)
+		response := AuthorizeResourcesResponse{
+			Explanations: explanations,
+		}
+		for _, explanation := range explanations {
+			if explanation.Decision == api.DECISION_ALLOWED {
+				response.ResourcesAllowed = append(response.ResourcesAllowed, explanation.Urn)
+			}
+		}
+		wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+		return
+	}
 
 	// Retrieve allowed resources
 	result, err := wh.worker.AuthzApi.GetAuthorizedExternalResources(requestInfo, request.Action, request.Resources)
@@ -38,3 +91,123 @@ func (wh *WorkerHandler) HandleGetAuthorizedExternalResources(w http.ResponseWri
 	}
 	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
 }
+
+func (wh *WorkerHandler) HandleGetAuthorizedExternalResourcesBatch(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	// Process request
+	request := &AuthorizeResourcesBatchRequest{}
+	requestInfo, _, apiErr := wh.processHttpRequest(r, w, nil, request)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+
+	// Add request attributes to evaluate conditions
+	requestInfo.Context.Attributes = request.Context
+
+	// Retrieve allowed resources for each item
+	result, err := wh.worker.AuthzApi.GetAuthorizedExternalResourcesBatch(requestInfo, request.Items)
+	response := AuthorizeResourcesBatchResponse{
+		Items: result,
+	}
+	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+}
+
+func (wh *WorkerHandler) HandleSimulateAuthorizedExternalResources(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	// Process request
+	request := &SimulateAuthorizationRequest{}
+	requestInfo, _, apiErr := wh.processHttpRequest(r, w, nil, request)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+
+	// Add request attributes to evaluate conditions
+	requestInfo.Context.Attributes = request.Context
+
+	// Simulate authorization with candidate statements
+	explanations, err := wh.worker.AuthzApi.SimulateAuthorizedExternalResources(requestInfo, request.ExternalID,
+		request.Statements, request.Replace, request.Action, request.Resources)
+	response := SimulateAuthorizationResponse{
+		Explanations: explanations,
+	}
+	for _, explanation := range explanations {
+		if explanation.Decision == api.DECISION_ALLOWED {
+			response.ResourcesAllowed = append(response.ResourcesAllowed, explanation.Urn)
+		}
+	}
+	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+}
+
+func (wh *WorkerHandler) HandleGetAuthorizedPrincipals(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, _, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+
+	// Retrieve users and groups allowed
+	result, err := wh.worker.AuthzApi.GetAuthorizedPrincipals(requestInfo, r.URL.Query().Get("Action"), r.URL.Query().Get("Urn"))
+	response := GetAuthorizedPrincipalsResponse{}
+	if result != nil {
+		response.Users = result.Users
+		response.Groups = result.Groups
+	}
+	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+}
+
+func (wh *WorkerHandler) HandleGetAuthzSnapshot(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, _, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+
+	// Retrieve users, groups and policies
+	response, err := wh.worker.AuthzApi.GetAuthzSnapshot(requestInfo)
+	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+}
+
+func (wh *WorkerHandler) HandleGetAuthzCacheStats(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, _, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+
+	// Only admin is authorized
+	if !requestInfo.Admin {
+		apiErr = &api.Error{
+			Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+			Message: "Unauthorized, user is not admin",
+		}
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusForbidden)
+		return
+	}
+
+	wh.processHttpResponse(r, w, requestInfo, wh.worker.AuthzCache.Stats(), nil, http.StatusOK)
+}
+
+func (wh *WorkerHandler) HandleFlushAuthzCache(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, _, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+
+	// Only admin is authorized
+	if !requestInfo.Admin {
+		apiErr = &api.Error{
+			Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+			Message: "Unauthorized, user is not admin",
+		}
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusForbidden)
+		return
+	}
+
+	wh.worker.AuthzCache.Flush()
+	wh.processHttpResponse(r, w, requestInfo, nil, nil, http.StatusNoContent)
+}
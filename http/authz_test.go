@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/middleware"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -19,9 +21,11 @@ func TestWorkerHandler_HandleGetAuthorizedExternalResources(t *testing.T) {
 		expectedResponse   AuthorizeResourcesResponse
 		expectedError      api.Error
 		// Manager Results
-		getAuthorizedExternalResourcesResult []string
+		getAuthorizedExternalResourcesResult     []string
+		explainAuthorizedExternalResourcesResult []api.ResourceExplanation
 		// Manager Errors
-		getAuthorizedExternalResourcesErr error
+		getAuthorizedExternalResourcesErr     error
+		explainAuthorizedExternalResourcesErr error
 	}{
 		"OkCase": {
 			request: &AuthorizeResourcesRequest{
@@ -34,6 +38,85 @@ func TestWorkerHandler_HandleGetAuthorizedExternalResources(t *testing.T) {
 			},
 			getAuthorizedExternalResourcesResult: []string{"resource1", "resource2"},
 		},
+		"OkCaseWithContext": {
+			request: &AuthorizeResourcesRequest{
+				Resources: []string{},
+				Action:    api.USER_ACTION_GET_USER,
+				Context: map[string]string{
+					"env": "prod",
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: AuthorizeResourcesResponse{
+				ResourcesAllowed: []string{"resource1"},
+			},
+			getAuthorizedExternalResourcesResult: []string{"resource1"},
+		},
+		"OkCaseExplain": {
+			request: &AuthorizeResourcesRequest{
+				Resources: []string{"resource1", "resource2"},
+				Action:    api.USER_ACTION_GET_USER,
+				Explain:   true,
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: AuthorizeResourcesResponse{
+				ResourcesAllowed: []string{"resource1"},
+				Explanations: []api.ResourceExplanation{
+					{
+						Urn:                "resource1",
+						Decision:           api.DECISION_ALLOWED,
+						WinningRestriction: "resource*",
+						Statements: []api.StatementMatch{
+							{
+								PolicyName:     "policy",
+								StatementIndex: 0,
+								Effect:         "allow",
+								Resource:       "resource*",
+							},
+						},
+					},
+					{
+						Urn:      "resource2",
+						Decision: api.DECISION_IMPLICIT_DENY,
+					},
+				},
+			},
+			explainAuthorizedExternalResourcesResult: []api.ResourceExplanation{
+				{
+					Urn:                "resource1",
+					Decision:           api.DECISION_ALLOWED,
+					WinningRestriction: "resource*",
+					Statements: []api.StatementMatch{
+						{
+							PolicyName:     "policy",
+							StatementIndex: 0,
+							Effect:         "allow",
+							Resource:       "resource*",
+						},
+					},
+				},
+				{
+					Urn:      "resource2",
+					Decision: api.DECISION_IMPLICIT_DENY,
+				},
+			},
+		},
+		"ErrorCaseExplainInvalidParameter": {
+			request: &AuthorizeResourcesRequest{
+				Resources: []string{},
+				Action:    api.USER_ACTION_GET_USER,
+				Explain:   true,
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Error",
+			},
+			explainAuthorizedExternalResourcesErr: &api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Error",
+			},
+		},
 		"ErrorCaseMalformedRequest": {
 			expectedStatusCode: http.StatusBadRequest,
 			expectedError: api.Error{
@@ -90,6 +173,8 @@ func TestWorkerHandler_HandleGetAuthorizedExternalResources(t *testing.T) {
 
 		testApi.ArgsOut[GetAuthorizedExternalResourcesMethod][0] = test.getAuthorizedExternalResourcesResult
 		testApi.ArgsOut[GetAuthorizedExternalResourcesMethod][1] = test.getAuthorizedExternalResourcesErr
+		testApi.ArgsOut[ExplainAuthorizedExternalResourcesMethod][0] = test.explainAuthorizedExternalResourcesResult
+		testApi.ArgsOut[ExplainAuthorizedExternalResourcesMethod][1] = test.explainAuthorizedExternalResourcesErr
 
 		var body *bytes.Buffer
 		if test.request != nil {
@@ -116,13 +201,747 @@ func TestWorkerHandler_HandleGetAuthorizedExternalResources(t *testing.T) {
 			assert.Nil(t, err, "Error in test case %v", n)
 			// Check result
 			assert.Equal(t, test.expectedResponse, authorizeResourcesResponse, "Error in test case %v", n)
+			// Check request context received
+			method := GetAuthorizedExternalResourcesMethod
+			if test.request.Explain {
+				method = ExplainAuthorizedExternalResourcesMethod
+			}
+			requestInfo := testApi.ArgsIn[method][0].(api.RequestInfo)
+			assert.Equal(t, test.request.Context, requestInfo.Context.Attributes, "Error in test case %v", n)
+			assert.Equal(t, "127.0.0.1", requestInfo.Context.SourceIP, "Error in test case %v", n)
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestWorkerHandler_HandleGetAuthorizedExternalResourcesSourceIP(t *testing.T) {
+	testcases := map[string]struct {
+		// Request headers
+		sourceIP    string
+		proxySecret string
+		admin       bool
+		// Expected result
+		expectedSourceIP string
+	}{
+		"OkCaseNoForwardedIP": {
+			expectedSourceIP: "127.0.0.1",
+		},
+		"OkCaseProxySecret": {
+			sourceIP:         "10.1.2.3",
+			proxySecret:      "proxysecret",
+			expectedSourceIP: "10.1.2.3",
+		},
+		"OkCaseAdmin": {
+			sourceIP:         "10.1.2.3",
+			admin:            true,
+			expectedSourceIP: "10.1.2.3",
+		},
+		"OkCaseNoProxySecret": {
+			sourceIP:         "10.1.2.3",
+			expectedSourceIP: "127.0.0.1",
+		},
+		"OkCaseInvalidProxySecret": {
+			sourceIP:         "10.1.2.3",
+			proxySecret:      "invalid",
+			expectedSourceIP: "127.0.0.1",
+		},
+		"OkCaseInvalidForwardedIP": {
+			sourceIP:         "invalid",
+			proxySecret:      "proxysecret",
+			expectedSourceIP: "127.0.0.1",
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+		testApi.ArgsOut[GetAuthorizedExternalResourcesMethod][0] = []string{"resource1"}
+		testApi.ArgsOut[GetAuthorizedExternalResourcesMethod][1] = nil
+
+		jsonObject, err := json.Marshal(&AuthorizeResourcesRequest{
+			Resources: []string{"resource1"},
+			Action:    api.USER_ACTION_GET_USER,
+		})
+		assert.Nil(t, err, "Error in test case %v", n)
+		req, err := http.NewRequest(http.MethodPost, server.URL+RESOURCE_URL, bytes.NewBuffer(jsonObject))
+		assert.Nil(t, err, "Error in test case %v", n)
+		if test.sourceIP != "" {
+			req.Header.Set(middleware.SOURCE_IP_HEADER, test.sourceIP)
+		}
+		if test.proxySecret != "" {
+			req.Header.Set(middleware.PROXY_SECRET_HEADER, test.proxySecret)
+		}
+		if test.admin {
+			req.SetBasicAuth("admin", "admin")
+		}
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+		assert.Equal(t, http.StatusOK, res.StatusCode, "Error in test case %v", n)
+
+		requestInfo := testApi.ArgsIn[GetAuthorizedExternalResourcesMethod][0].(api.RequestInfo)
+		assert.Equal(t, test.expectedSourceIP, requestInfo.Context.SourceIP, "Error in test case %v", n)
+	}
+}
+
+func TestWorkerHandler_HandleGetAuthorizedExternalResourcesBatch(t *testing.T) {
+	testcases := map[string]struct {
+		// API method args
+		request *AuthorizeResourcesBatchRequest
+		// Expected result
+		expectedStatusCode int
+		expectedResponse   AuthorizeResourcesBatchResponse
+		expectedError      api.Error
+		// Manager Results
+		getAuthorizedExternalResourcesBatchResult []api.AuthorizeItemResult
+		// Manager Errors
+		getAuthorizedExternalResourcesBatchErr error
+	}{
+		"OkCase": {
+			request: &AuthorizeResourcesBatchRequest{
+				Items: []api.AuthorizeItem{
+					{
+						Action:    "example:Read",
+						Resources: []string{"resource1", "resource2"},
+					},
+					{
+						Action:    "example:Write",
+						Resources: []string{"resource1"},
+					},
+				},
+				Context: map[string]string{
+					"env": "prod",
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: AuthorizeResourcesBatchResponse{
+				Items: []api.AuthorizeItemResult{
+					{
+						Action:           "example:Read",
+						ResourcesAllowed: []string{"resource1", "resource2"},
+					},
+					{
+						Action:           "example:Write",
+						ResourcesAllowed: []string{},
+					},
+				},
+			},
+			getAuthorizedExternalResourcesBatchResult: []api.AuthorizeItemResult{
+				{
+					Action:           "example:Read",
+					ResourcesAllowed: []string{"resource1", "resource2"},
+				},
+				{
+					Action:           "example:Write",
+					ResourcesAllowed: []string{},
+				},
+			},
+		},
+		"ErrorCaseMalformedRequest": {
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "EOF",
+			},
+		},
+		"ErrorCaseInvalidParameter": {
+			request: &AuthorizeResourcesBatchRequest{
+				Items: []api.AuthorizeItem{},
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Error",
+			},
+			getAuthorizedExternalResourcesBatchErr: &api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseUnauthorizedError": {
+			request: &AuthorizeResourcesBatchRequest{
+				Items: []api.AuthorizeItem{
+					{
+						Action:    "example:Read",
+						Resources: []string{"resource1"},
+					},
+				},
+			},
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Error",
+			},
+			getAuthorizedExternalResourcesBatchErr: &api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseUnknownApiError": {
+			request: &AuthorizeResourcesBatchRequest{
+				Items: []api.AuthorizeItem{
+					{
+						Action:    "example:Read",
+						Resources: []string{"resource1"},
+					},
+				},
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			getAuthorizedExternalResourcesBatchErr: &api.Error{
+				Code:    api.UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[GetAuthorizedExternalResourcesBatchMethod][0] = test.getAuthorizedExternalResourcesBatchResult
+		testApi.ArgsOut[GetAuthorizedExternalResourcesBatchMethod][1] = test.getAuthorizedExternalResourcesBatchErr
+
+		var body *bytes.Buffer
+		if test.request != nil {
+			jsonObject, err := json.Marshal(test.request)
+			assert.Nil(t, err, "Error in test case %v", n)
+			body = bytes.NewBuffer(jsonObject)
+		}
+		if body == nil {
+			body = bytes.NewBuffer([]byte{})
+		}
+		req, err := http.NewRequest(http.MethodPost, server.URL+RESOURCE_BATCH_URL, body)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			authorizeResourcesBatchResponse := AuthorizeResourcesBatchResponse{}
+			err = json.NewDecoder(res.Body).Decode(&authorizeResourcesBatchResponse)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedResponse, authorizeResourcesBatchResponse, "Error in test case %v", n)
+			// Check request received
+			assert.Equal(t, test.request.Items, testApi.ArgsIn[GetAuthorizedExternalResourcesBatchMethod][1], "Error in test case %v", n)
+			requestInfo := testApi.ArgsIn[GetAuthorizedExternalResourcesBatchMethod][0].(api.RequestInfo)
+			assert.Equal(t, test.request.Context, requestInfo.Context.Attributes, "Error in test case %v", n)
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestWorkerHandler_HandleSimulateAuthorizedExternalResources(t *testing.T) {
+	testcases := map[string]struct {
+		// API method args
+		request *SimulateAuthorizationRequest
+		// Expected result
+		expectedStatusCode int
+		expectedResponse   SimulateAuthorizationResponse
+		expectedError      api.Error
+		// Manager Results
+		simulateAuthorizedExternalResourcesResult []api.ResourceExplanation
+		// Manager Errors
+		simulateAuthorizedExternalResourcesErr error
+	}{
+		"OkCase": {
+			request: &SimulateAuthorizationRequest{
+				ExternalID: "user1",
+				Statements: []api.Statement{
+					{
+						Effect:    "allow",
+						Actions:   []string{"product:DoAction"},
+						Resources: []string{"resource*"},
+					},
+				},
+				Replace:   true,
+				Action:    "product:DoAction",
+				Resources: []string{"resource1", "other"},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: SimulateAuthorizationResponse{
+				ResourcesAllowed: []string{"resource1"},
+				Explanations: []api.ResourceExplanation{
+					{
+						Urn:                "resource1",
+						Decision:           api.DECISION_ALLOWED,
+						WinningRestriction: "resource*",
+					},
+					{
+						Urn:      "other",
+						Decision: api.DECISION_IMPLICIT_DENY,
+					},
+				},
+			},
+			simulateAuthorizedExternalResourcesResult: []api.ResourceExplanation{
+				{
+					Urn:                "resource1",
+					Decision:           api.DECISION_ALLOWED,
+					WinningRestriction: "resource*",
+				},
+				{
+					Urn:      "other",
+					Decision: api.DECISION_IMPLICIT_DENY,
+				},
+			},
+		},
+		"ErrorCaseMalformedRequest": {
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "EOF",
+			},
+		},
+		"ErrorCaseUserNotFound": {
+			request: &SimulateAuthorizationRequest{
+				ExternalID: "user1",
+				Action:     "product:DoAction",
+				Resources:  []string{"resource1"},
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedError: api.Error{
+				Code:    api.USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message: "Error",
+			},
+			simulateAuthorizedExternalResourcesErr: &api.Error{
+				Code:    api.USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseUnauthorizedError": {
+			request: &SimulateAuthorizationRequest{
+				ExternalID: "user1",
+				Action:     "product:DoAction",
+				Resources:  []string{"resource1"},
+			},
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Error",
+			},
+			simulateAuthorizedExternalResourcesErr: &api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseUnknownApiError": {
+			request: &SimulateAuthorizationRequest{
+				ExternalID: "user1",
+				Action:     "product:DoAction",
+				Resources:  []string{"resource1"},
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			simulateAuthorizedExternalResourcesErr: &api.Error{
+				Code:    api.UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[SimulateAuthorizedExternalResourcesMethod][0] = test.simulateAuthorizedExternalResourcesResult
+		testApi.ArgsOut[SimulateAuthorizedExternalResourcesMethod][1] = test.simulateAuthorizedExternalResourcesErr
+
+		var body *bytes.Buffer
+		if test.request != nil {
+			jsonObject, err := json.Marshal(test.request)
+			assert.Nil(t, err, "Error in test case %v", n)
+			body = bytes.NewBuffer(jsonObject)
+		}
+		if body == nil {
+			body = bytes.NewBuffer([]byte{})
+		}
+		req, err := http.NewRequest(http.MethodPost, server.URL+RESOURCE_SIMULATE_URL, body)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		if test.request != nil {
+			// Check received parameters
+			assert.Equal(t, test.request.ExternalID, testApi.ArgsIn[SimulateAuthorizedExternalResourcesMethod][1], "Error in test case %v", n)
+			assert.Equal(t, test.request.Statements, testApi.ArgsIn[SimulateAuthorizedExternalResourcesMethod][2], "Error in test case %v", n)
+			assert.Equal(t, test.request.Replace, testApi.ArgsIn[SimulateAuthorizedExternalResourcesMethod][3], "Error in test case %v", n)
+			assert.Equal(t, test.request.Action, testApi.ArgsIn[SimulateAuthorizedExternalResourcesMethod][4], "Error in test case %v", n)
+			assert.Equal(t, test.request.Resources, testApi.ArgsIn[SimulateAuthorizedExternalResourcesMethod][5], "Error in test case %v", n)
+		}
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			simulateAuthorizationResponse := SimulateAuthorizationResponse{}
+			err = json.NewDecoder(res.Body).Decode(&simulateAuthorizationResponse)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedResponse, simulateAuthorizationResponse, "Error in test case %v", n)
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestWorkerHandler_HandleGetAuthorizedPrincipals(t *testing.T) {
+	testcases := map[string]struct {
+		// API method args
+		action      string
+		resourceUrn string
+		// Expected result
+		expectedStatusCode int
+		expectedResponse   GetAuthorizedPrincipalsResponse
+		expectedError      api.Error
+		// Manager Results
+		getAuthorizedPrincipalsResult *api.AuthorizedPrincipals
+		// Manager Errors
+		getAuthorizedPrincipalsErr error
+	}{
+		"OkCase": {
+			action:             api.POLICY_ACTION_DELETE_POLICY,
+			resourceUrn:        "urn:iws:iam:acme:policy/prod/*",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: GetAuthorizedPrincipalsResponse{
+				Users: []api.AuthorizedUser{
+					{
+						ExternalID: "user1",
+						Urn:        "urn:iws:iam::user/user1",
+						Groups:     []api.GroupIdentity{{Org: "acme", Name: "group1"}},
+						Policies:   []api.PolicyIdentity{{Org: "acme", Name: "policy1"}},
+					},
+				},
+				Groups: []api.AuthorizedGroup{
+					{
+						Org:      "acme",
+						Name:     "group1",
+						Urn:      "urn:iws:iam:acme:group/group1",
+						Policies: []api.PolicyIdentity{{Org: "acme", Name: "policy1"}},
+					},
+				},
+			},
+			getAuthorizedPrincipalsResult: &api.AuthorizedPrincipals{
+				Users: []api.AuthorizedUser{
+					{
+						ExternalID: "user1",
+						Urn:        "urn:iws:iam::user/user1",
+						Groups:     []api.GroupIdentity{{Org: "acme", Name: "group1"}},
+						Policies:   []api.PolicyIdentity{{Org: "acme", Name: "policy1"}},
+					},
+				},
+				Groups: []api.AuthorizedGroup{
+					{
+						Org:      "acme",
+						Name:     "group1",
+						Urn:      "urn:iws:iam:acme:group/group1",
+						Policies: []api.PolicyIdentity{{Org: "acme", Name: "policy1"}},
+					},
+				},
+			},
+		},
+		"ErrorCaseInvalidParameter": {
+			action:             "invalid::action",
+			resourceUrn:        "urn:iws:iam:acme:policy/prod/*",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Error",
+			},
+			getAuthorizedPrincipalsErr: &api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseUnauthorizedError": {
+			action:             api.POLICY_ACTION_DELETE_POLICY,
+			resourceUrn:        "urn:iws:iam:acme:policy/prod/*",
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Error",
+			},
+			getAuthorizedPrincipalsErr: &api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseUnknownApiError": {
+			action:             api.POLICY_ACTION_DELETE_POLICY,
+			resourceUrn:        "urn:iws:iam:acme:policy/prod/*",
+			expectedStatusCode: http.StatusInternalServerError,
+			getAuthorizedPrincipalsErr: &api.Error{
+				Code:    api.UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[GetAuthorizedPrincipalsMethod][0] = test.getAuthorizedPrincipalsResult
+		testApi.ArgsOut[GetAuthorizedPrincipalsMethod][1] = test.getAuthorizedPrincipalsErr
+
+		req, err := http.NewRequest(http.MethodGet, server.URL+RESOURCE_PRINCIPALS_URL, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+		q := req.URL.Query()
+		q.Add("Action", test.action)
+		q.Add("Urn", test.resourceUrn)
+		req.URL.RawQuery = q.Encode()
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		// Check received parameters
+		assert.Equal(t, test.action, testApi.ArgsIn[GetAuthorizedPrincipalsMethod][1], "Error in test case %v", n)
+		assert.Equal(t, test.resourceUrn, testApi.ArgsIn[GetAuthorizedPrincipalsMethod][2], "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			getAuthorizedPrincipalsResponse := GetAuthorizedPrincipalsResponse{}
+			err = json.NewDecoder(res.Body).Decode(&getAuthorizedPrincipalsResponse)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedResponse, getAuthorizedPrincipalsResponse, "Error in test case %v", n)
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestWorkerHandler_HandleGetAuthzSnapshot(t *testing.T) {
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// Expected result
+		expectedStatusCode int
+		expectedResponse   api.AuthzSnapshot
+		expectedError      api.Error
+		// Manager Results
+		getAuthzSnapshotResult *api.AuthzSnapshot
+		// Manager Errors
+		getAuthzSnapshotErr error
+	}{
+		"OkCase": {
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: api.AuthzSnapshot{
+				Users: []api.User{
+					{
+						ID:         "UserID",
+						ExternalID: "user1",
+						CreateAt:   now,
+						UpdateAt:   now,
+					},
+				},
+				UserPolicies: []api.AuthzSnapshotRelation{
+					{ID: "UserID", RelatedID: "PolicyID"},
+				},
+				CreateAt: now,
+			},
+			getAuthzSnapshotResult: &api.AuthzSnapshot{
+				Users: []api.User{
+					{
+						ID:         "UserID",
+						ExternalID: "user1",
+						CreateAt:   now,
+						UpdateAt:   now,
+					},
+				},
+				UserPolicies: []api.AuthzSnapshotRelation{
+					{ID: "UserID", RelatedID: "PolicyID"},
+				},
+				CreateAt: now,
+			},
+		},
+		"ErrorCaseUnauthorizedError": {
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Error",
+			},
+			getAuthzSnapshotErr: &api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseUnknownApiError": {
+			expectedStatusCode: http.StatusInternalServerError,
+			getAuthzSnapshotErr: &api.Error{
+				Code:    api.UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+		testApi.ArgsOut[GetAuthzSnapshotMethod][0] = test.getAuthzSnapshotResult
+		testApi.ArgsOut[GetAuthzSnapshotMethod][1] = test.getAuthzSnapshotErr
+
+		req, err := http.NewRequest(http.MethodGet, server.URL+AUTHZ_SNAPSHOT_URL, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			response := api.AuthzSnapshot{}
+			err = json.NewDecoder(res.Body).Decode(&response)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedResponse, response, "Error in test case %v", n)
 		case http.StatusInternalServerError: // Empty message so continue
 			continue
 		default:
 			apiError := api.Error{}
 			err = json.NewDecoder(res.Body).Decode(&apiError)
 			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestWorkerHandler_HandleGetAuthzCacheStats(t *testing.T) {
+	testcases := map[string]struct {
+		adminUser     string
+		adminPassword string
+		// Expected result
+		expectedStatusCode int
+		expectedResponse   api.AuthzCacheStats
+		expectedError      api.Error
+	}{
+		"OkCase": {
+			adminUser:          "admin",
+			adminPassword:      "admin",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: api.AuthzCacheStats{
+				Enabled: true,
+				TTL:     "1m0s",
+			},
+		},
+		"ErrorCaseInvalidAdmin": {
+			adminUser:          "admin",
+			adminPassword:      "fail",
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized, user is not admin",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+		req, err := http.NewRequest(http.MethodGet, server.URL+AUTHZ_CACHE_URL, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		req.SetBasicAuth(test.adminUser, test.adminPassword)
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			response := api.AuthzCacheStats{}
+			err = json.NewDecoder(res.Body).Decode(&response)
+			assert.Nil(t, err, "Error in test case %v", n)
 			// Check result
+			assert.Equal(t, test.expectedResponse, response, "Error in test case %v", n)
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestWorkerHandler_HandleFlushAuthzCache(t *testing.T) {
+	testcases := map[string]struct {
+		adminUser     string
+		adminPassword string
+		// Expected result
+		expectedStatusCode int
+		expectedError      api.Error
+	}{
+		"OkCase": {
+			adminUser:          "admin",
+			adminPassword:      "admin",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		"ErrorCaseInvalidAdmin": {
+			adminUser:          "admin",
+			adminPassword:      "fail",
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized, user is not admin",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+		req, err := http.NewRequest(http.MethodDelete, server.URL+AUTHZ_CACHE_URL, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		req.SetBasicAuth(test.adminUser, test.adminPassword)
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		if res.StatusCode != http.StatusNoContent {
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
 			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
 		}
 	}
@@ -0,0 +1,228 @@
+package authzcheck
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/middleware"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// Authorization error codes
+	HOST_UNREACHABLE      = "HostUnreachableError"
+	INTERNAL_SERVER_ERROR = "InternalServerError"
+	BAD_REQUEST           = "BadRequest"
+	FORBIDDEN_ERROR       = "ForbiddenError"
+
+	// Worker path to authorize resources
+	RESOURCE_URL = "/api/v1/resource"
+
+	// Time bucket of authorization cache keys, the granularity of time of day conditions
+	AUTHZ_CACHE_TIME_BUCKET = time.Minute
+)
+
+// REQUESTS
+
+type authorizeResourcesRequest struct {
+	Action    string   `json:"action,omitempty"`
+	Resources []string `json:"resources,omitempty"`
+}
+
+// RESPONSES
+
+type authorizeResourcesResponse struct {
+	ResourcesAllowed []string `json:"resourcesAllowed,omitempty"`
+}
+
+var rUrnParam, _ = regexp.Compile(`\{(\w+)\}`)
+
+// ReplaceUrnParams replaces the {param} placeholders of a proxy resource URN with the values of the path params
+func ReplaceUrnParams(urn string, ps httprouter.Params) string {
+	for _, p := range getUrnParameters(urn) {
+		urn = strings.Replace(urn, p[0], ps.ByName(p[1]), -1)
+	}
+	return urn
+}
+
+// AuthorizationErrorResponse returns the status code and the error to respond when a request isn't authorized
+func AuthorizationErrorResponse(apiError *api.Error) (int, *api.Error) {
+	switch apiError.Code {
+	case FORBIDDEN_ERROR:
+		return http.StatusForbidden, getErrorMessage(FORBIDDEN_ERROR, "")
+	case api.INVALID_PARAMETER_ERROR, api.REGEX_NO_MATCH, BAD_REQUEST:
+		return http.StatusBadRequest, getErrorMessage(api.INVALID_PARAMETER_ERROR, "Bad request")
+	default:
+		return http.StatusInternalServerError, getErrorMessage(INTERNAL_SERVER_ERROR, "Internal server error. Contact the administrator")
+	}
+}
+
+// HashCredentials returns a hash of the values of headers in request r, so credentials aren't kept in memory, or an
+// empty string if r doesn't have any of the headers
+func HashCredentials(r *http.Request, headers []string) string {
+	hash := sha256.New()
+	withCredentials := false
+	for _, header := range headers {
+		values := r.Header[http.CanonicalHeaderKey(header)]
+		if len(values) > 0 {
+			withCredentials = true
+		}
+		fmt.Fprintf(hash, "%q:%q\n", header, values)
+	}
+	if !withCredentials {
+		return ""
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// AuthzCacheKey returns the key of an authorization result of identity from sourceIP doing action over urn. Policy
+// conditions are evaluated with the source IP and time of requests, so results are only reused from the same IP and
+// in the same AUTHZ_CACHE_TIME_BUCKET they were decided
+func AuthzCacheKey(identity string, sourceIP string, action string, urn string) string {
+	bucket := time.Now().UTC().Truncate(AUTHZ_CACHE_TIME_BUCKET).Unix()
+	return fmt.Sprintf("%q %v %v %v %v", identity, sourceIP, bucket, action, urn)
+}
+
+// ValidateAuthorization checks that urn is a valid full urn and action is valid
+func ValidateAuthorization(urn string, action string) error {
+	if !isFullUrn(urn) {
+		return getErrorMessage(api.INVALID_PARAMETER_ERROR, fmt.Sprintf("Urn %v is a prefix, it would be a full urn resource", urn))
+	}
+	if err := api.AreValidResources([]string{urn}, api.RESOURCE_EXTERNAL); err != nil {
+		return err
+	}
+	return api.AreValidActions([]string{action})
+}
+
+// CheckAuthorization asks the worker at workerHost if the user of request r, authenticated with its headers, is
+// allowed to do action over urn. Source IP of r is forwarded to worker, which only trusts it if proxySecret is the
+// one configured in worker. It returns the request ID of the worker response, the user authenticated by worker,
+// empty if worker didn't authenticate it, and an *api.Error with code FORBIDDEN_ERROR if the user isn't allowed
+func CheckAuthorization(client *http.Client, workerHost string, proxySecret string, r *http.Request, urn string,
+	action string) (string, string, error) {
+	workerRequestID := "None"
+	if err := ValidateAuthorization(urn, action); err != nil {
+		return workerRequestID, "", err
+	}
+
+	body, err := json.Marshal(authorizeResourcesRequest{
+		Action:    action,
+		Resources: []string{urn},
+	})
+	if err != nil {
+		return workerRequestID, "", getErrorMessage(api.UNKNOWN_API_ERROR, err.Error())
+	}
+
+	req, err := http.NewRequest(http.MethodPost, workerHost+RESOURCE_URL, bytes.NewBuffer(body))
+	if err != nil {
+		return workerRequestID, "", getErrorMessage(api.UNKNOWN_API_ERROR, err.Error())
+	}
+	// Add all headers from original request, except the ones set by proxy
+	req.Header = cloneHeader(r.Header)
+	req.Header.Del(middleware.PROXY_SECRET_HEADER)
+	req.Header.Set(middleware.SOURCE_IP_HEADER, middleware.GetSourceIP(r))
+	if proxySecret != "" {
+		req.Header.Set(middleware.PROXY_SECRET_HEADER, proxySecret)
+	}
+	// Call worker to retrieve authorization
+	res, err := client.Do(req)
+	if err != nil {
+		return workerRequestID, "", getErrorMessage(HOST_UNREACHABLE, err.Error())
+	}
+
+	defer res.Body.Close()
+
+	workerRequestID = res.Header.Get(middleware.REQUEST_ID_HEADER)
+	user := res.Header.Get(middleware.USER_ID_HEADER)
+
+	switch res.StatusCode {
+	case http.StatusUnauthorized:
+		return workerRequestID, user, getErrorMessage(FORBIDDEN_ERROR, "Unauthenticated user")
+	case http.StatusForbidden:
+		return workerRequestID, user, getErrorMessage(FORBIDDEN_ERROR, fmt.Sprintf("Restricted access to urn %v", urn))
+	case http.StatusBadRequest:
+		return workerRequestID, user, getErrorMessage(BAD_REQUEST, "Invalid request")
+	case http.StatusOK:
+		authzResponse := authorizeResourcesResponse{}
+		err = json.NewDecoder(res.Body).Decode(&authzResponse)
+		if err != nil {
+			return workerRequestID, user, getErrorMessage(api.UNKNOWN_API_ERROR, fmt.Sprintf("Error parsing foulkon response %v", err.Error()))
+		}
+
+		// Check urns allowed to find target urn
+		allowed := false
+		for _, allowedRes := range authzResponse.ResourcesAllowed {
+			if allowedRes == urn {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return workerRequestID, user,
+				getErrorMessage(FORBIDDEN_ERROR, fmt.Sprintf("No access for urn %v received from server", urn))
+		}
+
+		return workerRequestID, user, nil
+	default:
+		return workerRequestID, user,
+			getErrorMessage(INTERNAL_SERVER_ERROR, fmt.Sprintf("There was a problem retrieving authorization, status code %v", res.StatusCode))
+	}
+}
+
+// WriteErrorResponse writes apiError as the JSON body of a response with statusCode
+func WriteErrorResponse(w http.ResponseWriter, statusCode int, apiError *api.Error) {
+	b, err := json.Marshal(apiError)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(b)
+}
+
+// PRIVATE HELPER METHODS
+
+// Check parameters in URN to replace with URI parameters
+func getUrnParameters(urn string) [][]string {
+	match := rUrnParam.FindAllStringSubmatch(urn, -1)
+	if match != nil && len(match) > 0 {
+		return match
+	}
+	return nil
+}
+
+func isFullUrn(resource string) bool {
+	return !strings.ContainsAny(resource, "*")
+}
+
+// Return a copy of header that can be modified without changing the original one
+func cloneHeader(header http.Header) http.Header {
+	clone := http.Header{}
+	for key, values := range header {
+		clone[key] = append([]string{}, values...)
+	}
+	return clone
+}
+
+func getErrorMessage(errorCode string, message string) *api.Error {
+	if message == "" {
+		return &api.Error{
+			Code:    errorCode,
+			Message: "Forbidden resource. If you need access, contact the administrator",
+		}
+	}
+	return &api.Error{
+		Code:    errorCode,
+		Message: message,
+	}
+}
@@ -0,0 +1,53 @@
+package authzcheck
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashCredentials(t *testing.T) {
+	testcases := map[string]struct {
+		headers map[string]string
+		// Expected result
+		expectedEmpty bool
+	}{
+		"OkCase": {
+			headers: map[string]string{"Authorization": "Bearer token"},
+		},
+		"OkCaseOtherHeader": {
+			headers: map[string]string{"X-Api-Key": "key"},
+		},
+		"OkCaseWithoutCredentials": {
+			headers:       map[string]string{"Accept": "application/json"},
+			expectedEmpty: true,
+		},
+	}
+
+	for n, test := range testcases {
+		r, err := http.NewRequest(http.MethodGet, "/resource", nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+		for key, value := range test.headers {
+			r.Header.Set(key, value)
+		}
+		hash := HashCredentials(r, []string{"Authorization", "X-Api-Key"})
+		assert.Equal(t, test.expectedEmpty, hash == "", "Error in test case %v", n)
+		for _, value := range test.headers {
+			assert.NotContains(t, hash, value, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestAuthzCacheKey(t *testing.T) {
+	key := AuthzCacheKey("user1", "127.0.0.1", "example:get", "urn:ews:example:instance1:resource/1")
+	assert.Equal(t, key, AuthzCacheKey("user1", "127.0.0.1", "example:get", "urn:ews:example:instance1:resource/1"))
+	// Results aren't shared between users or source IPs
+	assert.NotEqual(t, key, AuthzCacheKey("user2", "127.0.0.1", "example:get", "urn:ews:example:instance1:resource/1"))
+	assert.NotEqual(t, key, AuthzCacheKey("user1", "10.0.0.1", "example:get", "urn:ews:example:instance1:resource/1"))
+	// Time bucket is part of the key
+	bucket := time.Now().UTC().Truncate(AUTHZ_CACHE_TIME_BUCKET).Unix()
+	assert.Contains(t, key, fmt.Sprintf(" %v ", bucket))
+}
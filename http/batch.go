@@ -0,0 +1,40 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// REQUESTS
+
+type BatchRequest struct {
+	Operations []api.BatchOperation `json:"operations,omitempty"`
+}
+
+// RESPONSES
+
+type BatchResponse struct {
+	Results []api.BatchOperationResult `json:"results"`
+}
+
+// HANDLERS
+
+func (wh *WorkerHandler) HandleExecuteBatch(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	request := &BatchRequest{}
+	requestInfo, _, apiErr := wh.processHttpRequest(r, w, ps, request)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+	// Call batch API to apply the operations
+	result, err := wh.worker.BatchApi.ExecuteBatch(requestInfo, request.Operations)
+
+	// Create response
+	response := &BatchResponse{
+		Results: result,
+	}
+	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+}
@@ -0,0 +1,194 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerHandler_HandleExecuteBatch(t *testing.T) {
+	testcases := map[string]struct {
+		// API method args
+		request *BatchRequest
+		// Expected result
+		expectedStatusCode int
+		expectedResponse   BatchResponse
+		expectedError      api.Error
+		// Manager Results
+		executeBatchResult []api.BatchOperationResult
+		// Manager Errors
+		executeBatchErr error
+	}{
+		"OkCase": {
+			request: &BatchRequest{
+				Operations: []api.BatchOperation{
+					{
+						Action:     api.GROUP_ACTION_ADD_MEMBER,
+						ExternalID: "user1",
+						Org:        "org1",
+						Group:      "group1",
+					},
+					{
+						Action: api.GROUP_ACTION_ATTACH_GROUP_POLICY,
+						Org:    "org1",
+						Group:  "group1",
+						Policy: "policy1",
+					},
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: BatchResponse{
+				Results: []api.BatchOperationResult{
+					{
+						Action: api.GROUP_ACTION_ADD_MEMBER,
+					},
+					{
+						Action: api.GROUP_ACTION_ATTACH_GROUP_POLICY,
+					},
+				},
+			},
+			executeBatchResult: []api.BatchOperationResult{
+				{
+					Action: api.GROUP_ACTION_ADD_MEMBER,
+				},
+				{
+					Action: api.GROUP_ACTION_ATTACH_GROUP_POLICY,
+				},
+			},
+		},
+		"ErrorCaseMalformedRequest": {
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "EOF",
+			},
+		},
+		"ErrorCaseInvalidParameterError": {
+			request: &BatchRequest{
+				Operations: []api.BatchOperation{
+					{
+						Action: api.POLICY_ACTION_LIST_POLICIES,
+					},
+				},
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: action iam:ListPolicies in operation 0",
+			},
+			executeBatchErr: &api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: action iam:ListPolicies in operation 0",
+			},
+		},
+		"ErrorCaseGroupNotFound": {
+			request: &BatchRequest{
+				Operations: []api.BatchOperation{
+					{
+						Action:     api.GROUP_ACTION_ADD_MEMBER,
+						ExternalID: "user1",
+						Org:        "org1",
+						Group:      "group1",
+					},
+				},
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedError: api.Error{
+				Code:    api.GROUP_BY_ORG_AND_NAME_NOT_FOUND,
+				Message: "Operation 0 with action iam:AddMember failed: Error",
+			},
+			executeBatchErr: &api.Error{
+				Code:    api.GROUP_BY_ORG_AND_NAME_NOT_FOUND,
+				Message: "Operation 0 with action iam:AddMember failed: Error",
+			},
+		},
+		"ErrorCaseUnauthorizedError": {
+			request: &BatchRequest{
+				Operations: []api.BatchOperation{
+					{
+						Action:     api.GROUP_ACTION_ADD_MEMBER,
+						ExternalID: "user1",
+						Org:        "org1",
+						Group:      "group1",
+					},
+				},
+			},
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+			executeBatchErr: &api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+		},
+		"ErrorCaseUnknownApiError": {
+			request: &BatchRequest{
+				Operations: []api.BatchOperation{
+					{
+						Action:     api.GROUP_ACTION_ADD_MEMBER,
+						ExternalID: "user1",
+						Org:        "org1",
+						Group:      "group1",
+					},
+				},
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			executeBatchErr: &api.Error{
+				Code:    api.UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsIn[ExecuteBatchMethod][1] = nil
+		testApi.ArgsOut[ExecuteBatchMethod][0] = test.executeBatchResult
+		testApi.ArgsOut[ExecuteBatchMethod][1] = test.executeBatchErr
+
+		body := bytes.NewBuffer([]byte{})
+		if test.request != nil {
+			jsonObject, err := json.Marshal(test.request)
+			assert.Nil(t, err, "Error in test case %v", n)
+			body = bytes.NewBuffer(jsonObject)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, server.URL+BATCH_URL, body)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		if test.request != nil {
+			// Check received parameters
+			assert.Equal(t, test.request.Operations, testApi.ArgsIn[ExecuteBatchMethod][1], "Error in test case %v", n)
+		}
+
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			batchResponse := BatchResponse{}
+			err = json.NewDecoder(res.Body).Decode(&batchResponse)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedResponse, batchResponse, "Error in test case %v", n)
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
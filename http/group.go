@@ -49,6 +49,13 @@ type ListAttachedGroupPoliciesResponse struct {
 	Total            int                 `json:"total"`
 }
 
+type ListSubgroupsResponse struct {
+	Subgroups []api.GroupSubgroups `json:"subgroups,omitempty"`
+	Limit     int                  `json:"limit"`
+	Offset    int                  `json:"offset"`
+	Total     int                  `json:"total"`
+}
+
 // HANDLERS
 
 func (wh *WorkerHandler) HandleAddGroup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -73,6 +80,9 @@ func (wh *WorkerHandler) HandleGetGroupByName(w http.ResponseWriter, r *http.Req
 	}
 	// Call group API to retrieve group
 	response, err := wh.worker.GroupApi.GetGroupByName(requestInfo, filterData.Org, filterData.GroupName)
+	if err == nil {
+		w.Header().Set(ETAG_HEADER, api.ETag(response.UpdateAt))
+	}
 	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
 }
 
@@ -128,6 +138,9 @@ func (wh *WorkerHandler) HandleUpdateGroup(w http.ResponseWriter, r *http.Reques
 	}
 	// Call group API to update group
 	response, err := wh.worker.GroupApi.UpdateGroup(requestInfo, filterData.Org, filterData.GroupName, request.Name, request.Path)
+	if err == nil {
+		w.Header().Set(ETAG_HEADER, api.ETag(response.UpdateAt))
+	}
 	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
 }
 
@@ -227,3 +240,45 @@ func (wh *WorkerHandler) HandleListAttachedGroupPolicies(w http.ResponseWriter,
 	}
 	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
 }
+
+func (wh *WorkerHandler) HandleAddSubgroup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, filterData, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+	// Call group API to add subgroup to group
+	err := wh.worker.GroupApi.AddSubgroup(requestInfo, filterData.Org, filterData.GroupName, ps.ByName(SUBGROUP_NAME))
+	wh.processHttpResponse(r, w, requestInfo, nil, err, http.StatusNoContent)
+}
+
+func (wh *WorkerHandler) HandleRemoveSubgroup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, filterData, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+	// Call group API to remove subgroup from group
+	err := wh.worker.GroupApi.RemoveSubgroup(requestInfo, filterData.Org, filterData.GroupName, ps.ByName(SUBGROUP_NAME))
+	wh.processHttpResponse(r, w, requestInfo, nil, err, http.StatusNoContent)
+}
+
+func (wh *WorkerHandler) HandleListSubgroups(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, filterData, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+	// Call group API to list subgroups of group
+	result, total, err := wh.worker.GroupApi.ListSubgroups(requestInfo, filterData)
+	response := &ListSubgroupsResponse{
+		Subgroups: result,
+		Offset:    filterData.Offset,
+		Limit:     filterData.Limit,
+		Total:     total,
+	}
+	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+}
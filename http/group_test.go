@@ -1880,3 +1880,428 @@ func TestWorkerHandler_HandleListAttachedGroupPolicies(t *testing.T) {
 		}
 	}
 }
+
+func TestWorkerHandler_HandleAddSubgroup(t *testing.T) {
+	testcases := map[string]struct {
+		// API method args
+		org          string
+		groupName    string
+		subgroupName string
+		offset       string
+		ignoreArgsIn bool
+		// Expected result
+		expectedStatusCode int
+		expectedError      api.Error
+		// Manager Errors
+		addSubgroupErr error
+	}{
+		"OkCase": {
+			org:                "org1",
+			groupName:          "group1",
+			subgroupName:       "group2",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		"ErrorCaseInvalidRequest": {
+			org:                "org1",
+			groupName:          "group1",
+			subgroupName:       "group2",
+			offset:             "-1",
+			ignoreArgsIn:       true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: Offset -1",
+			},
+		},
+		"ErrorCaseGroupNotFoundErr": {
+			org:                "org1",
+			groupName:          "group1",
+			subgroupName:       "group2",
+			expectedStatusCode: http.StatusNotFound,
+			expectedError: api.Error{
+				Code:    api.GROUP_BY_ORG_AND_NAME_NOT_FOUND,
+				Message: "Group Not Found",
+			},
+			addSubgroupErr: &api.Error{
+				Code:    api.GROUP_BY_ORG_AND_NAME_NOT_FOUND,
+				Message: "Group Not Found",
+			},
+		},
+		"ErrorCaseUnauthorizedError": {
+			org:                "org1",
+			groupName:          "group1",
+			subgroupName:       "group2",
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+			addSubgroupErr: &api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+		},
+		"ErrorCaseGroupIsAlreadyMemberErr": {
+			org:                "org1",
+			groupName:          "group1",
+			subgroupName:       "group2",
+			expectedStatusCode: http.StatusConflict,
+			expectedError: api.Error{
+				Code:    api.GROUP_IS_ALREADY_A_MEMBER_OF_GROUP,
+				Message: "Group is already a member of group",
+			},
+			addSubgroupErr: &api.Error{
+				Code:    api.GROUP_IS_ALREADY_A_MEMBER_OF_GROUP,
+				Message: "Group is already a member of group",
+			},
+		},
+		"ErrorCaseGroupHierarchyCycleErr": {
+			org:                "org1",
+			groupName:          "group1",
+			subgroupName:       "group2",
+			expectedStatusCode: http.StatusConflict,
+			expectedError: api.Error{
+				Code:    api.GROUP_HIERARCHY_CYCLE,
+				Message: "Cycle in group hierarchy",
+			},
+			addSubgroupErr: &api.Error{
+				Code:    api.GROUP_HIERARCHY_CYCLE,
+				Message: "Cycle in group hierarchy",
+			},
+		},
+		"ErrorCaseUnknownApiError": {
+			org:                "org1",
+			groupName:          "group1",
+			subgroupName:       "group2",
+			expectedStatusCode: http.StatusInternalServerError,
+			addSubgroupErr: &api.Error{
+				Code:    api.UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[AddSubgroupMethod][0] = test.addSubgroupErr
+
+		url := fmt.Sprintf(server.URL+API_VERSION_1+"/organizations/%v/groups/%v/groups/%v", test.org, test.groupName, test.subgroupName)
+		req, err := http.NewRequest(http.MethodPost, url, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		q := req.URL.Query()
+		q.Add("Offset", test.offset)
+		req.URL.RawQuery = q.Encode()
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		if !test.ignoreArgsIn {
+			// Check received parameters
+			assert.Equal(t, test.org, testApi.ArgsIn[AddSubgroupMethod][1], "Error in test case %v", n)
+			assert.Equal(t, test.groupName, testApi.ArgsIn[AddSubgroupMethod][2], "Error in test case %v", n)
+			assert.Equal(t, test.subgroupName, testApi.ArgsIn[AddSubgroupMethod][3], "Error in test case %v", n)
+		}
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusNoContent:
+			// No message expected
+			continue
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestWorkerHandler_HandleRemoveSubgroup(t *testing.T) {
+	testcases := map[string]struct {
+		// API method args
+		org          string
+		groupName    string
+		subgroupName string
+		offset       string
+		ignoreArgsIn bool
+		// Expected result
+		expectedStatusCode int
+		expectedError      api.Error
+		// Manager Errors
+		removeSubgroupErr error
+	}{
+		"OkCase": {
+			org:                "org1",
+			groupName:          "group1",
+			subgroupName:       "group2",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		"ErrorCaseInvalidRequest": {
+			org:                "org1",
+			groupName:          "group1",
+			subgroupName:       "group2",
+			offset:             "-1",
+			ignoreArgsIn:       true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: Offset -1",
+			},
+		},
+		"ErrorCaseGroupNotFoundErr": {
+			org:                "org1",
+			groupName:          "group1",
+			subgroupName:       "group2",
+			expectedStatusCode: http.StatusNotFound,
+			expectedError: api.Error{
+				Code:    api.GROUP_BY_ORG_AND_NAME_NOT_FOUND,
+				Message: "Group Not Found",
+			},
+			removeSubgroupErr: &api.Error{
+				Code:    api.GROUP_BY_ORG_AND_NAME_NOT_FOUND,
+				Message: "Group Not Found",
+			},
+		},
+		"ErrorCaseUnauthorizedError": {
+			org:                "org1",
+			groupName:          "group1",
+			subgroupName:       "group2",
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+			removeSubgroupErr: &api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+		},
+		"ErrorCaseGroupIsNotMemberErr": {
+			org:                "org1",
+			groupName:          "group1",
+			subgroupName:       "group2",
+			expectedStatusCode: http.StatusNotFound,
+			expectedError: api.Error{
+				Code:    api.GROUP_IS_NOT_A_MEMBER_OF_GROUP,
+				Message: "Group is not a member of group",
+			},
+			removeSubgroupErr: &api.Error{
+				Code:    api.GROUP_IS_NOT_A_MEMBER_OF_GROUP,
+				Message: "Group is not a member of group",
+			},
+		},
+		"ErrorCaseUnknownApiError": {
+			org:                "org1",
+			groupName:          "group1",
+			subgroupName:       "group2",
+			expectedStatusCode: http.StatusInternalServerError,
+			removeSubgroupErr: &api.Error{
+				Code:    api.UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[RemoveSubgroupMethod][0] = test.removeSubgroupErr
+
+		url := fmt.Sprintf(server.URL+API_VERSION_1+"/organizations/%v/groups/%v/groups/%v", test.org, test.groupName, test.subgroupName)
+		req, err := http.NewRequest(http.MethodDelete, url, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		q := req.URL.Query()
+		q.Add("Offset", test.offset)
+		req.URL.RawQuery = q.Encode()
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		if !test.ignoreArgsIn {
+			// Check received parameters
+			assert.Equal(t, test.org, testApi.ArgsIn[RemoveSubgroupMethod][1], "Error in test case %v", n)
+			assert.Equal(t, test.groupName, testApi.ArgsIn[RemoveSubgroupMethod][2], "Error in test case %v", n)
+			assert.Equal(t, test.subgroupName, testApi.ArgsIn[RemoveSubgroupMethod][3], "Error in test case %v", n)
+		}
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusNoContent:
+			// No message expected
+			continue
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestWorkerHandler_HandleListSubgroups(t *testing.T) {
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// API method args
+		filter       *api.Filter
+		ignoreArgsIn bool
+		// Expected result
+		expectedStatusCode int
+		expectedResponse   ListSubgroupsResponse
+		expectedError      api.Error
+		// Manager Results
+		listSubgroupsResult []api.GroupSubgroups
+		totalSubgroups      int
+		// Manager Errors
+		listSubgroupsErr error
+	}{
+		"OkCase": {
+			filter: &api.Filter{
+				Org:       "org1",
+				GroupName: "group1",
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: ListSubgroupsResponse{
+				Subgroups: []api.GroupSubgroups{
+					{
+						Subgroup: "group2",
+						CreateAt: now,
+					},
+					{
+						Subgroup:  "group3",
+						CreateAt:  now,
+						Inherited: true,
+					},
+				},
+				Total: 2,
+			},
+			listSubgroupsResult: []api.GroupSubgroups{
+				{
+					Subgroup: "group2",
+					CreateAt: now,
+				},
+				{
+					Subgroup:  "group3",
+					CreateAt:  now,
+					Inherited: true,
+				},
+			},
+			totalSubgroups: 2,
+		},
+		"ErrorCaseInvalidFilterParams": {
+			filter: &api.Filter{
+				Org:       "org1",
+				GroupName: "group1",
+				Offset:    -1,
+			},
+			ignoreArgsIn:       true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: Offset -1",
+			},
+		},
+		"ErrorCaseGroupNotFoundErr": {
+			filter: &api.Filter{
+				Org:       "org1",
+				GroupName: "group1",
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedError: api.Error{
+				Code:    api.GROUP_BY_ORG_AND_NAME_NOT_FOUND,
+				Message: "Group Not Found",
+			},
+			listSubgroupsErr: &api.Error{
+				Code:    api.GROUP_BY_ORG_AND_NAME_NOT_FOUND,
+				Message: "Group Not Found",
+			},
+		},
+		"ErrorCaseUnauthorizedError": {
+			filter: &api.Filter{
+				Org:       "org1",
+				GroupName: "group1",
+			},
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+			listSubgroupsErr: &api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+		},
+		"ErrorCaseUnknownApiError": {
+			filter: &api.Filter{
+				Org:       "org1",
+				GroupName: "group1",
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			listSubgroupsErr: &api.Error{
+				Code:    api.UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[ListSubgroupsMethod][0] = test.listSubgroupsResult
+		testApi.ArgsOut[ListSubgroupsMethod][1] = test.totalSubgroups
+		testApi.ArgsOut[ListSubgroupsMethod][2] = test.listSubgroupsErr
+
+		url := fmt.Sprintf(server.URL+API_VERSION_1+"/organizations/%v/groups/%v/groups", test.filter.Org, test.filter.GroupName)
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		addQueryParams(test.filter, req)
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		if !test.ignoreArgsIn {
+			// Check received parameter
+			filterData, ok := testApi.ArgsIn[ListSubgroupsMethod][1].(*api.Filter)
+			if ok {
+				// Check result
+				assert.Equal(t, test.filter, filterData, "Error in test case %v", n)
+			}
+		}
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			listSubgroupsResponse := ListSubgroupsResponse{}
+			err = json.NewDecoder(res.Body).Decode(&listSubgroupsResponse)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedResponse, listSubgroupsResponse, "Error in test case %v", n)
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
@@ -1,14 +1,19 @@
 package http
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"net"
 	"net/http"
+	"time"
 
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/Tecsisa/foulkon/api"
 	"github.com/Tecsisa/foulkon/foulkon"
+	"github.com/Tecsisa/foulkon/middleware"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -16,11 +21,18 @@ const (
 	// Constants for values in url
 	USER_ID             = "userid"
 	GROUP_NAME          = "groupname"
+	SUBGROUP_NAME       = "subgroupname"
 	POLICY_NAME         = "policyname"
+	POLICY_VERSION      = "policyversion"
+	TARGET_VERSION      = "targetversion"
 	PROXY_RESOURCE_NAME = "proxyresourcename"
 	AUTH_PROVIDER_NAME  = "authprovidername"
 	ORG_NAME            = "orgname"
 
+	// Headers for optimistic concurrency control
+	ETAG_HEADER     = "ETag"
+	IF_MATCH_HEADER = "If-Match"
+
 	// URI Path param prefix
 	URI_PATH_PREFIX = "/:"
 
@@ -32,9 +44,12 @@ const (
 	ORG_ROOT = "/organizations/:" + ORG_NAME
 
 	// User API urls
-	USER_ROOT_URL      = API_VERSION_1 + "/users"
-	USER_ID_URL        = USER_ROOT_URL + URI_PATH_PREFIX + USER_ID
-	USER_ID_GROUPS_URL = USER_ID_URL + "/groups"
+	USER_ROOT_URL           = API_VERSION_1 + "/users"
+	USER_ID_URL             = USER_ROOT_URL + URI_PATH_PREFIX + USER_ID
+	USER_ID_GROUPS_URL      = USER_ID_URL + "/groups"
+	USER_ID_PERMS_URL       = USER_ID_URL + "/effective-permissions"
+	USER_ID_POLICIES_URL    = USER_ID_URL + "/policies"
+	USER_ID_POLICIES_ID_URL = USER_ID_POLICIES_URL + ORG_ROOT + URI_PATH_PREFIX + POLICY_NAME
 
 	// Group organization API urls
 	GROUP_ORG_ROOT_URL       = API_VERSION_1 + ORG_ROOT + "/groups"
@@ -43,18 +58,35 @@ const (
 	GROUP_ID_USERS_ID_URL    = GROUP_ID_USERS_URL + URI_PATH_PREFIX + USER_ID
 	GROUP_ID_POLICIES_URL    = GROUP_ID_URL + "/policies"
 	GROUP_ID_POLICIES_ID_URL = GROUP_ID_POLICIES_URL + URI_PATH_PREFIX + POLICY_NAME
+	GROUP_ID_GROUPS_URL      = GROUP_ID_URL + "/groups"
+	GROUP_ID_GROUPS_ID_URL   = GROUP_ID_GROUPS_URL + URI_PATH_PREFIX + SUBGROUP_NAME
 
 	// Policy API urls
 	POLICY_ROOT_URL      = API_VERSION_1 + ORG_ROOT + "/policies"
 	POLICY_ID_URL        = POLICY_ROOT_URL + URI_PATH_PREFIX + POLICY_NAME
 	POLICY_ID_GROUPS_URL = POLICY_ROOT_URL + URI_PATH_PREFIX + POLICY_NAME + "/groups"
 
+	// Policy version API urls
+	POLICY_ID_VERSIONS_URL          = POLICY_ID_URL + "/versions"
+	POLICY_ID_VERSIONS_ID_URL       = POLICY_ID_VERSIONS_URL + URI_PATH_PREFIX + POLICY_VERSION
+	POLICY_ID_VERSIONS_DIFF_URL     = POLICY_ID_VERSIONS_ID_URL + "/diff" + URI_PATH_PREFIX + TARGET_VERSION
+	POLICY_ID_VERSIONS_ROLLBACK_URL = POLICY_ID_VERSIONS_ID_URL + "/rollback"
+
 	// Proxy resource API urls
 	PROXY_RESOURCE_ROOT_URL = API_VERSION_1 + ORG_ROOT + "/proxy-resources"
 	PROXY_RESOURCE_ID_URL   = PROXY_RESOURCE_ROOT_URL + URI_PATH_PREFIX + PROXY_RESOURCE_NAME
 
 	// Authorization URLs
-	RESOURCE_URL = API_VERSION_1 + "/resource"
+	RESOURCE_URL            = API_VERSION_1 + "/resource"
+	RESOURCE_BATCH_URL      = RESOURCE_URL + "/batch"
+	RESOURCE_SIMULATE_URL   = RESOURCE_URL + "/simulate"
+	RESOURCE_PRINCIPALS_URL = RESOURCE_URL + "/principals"
+
+	// Audit URL
+	AUDIT_ROOT_URL = API_VERSION_1 + "/audit"
+
+	// Batch URL
+	BATCH_URL = API_VERSION_1 + "/batch"
 
 	// Admin URLs
 	ADMIN_ROOT = "/admin"
@@ -63,6 +95,18 @@ const (
 	OIDC_AUTH_ROOT_URL = API_VERSION_1 + ADMIN_ROOT + "/auth/oidc/providers"
 	OIDC_AUTH_ID_URL   = OIDC_AUTH_ROOT_URL + URI_PATH_PREFIX + AUTH_PROVIDER_NAME
 
+	// Admin authorization cache URL
+	AUTHZ_CACHE_URL = API_VERSION_1 + ADMIN_ROOT + "/authz/cache"
+
+	// Admin authorization snapshot URL
+	AUTHZ_SNAPSHOT_URL = API_VERSION_1 + ADMIN_ROOT + "/authz/snapshot"
+
+	// Admin root URL of proxy, reserved for its own routes, so it isn't available to proxy resources
+	PROXY_ADMIN_ROOT = API_VERSION_1 + ADMIN_ROOT + "/proxy"
+
+	// Admin proxy authorization result cache URL, served by proxy
+	PROXY_AUTHZ_CACHE_URL = PROXY_ADMIN_ROOT + "/authz/cache"
+
 	// Foulkon configuration URL
 	ABOUT = "/about"
 )
@@ -112,8 +156,10 @@ func (wh *WorkerHandler) processHttpResponse(r *http.Request, w http.ResponseWri
 		switch apiError.Code {
 		case api.USER_ALREADY_EXIST, api.GROUP_ALREADY_EXIST,
 			api.USER_IS_ALREADY_A_MEMBER_OF_GROUP,
+			api.GROUP_IS_ALREADY_A_MEMBER_OF_GROUP, api.GROUP_HIERARCHY_CYCLE,
 			api.PROXY_RESOURCE_ALREADY_EXIST,
 			api.POLICY_IS_ALREADY_ATTACHED_TO_GROUP, api.POLICY_ALREADY_EXIST,
+			api.POLICY_IS_ALREADY_ATTACHED_TO_USER,
 			api.PROXY_RESOURCES_ROUTES_CONFLICT,
 			api.AUTH_OIDC_PROVIDER_ALREADY_EXIST:
 			// A conflict occurs
@@ -123,13 +169,18 @@ func (wh *WorkerHandler) processHttpResponse(r *http.Request, w http.ResponseWri
 			statusCode = http.StatusForbidden
 		case api.USER_BY_EXTERNAL_ID_NOT_FOUND, api.GROUP_BY_ORG_AND_NAME_NOT_FOUND,
 			api.USER_IS_NOT_A_MEMBER_OF_GROUP, api.POLICY_IS_NOT_ATTACHED_TO_GROUP,
+			api.GROUP_IS_NOT_A_MEMBER_OF_GROUP,
+			api.POLICY_IS_NOT_ATTACHED_TO_USER,
 			api.POLICY_BY_ORG_AND_NAME_NOT_FOUND, api.PROXY_RESOURCE_BY_ORG_AND_NAME_NOT_FOUND,
-			api.AUTH_OIDC_PROVIDER_BY_NAME_NOT_FOUND:
+			api.AUTH_OIDC_PROVIDER_BY_NAME_NOT_FOUND, api.POLICY_VERSION_NOT_FOUND:
 			// Resource or relation not found
 			statusCode = http.StatusNotFound
 		case api.INVALID_PARAMETER_ERROR, api.REGEX_NO_MATCH:
 			// Unexpected input in validation parameters
 			statusCode = http.StatusBadRequest
+		case api.PRECONDITION_FAILED_ERROR:
+			// Resource changed since client retrieved it
+			statusCode = http.StatusPreconditionFailed
 		default: // Unexpected API error
 			statusCode = http.StatusInternalServerError
 		}
@@ -148,6 +199,11 @@ func (wh *WorkerHandler) getRequestInfo(r *http.Request) api.RequestInfo {
 		Identifier: mc.UserId,
 		Admin:      mc.Admin,
 		RequestID:  mc.XRequestId,
+		Context: api.RequestContext{
+			SourceIP:    wh.getForwardedSourceIP(r, mc.Admin),
+			RequestTime: time.Now().UTC(),
+		},
+		IfMatch: getIfMatch(r),
 	}
 }
 
@@ -164,10 +220,17 @@ func WorkerHandlerRouter(worker *foulkon.Worker) http.Handler {
 
 	router.GET(USER_ID_URL, workerHandler.HandleGetUserByExternalID)
 	router.PUT(USER_ID_URL, workerHandler.HandleUpdateUser)
+	router.PATCH(USER_ID_URL, workerHandler.HandlePatchUser)
 	router.DELETE(USER_ID_URL, workerHandler.HandleRemoveUser)
 
 	router.GET(USER_ID_GROUPS_URL, workerHandler.HandleListGroupsByUser)
 
+	router.GET(USER_ID_PERMS_URL, workerHandler.HandleListEffectivePermissionsByUser)
+
+	router.GET(USER_ID_POLICIES_URL, workerHandler.HandleListAttachedUserPolicies)
+	router.POST(USER_ID_POLICIES_ID_URL, workerHandler.HandleAttachPolicyToUser)
+	router.DELETE(USER_ID_POLICIES_ID_URL, workerHandler.HandleDetachPolicyToUser)
+
 	// Group api
 	router.POST(GROUP_ORG_ROOT_URL, workerHandler.HandleAddGroup)
 	router.GET(GROUP_ORG_ROOT_URL, workerHandler.HandleListGroups)
@@ -175,6 +238,7 @@ func WorkerHandlerRouter(worker *foulkon.Worker) http.Handler {
 	router.DELETE(GROUP_ID_URL, workerHandler.HandleRemoveGroup)
 	router.GET(GROUP_ID_URL, workerHandler.HandleGetGroupByName)
 	router.PUT(GROUP_ID_URL, workerHandler.HandleUpdateGroup)
+	router.PATCH(GROUP_ID_URL, workerHandler.HandlePatchGroup)
 
 	router.GET(GROUP_ID_USERS_URL, workerHandler.HandleListMembers)
 
@@ -186,6 +250,11 @@ func WorkerHandlerRouter(worker *foulkon.Worker) http.Handler {
 	router.POST(GROUP_ID_POLICIES_ID_URL, workerHandler.HandleAttachPolicyToGroup)
 	router.DELETE(GROUP_ID_POLICIES_ID_URL, workerHandler.HandleDetachPolicyToGroup)
 
+	router.GET(GROUP_ID_GROUPS_URL, workerHandler.HandleListSubgroups)
+
+	router.POST(GROUP_ID_GROUPS_ID_URL, workerHandler.HandleAddSubgroup)
+	router.DELETE(GROUP_ID_GROUPS_ID_URL, workerHandler.HandleRemoveSubgroup)
+
 	// Special endpoint without organization URI for groups
 	router.GET(API_VERSION_1+"/groups", workerHandler.HandleListAllGroups)
 
@@ -197,9 +266,15 @@ func WorkerHandlerRouter(worker *foulkon.Worker) http.Handler {
 
 	router.GET(POLICY_ID_URL, workerHandler.HandleGetPolicyByName)
 	router.PUT(POLICY_ID_URL, workerHandler.HandleUpdatePolicy)
+	router.PATCH(POLICY_ID_URL, workerHandler.HandlePatchPolicy)
 
 	router.GET(POLICY_ID_GROUPS_URL, workerHandler.HandleListAttachedGroups)
 
+	router.GET(POLICY_ID_VERSIONS_URL, workerHandler.HandleListPolicyVersions)
+	router.GET(POLICY_ID_VERSIONS_ID_URL, workerHandler.HandleGetPolicyVersion)
+	router.GET(POLICY_ID_VERSIONS_DIFF_URL, workerHandler.HandleDiffPolicyVersions)
+	router.POST(POLICY_ID_VERSIONS_ROLLBACK_URL, workerHandler.HandleRollbackPolicy)
+
 	// Special endpoint without organization URI for policies
 	router.GET(API_VERSION_1+"/policies", workerHandler.HandleListAllPolicies)
 
@@ -211,9 +286,19 @@ func WorkerHandlerRouter(worker *foulkon.Worker) http.Handler {
 
 	router.GET(PROXY_RESOURCE_ID_URL, workerHandler.HandleGetProxyResourceByName)
 	router.PUT(PROXY_RESOURCE_ID_URL, workerHandler.HandleUpdateProxyResource)
+	router.PATCH(PROXY_RESOURCE_ID_URL, workerHandler.HandlePatchProxyResource)
 
 	// Resources authorized endpoint
 	router.POST(RESOURCE_URL, workerHandler.HandleGetAuthorizedExternalResources)
+	router.POST(RESOURCE_BATCH_URL, workerHandler.HandleGetAuthorizedExternalResourcesBatch)
+	router.POST(RESOURCE_SIMULATE_URL, workerHandler.HandleSimulateAuthorizedExternalResources)
+	router.GET(RESOURCE_PRINCIPALS_URL, workerHandler.HandleGetAuthorizedPrincipals)
+
+	// Audit events API
+	router.GET(AUDIT_ROOT_URL, workerHandler.HandleListAuditEvents)
+
+	// Batch operations API
+	router.POST(BATCH_URL, workerHandler.HandleExecuteBatch)
 
 	// OIDC authentication api
 	router.GET(OIDC_AUTH_ROOT_URL, workerHandler.HandleListOidcProviders)
@@ -224,6 +309,13 @@ func WorkerHandlerRouter(worker *foulkon.Worker) http.Handler {
 	router.GET(OIDC_AUTH_ID_URL, workerHandler.HandleGetOidcProviderByName)
 	router.PUT(OIDC_AUTH_ID_URL, workerHandler.HandleUpdateOidcProvider)
 
+	// Authorization cache api
+	router.GET(AUTHZ_CACHE_URL, workerHandler.HandleGetAuthzCacheStats)
+	router.DELETE(AUTHZ_CACHE_URL, workerHandler.HandleFlushAuthzCache)
+
+	// Authorization snapshot api
+	router.GET(AUTHZ_SNAPSHOT_URL, workerHandler.HandleGetAuthzSnapshot)
+
 	// Current Foulkon configuration
 	router.GET(ABOUT, workerHandler.HandleGetCurrentConfig)
 
@@ -256,6 +348,34 @@ func WriteHttpResponse(r *http.Request, w http.ResponseWriter, requestId string,
 
 // Private Helper Methods
 
+// getForwardedSourceIP returns the client IP forwarded by a proxy in SOURCE_IP_HEADER. Header is only trusted in
+// requests of admin or with the proxy secret of worker, the IP of the connection is returned otherwise
+func (wh *WorkerHandler) getForwardedSourceIP(r *http.Request, admin bool) string {
+	forwardedIP := strings.TrimSpace(r.Header.Get(middleware.SOURCE_IP_HEADER))
+	if forwardedIP == "" || net.ParseIP(forwardedIP) == nil {
+		return middleware.GetSourceIP(r)
+	}
+	secret := wh.worker.ProxySecret
+	if admin || (secret != "" &&
+		subtle.ConstantTimeCompare([]byte(r.Header.Get(middleware.PROXY_SECRET_HEADER)), []byte(secret)) == 1) {
+		return forwardedIP
+	}
+	return middleware.GetSourceIP(r)
+}
+
+// getIfMatch returns the entity tags of If-Match header
+func getIfMatch(r *http.Request) []string {
+	var tags []string
+	for _, header := range r.Header[IF_MATCH_HEADER] {
+		for _, tag := range strings.Split(header, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
 func getFilterData(r *http.Request, ps httprouter.Params) (*api.Filter, error) {
 	var err error
 	// Retrieve Offset
@@ -283,6 +403,48 @@ func getFilterData(r *http.Request, ps httprouter.Params) (*api.Filter, error) {
 		}
 	}
 
+	// Retrieve time range
+	var from, to time.Time
+	if f := r.URL.Query().Get("From"); len(f) != 0 {
+		from, err = time.Parse(time.RFC3339, f)
+		if err != nil {
+			return nil, &api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: fmt.Sprintf("Invalid parameter: From %v", f),
+			}
+		}
+	}
+	if t := r.URL.Query().Get("To"); len(t) != 0 {
+		to, err = time.Parse(time.RFC3339, t)
+		if err != nil {
+			return nil, &api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: fmt.Sprintf("Invalid parameter: To %v", t),
+			}
+		}
+	}
+
+	// Retrieve policy versions
+	var policyVersion, targetPolicyVersion int
+	if v := ps.ByName(POLICY_VERSION); len(v) != 0 {
+		policyVersion, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, &api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: fmt.Sprintf("Invalid parameter: version %v", v),
+			}
+		}
+	}
+	if v := ps.ByName(TARGET_VERSION); len(v) != 0 {
+		targetPolicyVersion, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, &api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: fmt.Sprintf("Invalid parameter: target version %v", v),
+			}
+		}
+	}
+
 	// Retrieve Org
 	var org string
 	if org = ps.ByName(ORG_NAME); len(org) == 0 {
@@ -290,15 +452,22 @@ func getFilterData(r *http.Request, ps httprouter.Params) (*api.Filter, error) {
 	}
 
 	return &api.Filter{
-		PathPrefix:        r.URL.Query().Get("PathPrefix"),
-		Org:               org,
-		ExternalID:        ps.ByName(USER_ID),
-		PolicyName:        ps.ByName(POLICY_NAME),
-		GroupName:         ps.ByName(GROUP_NAME),
-		ProxyResourceName: ps.ByName(PROXY_RESOURCE_NAME),
-		AuthProviderName:  ps.ByName(AUTH_PROVIDER_NAME),
-		Offset:            offset,
-		Limit:             limit,
-		OrderBy:           r.URL.Query().Get("OrderBy"),
+		PathPrefix:          r.URL.Query().Get("PathPrefix"),
+		Org:                 org,
+		ExternalID:          ps.ByName(USER_ID),
+		PolicyName:          ps.ByName(POLICY_NAME),
+		GroupName:           ps.ByName(GROUP_NAME),
+		ProxyResourceName:   ps.ByName(PROXY_RESOURCE_NAME),
+		AuthProviderName:    ps.ByName(AUTH_PROVIDER_NAME),
+		ActionPrefix:        r.URL.Query().Get("ActionPrefix"),
+		Actor:               r.URL.Query().Get("Actor"),
+		UrnPrefix:           r.URL.Query().Get("UrnPrefix"),
+		From:                from,
+		To:                  to,
+		PolicyVersion:       policyVersion,
+		TargetPolicyVersion: targetPolicyVersion,
+		Offset:              offset,
+		Limit:               limit,
+		OrderBy:             r.URL.Query().Get("OrderBy"),
 	}, nil
 }
@@ -18,18 +18,21 @@ import (
 	"github.com/Tecsisa/foulkon/middleware/auth"
 	"github.com/Tecsisa/foulkon/middleware/logger"
 	"github.com/Tecsisa/foulkon/middleware/xrequestid"
-	"github.com/julienschmidt/httprouter"
 	logrusTest "github.com/sirupsen/logrus/hooks/test"
 )
 
 const (
 	// USER API METHODS
-	AddUserMethod             = "AddUser"
-	GetUserByExternalIdMethod = "GetUserByExternalId"
-	ListUsersMethod           = "ListUsers"
-	UpdateUserMethod          = "UpdateUser"
-	RemoveUserMethod          = "RemoveUser"
-	ListGroupsByUserMethod    = "ListGroupsByUser"
+	AddUserMethod                  = "AddUser"
+	GetUserByExternalIdMethod      = "GetUserByExternalId"
+	ListUsersMethod                = "ListUsers"
+	UpdateUserMethod               = "UpdateUser"
+	RemoveUserMethod               = "RemoveUser"
+	ListGroupsByUserMethod         = "ListGroupsByUser"
+	ListEffectivePermsMethod       = "ListEffectivePermissionsByUser"
+	AttachPolicyToUserMethod       = "AttachPolicyToUser"
+	DetachPolicyToUserMethod       = "DetachPolicyToUser"
+	ListAttachedUserPoliciesMethod = "ListAttachedUserPolicies"
 
 	// GROUP API METHODS
 	AddGroupMethod                  = "AddGroup"
@@ -43,6 +46,9 @@ const (
 	AttachPolicyToGroupMethod       = "AttachPolicyToGroup"
 	DetachPolicyToGroupMethod       = "DetachPolicyToGroup"
 	ListAttachedGroupPoliciesMethod = "ListAttachedGroupPolicies"
+	AddSubgroupMethod               = "AddSubgroup"
+	RemoveSubgroupMethod            = "RemoveSubgroup"
+	ListSubgroupsMethod             = "ListSubgroups"
 
 	// POLICY API METHODS
 	AddPolicyMethod          = "AddPolicy"
@@ -51,13 +57,22 @@ const (
 	UpdatePolicyMethod       = "UpdatePolicy"
 	RemovePolicyMethod       = "RemovePolicy"
 	ListAttachedGroupsMethod = "ListAttachedGroups"
+	ListPolicyVersionsMethod = "ListPolicyVersions"
+	GetPolicyVersionMethod   = "GetPolicyVersion"
+	DiffPolicyVersionsMethod = "DiffPolicyVersions"
+	RollbackPolicyMethod     = "RollbackPolicy"
 
 	// AUTHZ API
-	GetAuthorizedUsersMethod             = "GetAuthorizedUsers"
-	GetAuthorizedGroupsMethod            = "GetAuthorizedGroups"
-	GetAuthorizedPoliciesMethod          = "GetAuthorizedPolicies"
-	GetAuthorizedExternalResourcesMethod = "GetAuthorizedExternalResources"
-	GetAuthorizedProxyResources          = "GetAuthorizedProxyResources"
+	GetAuthorizedUsersMethod                  = "GetAuthorizedUsers"
+	GetAuthorizedGroupsMethod                 = "GetAuthorizedGroups"
+	GetAuthorizedPoliciesMethod               = "GetAuthorizedPolicies"
+	GetAuthorizedExternalResourcesMethod      = "GetAuthorizedExternalResources"
+	GetAuthorizedExternalResourcesBatchMethod = "GetAuthorizedExternalResourcesBatch"
+	ExplainAuthorizedExternalResourcesMethod  = "ExplainAuthorizedExternalResources"
+	SimulateAuthorizedExternalResourcesMethod = "SimulateAuthorizedExternalResources"
+	GetAuthorizedPrincipalsMethod             = "GetAuthorizedPrincipals"
+	GetAuthzSnapshotMethod                    = "GetAuthzSnapshot"
+	GetAuthorizedProxyResources               = "GetAuthorizedProxyResources"
 
 	// PROXY API
 	AddProxyResourceMethod       = "AddProxyResource"
@@ -73,6 +88,12 @@ const (
 	ListOidcProvidersMethod     = "ListOidcProviders"
 	UpdateOidcProviderMethod    = "UpdateOidcProvider"
 	RemoveOidcProviderMethod    = "RemoveOidcProvider"
+
+	// AUDIT API
+	ListAuditEventsMethod = "ListAuditEvents"
+
+	// BATCH API
+	ExecuteBatchMethod = "ExecuteBatch"
 )
 
 // Test server used to test handlers
@@ -197,6 +218,10 @@ func TestMain(m *testing.M) {
 		AuthzApi:          testApi,
 		ProxyApi:          testApi,
 		AuthOidcAPI:       testApi,
+		AuditApi:          testApi,
+		BatchApi:          testApi,
+		AuthzCache:        api.NewAuthzCache(time.Minute),
+		ProxySecret:       "proxysecret",
 		Config:            config,
 	}
 
@@ -230,6 +255,10 @@ func makeTestApi() *TestAPI {
 	testApi.ArgsIn[UpdateUserMethod] = make([]interface{}, 3)
 	testApi.ArgsIn[RemoveUserMethod] = make([]interface{}, 2)
 	testApi.ArgsIn[ListGroupsByUserMethod] = make([]interface{}, 2)
+	testApi.ArgsIn[ListEffectivePermsMethod] = make([]interface{}, 2)
+	testApi.ArgsIn[AttachPolicyToUserMethod] = make([]interface{}, 4)
+	testApi.ArgsIn[DetachPolicyToUserMethod] = make([]interface{}, 4)
+	testApi.ArgsIn[ListAttachedUserPoliciesMethod] = make([]interface{}, 2)
 
 	testApi.ArgsIn[AddGroupMethod] = make([]interface{}, 4)
 	testApi.ArgsIn[GetGroupByNameMethod] = make([]interface{}, 3)
@@ -242,6 +271,9 @@ func makeTestApi() *TestAPI {
 	testApi.ArgsIn[AttachPolicyToGroupMethod] = make([]interface{}, 4)
 	testApi.ArgsIn[DetachPolicyToGroupMethod] = make([]interface{}, 4)
 	testApi.ArgsIn[ListAttachedGroupPoliciesMethod] = make([]interface{}, 2)
+	testApi.ArgsIn[AddSubgroupMethod] = make([]interface{}, 4)
+	testApi.ArgsIn[RemoveSubgroupMethod] = make([]interface{}, 4)
+	testApi.ArgsIn[ListSubgroupsMethod] = make([]interface{}, 2)
 
 	testApi.ArgsIn[AddPolicyMethod] = make([]interface{}, 5)
 	testApi.ArgsIn[GetPolicyByNameMethod] = make([]interface{}, 3)
@@ -249,11 +281,20 @@ func makeTestApi() *TestAPI {
 	testApi.ArgsIn[UpdatePolicyMethod] = make([]interface{}, 6)
 	testApi.ArgsIn[RemovePolicyMethod] = make([]interface{}, 3)
 	testApi.ArgsIn[ListAttachedGroupsMethod] = make([]interface{}, 2)
+	testApi.ArgsIn[ListPolicyVersionsMethod] = make([]interface{}, 2)
+	testApi.ArgsIn[GetPolicyVersionMethod] = make([]interface{}, 4)
+	testApi.ArgsIn[DiffPolicyVersionsMethod] = make([]interface{}, 5)
+	testApi.ArgsIn[RollbackPolicyMethod] = make([]interface{}, 4)
 
 	testApi.ArgsIn[GetAuthorizedUsersMethod] = make([]interface{}, 4)
 	testApi.ArgsIn[GetAuthorizedGroupsMethod] = make([]interface{}, 4)
 	testApi.ArgsIn[GetAuthorizedPoliciesMethod] = make([]interface{}, 4)
 	testApi.ArgsIn[GetAuthorizedExternalResourcesMethod] = make([]interface{}, 3)
+	testApi.ArgsIn[GetAuthorizedExternalResourcesBatchMethod] = make([]interface{}, 2)
+	testApi.ArgsIn[ExplainAuthorizedExternalResourcesMethod] = make([]interface{}, 3)
+	testApi.ArgsIn[SimulateAuthorizedExternalResourcesMethod] = make([]interface{}, 6)
+	testApi.ArgsIn[GetAuthorizedPrincipalsMethod] = make([]interface{}, 3)
+	testApi.ArgsIn[GetAuthzSnapshotMethod] = make([]interface{}, 1)
 	testApi.ArgsIn[GetAuthorizedProxyResources] = make([]interface{}, 4)
 
 	testApi.ArgsIn[AddProxyResourceMethod] = make([]interface{}, 5)
@@ -275,6 +316,10 @@ func makeTestApi() *TestAPI {
 	testApi.ArgsOut[UpdateUserMethod] = make([]interface{}, 2)
 	testApi.ArgsOut[RemoveUserMethod] = make([]interface{}, 1)
 	testApi.ArgsOut[ListGroupsByUserMethod] = make([]interface{}, 3)
+	testApi.ArgsOut[ListEffectivePermsMethod] = make([]interface{}, 3)
+	testApi.ArgsOut[AttachPolicyToUserMethod] = make([]interface{}, 1)
+	testApi.ArgsOut[DetachPolicyToUserMethod] = make([]interface{}, 1)
+	testApi.ArgsOut[ListAttachedUserPoliciesMethod] = make([]interface{}, 3)
 
 	testApi.ArgsOut[AddGroupMethod] = make([]interface{}, 2)
 	testApi.ArgsOut[GetGroupByNameMethod] = make([]interface{}, 2)
@@ -287,6 +332,9 @@ func makeTestApi() *TestAPI {
 	testApi.ArgsOut[AttachPolicyToGroupMethod] = make([]interface{}, 1)
 	testApi.ArgsOut[DetachPolicyToGroupMethod] = make([]interface{}, 1)
 	testApi.ArgsOut[ListAttachedGroupPoliciesMethod] = make([]interface{}, 3)
+	testApi.ArgsOut[AddSubgroupMethod] = make([]interface{}, 1)
+	testApi.ArgsOut[RemoveSubgroupMethod] = make([]interface{}, 1)
+	testApi.ArgsOut[ListSubgroupsMethod] = make([]interface{}, 3)
 
 	testApi.ArgsOut[AddPolicyMethod] = make([]interface{}, 2)
 	testApi.ArgsOut[GetPolicyByNameMethod] = make([]interface{}, 2)
@@ -294,11 +342,20 @@ func makeTestApi() *TestAPI {
 	testApi.ArgsOut[UpdatePolicyMethod] = make([]interface{}, 2)
 	testApi.ArgsOut[RemovePolicyMethod] = make([]interface{}, 1)
 	testApi.ArgsOut[ListAttachedGroupsMethod] = make([]interface{}, 3)
+	testApi.ArgsOut[ListPolicyVersionsMethod] = make([]interface{}, 3)
+	testApi.ArgsOut[GetPolicyVersionMethod] = make([]interface{}, 2)
+	testApi.ArgsOut[DiffPolicyVersionsMethod] = make([]interface{}, 2)
+	testApi.ArgsOut[RollbackPolicyMethod] = make([]interface{}, 2)
 
 	testApi.ArgsOut[GetAuthorizedUsersMethod] = make([]interface{}, 2)
 	testApi.ArgsOut[GetAuthorizedGroupsMethod] = make([]interface{}, 2)
 	testApi.ArgsOut[GetAuthorizedPoliciesMethod] = make([]interface{}, 2)
 	testApi.ArgsOut[GetAuthorizedExternalResourcesMethod] = make([]interface{}, 2)
+	testApi.ArgsOut[GetAuthorizedExternalResourcesBatchMethod] = make([]interface{}, 2)
+	testApi.ArgsOut[ExplainAuthorizedExternalResourcesMethod] = make([]interface{}, 2)
+	testApi.ArgsOut[SimulateAuthorizedExternalResourcesMethod] = make([]interface{}, 2)
+	testApi.ArgsOut[GetAuthorizedPrincipalsMethod] = make([]interface{}, 2)
+	testApi.ArgsOut[GetAuthzSnapshotMethod] = make([]interface{}, 2)
 	testApi.ArgsOut[GetAuthorizedProxyResources] = make([]interface{}, 2)
 
 	testApi.ArgsOut[AddProxyResourceMethod] = make([]interface{}, 2)
@@ -314,6 +371,12 @@ func makeTestApi() *TestAPI {
 	testApi.ArgsOut[UpdateOidcProviderMethod] = make([]interface{}, 2)
 	testApi.ArgsOut[RemoveOidcProviderMethod] = make([]interface{}, 1)
 
+	testApi.ArgsIn[ListAuditEventsMethod] = make([]interface{}, 2)
+	testApi.ArgsOut[ListAuditEventsMethod] = make([]interface{}, 3)
+
+	testApi.ArgsIn[ExecuteBatchMethod] = make([]interface{}, 2)
+	testApi.ArgsOut[ExecuteBatchMethod] = make([]interface{}, 2)
+
 	return testApi
 }
 
@@ -410,6 +473,67 @@ func (t TestAPI) ListGroupsByUser(authenticatedUser api.RequestInfo, filter *api
 	return groups, total, err
 }
 
+func (t TestAPI) ListEffectivePermissionsByUser(authenticatedUser api.RequestInfo, filter *api.Filter) ([]api.EffectivePermission, int, error) {
+	t.ArgsIn[ListEffectivePermsMethod][0] = authenticatedUser
+	t.ArgsIn[ListEffectivePermsMethod][1] = filter
+	var permissions []api.EffectivePermission
+	var total int
+	if t.ArgsOut[ListEffectivePermsMethod][1] != nil {
+		total = t.ArgsOut[ListEffectivePermsMethod][1].(int)
+	}
+	if t.ArgsOut[ListEffectivePermsMethod][0] != nil {
+		permissions = t.ArgsOut[ListEffectivePermsMethod][0].([]api.EffectivePermission)
+	}
+	var err error
+	if t.ArgsOut[ListEffectivePermsMethod][2] != nil {
+		err = t.ArgsOut[ListEffectivePermsMethod][2].(error)
+	}
+	return permissions, total, err
+}
+
+func (t TestAPI) AttachPolicyToUser(authenticatedUser api.RequestInfo, externalId string, org string, policyName string) error {
+	t.ArgsIn[AttachPolicyToUserMethod][0] = authenticatedUser
+	t.ArgsIn[AttachPolicyToUserMethod][1] = externalId
+	t.ArgsIn[AttachPolicyToUserMethod][2] = org
+	t.ArgsIn[AttachPolicyToUserMethod][3] = policyName
+	var err error
+	if t.ArgsOut[AttachPolicyToUserMethod][0] != nil {
+		err = t.ArgsOut[AttachPolicyToUserMethod][0].(error)
+	}
+	return err
+}
+
+func (t TestAPI) DetachPolicyToUser(authenticatedUser api.RequestInfo, externalId string, org string, policyName string) error {
+	t.ArgsIn[DetachPolicyToUserMethod][0] = authenticatedUser
+	t.ArgsIn[DetachPolicyToUserMethod][1] = externalId
+	t.ArgsIn[DetachPolicyToUserMethod][2] = org
+	t.ArgsIn[DetachPolicyToUserMethod][3] = policyName
+	var err error
+	if t.ArgsOut[DetachPolicyToUserMethod][0] != nil {
+		err = t.ArgsOut[DetachPolicyToUserMethod][0].(error)
+	}
+	return err
+}
+
+func (t TestAPI) ListAttachedUserPolicies(authenticatedUser api.RequestInfo, filter *api.Filter) ([]api.UserPolicies, int, error) {
+	t.ArgsIn[ListAttachedUserPoliciesMethod][0] = authenticatedUser
+	t.ArgsIn[ListAttachedUserPoliciesMethod][1] = filter
+
+	var policies []api.UserPolicies
+	var total int
+	if t.ArgsOut[ListAttachedUserPoliciesMethod][1] != nil {
+		total = t.ArgsOut[ListAttachedUserPoliciesMethod][1].(int)
+	}
+	if t.ArgsOut[ListAttachedUserPoliciesMethod][0] != nil {
+		policies = t.ArgsOut[ListAttachedUserPoliciesMethod][0].([]api.UserPolicies)
+	}
+	var err error
+	if t.ArgsOut[ListAttachedUserPoliciesMethod][2] != nil {
+		err = t.ArgsOut[ListAttachedUserPoliciesMethod][2].(error)
+	}
+	return policies, total, err
+}
+
 // GROUP API
 
 func (t TestAPI) AddGroup(authenticatedUser api.RequestInfo, org string, name string, path string) (*api.Group, error) {
@@ -576,6 +700,49 @@ func (t TestAPI) ListAttachedGroupPolicies(authenticatedUser api.RequestInfo, fi
 	return policies, total, err
 }
 
+func (t TestAPI) AddSubgroup(authenticatedUser api.RequestInfo, org string, groupName string, subgroupName string) error {
+	t.ArgsIn[AddSubgroupMethod][0] = authenticatedUser
+	t.ArgsIn[AddSubgroupMethod][1] = org
+	t.ArgsIn[AddSubgroupMethod][2] = groupName
+	t.ArgsIn[AddSubgroupMethod][3] = subgroupName
+	var err error
+	if t.ArgsOut[AddSubgroupMethod][0] != nil {
+		err = t.ArgsOut[AddSubgroupMethod][0].(error)
+	}
+	return err
+}
+
+func (t TestAPI) RemoveSubgroup(authenticatedUser api.RequestInfo, org string, groupName string, subgroupName string) error {
+	t.ArgsIn[RemoveSubgroupMethod][0] = authenticatedUser
+	t.ArgsIn[RemoveSubgroupMethod][1] = org
+	t.ArgsIn[RemoveSubgroupMethod][2] = groupName
+	t.ArgsIn[RemoveSubgroupMethod][3] = subgroupName
+	var err error
+	if t.ArgsOut[RemoveSubgroupMethod][0] != nil {
+		err = t.ArgsOut[RemoveSubgroupMethod][0].(error)
+	}
+	return err
+}
+
+func (t TestAPI) ListSubgroups(authenticatedUser api.RequestInfo, filter *api.Filter) ([]api.GroupSubgroups, int, error) {
+	t.ArgsIn[ListSubgroupsMethod][0] = authenticatedUser
+	t.ArgsIn[ListSubgroupsMethod][1] = filter
+
+	var subgroups []api.GroupSubgroups
+	var total int
+	if t.ArgsOut[ListSubgroupsMethod][1] != nil {
+		total = t.ArgsOut[ListSubgroupsMethod][1].(int)
+	}
+	if t.ArgsOut[ListSubgroupsMethod][0] != nil {
+		subgroups = t.ArgsOut[ListSubgroupsMethod][0].([]api.GroupSubgroups)
+	}
+	var err error
+	if t.ArgsOut[ListSubgroupsMethod][2] != nil {
+		err = t.ArgsOut[ListSubgroupsMethod][2].(error)
+	}
+	return subgroups, total, err
+}
+
 // POLICY API
 
 func (t TestAPI) AddPolicy(authenticatedUser api.RequestInfo, name string, path string, org string, statements []api.Statement) (*api.Policy, error) {
@@ -679,6 +846,77 @@ func (t TestAPI) ListAttachedGroups(authenticatedUser api.RequestInfo, filter *a
 	return groups, total, err
 }
 
+func (t TestAPI) ListPolicyVersions(authenticatedUser api.RequestInfo, filter *api.Filter) ([]api.PolicyVersion, int, error) {
+	t.ArgsIn[ListPolicyVersionsMethod][0] = authenticatedUser
+	t.ArgsIn[ListPolicyVersionsMethod][1] = filter
+
+	var versions []api.PolicyVersion
+	if t.ArgsOut[ListPolicyVersionsMethod][0] != nil {
+		versions = t.ArgsOut[ListPolicyVersionsMethod][0].([]api.PolicyVersion)
+	}
+	var total int
+	if t.ArgsOut[ListPolicyVersionsMethod][1] != nil {
+		total = t.ArgsOut[ListPolicyVersionsMethod][1].(int)
+	}
+	var err error
+	if t.ArgsOut[ListPolicyVersionsMethod][2] != nil {
+		err = t.ArgsOut[ListPolicyVersionsMethod][2].(error)
+	}
+	return versions, total, err
+}
+
+func (t TestAPI) GetPolicyVersion(authenticatedUser api.RequestInfo, org string, name string, version int) (*api.PolicyVersion, error) {
+	t.ArgsIn[GetPolicyVersionMethod][0] = authenticatedUser
+	t.ArgsIn[GetPolicyVersionMethod][1] = org
+	t.ArgsIn[GetPolicyVersionMethod][2] = name
+	t.ArgsIn[GetPolicyVersionMethod][3] = version
+
+	var policyVersion *api.PolicyVersion
+	if t.ArgsOut[GetPolicyVersionMethod][0] != nil {
+		policyVersion = t.ArgsOut[GetPolicyVersionMethod][0].(*api.PolicyVersion)
+	}
+	var err error
+	if t.ArgsOut[GetPolicyVersionMethod][1] != nil {
+		err = t.ArgsOut[GetPolicyVersionMethod][1].(error)
+	}
+	return policyVersion, err
+}
+
+func (t TestAPI) DiffPolicyVersions(authenticatedUser api.RequestInfo, org string, name string, version int, targetVersion int) (*api.PolicyVersionDiff, error) {
+	t.ArgsIn[DiffPolicyVersionsMethod][0] = authenticatedUser
+	t.ArgsIn[DiffPolicyVersionsMethod][1] = org
+	t.ArgsIn[DiffPolicyVersionsMethod][2] = name
+	t.ArgsIn[DiffPolicyVersionsMethod][3] = version
+	t.ArgsIn[DiffPolicyVersionsMethod][4] = targetVersion
+
+	var diff *api.PolicyVersionDiff
+	if t.ArgsOut[DiffPolicyVersionsMethod][0] != nil {
+		diff = t.ArgsOut[DiffPolicyVersionsMethod][0].(*api.PolicyVersionDiff)
+	}
+	var err error
+	if t.ArgsOut[DiffPolicyVersionsMethod][1] != nil {
+		err = t.ArgsOut[DiffPolicyVersionsMethod][1].(error)
+	}
+	return diff, err
+}
+
+func (t TestAPI) RollbackPolicy(authenticatedUser api.RequestInfo, org string, name string, version int) (*api.Policy, error) {
+	t.ArgsIn[RollbackPolicyMethod][0] = authenticatedUser
+	t.ArgsIn[RollbackPolicyMethod][1] = org
+	t.ArgsIn[RollbackPolicyMethod][2] = name
+	t.ArgsIn[RollbackPolicyMethod][3] = version
+
+	var policy *api.Policy
+	if t.ArgsOut[RollbackPolicyMethod][0] != nil {
+		policy = t.ArgsOut[RollbackPolicyMethod][0].(*api.Policy)
+	}
+	var err error
+	if t.ArgsOut[RollbackPolicyMethod][1] != nil {
+		err = t.ArgsOut[RollbackPolicyMethod][1].(error)
+	}
+	return policy, err
+}
+
 // AUTHZ API
 
 func (t TestAPI) GetAuthorizedUsers(authenticatedUser api.RequestInfo, resourceUrn string, action string, users []api.User) ([]api.User, error) {
@@ -708,6 +946,82 @@ func (t TestAPI) GetAuthorizedExternalResources(authenticatedUser api.RequestInf
 	return resourcesToReturn, err
 }
 
+func (t TestAPI) GetAuthorizedExternalResourcesBatch(authenticatedUser api.RequestInfo, items []api.AuthorizeItem) ([]api.AuthorizeItemResult, error) {
+	t.ArgsIn[GetAuthorizedExternalResourcesBatchMethod][0] = authenticatedUser
+	t.ArgsIn[GetAuthorizedExternalResourcesBatchMethod][1] = items
+	var results []api.AuthorizeItemResult
+	if t.ArgsOut[GetAuthorizedExternalResourcesBatchMethod][0] != nil {
+		results = t.ArgsOut[GetAuthorizedExternalResourcesBatchMethod][0].([]api.AuthorizeItemResult)
+	}
+	var err error
+	if t.ArgsOut[GetAuthorizedExternalResourcesBatchMethod][1] != nil {
+		err = t.ArgsOut[GetAuthorizedExternalResourcesBatchMethod][1].(error)
+	}
+	return results, err
+}
+
+func (t TestAPI) ExplainAuthorizedExternalResources(authenticatedUser api.RequestInfo, action string, resources []string) ([]api.ResourceExplanation, error) {
+	t.ArgsIn[ExplainAuthorizedExternalResourcesMethod][0] = authenticatedUser
+	t.ArgsIn[ExplainAuthorizedExternalResourcesMethod][1] = action
+	t.ArgsIn[ExplainAuthorizedExternalResourcesMethod][2] = resources
+	var explanations []api.ResourceExplanation
+	if t.ArgsOut[ExplainAuthorizedExternalResourcesMethod][0] != nil {
+		explanations = t.ArgsOut[ExplainAuthorizedExternalResourcesMethod][0].([]api.ResourceExplanation)
+	}
+	var err error
+	if t.ArgsOut[ExplainAuthorizedExternalResourcesMethod][1] != nil {
+		err = t.ArgsOut[ExplainAuthorizedExternalResourcesMethod][1].(error)
+	}
+	return explanations, err
+}
+
+func (t TestAPI) SimulateAuthorizedExternalResources(authenticatedUser api.RequestInfo, externalID string, statements []api.Statement,
+	replace bool, action string, resources []string) ([]api.ResourceExplanation, error) {
+	t.ArgsIn[SimulateAuthorizedExternalResourcesMethod][0] = authenticatedUser
+	t.ArgsIn[SimulateAuthorizedExternalResourcesMethod][1] = externalID
+	t.ArgsIn[SimulateAuthorizedExternalResourcesMethod][2] = statements
+	t.ArgsIn[SimulateAuthorizedExternalResourcesMethod][3] = replace
+	t.ArgsIn[SimulateAuthorizedExternalResourcesMethod][4] = action
+	t.ArgsIn[SimulateAuthorizedExternalResourcesMethod][5] = resources
+	var explanations []api.ResourceExplanation
+	if t.ArgsOut[SimulateAuthorizedExternalResourcesMethod][0] != nil {
+		explanations = t.ArgsOut[SimulateAuthorizedExternalResourcesMethod][0].([]api.ResourceExplanation)
+	}
+	var err error
+	if t.ArgsOut[SimulateAuthorizedExternalResourcesMethod][1] != nil {
+		err = t.ArgsOut[SimulateAuthorizedExternalResourcesMethod][1].(error)
+	}
+	return explanations, err
+}
+
+func (t TestAPI) GetAuthorizedPrincipals(authenticatedUser api.RequestInfo, action string, resourceUrn string) (*api.AuthorizedPrincipals, error) {
+	t.ArgsIn[GetAuthorizedPrincipalsMethod][0] = authenticatedUser
+	t.ArgsIn[GetAuthorizedPrincipalsMethod][1] = action
+	t.ArgsIn[GetAuthorizedPrincipalsMethod][2] = resourceUrn
+	var principals *api.AuthorizedPrincipals
+	if t.ArgsOut[GetAuthorizedPrincipalsMethod][0] != nil {
+		principals = t.ArgsOut[GetAuthorizedPrincipalsMethod][0].(*api.AuthorizedPrincipals)
+	}
+	var err error
+	if t.ArgsOut[GetAuthorizedPrincipalsMethod][1] != nil {
+		err = t.ArgsOut[GetAuthorizedPrincipalsMethod][1].(error)
+	}
+	return principals, err
+}
+
+func (t TestAPI) GetAuthzSnapshot(requestInfo api.RequestInfo) (*api.AuthzSnapshot, error) {
+	t.ArgsIn[GetAuthzSnapshotMethod][0] = requestInfo
+	var snapshot *api.AuthzSnapshot
+	if t.ArgsOut[GetAuthzSnapshotMethod][0] != nil {
+		snapshot = t.ArgsOut[GetAuthzSnapshotMethod][0].(*api.AuthzSnapshot)
+	}
+	var err error
+	if t.ArgsOut[GetAuthzSnapshotMethod][1] != nil {
+		err = t.ArgsOut[GetAuthzSnapshotMethod][1].(error)
+	}
+	return snapshot, err
+}
+
 func (t TestAPI) GetAuthorizedProxyResources(authenticatedUser api.RequestInfo, resourceUrn string, action string, proxyResources []api.ProxyResource) ([]api.ProxyResource, error) {
 	return nil, nil
 }
@@ -890,6 +1204,42 @@ func (t TestAPI) RemoveOidcProvider(requestInfo api.RequestInfo, name string) er
 	return err
 }
 
+// AUDIT API
+
+func (t TestAPI) ListAuditEvents(requestInfo api.RequestInfo, filter *api.Filter) ([]api.AuditEvent, int, error) {
+	t.ArgsIn[ListAuditEventsMethod][0] = requestInfo
+	t.ArgsIn[ListAuditEventsMethod][1] = filter
+	var events []api.AuditEvent
+	if t.ArgsOut[ListAuditEventsMethod][0] != nil {
+		events = t.ArgsOut[ListAuditEventsMethod][0].([]api.AuditEvent)
+	}
+	var total int
+	if t.ArgsOut[ListAuditEventsMethod][1] != nil {
+		total = t.ArgsOut[ListAuditEventsMethod][1].(int)
+	}
+	var err error
+	if t.ArgsOut[ListAuditEventsMethod][2] != nil {
+		err = t.ArgsOut[ListAuditEventsMethod][2].(error)
+	}
+	return events, total, err
+}
+
+// BATCH API
+
+func (t TestAPI) ExecuteBatch(requestInfo api.RequestInfo, operations []api.BatchOperation) ([]api.BatchOperationResult, error) {
+	t.ArgsIn[ExecuteBatchMethod][0] = requestInfo
+	t.ArgsIn[ExecuteBatchMethod][1] = operations
+	var results []api.BatchOperationResult
+	if t.ArgsOut[ExecuteBatchMethod][0] != nil {
+		results = t.ArgsOut[ExecuteBatchMethod][0].([]api.BatchOperationResult)
+	}
+	var err error
+	if t.ArgsOut[ExecuteBatchMethod][1] != nil {
+		err = t.ArgsOut[ExecuteBatchMethod][1].(error)
+	}
+	return results, err
+}
+
 // Private helper methods
 
 func addQueryParams(filter *api.Filter, r *http.Request) {
@@ -898,6 +1248,21 @@ func addQueryParams(filter *api.Filter, r *http.Request) {
 		if filter.PathPrefix != "" {
 			q.Add("PathPrefix", filter.PathPrefix)
 		}
+		if filter.ActionPrefix != "" {
+			q.Add("ActionPrefix", filter.ActionPrefix)
+		}
+		if filter.Actor != "" {
+			q.Add("Actor", filter.Actor)
+		}
+		if filter.UrnPrefix != "" {
+			q.Add("UrnPrefix", filter.UrnPrefix)
+		}
+		if !filter.From.IsZero() {
+			q.Add("From", filter.From.Format(time.RFC3339))
+		}
+		if !filter.To.IsZero() {
+			q.Add("To", filter.To.Format(time.RFC3339))
+		}
 		q.Add("Offset", fmt.Sprintf("%v", filter.Offset))
 		q.Add("Limit", fmt.Sprintf("%v", filter.Limit))
 		r.URL.RawQuery = q.Encode()
@@ -905,9 +1270,6 @@ func addQueryParams(filter *api.Filter, r *http.Request) {
 }
 
 func proxyHandlerRouter(proxy *foulkon.Proxy) http.Handler {
-	// Create the muxer to handle the actual endpoints
-	router := httprouter.New()
-
 	proxyHandler := ProxyHandler{proxy: proxy, client: http.DefaultClient}
 
 	APIResources := []api.ProxyResource{
@@ -973,9 +1335,5 @@ func proxyHandlerRouter(proxy *foulkon.Proxy) http.Handler {
 		},
 	}
 
-	for _, res := range APIResources {
-		router.Handle(res.Resource.Method, res.Resource.Path, proxyHandler.HandleRequest(res))
-	}
-
-	return router
+	return newProxyRouter(&proxyHandler, APIResources)
 }
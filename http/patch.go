@@ -0,0 +1,499 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// Content types of PATCH requests
+	MERGE_PATCH_CONTENT_TYPE = "application/merge-patch+json"
+	JSON_PATCH_CONTENT_TYPE  = "application/json-patch+json"
+
+	// JSON Patch operations
+	JSON_PATCH_ADD     = "add"
+	JSON_PATCH_REMOVE  = "remove"
+	JSON_PATCH_REPLACE = "replace"
+	JSON_PATCH_MOVE    = "move"
+	JSON_PATCH_COPY    = "copy"
+	JSON_PATCH_TEST    = "test"
+)
+
+// TYPE DEFINITIONS
+
+// Operation of a JSON Patch document (RFC 6902). Value is kept raw to tell a null value from a missing one
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// HANDLERS
+
+func (wh *WorkerHandler) HandlePatchUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, filterData, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+
+	// Call user API to retrieve user to patch
+	user, err := wh.worker.UserApi.GetUserByExternalID(requestInfo, filterData.ExternalID)
+	if err != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, err, http.StatusOK)
+		return
+	}
+
+	// Apply patch
+	current := &UpdateUserRequest{
+		Path: user.Path,
+	}
+	request := &UpdateUserRequest{}
+	if err := patchRequest(r, &requestInfo, user.Urn, user.UpdateAt, current, request); err != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, err, http.StatusBadRequest)
+		return
+	}
+
+	// Call user API to update user
+	response, err := wh.worker.UserApi.UpdateUser(requestInfo, filterData.ExternalID, request.Path)
+	if err == nil {
+		w.Header().Set(ETAG_HEADER, api.ETag(response.UpdateAt))
+	}
+	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+}
+
+func (wh *WorkerHandler) HandlePatchGroup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, filterData, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+
+	// Call group API to retrieve group to patch
+	group, err := wh.worker.GroupApi.GetGroupByName(requestInfo, filterData.Org, filterData.GroupName)
+	if err != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, err, http.StatusOK)
+		return
+	}
+
+	// Apply patch
+	current := &UpdateGroupRequest{
+		Name: group.Name,
+		Path: group.Path,
+	}
+	request := &UpdateGroupRequest{}
+	if err := patchRequest(r, &requestInfo, group.Urn, group.UpdateAt, current, request); err != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, err, http.StatusBadRequest)
+		return
+	}
+
+	// Call group API to update group
+	response, err := wh.worker.GroupApi.UpdateGroup(requestInfo, filterData.Org, filterData.GroupName, request.Name, request.Path)
+	if err == nil {
+		w.Header().Set(ETAG_HEADER, api.ETag(response.UpdateAt))
+	}
+	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+}
+
+func (wh *WorkerHandler) HandlePatchPolicy(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, filterData, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+
+	// Call policy API to retrieve policy to patch
+	policy, err := wh.worker.PolicyApi.GetPolicyByName(requestInfo, filterData.Org, filterData.PolicyName)
+	if err != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, err, http.StatusOK)
+		return
+	}
+
+	// Apply patch
+	current := &UpdatePolicyRequest{
+		Name: policy.Name,
+		Path: policy.Path,
+	}
+	if policy.Statements != nil {
+		current.Statements = *policy.Statements
+	}
+	request := &UpdatePolicyRequest{}
+	if err := patchRequest(r, &requestInfo, policy.Urn, policy.UpdateAt, current, request); err != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, err, http.StatusBadRequest)
+		return
+	}
+
+	// Call policy API to update policy
+	response, err := wh.worker.PolicyApi.UpdatePolicy(requestInfo, filterData.Org, filterData.PolicyName, request.Name, request.Path, request.Statements)
+	if err == nil {
+		w.Header().Set(ETAG_HEADER, api.ETag(response.UpdateAt))
+	}
+	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+}
+
+func (wh *WorkerHandler) HandlePatchProxyResource(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, filterData, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+
+	// Call proxy resource API to retrieve proxy resource to patch
+	proxyResource, err := wh.worker.ProxyApi.GetProxyResourceByName(requestInfo, filterData.Org, filterData.ProxyResourceName)
+	if err != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, err, http.StatusOK)
+		return
+	}
+
+	// Apply patch
+	current := &UpdateProxyResourceRequest{
+		Name:     proxyResource.Name,
+		Path:     proxyResource.Path,
+		Resource: proxyResource.Resource,
+	}
+	request := &UpdateProxyResourceRequest{}
+	if err := patchRequest(r, &requestInfo, proxyResource.Urn, proxyResource.UpdateAt, current, request); err != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, err, http.StatusBadRequest)
+		return
+	}
+
+	// Call proxy resource API to update proxy resource
+	response, err := wh.worker.ProxyApi.UpdateProxyResource(requestInfo, filterData.Org, filterData.ProxyResourceName, request.Name, request.Path, request.Resource)
+	if err == nil {
+		w.Header().Set(ETAG_HEADER, api.ETag(response.UpdateAt))
+	}
+	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+}
+
+// PRIVATE HELPER METHODS
+
+// patchRequest applies the patch in request body to the current update request of a resource, and decodes the result
+// in request. Patch is applied to the current version of the resource, so the client precondition is checked against
+// it and the update is only allowed if resource doesn't change in between
+func patchRequest(r *http.Request, requestInfo *api.RequestInfo, urn string, updateAt time.Time, current interface{}, request interface{}) error {
+	if err := api.CheckPrecondition(*requestInfo, urn, updateAt); err != nil {
+		return err
+	}
+	requestInfo.IfMatch = []string{api.ETag(updateAt)}
+
+	// Decode current request and patch as generic JSON documents
+	b, err := json.Marshal(current)
+	if err != nil {
+		return &api.Error{
+			Code:    api.UNKNOWN_API_ERROR,
+			Message: err.Error(),
+		}
+	}
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return &api.Error{
+			Code:    api.UNKNOWN_API_ERROR,
+			Message: err.Error(),
+		}
+	}
+	patch, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return &api.Error{
+			Code:    api.INVALID_PARAMETER_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	// Apply patch according to its content type
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch contentType {
+	case MERGE_PATCH_CONTENT_TYPE:
+		var mergePatch interface{}
+		if err = json.Unmarshal(patch, &mergePatch); err == nil {
+			doc = applyMergePatch(doc, mergePatch)
+		}
+	case JSON_PATCH_CONTENT_TYPE:
+		var operations []jsonPatchOperation
+		if err = json.Unmarshal(patch, &operations); err == nil {
+			doc, err = applyJSONPatch(doc, operations)
+		}
+	default:
+		err = fmt.Errorf("Invalid parameter: content type %v, expected %v or %v", r.Header.Get("Content-Type"),
+			MERGE_PATCH_CONTENT_TYPE, JSON_PATCH_CONTENT_TYPE)
+	}
+	if err != nil {
+		return &api.Error{
+			Code:    api.INVALID_PARAMETER_ERROR,
+			Message: err.Error(),
+		}
+	}
+
+	// Decode patched document
+	if b, err = json.Marshal(doc); err == nil {
+		err = json.Unmarshal(b, request)
+	}
+	if err != nil {
+		return &api.Error{
+			Code:    api.INVALID_PARAMETER_ERROR,
+			Message: fmt.Sprintf("Invalid parameter: patched document %v", err),
+		}
+	}
+
+	return nil
+}
+
+// applyMergePatch returns the target document patched as described in RFC 7396
+func applyMergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = applyMergePatch(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
+
+// applyJSONPatch returns the document patched with the operations described in RFC 6902. Operations are applied in
+// order, and none of them is applied if one fails
+func applyJSONPatch(doc interface{}, operations []jsonPatchOperation) (interface{}, error) {
+	for i, operation := range operations {
+		var err error
+		doc, err = applyJSONPatchOperation(doc, operation)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid parameter: patch operation %v, %v", i, err)
+		}
+	}
+	return doc, nil
+}
+
+func applyJSONPatchOperation(doc interface{}, operation jsonPatchOperation) (interface{}, error) {
+	path, err := parseJSONPointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case JSON_PATCH_ADD, JSON_PATCH_REPLACE, JSON_PATCH_TEST:
+		if operation.Value == nil {
+			return nil, fmt.Errorf("missing value")
+		}
+		var value interface{}
+		if err := json.Unmarshal(operation.Value, &value); err != nil {
+			return nil, err
+		}
+		if operation.Op == JSON_PATCH_TEST {
+			current, err := getJSONValue(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, fmt.Errorf("test failed in path %v", operation.Path)
+			}
+			return doc, nil
+		}
+		return setJSONValue(doc, path, value, operation.Op == JSON_PATCH_REPLACE)
+	case JSON_PATCH_REMOVE:
+		doc, _, err = removeJSONValue(doc, path)
+		return doc, err
+	case JSON_PATCH_MOVE, JSON_PATCH_COPY:
+		from, err := parseJSONPointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if operation.Op == JSON_PATCH_MOVE {
+			if len(from) < len(path) && reflect.DeepEqual(from, path[:len(from)]) {
+				return nil, fmt.Errorf("path %v can't be moved into itself", operation.From)
+			}
+			doc, value, err = removeJSONValue(doc, from)
+		} else {
+			value, err = getJSONValue(doc, from)
+			if err == nil {
+				value, err = copyJSONValue(value)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return setJSONValue(doc, path, value, false)
+	default:
+		return nil, fmt.Errorf("unknown operation %v", operation.Op)
+	}
+}
+
+// parseJSONPointer returns the reference tokens of a JSON Pointer (RFC 6901)
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path %v", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// parseJSONArrayIndex returns the array index of the token, that must be lower than size
+func parseJSONArrayIndex(token string, size int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index >= size || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %v", token)
+	}
+	return index, nil
+}
+
+func getJSONValue(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %v not found", token)
+			}
+			doc = value
+		case []interface{}:
+			index, err := parseJSONArrayIndex(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+			doc = node[index]
+		default:
+			return nil, fmt.Errorf("path %v not found", token)
+		}
+	}
+	return doc, nil
+}
+
+// setJSONValue adds the value in path, or replaces the existing one if replace is true. Values added to arrays are
+// inserted in the index of the path, and "-" appends them
+func setJSONValue(doc interface{}, path []string, value interface{}, replace bool) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token := path[0]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			if _, ok := node[token]; replace && !ok {
+				return nil, fmt.Errorf("path %v not found", token)
+			}
+			node[token] = value
+			return node, nil
+		}
+		child, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("path %v not found", token)
+		}
+		child, err := setJSONValue(child, path[1:], value, replace)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+	case []interface{}:
+		if len(path) == 1 && !replace {
+			if token == "-" {
+				return append(node, value), nil
+			}
+			index, err := parseJSONArrayIndex(token, len(node)+1)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[index+1:], node[index:])
+			node[index] = value
+			return node, nil
+		}
+		index, err := parseJSONArrayIndex(token, len(node))
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 1 {
+			node[index] = value
+			return node, nil
+		}
+		child, err := setJSONValue(node[index], path[1:], value, replace)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = child
+		return node, nil
+	default:
+		return nil, fmt.Errorf("path %v not found", token)
+	}
+}
+
+// removeJSONValue removes the value in path, returning the document and the removed value
+func removeJSONValue(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("whole document can't be removed")
+	}
+	token := path[0]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("path %v not found", token)
+		}
+		if len(path) == 1 {
+			delete(node, token)
+			return node, child, nil
+		}
+		child, removed, err := removeJSONValue(child, path[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		node[token] = child
+		return node, removed, nil
+	case []interface{}:
+		index, err := parseJSONArrayIndex(token, len(node))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(path) == 1 {
+			removed := node[index]
+			return append(node[:index], node[index+1:]...), removed, nil
+		}
+		child, removed, err := removeJSONValue(node[index], path[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		node[index] = child
+		return node, removed, nil
+	default:
+		return nil, nil, fmt.Errorf("path %v not found", token)
+	}
+}
+
+// copyJSONValue returns a deep copy of a value, so changes in the copy don't affect the original one
+func copyJSONValue(value interface{}) (interface{}, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var copied interface{}
+	err = json.Unmarshal(b, &copied)
+	return copied, err
+}
@@ -0,0 +1,471 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	testcases := map[string]struct {
+		target   string
+		patch    string
+		expected string
+	}{
+		"OkCaseReplaceField": {
+			target:   `{"name":"policy1","path":"/path/"}`,
+			patch:    `{"path":"/path2/"}`,
+			expected: `{"name":"policy1","path":"/path2/"}`,
+		},
+		"OkCaseRemoveField": {
+			target:   `{"name":"policy1","path":"/path/"}`,
+			patch:    `{"path":null}`,
+			expected: `{"name":"policy1"}`,
+		},
+		"OkCaseNestedObject": {
+			target:   `{"resource":{"host":"https://host","path":"/path"}}`,
+			patch:    `{"resource":{"path":"/path2","method":"GET"}}`,
+			expected: `{"resource":{"host":"https://host","path":"/path2","method":"GET"}}`,
+		},
+		"OkCaseReplaceArray": {
+			target:   `{"statements":[{"effect":"allow"},{"effect":"deny"}]}`,
+			patch:    `{"statements":[{"effect":"deny"}]}`,
+			expected: `{"statements":[{"effect":"deny"}]}`,
+		},
+		"OkCaseNotObjectPatch": {
+			target:   `{"name":"policy1"}`,
+			patch:    `["a"]`,
+			expected: `["a"]`,
+		},
+	}
+
+	for n, test := range testcases {
+		var target, patch, expected interface{}
+		assert.Nil(t, json.Unmarshal([]byte(test.target), &target), "Error in test case %v", n)
+		assert.Nil(t, json.Unmarshal([]byte(test.patch), &patch), "Error in test case %v", n)
+		assert.Nil(t, json.Unmarshal([]byte(test.expected), &expected), "Error in test case %v", n)
+		assert.Equal(t, expected, applyMergePatch(target, patch), "Error in test case %v", n)
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	doc := `{"name":"policy1","path":"/path/","statements":[{"effect":"allow"},{"effect":"deny"}]}`
+	testcases := map[string]struct {
+		patch string
+		// Expected result
+		expected    string
+		expectedErr string
+	}{
+		"OkCaseAddField": {
+			patch:    `[{"op":"add","path":"/org","value":"org1"}]`,
+			expected: `{"name":"policy1","path":"/path/","org":"org1","statements":[{"effect":"allow"},{"effect":"deny"}]}`,
+		},
+		"OkCaseAppendToArray": {
+			patch:    `[{"op":"add","path":"/statements/-","value":{"effect":"allow","actions":["iam:*"]}}]`,
+			expected: `{"name":"policy1","path":"/path/","statements":[{"effect":"allow"},{"effect":"deny"},{"effect":"allow","actions":["iam:*"]}]}`,
+		},
+		"OkCaseInsertInArray": {
+			patch:    `[{"op":"add","path":"/statements/1","value":{"effect":"other"}}]`,
+			expected: `{"name":"policy1","path":"/path/","statements":[{"effect":"allow"},{"effect":"other"},{"effect":"deny"}]}`,
+		},
+		"OkCaseReplace": {
+			patch:    `[{"op":"replace","path":"/statements/0/effect","value":"deny"}]`,
+			expected: `{"name":"policy1","path":"/path/","statements":[{"effect":"deny"},{"effect":"deny"}]}`,
+		},
+		"OkCaseRemove": {
+			patch:    `[{"op":"remove","path":"/statements/0"}]`,
+			expected: `{"name":"policy1","path":"/path/","statements":[{"effect":"deny"}]}`,
+		},
+		"OkCaseMove": {
+			patch:    `[{"op":"move","from":"/statements/0","path":"/statements/-"}]`,
+			expected: `{"name":"policy1","path":"/path/","statements":[{"effect":"deny"},{"effect":"allow"}]}`,
+		},
+		"OkCaseCopy": {
+			patch:    `[{"op":"copy","from":"/name","path":"/path"}]`,
+			expected: `{"name":"policy1","path":"policy1","statements":[{"effect":"allow"},{"effect":"deny"}]}`,
+		},
+		"OkCaseTestAndReplace": {
+			patch:    `[{"op":"test","path":"/path","value":"/path/"},{"op":"replace","path":"/path","value":"/path2/"}]`,
+			expected: `{"name":"policy1","path":"/path2/","statements":[{"effect":"allow"},{"effect":"deny"}]}`,
+		},
+		"OkCaseEscapedPointer": {
+			patch:    `[{"op":"add","path":"/a~1b~0c","value":1}]`,
+			expected: `{"name":"policy1","path":"/path/","a/b~c":1,"statements":[{"effect":"allow"},{"effect":"deny"}]}`,
+		},
+		"ErrorCaseTestFailed": {
+			patch:       `[{"op":"replace","path":"/name","value":"policy2"},{"op":"test","path":"/path","value":"/other/"}]`,
+			expectedErr: "Invalid parameter: patch operation 1, test failed in path /path",
+		},
+		"ErrorCaseReplaceNotFound": {
+			patch:       `[{"op":"replace","path":"/org","value":"org1"}]`,
+			expectedErr: "Invalid parameter: patch operation 0, path org not found",
+		},
+		"ErrorCaseInvalidIndex": {
+			patch:       `[{"op":"remove","path":"/statements/2"}]`,
+			expectedErr: "Invalid parameter: patch operation 0, invalid array index 2",
+		},
+		"ErrorCaseLeadingZeroIndex": {
+			patch:       `[{"op":"replace","path":"/statements/01","value":{}}]`,
+			expectedErr: "Invalid parameter: patch operation 0, invalid array index 01",
+		},
+		"ErrorCaseMissingValue": {
+			patch:       `[{"op":"add","path":"/org"}]`,
+			expectedErr: "Invalid parameter: patch operation 0, missing value",
+		},
+		"ErrorCaseInvalidPath": {
+			patch:       `[{"op":"remove","path":"name"}]`,
+			expectedErr: "Invalid parameter: patch operation 0, invalid path name",
+		},
+		"ErrorCaseMoveIntoItself": {
+			patch:       `[{"op":"move","from":"/statements","path":"/statements/0"}]`,
+			expectedErr: "Invalid parameter: patch operation 0, path /statements can't be moved into itself",
+		},
+		"ErrorCaseUnknownOperation": {
+			patch:       `[{"op":"append","path":"/statements"}]`,
+			expectedErr: "Invalid parameter: patch operation 0, unknown operation append",
+		},
+	}
+
+	for n, test := range testcases {
+		var target interface{}
+		assert.Nil(t, json.Unmarshal([]byte(doc), &target), "Error in test case %v", n)
+		var operations []jsonPatchOperation
+		assert.Nil(t, json.Unmarshal([]byte(test.patch), &operations), "Error in test case %v", n)
+
+		result, err := applyJSONPatch(target, operations)
+		if test.expectedErr != "" {
+			assert.EqualError(t, err, test.expectedErr, "Error in test case %v", n)
+			continue
+		}
+		assert.Nil(t, err, "Error in test case %v", n)
+		var expected interface{}
+		assert.Nil(t, json.Unmarshal([]byte(test.expected), &expected), "Error in test case %v", n)
+		assert.Equal(t, expected, result, "Error in test case %v", n)
+	}
+}
+
+func TestWorkerHandler_HandlePatchPolicy(t *testing.T) {
+	now := time.Now().UTC()
+	statement := api.Statement{
+		Effect:    "allow",
+		Actions:   []string{api.USER_ACTION_GET_USER},
+		Resources: []string{api.GetUrnPrefix("", api.RESOURCE_USER, "/path/")},
+	}
+	newStatement := api.Statement{
+		Effect:    "deny",
+		Actions:   []string{api.USER_ACTION_DELETE_USER},
+		Resources: []string{api.GetUrnPrefix("", api.RESOURCE_USER, "/path/")},
+	}
+	policy := &api.Policy{
+		ID:         "test1",
+		Name:       "policy1",
+		Org:        "org1",
+		Path:       "/path/",
+		CreateAt:   now,
+		UpdateAt:   now,
+		Urn:        api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy1"),
+		Statements: &[]api.Statement{statement},
+	}
+	testcases := map[string]struct {
+		// API method args
+		contentType string
+		patch       string
+		ifMatch     string
+		// Expected result
+		expectedStatusCode int
+		expectedRequest    *UpdatePolicyRequest
+		expectedResponse   api.Policy
+		expectedError      api.Error
+		// Manager Results
+		getPolicyByNameResult *api.Policy
+		updatePolicyResult    *api.Policy
+		// Manager Errors
+		getPolicyByNameErr error
+		updatePolicyErr    error
+	}{
+		"OkCaseMergePatch": {
+			contentType:           MERGE_PATCH_CONTENT_TYPE,
+			patch:                 `{"path":"/path2/"}`,
+			getPolicyByNameResult: policy,
+			updatePolicyResult: &api.Policy{
+				ID:         "test1",
+				Name:       "policy1",
+				Org:        "org1",
+				Path:       "/path2/",
+				CreateAt:   now,
+				UpdateAt:   now.Add(time.Second),
+				Urn:        api.CreateUrn("org1", api.RESOURCE_POLICY, "/path2/", "policy1"),
+				Statements: &[]api.Statement{statement},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedRequest: &UpdatePolicyRequest{
+				Name:       "policy1",
+				Path:       "/path2/",
+				Statements: []api.Statement{statement},
+			},
+			expectedResponse: api.Policy{
+				ID:         "test1",
+				Name:       "policy1",
+				Org:        "org1",
+				Path:       "/path2/",
+				CreateAt:   now,
+				UpdateAt:   now.Add(time.Second),
+				Urn:        api.CreateUrn("org1", api.RESOURCE_POLICY, "/path2/", "policy1"),
+				Statements: &[]api.Statement{statement},
+			},
+		},
+		"OkCaseJSONPatchAppendStatement": {
+			contentType:           JSON_PATCH_CONTENT_TYPE + "; charset=utf-8",
+			patch:                 `[{"op":"add","path":"/statements/-","value":{"effect":"deny","actions":["iam:DeleteUser"],"resources":["urn:iws:iam::user/path/*"]}}]`,
+			ifMatch:               api.ETag(now),
+			getPolicyByNameResult: policy,
+			updatePolicyResult: &api.Policy{
+				ID:         "test1",
+				Name:       "policy1",
+				Org:        "org1",
+				Path:       "/path/",
+				CreateAt:   now,
+				UpdateAt:   now.Add(time.Second),
+				Urn:        api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy1"),
+				Statements: &[]api.Statement{statement, newStatement},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedRequest: &UpdatePolicyRequest{
+				Name:       "policy1",
+				Path:       "/path/",
+				Statements: []api.Statement{statement, newStatement},
+			},
+			expectedResponse: api.Policy{
+				ID:         "test1",
+				Name:       "policy1",
+				Org:        "org1",
+				Path:       "/path/",
+				CreateAt:   now,
+				UpdateAt:   now.Add(time.Second),
+				Urn:        api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "policy1"),
+				Statements: &[]api.Statement{statement, newStatement},
+			},
+		},
+		"ErrorCaseInvalidContentType": {
+			contentType:           "application/json",
+			patch:                 `{"path":"/path2/"}`,
+			getPolicyByNameResult: policy,
+			expectedStatusCode:    http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: content type application/json, expected application/merge-patch+json or application/json-patch+json",
+			},
+		},
+		"ErrorCaseInvalidPatch": {
+			contentType:           JSON_PATCH_CONTENT_TYPE,
+			patch:                 `[{"op":"remove","path":"/statements/3"}]`,
+			getPolicyByNameResult: policy,
+			expectedStatusCode:    http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: patch operation 0, invalid array index 3",
+			},
+		},
+		"ErrorCasePatchedDocumentInvalid": {
+			contentType:           MERGE_PATCH_CONTENT_TYPE,
+			patch:                 `{"statements":"all"}`,
+			getPolicyByNameResult: policy,
+			expectedStatusCode:    http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: patched document json: cannot unmarshal string into Go struct field UpdatePolicyRequest.statements of type []api.Statement",
+			},
+		},
+		"ErrorCasePreconditionFailed": {
+			contentType:           MERGE_PATCH_CONTENT_TYPE,
+			patch:                 `{"path":"/path2/"}`,
+			ifMatch:               `"1"`,
+			getPolicyByNameResult: policy,
+			expectedStatusCode:    http.StatusPreconditionFailed,
+			expectedError: api.Error{
+				Code:    api.PRECONDITION_FAILED_ERROR,
+				Message: fmt.Sprintf("Resource %v has changed, current entity tag is %v", policy.Urn, api.ETag(now)),
+			},
+		},
+		"ErrorCasePolicyNotFound": {
+			contentType:        MERGE_PATCH_CONTENT_TYPE,
+			patch:              `{"path":"/path2/"}`,
+			expectedStatusCode: http.StatusNotFound,
+			getPolicyByNameErr: &api.Error{
+				Code: api.POLICY_BY_ORG_AND_NAME_NOT_FOUND,
+			},
+			expectedError: api.Error{
+				Code: api.POLICY_BY_ORG_AND_NAME_NOT_FOUND,
+			},
+		},
+		"ErrorCaseInvalidStatements": {
+			contentType:           MERGE_PATCH_CONTENT_TYPE,
+			patch:                 `{"statements":[{"effect":"maybe"}]}`,
+			getPolicyByNameResult: policy,
+			expectedStatusCode:    http.StatusBadRequest,
+			expectedRequest: &UpdatePolicyRequest{
+				Name:       "policy1",
+				Path:       "/path/",
+				Statements: []api.Statement{{Effect: "maybe"}},
+			},
+			updatePolicyErr: &api.Error{
+				Code: api.INVALID_PARAMETER_ERROR,
+			},
+			expectedError: api.Error{
+				Code: api.INVALID_PARAMETER_ERROR,
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[GetPolicyByNameMethod][0] = test.getPolicyByNameResult
+		testApi.ArgsOut[GetPolicyByNameMethod][1] = test.getPolicyByNameErr
+		testApi.ArgsOut[UpdatePolicyMethod][0] = test.updatePolicyResult
+		testApi.ArgsOut[UpdatePolicyMethod][1] = test.updatePolicyErr
+
+		url := server.URL + API_VERSION_1 + "/organizations/org1/policies/policy1"
+		req, err := http.NewRequest(http.MethodPatch, url, bytes.NewBufferString(test.patch))
+		assert.Nil(t, err, "Error in test case %v", n)
+		req.Header.Set("Content-Type", test.contentType)
+		if test.ifMatch != "" {
+			req.Header.Set(IF_MATCH_HEADER, test.ifMatch)
+		}
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		if test.expectedRequest != nil {
+			// Check received parameters
+			requestInfo := testApi.ArgsIn[UpdatePolicyMethod][0].(api.RequestInfo)
+			assert.Equal(t, []string{api.ETag(now)}, requestInfo.IfMatch, "Error in test case %v", n)
+			assert.Equal(t, "org1", testApi.ArgsIn[UpdatePolicyMethod][1], "Error in test case %v", n)
+			assert.Equal(t, "policy1", testApi.ArgsIn[UpdatePolicyMethod][2], "Error in test case %v", n)
+			assert.Equal(t, test.expectedRequest.Name, testApi.ArgsIn[UpdatePolicyMethod][3], "Error in test case %v", n)
+			assert.Equal(t, test.expectedRequest.Path, testApi.ArgsIn[UpdatePolicyMethod][4], "Error in test case %v", n)
+			assert.Equal(t, test.expectedRequest.Statements, testApi.ArgsIn[UpdatePolicyMethod][5], "Error in test case %v", n)
+		}
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			response := api.Policy{}
+			err = json.NewDecoder(res.Body).Decode(&response)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedResponse, response, "Error in test case %v", n)
+			assert.Equal(t, api.ETag(test.expectedResponse.UpdateAt), res.Header.Get(ETAG_HEADER), "Error in test case %v", n)
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestWorkerHandler_HandlePatchUser(t *testing.T) {
+	now := time.Now().UTC()
+	user := &api.User{
+		ID:         "UserID",
+		ExternalID: "user1",
+		Path:       "/path/",
+		CreateAt:   now,
+		UpdateAt:   now,
+		Urn:        api.CreateUrn("", api.RESOURCE_USER, "/path/", "user1"),
+	}
+	testcases := map[string]struct {
+		// API method args
+		contentType string
+		patch       string
+		// Expected result
+		expectedStatusCode int
+		expectedPath       string
+		expectedError      api.Error
+		// Manager Results
+		updateUserResult *api.User
+		// Manager Errors
+		updateUserErr error
+	}{
+		"OkCase": {
+			contentType: JSON_PATCH_CONTENT_TYPE,
+			patch:       `[{"op":"replace","path":"/path","value":"/path2/"}]`,
+			updateUserResult: &api.User{
+				ID:         "UserID",
+				ExternalID: "user1",
+				Path:       "/path2/",
+				CreateAt:   now,
+				UpdateAt:   now,
+				Urn:        api.CreateUrn("", api.RESOURCE_USER, "/path2/", "user1"),
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedPath:       "/path2/",
+		},
+		"ErrorCaseInvalidPath": {
+			contentType: MERGE_PATCH_CONTENT_TYPE,
+			patch:       `{"path":"path"}`,
+			updateUserErr: &api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: path path",
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedPath:       "path",
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: path path",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[GetUserByExternalIdMethod][0] = user
+		testApi.ArgsOut[GetUserByExternalIdMethod][1] = nil
+		testApi.ArgsOut[UpdateUserMethod][0] = test.updateUserResult
+		testApi.ArgsOut[UpdateUserMethod][1] = test.updateUserErr
+
+		req, err := http.NewRequest(http.MethodPatch, server.URL+USER_ROOT_URL+"/user1", bytes.NewBufferString(test.patch))
+		assert.Nil(t, err, "Error in test case %v", n)
+		req.Header.Set("Content-Type", test.contentType)
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		// Check received parameters
+		assert.Equal(t, "user1", testApi.ArgsIn[UpdateUserMethod][1], "Error in test case %v", n)
+		assert.Equal(t, test.expectedPath, testApi.ArgsIn[UpdateUserMethod][2], "Error in test case %v", n)
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			response := api.User{}
+			err = json.NewDecoder(res.Body).Decode(&response)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, *test.updateUserResult, response, "Error in test case %v", n)
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
@@ -70,6 +70,9 @@ func (wh *WorkerHandler) HandleGetPolicyByName(w http.ResponseWriter, r *http.Re
 
 	// Call policy API to retrieve policy
 	response, err := wh.worker.PolicyApi.GetPolicyByName(requestInfo, filterData.Org, filterData.PolicyName)
+	if err == nil {
+		w.Header().Set(ETAG_HEADER, api.ETag(response.UpdateAt))
+	}
 	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
 }
 
@@ -125,6 +128,9 @@ func (wh *WorkerHandler) HandleUpdatePolicy(w http.ResponseWriter, r *http.Reque
 	}
 	// Call policy API to update policy
 	response, err := wh.worker.PolicyApi.UpdatePolicy(requestInfo, filterData.Org, filterData.PolicyName, request.Name, request.Path, request.Statements)
+	if err == nil {
+		w.Header().Set(ETAG_HEADER, api.ETag(response.UpdateAt))
+	}
 	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
 }
 
@@ -407,6 +407,7 @@ func TestWorkerHandler_HandleGetPolicyByName(t *testing.T) {
 			assert.Nil(t, err, "Error in test case %v", n)
 			// Check result
 			assert.Equal(t, test.expectedResponse, response, "Error in test case %v", n)
+			assert.Equal(t, api.ETag(test.expectedResponse.UpdateAt), res.Header.Get(ETAG_HEADER), "Error in test case %v", n)
 		case http.StatusInternalServerError: // Empty message so continue
 			continue
 		default:
@@ -733,7 +734,9 @@ func TestWorkerHandler_HandleUpdatePolicy(t *testing.T) {
 		// API method args
 		org     string
 		request *UpdatePolicyRequest
+		ifMatch string
 		// Expected result
+		expectedIfMatch    []string
 		expectedStatusCode int
 		expectedResponse   api.Policy
 		expectedError      api.Error
@@ -925,6 +928,22 @@ func TestWorkerHandler_HandleUpdatePolicy(t *testing.T) {
 				Code: api.UNKNOWN_API_ERROR,
 			},
 		},
+		"ErrorCasePreconditionFailed": {
+			org: "org1",
+			request: &UpdatePolicyRequest{
+				Name: "policy1",
+				Path: "path1",
+			},
+			ifMatch:         `"1", "2"`,
+			expectedIfMatch: []string{`"1"`, `"2"`},
+			updatePolicyErr: &api.Error{
+				Code: api.PRECONDITION_FAILED_ERROR,
+			},
+			expectedStatusCode: http.StatusPreconditionFailed,
+			expectedError: api.Error{
+				Code: api.PRECONDITION_FAILED_ERROR,
+			},
+		},
 	}
 
 	client := http.DefaultClient
@@ -947,6 +966,9 @@ func TestWorkerHandler_HandleUpdatePolicy(t *testing.T) {
 		url := fmt.Sprintf(server.URL+API_VERSION_1+"/organizations/%v/policies/policy1", test.org)
 		req, err := http.NewRequest(http.MethodPut, url, body)
 		assert.Nil(t, err, "Error in test case %v", n)
+		if test.ifMatch != "" {
+			req.Header.Set(IF_MATCH_HEADER, test.ifMatch)
+		}
 
 		res, err := client.Do(req)
 		assert.Nil(t, err, "Error in test case %v", n)
@@ -958,6 +980,8 @@ func TestWorkerHandler_HandleUpdatePolicy(t *testing.T) {
 			assert.Equal(t, test.request.Name, testApi.ArgsIn[UpdatePolicyMethod][3], "Error in test case %v", n)
 			assert.Equal(t, test.request.Path, testApi.ArgsIn[UpdatePolicyMethod][4], "Error in test case %v", n)
 			assert.Equal(t, test.request.Statements, testApi.ArgsIn[UpdatePolicyMethod][5], "Error in test case %v", n)
+			requestInfo := testApi.ArgsIn[UpdatePolicyMethod][0].(api.RequestInfo)
+			assert.Equal(t, test.expectedIfMatch, requestInfo.IfMatch, "Error in test case %v", n)
 		}
 
 		// check status code
@@ -970,6 +994,7 @@ func TestWorkerHandler_HandleUpdatePolicy(t *testing.T) {
 			assert.Nil(t, err, "Error in test case %v", n)
 			// Check result
 			assert.Equal(t, test.expectedResponse, response, "Error in test case %v", n)
+			assert.Equal(t, api.ETag(test.expectedResponse.UpdateAt), res.Header.Get(ETAG_HEADER), "Error in test case %v", n)
 		case http.StatusInternalServerError: // Empty message so continue
 			continue
 		default:
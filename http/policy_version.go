@@ -0,0 +1,83 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// RESPONSES
+
+type ListPolicyVersionsResponse struct {
+	Versions []api.PolicyVersion `json:"versions,omitempty"`
+	Limit    int                 `json:"limit"`
+	Offset   int                 `json:"offset"`
+	Total    int                 `json:"total"`
+}
+
+// HANDLERS
+
+func (wh *WorkerHandler) HandleListPolicyVersions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, filterData, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+
+	// Call policy API to list policy versions
+	result, total, err := wh.worker.PolicyApi.ListPolicyVersions(requestInfo, filterData)
+
+	// Create response
+	response := &ListPolicyVersionsResponse{
+		Versions: result,
+		Offset:   filterData.Offset,
+		Limit:    filterData.Limit,
+		Total:    total,
+	}
+	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+}
+
+func (wh *WorkerHandler) HandleGetPolicyVersion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, filterData, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+
+	// Call policy API to retrieve policy version
+	response, err := wh.worker.PolicyApi.GetPolicyVersion(requestInfo, filterData.Org, filterData.PolicyName, filterData.PolicyVersion)
+	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+}
+
+func (wh *WorkerHandler) HandleDiffPolicyVersions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, filterData, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+
+	// Call policy API to compare policy versions
+	response, err := wh.worker.PolicyApi.DiffPolicyVersions(requestInfo, filterData.Org, filterData.PolicyName,
+		filterData.PolicyVersion, filterData.TargetPolicyVersion)
+	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+}
+
+func (wh *WorkerHandler) HandleRollbackPolicy(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, filterData, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+
+	// Call policy API to restore policy version
+	response, err := wh.worker.PolicyApi.RollbackPolicy(requestInfo, filterData.Org, filterData.PolicyName, filterData.PolicyVersion)
+	if err == nil {
+		w.Header().Set(ETAG_HEADER, api.ETag(response.UpdateAt))
+	}
+	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+}
@@ -0,0 +1,579 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerHandler_HandleListPolicyVersions(t *testing.T) {
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// API method args
+		filter       *api.Filter
+		ignoreArgsIn bool
+		// Expected result
+		expectedStatusCode int
+		expectedResponse   ListPolicyVersionsResponse
+		expectedError      api.Error
+		// Manager Results
+		listPolicyVersionsResult []api.PolicyVersion
+		totalPolicyVersions      int
+		// Manager Errors
+		listPolicyVersionsErr error
+	}{
+		"OkCase": {
+			filter: &api.Filter{
+				Org:        "org1",
+				PolicyName: "p1",
+				Offset:     0,
+				Limit:      0,
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: ListPolicyVersionsResponse{
+				Versions: []api.PolicyVersion{
+					{
+						Version:  2,
+						Author:   "user1",
+						CreateAt: now,
+						Statements: &[]api.Statement{
+							{
+								Effect:    "allow",
+								Actions:   []string{api.USER_ACTION_GET_USER},
+								Resources: []string{api.GetUrnPrefix("", api.RESOURCE_USER, "/path/")},
+							},
+						},
+					},
+				},
+				Offset: 0,
+				Limit:  0,
+				Total:  1,
+			},
+			listPolicyVersionsResult: []api.PolicyVersion{
+				{
+					Version:  2,
+					Author:   "user1",
+					CreateAt: now,
+					Statements: &[]api.Statement{
+						{
+							Effect:    "allow",
+							Actions:   []string{api.USER_ACTION_GET_USER},
+							Resources: []string{api.GetUrnPrefix("", api.RESOURCE_USER, "/path/")},
+						},
+					},
+				},
+			},
+			totalPolicyVersions: 1,
+		},
+		"ErrorCaseInvalidFilterParams": {
+			filter: &api.Filter{
+				Org:        "org1",
+				PolicyName: "p1",
+				Limit:      -1,
+			},
+			ignoreArgsIn:       true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: Limit -1",
+			},
+		},
+		"ErrorCasePolicyNotFound": {
+			filter: &api.Filter{
+				Org:        "org1",
+				PolicyName: "p1",
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedError: api.Error{
+				Code:    api.POLICY_BY_ORG_AND_NAME_NOT_FOUND,
+				Message: "Error",
+			},
+			listPolicyVersionsErr: &api.Error{
+				Code:    api.POLICY_BY_ORG_AND_NAME_NOT_FOUND,
+				Message: "Error",
+			},
+		},
+		"ErrorCaseUnauthorizedError": {
+			filter: &api.Filter{
+				Org:        "org1",
+				PolicyName: "p1",
+			},
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+			listPolicyVersionsErr: &api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+		},
+		"ErrorCaseUnknownApiError": {
+			filter: &api.Filter{
+				Org:        "org1",
+				PolicyName: "p1",
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			listPolicyVersionsErr: &api.Error{
+				Code:    api.UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[ListPolicyVersionsMethod][0] = test.listPolicyVersionsResult
+		testApi.ArgsOut[ListPolicyVersionsMethod][1] = test.totalPolicyVersions
+		testApi.ArgsOut[ListPolicyVersionsMethod][2] = test.listPolicyVersionsErr
+
+		url := fmt.Sprintf(server.URL+API_VERSION_1+"/organizations/%v/policies/%v/versions", test.filter.Org, test.filter.PolicyName)
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		addQueryParams(test.filter, req)
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		if !test.ignoreArgsIn {
+			// Check received parameters
+			filterData, ok := testApi.ArgsIn[ListPolicyVersionsMethod][1].(*api.Filter)
+			if ok {
+				// Check result
+				assert.Equal(t, test.filter, filterData, "Error in test case %v", n)
+			}
+		}
+
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			listPolicyVersions := ListPolicyVersionsResponse{}
+			err = json.NewDecoder(res.Body).Decode(&listPolicyVersions)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedResponse, listPolicyVersions, "Error in test case %v", n)
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestWorkerHandler_HandleGetPolicyVersion(t *testing.T) {
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// API method args
+		org          string
+		policyName   string
+		version      string
+		ignoreArgsIn bool
+		// Expected result
+		expectedVersion    int
+		expectedStatusCode int
+		expectedResponse   api.PolicyVersion
+		expectedError      api.Error
+		// Manager Results
+		getPolicyVersionResult *api.PolicyVersion
+		// Manager Errors
+		getPolicyVersionErr error
+	}{
+		"OkCase": {
+			org:                "org1",
+			policyName:         "p1",
+			version:            "1",
+			expectedVersion:    1,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: api.PolicyVersion{
+				Version:  1,
+				Author:   "user1",
+				CreateAt: now,
+				Statements: &[]api.Statement{
+					{
+						Effect:    "allow",
+						Actions:   []string{api.USER_ACTION_GET_USER},
+						Resources: []string{api.GetUrnPrefix("", api.RESOURCE_USER, "/path/")},
+					},
+				},
+			},
+			getPolicyVersionResult: &api.PolicyVersion{
+				Version:  1,
+				Author:   "user1",
+				CreateAt: now,
+				Statements: &[]api.Statement{
+					{
+						Effect:    "allow",
+						Actions:   []string{api.USER_ACTION_GET_USER},
+						Resources: []string{api.GetUrnPrefix("", api.RESOURCE_USER, "/path/")},
+					},
+				},
+			},
+		},
+		"ErrorCaseInvalidVersion": {
+			org:                "org1",
+			policyName:         "p1",
+			version:            "first",
+			ignoreArgsIn:       true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: version first",
+			},
+		},
+		"ErrorCasePolicyVersionNotFound": {
+			org:                "org1",
+			policyName:         "p1",
+			version:            "5",
+			expectedVersion:    5,
+			expectedStatusCode: http.StatusNotFound,
+			expectedError: api.Error{
+				Code: api.POLICY_VERSION_NOT_FOUND,
+			},
+			getPolicyVersionErr: &api.Error{
+				Code: api.POLICY_VERSION_NOT_FOUND,
+			},
+		},
+		"ErrorCaseUnauthorized": {
+			org:                "org1",
+			policyName:         "p1",
+			version:            "1",
+			expectedVersion:    1,
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code: api.UNAUTHORIZED_RESOURCES_ERROR,
+			},
+			getPolicyVersionErr: &api.Error{
+				Code: api.UNAUTHORIZED_RESOURCES_ERROR,
+			},
+		},
+		"ErrorCaseInternalServerError": {
+			org:                "org1",
+			policyName:         "p1",
+			version:            "1",
+			expectedVersion:    1,
+			expectedStatusCode: http.StatusInternalServerError,
+			getPolicyVersionErr: &api.Error{
+				Code: api.UNKNOWN_API_ERROR,
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[GetPolicyVersionMethod][0] = test.getPolicyVersionResult
+		testApi.ArgsOut[GetPolicyVersionMethod][1] = test.getPolicyVersionErr
+
+		url := fmt.Sprintf(server.URL+API_VERSION_1+"/organizations/%v/policies/%v/versions/%v", test.org, test.policyName, test.version)
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		if !test.ignoreArgsIn {
+			// Check received parameters
+			assert.Equal(t, test.org, testApi.ArgsIn[GetPolicyVersionMethod][1], "Error in test case %v", n)
+			assert.Equal(t, test.policyName, testApi.ArgsIn[GetPolicyVersionMethod][2], "Error in test case %v", n)
+			assert.Equal(t, test.expectedVersion, testApi.ArgsIn[GetPolicyVersionMethod][3], "Error in test case %v", n)
+		}
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			response := api.PolicyVersion{}
+			err = json.NewDecoder(res.Body).Decode(&response)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedResponse, response, "Error in test case %v", n)
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestWorkerHandler_HandleDiffPolicyVersions(t *testing.T) {
+	testcases := map[string]struct {
+		// API method args
+		version       string
+		targetVersion string
+		ignoreArgsIn  bool
+		// Expected result
+		expectedVersion       int
+		expectedTargetVersion int
+		expectedStatusCode    int
+		expectedResponse      api.PolicyVersionDiff
+		expectedError         api.Error
+		// Manager Results
+		diffPolicyVersionsResult *api.PolicyVersionDiff
+		// Manager Errors
+		diffPolicyVersionsErr error
+	}{
+		"OkCase": {
+			version:               "1",
+			targetVersion:         "2",
+			expectedVersion:       1,
+			expectedTargetVersion: 2,
+			expectedStatusCode:    http.StatusOK,
+			expectedResponse: api.PolicyVersionDiff{
+				Version:       1,
+				TargetVersion: 2,
+				Added: []api.Statement{
+					{
+						Effect:    "deny",
+						Actions:   []string{api.USER_ACTION_GET_USER},
+						Resources: []string{api.GetUrnPrefix("", api.RESOURCE_USER, "/path/")},
+					},
+				},
+				Removed: []api.Statement{},
+			},
+			diffPolicyVersionsResult: &api.PolicyVersionDiff{
+				Version:       1,
+				TargetVersion: 2,
+				Added: []api.Statement{
+					{
+						Effect:    "deny",
+						Actions:   []string{api.USER_ACTION_GET_USER},
+						Resources: []string{api.GetUrnPrefix("", api.RESOURCE_USER, "/path/")},
+					},
+				},
+				Removed: []api.Statement{},
+			},
+		},
+		"ErrorCaseInvalidTargetVersion": {
+			version:            "1",
+			targetVersion:      "last",
+			ignoreArgsIn:       true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: target version last",
+			},
+		},
+		"ErrorCasePolicyVersionNotFound": {
+			version:               "1",
+			targetVersion:         "7",
+			expectedVersion:       1,
+			expectedTargetVersion: 7,
+			expectedStatusCode:    http.StatusNotFound,
+			expectedError: api.Error{
+				Code: api.POLICY_VERSION_NOT_FOUND,
+			},
+			diffPolicyVersionsErr: &api.Error{
+				Code: api.POLICY_VERSION_NOT_FOUND,
+			},
+		},
+		"ErrorCaseInternalServerError": {
+			version:               "1",
+			targetVersion:         "2",
+			expectedVersion:       1,
+			expectedTargetVersion: 2,
+			expectedStatusCode:    http.StatusInternalServerError,
+			diffPolicyVersionsErr: &api.Error{
+				Code: api.UNKNOWN_API_ERROR,
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[DiffPolicyVersionsMethod][0] = test.diffPolicyVersionsResult
+		testApi.ArgsOut[DiffPolicyVersionsMethod][1] = test.diffPolicyVersionsErr
+
+		url := fmt.Sprintf(server.URL+API_VERSION_1+"/organizations/org1/policies/p1/versions/%v/diff/%v", test.version, test.targetVersion)
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		if !test.ignoreArgsIn {
+			// Check received parameters
+			assert.Equal(t, "org1", testApi.ArgsIn[DiffPolicyVersionsMethod][1], "Error in test case %v", n)
+			assert.Equal(t, "p1", testApi.ArgsIn[DiffPolicyVersionsMethod][2], "Error in test case %v", n)
+			assert.Equal(t, test.expectedVersion, testApi.ArgsIn[DiffPolicyVersionsMethod][3], "Error in test case %v", n)
+			assert.Equal(t, test.expectedTargetVersion, testApi.ArgsIn[DiffPolicyVersionsMethod][4], "Error in test case %v", n)
+		}
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			response := api.PolicyVersionDiff{}
+			err = json.NewDecoder(res.Body).Decode(&response)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedResponse, response, "Error in test case %v", n)
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestWorkerHandler_HandleRollbackPolicy(t *testing.T) {
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// API method args
+		version      string
+		ignoreArgsIn bool
+		// Expected result
+		expectedVersion    int
+		expectedStatusCode int
+		expectedResponse   api.Policy
+		expectedError      api.Error
+		// Manager Results
+		rollbackPolicyResult *api.Policy
+		// Manager Errors
+		rollbackPolicyErr error
+	}{
+		"OkCase": {
+			version:            "1",
+			expectedVersion:    1,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: api.Policy{
+				ID:       "test1",
+				Name:     "p1",
+				Org:      "org1",
+				Path:     "/path/",
+				CreateAt: now,
+				UpdateAt: now,
+				Urn:      api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "p1"),
+				Statements: &[]api.Statement{
+					{
+						Effect:    "allow",
+						Actions:   []string{api.USER_ACTION_GET_USER},
+						Resources: []string{api.GetUrnPrefix("", api.RESOURCE_USER, "/path/")},
+					},
+				},
+			},
+			rollbackPolicyResult: &api.Policy{
+				ID:       "test1",
+				Name:     "p1",
+				Org:      "org1",
+				Path:     "/path/",
+				CreateAt: now,
+				UpdateAt: now,
+				Urn:      api.CreateUrn("org1", api.RESOURCE_POLICY, "/path/", "p1"),
+				Statements: &[]api.Statement{
+					{
+						Effect:    "allow",
+						Actions:   []string{api.USER_ACTION_GET_USER},
+						Resources: []string{api.GetUrnPrefix("", api.RESOURCE_USER, "/path/")},
+					},
+				},
+			},
+		},
+		"ErrorCaseInvalidVersion": {
+			version:            "v1",
+			ignoreArgsIn:       true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: version v1",
+			},
+		},
+		"ErrorCasePolicyVersionNotFound": {
+			version:            "3",
+			expectedVersion:    3,
+			expectedStatusCode: http.StatusNotFound,
+			expectedError: api.Error{
+				Code: api.POLICY_VERSION_NOT_FOUND,
+			},
+			rollbackPolicyErr: &api.Error{
+				Code: api.POLICY_VERSION_NOT_FOUND,
+			},
+		},
+		"ErrorCaseUnauthorized": {
+			version:            "1",
+			expectedVersion:    1,
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code: api.UNAUTHORIZED_RESOURCES_ERROR,
+			},
+			rollbackPolicyErr: &api.Error{
+				Code: api.UNAUTHORIZED_RESOURCES_ERROR,
+			},
+		},
+		"ErrorCaseInternalServerError": {
+			version:            "1",
+			expectedVersion:    1,
+			expectedStatusCode: http.StatusInternalServerError,
+			rollbackPolicyErr: &api.Error{
+				Code: api.UNKNOWN_API_ERROR,
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[RollbackPolicyMethod][0] = test.rollbackPolicyResult
+		testApi.ArgsOut[RollbackPolicyMethod][1] = test.rollbackPolicyErr
+
+		url := fmt.Sprintf(server.URL+API_VERSION_1+"/organizations/org1/policies/p1/versions/%v/rollback", test.version)
+		req, err := http.NewRequest(http.MethodPost, url, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		if !test.ignoreArgsIn {
+			// Check received parameters
+			assert.Equal(t, "org1", testApi.ArgsIn[RollbackPolicyMethod][1], "Error in test case %v", n)
+			assert.Equal(t, "p1", testApi.ArgsIn[RollbackPolicyMethod][2], "Error in test case %v", n)
+			assert.Equal(t, test.expectedVersion, testApi.ArgsIn[RollbackPolicyMethod][3], "Error in test case %v", n)
+		}
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			response := api.Policy{}
+			err = json.NewDecoder(res.Body).Decode(&response)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedResponse, response, "Error in test case %v", n)
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
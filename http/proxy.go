@@ -1,17 +1,17 @@
 package http
 
 import (
-	"bytes"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
-	"regexp"
-	"strings"
+	"time"
 
 	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/http/authzcheck"
 	"github.com/Tecsisa/foulkon/middleware"
 	"github.com/julienschmidt/httprouter"
 	"github.com/satori/go.uuid"
@@ -20,10 +20,10 @@ import (
 const (
 	// Proxy error codes
 	INVALID_DEST_HOST_URL = "InvalidDestinationHostURL"
-	HOST_UNREACHABLE      = "HostUnreachableError"
-	INTERNAL_SERVER_ERROR = "InternalServerError"
-	BAD_REQUEST           = "BadRequest"
-	FORBIDDEN_ERROR       = "ForbiddenError"
+	HOST_UNREACHABLE      = authzcheck.HOST_UNREACHABLE
+	INTERNAL_SERVER_ERROR = authzcheck.INTERNAL_SERVER_ERROR
+	BAD_REQUEST           = authzcheck.BAD_REQUEST
+	FORBIDDEN_ERROR       = authzcheck.FORBIDDEN_ERROR
 )
 
 // REQUESTS
@@ -53,19 +53,15 @@ type ListProxyResourcesResponse struct {
 	Total     int      `json:"total"`
 }
 
-var rUrnParam, _ = regexp.Compile(`\{(\w+)\}`)
-
 func (ph *ProxyHandler) HandleRequest(proxyResource api.ProxyResource) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		requestID := uuid.NewV4().String()
 		w.Header().Set(middleware.REQUEST_ID_HEADER, requestID)
-		// Retrieve parameters to replace in URN
-		parameters := getUrnParameters(proxyResource.Resource.Urn)
-		urn := proxyResource.Resource.Urn
-		for _, p := range parameters {
-			urn = strings.Replace(urn, p[0], ps.ByName(p[1]), -1)
-		}
-		if workerRequestID, err := ph.checkAuthorization(r, urn, proxyResource.Resource.Action); err == nil {
+		urn := authzcheck.ReplaceUrnParams(proxyResource.Resource.Urn, ps)
+		start := time.Now()
+		workerRequestID, user, err := ph.checkAuthorization(r, urn, proxyResource.Resource.Action)
+		ph.logAuthzDecision(requestID, workerRequestID, user, urn, proxyResource.Resource.Action, err == nil, start)
+		if err == nil {
 			destURL, err := url.Parse(proxyResource.Resource.Host)
 			if err != nil {
 				apiErr := getErrorMessage(INVALID_DEST_HOST_URL, fmt.Sprintf("Error creating destination host URL: %v", err.Error()))
@@ -84,19 +80,7 @@ func (ph *ProxyHandler) HandleRequest(proxyResource api.ProxyResource) httproute
 			reverseProxy.ServeHTTP(w, r)
 		} else {
 			apiError := err.(*api.Error)
-			var statusCode int
-			var responseErr *api.Error
-			switch apiError.Code {
-			case FORBIDDEN_ERROR:
-				statusCode = http.StatusForbidden
-				responseErr = getErrorMessage(FORBIDDEN_ERROR, "")
-			case api.INVALID_PARAMETER_ERROR, api.REGEX_NO_MATCH, BAD_REQUEST:
-				statusCode = http.StatusBadRequest
-				responseErr = getErrorMessage(api.INVALID_PARAMETER_ERROR, "Bad request")
-			default:
-				statusCode = http.StatusInternalServerError
-				responseErr = getErrorMessage(INTERNAL_SERVER_ERROR, "Internal server error. Contact the administrator")
-			}
+			statusCode, responseErr := authzcheck.AuthorizationErrorResponse(apiError)
 			WriteHttpResponse(r, w, requestID, "", statusCode, responseErr)
 			api.TransactionProxyErrorLogWithStatus(requestID, workerRequestID, r, statusCode, apiError)
 			return
@@ -130,6 +114,9 @@ func (wh *WorkerHandler) HandleGetProxyResourceByName(w http.ResponseWriter, r *
 
 	// Call policy API to retrieve policy
 	response, err := wh.worker.ProxyApi.GetProxyResourceByName(requestInfo, filterData.Org, filterData.ProxyResourceName)
+	if err == nil {
+		w.Header().Set(ETAG_HEADER, api.ETag(response.UpdateAt))
+	}
 	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
 }
 
@@ -166,6 +153,9 @@ func (wh *WorkerHandler) HandleUpdateProxyResource(w http.ResponseWriter, r *htt
 	}
 	// Call proxy resource API to update proxy resource
 	response, err := wh.worker.ProxyApi.UpdateProxyResource(requestInfo, filterData.Org, filterData.ProxyResourceName, request.Name, request.Path, request.Resource)
+	if err == nil {
+		w.Header().Set(ETAG_HEADER, api.ETag(response.UpdateAt))
+	}
 	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
 }
 
@@ -181,89 +171,178 @@ func (wh *WorkerHandler) HandleRemoveProxyResource(w http.ResponseWriter, r *htt
 	wh.processHttpResponse(r, w, requestInfo, nil, err, http.StatusNoContent)
 }
 
-func (ph *ProxyHandler) checkAuthorization(r *http.Request, urn string, action string) (string, error) {
-	workerRequestID := "None"
-	if !isFullUrn(urn) {
-		return workerRequestID,
-			getErrorMessage(api.INVALID_PARAMETER_ERROR, fmt.Sprintf("Urn %v is a prefix, it would be a full urn resource", urn))
+// Check if the user of request r is allowed to do action over urn, returning the request ID of worker and the
+// authenticated user, empty if it isn't known
+func (ph *ProxyHandler) checkAuthorization(r *http.Request, urn string, action string) (string, string, error) {
+	if ph.proxy.LocalAuthorizer != nil {
+		user, err := ph.checkLocalAuthorization(r, urn, action)
+		return "None", user, err
 	}
-	if err := api.AreValidResources([]string{urn}, api.RESOURCE_EXTERNAL); err != nil {
-		return workerRequestID, err
+
+	// Users aren't known before worker authenticates them, so results are cached by their credentials
+	key := ""
+	if ph.proxy.AuthzResultCache != nil {
+		if credentials := authzcheck.HashCredentials(r, ph.proxy.AuthzCacheHeaders); credentials != "" {
+			key = authzcheck.AuthzCacheKey(credentials, middleware.GetSourceIP(r), action, urn)
+		}
 	}
-	if err := api.AreValidActions([]string{action}); err != nil {
-		return workerRequestID, err
+	if user, err, ok := ph.getAuthzResult(key); ok {
+		return "None", user, err
 	}
+	workerRequestID, user, err := authzcheck.CheckAuthorization(ph.client, ph.proxy.WorkerHost, ph.proxy.ProxySecret,
+		r, urn, action)
+	ph.setAuthzResult(key, user, err)
+	return workerRequestID, user, err
+}
 
-	body, err := json.Marshal(AuthorizeResourcesRequest{
-		Action:    action,
-		Resources: []string{urn},
-	})
-	if err != nil {
-		return workerRequestID, getErrorMessage(api.UNKNOWN_API_ERROR, err.Error())
+// Return the authorization result cached with key and the user it was decided for. Empty keys are never cached
+func (ph *ProxyHandler) getAuthzResult(key string) (string, error, bool) {
+	if key == "" {
+		return "", nil, false
 	}
+	user, apiError, ok := ph.proxy.AuthzResultCache.Get(key)
+	if !ok {
+		return "", nil, false
+	}
+	if apiError != nil {
+		return user, apiError, true
+	}
+	return user, nil, true
+}
 
-	req, err := http.NewRequest(http.MethodPost, ph.proxy.WorkerHost+RESOURCE_URL, bytes.NewBuffer(body))
-	if err != nil {
-		return workerRequestID, getErrorMessage(api.UNKNOWN_API_ERROR, err.Error())
+// Cache the authorization result of key, unless key is empty. Only results are cached, errors retrieving them aren't
+func (ph *ProxyHandler) setAuthzResult(key string, user string, err error) {
+	if key == "" {
+		return
 	}
-	// Add all headers from original request
-	req.Header = r.Header
-	// Call worker to retrieve authorization
-	res, err := ph.client.Do(req)
-	if err != nil {
-		return workerRequestID, getErrorMessage(HOST_UNREACHABLE, err.Error())
+	if err == nil {
+		ph.proxy.AuthzResultCache.Set(key, user, nil)
+	} else if apiError := err.(*api.Error); apiError.Code == FORBIDDEN_ERROR {
+		ph.proxy.AuthzResultCache.Set(key, user, apiError)
 	}
+}
 
-	defer res.Body.Close()
+// HandleGetAuthzResultCacheStats returns the statistics of the authorization result cache of proxy
+func (ph *ProxyHandler) HandleGetAuthzResultCacheStats(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	requestID, ok := ph.checkAdmin(w, r)
+	if !ok {
+		return
+	}
+	WriteHttpResponse(r, w, requestID, ph.proxy.AdminUser, http.StatusOK, ph.proxy.AuthzResultCache.Stats())
+}
 
-	workerRequestID = res.Header.Get(middleware.REQUEST_ID_HEADER)
-
-	switch res.StatusCode {
-	case http.StatusUnauthorized:
-		return workerRequestID, getErrorMessage(FORBIDDEN_ERROR, "Unauthenticated user")
-	case http.StatusForbidden:
-		return workerRequestID, getErrorMessage(FORBIDDEN_ERROR, fmt.Sprintf("Restricted access to urn %v", urn))
-	case http.StatusBadRequest:
-		return workerRequestID, getErrorMessage(BAD_REQUEST, "Invalid request")
-	case http.StatusOK:
-		authzResponse := AuthorizeResourcesResponse{}
-		err = json.NewDecoder(res.Body).Decode(&authzResponse)
-		if err != nil {
-			return workerRequestID, getErrorMessage(api.UNKNOWN_API_ERROR, fmt.Sprintf("Error parsing foulkon response %v", err.Error()))
-		}
+// HandleFlushAuthzResultCache removes every entry of the authorization result cache of proxy
+func (ph *ProxyHandler) HandleFlushAuthzResultCache(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	requestID, ok := ph.checkAdmin(w, r)
+	if !ok {
+		return
+	}
+	ph.proxy.AuthzResultCache.Flush()
+	api.LogOperation(requestID, ph.proxy.AdminUser, "Authorization result cache flushed")
+	WriteHttpResponse(r, w, requestID, ph.proxy.AdminUser, http.StatusNoContent, nil)
+}
 
-		// Check urns allowed to find target urn
-		allowed := false
-		for _, allowedRes := range authzResponse.ResourcesAllowed {
-			if allowedRes == urn {
-				allowed = true
-				break
-			}
+// Check that request r is authenticated as the admin user of proxy, writing an error response if it isn't
+func (ph *ProxyHandler) checkAdmin(w http.ResponseWriter, r *http.Request) (string, bool) {
+	requestID := uuid.NewV4().String()
+	w.Header().Set(middleware.REQUEST_ID_HEADER, requestID)
+	username, password, ok := r.BasicAuth()
+	if !ok || ph.proxy.AdminUser == "" ||
+		subtle.ConstantTimeCompare([]byte(username), []byte(ph.proxy.AdminUser)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(password), []byte(ph.proxy.AdminPassword)) != 1 {
+		apiError := &api.Error{
+			Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+			Message: "Unauthorized, user is not admin",
 		}
+		api.TransactionResponseErrorLog(requestID, username, r, http.StatusForbidden, apiError)
+		WriteHttpResponse(r, w, requestID, username, http.StatusForbidden, apiError)
+		return requestID, false
+	}
+	return requestID, true
+}
 
-		if !allowed {
-			return workerRequestID,
-				getErrorMessage(FORBIDDEN_ERROR, fmt.Sprintf("No access for urn %v received from server", urn))
-		}
+// Check if the user of request r is allowed to do action over urn with the local authorizer of proxy, returning
+// the authenticated user and the same errors as authzcheck.CheckAuthorization. Request is authenticated like worker
+// does
+func (ph *ProxyHandler) checkLocalAuthorization(r *http.Request, urn string, action string) (string, error) {
+	if err := authzcheck.ValidateAuthorization(urn, action); err != nil {
+		return "", err
+	}
 
-		return workerRequestID, nil
-	default:
-		return workerRequestID,
-			getErrorMessage(INTERNAL_SERVER_ERROR, fmt.Sprintf("There was a problem retrieving authorization, status code %v", res.StatusCode))
+	requestInfo, _, ok := ph.proxy.MiddlewareHandler.Authenticate(r)
+	if !ok {
+		return "", getErrorMessage(FORBIDDEN_ERROR, "Unauthenticated user")
+	}
+
+	// Users are authenticated before authorization, so results are cached by their identifier
+	key := ""
+	if ph.proxy.AuthzResultCache != nil {
+		key = authzcheck.AuthzCacheKey(requestInfo.Identifier, requestInfo.Context.SourceIP, action, urn)
+	}
+	if _, err, ok := ph.getAuthzResult(key); ok {
+		return requestInfo.Identifier, err
+	}
+
+	var err error
+	if _, authzErr := ph.proxy.LocalAuthorizer.GetAuthorizedExternalResources(requestInfo, action, []string{urn}); authzErr != nil {
+		apiError := authzErr.(*api.Error)
+		switch apiError.Code {
+		case api.UNAUTHORIZED_RESOURCES_ERROR:
+			err = getErrorMessage(FORBIDDEN_ERROR, fmt.Sprintf("Restricted access to urn %v", urn))
+		case api.INVALID_PARAMETER_ERROR, api.REGEX_NO_MATCH:
+			err = getErrorMessage(BAD_REQUEST, "Invalid request")
+		default:
+			err = getErrorMessage(INTERNAL_SERVER_ERROR, fmt.Sprintf("There was a problem retrieving authorization: %v", apiError.Message))
+		}
 	}
+	ph.setAuthzResult(key, requestInfo.Identifier, err)
+	return requestInfo.Identifier, err
 }
 
-// Check parameters in URN to replace with URI parameters
-func getUrnParameters(urn string) [][]string {
-	match := rUrnParam.FindAllStringSubmatch(urn, -1)
-	if match != nil && len(match) > 0 {
-		return match
+// Retrieve the authorization snapshot of worker at workerHost, authenticated as admin
+func getWorkerAuthzSnapshot(client *http.Client, workerHost string, adminUser string, adminPassword string) (*api.AuthzSnapshot, error) {
+	req, err := http.NewRequest(http.MethodGet, workerHost+AUTHZ_SNAPSHOT_URL, nil)
+	if err != nil {
+		return nil, getErrorMessage(api.UNKNOWN_API_ERROR, err.Error())
+	}
+	req.SetBasicAuth(adminUser, adminPassword)
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, getErrorMessage(HOST_UNREACHABLE, err.Error())
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, getErrorMessage(INTERNAL_SERVER_ERROR,
+			fmt.Sprintf("There was a problem retrieving authorization snapshot, status code %v", res.StatusCode))
 	}
-	return nil
+	snapshot := &api.AuthzSnapshot{}
+	if err := json.NewDecoder(res.Body).Decode(snapshot); err != nil {
+		return nil, getErrorMessage(api.UNKNOWN_API_ERROR, fmt.Sprintf("Error parsing foulkon response %v", err.Error()))
+	}
+	return snapshot, nil
 }
 
-func isFullUrn(resource string) bool {
-	return !strings.ContainsAny(resource, "*")
+// Log the decision made by proxy for the requested urn
+func (ph *ProxyHandler) logAuthzDecision(requestID string, workerRequestID string, user string, urn string, action string,
+	allowed bool, start time.Time) {
+	if ph.proxy.AuthzLogger == nil {
+		return
+	}
+	decision := api.AuthzDecision{
+		Source:          api.AUTHZ_DECISION_SOURCE_PROXY,
+		RequestID:       requestID,
+		WorkerRequestID: workerRequestID,
+		User:            user,
+		Action:          action,
+		Requested:       []string{urn},
+		Allowed:         []string{},
+		Latency:         time.Since(start),
+	}
+	if allowed {
+		decision.Allowed = []string{urn}
+	}
+	ph.proxy.AuthzLogger.Log(decision)
 }
 
 func getErrorMessage(errorCode string, message string) *api.Error {
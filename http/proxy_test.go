@@ -8,8 +8,13 @@ import (
 
 	"bytes"
 	"fmt"
+	"net/http/httptest"
 
 	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/foulkon"
+	"github.com/Tecsisa/foulkon/http/authzcheck"
+	"github.com/Tecsisa/foulkon/middleware"
+	"github.com/Tecsisa/foulkon/middleware/auth"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -210,6 +215,543 @@ func TestProxyHandler_HandleRequest(t *testing.T) {
 	}
 }
 
+func TestProxyHandler_HandleRequestLocalAuthorization(t *testing.T) {
+	snapshot := &api.AuthzSnapshot{
+		Users: []api.User{
+			{
+				ID:         "UserID",
+				ExternalID: "userID",
+			},
+		},
+		Policies: []api.Policy{
+			{
+				ID: "PolicyID",
+				Statements: &[]api.Statement{
+					{
+						Effect:    "allow",
+						Actions:   []string{"example:user"},
+						Resources: []string{"urn:ews:example:instance1:resource/allowed"},
+					},
+				},
+			},
+		},
+		UserPolicies: []api.AuthzSnapshotRelation{
+			{ID: "UserID", RelatedID: "PolicyID"},
+		},
+	}
+	testcases := map[string]struct {
+		resource       string
+		admin          bool
+		authStatusCode int
+		// Snapshot loaded in local authorizer
+		snapshot *api.AuthzSnapshot
+		// Expected result
+		expectedStatusCode int
+		expectedError      *api.Error
+		expectedUser       string
+		// Results cached by user identifier
+		expectedCacheEntries int
+	}{
+		"OkCaseAllowed": {
+			resource:             USER_ROOT_URL + "/allowed",
+			snapshot:             snapshot,
+			expectedStatusCode:   http.StatusOK,
+			expectedUser:         "userID",
+			expectedCacheEntries: 1,
+		},
+		"OkCaseAdmin": {
+			resource:             USER_ROOT_URL + "/denied",
+			admin:                true,
+			snapshot:             snapshot,
+			expectedStatusCode:   http.StatusOK,
+			expectedUser:         "admin",
+			expectedCacheEntries: 1,
+		},
+		"ErrorCaseForbidden": {
+			resource:           USER_ROOT_URL + "/denied",
+			snapshot:           snapshot,
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: &api.Error{
+				Code:    FORBIDDEN_ERROR,
+				Message: "Forbidden resource. If you need access, contact the administrator",
+			},
+			expectedUser:         "userID",
+			expectedCacheEntries: 1,
+		},
+		"ErrorCaseUnauthenticated": {
+			resource:           USER_ROOT_URL + "/allowed",
+			authStatusCode:     http.StatusUnauthorized,
+			snapshot:           snapshot,
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: &api.Error{
+				Code:    FORBIDDEN_ERROR,
+				Message: "Forbidden resource. If you need access, contact the administrator",
+			},
+		},
+		"ErrorCaseSnapshotNotLoaded": {
+			resource:           USER_ROOT_URL + "/allowed",
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedError: &api.Error{
+				Code:    INTERNAL_SERVER_ERROR,
+				Message: "Internal server error. Contact the administrator",
+			},
+			expectedUser: "userID",
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+		authorizer := &api.LocalAuthorizer{}
+		if test.snapshot != nil {
+			authorizer.Load(test.snapshot)
+		}
+		middlewares := map[string]middleware.Middleware{
+			middleware.AUTHENTICATOR_MIDDLEWARE: auth.NewAuthenticatorMiddleware(authConnector, "admin", "admin"),
+		}
+		sink := &testAuthzDecisionSink{}
+		proxyCore := &foulkon.Proxy{
+			WorkerHost:        server.URL,
+			ProxyApi:          testApi,
+			LocalAuthorizer:   authorizer,
+			MiddlewareHandler: &middleware.MiddlewareHandler{Middlewares: middlewares},
+			AuthzLogger:       api.NewAuthzDecisionLogger(sink, 1, 10, time.Hour),
+			AuthzResultCache:  api.NewAuthzResultCache(10, time.Minute, time.Minute),
+		}
+		proxyServer := httptest.NewServer(proxyHandlerRouter(proxyCore))
+
+		testApi.ArgsOut[GetUserByExternalIdMethod][0] = &api.User{}
+		testApi.ArgsOut[GetUserByExternalIdMethod][1] = nil
+
+		req, err := http.NewRequest(http.MethodGet, proxyServer.URL+test.resource, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+		if test.admin {
+			req.SetBasicAuth("admin", "admin")
+		}
+		if test.authStatusCode != 0 {
+			authConnector.statusCode = test.authStatusCode
+		}
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		if test.expectedError != nil {
+			apiError := &api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+		res.Body.Close()
+		proxyServer.Close()
+
+		assert.Equal(t, test.expectedCacheEntries, proxyCore.AuthzResultCache.Stats().Entries, "Error in test case %v", n)
+
+		// Check user authenticated by proxy is logged
+		assert.Nil(t, proxyCore.AuthzLogger.Close(), "Error in test case %v", n)
+		if assert.Equal(t, 1, len(sink.decisions), "Error in test case %v", n) {
+			assert.Equal(t, test.expectedUser, sink.decisions[0].User, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestGetWorkerAuthzSnapshot(t *testing.T) {
+	testcases := map[string]struct {
+		adminPassword string
+		// Expected result
+		expectedResponse *api.AuthzSnapshot
+		expectedError    *api.Error
+		// Manager Results
+		getAuthzSnapshotResult *api.AuthzSnapshot
+		// Manager Errors
+		getAuthzSnapshotErr error
+	}{
+		"OkCase": {
+			adminPassword: "admin",
+			expectedResponse: &api.AuthzSnapshot{
+				Users: []api.User{
+					{
+						ID:         "UserID",
+						ExternalID: "userID",
+					},
+				},
+			},
+			getAuthzSnapshotResult: &api.AuthzSnapshot{
+				Users: []api.User{
+					{
+						ID:         "UserID",
+						ExternalID: "userID",
+					},
+				},
+			},
+		},
+		"ErrorCaseWorkerError": {
+			adminPassword: "admin",
+			expectedError: &api.Error{
+				Code:    INTERNAL_SERVER_ERROR,
+				Message: "There was a problem retrieving authorization snapshot, status code 500",
+			},
+			getAuthzSnapshotErr: &api.Error{
+				Code:    api.UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		testApi.ArgsOut[GetAuthzSnapshotMethod][0] = test.getAuthzSnapshotResult
+		testApi.ArgsOut[GetAuthzSnapshotMethod][1] = test.getAuthzSnapshotErr
+
+		snapshot, err := getWorkerAuthzSnapshot(http.DefaultClient, server.URL, "admin", test.adminPassword)
+		if test.expectedError != nil {
+			assert.Equal(t, test.expectedError, err, "Error in test case %v", n)
+		} else {
+			assert.Nil(t, err, "Error in test case %v", n)
+			assert.Equal(t, test.expectedResponse, snapshot, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestCheckAuthorization(t *testing.T) {
+	urn := "urn:ews:product:instance:resource/res1"
+	testcases := map[string]struct {
+		proxySecret string
+		// Expected result
+		expectedSourceIP string
+	}{
+		"OkCaseProxySecret": {
+			proxySecret:      "proxysecret",
+			expectedSourceIP: "10.1.2.3",
+		},
+		"OkCaseNoProxySecret": {
+			expectedSourceIP: "127.0.0.1",
+		},
+	}
+
+	for n, test := range testcases {
+		testApi.ArgsOut[GetAuthorizedExternalResourcesMethod][0] = []string{urn}
+		testApi.ArgsOut[GetAuthorizedExternalResourcesMethod][1] = nil
+
+		r, err := http.NewRequest(http.MethodGet, "/resource", nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+		r.RemoteAddr = "10.1.2.3:4321"
+		// Headers sent by client are overwritten
+		r.Header.Set(middleware.SOURCE_IP_HEADER, "192.168.1.1")
+		r.Header.Set(middleware.PROXY_SECRET_HEADER, "proxysecret")
+
+		_, user, err := authzcheck.CheckAuthorization(http.DefaultClient, server.URL, test.proxySecret, r, urn, "product:DoAction")
+		assert.Nil(t, err, "Error in test case %v", n)
+		assert.Equal(t, "userID", user, "Error in test case %v", n)
+
+		requestInfo := testApi.ArgsIn[GetAuthorizedExternalResourcesMethod][0].(api.RequestInfo)
+		assert.Equal(t, test.expectedSourceIP, requestInfo.Context.SourceIP, "Error in test case %v", n)
+		// Original request isn't modified
+		assert.Equal(t, "192.168.1.1", r.Header.Get(middleware.SOURCE_IP_HEADER), "Error in test case %v", n)
+	}
+}
+
+func TestProxyHandler_HandleRequestAuthzResultCache(t *testing.T) {
+	testcases := map[string]struct {
+		ttl         time.Duration
+		negativeTTL time.Duration
+		// Authorization header of requests
+		authorization string
+		// Manager results of first and second requests
+		firstResult  []string
+		firstErr     error
+		secondResult []string
+		secondErr    error
+		// Expected result of second request
+		expectedSecond  int
+		expectedEntries int
+		expectedHits    uint64
+	}{
+		"OkCaseAllowedCached": {
+			ttl:             time.Minute,
+			authorization:   "Basic YWRtaW46YWRtaW4=",
+			firstResult:     []string{"urn:ews:example:instance1:resource/user"},
+			secondErr:       &api.Error{Code: api.UNAUTHORIZED_RESOURCES_ERROR},
+			expectedSecond:  http.StatusOK,
+			expectedEntries: 1,
+			expectedHits:    1,
+		},
+		"OkCaseDeniedCached": {
+			ttl:             time.Minute,
+			negativeTTL:     time.Minute,
+			authorization:   "Basic YWRtaW46YWRtaW4=",
+			firstErr:        &api.Error{Code: api.UNAUTHORIZED_RESOURCES_ERROR},
+			secondResult:    []string{"urn:ews:example:instance1:resource/user"},
+			expectedSecond:  http.StatusForbidden,
+			expectedEntries: 1,
+			expectedHits:    1,
+		},
+		"OkCaseDeniedNotCached": {
+			ttl:            time.Minute,
+			authorization:  "Basic YWRtaW46YWRtaW4=",
+			firstErr:       &api.Error{Code: api.UNAUTHORIZED_RESOURCES_ERROR},
+			secondResult:   []string{"urn:ews:example:instance1:resource/user"},
+			expectedSecond: http.StatusOK,
+			// Only the result of second request is cached
+			expectedEntries: 1,
+		},
+		"OkCaseErrorNotCached": {
+			ttl:            time.Minute,
+			negativeTTL:    time.Minute,
+			authorization:  "Basic YWRtaW46YWRtaW4=",
+			firstErr:       &api.Error{Code: api.UNKNOWN_API_ERROR},
+			secondResult:   []string{"urn:ews:example:instance1:resource/user"},
+			expectedSecond: http.StatusOK,
+			// Only the result of second request is cached
+			expectedEntries: 1,
+		},
+		"OkCaseWithoutCredentials": {
+			ttl:            time.Minute,
+			negativeTTL:    time.Minute,
+			firstResult:    []string{"urn:ews:example:instance1:resource/user"},
+			secondErr:      &api.Error{Code: api.UNAUTHORIZED_RESOURCES_ERROR},
+			expectedSecond: http.StatusForbidden,
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+		proxyCore := &foulkon.Proxy{
+			WorkerHost:        server.URL,
+			ProxyApi:          testApi,
+			AuthzResultCache:  api.NewAuthzResultCache(10, test.ttl, test.negativeTTL),
+			AuthzCacheHeaders: []string{"Authorization"},
+		}
+		proxyServer := httptest.NewServer(proxyHandlerRouter(proxyCore))
+		testApi.ArgsOut[GetUserByExternalIdMethod][0] = &api.User{}
+		testApi.ArgsOut[GetUserByExternalIdMethod][1] = nil
+
+		results := [][]string{test.firstResult, test.secondResult}
+		errs := []error{test.firstErr, test.secondErr}
+		var statusCode int
+		for i := range results {
+			testApi.ArgsOut[GetAuthorizedExternalResourcesMethod][0] = results[i]
+			testApi.ArgsOut[GetAuthorizedExternalResourcesMethod][1] = errs[i]
+
+			req, err := http.NewRequest(http.MethodGet, proxyServer.URL+USER_ROOT_URL+"/user", nil)
+			assert.Nil(t, err, "Error in test case %v", n)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			res, err := client.Do(req)
+			assert.Nil(t, err, "Error in test case %v", n)
+			res.Body.Close()
+			statusCode = res.StatusCode
+		}
+		proxyServer.Close()
+
+		// Check second request and cache usage
+		assert.Equal(t, test.expectedSecond, statusCode, "Error in test case %v", n)
+		stats := proxyCore.AuthzResultCache.Stats()
+		assert.Equal(t, test.expectedEntries, stats.Entries, "Error in test case %v", n)
+		assert.Equal(t, test.expectedHits, stats.Hits, "Error in test case %v", n)
+	}
+}
+
+func TestProxyHandler_HandleAuthzResultCache(t *testing.T) {
+	testcases := map[string]struct {
+		method        string
+		adminUser     string
+		adminPassword string
+		// Expected result
+		expectedStatusCode int
+		expectedResponse   api.AuthzResultCacheStats
+		expectedError      api.Error
+		expectedEntries    int
+	}{
+		"OkCaseStats": {
+			method:             http.MethodGet,
+			adminUser:          "admin",
+			adminPassword:      "admin",
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: api.AuthzResultCacheStats{
+				Enabled:     true,
+				Size:        10,
+				TTL:         "1m0s",
+				NegativeTTL: "0s",
+				Entries:     1,
+			},
+			expectedEntries: 1,
+		},
+		"OkCaseFlush": {
+			method:             http.MethodDelete,
+			adminUser:          "admin",
+			adminPassword:      "admin",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		"ErrorCaseInvalidAdmin": {
+			method:             http.MethodDelete,
+			adminUser:          "admin",
+			adminPassword:      "fail",
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized, user is not admin",
+			},
+			expectedEntries: 1,
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+		proxyCore := &foulkon.Proxy{
+			WorkerHost:        server.URL,
+			ProxyApi:          testApi,
+			AdminUser:         "admin",
+			AdminPassword:     "admin",
+			AuthzResultCache:  api.NewAuthzResultCache(10, time.Minute, 0),
+			AuthzCacheHeaders: []string{"Authorization"},
+		}
+		proxyCore.AuthzResultCache.Set("key", "admin", nil)
+		proxyServer := httptest.NewServer(proxyHandlerRouter(proxyCore))
+
+		req, err := http.NewRequest(test.method, proxyServer.URL+PROXY_AUTHZ_CACHE_URL, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+		req.SetBasicAuth(test.adminUser, test.adminPassword)
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+		assert.NotEmpty(t, res.Header.Get(middleware.REQUEST_ID_HEADER), "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			response := api.AuthzResultCacheStats{}
+			err = json.NewDecoder(res.Body).Decode(&response)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedResponse, response, "Error in test case %v", n)
+		case http.StatusNoContent:
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+		res.Body.Close()
+		proxyServer.Close()
+
+		assert.Equal(t, test.expectedEntries, proxyCore.AuthzResultCache.Stats().Entries, "Error in test case %v", n)
+	}
+}
+
+// Sink that stores decisions in memory
+type testAuthzDecisionSink struct {
+	decisions []api.AuthzDecision
+}
+
+func (s *testAuthzDecisionSink) AddAuthzDecisions(decisions []api.AuthzDecision) error {
+	s.decisions = append(s.decisions, decisions...)
+	return nil
+}
+
+func TestProxyHandler_HandleRequestDecisionLog(t *testing.T) {
+	testcases := map[string]struct {
+		resource  string
+		basicAuth bool
+		// Expected result
+		expectedDecision api.AuthzDecision
+		// Manager Results
+		getAuthorizedExternalResourcesResult []string
+		// Manager Errors
+		getAuthorizedExternalResourcesErr error
+	}{
+		"OkCaseAllowed": {
+			resource:  USER_ROOT_URL + "/user",
+			basicAuth: true,
+			expectedDecision: api.AuthzDecision{
+				Source:    api.AUTHZ_DECISION_SOURCE_PROXY,
+				User:      "admin",
+				Action:    "example:user",
+				Requested: []string{"urn:ews:example:instance1:resource/user"},
+				Allowed:   []string{"urn:ews:example:instance1:resource/user"},
+			},
+			getAuthorizedExternalResourcesResult: []string{"urn:ews:example:instance1:resource/user"},
+		},
+		"OkCaseDenied": {
+			resource:  USER_ROOT_URL + "/user",
+			basicAuth: true,
+			expectedDecision: api.AuthzDecision{
+				Source:    api.AUTHZ_DECISION_SOURCE_PROXY,
+				User:      "admin",
+				Action:    "example:user",
+				Requested: []string{"urn:ews:example:instance1:resource/user"},
+				Allowed:   []string{},
+			},
+			getAuthorizedExternalResourcesErr: &api.Error{
+				Code: api.UNAUTHORIZED_RESOURCES_ERROR,
+			},
+		},
+		"OkCaseNotBasicAuth": {
+			resource: USER_ROOT_URL + "/user",
+			expectedDecision: api.AuthzDecision{
+				Source:    api.AUTHZ_DECISION_SOURCE_PROXY,
+				User:      "userID",
+				Action:    "example:user",
+				Requested: []string{"urn:ews:example:instance1:resource/user"},
+				Allowed:   []string{"urn:ews:example:instance1:resource/user"},
+			},
+			getAuthorizedExternalResourcesResult: []string{"urn:ews:example:instance1:resource/user"},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+		sink := &testAuthzDecisionSink{}
+		proxyCore := &foulkon.Proxy{
+			WorkerHost:  server.URL,
+			ProxyApi:    testApi,
+			AuthzLogger: api.NewAuthzDecisionLogger(sink, 1, 10, time.Hour),
+		}
+		proxyServer := httptest.NewServer(proxyHandlerRouter(proxyCore))
+
+		testApi.ArgsOut[GetAuthorizedExternalResourcesMethod][0] = test.getAuthorizedExternalResourcesResult
+		testApi.ArgsOut[GetAuthorizedExternalResourcesMethod][1] = test.getAuthorizedExternalResourcesErr
+		testApi.ArgsOut[GetUserByExternalIdMethod][0] = &api.User{}
+		testApi.ArgsOut[GetUserByExternalIdMethod][1] = nil
+
+		req, err := http.NewRequest(http.MethodGet, proxyServer.URL+test.resource, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+		if test.basicAuth {
+			req.SetBasicAuth("admin", "admin")
+		}
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+		res.Body.Close()
+		proxyServer.Close()
+		assert.Nil(t, proxyCore.AuthzLogger.Close(), "Error in test case %v", n)
+
+		// Check decision logged
+		if assert.Equal(t, 1, len(sink.decisions), "Error in test case %v", n) {
+			decision := sink.decisions[0]
+			assert.Equal(t, res.Header.Get(middleware.REQUEST_ID_HEADER), decision.RequestID, "Error in test case %v", n)
+			assert.NotEmpty(t, decision.WorkerRequestID, "Error in test case %v", n)
+			assert.NotEmpty(t, decision.ID, "Error in test case %v", n)
+			// Ignore generated fields
+			decision.ID = ""
+			decision.RequestID = ""
+			decision.WorkerRequestID = ""
+			decision.Latency = 0
+			decision.CreateAt = time.Time{}
+			assert.Equal(t, test.expectedDecision, decision, "Error in test case %v", n)
+		}
+	}
+}
+
 func TestWorkerHandler_HandleAddProxyResource(t *testing.T) {
 	now := time.Now().UTC()
 	testcases := map[string]struct {
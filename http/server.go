@@ -8,6 +8,7 @@ import (
 	"crypto/tls"
 	"net"
 
+	"strings"
 	"sync"
 
 	"github.com/Tecsisa/foulkon/api"
@@ -161,16 +162,26 @@ func (ps *ProxyServer) RefreshResources(proxy *foulkon.Proxy) func(s *ProxyServe
 	return func(srv *ProxyServer) bool {
 		proxyHandler := ProxyHandler{proxy: proxy, client: http.DefaultClient}
 
+		// Authorization data of local authorizer is refreshed with resources
+		if proxy.LocalAuthorizer != nil {
+			refreshAuthzSnapshot(proxy, proxyHandler.client)
+		}
+
 		// Get proxy resources
 		newProxyResources, err := proxy.ProxyApi.GetProxyResources()
 		if err != nil {
 			api.Log.Errorf("Unexpected error reading proxy resources from database %v", err)
+			// Admin routes are served even if resources can't be read at start
+			if srv.Handler == nil {
+				srv.resourceLock.Lock()
+				srv.Handler = newProxyRouter(&proxyHandler, nil)
+				srv.resourceLock.Unlock()
+			}
 			return false
 		}
 
-		if diff := pretty.Compare(srv.currentResources, newProxyResources); diff != "" {
-			router := httprouter.New()
-
+		// Router is built at start even without resources, so admin routes are always served
+		if diff := pretty.Compare(srv.currentResources, newProxyResources); diff != "" || srv.Handler == nil {
 			defer srv.resourceLock.Unlock()
 			srv.resourceLock.Lock()
 
@@ -178,23 +189,70 @@ func (ps *ProxyServer) RefreshResources(proxy *foulkon.Proxy) func(s *ProxyServe
 			ps.currentResources = newProxyResources
 
 			api.Log.Info("Updating resources ...")
-			for _, pr := range newProxyResources {
-				// Clean path
-				pr.Resource.Path = httprouter.CleanPath(pr.Resource.Path)
-
-				// Attach resource
-				safeRouterAdderHandler(router, pr, &proxyHandler)
-			}
 			// TODO: test when resources are empty
 			// If we had resources and those were deleted then handler must be
 			// created with empty router.
-			ps.Server.Handler = router
+			ps.Server.Handler = newProxyRouter(&proxyHandler, newProxyResources)
 			return true
 		}
 		return false
 	}
 }
 
+// Return a handler that serves the admin routes of proxy handler under PROXY_ADMIN_ROOT and a route for each resource
+// elsewhere. Admin routes are matched first, so resources under PROXY_ADMIN_ROOT are discarded
+func newProxyRouter(proxyHandler *ProxyHandler, resources []api.ProxyResource) http.Handler {
+	adminRouter := httprouter.New()
+	if proxyHandler.proxy.AuthzResultCache != nil {
+		adminRouter.GET(PROXY_AUTHZ_CACHE_URL, proxyHandler.HandleGetAuthzResultCacheStats)
+		adminRouter.DELETE(PROXY_AUTHZ_CACHE_URL, proxyHandler.HandleFlushAuthzResultCache)
+	}
+	router := httprouter.New()
+	for _, pr := range resources {
+		// Clean path
+		pr.Resource.Path = httprouter.CleanPath(pr.Resource.Path)
+
+		if isProxyAdminPath(pr.Resource.Path) {
+			api.Log.Errorf("Proxy resource with name %v and org %v is ignored, path %v is reserved for proxy admin routes under %v",
+				pr.Name, pr.Org, pr.Resource.Path, PROXY_ADMIN_ROOT)
+			continue
+		}
+
+		// Attach resource
+		safeRouterAdderHandler(router, pr, proxyHandler)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isProxyAdminPath(httprouter.CleanPath(r.URL.Path)) {
+			adminRouter.ServeHTTP(w, r)
+			return
+		}
+		router.ServeHTTP(w, r)
+	})
+}
+
+// Check if path is under PROXY_ADMIN_ROOT
+func isProxyAdminPath(path string) bool {
+	return path == PROXY_ADMIN_ROOT || strings.HasPrefix(path, PROXY_ADMIN_ROOT+"/")
+}
+
+// Load a new authorization snapshot in the local authorizer of proxy. Previous snapshot is kept if it can't be retrieved
+func refreshAuthzSnapshot(proxy *foulkon.Proxy, client *http.Client) {
+	var snapshot *api.AuthzSnapshot
+	var err error
+	if proxy.AuthzSnapshotRepo != nil {
+		snapshot, err = proxy.AuthzSnapshotRepo.GetAuthzSnapshot()
+	} else {
+		snapshot, err = getWorkerAuthzSnapshot(client, proxy.WorkerHost, proxy.AdminUser, proxy.AdminPassword)
+	}
+	if err != nil {
+		api.Log.Errorf("Unexpected error reading authorization snapshot %v", err)
+		return
+	}
+	proxy.LocalAuthorizer.Load(snapshot)
+	api.Log.Debugf("Authorization snapshot loaded with %v users, %v groups and %v policies", len(snapshot.Users),
+		len(snapshot.Groups), len(snapshot.Policies))
+}
+
 // Method to control when router has a resource already defined that collides with another
 func safeRouterAdderHandler(router *httprouter.Router, pr api.ProxyResource, ph *ProxyHandler) {
 	defer func() {
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 
 	"net/http"
+	"net/http/httptest"
 
 	"time"
 
@@ -128,6 +129,7 @@ func TestNewProxy(t *testing.T) {
 				ProxyApi:    testApi,
 			},
 			getProxyResourcesMethod: []api.ProxyResource{},
+			expectedResources:       []api.ProxyResource{},
 		},
 		"ErrorCaseGetProxyResources": {
 			proxy: &foulkon.Proxy{
@@ -287,6 +289,105 @@ func TestNewProxy(t *testing.T) {
 	}
 }
 
+func TestProxyServer_RefreshResourcesAdminRoutes(t *testing.T) {
+	testApi := makeTestApi()
+	testcases := map[string]struct {
+		getProxyResourcesMethod []api.ProxyResource
+		getProxyResourcesError  error
+	}{
+		"OKCaseEmptyResources": {
+			getProxyResourcesMethod: []api.ProxyResource{},
+		},
+		"OKCaseErrorGettingResources": {
+			getProxyResourcesError: api.Error{
+				Code:    INTERNAL_SERVER_ERROR,
+				Message: "Unknow error",
+			},
+		},
+	}
+	for n, test := range testcases {
+		testApi.ArgsOut[GetProxyResourcesMethod][0] = test.getProxyResourcesMethod
+		testApi.ArgsOut[GetProxyResourcesMethod][1] = test.getProxyResourcesError
+
+		srv := NewProxy(&foulkon.Proxy{
+			RefreshTime:      10,
+			ProxyApi:         testApi,
+			AdminUser:        "admin",
+			AdminPassword:    "admin",
+			AuthzResultCache: api.NewAuthzResultCache(10, time.Minute, 0),
+		})
+		ps := srv.(*ProxyServer)
+
+		// Cache admin routes are served without resources
+		req := httptest.NewRequest(http.MethodGet, PROXY_AUTHZ_CACHE_URL, nil)
+		req.SetBasicAuth("admin", "admin")
+		w := httptest.NewRecorder()
+		ps.resourceLock.Lock()
+		if assert.NotNil(t, ps.Handler, "Error in test case %v", n) {
+			ps.Handler.ServeHTTP(w, req)
+		}
+		ps.resourceLock.Unlock()
+		assert.Equal(t, http.StatusOK, w.Code, "Error in test case %v", n)
+	}
+}
+
+func Test_newProxyRouter(t *testing.T) {
+	testcases := map[string]struct {
+		resource api.ProxyResource
+		// Expected result
+		expectedError string
+	}{
+		"OKCaseCatchAllResource": {
+			resource: api.ProxyResource{
+				Name: "catchall",
+				Org:  "org1",
+				Resource: api.ResourceEntity{
+					Method: http.MethodGet,
+					Path:   "/api/*path",
+				},
+			},
+		},
+		"ErrorCaseAdminResource": {
+			resource: api.ProxyResource{
+				Name: "admin",
+				Org:  "org1",
+				Resource: api.ResourceEntity{
+					Method: http.MethodGet,
+					Path:   PROXY_ADMIN_ROOT + "/users",
+				},
+			},
+			expectedError: "Proxy resource with name admin and org org1 is ignored, path " + PROXY_ADMIN_ROOT +
+				"/users is reserved for proxy admin routes under " + PROXY_ADMIN_ROOT,
+		},
+	}
+	for n, test := range testcases {
+		hook.Reset()
+		proxyHandler := &ProxyHandler{
+			proxy: &foulkon.Proxy{
+				AdminUser:        "admin",
+				AdminPassword:    "admin",
+				AuthzResultCache: api.NewAuthzResultCache(10, time.Minute, 0),
+			},
+			client: http.DefaultClient,
+		}
+		router := newProxyRouter(proxyHandler, []api.ProxyResource{test.resource})
+		if test.expectedError != "" {
+			if assert.NotNil(t, hook.LastEntry(), "Error in test case %v", n) {
+				assert.Equal(t, test.expectedError, hook.LastEntry().Message, "Error in test case %v", n)
+			}
+		} else {
+			assert.Nil(t, hook.LastEntry(), "Error in test case %v", n)
+		}
+
+		// Admin routes are served whatever resources are configured
+		req := httptest.NewRequest(http.MethodGet, PROXY_AUTHZ_CACHE_URL, nil)
+		req.SetBasicAuth("admin", "admin")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, "Error in test case %v", n)
+	}
+}
+
 func Test_strSliceContains(t *testing.T) {
 	testcases := map[string]struct {
 		ss             []string
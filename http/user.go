@@ -34,6 +34,20 @@ type GetGroupsByUserIdResponse struct {
 	Total  int              `json:"total"`
 }
 
+type GetEffectivePermissionsResponse struct {
+	Permissions []api.EffectivePermission `json:"permissions,omitempty"`
+	Limit       int                       `json:"limit"`
+	Offset      int                       `json:"offset"`
+	Total       int                       `json:"total"`
+}
+
+type ListAttachedUserPoliciesResponse struct {
+	AttachedPolicies []api.UserPolicies `json:"policies,omitempty"`
+	Limit            int                `json:"limit"`
+	Offset           int                `json:"offset"`
+	Total            int                `json:"total"`
+}
+
 // HANDLERS
 
 func (wh *WorkerHandler) HandleAddUser(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -60,6 +74,9 @@ func (wh *WorkerHandler) HandleGetUserByExternalID(w http.ResponseWriter, r *htt
 
 	// Call user API to get user
 	response, err := wh.worker.UserApi.GetUserByExternalID(requestInfo, filterData.ExternalID)
+	if err == nil {
+		w.Header().Set(ETAG_HEADER, api.ETag(response.UpdateAt))
+	}
 	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
 }
 
@@ -94,6 +111,9 @@ func (wh *WorkerHandler) HandleUpdateUser(w http.ResponseWriter, r *http.Request
 
 	// Call user API to update user
 	response, err := wh.worker.UserApi.UpdateUser(requestInfo, filterData.ExternalID, request.Path)
+	if err == nil {
+		w.Header().Set(ETAG_HEADER, api.ETag(response.UpdateAt))
+	}
 	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
 }
 
@@ -128,3 +148,65 @@ func (wh *WorkerHandler) HandleListGroupsByUser(w http.ResponseWriter, r *http.R
 	}
 	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
 }
+
+func (wh *WorkerHandler) HandleListEffectivePermissionsByUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, filterData, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+
+	// Call user API to retrieve user's effective permissions
+	result, total, err := wh.worker.UserApi.ListEffectivePermissionsByUser(requestInfo, filterData)
+	response := GetEffectivePermissionsResponse{
+		Permissions: result,
+		Offset:      filterData.Offset,
+		Limit:       filterData.Limit,
+		Total:       total,
+	}
+	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+}
+
+func (wh *WorkerHandler) HandleAttachPolicyToUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, filterData, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+	// Call user API to attach policy to user
+	err := wh.worker.UserApi.AttachPolicyToUser(requestInfo, filterData.ExternalID, filterData.Org, filterData.PolicyName)
+	wh.processHttpResponse(r, w, requestInfo, nil, err, http.StatusNoContent)
+}
+
+func (wh *WorkerHandler) HandleDetachPolicyToUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, filterData, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+	// Call user API to detach policy from user
+	err := wh.worker.UserApi.DetachPolicyToUser(requestInfo, filterData.ExternalID, filterData.Org, filterData.PolicyName)
+	wh.processHttpResponse(r, w, requestInfo, nil, err, http.StatusNoContent)
+}
+
+func (wh *WorkerHandler) HandleListAttachedUserPolicies(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Process request
+	requestInfo, filterData, apiErr := wh.processHttpRequest(r, w, ps, nil)
+	if apiErr != nil {
+		wh.processHttpResponse(r, w, requestInfo, nil, apiErr, http.StatusBadRequest)
+		return
+	}
+	// Call user API to list user policies
+	result, total, err := wh.worker.UserApi.ListAttachedUserPolicies(requestInfo, filterData)
+	// Create response
+	response := &ListAttachedUserPoliciesResponse{
+		AttachedPolicies: result,
+		Offset:           filterData.Offset,
+		Limit:            filterData.Limit,
+		Total:            total,
+	}
+	wh.processHttpResponse(r, w, requestInfo, response, err, http.StatusOK)
+}
@@ -868,3 +868,570 @@ func TestWorkerHandler_HandleListGroupsByUser(t *testing.T) {
 		}
 	}
 }
+
+func TestWorkerHandler_HandleListEffectivePermissionsByUser(t *testing.T) {
+	testcases := map[string]struct {
+		// API method args
+		filter       *api.Filter
+		ignoreArgsIn bool
+		// Expected result
+		expectedStatusCode int
+		expectedResponse   GetEffectivePermissionsResponse
+		expectedError      api.Error
+		// Manager Results
+		listEffectivePermissionsResult []api.EffectivePermission
+		totalPermissionsResult         int
+		// Manager Errors
+		listEffectivePermissionsErr error
+	}{
+		"OkCase": {
+			filter: &api.Filter{
+				ExternalID:   "UserID",
+				ActionPrefix: "iam:",
+				Limit:        1,
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: GetEffectivePermissionsResponse{
+				Permissions: []api.EffectivePermission{
+					{
+						Action: api.USER_ACTION_GET_USER,
+						Restrictions: &api.Restrictions{
+							AllowedUrnPrefixes: []string{"urn:iws:iam::user/*"},
+						},
+					},
+				},
+				Offset: 0,
+				Limit:  1,
+				Total:  2,
+			},
+			listEffectivePermissionsResult: []api.EffectivePermission{
+				{
+					Action: api.USER_ACTION_GET_USER,
+					Restrictions: &api.Restrictions{
+						AllowedUrnPrefixes: []string{"urn:iws:iam::user/*"},
+					},
+				},
+			},
+			totalPermissionsResult: 2,
+		},
+		"ErrorCaseInvalidFilterParams": {
+			filter: &api.Filter{
+				ExternalID: "UserID",
+				Limit:      -1,
+			},
+			ignoreArgsIn:       true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: Limit -1",
+			},
+		},
+		"ErrorCaseUserNotExist": {
+			filter: &api.Filter{
+				ExternalID: "UserID",
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedError: api.Error{
+				Code:    api.USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message: "User not exist",
+			},
+			listEffectivePermissionsErr: &api.Error{
+				Code:    api.USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message: "User not exist",
+			},
+		},
+		"ErrorCaseUnauthorizedResourcesError": {
+			filter: &api.Filter{
+				ExternalID: "UnauthorizedID",
+			},
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+			listEffectivePermissionsErr: &api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+		},
+		"ErrorCaseUnknownApiError": {
+			filter: &api.Filter{
+				ExternalID: "ExceptionID",
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			listEffectivePermissionsErr: &api.Error{
+				Code:    api.UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[ListEffectivePermsMethod][0] = test.listEffectivePermissionsResult
+		testApi.ArgsOut[ListEffectivePermsMethod][1] = test.totalPermissionsResult
+		testApi.ArgsOut[ListEffectivePermsMethod][2] = test.listEffectivePermissionsErr
+
+		url := fmt.Sprintf(server.URL+USER_ROOT_URL+"/%v/effective-permissions", test.filter.ExternalID)
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		addQueryParams(test.filter, req)
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		if !test.ignoreArgsIn {
+			// Check received parameters
+			filterData, ok := testApi.ArgsIn[ListEffectivePermsMethod][1].(*api.Filter)
+			if ok {
+				// Check result
+				assert.Equal(t, test.filter, filterData, "Error in test case %v", n)
+			}
+		}
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			getEffectivePermissionsResponse := GetEffectivePermissionsResponse{}
+			err = json.NewDecoder(res.Body).Decode(&getEffectivePermissionsResponse)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedResponse, getEffectivePermissionsResponse, "Error in test case %v", n)
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestWorkerHandler_HandleAttachPolicyToUser(t *testing.T) {
+	testcases := map[string]struct {
+		// API method args
+		externalID   string
+		org          string
+		policyName   string
+		offset       string
+		ignoreArgsIn bool
+		// Expected result
+		expectedStatusCode int
+		expectedError      api.Error
+		// Manager Errors
+		attachUserPolicyErr error
+	}{
+		"OkCase": {
+			externalID:         "user1",
+			org:                "org1",
+			policyName:         "policy1",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		"ErrorCaseInvalidRequest": {
+			externalID:         "user1",
+			org:                "org1",
+			policyName:         "policy1",
+			offset:             "-1",
+			ignoreArgsIn:       true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: Offset -1",
+			},
+		},
+		"ErrorCaseUserNotFoundErr": {
+			externalID:         "user1",
+			org:                "org1",
+			policyName:         "policy1",
+			expectedStatusCode: http.StatusNotFound,
+			expectedError: api.Error{
+				Code:    api.USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message: "User Not Found",
+			},
+			attachUserPolicyErr: &api.Error{
+				Code:    api.USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message: "User Not Found",
+			},
+		},
+		"ErrorCasePolicyNotFoundErr": {
+			externalID:         "user1",
+			org:                "org1",
+			policyName:         "policy1",
+			expectedStatusCode: http.StatusNotFound,
+			expectedError: api.Error{
+				Code:    api.POLICY_BY_ORG_AND_NAME_NOT_FOUND,
+				Message: "Policy Not Found",
+			},
+			attachUserPolicyErr: &api.Error{
+				Code:    api.POLICY_BY_ORG_AND_NAME_NOT_FOUND,
+				Message: "Policy Not Found",
+			},
+		},
+		"ErrorCaseUnauthorizedError": {
+			externalID:         "user1",
+			org:                "org1",
+			policyName:         "policy1",
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+			attachUserPolicyErr: &api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+		},
+		"ErrorCasePolicyIsAlreadyAttachedErr": {
+			externalID:         "user1",
+			org:                "org1",
+			policyName:         "policy1",
+			expectedStatusCode: http.StatusConflict,
+			expectedError: api.Error{
+				Code:    api.POLICY_IS_ALREADY_ATTACHED_TO_USER,
+				Message: "Policy is already attached to user",
+			},
+			attachUserPolicyErr: &api.Error{
+				Code:    api.POLICY_IS_ALREADY_ATTACHED_TO_USER,
+				Message: "Policy is already attached to user",
+			},
+		},
+		"ErrorCaseUnknownApiError": {
+			externalID:         "user1",
+			org:                "org1",
+			policyName:         "policy1",
+			expectedStatusCode: http.StatusInternalServerError,
+			attachUserPolicyErr: &api.Error{
+				Code:    api.UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[AttachPolicyToUserMethod][0] = test.attachUserPolicyErr
+
+		url := fmt.Sprintf(server.URL+USER_ROOT_URL+"/%v/policies/organizations/%v/%v", test.externalID, test.org, test.policyName)
+		req, err := http.NewRequest(http.MethodPost, url, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		q := req.URL.Query()
+		q.Add("Offset", test.offset)
+		req.URL.RawQuery = q.Encode()
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		if !test.ignoreArgsIn {
+			// Check received parameters
+			assert.Equal(t, test.externalID, testApi.ArgsIn[AttachPolicyToUserMethod][1], "Error in test case %v", n)
+			assert.Equal(t, test.org, testApi.ArgsIn[AttachPolicyToUserMethod][2], "Error in test case %v", n)
+			assert.Equal(t, test.policyName, testApi.ArgsIn[AttachPolicyToUserMethod][3], "Error in test case %v", n)
+		}
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusNoContent:
+			// No message expected
+			continue
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestWorkerHandler_HandleDetachPolicyToUser(t *testing.T) {
+	testcases := map[string]struct {
+		// API method args
+		externalID   string
+		org          string
+		policyName   string
+		offset       string
+		ignoreArgsIn bool
+		// Expected result
+		expectedStatusCode int
+		expectedError      api.Error
+		// Manager Errors
+		detachUserPolicyErr error
+	}{
+		"OkCase": {
+			externalID:         "user1",
+			org:                "org1",
+			policyName:         "policy1",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		"ErrorCaseInvalidRequest": {
+			externalID:         "user1",
+			org:                "org1",
+			policyName:         "policy1",
+			offset:             "-1",
+			ignoreArgsIn:       true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: Offset -1",
+			},
+		},
+		"ErrorCaseUserNotFoundErr": {
+			externalID:         "user1",
+			org:                "org1",
+			policyName:         "policy1",
+			expectedStatusCode: http.StatusNotFound,
+			expectedError: api.Error{
+				Code:    api.USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message: "User Not Found",
+			},
+			detachUserPolicyErr: &api.Error{
+				Code:    api.USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message: "User Not Found",
+			},
+		},
+		"ErrorCaseUnauthorizedError": {
+			externalID:         "user1",
+			org:                "org1",
+			policyName:         "policy1",
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+			detachUserPolicyErr: &api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+		},
+		"ErrorCasePolicyIsNotAttachedErr": {
+			externalID:         "user1",
+			org:                "org1",
+			policyName:         "policy1",
+			expectedStatusCode: http.StatusNotFound,
+			expectedError: api.Error{
+				Code:    api.POLICY_IS_NOT_ATTACHED_TO_USER,
+				Message: "Policy is not attached to user",
+			},
+			detachUserPolicyErr: &api.Error{
+				Code:    api.POLICY_IS_NOT_ATTACHED_TO_USER,
+				Message: "Policy is not attached to user",
+			},
+		},
+		"ErrorCaseUnknownApiError": {
+			externalID:         "user1",
+			org:                "org1",
+			policyName:         "policy1",
+			expectedStatusCode: http.StatusInternalServerError,
+			detachUserPolicyErr: &api.Error{
+				Code:    api.UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[DetachPolicyToUserMethod][0] = test.detachUserPolicyErr
+
+		url := fmt.Sprintf(server.URL+USER_ROOT_URL+"/%v/policies/organizations/%v/%v", test.externalID, test.org, test.policyName)
+		req, err := http.NewRequest(http.MethodDelete, url, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		q := req.URL.Query()
+		q.Add("Offset", test.offset)
+		req.URL.RawQuery = q.Encode()
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		if !test.ignoreArgsIn {
+			// Check received parameters
+			assert.Equal(t, test.externalID, testApi.ArgsIn[DetachPolicyToUserMethod][1], "Error in test case %v", n)
+			assert.Equal(t, test.org, testApi.ArgsIn[DetachPolicyToUserMethod][2], "Error in test case %v", n)
+			assert.Equal(t, test.policyName, testApi.ArgsIn[DetachPolicyToUserMethod][3], "Error in test case %v", n)
+		}
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusNoContent:
+			// No message expected
+			continue
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
+
+func TestWorkerHandler_HandleListAttachedUserPolicies(t *testing.T) {
+	now := time.Now().UTC()
+	testcases := map[string]struct {
+		// API method args
+		filter       *api.Filter
+		ignoreArgsIn bool
+		// Expected result
+		expectedStatusCode int
+		expectedResponse   ListAttachedUserPoliciesResponse
+		expectedError      api.Error
+		// Manager Results
+		getListAttachedUserPoliciesResult []api.UserPolicies
+		totalPoliciesResult               int
+		// Manager Errors
+		getListAttachedUserPoliciesErr error
+	}{
+		"OkCase": {
+			filter: &api.Filter{
+				ExternalID: "user1",
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: ListAttachedUserPoliciesResponse{
+				AttachedPolicies: []api.UserPolicies{
+					{
+						Org:      "org1",
+						Policy:   "policy1",
+						CreateAt: now,
+					},
+					{
+						Org:      "org2",
+						Policy:   "policy2",
+						CreateAt: now,
+					},
+				},
+				Total: 2,
+			},
+			getListAttachedUserPoliciesResult: []api.UserPolicies{
+				{
+					Org:      "org1",
+					Policy:   "policy1",
+					CreateAt: now,
+				},
+				{
+					Org:      "org2",
+					Policy:   "policy2",
+					CreateAt: now,
+				},
+			},
+			totalPoliciesResult: 2,
+		},
+		"ErrorCaseInvalidFilterParams": {
+			filter: &api.Filter{
+				ExternalID: "user1",
+				Offset:     -1,
+			},
+			ignoreArgsIn:       true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError: api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter: Offset -1",
+			},
+		},
+		"ErrorCaseUserNotFoundErr": {
+			filter: &api.Filter{
+				ExternalID: "user1",
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedError: api.Error{
+				Code:    api.USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message: "User Not Found",
+			},
+			getListAttachedUserPoliciesErr: &api.Error{
+				Code:    api.USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message: "User Not Found",
+			},
+		},
+		"ErrorCaseUnauthorizedError": {
+			filter: &api.Filter{
+				ExternalID: "user1",
+			},
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+			getListAttachedUserPoliciesErr: &api.Error{
+				Code:    api.UNAUTHORIZED_RESOURCES_ERROR,
+				Message: "Unauthorized",
+			},
+		},
+		"ErrorCaseUnknownApiError": {
+			filter: &api.Filter{
+				ExternalID: "user1",
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			getListAttachedUserPoliciesErr: &api.Error{
+				Code:    api.UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+		},
+	}
+
+	client := http.DefaultClient
+
+	for n, test := range testcases {
+
+		testApi.ArgsOut[ListAttachedUserPoliciesMethod][0] = test.getListAttachedUserPoliciesResult
+		testApi.ArgsOut[ListAttachedUserPoliciesMethod][1] = test.totalPoliciesResult
+		testApi.ArgsOut[ListAttachedUserPoliciesMethod][2] = test.getListAttachedUserPoliciesErr
+
+		url := fmt.Sprintf(server.URL+USER_ROOT_URL+"/%v/policies", test.filter.ExternalID)
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		addQueryParams(test.filter, req)
+
+		res, err := client.Do(req)
+		assert.Nil(t, err, "Error in test case %v", n)
+
+		if !test.ignoreArgsIn {
+			// Check received parameters
+			filterData, ok := testApi.ArgsIn[ListAttachedUserPoliciesMethod][1].(*api.Filter)
+			if ok {
+				// Check result
+				assert.Equal(t, test.filter, filterData, "Error in test case %v", n)
+			}
+		}
+
+		// check status code
+		assert.Equal(t, test.expectedStatusCode, res.StatusCode, "Error in test case %v", n)
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			getUserPoliciesResponse := ListAttachedUserPoliciesResponse{}
+			err = json.NewDecoder(res.Body).Decode(&getUserPoliciesResponse)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check result
+			assert.Equal(t, test.expectedResponse, getUserPoliciesResponse, "Error in test case %v", n)
+		case http.StatusInternalServerError: // Empty message so continue
+			continue
+		default:
+			apiError := api.Error{}
+			err = json.NewDecoder(res.Body).Decode(&apiError)
+			assert.Nil(t, err, "Error in test case %v", n)
+			// Check error
+			assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+		}
+	}
+}
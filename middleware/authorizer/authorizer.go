@@ -0,0 +1,199 @@
+package authorizer
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/http/authzcheck"
+	"github.com/Tecsisa/foulkon/middleware"
+	"github.com/julienschmidt/httprouter"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	// Default values of authorizer config
+	DEFAULT_TIMEOUT    = 10 * time.Second
+	DEFAULT_CACHE_SIZE = 10000
+)
+
+// Default headers with the credentials of requests, used if none are configured
+var DEFAULT_CACHE_HEADERS = []string{"Authorization"}
+
+// TYPE DEFINITIONS
+
+// Config of an authorizer middleware
+type Config struct {
+	// Worker URL, e.g. http://localhost:8000
+	WorkerURL string
+	// Proxy secret of worker, sent to forward the source IP of requests. Worker uses the IP of the middleware
+	// otherwise
+	ProxySecret string
+	// HTTP client to call worker. A client with DEFAULT_TIMEOUT is used if nil
+	HTTPClient *http.Client
+	// Resources to authorize, matched by method and path. Path params, e.g. /users/:id, replace the {param}
+	// placeholders of their URN, e.g. urn:ews:example:instance1:user/{id}. Host isn't used
+	Resources []api.ResourceEntity
+	// Pass requests that don't match any resource without authorization. They are rejected otherwise
+	AllowUnmatched bool
+	// Time each decision is cached for the credentials of a request. Cache is disabled if 0
+	CacheTTL time.Duration
+	// Max number of cached decisions, the least recently used one is evicted when full. DEFAULT_CACHE_SIZE is used
+	// if 0
+	CacheSize int
+	// Headers with the credentials of requests, whose hash is the key of cached decisions. Requests without any of
+	// them aren't cached. DEFAULT_CACHE_HEADERS are used if empty
+	CacheHeaders []string
+}
+
+// AuthorizerMiddleware authorizes requests with worker before calling the wrapped handler, like the proxy does
+// before forwarding them. Denied requests are rejected with the same responses as proxy ones
+type AuthorizerMiddleware struct {
+	config Config
+	client *http.Client
+	cache  *api.AuthzResultCache
+}
+
+// NewAuthorizerMiddleware returns an AuthorizerMiddleware configured with config, or an error if any resource
+// isn't valid or collides with another one
+func NewAuthorizerMiddleware(config Config) (*AuthorizerMiddleware, error) {
+	resources := []api.ResourceEntity{}
+	for _, resource := range config.Resources {
+		resource.Path = httprouter.CleanPath(resource.Path)
+		if err := api.IsValidResourceRoute(&resource); err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+	config.Resources = resources
+	// Check collisions before routers are built for each wrapped handler
+	if err := addRoutes(httprouter.New(), config.Resources, nil); err != nil {
+		return nil, err
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: DEFAULT_TIMEOUT}
+	}
+	if config.CacheSize <= 0 {
+		config.CacheSize = DEFAULT_CACHE_SIZE
+	}
+	if len(config.CacheHeaders) == 0 {
+		config.CacheHeaders = DEFAULT_CACHE_HEADERS
+	}
+	return &AuthorizerMiddleware{
+		config: config,
+		client: client,
+		cache:  api.NewAuthzResultCache(config.CacheSize, config.CacheTTL, config.CacheTTL),
+	}, nil
+}
+
+// Action returns a handler that calls next only with authorized requests
+func (a *AuthorizerMiddleware) Action(next http.Handler) http.Handler {
+	router := httprouter.New()
+	// Requests are matched only by configured routes, so they aren't redirected or rejected with 405
+	router.RedirectTrailingSlash = false
+	router.RedirectFixedPath = false
+	router.HandleMethodNotAllowed = false
+	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := setRequestID(w, r)
+		if a.config.AllowUnmatched {
+			next.ServeHTTP(w, r)
+			return
+		}
+		apiError := &api.Error{
+			Code:    authzcheck.FORBIDDEN_ERROR,
+			Message: fmt.Sprintf("No resource configured for %v %v", r.Method, r.URL.Path),
+		}
+		a.reject(w, r, requestID, "None", apiError)
+	})
+	addRoutes(router, a.config.Resources, func(resource api.ResourceEntity) httprouter.Handle {
+		return a.authorize(resource, next)
+	})
+	return router
+}
+
+// PRIVATE HELPER METHODS
+
+// Return a handler that authorizes requests of resource before calling next
+func (a *AuthorizerMiddleware) authorize(resource api.ResourceEntity, next http.Handler) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		requestID := setRequestID(w, r)
+		urn := authzcheck.ReplaceUrnParams(resource.Urn, ps)
+
+		// Credentials aren't kept in memory, results are cached by their hash
+		key := ""
+		if a.cache != nil {
+			if credentials := authzcheck.HashCredentials(r, a.config.CacheHeaders); credentials != "" {
+				key = authzcheck.AuthzCacheKey(credentials, middleware.GetSourceIP(r), resource.Action, urn)
+			}
+		}
+		workerRequestID := "None"
+		var apiError *api.Error
+		cached := false
+		if key != "" {
+			_, apiError, cached = a.cache.Get(key)
+		}
+		if !cached {
+			var user string
+			var err error
+			workerRequestID, user, err = authzcheck.CheckAuthorization(a.client, a.config.WorkerURL, a.config.ProxySecret,
+				r, urn, resource.Action)
+			if err != nil {
+				apiError = err.(*api.Error)
+			}
+			if key != "" && (apiError == nil || apiError.Code == authzcheck.FORBIDDEN_ERROR) {
+				// Only decisions are cached, errors retrieving them aren't
+				a.cache.Set(key, user, apiError)
+			}
+		}
+		if apiError != nil {
+			a.reject(w, r, requestID, workerRequestID, apiError)
+			return
+		}
+		api.TransactionProxyLog(requestID, workerRequestID, r, "Request accepted")
+		next.ServeHTTP(w, r)
+	}
+}
+
+// Write the response of a request that isn't authorized
+func (a *AuthorizerMiddleware) reject(w http.ResponseWriter, r *http.Request, requestID string, workerRequestID string,
+	apiError *api.Error) {
+	statusCode, responseErr := authzcheck.AuthorizationErrorResponse(apiError)
+	authzcheck.WriteErrorResponse(w, statusCode, responseErr)
+	api.TransactionProxyErrorLogWithStatus(requestID, workerRequestID, r, statusCode, apiError)
+}
+
+// Add a route for each resource, returning an error if it collides with another one
+func addRoutes(router *httprouter.Router, resources []api.ResourceEntity,
+	handle func(resource api.ResourceEntity) httprouter.Handle) (err error) {
+	var resource api.ResourceEntity
+	defer func() {
+		if r := recover(); r != nil {
+			err = &api.Error{
+				Code:    api.PROXY_RESOURCES_ROUTES_CONFLICT,
+				Message: fmt.Sprintf("Route %v %v collides with another resource: %v", resource.Method, resource.Path, r),
+			}
+		}
+	}()
+	for _, resource = range resources {
+		h := func(http.ResponseWriter, *http.Request, httprouter.Params) {}
+		if handle != nil {
+			h = handle(resource)
+		}
+		router.Handle(resource.Method, resource.Path, h)
+	}
+	return nil
+}
+
+// Keep request ID sent by client, so requests can be traced in worker, or create a new one
+func setRequestID(w http.ResponseWriter, r *http.Request) string {
+	requestID := r.Header.Get(middleware.REQUEST_ID_HEADER)
+	if requestID == "" {
+		requestID = uuid.NewV4().String()
+		r.Header.Set(middleware.REQUEST_ID_HEADER, requestID)
+	}
+	w.Header().Set(middleware.REQUEST_ID_HEADER, requestID)
+	return requestID
+}
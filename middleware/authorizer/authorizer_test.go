@@ -0,0 +1,262 @@
+package authorizer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/http/authzcheck"
+	"github.com/Tecsisa/foulkon/middleware"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// Bodies of worker authorization requests and responses
+type workerRequest struct {
+	Action    string   `json:"action,omitempty"`
+	Resources []string `json:"resources,omitempty"`
+}
+
+type workerResponse struct {
+	ResourcesAllowed []string `json:"resourcesAllowed,omitempty"`
+}
+
+func TestNewAuthorizerMiddleware(t *testing.T) {
+	testcases := map[string]struct {
+		// Middleware args
+		resources []api.ResourceEntity
+		// Expected result
+		expectedError     *api.Error
+		expectedErrorCode string
+	}{
+		"OkCase": {
+			resources: []api.ResourceEntity{
+				{Method: http.MethodGet, Path: "/users/:id", Urn: "urn:ews:example:instance1:user/{id}", Action: "example:get"},
+				{Method: http.MethodPost, Path: "/users", Urn: "urn:ews:example:instance1:user/new", Action: "example:add"},
+			},
+		},
+		"ErrorCaseInvalidMethod": {
+			resources: []api.ResourceEntity{
+				{Method: "INVALID", Path: "/users", Urn: "urn:ews:example:instance1:user/new", Action: "example:add"},
+			},
+			expectedError: &api.Error{
+				Code:    api.REGEX_NO_MATCH,
+				Message: "Invalid parameter method, value: INVALID",
+			},
+		},
+		"ErrorCaseInvalidUrn": {
+			resources: []api.ResourceEntity{
+				{Method: http.MethodGet, Path: "/users", Urn: "urn:ews:example:instance1:user/*", Action: "example:get"},
+			},
+			expectedError: &api.Error{
+				Code:    api.REGEX_NO_MATCH,
+				Message: "Invalid parameter urn, value: urn:ews:example:instance1:user/*",
+			},
+		},
+		"ErrorCaseRoutesConflict": {
+			resources: []api.ResourceEntity{
+				{Method: http.MethodGet, Path: "/users/:id", Urn: "urn:ews:example:instance1:user/{id}", Action: "example:get"},
+				{Method: http.MethodGet, Path: "/users/:name", Urn: "urn:ews:example:instance1:user/{name}", Action: "example:get"},
+			},
+			expectedErrorCode: api.PROXY_RESOURCES_ROUTES_CONFLICT,
+		},
+	}
+
+	for n, test := range testcases {
+		_, err := NewAuthorizerMiddleware(Config{Resources: test.resources})
+		if test.expectedErrorCode != "" {
+			// Collision details depend on router
+			apiError, ok := err.(*api.Error)
+			assert.True(t, ok, "Error in test case %v", n)
+			assert.Equal(t, test.expectedErrorCode, apiError.Code, "Error in test case %v", n)
+			continue
+		}
+		if test.expectedError != nil {
+			assert.Equal(t, test.expectedError, err, "Error in test case %v", n)
+			continue
+		}
+		assert.Nil(t, err, "Error in test case %v", n)
+	}
+}
+
+func TestAuthorizerMiddleware_Action(t *testing.T) {
+	testLogger, _ := test.NewNullLogger()
+	api.Log = testLogger
+	resources := []api.ResourceEntity{
+		{Method: http.MethodGet, Path: "/users/:id", Urn: "urn:ews:example:instance1:user/{id}", Action: "example:get"},
+	}
+	testcases := map[string]struct {
+		// Middleware args
+		allowUnmatched bool
+		cacheTTL       time.Duration
+		cacheHeaders   []string
+		// Requests
+		method        string
+		path          string
+		authorization string
+		requests      int
+		// Worker response
+		workerStatusCode int
+		resourcesAllowed []string
+		// Expected result
+		expectedStatusCode     int
+		expectedError          *api.Error
+		expectedWorkerRequests []workerRequest
+	}{
+		"OkCaseAllowed": {
+			method:             http.MethodGet,
+			path:               "/users/user1",
+			authorization:      "Bearer token",
+			requests:           1,
+			workerStatusCode:   http.StatusOK,
+			resourcesAllowed:   []string{"urn:ews:example:instance1:user/user1"},
+			expectedStatusCode: http.StatusOK,
+			expectedWorkerRequests: []workerRequest{
+				{Action: "example:get", Resources: []string{"urn:ews:example:instance1:user/user1"}},
+			},
+		},
+		"OkCaseAllowedCached": {
+			cacheTTL:           time.Minute,
+			method:             http.MethodGet,
+			path:               "/users/user1",
+			authorization:      "Bearer token",
+			requests:           2,
+			workerStatusCode:   http.StatusOK,
+			resourcesAllowed:   []string{"urn:ews:example:instance1:user/user1"},
+			expectedStatusCode: http.StatusOK,
+			expectedWorkerRequests: []workerRequest{
+				{Action: "example:get", Resources: []string{"urn:ews:example:instance1:user/user1"}},
+			},
+		},
+		"OkCaseAllowedNotCachedWithoutCacheHeaders": {
+			cacheTTL:           time.Minute,
+			cacheHeaders:       []string{"X-Api-Key"},
+			method:             http.MethodGet,
+			path:               "/users/user1",
+			authorization:      "Bearer token",
+			requests:           2,
+			workerStatusCode:   http.StatusOK,
+			resourcesAllowed:   []string{"urn:ews:example:instance1:user/user1"},
+			expectedStatusCode: http.StatusOK,
+			expectedWorkerRequests: []workerRequest{
+				{Action: "example:get", Resources: []string{"urn:ews:example:instance1:user/user1"}},
+				{Action: "example:get", Resources: []string{"urn:ews:example:instance1:user/user1"}},
+			},
+		},
+		"OkCaseUnmatchedAllowed": {
+			allowUnmatched:     true,
+			method:             http.MethodPost,
+			path:               "/users",
+			requests:           1,
+			expectedStatusCode: http.StatusOK,
+		},
+		"ErrorCaseDeniedCached": {
+			cacheTTL:           time.Minute,
+			method:             http.MethodGet,
+			path:               "/users/user1",
+			authorization:      "Bearer token",
+			requests:           2,
+			workerStatusCode:   http.StatusOK,
+			resourcesAllowed:   []string{},
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: &api.Error{
+				Code:    authzcheck.FORBIDDEN_ERROR,
+				Message: "Forbidden resource. If you need access, contact the administrator",
+			},
+			expectedWorkerRequests: []workerRequest{
+				{Action: "example:get", Resources: []string{"urn:ews:example:instance1:user/user1"}},
+			},
+		},
+		"ErrorCaseUnauthenticatedNotCached": {
+			cacheTTL:           time.Minute,
+			method:             http.MethodGet,
+			path:               "/users/user1",
+			requests:           2,
+			workerStatusCode:   http.StatusUnauthorized,
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: &api.Error{
+				Code:    authzcheck.FORBIDDEN_ERROR,
+				Message: "Forbidden resource. If you need access, contact the administrator",
+			},
+			expectedWorkerRequests: []workerRequest{
+				{Action: "example:get", Resources: []string{"urn:ews:example:instance1:user/user1"}},
+				{Action: "example:get", Resources: []string{"urn:ews:example:instance1:user/user1"}},
+			},
+		},
+		"ErrorCaseWorkerErrorNotCached": {
+			cacheTTL:           time.Minute,
+			method:             http.MethodGet,
+			path:               "/users/user1",
+			authorization:      "Bearer token",
+			requests:           2,
+			workerStatusCode:   http.StatusInternalServerError,
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedError: &api.Error{
+				Code:    authzcheck.INTERNAL_SERVER_ERROR,
+				Message: "Internal server error. Contact the administrator",
+			},
+			expectedWorkerRequests: []workerRequest{
+				{Action: "example:get", Resources: []string{"urn:ews:example:instance1:user/user1"}},
+				{Action: "example:get", Resources: []string{"urn:ews:example:instance1:user/user1"}},
+			},
+		},
+		"ErrorCaseUnmatchedDenied": {
+			method:             http.MethodPost,
+			path:               "/users",
+			requests:           1,
+			expectedStatusCode: http.StatusForbidden,
+			expectedError: &api.Error{
+				Code:    authzcheck.FORBIDDEN_ERROR,
+				Message: "Forbidden resource. If you need access, contact the administrator",
+			},
+		},
+	}
+
+	for n, test := range testcases {
+		var workerRequests []workerRequest
+		worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request := workerRequest{}
+			json.NewDecoder(r.Body).Decode(&request)
+			workerRequests = append(workerRequests, request)
+			assert.Equal(t, "request-id", r.Header.Get(middleware.REQUEST_ID_HEADER), "Error in test case %v", n)
+			w.Header().Set(middleware.REQUEST_ID_HEADER, "worker-request-id")
+			w.WriteHeader(test.workerStatusCode)
+			json.NewEncoder(w).Encode(workerResponse{ResourcesAllowed: test.resourcesAllowed})
+		}))
+		authorizer, err := NewAuthorizerMiddleware(Config{
+			WorkerURL:      worker.URL,
+			Resources:      resources,
+			AllowUnmatched: test.allowUnmatched,
+			CacheTTL:       test.cacheTTL,
+			CacheHeaders:   test.cacheHeaders,
+		})
+		assert.Nil(t, err, "Error in test case %v", n)
+		handler := authorizer.Action(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		for i := 0; i < test.requests; i++ {
+			req, _ := http.NewRequest(test.method, test.path, nil)
+			req.Header.Set(middleware.REQUEST_ID_HEADER, "request-id")
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code, "Error in test case %v", n)
+			assert.Equal(t, "request-id", w.Header().Get(middleware.REQUEST_ID_HEADER), "Error in test case %v", n)
+			if test.expectedError != nil {
+				apiError := &api.Error{}
+				err := json.NewDecoder(w.Body).Decode(apiError)
+				assert.Nil(t, err, "Error in test case %v", n)
+				assert.Equal(t, test.expectedError, apiError, "Error in test case %v", n)
+			}
+		}
+		worker.Close()
+		assert.Equal(t, test.expectedWorkerRequests, workerRequests, "Error in test case %v", n)
+	}
+}
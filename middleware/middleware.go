@@ -1,11 +1,19 @@
 package middleware
 
-import "net/http"
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Tecsisa/foulkon/api"
+)
 
 const (
 	// HTTP Header
-	REQUEST_ID_HEADER = "X-Request-Id"
-	USER_ID_HEADER    = "X-FOULKON-USER-ID"
+	REQUEST_ID_HEADER   = "X-Request-Id"
+	USER_ID_HEADER      = "X-FOULKON-USER-ID"
+	SOURCE_IP_HEADER    = "X-FOULKON-SOURCE-IP"
+	PROXY_SECRET_HEADER = "X-FOULKON-PROXY-SECRET"
 
 	// Middleware names
 	AUTHENTICATOR_MIDDLEWARE  = "AUTHENTICATOR"
@@ -68,3 +76,60 @@ func (mwh *MiddlewareHandler) GetMiddlewareContext(r *http.Request) *MiddlewareC
 
 	return context
 }
+
+// Authenticate runs request r through the middlewares and returns the request info of the authenticated user, with
+// false if authentication fails. Middlewares receive a copy of r without USER_ID_HEADER, which is only set by
+// authenticator, so r isn't modified. Headers written by middlewares are returned, the rest of their response is
+// discarded
+func (mwh *MiddlewareHandler) Authenticate(r *http.Request) (api.RequestInfo, http.Header, bool) {
+	authRequest := new(http.Request)
+	*authRequest = *r
+	authRequest.Header = http.Header{}
+	for key, values := range r.Header {
+		authRequest.Header[key] = append([]string{}, values...)
+	}
+	authRequest.Header.Del(USER_ID_HEADER)
+
+	var requestInfo api.RequestInfo
+	authenticated := false
+	w := &headerWriter{header: http.Header{}}
+	mwh.Handle(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		authenticated = true
+		mc := mwh.GetMiddlewareContext(r)
+		requestInfo = api.RequestInfo{
+			Identifier: mc.UserId,
+			Admin:      mc.Admin,
+			RequestID:  mc.XRequestId,
+			Context: api.RequestContext{
+				SourceIP:    GetSourceIP(r),
+				RequestTime: time.Now().UTC(),
+			},
+		}
+	})).ServeHTTP(w, authRequest)
+	return requestInfo, w.header, authenticated
+}
+
+// GetSourceIP returns the IP address of the client connection of request r, without port
+func GetSourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// headerWriter is the response writer of Authenticate. Only its headers are kept, the status code and body written
+// when authentication fails are discarded
+type headerWriter struct {
+	header http.Header
+}
+
+func (hw *headerWriter) Header() http.Header {
+	return hw.header
+}
+
+func (hw *headerWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (hw *headerWriter) WriteHeader(int) {}
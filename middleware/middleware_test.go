@@ -11,6 +11,7 @@ import (
 
 const (
 	TEST_HEADER_NAME = "TestHeaderName"
+	TEST_USER_HEADER = "TestUserHeader"
 )
 
 // TestMiddleware that implements middleware interface
@@ -30,6 +31,26 @@ func (tm *TestMiddleware) GetInfo(r *http.Request, mc *MiddlewareContext) {
 	mc.XRequestId = r.Header.Get(TEST_HEADER_NAME)
 }
 
+// TestAuthenticator authenticates requests with the user of TEST_USER_HEADER
+type TestAuthenticator struct{}
+
+func (ta *TestAuthenticator) Action(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(REQUEST_ID_HEADER, "123")
+		user := r.Header.Get(TEST_USER_HEADER)
+		if user == "" {
+			http.Error(w, "Authentication failed", http.StatusUnauthorized)
+			return
+		}
+		r.Header.Add(USER_ID_HEADER, user)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (ta *TestAuthenticator) GetInfo(r *http.Request, mc *MiddlewareContext) {
+	mc.UserId = r.Header.Get(USER_ID_HEADER)
+}
+
 func TestMiddlewareHandler_Handle(t *testing.T) {
 	testMessage := "TestMessage"
 	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -119,6 +140,46 @@ func TestMiddlewareHandler_GetMiddlewareContext(t *testing.T) {
 
 }
 
+func TestMiddlewareHandler_Authenticate(t *testing.T) {
+	testcases := map[string]struct {
+		user string
+		// Expected result
+		expectedIdentifier string
+		expectedOk         bool
+	}{
+		"OkTestCase": {
+			user:               "user1",
+			expectedIdentifier: "user1",
+			expectedOk:         true,
+		},
+		"ErrorTestCaseUnauthenticated": {
+			expectedOk: false,
+		},
+	}
+
+	for x, testcase := range testcases {
+		mwh := getMiddlewareHandler(map[string]Middleware{
+			AUTHENTICATOR_MIDDLEWARE: &TestAuthenticator{},
+		})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		// User ID header sent by client must be ignored
+		req.Header.Set(USER_ID_HEADER, "admin")
+		if testcase.user != "" {
+			req.Header.Set(TEST_USER_HEADER, testcase.user)
+		}
+		requestInfo, header, ok := mwh.Authenticate(req)
+
+		assert.Equal(t, testcase.expectedOk, ok, "Error in test case %v", x)
+		assert.Equal(t, "123", header.Get(REQUEST_ID_HEADER), "Error in test case %v", x)
+		assert.Equal(t, "admin", req.Header.Get(USER_ID_HEADER), "Error in test case %v", x)
+		if testcase.expectedOk {
+			assert.Equal(t, testcase.expectedIdentifier, requestInfo.Identifier, "Error in test case %v", x)
+			assert.Equal(t, "10.0.0.1", requestInfo.Context.SourceIP, "Error in test case %v", x)
+		}
+	}
+}
+
 // Private helper methods
 func getMiddlewareHandler(middlewares map[string]Middleware) *MiddlewareHandler {
 	return &MiddlewareHandler{Middlewares: middlewares}
@@ -40,6 +40,43 @@ func TestXRequestIdMiddleware_Action(t *testing.T) {
 	assert.Nil(t, err, "Error in test")
 }
 
+func TestXRequestIdMiddleware_ActionWithRequestID(t *testing.T) {
+	testcases := map[string]struct {
+		requestID         string
+		expectedRequestID string
+	}{
+		"OkCaseValidRequestID": {
+			requestID:         "client-request.1234:5678",
+			expectedRequestID: "client-request.1234:5678",
+		},
+		"OkCaseInvalidRequestID": {
+			requestID: "invalid request id",
+		},
+	}
+
+	mw := NewXRequestIdMiddleware()
+	for n, test := range testcases {
+		var requestID string
+		testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID = r.Header.Get(middleware.REQUEST_ID_HEADER)
+			w.WriteHeader(http.StatusOK)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(middleware.REQUEST_ID_HEADER, test.requestID)
+		w := httptest.NewRecorder()
+		mw.Action(testHandler).ServeHTTP(w, req)
+		res := w.Result()
+
+		if test.expectedRequestID != "" {
+			assert.Equal(t, test.expectedRequestID, requestID, "Error in test case %v", n)
+		} else {
+			_, err := uuid.FromString(requestID)
+			assert.Nil(t, err, "Error in test case %v", n)
+		}
+		assert.Equal(t, requestID, res.Header.Get(middleware.REQUEST_ID_HEADER), "Error in test case %v", n)
+	}
+}
+
 func TestXRequestIdMiddleware_GetInfo(t *testing.T) {
 	mw := NewXRequestIdMiddleware()
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -0,0 +1,437 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: authz.proto
+
+package rpc
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type AuthorizeRequest struct {
+	Action               string            `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`
+	Resources            []string          `protobuf:"bytes,2,rep,name=resources,proto3" json:"resources,omitempty"`
+	Context              map[string]string `protobuf:"bytes,3,rep,name=context,proto3" json:"context,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *AuthorizeRequest) Reset()         { *m = AuthorizeRequest{} }
+func (m *AuthorizeRequest) String() string { return proto.CompactTextString(m) }
+func (*AuthorizeRequest) ProtoMessage()    {}
+func (*AuthorizeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_authz_634e1a514f27dc4f, []int{0}
+}
+func (m *AuthorizeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuthorizeRequest.Unmarshal(m, b)
+}
+func (m *AuthorizeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuthorizeRequest.Marshal(b, m, deterministic)
+}
+func (dst *AuthorizeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuthorizeRequest.Merge(dst, src)
+}
+func (m *AuthorizeRequest) XXX_Size() int {
+	return xxx_messageInfo_AuthorizeRequest.Size(m)
+}
+func (m *AuthorizeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuthorizeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuthorizeRequest proto.InternalMessageInfo
+
+func (m *AuthorizeRequest) GetAction() string {
+	if m != nil {
+		return m.Action
+	}
+	return ""
+}
+
+func (m *AuthorizeRequest) GetResources() []string {
+	if m != nil {
+		return m.Resources
+	}
+	return nil
+}
+
+func (m *AuthorizeRequest) GetContext() map[string]string {
+	if m != nil {
+		return m.Context
+	}
+	return nil
+}
+
+type AuthorizeResponse struct {
+	ResourcesAllowed     []string `protobuf:"bytes,1,rep,name=resources_allowed,json=resourcesAllowed,proto3" json:"resources_allowed,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuthorizeResponse) Reset()         { *m = AuthorizeResponse{} }
+func (m *AuthorizeResponse) String() string { return proto.CompactTextString(m) }
+func (*AuthorizeResponse) ProtoMessage()    {}
+func (*AuthorizeResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_authz_634e1a514f27dc4f, []int{1}
+}
+func (m *AuthorizeResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuthorizeResponse.Unmarshal(m, b)
+}
+func (m *AuthorizeResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuthorizeResponse.Marshal(b, m, deterministic)
+}
+func (dst *AuthorizeResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuthorizeResponse.Merge(dst, src)
+}
+func (m *AuthorizeResponse) XXX_Size() int {
+	return xxx_messageInfo_AuthorizeResponse.Size(m)
+}
+func (m *AuthorizeResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuthorizeResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuthorizeResponse proto.InternalMessageInfo
+
+func (m *AuthorizeResponse) GetResourcesAllowed() []string {
+	if m != nil {
+		return m.ResourcesAllowed
+	}
+	return nil
+}
+
+type AuthorizeItem struct {
+	Action               string   `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`
+	Resources            []string `protobuf:"bytes,2,rep,name=resources,proto3" json:"resources,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuthorizeItem) Reset()         { *m = AuthorizeItem{} }
+func (m *AuthorizeItem) String() string { return proto.CompactTextString(m) }
+func (*AuthorizeItem) ProtoMessage()    {}
+func (*AuthorizeItem) Descriptor() ([]byte, []int) {
+	return fileDescriptor_authz_634e1a514f27dc4f, []int{2}
+}
+func (m *AuthorizeItem) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuthorizeItem.Unmarshal(m, b)
+}
+func (m *AuthorizeItem) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuthorizeItem.Marshal(b, m, deterministic)
+}
+func (dst *AuthorizeItem) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuthorizeItem.Merge(dst, src)
+}
+func (m *AuthorizeItem) XXX_Size() int {
+	return xxx_messageInfo_AuthorizeItem.Size(m)
+}
+func (m *AuthorizeItem) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuthorizeItem.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuthorizeItem proto.InternalMessageInfo
+
+func (m *AuthorizeItem) GetAction() string {
+	if m != nil {
+		return m.Action
+	}
+	return ""
+}
+
+func (m *AuthorizeItem) GetResources() []string {
+	if m != nil {
+		return m.Resources
+	}
+	return nil
+}
+
+type BatchAuthorizeRequest struct {
+	Items                []*AuthorizeItem  `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	Context              map[string]string `protobuf:"bytes,2,rep,name=context,proto3" json:"context,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *BatchAuthorizeRequest) Reset()         { *m = BatchAuthorizeRequest{} }
+func (m *BatchAuthorizeRequest) String() string { return proto.CompactTextString(m) }
+func (*BatchAuthorizeRequest) ProtoMessage()    {}
+func (*BatchAuthorizeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_authz_634e1a514f27dc4f, []int{3}
+}
+func (m *BatchAuthorizeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchAuthorizeRequest.Unmarshal(m, b)
+}
+func (m *BatchAuthorizeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchAuthorizeRequest.Marshal(b, m, deterministic)
+}
+func (dst *BatchAuthorizeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchAuthorizeRequest.Merge(dst, src)
+}
+func (m *BatchAuthorizeRequest) XXX_Size() int {
+	return xxx_messageInfo_BatchAuthorizeRequest.Size(m)
+}
+func (m *BatchAuthorizeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchAuthorizeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchAuthorizeRequest proto.InternalMessageInfo
+
+func (m *BatchAuthorizeRequest) GetItems() []*AuthorizeItem {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+func (m *BatchAuthorizeRequest) GetContext() map[string]string {
+	if m != nil {
+		return m.Context
+	}
+	return nil
+}
+
+type AuthorizeItemResult struct {
+	Action               string   `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`
+	ResourcesAllowed     []string `protobuf:"bytes,2,rep,name=resources_allowed,json=resourcesAllowed,proto3" json:"resources_allowed,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuthorizeItemResult) Reset()         { *m = AuthorizeItemResult{} }
+func (m *AuthorizeItemResult) String() string { return proto.CompactTextString(m) }
+func (*AuthorizeItemResult) ProtoMessage()    {}
+func (*AuthorizeItemResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_authz_634e1a514f27dc4f, []int{4}
+}
+func (m *AuthorizeItemResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuthorizeItemResult.Unmarshal(m, b)
+}
+func (m *AuthorizeItemResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuthorizeItemResult.Marshal(b, m, deterministic)
+}
+func (dst *AuthorizeItemResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuthorizeItemResult.Merge(dst, src)
+}
+func (m *AuthorizeItemResult) XXX_Size() int {
+	return xxx_messageInfo_AuthorizeItemResult.Size(m)
+}
+func (m *AuthorizeItemResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuthorizeItemResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuthorizeItemResult proto.InternalMessageInfo
+
+func (m *AuthorizeItemResult) GetAction() string {
+	if m != nil {
+		return m.Action
+	}
+	return ""
+}
+
+func (m *AuthorizeItemResult) GetResourcesAllowed() []string {
+	if m != nil {
+		return m.ResourcesAllowed
+	}
+	return nil
+}
+
+type BatchAuthorizeResponse struct {
+	Items                []*AuthorizeItemResult `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *BatchAuthorizeResponse) Reset()         { *m = BatchAuthorizeResponse{} }
+func (m *BatchAuthorizeResponse) String() string { return proto.CompactTextString(m) }
+func (*BatchAuthorizeResponse) ProtoMessage()    {}
+func (*BatchAuthorizeResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_authz_634e1a514f27dc4f, []int{5}
+}
+func (m *BatchAuthorizeResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchAuthorizeResponse.Unmarshal(m, b)
+}
+func (m *BatchAuthorizeResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchAuthorizeResponse.Marshal(b, m, deterministic)
+}
+func (dst *BatchAuthorizeResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchAuthorizeResponse.Merge(dst, src)
+}
+func (m *BatchAuthorizeResponse) XXX_Size() int {
+	return xxx_messageInfo_BatchAuthorizeResponse.Size(m)
+}
+func (m *BatchAuthorizeResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchAuthorizeResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchAuthorizeResponse proto.InternalMessageInfo
+
+func (m *BatchAuthorizeResponse) GetItems() []*AuthorizeItemResult {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*AuthorizeRequest)(nil), "foulkon.AuthorizeRequest")
+	proto.RegisterMapType((map[string]string)(nil), "foulkon.AuthorizeRequest.ContextEntry")
+	proto.RegisterType((*AuthorizeResponse)(nil), "foulkon.AuthorizeResponse")
+	proto.RegisterType((*AuthorizeItem)(nil), "foulkon.AuthorizeItem")
+	proto.RegisterType((*BatchAuthorizeRequest)(nil), "foulkon.BatchAuthorizeRequest")
+	proto.RegisterMapType((map[string]string)(nil), "foulkon.BatchAuthorizeRequest.ContextEntry")
+	proto.RegisterType((*AuthorizeItemResult)(nil), "foulkon.AuthorizeItemResult")
+	proto.RegisterType((*BatchAuthorizeResponse)(nil), "foulkon.BatchAuthorizeResponse")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// AuthorizationClient is the client API for Authorization service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AuthorizationClient interface {
+	Authorize(ctx context.Context, in *AuthorizeRequest, opts ...grpc.CallOption) (*AuthorizeResponse, error)
+	BatchAuthorize(ctx context.Context, in *BatchAuthorizeRequest, opts ...grpc.CallOption) (*BatchAuthorizeResponse, error)
+}
+
+type authorizationClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAuthorizationClient(cc *grpc.ClientConn) AuthorizationClient {
+	return &authorizationClient{cc}
+}
+
+func (c *authorizationClient) Authorize(ctx context.Context, in *AuthorizeRequest, opts ...grpc.CallOption) (*AuthorizeResponse, error) {
+	out := new(AuthorizeResponse)
+	err := c.cc.Invoke(ctx, "/foulkon.Authorization/Authorize", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizationClient) BatchAuthorize(ctx context.Context, in *BatchAuthorizeRequest, opts ...grpc.CallOption) (*BatchAuthorizeResponse, error) {
+	out := new(BatchAuthorizeResponse)
+	err := c.cc.Invoke(ctx, "/foulkon.Authorization/BatchAuthorize", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuthorizationServer is the server API for Authorization service.
+type AuthorizationServer interface {
+	Authorize(context.Context, *AuthorizeRequest) (*AuthorizeResponse, error)
+	BatchAuthorize(context.Context, *BatchAuthorizeRequest) (*BatchAuthorizeResponse, error)
+}
+
+func RegisterAuthorizationServer(s *grpc.Server, srv AuthorizationServer) {
+	s.RegisterService(&_Authorization_serviceDesc, srv)
+}
+
+func _Authorization_Authorize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthorizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizationServer).Authorize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/foulkon.Authorization/Authorize",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizationServer).Authorize(ctx, req.(*AuthorizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorization_BatchAuthorize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchAuthorizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizationServer).BatchAuthorize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/foulkon.Authorization/BatchAuthorize",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizationServer).BatchAuthorize(ctx, req.(*BatchAuthorizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Authorization_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "foulkon.Authorization",
+	HandlerType: (*AuthorizationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Authorize",
+			Handler:    _Authorization_Authorize_Handler,
+		},
+		{
+			MethodName: "BatchAuthorize",
+			Handler:    _Authorization_BatchAuthorize_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "authz.proto",
+}
+
+func init() { proto.RegisterFile("authz.proto", fileDescriptor_authz_634e1a514f27dc4f) }
+
+var fileDescriptor_authz_634e1a514f27dc4f = []byte{
+	// 361 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x93, 0xcf, 0x4e, 0xf2, 0x40,
+	0x10, 0xc0, 0xb3, 0x6d, 0x80, 0x74, 0xf8, 0x3e, 0x03, 0xab, 0x92, 0xda, 0x10, 0x25, 0x3d, 0x18,
+	0x12, 0x4c, 0x0f, 0xf5, 0x62, 0x38, 0x01, 0x86, 0x83, 0x89, 0x17, 0x7b, 0xe4, 0x62, 0x6a, 0x1d,
+	0x03, 0xa1, 0x74, 0xb1, 0xbb, 0xab, 0xc2, 0x03, 0xf9, 0x28, 0xc6, 0xc7, 0x32, 0x6c, 0x6b, 0xf9,
+	0xd7, 0x7a, 0x30, 0xf1, 0xd6, 0x9d, 0x99, 0xce, 0xfc, 0xfa, 0x9b, 0x2d, 0x54, 0x7d, 0x29, 0xc6,
+	0x4b, 0x67, 0x1e, 0x33, 0xc1, 0x68, 0xe5, 0x89, 0xc9, 0x70, 0xca, 0x22, 0xfb, 0x83, 0x40, 0xad,
+	0x2f, 0xc5, 0x98, 0xc5, 0x93, 0x25, 0x7a, 0xf8, 0x2c, 0x91, 0x0b, 0xda, 0x80, 0xb2, 0x1f, 0x88,
+	0x09, 0x8b, 0x4c, 0xd2, 0x22, 0x6d, 0xc3, 0x4b, 0x4f, 0xb4, 0x09, 0x46, 0x8c, 0x9c, 0xc9, 0x38,
+	0x40, 0x6e, 0x6a, 0x2d, 0xbd, 0x6d, 0x78, 0xeb, 0x00, 0xed, 0x41, 0x25, 0x60, 0x91, 0xc0, 0x37,
+	0x61, 0xea, 0x2d, 0xbd, 0x5d, 0x75, 0xcf, 0x9d, 0x74, 0x8a, 0xb3, 0x3b, 0xc1, 0xb9, 0x4e, 0x0a,
+	0x87, 0x91, 0x88, 0x17, 0xde, 0xf7, 0x6b, 0x56, 0x17, 0xfe, 0x6d, 0x26, 0x68, 0x0d, 0xf4, 0x29,
+	0x2e, 0x52, 0x88, 0xd5, 0x23, 0x3d, 0x82, 0xd2, 0x8b, 0x1f, 0x4a, 0x34, 0x35, 0x15, 0x4b, 0x0e,
+	0x5d, 0xed, 0x8a, 0xd8, 0x3d, 0xa8, 0x6f, 0x4c, 0xe1, 0x73, 0x16, 0x71, 0xa4, 0x1d, 0xa8, 0x67,
+	0x7c, 0xf7, 0x7e, 0x18, 0xb2, 0x57, 0x7c, 0x34, 0x89, 0x02, 0xaf, 0x65, 0x89, 0x7e, 0x12, 0xb7,
+	0x87, 0xf0, 0x3f, 0xeb, 0x70, 0x23, 0x70, 0xf6, 0x3b, 0x0d, 0xf6, 0x27, 0x81, 0xe3, 0x81, 0x2f,
+	0x82, 0xf1, 0x9e, 0xd6, 0x0b, 0x28, 0x4d, 0x04, 0xce, 0xb8, 0x22, 0xa8, 0xba, 0x8d, 0x7d, 0x3d,
+	0xab, 0xb1, 0x5e, 0x52, 0x44, 0x87, 0x6b, 0x9d, 0x9a, 0xaa, 0xef, 0x64, 0xf5, 0xb9, 0xed, 0xff,
+	0xc0, 0xe9, 0x08, 0x0e, 0xb7, 0xd1, 0x90, 0xcb, 0xb0, 0xf8, 0x7a, 0xe4, 0xda, 0xd6, 0x0a, 0x6c,
+	0xdf, 0x42, 0x63, 0xf7, 0x33, 0xd2, 0xa5, 0xb9, 0xdb, 0x9a, 0x9a, 0x05, 0x9a, 0x14, 0x4b, 0x2a,
+	0xcb, 0x7d, 0x27, 0xeb, 0xe5, 0xf9, 0x0a, 0x66, 0x00, 0x46, 0x56, 0x4f, 0x4f, 0x0a, 0x6f, 0xa2,
+	0x65, 0xe5, 0xa5, 0x52, 0x92, 0x3b, 0x38, 0xd8, 0x66, 0xa4, 0xa7, 0x3f, 0xef, 0xc0, 0x3a, 0x2b,
+	0xcc, 0x27, 0x2d, 0x07, 0xa5, 0x91, 0x1e, 0xcf, 0x83, 0x87, 0xb2, 0xfa, 0x0d, 0x2f, 0xbf, 0x06,
+	0x00, 0xce, 0x5e, 0xea, 0x10, 0x95, 0x03, 0x00, 0x00,
+}
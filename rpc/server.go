@@ -0,0 +1,160 @@
+package rpc
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/foulkon"
+	"github.com/Tecsisa/foulkon/middleware"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcServer serves the gRPC authorization service of a worker
+type GrpcServer struct {
+	certFile string
+	keyFile  string
+	addr     string
+
+	worker *foulkon.Worker
+}
+
+// authorizationService implements AuthorizationServer with worker authorization API
+type authorizationService struct {
+	worker *foulkon.Worker
+}
+
+// NewGrpcServer returns a GrpcServer that listens in worker host and gRPC port, using worker TLS configuration
+func NewGrpcServer(worker *foulkon.Worker) *GrpcServer {
+	return &GrpcServer{
+		certFile: worker.CertFile,
+		keyFile:  worker.KeyFile,
+		addr:     worker.Host + ":" + worker.GrpcPort,
+		worker:   worker,
+	}
+}
+
+// Run starts a gRPC server
+func (gs *GrpcServer) Run() error {
+	var opts []grpc.ServerOption
+	if gs.certFile != "" || gs.keyFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(gs.certFile, gs.keyFile)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	ln, err := net.Listen("tcp", gs.addr)
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer(opts...)
+	RegisterAuthorizationServer(server, &authorizationService{worker: gs.worker})
+	return server.Serve(ln)
+}
+
+// Configuration of a gRPC server
+func (gs *GrpcServer) Configuration() error { return nil }
+
+// Authorize returns the resources that authenticated user is allowed to access with action
+func (as *authorizationService) Authorize(ctx context.Context, request *AuthorizeRequest) (*AuthorizeResponse, error) {
+	requestInfo, err := as.authenticate(ctx, "Authorize")
+	if err != nil {
+		return nil, err
+	}
+	// Add request attributes to evaluate conditions
+	requestInfo.Context.Attributes = request.Context
+
+	result, err := as.worker.AuthzApi.GetAuthorizedExternalResources(requestInfo, request.Action, request.Resources)
+	if err != nil {
+		return nil, statusError(requestInfo, err)
+	}
+	return &AuthorizeResponse{
+		ResourcesAllowed: result,
+	}, nil
+}
+
+// BatchAuthorize returns the resources that authenticated user is allowed to access for each item
+func (as *authorizationService) BatchAuthorize(ctx context.Context, request *BatchAuthorizeRequest) (*BatchAuthorizeResponse, error) {
+	requestInfo, err := as.authenticate(ctx, "BatchAuthorize")
+	if err != nil {
+		return nil, err
+	}
+	// Add request attributes to evaluate conditions
+	requestInfo.Context.Attributes = request.Context
+
+	items := []api.AuthorizeItem{}
+	for _, item := range request.Items {
+		items = append(items, api.AuthorizeItem{
+			Action:    item.Action,
+			Resources: item.Resources,
+		})
+	}
+	result, err := as.worker.AuthzApi.GetAuthorizedExternalResourcesBatch(requestInfo, items)
+	if err != nil {
+		return nil, statusError(requestInfo, err)
+	}
+	response := &BatchAuthorizeResponse{}
+	for _, item := range result {
+		response.Items = append(response.Items, &AuthorizeItemResult{
+			Action:           item.Action,
+			ResourcesAllowed: item.ResourcesAllowed,
+		})
+	}
+	return response, nil
+}
+
+// PRIVATE HELPER METHODS
+
+// Authenticate a call passing its metadata as the headers of an HTTP request through worker middlewares, so it's
+// authenticated like HTTP API requests. The request ID is sent back in the header of the call
+func (as *authorizationService) authenticate(ctx context.Context, method string) (api.RequestInfo, error) {
+	r, err := http.NewRequest(http.MethodPost, "/foulkon.Authorization/"+method, nil)
+	if err != nil {
+		return api.RequestInfo{}, status.Error(codes.Internal, err.Error())
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		for _, value := range values {
+			r.Header.Add(key, value)
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+	}
+
+	requestInfo, header, ok := as.worker.MiddlewareHandler.Authenticate(r)
+	if requestID := header.Get(middleware.REQUEST_ID_HEADER); requestID != "" {
+		grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(middleware.REQUEST_ID_HEADER), requestID))
+	}
+	if !ok {
+		return api.RequestInfo{}, status.Error(codes.Unauthenticated, "Authentication failed")
+	}
+	return requestInfo, nil
+}
+
+// Transform an API error into a gRPC status error, with the same grouping of HTTP API status codes
+func statusError(requestInfo api.RequestInfo, err error) error {
+	apiError := err.(*api.Error)
+	api.LogOperationError(requestInfo.RequestID, requestInfo.Identifier, apiError)
+	var code codes.Code
+	switch apiError.Code {
+	case api.UNAUTHORIZED_RESOURCES_ERROR:
+		code = codes.PermissionDenied
+	case api.USER_BY_EXTERNAL_ID_NOT_FOUND:
+		code = codes.NotFound
+	case api.INVALID_PARAMETER_ERROR, api.REGEX_NO_MATCH:
+		code = codes.InvalidArgument
+	default:
+		code = codes.Internal
+	}
+	return status.Error(code, apiError.Error())
+}
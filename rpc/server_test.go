@@ -0,0 +1,282 @@
+package rpc
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Tecsisa/foulkon/api"
+	"github.com/Tecsisa/foulkon/foulkon"
+	"github.com/Tecsisa/foulkon/middleware"
+	"github.com/Tecsisa/foulkon/middleware/auth"
+	"github.com/Tecsisa/foulkon/middleware/auth/header"
+	"github.com/Tecsisa/foulkon/middleware/xrequestid"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// testAuthzAPI records the calls of authorization methods and returns the configured results
+type testAuthzAPI struct {
+	api.AuthzAPI
+
+	requestInfo api.RequestInfo
+	action      string
+	resources   []string
+	items       []api.AuthorizeItem
+
+	resourcesResult []string
+	itemsResult     []api.AuthorizeItemResult
+	err             error
+}
+
+func (t *testAuthzAPI) GetAuthorizedExternalResources(requestInfo api.RequestInfo, action string, resources []string) ([]string, error) {
+	t.requestInfo = requestInfo
+	t.action = action
+	t.resources = resources
+	return t.resourcesResult, t.err
+}
+
+func (t *testAuthzAPI) GetAuthorizedExternalResourcesBatch(requestInfo api.RequestInfo, items []api.AuthorizeItem) ([]api.AuthorizeItemResult, error) {
+	t.requestInfo = requestInfo
+	t.items = items
+	return t.itemsResult, t.err
+}
+
+// Start a gRPC server of a worker with testAuthzAPI, header authenticator and X-Request-Id middleware
+func startTestServer(authzAPI *testAuthzAPI) (AuthorizationClient, func()) {
+	middlewares := map[string]middleware.Middleware{
+		middleware.AUTHENTICATOR_MIDDLEWARE: auth.NewAuthenticatorMiddleware(header.InitHeaderConnector("X-Foulkon-User"), "admin", "admin"),
+		middleware.XREQUESTID_MIDDLEWARE:    xrequestid.NewXRequestIdMiddleware(),
+	}
+	worker := &foulkon.Worker{
+		AuthzApi:          authzAPI,
+		MiddlewareHandler: &middleware.MiddlewareHandler{Middlewares: middlewares},
+	}
+
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	server := grpc.NewServer()
+	RegisterAuthorizationServer(server, &authorizationService{worker: worker})
+	go server.Serve(ln)
+
+	conn, _ := grpc.Dial(ln.Addr().String(), grpc.WithInsecure())
+	return NewAuthorizationClient(conn), func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func TestAuthorizationService_Authorize(t *testing.T) {
+	testLogger, _ := test.NewNullLogger()
+	api.Log = testLogger
+	testcases := map[string]struct {
+		// Call metadata
+		metadata metadata.MD
+		// Method args
+		request *AuthorizeRequest
+		// API results
+		resourcesResult []string
+		err             error
+		// Expected result
+		expectedResponse    *AuthorizeResponse
+		expectedRequestInfo api.RequestInfo
+		expectedCode        codes.Code
+	}{
+		"OkCaseUser": {
+			metadata: metadata.Pairs("x-foulkon-user", "user1", "x-request-id", "request-id"),
+			request: &AuthorizeRequest{
+				Action:    "example:get",
+				Resources: []string{"urn:ews:example:instance1:resource/res1", "urn:ews:example:instance1:resource/res2"},
+				Context:   map[string]string{"env": "prod"},
+			},
+			resourcesResult: []string{"urn:ews:example:instance1:resource/res1"},
+			expectedResponse: &AuthorizeResponse{
+				ResourcesAllowed: []string{"urn:ews:example:instance1:resource/res1"},
+			},
+			expectedRequestInfo: api.RequestInfo{
+				Identifier: "user1",
+				RequestID:  "request-id",
+				Context: api.RequestContext{
+					SourceIP:   "127.0.0.1",
+					Attributes: map[string]string{"env": "prod"},
+				},
+			},
+		},
+		"OkCaseAdmin": {
+			// Basic admin:admin
+			metadata: metadata.Pairs("authorization", "Basic YWRtaW46YWRtaW4=", "x-request-id", "request-id"),
+			request: &AuthorizeRequest{
+				Action:    "example:get",
+				Resources: []string{"urn:ews:example:instance1:resource/res1"},
+			},
+			resourcesResult: []string{"urn:ews:example:instance1:resource/res1"},
+			expectedResponse: &AuthorizeResponse{
+				ResourcesAllowed: []string{"urn:ews:example:instance1:resource/res1"},
+			},
+			expectedRequestInfo: api.RequestInfo{
+				Identifier: "admin",
+				Admin:      true,
+				RequestID:  "request-id",
+				Context: api.RequestContext{
+					SourceIP: "127.0.0.1",
+				},
+			},
+		},
+		"ErrorCaseUnauthenticated": {
+			metadata: metadata.Pairs("x-request-id", "request-id"),
+			request: &AuthorizeRequest{
+				Action:    "example:get",
+				Resources: []string{"urn:ews:example:instance1:resource/res1"},
+			},
+			expectedCode: codes.Unauthenticated,
+		},
+		"ErrorCaseInvalidParameter": {
+			metadata: metadata.Pairs("x-foulkon-user", "user1", "x-request-id", "request-id"),
+			request: &AuthorizeRequest{
+				Action:    "invalid action",
+				Resources: []string{"urn:ews:example:instance1:resource/res1"},
+			},
+			err: &api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter action invalid action",
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		"ErrorCaseUserNotFound": {
+			metadata: metadata.Pairs("x-foulkon-user", "user1", "x-request-id", "request-id"),
+			request: &AuthorizeRequest{
+				Action:    "example:get",
+				Resources: []string{"urn:ews:example:instance1:resource/res1"},
+			},
+			err: &api.Error{
+				Code:    api.USER_BY_EXTERNAL_ID_NOT_FOUND,
+				Message: "User with externalId user1 not found",
+			},
+			expectedCode: codes.NotFound,
+		},
+		"ErrorCaseUnknownError": {
+			metadata: metadata.Pairs("x-foulkon-user", "user1", "x-request-id", "request-id"),
+			request: &AuthorizeRequest{
+				Action:    "example:get",
+				Resources: []string{"urn:ews:example:instance1:resource/res1"},
+			},
+			err: &api.Error{
+				Code:    api.UNKNOWN_API_ERROR,
+				Message: "Error",
+			},
+			expectedCode: codes.Internal,
+		},
+	}
+
+	for n, test := range testcases {
+		authzAPI := &testAuthzAPI{
+			resourcesResult: test.resourcesResult,
+			err:             test.err,
+		}
+		client, stop := startTestServer(authzAPI)
+
+		var responseHeader metadata.MD
+		ctx := metadata.NewOutgoingContext(context.Background(), test.metadata)
+		response, err := client.Authorize(ctx, test.request, grpc.Header(&responseHeader))
+		stop()
+
+		assert.Equal(t, []string{"request-id"}, responseHeader["x-request-id"], "Error in test case %v", n)
+		if test.expectedCode != codes.OK {
+			assert.Equal(t, test.expectedCode, grpc.Code(err), "Error in test case %v", n)
+			continue
+		}
+		assert.Nil(t, err, "Error in test case %v", n)
+		assert.Equal(t, test.expectedResponse.ResourcesAllowed, response.ResourcesAllowed, "Error in test case %v", n)
+		assert.Equal(t, test.request.Action, authzAPI.action, "Error in test case %v", n)
+		assert.Equal(t, test.request.Resources, authzAPI.resources, "Error in test case %v", n)
+		// Request time is set when call is received
+		authzAPI.requestInfo.Context.RequestTime = test.expectedRequestInfo.Context.RequestTime
+		assert.Equal(t, test.expectedRequestInfo, authzAPI.requestInfo, "Error in test case %v", n)
+	}
+}
+
+func TestAuthorizationService_BatchAuthorize(t *testing.T) {
+	testLogger, _ := test.NewNullLogger()
+	api.Log = testLogger
+	testcases := map[string]struct {
+		// Call metadata
+		metadata metadata.MD
+		// Method args
+		request *BatchAuthorizeRequest
+		// API results
+		itemsResult []api.AuthorizeItemResult
+		err         error
+		// Expected result
+		expectedItems    []api.AuthorizeItem
+		expectedResponse *BatchAuthorizeResponse
+		expectedCode     codes.Code
+	}{
+		"OkCase": {
+			metadata: metadata.Pairs("x-foulkon-user", "user1"),
+			request: &BatchAuthorizeRequest{
+				Items: []*AuthorizeItem{
+					{Action: "example:get", Resources: []string{"urn:ews:example:instance1:resource/res1"}},
+					{Action: "example:delete", Resources: []string{"urn:ews:example:instance1:resource/res1"}},
+				},
+			},
+			itemsResult: []api.AuthorizeItemResult{
+				{Action: "example:get", ResourcesAllowed: []string{"urn:ews:example:instance1:resource/res1"}},
+				{Action: "example:delete", ResourcesAllowed: []string{}},
+			},
+			expectedItems: []api.AuthorizeItem{
+				{Action: "example:get", Resources: []string{"urn:ews:example:instance1:resource/res1"}},
+				{Action: "example:delete", Resources: []string{"urn:ews:example:instance1:resource/res1"}},
+			},
+			expectedResponse: &BatchAuthorizeResponse{
+				Items: []*AuthorizeItemResult{
+					{Action: "example:get", ResourcesAllowed: []string{"urn:ews:example:instance1:resource/res1"}},
+					{Action: "example:delete"},
+				},
+			},
+		},
+		"ErrorCaseUnauthenticated": {
+			request: &BatchAuthorizeRequest{
+				Items: []*AuthorizeItem{
+					{Action: "example:get", Resources: []string{"urn:ews:example:instance1:resource/res1"}},
+				},
+			},
+			expectedCode: codes.Unauthenticated,
+		},
+		"ErrorCaseInvalidParameter": {
+			metadata: metadata.Pairs("x-foulkon-user", "user1"),
+			request:  &BatchAuthorizeRequest{},
+			err: &api.Error{
+				Code:    api.INVALID_PARAMETER_ERROR,
+				Message: "Invalid parameter items",
+			},
+			expectedItems: []api.AuthorizeItem{},
+			expectedCode:  codes.InvalidArgument,
+		},
+	}
+
+	for n, test := range testcases {
+		authzAPI := &testAuthzAPI{
+			itemsResult: test.itemsResult,
+			err:         test.err,
+		}
+		client, stop := startTestServer(authzAPI)
+
+		ctx := metadata.NewOutgoingContext(context.Background(), test.metadata)
+		response, err := client.BatchAuthorize(ctx, test.request)
+		stop()
+
+		assert.Equal(t, test.expectedItems, authzAPI.items, "Error in test case %v", n)
+		if test.expectedCode != codes.OK {
+			assert.Equal(t, test.expectedCode, grpc.Code(err), "Error in test case %v", n)
+			continue
+		}
+		assert.Nil(t, err, "Error in test case %v", n)
+		assert.Equal(t, len(test.expectedResponse.Items), len(response.Items), "Error in test case %v", n)
+		for i, item := range test.expectedResponse.Items {
+			assert.Equal(t, item.Action, response.Items[i].Action, "Error in test case %v", n)
+			assert.Equal(t, item.ResourcesAllowed, response.Items[i].ResourcesAllowed, "Error in test case %v", n)
+		}
+	}
+}
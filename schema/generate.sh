@@ -4,4 +4,6 @@ prmd doc user.json > ../doc/api/user.md
 prmd doc policy.json > ../doc/api/policy.md
 prmd doc proxy_resource.json > ../doc/api/proxy_resource.md
 prmd doc resource.json > ../doc/api/resource.md
-prmd doc oidc_provider.json > ../doc/api/oidc_provider.md
\ No newline at end of file
+prmd doc oidc_provider.json > ../doc/api/oidc_provider.md
+prmd doc audit.json > ../doc/api/audit.md
+prmd doc batch.json > ../doc/api/batch.md